@@ -18,14 +18,19 @@
 package core
 
 import (
+	"fmt"
 	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
 	"github.com/linkedin/Burrow/core/internal/cluster"
 	"github.com/linkedin/Burrow/core/internal/consumer"
+	"github.com/linkedin/Burrow/core/internal/emitter"
 	"github.com/linkedin/Burrow/core/internal/evaluator"
+	"github.com/linkedin/Burrow/core/internal/grpcserver"
 	"github.com/linkedin/Burrow/core/internal/helpers"
 	"github.com/linkedin/Burrow/core/internal/httpserver"
 	"github.com/linkedin/Burrow/core/internal/notifier"
@@ -41,7 +46,7 @@ func newCoordinators(app *protocol.ApplicationContext) []protocol.Coordinator {
 	haveNotifiers := viper.IsSet("notifier")
 
 	// Only include zookeeper if we have dependant coordinators
-	if haveNotifiers {
+	if haveNotifiers && (notifier.Election() == "zookeeper") {
 		coordinators = append(coordinators,
 			&zookeeper.Coordinator{
 				App: app,
@@ -77,6 +82,19 @@ func newCoordinators(app *protocol.ApplicationContext) []protocol.Coordinator {
 		},
 	)
 
+	// The gRPC server is only included if it is configured, as it has no default listener
+	if viper.IsSet("grpcserver") {
+		coordinators = append(coordinators,
+			&grpcserver.Coordinator{
+				App: app,
+				Log: app.Logger.With(
+					zap.String("type", "coordinator"),
+					zap.String("name", "grpcserver"),
+				),
+			},
+		)
+	}
+
 	if haveNotifiers {
 		coordinators = append(coordinators,
 			&notifier.Coordinator{
@@ -89,6 +107,19 @@ func newCoordinators(app *protocol.ApplicationContext) []protocol.Coordinator {
 		)
 	}
 
+	// Emitters only read from storage, so they are only included if any are configured
+	if viper.IsSet("emitter") {
+		coordinators = append(coordinators,
+			&emitter.Coordinator{
+				App: app,
+				Log: app.Logger.With(
+					zap.String("type", "coordinator"),
+					zap.String("name", "emitter"),
+				),
+			},
+		)
+	}
+
 	coordinators = append(coordinators,
 		&cluster.Coordinator{
 			App: app,
@@ -126,6 +157,69 @@ func configureCoordinators(app *protocol.ApplicationContext, coordinators []prot
 	app.ConfigurationValid = true
 }
 
+// ValidateConfig checks the configuration without running Burrow. It sets up the coordinators and calls their
+// Configure funcs, which configure the modules, as Start does, but does not start anything. Rather than stopping at
+// the first error, it returns every error that it finds, with the module (or coordinator) that it is for. An error in a
+// coordinator's own configuration stops that coordinator from checking the rest of its modules. As with Start, the
+// configuration must have been loaded by viper before calling this func. If the configuration is valid, the returned
+// slice is empty.
+func ValidateConfig() []string {
+	app := &protocol.ApplicationContext{
+		Logger:           zap.NewNop(),
+		EvaluatorChannel: make(chan *protocol.EvaluatorRequest),
+		StorageChannel:   make(chan *protocol.StorageRequest),
+		ClusterChannel:   make(chan *protocol.ClusterRequest),
+		ConsumerChannel:  make(chan *protocol.ConsumerRequest),
+	}
+	logLevel := zap.NewAtomicLevel()
+	app.LogLevel = &logLevel
+
+	coordinators := newCoordinators(app)
+	errs := helpers.CollectConfigErrors(func() {
+		for _, coordinator := range coordinators {
+			helpers.RecordConfigError(fmt.Sprintf("%T", coordinator), coordinator.Configure)
+		}
+	})
+	if viper.GetInt("general.shutdown-timeout") < 0 {
+		errs = append(errs, "general.shutdown-timeout must be zero or greater")
+	}
+	return errs
+}
+
+// stopCoordinators stops the coordinators in the reverse order. This assures that request senders are stopped before
+// request servers. If they have not all stopped within the timeout, the coordinator and modules that are still stopping
+// are logged and false is returned, leaving them running. A timeout of zero waits for them to stop, however long it is.
+func stopCoordinators(log *zap.Logger, coordinators []protocol.Coordinator, timeout time.Duration) bool {
+	var stopping atomic.Value
+	stopped := make(chan struct{})
+	go func() {
+		for i := len(coordinators) - 1; i >= 0; i-- {
+			stopping.Store(fmt.Sprintf("%T", coordinators[i]))
+			coordinators[i].Stop()
+		}
+		close(stopped)
+	}()
+
+	if timeout == 0 {
+		<-stopped
+		return true
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	select {
+	case <-stopped:
+		return true
+	case <-deadline.C:
+		log.Error("shutdown timed out",
+			zap.Duration("timeout", timeout),
+			zap.Any("coordinator", stopping.Load()),
+			zap.Strings("modules", helpers.StoppingModules()),
+		)
+		return false
+	}
+}
+
 // Start is called to start the Burrow application. This is exposed so that it is possible to use Burrow as a library
 // from within another application. Prior to calling this func, the configuration must have been loaded by viper from
 // some underlying source (e.g. a TOML configuration file, or explicitly set in code after reading from another source).
@@ -137,7 +231,9 @@ func configureCoordinators(app *protocol.ApplicationContext, coordinators []prot
 //
 // exitChannel is a signal channel that is provided by the calling application in order to signal Burrow to shut down.
 // Burrow does not currently check the signal type: if any message is received on the channel, or if the channel is
-// closed, Burrow will exit and Start will return 0.
+// closed, Burrow will exit and Start will return 0. If general.shutdown-timeout is set, and Burrow has not stopped within
+// that many seconds, Start gives up on the modules that are still stopping and returns 1, so that the calling
+// application can exit anyway.
 //
 // Start will return a 1 on any failure, including invalid configurations or a failure to start Burrow modules.
 func Start(app *protocol.ApplicationContext, exitChannel chan os.Signal) int {
@@ -165,14 +261,22 @@ func Start(app *protocol.ApplicationContext, exitChannel chan os.Signal) int {
 	//   * The Notifiers send evaluation requests to the evaluator coordinator to check group status
 	//   * The Evaluators send requests to the storage coordinator for group offset and lag information
 	//   * The HTTP server sends requests to both the evaluator and storage coordinators to fulfill API requests
+	// There are also channels for the HTTP server to send admin requests to the cluster and consumer modules
 	app.EvaluatorChannel = make(chan *protocol.EvaluatorRequest)
 	app.StorageChannel = make(chan *protocol.StorageRequest)
+	app.ClusterChannel = make(chan *protocol.ClusterRequest)
+	app.ConsumerChannel = make(chan *protocol.ConsumerRequest)
 
 	// Configure coordinators and exit if anything fails
 	configureCoordinators(app, coordinators)
 	if !app.ConfigurationValid {
 		return 1
 	}
+	shutdownTimeout := viper.GetInt("general.shutdown-timeout")
+	if shutdownTimeout < 0 {
+		log.Error("general.shutdown-timeout must be zero or greater")
+		return 1
+	}
 
 	// Start the coordinators in order
 	for i, coordinator := range coordinators {
@@ -190,9 +294,8 @@ func Start(app *protocol.ApplicationContext, exitChannel chan os.Signal) int {
 	<-exitChannel
 	log.Info("Shutdown triggered")
 
-	// Stop the coordinators in the reverse order. This assures that request senders are stopped before request servers
-	for i := len(coordinators) - 1; i >= 0; i-- {
-		coordinators[i].Stop()
+	if !stopCoordinators(log, coordinators, time.Duration(shutdownTimeout)*time.Second) {
+		return 1
 	}
 
 	// Exit cleanly
@@ -0,0 +1,104 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"math/rand"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// A broker whose offset requests keep failing after all of their retries is skipped by the offset refreshes for a
+// while, rather than being sent requests at every refresh. The backoff starts at offset-fetch-backoff-min and doubles
+// with each consecutive failure, up to offset-fetch-backoff-max. Each backoff is between half and all of that, so that
+// the brokers that failed together are not all tried again at once. A successful request resets the backoff.
+
+// brokerBackoff is the backoff state of a single broker
+type brokerBackoff struct {
+	failures int
+	until    time.Time
+}
+
+// brokerBackedOff returns true if the broker is being skipped until a backoff passes, which is checked when the offset
+// requests are generated. It is always false if the backoff is not enabled.
+func (module *KafkaCluster) brokerBackedOff(brokerID int32, now time.Time) bool {
+	_, backedOff := module.brokerBackoffUntil(brokerID, now)
+	return backedOff
+}
+
+// brokerBackoffUntil returns the time that the backoff for the broker passes, and true, if it is being skipped at now
+func (module *KafkaCluster) brokerBackoffUntil(brokerID int32, now time.Time) (time.Time, bool) {
+	if module.offsetBackoffMin <= 0 {
+		return time.Time{}, false
+	}
+
+	module.brokerBackoffLock.Lock()
+	defer module.brokerBackoffLock.Unlock()
+	backoff, ok := module.brokerBackoffs[brokerID]
+	if !ok || !now.Before(backoff.until) {
+		return time.Time{}, false
+	}
+	return backoff.until, true
+}
+
+// brokerFetchFailed records an offset request to the broker that failed after all of its retries, and starts the
+// next backoff for it. It is called from the goroutines that fetch offsets from each broker.
+func (module *KafkaCluster) brokerFetchFailed(brokerID int32, now time.Time) {
+	if module.offsetBackoffMin <= 0 {
+		return
+	}
+
+	module.brokerBackoffLock.Lock()
+	defer module.brokerBackoffLock.Unlock()
+	backoff, ok := module.brokerBackoffs[brokerID]
+	if !ok {
+		backoff = &brokerBackoff{}
+		module.brokerBackoffs[brokerID] = backoff
+	}
+	backoff.failures++
+	delay := module.backoffDelay(backoff.failures)
+	backoff.until = now.Add(delay)
+
+	module.Log.Info("backing off offset fetches from broker",
+		zap.Int32("broker", brokerID),
+		zap.Int("failures", backoff.failures),
+		zap.Duration("backoff", delay),
+	)
+}
+
+// brokerFetchSucceeded resets the backoff for the broker after an offset request to it succeeds
+func (module *KafkaCluster) brokerFetchSucceeded(brokerID int32) {
+	if module.offsetBackoffMin <= 0 {
+		return
+	}
+
+	module.brokerBackoffLock.Lock()
+	defer module.brokerBackoffLock.Unlock()
+	if backoff, ok := module.brokerBackoffs[brokerID]; ok {
+		module.Log.Info("offset fetches from broker recovered",
+			zap.Int32("broker", brokerID),
+			zap.Int("failures", backoff.failures),
+		)
+		delete(module.brokerBackoffs, brokerID)
+	}
+}
+
+// backoffDelay returns the backoff after the given number of consecutive failures, with jitter
+func (module *KafkaCluster) backoffDelay(failures int) time.Duration {
+	delay := module.offsetBackoffMin
+	for i := 1; (i < failures) && (delay < module.offsetBackoffMax); i++ {
+		delay *= 2
+	}
+	if delay > module.offsetBackoffMax {
+		delay = module.offsetBackoffMax
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,149 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func TestKafkaCluster_backoffDelay(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-fetch-backoff-min", 10)
+	viper.Set("cluster.test.offset-fetch-backoff-max", 60)
+	module.Configure("test", "cluster.test")
+
+	// The backoff doubles from the minimum until it reaches the maximum, and is jittered down by up to half
+	for failures, expected := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second, 4: 60 * time.Second, 50: 60 * time.Second} {
+		for i := 0; i < 20; i++ {
+			delay := module.backoffDelay(failures)
+			assert.GreaterOrEqualf(t, delay, expected/2, "Expected backoff of at least %v after %v failures, not %v", expected/2, failures, delay)
+			assert.LessOrEqualf(t, delay, expected, "Expected backoff of at most %v after %v failures, not %v", expected, failures, delay)
+		}
+	}
+}
+
+func TestKafkaCluster_brokerBackoff(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-fetch-backoff-min", 10)
+	viper.Set("cluster.test.offset-fetch-backoff-max", 60)
+	module.Configure("test", "cluster.test")
+
+	now := time.Now()
+	assert.False(t, module.brokerBackedOff(13, now), "Expected no backoff before any failures")
+
+	module.brokerFetchFailed(13, now)
+	assert.True(t, module.brokerBackedOff(13, now.Add(4*time.Second)), "Expected broker 13 to be backed off")
+	assert.False(t, module.brokerBackedOff(14, now), "Expected broker 14 to not be backed off")
+	assert.False(t, module.brokerBackedOff(13, now.Add(11*time.Second)), "Expected the backoff to have passed")
+
+	module.brokerFetchFailed(13, now)
+	assert.Equal(t, 2, module.brokerBackoffs[13].failures)
+
+	module.brokerFetchSucceeded(13)
+	assert.False(t, module.brokerBackedOff(13, now), "Expected the backoff to be reset after a success")
+	assert.Empty(t, module.brokerBackoffs)
+}
+
+func TestKafkaCluster_brokerBackoff_Defaults(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	assert.Equal(t, 2*time.Second, module.offsetBackoffMin)
+	assert.Equal(t, 60*time.Second, module.offsetBackoffMax)
+}
+
+func TestKafkaCluster_brokerBackoff_Disabled(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-fetch-backoff-min", 0)
+	module.Configure("test", "cluster.test")
+
+	module.brokerFetchFailed(13, time.Now())
+	assert.False(t, module.brokerBackedOff(13, time.Now()), "Expected no backoff when it is disabled")
+	assert.Empty(t, module.brokerBackoffs)
+}
+
+func TestKafkaCluster_generateOffsetRequests_BrokerBackoff(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0, 1, 2}}
+	module.topicLeaders = map[string][]int32{"testtopic": {12, 13, 12}}
+	module.brokerFetchFailed(12, time.Now())
+
+	broker := &helpers.MockSaramaBroker{}
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(13)).Return(broker, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	// Only the healthy broker's partitions are requested, and the backed off broker is not looked up
+	requests, brokers := module.generateOffsetRequests(client, sarama.OffsetNewest)
+	assert.Len(t, requests, 1)
+	assert.Contains(t, brokers, int32(13))
+	assert.NotContains(t, brokers, int32(12))
+	client.AssertNotCalled(t, "Broker", int32(12))
+	assert.False(t, module.fetchMetadata.Load(), "Expected the backed off broker to not force a metadata refresh")
+}
+
+func TestKafkaCluster_getOffsets_BrokerBackoff(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-fetch-backoff-min", 60)
+	viper.Set("cluster.test.metadata-refresh-errors", 5)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0, 1}}
+	module.topicLeaders = map[string][]int32{"testtopic": {12, 13}}
+	module.fetchMetadata.Store(false)
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+
+	// Broker 12 fails every request, and broker 13 is healthy
+	var offsetResponse *sarama.OffsetResponse
+	failingBroker := &helpers.MockSaramaBroker{}
+	failingBroker.On("GetAvailableOffsets", mock.Anything).Return(offsetResponse, errors.New("broker failed"))
+	failingBroker.On("Close").Return(nil)
+	failingBroker.On("Open", mock.Anything).Return(nil)
+	healthyResponse := &sarama.OffsetResponse{Version: 1}
+	healthyResponse.AddTopicPartition("testtopic", 1, 8374)
+	healthyBroker := &helpers.MockSaramaBroker{}
+	healthyBroker.On("GetAvailableOffsets", mock.Anything).Return(healthyResponse, nil)
+
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(12)).Return(failingBroker, nil)
+	client.On("Broker", int32(13)).Return(healthyBroker, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	fixtureClientLeaders(module, client)
+	module.getOffsets(client)
+	failedCalls := len(failingBroker.Calls)
+	assert.True(t, module.brokerBackedOff(12, time.Now()), "Expected broker 12 to be backed off")
+
+	// The failed broker is skipped by the next refresh, and the healthy broker is still fetched
+	module.getOffsets(client)
+	assert.Len(t, failingBroker.Calls, failedCalls, "Expected no requests to the backed off broker")
+	healthyBroker.AssertNumberOfCalls(t, "GetAvailableOffsets", 2)
+	assert.Len(t, module.App.StorageChannel, 2)
+}
+
+func TestKafkaCluster_Configure_BadOffsetFetchBackoff(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-fetch-backoff-min", -1)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+
+	module = fixtureModule()
+	viper.Set("cluster.test.offset-fetch-backoff-min", 60)
+	viper.Set("cluster.test.offset-fetch-backoff-max", 30)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// updateCompactedTopics fetches the cleanup.policy of every topic from the broker that the metadata was fetched from,
+// and tells storage about the topics that are now compacted, or no longer are. Topics whose config cannot be described
+// (such as when the client is not allowed to) keep what was known about them, which is not compacted for a new topic.
+func (module *KafkaCluster) updateCompactedTopics(client helpers.SaramaClient, broker helpers.SaramaBroker, topicPartitions map[string][]int32) {
+	// Deleted topics are removed from storage entirely, so they are just forgotten here
+	for topic := range module.compactedTopics {
+		if _, ok := topicPartitions[topic]; !ok {
+			delete(module.compactedTopics, topic)
+		}
+	}
+
+	version := client.Config().Version
+	if !version.IsAtLeast(sarama.V0_11_0_0) {
+		module.Log.Warn("describing topic configs requires Kafka 0.11 or later")
+		return
+	}
+
+	topics := make([]string, 0, len(topicPartitions))
+	for topic := range topicPartitions {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	request := &sarama.DescribeConfigsRequest{
+		Resources: make([]*sarama.ConfigResource, 0, len(topics)),
+	}
+	if version.IsAtLeast(sarama.V2_0_0_0) {
+		request.Version = 2
+	} else if version.IsAtLeast(sarama.V1_1_0_0) {
+		request.Version = 1
+	}
+	for _, topic := range topics {
+		request.Resources = append(request.Resources, &sarama.ConfigResource{
+			Type:        sarama.TopicResource,
+			Name:        topic,
+			ConfigNames: []string{"cleanup.policy"},
+		})
+	}
+
+	response, err := broker.DescribeConfigs(request)
+	if err != nil {
+		module.Log.Warn("failed to describe topic configs", zap.Error(err))
+		return
+	}
+
+	for _, resource := range response.Resources {
+		if resource.ErrorCode != int16(sarama.ErrNoError) {
+			module.Log.Debug("failed to describe topic config",
+				zap.String("topic", resource.Name),
+				zap.String("sarama_error", sarama.KError(resource.ErrorCode).Error()),
+			)
+			continue
+		}
+
+		compacted := false
+		for _, config := range resource.Configs {
+			if config.Name == "cleanup.policy" {
+				compacted = strings.Contains(config.Value, "compact")
+			}
+		}
+		if compacted == module.compactedTopics[resource.Name] {
+			continue
+		}
+
+		module.Log.Info("topic compaction changed", zap.String("topic", resource.Name), zap.Bool("compacted", compacted))
+		if compacted {
+			module.compactedTopics[resource.Name] = true
+		} else {
+			delete(module.compactedTopics, resource.Name)
+		}
+		module.App.StorageChannel <- &protocol.StorageRequest{
+			RequestType: protocol.StorageSetTopicCompacted,
+			Cluster:     module.name,
+			Topic:       resource.Name,
+			Compacted:   compacted,
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func fixtureCompactedModule() (*KafkaCluster, *helpers.MockSaramaClient) {
+	module := fixtureModule()
+	viper.Set("cluster.test.detect-compacted-topics", true)
+	module.Configure("test", "cluster.test")
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+	client := &helpers.MockSaramaClient{}
+	client.On("Config").Return(config)
+	return module, client
+}
+
+func topicConfig(topic, policy string) *sarama.ResourceResponse {
+	return &sarama.ResourceResponse{
+		Type:    sarama.TopicResource,
+		Name:    topic,
+		Configs: []*sarama.ConfigEntry{{Name: "cleanup.policy", Value: policy}},
+	}
+}
+
+func TestKafkaCluster_updateCompactedTopics(t *testing.T) {
+	module, client := fixtureCompactedModule()
+	module.compactedTopics["deletedtopic"] = true
+	module.compactedTopics["oldtopic"] = true
+
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("DescribeConfigs", mock.MatchedBy(func(request *sarama.DescribeConfigsRequest) bool {
+		return (request.Version == 2) && (len(request.Resources) == 4) && (request.Resources[0].ConfigNames[0] == "cleanup.policy")
+	})).Return(&sarama.DescribeConfigsResponse{
+		Resources: []*sarama.ResourceResponse{
+			topicConfig("compacttopic", "compact,delete"),
+			topicConfig("deletetopic", "delete"),
+			topicConfig("oldtopic", "delete"),
+			{Type: sarama.TopicResource, Name: "deniedtopic", ErrorCode: int16(sarama.ErrTopicAuthorizationFailed)},
+		},
+	}, nil)
+
+	topicPartitions := map[string][]int32{
+		"compacttopic": {0},
+		"deletetopic":  {0},
+		"oldtopic":     {0},
+		"deniedtopic":  {0},
+	}
+	go module.updateCompactedTopics(client, broker, topicPartitions)
+
+	// Only the topics whose compaction changed are sent to storage
+	request := <-module.App.StorageChannel
+	assert.Equal(t, protocol.StorageSetTopicCompacted, request.RequestType)
+	assert.Equal(t, "compacttopic", request.Topic)
+	assert.True(t, request.Compacted, "Expected compacttopic to be compacted")
+	request = <-module.App.StorageChannel
+	assert.Equal(t, "oldtopic", request.Topic)
+	assert.False(t, request.Compacted, "Expected oldtopic to no longer be compacted")
+
+	broker.AssertExpectations(t)
+	assert.Equal(t, map[string]bool{"compacttopic": true}, module.compactedTopics)
+}
+
+func TestKafkaCluster_updateCompactedTopics_Failed(t *testing.T) {
+	module, client := fixtureCompactedModule()
+	module.compactedTopics["compacttopic"] = true
+
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("DescribeConfigs", mock.Anything).Return((*sarama.DescribeConfigsResponse)(nil), errors.New("failed"))
+
+	// What is already known about the topics is kept
+	module.updateCompactedTopics(client, broker, map[string][]int32{"compacttopic": {0}})
+	broker.AssertExpectations(t)
+	assert.Equal(t, map[string]bool{"compacttopic": true}, module.compactedTopics)
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_NoCompactedTopics(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	client, broker := fixtureMetadataClient(metadata)
+
+	module.fetchMetadata.Store(true)
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+	broker.AssertNotCalled(t, "DescribeConfigs", mock.Anything)
+}
@@ -26,6 +26,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/internal/httpserver"
 	"github.com/linkedin/Burrow/core/protocol"
 )
 
@@ -34,6 +35,13 @@ import (
 // offset) for each partition. This information is sent to the storage subsystem, where it can be retrieved by the
 // evaluator and HTTP server.
 
+// Module (cluster) is responsible for fetching topic and broker offset information from a Kafka cluster. It can also
+// take requests from the Coordinator, which are sent on the channel that GetCommunicationChannel returns.
+type Module interface {
+	protocol.Module
+	GetCommunicationChannel() chan *protocol.ClusterRequest
+}
+
 // Coordinator manages all cluster modules, making sure they are configured, started, and stopped at the appropriate
 // time.
 type Coordinator struct {
@@ -44,7 +52,11 @@ type Coordinator struct {
 	// fields that are appropriate to identify this coordinator
 	Log *zap.Logger
 
-	modules map[string]protocol.Module
+	quitChannel chan struct{}
+	modules     map[string]protocol.Module
+
+	// Skip cluster modules that fail to configure or start, rather than stopping Burrow
+	isolateFailures bool
 }
 
 // getModuleForClass returns the correct module based on the passed className. As part of the Configure steps, if there
@@ -69,41 +81,94 @@ func getModuleForClass(app *protocol.ApplicationContext, moduleName, className s
 // Configure is called to create each of the configured cluster modules and call their Configure funcs to validate
 // their individual configurations and set them up. If there are any problems, it is expected that these funcs will
 // panic with a descriptive error message, as configuration failures are not recoverable errors.
+//
+// If general.isolate-cluster-failures is set, a cluster module that fails to configure or start is skipped instead,
+// so that one bad cluster does not stop Burrow from monitoring the rest. The cluster's error is shown in the cluster
+// list and detail by the HTTP server.
+//
+// The offset scheduler in the application context is set up here, before the modules are configured, unless it has
+// already been set. If general.stagger-offset-fetches is set, it spreads the regular offset fetches of the clusters
+// across their refresh intervals, rather than having all the clusters fetch at once.
 func (bc *Coordinator) Configure() {
 	bc.Log.Info("configuring")
 
+	bc.quitChannel = make(chan struct{})
 	bc.modules = make(map[string]protocol.Module)
+	bc.isolateFailures = viper.GetBool("general.isolate-cluster-failures")
+	if bc.App.OffsetScheduler == nil {
+		bc.App.OffsetScheduler = helpers.NewOffsetFetchScheduler(viper.GetBool("general.stagger-offset-fetches"))
+	}
 
 	// Create all configured cluster modules, add to list of clusters
 	modules := viper.GetStringMap("cluster")
 	for name := range modules {
 		configRoot := "cluster." + name
-		module := getModuleForClass(bc.App, name, viper.GetString(configRoot+".class-name"))
-		module.Configure(name, configRoot)
-		bc.modules[name] = module
+		err := helpers.ConfigureIsolated(configRoot, bc.isolateFailures, func() {
+			module := getModuleForClass(bc.App, name, viper.GetString(configRoot+".class-name"))
+			module.Configure(name, configRoot)
+			bc.modules[name] = module
+		})
+		if err != nil {
+			bc.clusterFailed(name, "configure", err)
+		}
 	}
 }
 
-// Start calls each of the configured cluster modules' underlying Start funcs. As the coordinator itself has no ongoing
-// work to do, it does not start any other goroutines. If any module Start returns an error, this func stops immediately
-// and returns that error to the caller. No further modules will be loaded after that.
+// Start calls each of the configured cluster modules' underlying Start funcs. If any module Start returns an error,
+// this func stops immediately and returns that error to the caller. No further modules will be loaded after that.
+//
+// We also start a request forwarder goroutine, which listens to the ClusterChannel in the application context and
+// forwards each request to the module for the cluster that it names.
 func (bc *Coordinator) Start() error {
 	bc.Log.Info("starting")
 
 	// Start Cluster modules
-	err := helpers.StartCoordinatorModules(bc.modules)
-	if err != nil {
+	if bc.isolateFailures {
+		helpers.StartIsolatedModules(bc.modules, func(name string, err error) {
+			bc.clusterFailed(name, "start", err)
+		})
+	} else if err := helpers.StartCoordinatorModules(bc.modules); err != nil {
 		return errors.New("Error starting cluster module: " + err.Error())
 	}
+
+	go bc.forwardRequests()
 	return nil
 }
 
+// clusterFailed logs and records a cluster module that failed, and is being skipped
+func (bc *Coordinator) clusterFailed(name, stage string, err error) {
+	bc.Log.Error("skipping failed cluster",
+		zap.String("cluster", name),
+		zap.String("stage", stage),
+		zap.Error(err),
+	)
+	httpserver.SetClusterFailed(name, "cluster module failed to "+stage+": "+err.Error())
+}
+
+func (bc *Coordinator) forwardRequests() {
+	for {
+		select {
+		case request := <-bc.App.ClusterChannel:
+			if module, ok := bc.modules[request.Cluster].(Module); ok {
+				module.GetCommunicationChannel() <- request
+			} else {
+				// There is no module to respond, so tell the sender the cluster was not found
+				close(request.Reply)
+			}
+		case <-bc.quitChannel:
+			return
+		}
+	}
+}
+
 // Stop calls each of the configured cluster modules' underlying Stop funcs. It is expected that the module Stop will
 // not return until the module has been completely stopped. While an error can be returned, this func always returns no
 // error, as a failure during stopping is not a critical failure
 func (bc *Coordinator) Stop() error {
 	bc.Log.Info("stopping")
 
+	close(bc.quitChannel)
+
 	// The individual cluster modules can choose whether or not to implement a wait in the Stop routine
 	helpers.StopCoordinatorModules(bc.modules)
 	return nil
@@ -10,6 +10,7 @@
 package cluster
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/linkedin/Burrow/core/internal/helpers"
@@ -27,6 +28,7 @@ func fixtureCoordinator() *Coordinator {
 	coordinator.App = &protocol.ApplicationContext{
 		Logger:         zap.NewNop(),
 		StorageChannel: make(chan *protocol.StorageRequest),
+		ClusterChannel: make(chan *protocol.ClusterRequest),
 	}
 
 	viper.Reset()
@@ -55,6 +57,21 @@ func TestCoordinator_Configure_TwoModules(t *testing.T) {
 	coordinator.Configure()
 }
 
+func TestCoordinator_Configure_OffsetScheduler(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("general.stagger-offset-fetches", true)
+	viper.Set("cluster.anothertest.class-name", "kafka")
+	viper.Set("cluster.anothertest.servers", []string{"broker1.example.com:1234"})
+	coordinator.Configure()
+
+	// Both clusters register with the scheduler, and are staggered across the default 10 second refresh
+	assert.NotNil(t, coordinator.App.OffsetScheduler, "Expected offset scheduler to be set up")
+	assert.Equal(t, []*protocol.ScheduledOffsetFetch{
+		{Cluster: "anothertest", Interval: 10, Offset: 0},
+		{Cluster: "test", Interval: 10, Offset: 5},
+	}, coordinator.App.OffsetScheduler.Schedule())
+}
+
 func TestCoordinator_StartStop(t *testing.T) {
 	coordinator := fixtureCoordinator()
 	coordinator.Configure()
@@ -71,3 +88,67 @@ func TestCoordinator_StartStop(t *testing.T) {
 	coordinator.Stop()
 	mockModule.AssertCalled(t, "Stop")
 }
+
+func TestCoordinator_Configure_IsolateFailures(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("cluster.badcluster.class-name", "nonexistent")
+	assert.Panics(t, coordinator.Configure, "Expected panic for a bad cluster without isolate-cluster-failures")
+
+	coordinator = fixtureCoordinator()
+	viper.Set("cluster.badcluster.class-name", "nonexistent")
+	viper.Set("general.isolate-cluster-failures", true)
+	assert.NotPanics(t, coordinator.Configure, "Expected the bad cluster to be skipped")
+	assert.Lenf(t, coordinator.modules, 1, "Expected 1 module configured, not %v", len(coordinator.modules))
+	assert.Contains(t, coordinator.modules, "test")
+}
+
+func TestCoordinator_Start_IsolateFailures(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("general.isolate-cluster-failures", true)
+	coordinator.Configure()
+
+	// Swap out the coordinator modules with mocks for testing, one of which fails to start
+	mockModule := &helpers.MockModule{}
+	mockModule.On("Start").Return(nil)
+	mockModule.On("Stop").Return(nil)
+	badModule := &helpers.MockModule{}
+	badModule.On("Start").Return(errors.New("cannot connect"))
+	coordinator.modules["test"] = mockModule
+	coordinator.modules["badcluster"] = badModule
+
+	assert.NoError(t, coordinator.Start(), "Expected no error with a failed cluster skipped")
+	mockModule.AssertCalled(t, "Start")
+	assert.NotContains(t, coordinator.modules, "badcluster", "Expected the failed cluster to be removed")
+
+	coordinator.Stop()
+	mockModule.AssertCalled(t, "Stop")
+	badModule.AssertNotCalled(t, "Stop")
+}
+
+func TestCoordinator_forwardRequests(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.Configure()
+	go coordinator.forwardRequests()
+	defer close(coordinator.quitChannel)
+
+	// Requests for a known cluster go to its module
+	request := &protocol.ClusterRequest{
+		RequestType: protocol.ClusterRefreshBrokerOffsets,
+		Cluster:     "test",
+		Broker:      1,
+		Reply:       make(chan interface{}),
+	}
+	coordinator.App.ClusterChannel <- request
+	forwarded := <-coordinator.modules["test"].(Module).GetCommunicationChannel()
+	assert.Equal(t, request, forwarded, "Expected request to be forwarded to the module")
+
+	// Requests for an unknown cluster are closed without a reply
+	request = &protocol.ClusterRequest{
+		RequestType: protocol.ClusterRefreshBrokerOffsets,
+		Cluster:     "nocluster",
+		Broker:      1,
+		Reply:       make(chan interface{}),
+	}
+	coordinator.App.ClusterChannel <- request
+	assert.Nil(t, <-request.Reply, "Expected no reply for an unknown cluster")
+}
@@ -11,8 +11,12 @@ package cluster
 
 import (
 	"fmt"
-	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/IBM/sarama"
@@ -36,42 +40,125 @@ type KafkaCluster struct {
 	// fields that are appropriate to identify this coordinator
 	Log *zap.Logger
 
-	name                string
-	saramaConfig        *sarama.Config
-	servers             []string
-	offsetRefresh       int
-	topicRefresh        int
-	groupsReaperRefresh int
+	name                 string
+	saramaConfig         *sarama.Config
+	servers              []string
+	offsetRefresh        int
+	topicRefresh         int
+	discoveryRefresh     int
+	groupsReaperRefresh  int
+	groupsReaperMinRatio float64
+	trimGroupTopics      bool
+	reportedGroups       map[string]bool
+	offsetRetryMax       int
+	offsetRetryBackoff   time.Duration
+	refreshErrors        int
+	outOfRangeRetries    int
+	outOfRangeBackoff    time.Duration
+	maxOffsetFetches     int
+	fetchOldest          bool
+	offsetLookback       int
+	lookbackTime         int64
+	lookbackWarned       bool
+	timeLagSampling      bool
+	timeLagSampleAges    []int
+	timeLagInterval      int
+	leadershipFile       string
+	leadershipInterval   int
+	versionFile          string
+	metadataRack         string
+	redetectVersion      bool
+	detectCompacted      bool
+	priorityTopics       []*regexp.Regexp
+	topicAllowlist       []*regexp.Regexp
+	topicDenylist        []*regexp.Regexp
+	excludeInternal      bool
+	internalTopics       []*regexp.Regexp
+
+	// The bootstrap server sets, in order of preference, and the one the client is connected to. See serversets.go
+	serverSets       []serverSet
+	activeServerSet  int
+	failoverErrors   int
+	failbackInterval int
+	metadataFailures int
+
+	// The result of the last metadata refresh, one of the httpserver.ClusterMetadata states
+	metadataState string
+
+	// The func used to connect to the cluster (configurable to enable testing)
+	newSaramaClient func([]string, *sarama.Config) (sarama.Client, error)
 
 	offsetTicker       *time.Ticker
+	offsetStartTimer   *time.Timer
 	metadataTicker     *time.Ticker
+	discoveryTicker    *time.Ticker
 	groupsReaperTicker *time.Ticker
+	leadershipTicker   *time.Ticker
+	timeLagTicker      *time.Ticker
+	failbackTicker     *time.Ticker
 	quitChannel        chan struct{}
+	requestChannel     chan *protocol.ClusterRequest
 	running            sync.WaitGroup
 
-	fetchMetadata   bool
+	// fetchMetadata is set to refresh the metadata on the next offset fetch. It is atomic, so that it is safe to set
+	// from any goroutine, such as the ones that fetch offsets from each broker
+	fetchMetadata   atomic.Bool
 	topicPartitions map[string][]int32
+
+	// Topics can have their own offset refresh intervals, in which case the offset ticker fires at the shortest of the
+	// intervals, and each topic is only fetched once its own interval has passed. See topicrefresh.go
+	offsetInterval        time.Duration
+	topicRefreshOverrides []topicRefreshOverride
+	topicLastFetched      map[string]time.Time
+
+	topicLeaders    map[string][]int32
+	compactedTopics map[string]bool
+
+	// The topics in the metadata that the topic filter leaves out, which storage is told about so that commits to them
+	// are not counted as commits to topics that do not exist
+	filteredTopics map[string]bool
+
+	// The topics whose partitions changed during an offset fetch, which have their metadata fetched on their own if
+	// partition-change-refresh is "topic", up to partitionChangeMaxTopics at a time. See partitionchanges.go
+	partitionChangeRefresh   string
+	partitionChangeMaxTopics int
+	changedTopics            map[string]bool
+	changedTopicsLock        sync.Mutex
+
+	// Partitions that have had no leader since the time recorded, which are deleted from storage once they have been
+	// leaderless for leaderlessPartitionTimeout. See partitionreaper.go
+	leaderlessPartitionTimeout time.Duration
+	leaderlessSince            map[string]map[int32]time.Time
+
+	// Brokers whose offset requests keep failing are skipped for a while. See brokerbackoff.go
+	offsetBackoffMin  time.Duration
+	offsetBackoffMax  time.Duration
+	brokerBackoffs    map[int32]*brokerBackoff
+	brokerBackoffLock sync.Mutex
+
+	// The API versions last fetched from the brokers, which are only fetched when requested. See fetchAPIVersions
+	apiVersions *protocol.ClusterAPIVersions
 }
 
 // Configure validates the configuration for the cluster. At minimum, there must be a list of servers provided for the
 // Kafka cluster, of the form host:port. Default values will be set for the intervals to use for refreshing offsets
-// (10 seconds) and topics (60 seconds). A missing, or bad, list of servers will cause this func to panic.
+// (10 seconds) and topics (60 seconds). A faster topic-discovery-refresh, which only checks for new or deleted topics,
+// is disabled by default. A missing, or bad, list of servers will cause this func to panic. In place of servers, there
+// can be a list of server sets in order of preference, which the cluster fails over between (see serversets.go).
 func (module *KafkaCluster) Configure(name, configRoot string) {
 	module.Log.Info("configuring")
 
 	module.name = name
 	module.quitChannel = make(chan struct{})
+	module.requestChannel = make(chan *protocol.ClusterRequest)
 	module.running = sync.WaitGroup{}
 
-	profile := viper.GetString(configRoot + ".client-profile")
+	profile := helpers.GetClientProfileName(configRoot)
 	module.saramaConfig = helpers.GetSaramaConfigFromClientProfile(profile)
+	module.saramaConfig.ClientID = helpers.GetClusterClientID(name, profile)
 
-	module.servers = viper.GetStringSlice(configRoot + ".servers")
-	if len(module.servers) == 0 {
-		panic("No Kafka brokers specified for cluster " + module.name)
-	} else if !helpers.ValidateHostList(module.servers) {
-		panic("Cluster '" + name + "' has one or more improperly formatted servers (must be host:port)")
-	}
+	// The cluster can have more than one set of servers, such as one for each data center, that it fails over between
+	module.configureServerSets(name, configRoot)
 
 	// Set defaults for configs if needed
 	viper.SetDefault(configRoot+".offset-refresh", 10)
@@ -80,39 +167,253 @@ func (module *KafkaCluster) Configure(name, configRoot string) {
 	module.offsetRefresh = viper.GetInt(configRoot + ".offset-refresh")
 	module.topicRefresh = viper.GetInt(configRoot + ".topic-refresh")
 	module.groupsReaperRefresh = viper.GetInt(configRoot + ".groups-reaper-refresh")
+
+	// Topics matching a topic-refresh-overrides entry have their offsets fetched at that interval instead
+	module.topicRefreshOverrides = parseTopicRefreshOverrides(name, viper.GetStringSlice(configRoot+".topic-refresh-overrides"))
+	module.topicLastFetched = make(map[string]time.Time)
+	module.offsetInterval = minRefreshInterval(time.Duration(module.offsetRefresh)*time.Second, module.topicRefreshOverrides)
+
+	// The groups reaper does not delete any groups when Kafka returns no groups, or fewer than this ratio of the groups
+	// in storage, as that is more likely to be a transient problem with the group coordinators than real deletions
+	module.groupsReaperMinRatio = viper.GetFloat64(configRoot + ".groups-reaper-min-ratio")
+	if (module.groupsReaperMinRatio < 0) || (module.groupsReaperMinRatio > 1) {
+		panic("Cluster '" + name + "' groups-reaper-min-ratio must be between 0 and 1")
+	}
+
+	// The groups reaper can also remove the offsets for topics that no longer exist from the groups that are kept
+	module.trimGroupTopics = viper.GetBool(configRoot + ".groups-reaper-trim-topics")
+
+	// New topics can be found faster than the topic-refresh by only checking the list of topic names more often. The
+	// full metadata refresh is only done when the list has changed
+	module.discoveryRefresh = viper.GetInt(configRoot + ".topic-discovery-refresh")
+	if module.discoveryRefresh < 0 {
+		panic("Cluster '" + name + "' topic-discovery-refresh must be zero or greater")
+	}
+
+	// The end offsets for a partition are stale if they have not been fetched in this many offset refreshes, such as
+	// when the leader for the partition keeps failing. This is only used for reporting stale partitions
+	viper.SetDefault(configRoot+".stale-offset-intervals", 3)
+	if viper.GetInt(configRoot+".stale-offset-intervals") < 1 {
+		panic("Cluster '" + name + "' stale-offset-intervals must be at least 1")
+	}
+
+	// A full metadata refresh is expensive on a large cluster, so it can take more than one partition error in a
+	// single offset fetch to force one
+	viper.SetDefault(configRoot+".metadata-refresh-errors", 1)
+	module.refreshErrors = viper.GetInt(configRoot + ".metadata-refresh-errors")
+	if module.refreshErrors < 1 {
+		panic("Cluster '" + name + "' metadata-refresh-errors must be at least 1")
+	}
+
+	// Partitions that get an offset out of range error, which is common while a leader election is in progress, are
+	// retried on their own before they count towards metadata-refresh-errors
+	viper.SetDefault(configRoot+".offset-out-of-range-retries", 1)
+	viper.SetDefault(configRoot+".offset-out-of-range-backoff", 250)
+	module.outOfRangeRetries = viper.GetInt(configRoot + ".offset-out-of-range-retries")
+	if module.outOfRangeRetries < 0 {
+		panic("Cluster '" + name + "' offset-out-of-range-retries must be zero or greater")
+	}
+	outOfRangeBackoff := viper.GetInt(configRoot + ".offset-out-of-range-backoff")
+	if outOfRangeBackoff < 0 {
+		panic("Cluster '" + name + "' offset-out-of-range-backoff must be zero or greater")
+	}
+	module.outOfRangeBackoff = time.Duration(outOfRangeBackoff) * time.Millisecond
+
+	// The offset requests are sent to this many brokers at a time, so that a refresh on a cluster with hundreds of
+	// brokers does not open all of the requests at once
+	viper.SetDefault(configRoot+".max-concurrent-offset-fetches", 20)
+	module.maxOffsetFetches = viper.GetInt(configRoot + ".max-concurrent-offset-fetches")
+	if module.maxOffsetFetches < 1 {
+		panic("Cluster '" + name + "' max-concurrent-offset-fetches must be at least 1")
+	}
+
+	// A broker that fails its offset requests is skipped by the refreshes for an increasing time, from
+	// offset-fetch-backoff-min to offset-fetch-backoff-max seconds. Setting offset-fetch-backoff-min to 0 disables this
+	viper.SetDefault(configRoot+".offset-fetch-backoff-min", 2)
+	viper.SetDefault(configRoot+".offset-fetch-backoff-max", 60)
+	backoffMin := viper.GetInt(configRoot + ".offset-fetch-backoff-min")
+	backoffMax := viper.GetInt(configRoot + ".offset-fetch-backoff-max")
+	if backoffMin < 0 {
+		panic("Cluster '" + name + "' offset-fetch-backoff-min must be zero or greater")
+	}
+	if (backoffMin > 0) && (backoffMax < backoffMin) {
+		panic("Cluster '" + name + "' offset-fetch-backoff-max must be at least offset-fetch-backoff-min")
+	}
+	module.offsetBackoffMin = time.Duration(backoffMin) * time.Second
+	module.offsetBackoffMax = time.Duration(backoffMax) * time.Second
+	module.brokerBackoffs = make(map[int32]*brokerBackoff)
+
+	// A topic whose partitions change during an offset fetch has its metadata fetched on its own, rather than forcing
+	// a full metadata refresh. See partitionchanges.go
+	viper.SetDefault(configRoot+".partition-change-refresh", partitionChangeRefreshTopic)
+	module.partitionChangeRefresh = viper.GetString(configRoot + ".partition-change-refresh")
+	if (module.partitionChangeRefresh != partitionChangeRefreshFull) && (module.partitionChangeRefresh != partitionChangeRefreshTopic) {
+		panic("Cluster '" + name + "' partition-change-refresh must be \"full\" or \"topic\"")
+	}
+	viper.SetDefault(configRoot+".partition-change-max-topics", 20)
+	module.partitionChangeMaxTopics = viper.GetInt(configRoot + ".partition-change-max-topics")
+	if module.partitionChangeMaxTopics < 0 {
+		panic("Cluster '" + name + "' partition-change-max-topics must be zero or greater")
+	}
+
+	// Partitions that have had no leader for this long are deleted from storage, rather than keeping their last offsets
+	// forever. The default of zero never deletes them. See partitionreaper.go
+	leaderlessTimeout := viper.GetInt(configRoot + ".leaderless-partition-timeout")
+	if leaderlessTimeout < 0 {
+		panic("Cluster '" + name + "' leaderless-partition-timeout must be zero or greater")
+	}
+	module.leaderlessPartitionTimeout = time.Duration(leaderlessTimeout) * time.Second
+	module.leaderlessSince = make(map[string]map[int32]time.Time)
+
+	// The oldest offsets double the number of offset requests, so they are only fetched for evaluators that use the
+	// size of the partitions, or to show in the topic detail. fetch-earliest-offsets is accepted for the same config
+	module.fetchOldest = viper.GetBool(configRoot+".fetch-oldest-offsets") || viper.GetBool(configRoot+".fetch-earliest-offsets")
+
+	// The offset that each partition was at offset-lookback seconds ago is fetched with a timestamp ListOffsets request,
+	// so the lag of a group can be given in time as well as in messages. This needs Kafka 0.10.1 or later
+	module.offsetLookback = viper.GetInt(configRoot + ".offset-lookback")
+	if module.offsetLookback < 0 {
+		panic("Cluster '" + name + "' offset-lookback must be zero or greater")
+	}
+
+	// The offsets that each partition was at, at several times in the past, can be sampled periodically so that the
+	// time lag of a group can be found from when the message it is at was produced. See timelag.go
+	viper.SetDefault(configRoot+".time-lag-sample-ages", []int{60, 300, 900, 3600})
+	viper.SetDefault(configRoot+".time-lag-sample-interval", 300)
+	module.timeLagSampling = viper.GetBool(configRoot + ".time-lag-sampling")
+	module.timeLagSampleAges = viper.GetIntSlice(configRoot + ".time-lag-sample-ages")
+	module.timeLagInterval = viper.GetInt(configRoot + ".time-lag-sample-interval")
+	if module.timeLagSampling {
+		if len(module.timeLagSampleAges) == 0 {
+			panic("Cluster '" + name + "' time-lag-sample-ages must not be empty")
+		}
+		for _, age := range module.timeLagSampleAges {
+			if age < 1 {
+				panic("Cluster '" + name + "' time-lag-sample-ages must all be at least 1")
+			}
+		}
+		if module.timeLagInterval < 1 {
+			panic("Cluster '" + name + "' time-lag-sample-interval must be at least 1")
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(module.timeLagSampleAges)))
+	}
+
+	// The offsets for topics matching any of these patterns are fetched from each broker before the rest of its
+	// partitions, so they are still fresh when an offset refresh runs long
+	for _, pattern := range viper.GetStringSlice(configRoot + ".priority-topics") {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			panic("Cluster '" + name + "' failed to compile priority-topics '" + pattern + "': " + err.Error())
+		}
+		module.priorityTopics = append(module.priorityTopics, re)
+	}
+
+	// Only the topics that match one of the topic-filter.allowlist patterns (if any are set), and none of the
+	// topic-filter.denylist patterns, are tracked. The whitelist and blacklist names, as well as topic-filter-allow and
+	// topic-filter-deny, are accepted for the same lists
+	module.topicAllowlist = compileTopicFilter(name, "allowlist", topicFilterPatterns(configRoot, "topic-filter.allowlist", "topic-filter.whitelist", "topic-filter-allow"))
+	module.topicDenylist = compileTopicFilter(name, "denylist", topicFilterPatterns(configRoot, "topic-filter.denylist", "topic-filter.blacklist", "topic-filter-deny"))
+
+	// Kafka's internal topics (those starting with "__") are left out unless they match the topic allowlist. Topics
+	// matching any of the internal-topic-patterns, such as the changelog and repartition topics of Kafka Streams, are
+	// counted as internal as well
+	viper.SetDefault(configRoot+".exclude-internal-topics", true)
+	module.excludeInternal = viper.GetBool(configRoot + ".exclude-internal-topics")
+	for _, pattern := range viper.GetStringSlice(configRoot + ".internal-topic-patterns") {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			panic("Cluster '" + name + "' failed to compile internal-topic-patterns '" + pattern + "': " + err.Error())
+		}
+		module.internalTopics = append(module.internalTopics, re)
+	}
+
+	// The topic configs are only fetched to find compacted topics if asked for, as the client needs permission to
+	// describe the configs of every topic
+	module.detectCompacted = viper.GetBool(configRoot + ".detect-compacted-topics")
+	module.compactedTopics = make(map[string]bool)
+
+	// The partition leaders can be written to a file periodically, as a record for audits
+	viper.SetDefault(configRoot+".leadership-file-interval", 300)
+	module.leadershipFile = viper.GetString(configRoot + ".leadership-file")
+	module.leadershipInterval = viper.GetInt(configRoot + ".leadership-file-interval")
+	if (module.leadershipFile != "") && (module.leadershipInterval < 1) {
+		panic("Cluster '" + name + "' leadership-file-interval must be at least 1")
+	}
+
+	// The Kafka version detected at startup can be pinned in a file, so that the next start does not need to detect it
+	// again unless the cluster cannot be connected to with it, or redetect-version is set
+	module.versionFile = viper.GetString(configRoot + ".version-file")
+	module.redetectVersion = viper.GetBool(configRoot + ".redetect-version")
+	if module.newSaramaClient == nil {
+		module.newSaramaClient = sarama.NewClient
+	}
+
+	// Metadata requests can be sent to a broker in the same rack as Burrow, rather than the least loaded broker, to
+	// avoid cross-region requests. See metadataBroker for what this covers
+	module.metadataRack = viper.GetString(configRoot + ".metadata-rack")
+
+	// Burrow's own groups only exist in storage, so the groups reaper must not remove them
+	module.reportedGroups = helpers.GetReportedConsumerGroups(name)
+
+	// Offset request retries all happen within a single refresh. If they can take longer than the refresh interval,
+	// only a warning is logged here. The refreshes run one at a time from the main loop, so a slow one does not overlap
+	// with the next, but the refreshes fall behind the interval, and the other tickers and requests wait for it
+	module.offsetRetryMax, module.offsetRetryBackoff = helpers.GetOffsetRetryFromClientProfile(profile)
+	if time.Duration(module.offsetRetryMax)*module.offsetRetryBackoff >= time.Duration(module.offsetRefresh)*time.Second {
+		module.Log.Warn("offset request retries can take longer than the offset refresh interval",
+			zap.Int("offset_retry_max", module.offsetRetryMax),
+			zap.Duration("offset_retry_backoff", module.offsetRetryBackoff),
+			zap.Int("offset_refresh", module.offsetRefresh),
+		)
+	}
+
+	if module.App.OffsetScheduler != nil {
+		module.App.OffsetScheduler.Register(name, module.offsetInterval)
+	}
 }
 
-// Start connects to the Kafka cluster using the Shopify/sarama client. Any error connecting to the cluster is returned
-// to the caller. Once the client is set up, tickers are started to periodically refresh topics and offsets.
+// Start connects to the Kafka cluster using the Shopify/sarama client, detecting the Kafka version to use (see
+// newClient). If no server set can be connected to with any version, the error is returned to the caller. Once the
+// client is set up, tickers are started to periodically refresh topics and offsets.
 func (module *KafkaCluster) Start() error {
 	module.Log.Info("starting")
 
 	// Connect Kafka client
-	client, err := sarama.NewClient(module.servers, module.saramaConfig)
+	client, err := module.connectServerSets()
 	if err != nil {
-		module.Log.Error("failed to start client[cluster]version:"+module.saramaConfig.Version.String(), zap.Error(err))
-	}
-	if os.Getenv("CLUSTERS_VERSION") == "" {
-		vers := len(sarama.SupportedVersions)
-		for index := range vers {
-			module.saramaConfig.Version = sarama.SupportedVersions[vers-index-1]
-			if client, err = sarama.NewClient(module.servers, module.saramaConfig); err == nil {
-				module.Log.Info("try using client[cluster]version:" + module.saramaConfig.Version.String())
-				break
-			}
-		}
+		module.Log.Error("failed to connect to cluster", zap.Error(err))
+		return fmt.Errorf("failed to connect to cluster %v: %w", module.name, err)
 	}
+	httpserver.SetClusterKafkaVersion(module.name, module.saramaConfig.Version.String())
+	httpserver.SetOffsetRefreshInterval(module.name, time.Duration(module.offsetRefresh)*time.Second)
 
 	// Fire off the offset requests once, before we start the ticker, to make sure we start with good data for consumers
 	helperClient := &helpers.BurrowSaramaClient{
 		Client: client,
 	}
-	module.fetchMetadata = true
+	module.fetchMetadata.Store(true)
 	module.getOffsets(helperClient)
 
 	// Start main loop that has a timer for offset and topic fetches
-	module.offsetTicker = time.NewTicker(time.Duration(module.offsetRefresh) * time.Second)
+	module.offsetTicker = time.NewTicker(module.offsetInterval)
+	module.offsetStartTimer = time.NewTimer(module.offsetInterval)
+	module.offsetStartTimer.Stop()
+	if module.App.OffsetScheduler != nil {
+		// If the scheduler has shifted this cluster's fetches, the ticker is started when the timer fires
+		if delay := module.App.OffsetScheduler.Start(module.name); delay > module.offsetInterval {
+			module.offsetTicker.Stop()
+			module.offsetStartTimer.Reset(delay)
+			module.Log.Debug("offset fetches staggered", zap.Duration("first_fetch", delay))
+		}
+	}
 	module.metadataTicker = time.NewTicker(time.Duration(module.topicRefresh) * time.Second)
+	if module.discoveryRefresh != 0 {
+		module.discoveryTicker = time.NewTicker(time.Duration(module.discoveryRefresh) * time.Second)
+	} else {
+		// As with the groups reaper, a stopped ticker never fires
+		module.discoveryTicker = time.NewTicker(1 * time.Minute)
+		module.discoveryTicker.Stop()
+	}
 
 	if module.groupsReaperRefresh != 0 {
 		module.groupsReaperTicker = time.NewTicker(time.Duration(module.groupsReaperRefresh) * time.Second)
@@ -126,6 +427,21 @@ func (module *KafkaCluster) Start() error {
 		module.groupsReaperTicker = time.NewTicker(1 * time.Minute)
 		module.groupsReaperTicker.Stop()
 	}
+	if module.leadershipFile != "" {
+		module.writeLeadershipFile()
+		module.leadershipTicker = time.NewTicker(time.Duration(module.leadershipInterval) * time.Second)
+	} else {
+		module.leadershipTicker = time.NewTicker(1 * time.Minute)
+		module.leadershipTicker.Stop()
+	}
+	if module.timeLagSampling {
+		module.timeLagTicker = time.NewTicker(time.Duration(module.timeLagInterval) * time.Second)
+		module.sampleTimeLagOffsets(helperClient)
+	} else {
+		module.timeLagTicker = time.NewTicker(1 * time.Minute)
+		module.timeLagTicker.Stop()
+	}
+	module.startFailbackTicker()
 	go module.mainLoop(helperClient)
 
 	return nil
@@ -136,14 +452,25 @@ func (module *KafkaCluster) Stop() error {
 	module.Log.Info("stopping")
 
 	module.metadataTicker.Stop()
+	module.discoveryTicker.Stop()
 	module.offsetTicker.Stop()
+	module.offsetStartTimer.Stop()
 	module.groupsReaperTicker.Stop()
+	module.leadershipTicker.Stop()
+	module.timeLagTicker.Stop()
+	module.failbackTicker.Stop()
 	close(module.quitChannel)
 	module.running.Wait()
 
 	return nil
 }
 
+// GetCommunicationChannel returns the channel that the cluster Coordinator forwards requests for this cluster on. The
+// requests are handled in the same goroutine as the regular refreshes, so they never run at the same time.
+func (module *KafkaCluster) GetCommunicationChannel() chan *protocol.ClusterRequest {
+	return module.requestChannel
+}
+
 func (module *KafkaCluster) mainLoop(client helpers.SaramaClient) {
 	module.running.Add(1)
 	defer module.running.Done()
@@ -152,55 +479,199 @@ func (module *KafkaCluster) mainLoop(client helpers.SaramaClient) {
 		select {
 		case <-module.offsetTicker.C:
 			module.getOffsets(client)
+			client = module.checkServerSet(client)
+		case <-module.offsetStartTimer.C:
+			// This is the first of the staggered fetches, so the ticker takes over from here
+			module.offsetTicker.Reset(module.offsetInterval)
+			module.getOffsets(client)
+			client = module.checkServerSet(client)
 		case <-module.metadataTicker.C:
 			// Update metadata on next offset fetch
-			module.fetchMetadata = true
+			module.fetchMetadata.Store(true)
+		case <-module.discoveryTicker.C:
+			if module.topicsChanged(client) {
+				// Fetch offsets for the new topics now, rather than waiting for the next offset refresh
+				module.getOffsets(client)
+			}
 		case <-module.groupsReaperTicker.C:
 			module.reapNonExistingGroups(client)
+		case <-module.leadershipTicker.C:
+			module.writeLeadershipFile()
+		case <-module.timeLagTicker.C:
+			module.sampleTimeLagOffsets(client)
+		case <-module.failbackTicker.C:
+			client = module.failbackServerSet(client)
+		case request := <-module.requestChannel:
+			module.handleRequest(client, request)
 		case <-module.quitChannel:
 			return
 		}
 	}
 }
 
+// topicFilterPatterns returns the patterns from all of the config keys for one of the topic filter lists
+func topicFilterPatterns(configRoot string, keys ...string) []string {
+	patterns := make([]string, 0)
+	for _, key := range keys {
+		patterns = append(patterns, viper.GetStringSlice(configRoot+"."+key)...)
+	}
+	return patterns
+}
+
+// compileTopicFilter compiles the patterns for one of the topic filter lists. A bad pattern will cause this func to
+// panic, as it is called when configuring the module.
+func compileTopicFilter(name, list string, patterns []string) []*regexp.Regexp {
+	filter := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			panic("Cluster '" + name + "' failed to compile topic-filter." + list + " '" + pattern + "': " + err.Error())
+		}
+		filter = append(filter, re)
+	}
+	return filter
+}
+
+// acceptTopic returns true if the topic passes the topic filter. Topics that do not are left out of the metadata
+// refresh, so their partitions, leaders, and offsets are never fetched or sent to storage. If exclude-internal-topics is
+// set, an internal topic only passes if it matches the allowlist.
+func (module *KafkaCluster) acceptTopic(topic string) bool {
+	for _, re := range module.topicDenylist {
+		if re.MatchString(topic) {
+			return false
+		}
+	}
+	if module.excludeInternal && module.internalTopic(topic) {
+		return module.allowedTopic(topic)
+	}
+	return (len(module.topicAllowlist) == 0) || module.allowedTopic(topic)
+}
+
+// updateFilteredTopics tells storage about each topic that the topic filter has started leaving out since the last
+// metadata refresh, and each one that it no longer leaves out (including one that no longer exists)
+func (module *KafkaCluster) updateFilteredTopics(filteredTopics map[string]bool) {
+	for topic := range filteredTopics {
+		if !module.filteredTopics[topic] {
+			module.sendTopicFiltered(topic, true)
+		}
+	}
+	for topic := range module.filteredTopics {
+		if !filteredTopics[topic] {
+			module.sendTopicFiltered(topic, false)
+		}
+	}
+	module.filteredTopics = filteredTopics
+}
+
+func (module *KafkaCluster) sendTopicFiltered(topic string, filtered bool) {
+	module.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetTopicFiltered,
+		Cluster:     module.name,
+		Topic:       topic,
+		Filtered:    filtered,
+	}
+}
+
+// internalTopic returns true if the topic is one of Kafka's internal topics, or matches one of the
+// internal-topic-patterns
+func (module *KafkaCluster) internalTopic(topic string) bool {
+	if strings.HasPrefix(topic, "__") {
+		return true
+	}
+	for _, re := range module.internalTopics {
+		if re.MatchString(topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedTopic returns true if the topic matches one of the topic allowlist patterns
+func (module *KafkaCluster) allowedTopic(topic string) bool {
+	for _, re := range module.topicAllowlist {
+		if re.MatchString(topic) {
+			return true
+		}
+	}
+	return false
+}
+
 func (module *KafkaCluster) maybeUpdateMetadataAndDeleteTopics(client helpers.SaramaClient) {
-	if module.fetchMetadata {
-		module.fetchMetadata = false
-		client.RefreshMetadata()
+	if module.fetchMetadata.CompareAndSwap(true, false) {
 
-		// Get the current list of topics and make a map
-		topicList, err := client.Topics()
+		// Get every topic, partition, and leader from a single metadata response, rather than walking the client's
+		// metadata one topic and partition at a time. On a large cluster, that loop is expensive
+		broker := module.metadataBroker(client)
+		if broker == nil {
+			module.Log.Error("failed to fetch metadata", zap.String("sarama_error", sarama.ErrOutOfBrokers.Error()))
+			module.setMetadataState(httpserver.ClusterMetadataFailed)
+			module.metadataFailures++
+			module.forceMetadataRefresh("metadata-failed")
+			return
+		}
+		metadata, err := broker.GetMetadata(sarama.NewMetadataRequest(client.Config().Version, nil))
 		if err != nil {
-			module.Log.Error("failed to fetch topic list", zap.String("sarama_error", err.Error()))
+			module.Log.Error("failed to fetch metadata", zap.String("sarama_error", err.Error()))
+			module.setMetadataState(httpserver.ClusterMetadataFailed)
+			module.metadataFailures++
+			module.forceMetadataRefresh("metadata-failed")
 			return
 		}
+		module.metadataFailures = 0
 
-		// We'll use topicPartitions later
-		topicPartitions := make(map[string][]int32)
-		for _, topic := range topicList {
-			partitions, err := client.Partitions(topic)
-			if err != nil {
-				module.Log.Error("failed to fetch partition list", zap.String("sarama_error", err.Error()))
-				return
+		// A cluster with no topics at all is told apart from one whose metadata cannot be fetched. This counts every
+		// topic in the metadata, so a cluster whose topics are all filtered out is not empty
+		if len(metadata.Topics) == 0 {
+			module.setMetadataState(httpserver.ClusterMetadataEmpty)
+		} else {
+			module.setMetadataState(httpserver.ClusterMetadataOK)
+		}
+
+		// Offset requests are sent to the leaders via the client, so make sure it knows about all the brokers
+		for _, metadataBroker := range metadata.Brokers {
+			if _, err := client.Broker(metadataBroker.ID()); err != nil {
+				client.RefreshMetadata()
+				break
 			}
+		}
 
-			topicPartitions[topic] = make([]int32, 0, len(partitions))
-			for _, partitionID := range partitions {
-				if _, err := client.Leader(topic, partitionID); err != nil {
-					module.Log.Warn("failed to fetch leader for partition",
-						zap.String("topic", topic),
-						zap.Int32("partition", partitionID),
-						zap.String("sarama_error", err.Error()))
-				} else { // partitionID has a leader
-					// NOTE: append only happens here
-					// so cap(topicPartitions[topic]) is the partition count
-					topicPartitions[topic] = append(topicPartitions[topic], partitionID)
-				}
+		// We'll use topicPartitions and topicLeaders later
+		topicPartitions := make(map[string][]int32, len(metadata.Topics))
+		topicLeaders := make(map[string][]int32, len(metadata.Topics))
+		filteredTopics := make(map[string]bool)
+		for _, topic := range metadata.Topics {
+			if !module.acceptTopic(topic.Name) {
+				filteredTopics[topic.Name] = true
+				continue
 			}
+			if (topic.Err != sarama.ErrNoError) && (topic.Err != sarama.ErrLeaderNotAvailable) {
+				module.Log.Warn("failed to fetch partition list",
+					zap.String("topic", topic.Name),
+					zap.String("sarama_error", topic.Err.Error()))
+				continue
+			}
+
+			// NOTE: cap(topicPartitions[topic]) is the partition count
+			topicPartitions[topic.Name], topicLeaders[topic.Name] = module.topicPartitionLeaders(topic)
 		}
 
-		// Check for deleted topics if we have a previous map to check against
+		// Check for new and deleted topics if we have a previous map to check against. All of the topics are new on
+		// the first refresh, so they are not reported. Only the filtered topics are in either map, so a tracked topic
+		// that no longer passes the filter (including one that is now counted as internal) is deleted from storage the
+		// same as one that no longer exists
 		if module.topicPartitions != nil {
+			now := time.Now()
+			for topic, partitions := range topicPartitions {
+				previous, ok := module.topicPartitions[topic]
+				if !ok {
+					module.Log.Info("discovered new topic",
+						zap.String("topic", topic),
+						zap.Int("partitions", cap(partitions)),
+					)
+					httpserver.IncTopicDiscovered(module.name)
+				}
+				module.reapPartitions(topic, previous, partitions, now)
+			}
 			for topic := range module.topicPartitions {
 				if _, ok := topicPartitions[topic]; !ok {
 					// Topic no longer exists - tell storage to delete it
@@ -210,52 +681,201 @@ func (module *KafkaCluster) maybeUpdateMetadataAndDeleteTopics(client helpers.Sa
 						Topic:       topic,
 					}
 					httpserver.DeleteTopicMetrics(module.name, topic)
+					delete(module.leaderlessSince, topic)
 				}
 			}
 		}
 
-		// Save the new topicPartitions for next time
+		if module.detectCompacted {
+			module.updateCompactedTopics(client, broker, topicPartitions)
+		}
+		module.updateFilteredTopics(filteredTopics)
+
+		// Save the new topicPartitions and topicLeaders for next time
 		module.topicPartitions = topicPartitions
+		module.topicLeaders = topicLeaders
+	}
+}
+
+// setMetadataState records the result of a metadata refresh for the cluster detail and metric, and logs when it changes
+func (module *KafkaCluster) setMetadataState(state string) {
+	if state == module.metadataState {
+		return
+	}
+	if state == httpserver.ClusterMetadataEmpty {
+		module.Log.Info("cluster has no topics")
+	} else if module.metadataState != "" {
+		module.Log.Info("cluster metadata state changed",
+			zap.String("previous", module.metadataState),
+			zap.String("state", state),
+		)
+	}
+	module.metadataState = state
+	httpserver.SetClusterMetadataState(module.name, state)
+}
+
+// metadataBroker returns the broker to send metadata requests to. If a metadata-rack is configured, this is a broker in
+// that rack, if the client knows of one it can connect to. Otherwise, it is the least loaded broker, which is what
+// sarama itself uses.
+//
+// This only covers the metadata requests that Burrow makes itself, for the topic refresh and discovery. Sarama does not
+// allow choosing the broker for its own metadata refreshes (such as when connecting), and offset requests must go to
+// the leader of each partition. The rack of each broker is only known with Kafka 0.10.0.0 or later.
+func (module *KafkaCluster) metadataBroker(client helpers.SaramaClient) helpers.SaramaBroker {
+	if module.metadataRack != "" {
+		for _, broker := range client.Brokers() {
+			if broker.Rack() != module.metadataRack {
+				continue
+			}
+			// The brokers from the client metadata are not connected, but fetching one by ID connects it
+			if rackBroker, err := client.Broker(broker.ID()); err == nil {
+				return rackBroker
+			}
+		}
+		module.Log.Debug("no broker available in metadata rack", zap.String("rack", module.metadataRack))
+	}
+	return client.LeastLoadedBroker()
+}
+
+// topicsChanged fetches the list of topics for the cluster, and compares the names to the topics from the last full
+// metadata refresh. Unlike the full refresh, it does not walk the partitions and leaders or update storage. If a topic
+// has been created or deleted, the next offset fetch is made to refresh the metadata, and true is returned.
+func (module *KafkaCluster) topicsChanged(client helpers.SaramaClient) bool {
+	if module.fetchMetadata.Load() || (module.topicPartitions == nil) {
+		// A full refresh is already waiting for the next offset fetch
+		return false
+	}
+
+	broker := module.metadataBroker(client)
+	if broker == nil {
+		module.Log.Warn("failed to fetch topic list", zap.String("sarama_error", sarama.ErrOutOfBrokers.Error()))
+		return false
+	}
+	metadata, err := broker.GetMetadata(sarama.NewMetadataRequest(client.Config().Version, nil))
+	if err != nil {
+		module.Log.Warn("failed to fetch topic list", zap.String("sarama_error", err.Error()))
+		return false
+	}
+
+	// Topics with errors, or that do not pass the topic filter, are skipped by the full refresh, so they are not
+	// counted here either
+	topicCount := 0
+	for _, topic := range metadata.Topics {
+		if (!module.acceptTopic(topic.Name)) || ((topic.Err != sarama.ErrNoError) && (topic.Err != sarama.ErrLeaderNotAvailable)) {
+			continue
+		}
+		topicCount++
+		if _, ok := module.topicPartitions[topic.Name]; !ok {
+			module.Log.Debug("found new topic", zap.String("topic", topic.Name))
+			module.forceMetadataRefresh("topics-changed")
+			return true
+		}
+	}
+	if topicCount != len(module.topicPartitions) {
+		module.Log.Debug("found deleted topics")
+		module.forceMetadataRefresh("topics-changed")
+		return true
+	}
+	return false
+}
+
+// generateOffsetRequests builds an OffsetRequest for each broker for the partitions it leads, for the offset at
+// offsetTime (sarama.OffsetNewest or sarama.OffsetOldest).
+func (module *KafkaCluster) generateOffsetRequests(client helpers.SaramaClient, offsetTime int64) (map[int32]*sarama.OffsetRequest, map[int32]helpers.SaramaBroker) {
+	return module.generateTopicOffsetRequests(client, offsetTime, func(string) bool { return true })
+}
+
+// generateTieredOffsetRequests builds the OffsetRequests for each broker for the partitions of the topics that the due
+// func returns true for, split into tiers that are sent to the broker in order. If priority-topics is set, the first
+// tier has only the partitions for the topics that match it, and the second has the rest. Otherwise, there is a single
+// tier.
+func (module *KafkaCluster) generateTieredOffsetRequests(client helpers.SaramaClient, offsetTime int64, due func(string) bool) ([]map[int32]*sarama.OffsetRequest, map[int32]helpers.SaramaBroker) {
+	if len(module.priorityTopics) == 0 {
+		requests, brokers := module.generateTopicOffsetRequests(client, offsetTime, due)
+		return []map[int32]*sarama.OffsetRequest{requests}, brokers
+	}
+
+	priorityRequests, brokers := module.generateTopicOffsetRequests(client, offsetTime, func(topic string) bool { return due(topic) && module.isPriorityTopic(topic) })
+	requests, otherBrokers := module.generateTopicOffsetRequests(client, offsetTime, func(topic string) bool { return due(topic) && !module.isPriorityTopic(topic) })
+	for brokerID, broker := range otherBrokers {
+		brokers[brokerID] = broker
+	}
+	return []map[int32]*sarama.OffsetRequest{priorityRequests, requests}, brokers
+}
+
+func (module *KafkaCluster) isPriorityTopic(topic string) bool {
+	for _, re := range module.priorityTopics {
+		if re.MatchString(topic) {
+			return true
+		}
 	}
+	return false
 }
 
-func (module *KafkaCluster) generateOffsetRequests(client helpers.SaramaClient) (map[int32]*sarama.OffsetRequest, map[int32]helpers.SaramaBroker) {
+// generateTopicOffsetRequests builds an OffsetRequest for each broker for the partitions it leads of the topics that
+// the include func returns true for
+func (module *KafkaCluster) generateTopicOffsetRequests(client helpers.SaramaClient, offsetTime int64, include func(string) bool) (map[int32]*sarama.OffsetRequest, map[int32]helpers.SaramaBroker) {
 	requests := make(map[int32]*sarama.OffsetRequest)
 	brokers := make(map[int32]helpers.SaramaBroker)
 
+	// The partitions led by a broker that is backed off after failing its offset requests are left out, without
+	// counting as errors, as the failures were already counted. See brokerbackoff.go
+	now := time.Now()
+	backedOff := make(map[int32]bool)
+
 	// Generate an OffsetRequest for each topic:partition and bucket it to the leader broker
 	for topic, partitions := range module.topicPartitions {
-		for _, partitionID := range partitions {
-			broker, err := client.Leader(topic, partitionID)
-			if err != nil {
-				module.Log.Warn("failed to fetch leader for partition",
-					zap.String("topic", topic),
-					zap.Int32("partition", partitionID),
-					zap.String("sarama_error", err.Error()))
-				module.fetchMetadata = true
+		if !include(topic) {
+			continue
+		}
+		for i, partitionID := range partitions {
+			leaderID := module.topicLeaders[topic][i]
+			if backedOff[leaderID] {
 				continue
 			}
-			if _, ok := requests[broker.ID()]; !ok {
-				requests[broker.ID()] = &sarama.OffsetRequest{}
+			if _, ok := requests[leaderID]; !ok {
+				if module.brokerBackedOff(leaderID, now) {
+					backedOff[leaderID] = true
+					continue
+				}
+				broker, err := client.Broker(leaderID)
+				if err != nil {
+					if module.partitionsChanged(topic) {
+						module.Log.Debug("leader for partition changed, refreshing topic metadata",
+							zap.String("topic", topic),
+							zap.Int32("partition", partitionID),
+							zap.Int32("broker", leaderID),
+							zap.String("sarama_error", err.Error()))
+						continue
+					}
+					module.Log.Warn("failed to fetch leader for partition",
+						zap.String("topic", topic),
+						zap.Int32("partition", partitionID),
+						zap.Int32("broker", leaderID),
+						zap.String("sarama_error", err.Error()))
+					module.forceMetadataRefresh("no-leader")
+					continue
+				}
+				brokers[leaderID] = broker
+				requests[leaderID] = &sarama.OffsetRequest{}
 				// Match the version of the client as sarama's getOffset function does
 				// https://github.com/IBM/sarama/blob/main/client.go#L863-L876
 				if client.Config().Version.IsAtLeast(sarama.V2_1_0_0) {
 					// Version 4 adds the current leader epoch, which is used for fencing.
-					requests[broker.ID()].Version = 4
+					requests[leaderID].Version = 4
 				} else if client.Config().Version.IsAtLeast(sarama.V2_0_0_0) {
 					// Version 3 is the same as version 2.
-					requests[broker.ID()].Version = 3
+					requests[leaderID].Version = 3
 				} else if client.Config().Version.IsAtLeast(sarama.V0_11_0_0) {
 					// Version 2 adds the isolation level, which is used for transactional reads.
-					requests[broker.ID()].Version = 2
+					requests[leaderID].Version = 2
 				} else if client.Config().Version.IsAtLeast(sarama.V0_10_1_0) {
 					// Version 1 removes MaxNumOffsets.  From this version forward, only a single
 					// offset can be returned.
-					requests[broker.ID()].Version = 1
+					requests[leaderID].Version = 1
 				}
 			}
-			brokers[broker.ID()] = broker
-			requests[broker.ID()].AddBlock(topic, partitionID, sarama.OffsetNewest, 1)
+			requests[leaderID].AddBlock(topic, partitionID, offsetTime, 1)
 		}
 	}
 
@@ -265,69 +885,443 @@ func (module *KafkaCluster) generateOffsetRequests(client helpers.SaramaClient)
 // This function performs massively parallel OffsetRequests, which is better than Sarama's internal implementation,
 // which does one at a time. Several orders of magnitude faster.
 func (module *KafkaCluster) getOffsets(client helpers.SaramaClient) {
+	defer httpserver.RecordModuleCycle("cluster."+module.name+".offsets", time.Now())
+	defer func(start time.Time) {
+		httpserver.ObserveOffsetRefresh(module.name, time.Since(start))
+	}(time.Now())
+
 	module.maybeUpdateMetadataAndDeleteTopics(client)
-	requests, brokers := module.generateOffsetRequests(client)
+	due := module.dueTopics(time.Now())
+
+	// The topics with a leader that the client no longer knows (such as after a leader election) have their metadata
+	// fetched on their own before the offsets are, rather than failing in the fetch
+	if moved := module.movedLeaderTopics(client, due); len(moved) > 0 {
+		module.refreshChangedTopics(client, moved)
+	}
+	errorCount := module.fetchOffsets(client, due)
+
+	// The topics whose partitions changed during the fetch have their metadata fetched again on their own, and are
+	// fetched again with it. If they still do not match, a full metadata refresh is left to the next run
+	if changed := module.takeChangedTopics(); len(changed) > 0 {
+		if module.refreshChangedTopics(client, changed) {
+			errorCount += module.fetchOffsets(client, func(topic string) bool { return changed[topic] })
+		}
+		if len(module.takeChangedTopics()) > 0 {
+			module.forceMetadataRefresh("partitions-changed")
+		}
+	}
+
+	module.recordTrackedPartitions()
+	module.recordLeadership()
+
+	// If enough partitions had errors, force a metadata refresh on the next run
+	if int(errorCount) >= module.refreshErrors {
+		module.forceMetadataRefresh("offset-errors")
+	} else if errorCount > 0 {
+		module.Log.Debug("not forcing metadata refresh for offset errors",
+			zap.Int32("errors", errorCount),
+			zap.Int("metadata_refresh_errors", module.refreshErrors),
+		)
+	}
+}
+
+// fetchOffsets sends the OffsetRequests for the partitions of the topics that the due func returns true for, and
+// returns the number of partitions that had errors
+func (module *KafkaCluster) fetchOffsets(client helpers.SaramaClient, due func(string) bool) int32 {
+	requestTiers, brokers := module.generateTieredOffsetRequests(client, sarama.OffsetNewest, due)
+	var oldestRequestTiers []map[int32]*sarama.OffsetRequest
+	if module.fetchOldest {
+		oldestRequestTiers, _ = module.generateTieredOffsetRequests(client, sarama.OffsetOldest, due)
+	}
+	var lookbackRequestTiers []map[int32]*sarama.OffsetRequest
+	if module.lookbackSupported(client) {
+		module.lookbackTime = (time.Now().Unix() - int64(module.offsetLookback)) * 1000
+		lookbackRequestTiers, _ = module.generateTieredOffsetRequests(client, module.lookbackTime, due)
+	}
+
+	// Send out the OffsetRequests to each broker for all the partitions it is leader for, with the priority topics
+	// first. The results go to the offset storage module.
+	var errorCount atomic.Int32
+	module.forEachBroker(brokers, func(brokerID int32, broker helpers.SaramaBroker) {
+		for _, requests := range requestTiers {
+			request, ok := requests[brokerID]
+			if !ok {
+				continue
+			}
+			partitionErrors, err := module.getBrokerOffsets(client, brokerID, broker, request, protocol.StorageSetBrokerOffset)
+			errorCount.Add(int32(partitionErrors))
+			if err != nil {
+				// The broker has already failed all of its retries, so don't try it again in this refresh
+				return
+			}
+		}
+
+		// The oldest offsets are not needed without the end offsets, so they are only fetched after all of them
+		for _, oldestRequests := range oldestRequestTiers {
+			if oldestRequest, ok := oldestRequests[brokerID]; ok {
+				partitionErrors, _ := module.getBrokerOffsets(client, brokerID, broker, oldestRequest, protocol.StorageSetBrokerOldestOffset)
+				errorCount.Add(int32(partitionErrors))
+			}
+		}
+		for _, lookbackRequests := range lookbackRequestTiers {
+			if lookbackRequest, ok := lookbackRequests[brokerID]; ok {
+				partitionErrors, _ := module.getBrokerOffsets(client, brokerID, broker, lookbackRequest, protocol.StorageSetBrokerLookbackOffset)
+				errorCount.Add(int32(partitionErrors))
+			}
+		}
+	})
+	return errorCount.Load()
+}
 
-	// Send out the OffsetRequest to each broker for all the partitions it is leader for
-	// The results go to the offset storage module
+// forEachBroker calls fetch for each of the brokers in parallel, and returns once all of them have returned. No more
+// than max-concurrent-offset-fetches brokers are sent requests at a time, so a slot must be free before the goroutine
+// for the next broker is started.
+func (module *KafkaCluster) forEachBroker(brokers map[int32]helpers.SaramaBroker, fetch func(brokerID int32, broker helpers.SaramaBroker)) {
 	var wg = sync.WaitGroup{}
-	var errorTopics = sync.Map{}
+	slots := make(chan struct{}, module.maxOffsetFetches)
 
-	getBrokerOffsets := func(brokerID int32, request *sarama.OffsetRequest) {
-		defer wg.Done()
-		response, err := brokers[brokerID].GetAvailableOffsets(request)
+	for brokerID, broker := range brokers {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(brokerID int32, broker helpers.SaramaBroker) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			fetch(brokerID, broker)
+		}(brokerID, broker)
+	}
+	wg.Wait()
+}
+
+// getBrokerOffsets sends a single broker the OffsetRequest for the partitions it leads, retrying as configured, and
+// sends the offsets in the response to storage with the given request type. It returns the number of partitions that the broker returned an error
+// for, and the error if the request itself failed.
+func (module *KafkaCluster) getBrokerOffsets(client helpers.SaramaClient, brokerID int32, broker helpers.SaramaBroker, request *sarama.OffsetRequest, requestType protocol.StorageRequestConstant) (int, error) {
+	return module.requestBrokerOffsets(client, brokerID, broker, request, module.offsetTimeFor(requestType), func(topic string, partition int32, offset int64) {
+		ts := time.Now().Unix() * 1000
+		if requestType == protocol.StorageSetBrokerLookbackOffset {
+			// The offsets are for the time that was asked for, not for now
+			ts = module.lookbackTime
+		}
+		helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+			RequestType:         requestType,
+			Cluster:             module.name,
+			Topic:               topic,
+			Partition:           partition,
+			Offset:              offset,
+			Timestamp:           ts,
+			TopicPartitionCount: int32(cap(module.topicPartitions[topic])),
+		}, 1)
+	})
+}
+
+// requestBrokerOffsets sends a single broker the OffsetRequest for the partitions it leads, built for offsetTime,
+// retrying as configured and backing off from the broker if all of the retries fail (see brokerbackoff.go). The handle
+// func is called with each offset in the response. It returns the number of partitions that the broker returned an
+// error for, and the error if the request itself failed.
+func (module *KafkaCluster) requestBrokerOffsets(client helpers.SaramaClient, brokerID int32, broker helpers.SaramaBroker, request *sarama.OffsetRequest, offsetTime int64, handle func(topic string, partition int32, offset int64)) (int, error) {
+	response, err := broker.GetAvailableOffsets(request)
+	for attempt := 1; (err != nil) && (attempt <= module.offsetRetryMax); attempt++ {
+		module.Log.Warn("retrying offset fetch from broker",
+			zap.String("sarama_error", err.Error()),
+			zap.Int32("broker", brokerID),
+			zap.Int("attempt", attempt),
+		)
+		time.Sleep(module.offsetRetryBackoff)
+
+		// Reconnect to the broker, as the connection may not be usable after the failed request
+		broker.Close()
+		if err = broker.Open(client.Config()); err == nil {
+			response, err = broker.GetAvailableOffsets(request)
+		}
+	}
+	if err != nil {
+		module.Log.Error("failed to fetch offsets from broker",
+			zap.String("sarama_error", err.Error()),
+			zap.Int32("broker", brokerID),
+		)
+		httpserver.CountOffsetFetchError(module.name, brokerID)
+		module.brokerFetchFailed(brokerID, time.Now())
+		broker.Close()
+		return 0, err
+	}
+	module.brokerFetchSucceeded(brokerID)
+	partitionErrors, outOfRange := module.handleOffsetResponse(brokerID, response, handle)
+
+	// An offset out of range error is usually from a leader election that is still in progress, so the partitions are
+	// retried on their own shortly, rather than counting towards a metadata refresh right away
+	for attempt := 1; (len(outOfRange) > 0) && (attempt <= module.outOfRangeRetries); attempt++ {
+		module.Log.Debug("retrying offset out of range partitions",
+			zap.Int32("broker", brokerID),
+			zap.Int("partitions", countPartitions(outOfRange)),
+			zap.Int("attempt", attempt),
+		)
+		time.Sleep(module.outOfRangeBackoff)
+
+		retryRequest := &sarama.OffsetRequest{Version: request.Version}
+		for topic, partitions := range outOfRange {
+			for _, partition := range partitions {
+				retryRequest.AddBlock(topic, partition, offsetTime, 1)
+			}
+		}
+		retryResponse, err := broker.GetAvailableOffsets(retryRequest)
 		if err != nil {
-			module.Log.Error("failed to fetch offsets from broker",
+			// The partitions are counted as errors below, as the broker request itself has failed
+			module.Log.Warn("failed to retry offset out of range partitions",
 				zap.String("sarama_error", err.Error()),
 				zap.Int32("broker", brokerID),
 			)
-			brokers[brokerID].Close()
-			return
+			httpserver.CountOffsetFetchError(module.name, brokerID)
+			break
 		}
-		ts := time.Now().Unix() * 1000
-		for topic, partitions := range response.Blocks {
-			for partition, offsetResponse := range partitions {
-				if offsetResponse.Err != sarama.ErrNoError {
-					module.Log.Warn("error in OffsetResponse",
-						zap.String("sarama_error", offsetResponse.Err.Error()),
-						zap.Int32("broker", brokerID),
-						zap.String("topic", topic),
-						zap.Int32("partition", partition),
-					)
 
-					// Gather a list of topics that had errors
-					errorTopics.Store(topic, true)
-					continue
-				}
-				offset := &protocol.StorageRequest{
-					RequestType:         protocol.StorageSetBrokerOffset,
-					Cluster:             module.name,
-					Topic:               topic,
-					Partition:           partition,
-					Offset:              offsetResponse.Offsets[0],
-					Timestamp:           ts,
-					TopicPartitionCount: int32(cap(module.topicPartitions[topic])),
-				}
-				helpers.TimeoutSendStorageRequest(module.App.StorageChannel, offset, 1)
+		var retryErrors int
+		retryErrors, outOfRange = module.handleOffsetResponse(brokerID, retryResponse, handle)
+		partitionErrors += retryErrors
+	}
+
+	// The partitions that are still out of range count as errors, so enough of them will force a metadata refresh
+	for topic, partitions := range outOfRange {
+		for _, partition := range partitions {
+			module.Log.Warn("error in OffsetResponse",
+				zap.String("sarama_error", sarama.ErrOffsetOutOfRange.Error()),
+				zap.Int32("broker", brokerID),
+				zap.String("topic", topic),
+				zap.Int32("partition", partition),
+			)
+			partitionErrors++
+		}
+	}
+	return partitionErrors, nil
+}
+
+// handleOffsetResponse calls the handle func with each offset in the response from a broker. Errors are handled for
+// each partition on its own, so the other partitions in the response, including those of the same topic, are handled
+// regardless. Every partition error is counted in the partition offset errors metric by the error. It returns the
+// number of partitions that the broker returned an error for, except for offset out of range errors, and the
+// partitions (by topic) that had those.
+func (module *KafkaCluster) handleOffsetResponse(brokerID int32, response *sarama.OffsetResponse, handle func(topic string, partition int32, offset int64)) (int, map[string][]int32) {
+	partitionErrors := 0
+	outOfRange := make(map[string][]int32)
+	for topic, partitions := range response.Blocks {
+		for partition, offsetResponse := range partitions {
+			if offsetResponse.Err != sarama.ErrNoError {
+				httpserver.CountPartitionOffsetError(module.name, offsetResponse.Err)
+			}
+			if offsetResponse.Err == sarama.ErrOffsetOutOfRange {
+				outOfRange[topic] = append(outOfRange[topic], partition)
+				continue
+			}
+			if ((offsetResponse.Err == sarama.ErrUnknownTopicOrPartition) || (offsetResponse.Err == sarama.ErrNotLeaderForPartition)) && module.partitionsChanged(topic) {
+				// The topic's metadata is refreshed after this offset fetch, and the partition fetched again then
+				module.Log.Debug("partition changed since metadata refresh",
+					zap.String("sarama_error", offsetResponse.Err.Error()),
+					zap.Int32("broker", brokerID),
+					zap.String("topic", topic),
+					zap.Int32("partition", partition),
+				)
+				continue
+			}
+			if offsetResponse.Err != sarama.ErrNoError {
+				module.Log.Warn("error in OffsetResponse",
+					zap.String("sarama_error", offsetResponse.Err.Error()),
+					zap.Int32("broker", brokerID),
+					zap.String("topic", topic),
+					zap.Int32("partition", partition),
+				)
+
+				// Count the partitions that had errors
+				partitionErrors++
+				continue
 			}
+			handle(topic, partition, offsetResponse.Offsets[0])
 		}
 	}
+	return partitionErrors, outOfRange
+}
 
-	for brokerID, request := range requests {
-		wg.Add(1)
-		go getBrokerOffsets(brokerID, request)
+// recordTrackedPartitions sets the metrics for the number of topics and partitions that offsets are fetched for
+func (module *KafkaCluster) recordTrackedPartitions() {
+	partitionCount := 0
+	for _, partitions := range module.topicPartitions {
+		partitionCount += cap(partitions)
 	}
+	httpserver.SetClusterTrackedPartitions(module.name, len(module.topicPartitions), partitionCount)
+}
 
-	wg.Wait()
+// offsetTimeFor returns the offset time that the OffsetRequests for the storage request type are built with
+func (module *KafkaCluster) offsetTimeFor(requestType protocol.StorageRequestConstant) int64 {
+	switch requestType {
+	case protocol.StorageSetBrokerOldestOffset:
+		return sarama.OffsetOldest
+	case protocol.StorageSetBrokerLookbackOffset:
+		return module.lookbackTime
+	default:
+		return sarama.OffsetNewest
+	}
+}
 
-	// If there are any topics that had errors, force a metadata refresh on the next run
-	errorTopics.Range(func(key, value interface{}) bool {
-		module.fetchMetadata = true
+func countPartitions(topics map[string][]int32) int {
+	count := 0
+	for _, partitions := range topics {
+		count += len(partitions)
+	}
+	return count
+}
+
+// lookbackSupported returns true if offset-lookback is set and the Kafka version that the client uses has timestamp
+// ListOffsets requests (0.10.1 and later). Older versions only find the offset of the log segment for a timestamp, so
+// the lookback offsets are not fetched, with a warning the first time.
+func (module *KafkaCluster) lookbackSupported(client helpers.SaramaClient) bool {
+	if module.offsetLookback == 0 {
 		return false
-	})
+	}
+	if client.Config().Version.IsAtLeast(sarama.V0_10_1_0) {
+		return true
+	}
+	if !module.lookbackWarned {
+		module.Log.Warn("offset-lookback needs Kafka 0.10.1 or later, lookback offsets will not be fetched",
+			zap.String("kafka_version", client.Config().Version.String()),
+		)
+		module.lookbackWarned = true
+	}
+	return false
+}
+
+func (module *KafkaCluster) handleRequest(client helpers.SaramaClient, request *protocol.ClusterRequest) {
+	switch request.RequestType {
+	case protocol.ClusterRefreshBrokerOffsets:
+		module.refreshBrokerOffsets(client, request)
+	case protocol.ClusterFetchBrokerAPIVersions:
+		module.fetchAPIVersions(client, request)
+	case protocol.ClusterFetchTopicOffsetsAtTime:
+		module.fetchTopicOffsetsAtTime(client, request)
+	default:
+		module.Log.Error("unknown cluster request type", zap.Int("request_type", int(request.RequestType)))
+		close(request.Reply)
+	}
+}
+
+// refreshBrokerOffsets fetches the end offsets for only the partitions that one broker leads, as of the last metadata
+// refresh, for troubleshooting that broker without waiting for a full offset refresh. The reply has zero partitions
+// if the broker does not lead any. If the broker is backed off after failing its offset requests, it is not sent a
+// request, and the reply has an error that says when the backoff passes.
+func (module *KafkaCluster) refreshBrokerOffsets(client helpers.SaramaClient, request *protocol.ClusterRequest) {
+	defer close(request.Reply)
+
+	result := &protocol.ClusterBrokerOffsets{Broker: request.Broker}
+	for _, leaders := range module.topicLeaders {
+		for _, leaderID := range leaders {
+			if leaderID == request.Broker {
+				result.Partitions++
+			}
+		}
+	}
+
+	if until, backedOff := module.brokerBackoffUntil(request.Broker, time.Now()); (result.Partitions > 0) && backedOff {
+		result.Error = "broker is in backoff until " + until.Format(time.RFC3339)
+	} else if result.Partitions > 0 {
+		requests, brokers := module.generateOffsetRequests(client, sarama.OffsetNewest)
+		if offsetRequest, ok := requests[request.Broker]; ok {
+			partitionErrors, err := module.getBrokerOffsets(client, request.Broker, brokers[request.Broker], offsetRequest, protocol.StorageSetBrokerOffset)
+			result.PartitionErrors = partitionErrors
+			if err != nil {
+				result.Error = err.Error()
+			}
+		} else {
+			// The client could not return the broker, which generateTopicOffsetRequests has logged
+			result.Error = "failed to get broker from client"
+		}
+	}
+
+	module.Log.Info("refreshed broker offsets",
+		zap.Int32("broker", result.Broker),
+		zap.Int("partitions", result.Partitions),
+		zap.Int("partition_errors", result.PartitionErrors),
+	)
+	request.Reply <- result
+}
+
+// fetchAPIVersions replies with the API versions that each broker supports, for checking that every broker has been
+// upgraded before changing the Kafka version that Burrow uses. The versions do not change unless a broker is
+// restarted, so they are cached, and are only fetched from the brokers the first time or if the request asks for a
+// refresh.
+func (module *KafkaCluster) fetchAPIVersions(client helpers.SaramaClient, request *protocol.ClusterRequest) {
+	defer close(request.Reply)
+
+	if (module.apiVersions != nil) && !request.Refresh {
+		request.Reply <- module.apiVersions
+		return
+	}
+
+	result := &protocol.ClusterAPIVersions{
+		Fetched: time.Now().Unix() * 1000,
+		Brokers: make([]*protocol.BrokerAPIVersions, 0),
+	}
+	for _, metadataBroker := range client.Brokers() {
+		brokerVersions := &protocol.BrokerAPIVersions{Broker: metadataBroker.ID(), APIs: make([]*protocol.APIVersionRange, 0)}
+		result.Brokers = append(result.Brokers, brokerVersions)
+
+		// The brokers from the client metadata are not connected, but fetching one by ID connects it
+		broker, err := client.Broker(brokerVersions.Broker)
+		if err != nil {
+			brokerVersions.Error = err.Error()
+			continue
+		}
+		response, err := broker.ApiVersions(&sarama.ApiVersionsRequest{})
+		if err == nil && response.ErrorCode != int16(sarama.ErrNoError) {
+			err = sarama.KError(response.ErrorCode)
+		}
+		if err != nil {
+			module.Log.Warn("failed to fetch API versions",
+				zap.Int32("broker", brokerVersions.Broker),
+				zap.Error(err),
+			)
+			brokerVersions.Error = err.Error()
+			continue
+		}
+		for _, key := range response.ApiKeys {
+			brokerVersions.APIs = append(brokerVersions.APIs, &protocol.APIVersionRange{
+				APIKey:     key.ApiKey,
+				MinVersion: key.MinVersion,
+				MaxVersion: key.MaxVersion,
+			})
+		}
+		sort.Slice(brokerVersions.APIs, func(i, j int) bool { return brokerVersions.APIs[i].APIKey < brokerVersions.APIs[j].APIKey })
+	}
+	sort.Slice(result.Brokers, func(i, j int) bool { return result.Brokers[i].Broker < result.Brokers[j].Broker })
+	result.Uniform = apiVersionsUniform(result.Brokers)
+
+	module.Log.Info("fetched API versions",
+		zap.Int("brokers", len(result.Brokers)),
+		zap.Bool("uniform", result.Uniform),
+	)
+	module.apiVersions = result
+	request.Reply <- result
+}
+
+// apiVersionsUniform returns true if every broker responded with the same API versions
+func apiVersionsUniform(brokers []*protocol.BrokerAPIVersions) bool {
+	if len(brokers) == 0 {
+		return false
+	}
+	for _, broker := range brokers {
+		if (broker.Error != "") || !reflect.DeepEqual(broker.APIs, brokers[0].APIs) {
+			return false
+		}
+	}
+	return true
+}
+
+// forceMetadataRefresh makes the next offset fetch refresh the metadata first, outside of the regular topic refresh,
+// and counts it by reason in the forced refresh metric
+func (module *KafkaCluster) forceMetadataRefresh(reason string) {
+	module.fetchMetadata.Store(true)
+	httpserver.IncMetadataRefreshForced(module.name, reason)
 }
 
 func (module *KafkaCluster) reapNonExistingGroups(client helpers.SaramaClient) {
+	defer httpserver.RecordModuleCycle("cluster."+module.name+".groups-reaper", time.Now())
+
 	kafkaGroups, err := client.ListConsumerGroups()
 	if err != nil {
 		module.Log.Error("failed to get the list of available consumer groups", zap.Error(err))
@@ -347,14 +1341,17 @@ func (module *KafkaCluster) reapNonExistingGroups(client helpers.SaramaClient) {
 		return
 	}
 
-	// TODO: find how to get reportedConsumerGroup from KafkaClient
-	burrowIgnoreGroupName := "burrow-" + module.name
 	burrowGroups, _ := res.([]string)
+	skipDelete := module.isGroupListShort(len(kafkaGroups), burrowGroups)
+	keptGroups := make([]string, 0, len(burrowGroups))
 	for _, g := range burrowGroups {
-		if g == burrowIgnoreGroupName {
+		if module.reportedGroups[g] {
+			keptGroups = append(keptGroups, g)
 			continue
 		}
-		if _, ok := kafkaGroups[g]; !ok {
+		if _, ok := kafkaGroups[g]; ok || skipDelete {
+			keptGroups = append(keptGroups, g)
+		} else {
 			module.Log.Info(fmt.Sprintf("groups reaper: removing non existing kafka consumer group (%s) from burrow", g))
 			request := &protocol.StorageRequest{
 				RequestType: protocol.StorageSetDeleteGroup,
@@ -366,4 +1363,78 @@ func (module *KafkaCluster) reapNonExistingGroups(client helpers.SaramaClient) {
 			httpserver.DeleteConsumerMetrics(module.name, g)
 		}
 	}
+
+	if module.trimGroupTopics {
+		module.trimDeletedTopics(keptGroups)
+	}
+}
+
+// isGroupListShort returns true, with a warning, if the number of groups that Kafka listed is too few for the groups
+// reaper to trust it: none at all, or fewer than groups-reaper-min-ratio of the groups in storage (not counting
+// Burrow's own groups). A coordinator that is failing over can return an empty or partial list, and deleting groups
+// based on it would wipe out their offset history.
+func (module *KafkaCluster) isGroupListShort(kafkaCount int, burrowGroups []string) bool {
+	burrowCount := 0
+	for _, g := range burrowGroups {
+		if !module.reportedGroups[g] {
+			burrowCount++
+		}
+	}
+	if burrowCount == 0 {
+		return false
+	}
+	if (kafkaCount > 0) && (float64(kafkaCount) >= module.groupsReaperMinRatio*float64(burrowCount)) {
+		return false
+	}
+
+	module.Log.Warn("groups reaper: too few consumer groups listed by kafka, not deleting any groups",
+		zap.Int("kafka_groups", kafkaCount),
+		zap.Int("burrow_groups", burrowCount),
+		zap.Float64("min_ratio", module.groupsReaperMinRatio),
+	)
+	return true
+}
+
+// trimDeletedTopics removes the offsets for topics that are not in the cluster's metadata from each of the groups.
+// Storage removes a topic from all groups when the cluster sees it deleted, but a topic that was deleted before that
+// (such as while Burrow was not running, with offsets imported from before) would otherwise stay in the group, with
+// partitions that are never committed to again skewing the group's status.
+func (module *KafkaCluster) trimDeletedTopics(groups []string) {
+	if module.topicPartitions == nil {
+		// The topics in the cluster are not known until the metadata has been fetched
+		return
+	}
+
+	for _, group := range groups {
+		request := &protocol.StorageRequest{
+			RequestType: protocol.StorageFetchConsumer,
+			Reply:       make(chan interface{}),
+			Cluster:     module.name,
+			Group:       group,
+		}
+		if !helpers.TimeoutSendStorageRequest(module.App.StorageChannel, request, 1) {
+			module.Log.Warn("groups reaper: timed out fetching group to trim deleted topics", zap.String("group", group))
+			return
+		}
+		topics, ok := (<-request.Reply).(protocol.ConsumerTopics)
+		if !ok {
+			continue
+		}
+
+		for topic := range topics {
+			if _, ok := module.topicPartitions[topic]; ok {
+				continue
+			}
+			module.Log.Info("groups reaper: trimming offsets for deleted topic from group",
+				zap.String("group", group),
+				zap.String("topic", topic),
+			)
+			helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+				RequestType: protocol.StorageSetDeleteGroup,
+				Cluster:     module.name,
+				Group:       group,
+				Topic:       topic,
+			}, 1)
+		}
+	}
 }
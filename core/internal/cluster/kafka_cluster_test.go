@@ -11,12 +11,16 @@ package cluster
 
 import (
 	"errors"
+	"net"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -24,6 +28,7 @@ import (
 	"sync"
 
 	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/internal/httpserver"
 	"github.com/linkedin/Burrow/core/protocol"
 )
 
@@ -44,6 +49,32 @@ func fixtureModule() *KafkaCluster {
 	return &module
 }
 
+// fixtureMetadataClient returns a client mock that returns the metadata response from a single broker with ID 13
+func fixtureMetadataClient(metadata *sarama.MetadataResponse) (*helpers.MockSaramaClient, *helpers.MockSaramaBroker) {
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("GetMetadata", mock.MatchedBy(func(request *sarama.MetadataRequest) bool { return request != nil })).Return(metadata, nil)
+
+	client := &helpers.MockSaramaClient{}
+	client.On("LeastLoadedBroker").Return(broker)
+	client.On("Config").Return(sarama.NewConfig())
+	client.On("Broker", int32(13)).Return(broker, nil)
+
+	metadata.AddBroker("broker1.example.com:1234", 13)
+	return client, broker
+}
+
+// fixtureClientLeaders sets up the client mock's metadata to agree with the module's leaders for all of its partitions,
+// so that no topic is found to have a moved leader before the offsets are fetched
+func fixtureClientLeaders(module *KafkaCluster, client *helpers.MockSaramaClient) {
+	for topic, partitions := range module.topicPartitions {
+		for i, partitionID := range partitions {
+			leader := &helpers.MockSaramaBroker{}
+			leader.On("ID").Return(module.topicLeaders[topic][i])
+			client.On("Leader", topic, partitionID).Return(leader, nil)
+		}
+	}
+}
+
 func TestKafkaCluster_ImplementsModule(t *testing.T) {
 	assert.Implements(t, (*protocol.Module)(nil), new(KafkaCluster))
 }
@@ -52,6 +83,40 @@ func TestKafkaCluster_Configure(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "cluster.test")
 	assert.NotNil(t, module.saramaConfig, "Expected saramaConfig to be populated")
+	assert.Equal(t, partitionChangeRefreshTopic, module.partitionChangeRefresh, "Expected topic refreshes by default")
+}
+
+func TestKafkaCluster_Configure_ClientID(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("client-profile.p1.client-id", nil)
+	viper.Set("client-profile.p1.kafka-version", "0.10.2")
+	module.Configure("test", "cluster.test")
+	assert.Equal(t, "burrow-test", module.saramaConfig.ClientID, "Expected default client ID of burrow-<cluster>")
+
+	module = fixtureModule()
+	viper.Set("cluster.test.client-id", "clusterid")
+	module.Configure("test", "cluster.test")
+	assert.Equal(t, "clusterid", module.saramaConfig.ClientID, "Expected client ID to be set from the cluster")
+}
+
+func TestKafkaCluster_Configure_DefaultClientProfile(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.client-profile", nil)
+	viper.Set("general.client-profile", "p1")
+	module.Configure("test", "cluster.test")
+	assert.Equal(t, "testid", module.saramaConfig.ClientID, "Expected the client-id from the default client-profile")
+
+	module = fixtureModule()
+	viper.Set("cluster.test.client-profile", nil)
+	viper.Set("general.client-profile", "missing")
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_Configure_BadClientID(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.client-id", "bad client id")
+
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
 }
 
 func TestKafkaCluster_Configure_DefaultIntervals(t *testing.T) {
@@ -63,12 +128,32 @@ func TestKafkaCluster_Configure_DefaultIntervals(t *testing.T) {
 	assert.Equal(t, int(0), module.groupsReaperRefresh, "Default GroupsReaperRefresh value of 0 did not get set")
 }
 
+func TestKafkaCluster_Start_ConnectFailed(t *testing.T) {
+	for _, version := range []string{"", "2.8.2"} {
+		t.Setenv("CLUSTERS_VERSION", version)
+		module := fixtureModule()
+		module.Configure("test", "cluster.test")
+
+		connects := 0
+		module.newSaramaClient = func(_ []string, _ *sarama.Config) (sarama.Client, error) {
+			connects++
+			return nil, errors.New("cannot connect")
+		}
+
+		err := module.Start()
+		assert.Errorf(t, err, "Expected Start to fail with CLUSTERS_VERSION %q", version)
+		assert.Positive(t, connects, "Expected the client factory to be called")
+		assert.Nil(t, module.offsetTicker, "Expected no tickers to be started")
+	}
+}
+
 func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_NoUpdate(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "cluster.test")
 	client := &helpers.MockSaramaClient{}
 
 	module.maybeUpdateMetadataAndDeleteTopics(client)
+	client.AssertNotCalled(t, "LeastLoadedBroker")
 	client.AssertNotCalled(t, "RefreshMetadata")
 }
 
@@ -77,61 +162,147 @@ func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_NoDelete(t *testing.T)
 	module.Configure("test", "cluster.test")
 
 	// Set up the mock to return a test topic and partition
-	client := &helpers.MockSaramaClient{}
-	client.On("RefreshMetadata").Return(nil)
-	client.On("Topics").Return([]string{"testtopic"}, nil)
-	client.On("Partitions", "testtopic").Return([]int32{0}, nil)
-	client.On("Leader", "testtopic", int32(0)).Return(&helpers.MockSaramaBroker{}, nil)
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	client, broker := fixtureMetadataClient(metadata)
 
-	module.fetchMetadata = true
+	module.fetchMetadata.Store(true)
 	module.maybeUpdateMetadataAndDeleteTopics(client)
 
+	broker.AssertExpectations(t)
 	client.AssertExpectations(t)
-	assert.False(t, module.fetchMetadata, "Expected fetchMetadata to be reset to false")
+	client.AssertNotCalled(t, "RefreshMetadata")
+	assert.False(t, module.fetchMetadata.Load(), "Expected fetchMetadata to be reset to false")
 	assert.Lenf(t, module.topicPartitions, 1, "Expected 1 topic entry, not %v", len(module.topicPartitions))
 	topic, ok := module.topicPartitions["testtopic"]
 	assert.True(t, ok, "Expected to find testtopic in topicPartitions")
 	assert.Equalf(t, 1, len(topic), "Expected testtopic to be recorded with 1 partition, not %v", len(topic))
+	assert.Equalf(t, []int32{13}, module.topicLeaders["testtopic"], "Expected testtopic leaders to be [13], not %v", module.topicLeaders["testtopic"])
 }
 
 func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_PartialUpdate(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "cluster.test")
 
-	// Set up the mock to return a test topic and partition
-	client := &helpers.MockSaramaClient{}
-	client.On("RefreshMetadata").Return(nil)
-	client.On("Topics").Return([]string{"testtopic"}, nil)
-	client.On("Partitions", "testtopic").Return([]int32{0, 1}, nil)
-
-	var nilBroker *helpers.BurrowSaramaBroker
-	client.On("Leader", "testtopic", int32(0)).Return(nilBroker, errors.New("no leader error"))
-	client.On("Leader", "testtopic", int32(1)).Return(&helpers.MockSaramaBroker{}, nil)
+	// Set up the mock to return a test topic with one partition that has no leader
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, -1, nil, nil, nil, sarama.ErrLeaderNotAvailable)
+	metadata.AddTopicPartition("testtopic", 1, 13, nil, nil, nil, sarama.ErrNoError)
+	client, _ := fixtureMetadataClient(metadata)
 
-	module.fetchMetadata = true
+	module.fetchMetadata.Store(true)
 	module.maybeUpdateMetadataAndDeleteTopics(client)
 
 	client.AssertExpectations(t)
-	assert.False(t, module.fetchMetadata, "Expected fetchMetadata to be reset to false")
+	assert.False(t, module.fetchMetadata.Load(), "Expected fetchMetadata to be reset to false")
 	assert.Lenf(t, module.topicPartitions, 1, "Expected 1 topic entry, not %v", len(module.topicPartitions))
 	topic, ok := module.topicPartitions["testtopic"]
 	assert.True(t, ok, "Expected to find testtopic in topicPartitions")
 	assert.Equalf(t, len(topic), 1, "Expected testtopic's length to be 1, not %v", len(topic))
 	assert.Equalf(t, cap(topic), 2, "Expected testtopic's capacity to be 2, not %v", cap(topic))
+	assert.Equalf(t, []int32{1}, topic, "Expected testtopic's partitions to be [1], not %v", topic)
 }
 
-func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_Delete(t *testing.T) {
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_TopicError(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "cluster.test")
 
-	// Set up the mock to return a test topic and partition
+	// Set up the mock to return one good topic and one that is not authorized
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopic("badtopic", sarama.ErrTopicAuthorizationFailed)
+	client, _ := fixtureMetadataClient(metadata)
+
+	module.fetchMetadata.Store(true)
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+
+	client.AssertExpectations(t)
+	assert.Lenf(t, module.topicPartitions, 1, "Expected 1 topic entry, not %v", len(module.topicPartitions))
+	_, ok := module.topicPartitions["badtopic"]
+	assert.False(t, ok, "Expected badtopic to not be in topicPartitions")
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_MetadataFailed(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	var metadata *sarama.MetadataResponse
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("GetMetadata", mock.MatchedBy(func(request *sarama.MetadataRequest) bool { return request != nil })).Return(metadata, errors.New("metadata failed"))
 	client := &helpers.MockSaramaClient{}
+	client.On("LeastLoadedBroker").Return(broker)
+	client.On("Config").Return(sarama.NewConfig())
+
+	module.fetchMetadata.Store(true)
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+
+	broker.AssertExpectations(t)
+	client.AssertExpectations(t)
+	assert.True(t, module.fetchMetadata.Load(), "Expected fetchMetadata to be true so the fetch is retried")
+	assert.Nil(t, module.topicPartitions, "Expected topicPartitions to not be set")
+	assert.Equal(t, httpserver.ClusterMetadataFailed, module.metadataState, "Expected the metadata to be marked as failed")
+
+	// No brokers available at all
+	client = &helpers.MockSaramaClient{}
+	client.On("LeastLoadedBroker").Return(nil)
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+
+	client.AssertExpectations(t)
+	assert.True(t, module.fetchMetadata.Load(), "Expected fetchMetadata to be true so the fetch is retried")
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_Empty(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	// The metadata is fetched, but the cluster has no topics
+	client, _ := fixtureMetadataClient(&sarama.MetadataResponse{})
+	module.fetchMetadata.Store(true)
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+
+	assert.False(t, module.fetchMetadata.Load(), "Expected fetchMetadata to be reset to false")
+	assert.Empty(t, module.topicPartitions, "Expected no topics")
+	assert.Equal(t, httpserver.ClusterMetadataEmpty, module.metadataState, "Expected the cluster to be marked as empty")
+
+	// And then a topic is created
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	client, _ = fixtureMetadataClient(metadata)
+	module.fetchMetadata.Store(true)
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+	assert.Equal(t, httpserver.ClusterMetadataOK, module.metadataState, "Expected the cluster to be marked as ok")
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_UnknownBroker(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	// The metadata has a broker that the client does not know about yet, so the client metadata must be refreshed
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 14, nil, nil, nil, sarama.ErrNoError)
+	client, _ := fixtureMetadataClient(metadata)
+	metadata.AddBroker("broker2.example.com:1234", 14)
+	var nilBroker *helpers.BurrowSaramaBroker
+	client.On("Broker", int32(14)).Return(nilBroker, sarama.ErrBrokerNotFound)
 	client.On("RefreshMetadata").Return(nil)
-	client.On("Topics").Return([]string{"testtopic"}, nil)
-	client.On("Partitions", "testtopic").Return([]int32{0}, nil)
-	client.On("Leader", "testtopic", int32(0)).Return(&helpers.MockSaramaBroker{}, nil)
 
-	module.fetchMetadata = true
+	module.fetchMetadata.Store(true)
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+
+	client.AssertExpectations(t)
+	assert.Equalf(t, []int32{14}, module.topicLeaders["testtopic"], "Expected testtopic leaders to be [14], not %v", module.topicLeaders["testtopic"])
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_Delete(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	// Set up the mock to return a test topic and partition
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	client, _ := fixtureMetadataClient(metadata)
+
+	module.fetchMetadata.Store(true)
 	module.topicPartitions = make(map[string][]int32)
 	module.topicPartitions["topictodelete"] = make([]int32, 0, 10)
 	for i := 0; i < cap(module.topicPartitions["topictodelete"]); i++ {
@@ -152,29 +323,396 @@ func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_Delete(t *testing.T) {
 	wg.Wait()
 
 	client.AssertExpectations(t)
-	assert.False(t, module.fetchMetadata, "Expected fetchMetadata to be reset to false")
+	assert.False(t, module.fetchMetadata.Load(), "Expected fetchMetadata to be reset to false")
 	assert.Lenf(t, module.topicPartitions, 1, "Expected 1 topic entry, not %v", len(module.topicPartitions))
 	topic, ok := module.topicPartitions["testtopic"]
 	assert.True(t, ok, "Expected to find testtopic in topicPartitions")
 	assert.Equalf(t, 1, len(topic), "Expected testtopic to be recorded with 1 partition, not %v", len(topic))
 }
 
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_NewTopic(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	core, logs := observer.New(zap.InfoLevel)
+	module.Log = zap.New(core)
+
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("newtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("newtopic", 1, 13, nil, nil, nil, sarama.ErrNoError)
+	client, _ := fixtureMetadataClient(metadata)
+
+	module.fetchMetadata.Store(true)
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+
+	// Only the topic that was not known before is reported
+	discovered := logs.FilterMessage("discovered new topic").All()
+	assert.Len(t, discovered, 1, "Expected one new topic to be reported")
+	assert.Equal(t, "newtopic", discovered[0].ContextMap()["topic"])
+	assert.Equal(t, int64(2), discovered[0].ContextMap()["partitions"])
+	assert.Lenf(t, module.topicPartitions, 2, "Expected 2 topic entries, not %v", len(module.topicPartitions))
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_FirstLoad(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	core, logs := observer.New(zap.InfoLevel)
+	module.Log = zap.New(core)
+
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	client, _ := fixtureMetadataClient(metadata)
+
+	// All of the topics are new on the first load, so none are reported
+	module.fetchMetadata.Store(true)
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+	assert.Equal(t, 0, logs.FilterMessage("discovered new topic").Len(), "Expected no new topics to be reported")
+}
+
+func TestKafkaCluster_acceptTopic(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-filter.allowlist", []string{"^payments-.*$", "^orders$"})
+	viper.Set("cluster.test.topic-filter.denylist", []string{"-test$"})
+	module.Configure("test", "cluster.test")
+
+	assert.True(t, module.acceptTopic("payments-eu"), "Expected payments-eu to be accepted")
+	assert.True(t, module.acceptTopic("orders"), "Expected orders to be accepted")
+	assert.False(t, module.acceptTopic("orders-archive"), "Expected orders-archive to not match the allowlist")
+	assert.False(t, module.acceptTopic("payments-test"), "Expected payments-test to match the denylist")
+
+	// With no allowlist, every topic that is not denied is accepted. The blacklist name works the same as denylist
+	module = fixtureModule()
+	viper.Set("cluster.test.topic-filter.blacklist", []string{"^__"})
+	module.Configure("test", "cluster.test")
+	assert.True(t, module.acceptTopic("anytopic"), "Expected anytopic to be accepted")
+	assert.False(t, module.acceptTopic("__consumer_offsets"), "Expected __consumer_offsets to match the blacklist")
+}
+
+func TestKafkaCluster_acceptTopic_Overlapping(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-filter-allow", []string{"^payments-", "^__consumer_offsets$"})
+	viper.Set("cluster.test.topic-filter-deny", []string{"-internal$", "^__"})
+	module.Configure("test", "cluster.test")
+
+	// A topic that matches both lists is denied
+	assert.True(t, module.acceptTopic("payments-eu"), "Expected payments-eu to be accepted")
+	assert.False(t, module.acceptTopic("payments-internal"), "Expected payments-internal to be denied")
+	assert.False(t, module.acceptTopic("__consumer_offsets"), "Expected __consumer_offsets to be denied")
+
+	// The internal topic is tracked like any other if it is allowed, and not denied
+	module = fixtureModule()
+	viper.Set("cluster.test.topic-filter-allow", []string{"^__consumer_offsets$"})
+	module.Configure("test", "cluster.test")
+	assert.True(t, module.acceptTopic("__consumer_offsets"), "Expected __consumer_offsets to be accepted")
+	assert.False(t, module.acceptTopic("payments-eu"), "Expected payments-eu to not match the allowlist")
+
+	// The lists from every config name are combined
+	module = fixtureModule()
+	viper.Set("cluster.test.topic-filter.denylist", []string{"^__"})
+	viper.Set("cluster.test.topic-filter-deny", []string{"-internal$"})
+	module.Configure("test", "cluster.test")
+	assert.False(t, module.acceptTopic("__consumer_offsets"), "Expected __consumer_offsets to be denied")
+	assert.False(t, module.acceptTopic("payments-internal"), "Expected payments-internal to be denied")
+	assert.True(t, module.acceptTopic("payments-eu"), "Expected payments-eu to be accepted")
+}
+
+func TestKafkaCluster_acceptTopic_Internal(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	assert.False(t, module.acceptTopic("__consumer_offsets"), "Expected __consumer_offsets to be excluded by default")
+	assert.False(t, module.acceptTopic("__transaction_state"), "Expected __transaction_state to be excluded by default")
+	assert.True(t, module.acceptTopic("_single_underscore"), "Expected _single_underscore to be accepted")
+
+	// An internal topic that is in the allowlist is tracked, without the allowlist leaving out the other topics
+	module = fixtureModule()
+	viper.Set("cluster.test.topic-filter.allowlist", []string{"^__consumer_offsets$", "^payments-"})
+	module.Configure("test", "cluster.test")
+	assert.True(t, module.acceptTopic("__consumer_offsets"), "Expected __consumer_offsets to be accepted")
+	assert.False(t, module.acceptTopic("__transaction_state"), "Expected __transaction_state to be excluded")
+	assert.True(t, module.acceptTopic("payments-eu"), "Expected payments-eu to be accepted")
+
+	module = fixtureModule()
+	viper.Set("cluster.test.exclude-internal-topics", false)
+	module.Configure("test", "cluster.test")
+	assert.True(t, module.acceptTopic("__consumer_offsets"), "Expected __consumer_offsets to be accepted")
+}
+
+func TestKafkaCluster_Configure_BadTopicFilter(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-filter.whitelist", []string{"["})
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+
+	module = fixtureModule()
+	viper.Set("cluster.test.topic-filter.denylist", []string{"["})
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_TopicFilter(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-filter.allowlist", []string{"^tracked"})
+	module.Configure("test", "cluster.test")
+
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("trackedtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("othertopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("formertopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	client, _ := fixtureMetadataClient(metadata)
+
+	// formertopic was tracked before, but no longer passes the filter, so it is deleted from storage even though it
+	// still exists. othertopic was never tracked, so it is only sent as filtered, the same as formertopic
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+	module.fetchMetadata.Store(true)
+	module.topicPartitions = map[string][]int32{"trackedtopic": {0}, "formertopic": {0}}
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+
+	assert.Len(t, module.App.StorageChannel, 3, "Expected one topic to be deleted and two to be filtered")
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetDeleteTopic, request.RequestType, "Expected request sent with type StorageSetDeleteTopic, not %v", request.RequestType)
+	assert.Equalf(t, "formertopic", request.Topic, "Expected request sent with topic formertopic, not %v", request.Topic)
+	assert.Equal(t, map[string]bool{"othertopic": true, "formertopic": true}, filteredTopicRequests(module.App.StorageChannel), "Expected othertopic and formertopic to be filtered")
+
+	assert.Equal(t, map[string][]int32{"trackedtopic": {0}}, module.topicPartitions, "Expected only trackedtopic to be tracked")
+	assert.Equal(t, map[string][]int32{"trackedtopic": {13}}, module.topicLeaders, "Expected only the leaders of trackedtopic")
+
+	// Topics that do not pass the filter are not changes to the tracked topics
+	assert.False(t, module.topicsChanged(client), "Expected the filtered topics to be unchanged")
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_InternalTopics(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("__consumer_offsets", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("__transaction_state", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	client, _ := fixtureMetadataClient(metadata)
+
+	// The internal topics were never tracked, so they are not deleted from storage on either refresh. They are sent to
+	// storage as filtered on the first refresh only
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+	module.fetchMetadata.Store(true)
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+	assert.Equal(t, map[string]bool{"__consumer_offsets": true, "__transaction_state": true}, filteredTopicRequests(module.App.StorageChannel), "Expected the internal topics to be filtered")
+	module.fetchMetadata.Store(true)
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+
+	assert.Equal(t, map[string][]int32{"testtopic": {0}}, module.topicPartitions, "Expected only testtopic to be tracked")
+	assert.Empty(t, module.App.StorageChannel, "Expected no topics to be deleted")
+	assert.False(t, module.topicsChanged(client), "Expected the internal topics to not be changes to the tracked topics")
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_InternalTopicPatterns(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.internal-topic-patterns", []string{"-changelog$", "-repartition$"})
+	module.Configure("test", "cluster.test")
+
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("app-store-changelog", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("app-join-repartition", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	client, _ := fixtureMetadataClient(metadata)
+
+	// The changelog topic was tracked before the patterns were set, so it is deleted from storage
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+	module.topicPartitions = map[string][]int32{"testtopic": {0}, "app-store-changelog": {0}}
+	module.fetchMetadata.Store(true)
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+
+	assert.Equal(t, map[string][]int32{"testtopic": {0}}, module.topicPartitions, "Expected only testtopic to be tracked")
+	assert.Len(t, module.App.StorageChannel, 3, "Expected one topic to be deleted and two to be filtered")
+	request := <-module.App.StorageChannel
+	assert.Equal(t, protocol.StorageSetDeleteTopic, request.RequestType)
+	assert.Equal(t, "app-store-changelog", request.Topic)
+	assert.Equal(t, map[string]bool{"app-store-changelog": true, "app-join-repartition": true}, filteredTopicRequests(module.App.StorageChannel))
+
+	// With exclude-internal-topics off, the patterns do nothing
+	module = fixtureModule()
+	viper.Set("cluster.test.internal-topic-patterns", []string{"-changelog$", "-repartition$"})
+	viper.Set("cluster.test.exclude-internal-topics", false)
+	module.Configure("test", "cluster.test")
+	module.fetchMetadata.Store(true)
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+	assert.Len(t, module.topicPartitions, 3, "Expected all topics to be tracked")
+}
+
+func TestKafkaCluster_Configure_BadInternalTopicPattern(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.internal-topic-patterns", []string{"("})
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_TopicFilterDenied(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-filter-allow", []string{"^payments-", "^__consumer_offsets$"})
+	viper.Set("cluster.test.topic-filter-deny", []string{"^__"})
+	module.Configure("test", "cluster.test")
+
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("payments-eu", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("__consumer_offsets", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	client, _ := fixtureMetadataClient(metadata)
+
+	// __consumer_offsets was tracked before the deny pattern was added, so it is deleted from storage
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+	module.fetchMetadata.Store(true)
+	module.topicPartitions = map[string][]int32{"payments-eu": {0}, "__consumer_offsets": {0}}
+	module.maybeUpdateMetadataAndDeleteTopics(client)
+
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetDeleteTopic, request.RequestType, "Expected request sent with type StorageSetDeleteTopic, not %v", request.RequestType)
+	assert.Equalf(t, "__consumer_offsets", request.Topic, "Expected request sent with topic __consumer_offsets, not %v", request.Topic)
+	assert.Equal(t, map[string]bool{"__consumer_offsets": true}, filteredTopicRequests(module.App.StorageChannel), "Expected __consumer_offsets to be filtered")
+
+	assert.Equal(t, map[string][]int32{"payments-eu": {0}}, module.topicPartitions, "Expected only payments-eu to be tracked")
+}
+
+// filteredTopicRequests reads the StorageSetTopicFiltered requests that are waiting in the channel, and returns whether
+// each topic was sent as filtered
+func filteredTopicRequests(channel chan *protocol.StorageRequest) map[string]bool {
+	topics := make(map[string]bool)
+	for len(channel) > 0 {
+		request := <-channel
+		if request.RequestType == protocol.StorageSetTopicFiltered {
+			topics[request.Topic] = request.Filtered
+		}
+	}
+	return topics
+}
+
+func TestKafkaCluster_updateFilteredTopics(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+
+	module.updateFilteredTopics(map[string]bool{"topic1": true, "topic2": true})
+	assert.Equal(t, map[string]bool{"topic1": true, "topic2": true}, filteredTopicRequests(module.App.StorageChannel))
+
+	// Only the changes are sent
+	module.updateFilteredTopics(map[string]bool{"topic2": true, "topic3": true})
+	assert.Equal(t, map[string]bool{"topic1": false, "topic3": true}, filteredTopicRequests(module.App.StorageChannel))
+	module.updateFilteredTopics(map[string]bool{"topic2": true, "topic3": true})
+	assert.Empty(t, module.App.StorageChannel, "Expected nothing to be sent when the filtered topics have not changed")
+}
+
+func BenchmarkKafkaCluster_maybeUpdateMetadataAndDeleteTopics(b *testing.B) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	// A mock broker that serves the metadata for 1000 topics with 50 partitions each, so the 50k partitions go through
+	// a real sarama client as they would on a large cluster
+	broker := sarama.NewMockBroker(b, 13)
+	defer broker.Close()
+	metadata := sarama.NewMockMetadataResponse(b).SetBroker(broker.Addr(), broker.BrokerID()).SetController(broker.BrokerID())
+	for i := 0; i < 1000; i++ {
+		for j := 0; j < 50; j++ {
+			metadata.SetLeader("testtopic"+strconv.Itoa(i), int32(j), broker.BrokerID())
+		}
+	}
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(b),
+		"MetadataRequest":    metadata,
+	})
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_1_0_0
+	saramaClient, err := sarama.NewClient([]string{broker.Addr()}, config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer saramaClient.Close()
+	client := &helpers.BurrowSaramaClient{Client: saramaClient}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		module.fetchMetadata.Store(true)
+		module.maybeUpdateMetadataAndDeleteTopics(client)
+	}
+}
+
+func TestKafkaCluster_topicsChanged(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13}}
+
+	// The same topics
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopic("badtopic", sarama.ErrTopicAuthorizationFailed)
+	client, _ := fixtureMetadataClient(metadata)
+	assert.False(t, module.topicsChanged(client), "Expected topics to be unchanged")
+	assert.False(t, module.fetchMetadata.Load(), "Expected fetchMetadata to be false")
+
+	// A new topic
+	metadata = &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("newtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	client, _ = fixtureMetadataClient(metadata)
+	assert.True(t, module.topicsChanged(client), "Expected new topic to be found")
+	assert.True(t, module.fetchMetadata.Load(), "Expected fetchMetadata to be set")
+
+	// A deleted topic
+	module.fetchMetadata.Store(false)
+	metadata = &sarama.MetadataResponse{}
+	client, _ = fixtureMetadataClient(metadata)
+	assert.True(t, module.topicsChanged(client), "Expected deleted topic to be found")
+	assert.True(t, module.fetchMetadata.Load(), "Expected fetchMetadata to be set")
+}
+
+func TestKafkaCluster_topicsChanged_RefreshPending(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	client := &helpers.MockSaramaClient{}
+
+	// There has not been a full refresh yet
+	assert.False(t, module.topicsChanged(client), "Expected no change before the first refresh")
+
+	module.topicPartitions = map[string][]int32{}
+	module.fetchMetadata.Store(true)
+	assert.False(t, module.topicsChanged(client), "Expected no change while a refresh is pending")
+	client.AssertNotCalled(t, "LeastLoadedBroker")
+}
+
+func TestKafkaCluster_topicsChanged_MetadataFailed(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("GetMetadata", mock.MatchedBy(func(request *sarama.MetadataRequest) bool { return request != nil })).Return(&sarama.MetadataResponse{}, errors.New("metadata failed"))
+	client := &helpers.MockSaramaClient{}
+	client.On("LeastLoadedBroker").Return(broker)
+	client.On("Config").Return(sarama.NewConfig())
+
+	assert.False(t, module.topicsChanged(client), "Expected no change when the topic list fails")
+	assert.False(t, module.fetchMetadata.Load(), "Expected fetchMetadata to be false")
+}
+
+func TestKafkaCluster_Configure_BadTopicDiscoveryRefresh(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-discovery-refresh", -1)
+
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
 func TestKafkaCluster_generateOffsetRequests(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "cluster.test")
 	module.topicPartitions = make(map[string][]int32)
 	module.topicPartitions["testtopic"] = []int32{0}
+	module.topicLeaders = map[string][]int32{"testtopic": {13}}
 
 	// Set up a broker mock
 	broker := &helpers.MockSaramaBroker{}
-	broker.On("ID").Return(int32(13))
 
 	// Set up the mock to return the leader broker for a test topic and partition
 	client := &helpers.MockSaramaClient{}
-	client.On("Leader", "testtopic", int32(0)).Return(broker, nil)
+	client.On("Broker", int32(13)).Return(broker, nil)
 	client.On("Config").Return(sarama.NewConfig())
 
-	requests, brokers := module.generateOffsetRequests(client)
+	requests, brokers := module.generateOffsetRequests(client, sarama.OffsetNewest)
 
 	broker.AssertExpectations(t)
 	client.AssertExpectations(t)
@@ -187,22 +725,23 @@ func TestKafkaCluster_generateOffsetRequests(t *testing.T) {
 
 func TestKafkaCluster_generateOffsetRequests_NoLeader(t *testing.T) {
 	module := fixtureModule()
+	viper.Set("cluster.test.partition-change-refresh", partitionChangeRefreshFull)
 	module.Configure("test", "cluster.test")
 	module.topicPartitions = make(map[string][]int32)
 	module.topicPartitions["testtopic"] = []int32{0, 1}
+	module.topicLeaders = map[string][]int32{"testtopic": {12, 13}}
 
 	// Set up a broker mock
 	broker := &helpers.MockSaramaBroker{}
-	broker.On("ID").Return(int32(13))
 
 	// Set up the mock to return the leader broker for a test topic and partition
 	client := &helpers.MockSaramaClient{}
 	var nilBroker *helpers.BurrowSaramaBroker
-	client.On("Leader", "testtopic", int32(0)).Return(nilBroker, errors.New("no leader error"))
-	client.On("Leader", "testtopic", int32(1)).Return(broker, nil)
+	client.On("Broker", int32(12)).Return(nilBroker, errors.New("no broker error"))
+	client.On("Broker", int32(13)).Return(broker, nil)
 	client.On("Config").Return(sarama.NewConfig())
 
-	requests, brokers := module.generateOffsetRequests(client)
+	requests, brokers := module.generateOffsetRequests(client, sarama.OffsetNewest)
 
 	broker.AssertExpectations(t)
 	client.AssertExpectations(t)
@@ -211,30 +750,120 @@ func TestKafkaCluster_generateOffsetRequests_NoLeader(t *testing.T) {
 	assert.True(t, ok, "Expected key for the broker to be its ID")
 	assert.Equal(t, broker, brokers[13], "Expected broker returned to be the mock")
 	assert.Lenf(t, requests, 1, "Expected 1 request, not %v", len(requests))
-	assert.True(t, module.fetchMetadata, "Expected fetchMetadata to be true")
+	assert.True(t, module.fetchMetadata.Load(), "Expected fetchMetadata to be true")
 }
 
-func TestKafkaCluster_getOffsets(t *testing.T) {
+func TestKafkaCluster_Configure_BadPriorityTopics(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.priority-topics", []string{"^critical$", "["})
+
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_generateTieredOffsetRequests(t *testing.T) {
 	module := fixtureModule()
+	viper.Set("cluster.test.priority-topics", []string{"^critical"})
 	module.Configure("test", "cluster.test")
-	module.topicPartitions = make(map[string][]int32)
-	module.topicPartitions["testtopic"] = []int32{0, 1}
-	module.fetchMetadata = false
+	module.topicPartitions = map[string][]int32{"testtopic": {0, 1}, "critical-topic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13, 12}, "critical-topic": {13}}
 
-	// Set up an OffsetResponse
-	offsetResponse := &sarama.OffsetResponse{Version: 1}
-	offsetResponse.AddTopicPartition("testtopic", 0, 8374)
+	broker12 := &helpers.MockSaramaBroker{}
+	broker13 := &helpers.MockSaramaBroker{}
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(12)).Return(broker12, nil)
+	client.On("Broker", int32(13)).Return(broker13, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	tiers, brokers := module.generateTieredOffsetRequests(client, sarama.OffsetNewest, func(string) bool { return true })
+
+	// The priority topic is in the first tier for its leader, and the other topic's partitions in the second
+	assert.Len(t, brokers, 2, "Expected both brokers")
+	assert.Len(t, tiers, 2, "Expected two tiers of requests")
+	assert.Len(t, tiers[0], 1, "Expected a priority request for only one broker")
+	expected := &sarama.OffsetRequest{Version: tiers[0][13].Version}
+	expected.AddBlock("critical-topic", 0, sarama.OffsetNewest, 1)
+	assert.Equal(t, expected, tiers[0][13], "Expected only the priority topic in the first tier")
+	assert.Len(t, tiers[1], 2, "Expected a request for each broker in the second tier")
+	expected = &sarama.OffsetRequest{Version: tiers[1][13].Version}
+	expected.AddBlock("testtopic", 0, sarama.OffsetNewest, 1)
+	assert.Equal(t, expected, tiers[1][13], "Expected only the other topic in the second tier")
+}
+
+func TestKafkaCluster_generateTieredOffsetRequests_NoPriority(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}, "critical-topic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13}, "critical-topic": {13}}
 
-	// Set up a broker mock
 	broker := &helpers.MockSaramaBroker{}
-	broker.On("ID").Return(int32(13))
-	broker.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request != nil })).Return(offsetResponse, nil)
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(13)).Return(broker, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	tiers, brokers := module.generateTieredOffsetRequests(client, sarama.OffsetNewest, func(string) bool { return true })
+	assert.Len(t, brokers, 1, "Expected one broker")
+	assert.Len(t, tiers, 1, "Expected a single tier of requests")
+}
+
+func TestKafkaCluster_getOffsets_PriorityTopics(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.priority-topics", []string{"^critical"})
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}, "critical-topic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13}, "critical-topic": {13}}
+	module.fetchMetadata.Store(false)
+
+	priorityResponse := &sarama.OffsetResponse{Version: 1}
+	priorityResponse.AddTopicPartition("critical-topic", 0, 1234)
+	otherResponse := &sarama.OffsetResponse{Version: 1}
+	otherResponse.AddTopicPartition("testtopic", 0, 8374)
+
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("GetAvailableOffsets", mock.Anything).Return(priorityResponse, nil).Once()
+	broker.On("GetAvailableOffsets", mock.Anything).Return(otherResponse, nil).Once()
 
-	// Set up the mock to return the leader broker for a test topic and partition
 	client := &helpers.MockSaramaClient{}
-	var nilBroker *helpers.BurrowSaramaBroker
-	client.On("Leader", "testtopic", int32(0)).Return(broker, nil)
-	client.On("Leader", "testtopic", int32(1)).Return(nilBroker, errors.New("no leader error"))
+	client.On("Broker", int32(13)).Return(broker, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	fixtureClientLeaders(module, client)
+	go module.getOffsets(client)
+
+	// The broker is sent the request for the priority topic before the one for the rest of its partitions
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, "critical-topic", request.Topic, "Expected the priority topic first, not %v", request.Topic)
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, "testtopic", request.Topic, "Expected the other topic second, not %v", request.Topic)
+
+	firstRequest := broker.Calls[0].Arguments.Get(0).(*sarama.OffsetRequest)
+	expected := &sarama.OffsetRequest{Version: firstRequest.Version}
+	expected.AddBlock("critical-topic", 0, sarama.OffsetNewest, 1)
+	assert.Equal(t, expected, firstRequest, "Expected the first request to be for the priority topic")
+	broker.AssertExpectations(t)
+}
+
+func TestKafkaCluster_getOffsets(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.partition-change-refresh", partitionChangeRefreshFull)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = make(map[string][]int32)
+	module.topicPartitions["testtopic"] = []int32{0, 1}
+	module.topicLeaders = map[string][]int32{"testtopic": {13, 12}}
+	module.fetchMetadata.Store(false)
+
+	// Set up an OffsetResponse
+	offsetResponse := &sarama.OffsetResponse{Version: 1}
+	offsetResponse.AddTopicPartition("testtopic", 0, 8374)
+
+	// Set up a broker mock
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request != nil })).Return(offsetResponse, nil)
+
+	// Set up the mock to return the leader broker for a test topic and partition
+	client := &helpers.MockSaramaClient{}
+	var nilBroker *helpers.BurrowSaramaBroker
+	client.On("Broker", int32(13)).Return(broker, nil)
+	client.On("Broker", int32(12)).Return(nilBroker, errors.New("no broker error"))
 	client.On("Config").Return(sarama.NewConfig())
 
 	go module.getOffsets(client)
@@ -248,7 +877,7 @@ func TestKafkaCluster_getOffsets(t *testing.T) {
 	assert.Equalf(t, int32(0), request.Partition, "Expected request sent with partition 0, not %v", request.Partition)
 	assert.Equalf(t, int32(2), request.TopicPartitionCount, "Expected request sent with TopicPartitionCount 2, not %v", request.TopicPartitionCount)
 	assert.Equalf(t, int64(8374), request.Offset, "Expected request sent with offset 8374, not %v", request.Offset)
-	assert.True(t, module.fetchMetadata, "Expected fetchMetadata to be true")
+	assert.True(t, module.fetchMetadata.Load(), "Expected fetchMetadata to be true")
 
 	// Make sure there is nothing else on the channel
 	time.Sleep(100 * time.Millisecond)
@@ -260,32 +889,618 @@ func TestKafkaCluster_getOffsets(t *testing.T) {
 	}
 }
 
+func TestKafkaCluster_getOffsets_FetchOldest(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.fetch-oldest-offsets", true)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13}}
+	module.fetchMetadata.Store(false)
+
+	offsetResponse := &sarama.OffsetResponse{Version: 1}
+	offsetResponse.AddTopicPartition("testtopic", 0, 8374)
+
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request != nil })).Return(offsetResponse, nil)
+
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(13)).Return(broker, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	fixtureClientLeaders(module, client)
+	go module.getOffsets(client)
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetBrokerOffset, request.RequestType, "Expected request sent with type StorageSetBrokerOffset, not %v", request.RequestType)
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetBrokerOldestOffset, request.RequestType, "Expected request sent with type StorageSetBrokerOldestOffset, not %v", request.RequestType)
+	assert.Equalf(t, "testtopic", request.Topic, "Expected request sent with topic testtopic, not %v", request.Topic)
+	assert.Equalf(t, int32(1), request.TopicPartitionCount, "Expected request sent with TopicPartitionCount 1, not %v", request.TopicPartitionCount)
+
+	broker.AssertNumberOfCalls(t, "GetAvailableOffsets", 2)
+}
+
+func TestKafkaCluster_Configure_FetchEarliestOffsets(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.fetch-earliest-offsets", true)
+	module.Configure("test", "cluster.test")
+	assert.True(t, module.fetchOldest, "Expected fetch-earliest-offsets to turn on fetching the oldest offsets")
+}
+
+func TestKafkaCluster_getOffsets_Lookback(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-lookback", 3600)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13}}
+	module.fetchMetadata.Store(false)
+
+	offsetResponse := &sarama.OffsetResponse{Version: 1}
+	offsetResponse.AddTopicPartition("testtopic", 0, 8374)
+
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request != nil })).Return(offsetResponse, nil)
+
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(13)).Return(broker, nil)
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+	client.On("Config").Return(config)
+
+	startTime := time.Now().Unix() * 1000
+	fixtureClientLeaders(module, client)
+	go module.getOffsets(client)
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetBrokerOffset, request.RequestType, "Expected request sent with type StorageSetBrokerOffset, not %v", request.RequestType)
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetBrokerLookbackOffset, request.RequestType, "Expected request sent with type StorageSetBrokerLookbackOffset, not %v", request.RequestType)
+	assert.Equalf(t, "testtopic", request.Topic, "Expected request sent with topic testtopic, not %v", request.Topic)
+	assert.LessOrEqualf(t, request.Timestamp, startTime-3600000, "Expected request sent with the lookback time, not %v", request.Timestamp)
+
+	broker.AssertNumberOfCalls(t, "GetAvailableOffsets", 2)
+}
+
+func TestKafkaCluster_getOffsets_LookbackOldVersion(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-lookback", 3600)
+	module.Configure("test", "cluster.test")
+
+	client := &helpers.MockSaramaClient{}
+	config := sarama.NewConfig()
+	config.Version = sarama.V0_10_0_0
+	client.On("Config").Return(config)
+
+	assert.False(t, module.lookbackSupported(client), "Expected lookback to not be supported before Kafka 0.10.1")
+	assert.True(t, module.lookbackWarned, "Expected a warning to be logged")
+}
+
+func TestKafkaCluster_Configure_BadOffsetLookback(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-lookback", -1)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_refreshBrokerOffsets(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.partition-change-refresh", partitionChangeRefreshFull)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0, 1, 2}, "othertopic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13, 12, 13}, "othertopic": {12}}
+
+	offsetResponse := &sarama.OffsetResponse{Version: 1}
+	offsetResponse.AddTopicPartition("testtopic", 0, 8374)
+	offsetResponse.AddTopicPartition("testtopic", 2, 1234)
+	offsetResponse.Blocks["testtopic"][2].Err = sarama.ErrNotLeaderForPartition
+
+	// Only broker 13 is sent an offset request
+	broker13 := &helpers.MockSaramaBroker{}
+	broker13.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request != nil })).Return(offsetResponse, nil)
+	broker12 := &helpers.MockSaramaBroker{}
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(13)).Return(broker13, nil)
+	client.On("Broker", int32(12)).Return(broker12, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	request := &protocol.ClusterRequest{
+		RequestType: protocol.ClusterRefreshBrokerOffsets,
+		Cluster:     "test",
+		Broker:      13,
+		Reply:       make(chan interface{}),
+	}
+	go module.handleRequest(client, request)
+
+	storageRequest := <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetBrokerOffset, storageRequest.RequestType, "Expected request sent with type StorageSetBrokerOffset, not %v", storageRequest.RequestType)
+	assert.Equalf(t, int32(0), storageRequest.Partition, "Expected request sent with partition 0, not %v", storageRequest.Partition)
+	assert.Equalf(t, int64(8374), storageRequest.Offset, "Expected request sent with offset 8374, not %v", storageRequest.Offset)
+
+	response := <-request.Reply
+	assert.Equal(t, &protocol.ClusterBrokerOffsets{Broker: 13, Partitions: 2, PartitionErrors: 1}, response)
+	_, ok := <-request.Reply
+	assert.False(t, ok, "Expected reply channel to be closed")
+	broker13.AssertExpectations(t)
+	broker12.AssertNotCalled(t, "GetAvailableOffsets", mock.Anything)
+}
+
+func TestKafkaCluster_refreshBrokerOffsets_NotLeader(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13}}
+
+	broker := &helpers.MockSaramaBroker{}
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(13)).Return(broker, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	request := &protocol.ClusterRequest{
+		RequestType: protocol.ClusterRefreshBrokerOffsets,
+		Cluster:     "test",
+		Broker:      99,
+		Reply:       make(chan interface{}),
+	}
+	go module.handleRequest(client, request)
+
+	response := <-request.Reply
+	assert.Equal(t, &protocol.ClusterBrokerOffsets{Broker: 99}, response)
+	broker.AssertNotCalled(t, "GetAvailableOffsets", mock.Anything)
+}
+
+func TestKafkaCluster_refreshBrokerOffsets_BackedOff(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0, 1}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13, 13}}
+	module.brokerFetchFailed(13, time.Now())
+	until := module.brokerBackoffs[13].until
+
+	broker := &helpers.MockSaramaBroker{}
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(13)).Return(broker, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	request := &protocol.ClusterRequest{
+		RequestType: protocol.ClusterRefreshBrokerOffsets,
+		Cluster:     "test",
+		Broker:      13,
+		Reply:       make(chan interface{}),
+	}
+	go module.handleRequest(client, request)
+
+	// The reply says why the broker was not sent a request, rather than that it leads no partitions
+	response := <-request.Reply
+	assert.Equal(t, &protocol.ClusterBrokerOffsets{Broker: 13, Partitions: 2, Error: "broker is in backoff until " + until.Format(time.RFC3339)}, response)
+	broker.AssertNotCalled(t, "GetAvailableOffsets", mock.Anything)
+}
+
+func TestKafkaCluster_fetchAPIVersions(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	versions := &sarama.ApiVersionsResponse{ApiKeys: []sarama.ApiVersionsResponseKey{
+		{ApiKey: 3, MinVersion: 0, MaxVersion: 12},
+		{ApiKey: 0, MinVersion: 0, MaxVersion: 9},
+	}}
+	broker1 := &helpers.MockSaramaBroker{}
+	broker1.On("ID").Return(int32(1))
+	broker1.On("ApiVersions", mock.Anything).Return(versions, nil)
+	broker2 := &helpers.MockSaramaBroker{}
+	broker2.On("ID").Return(int32(2))
+	broker2.On("ApiVersions", mock.Anything).Return(versions, nil)
+	client := &helpers.MockSaramaClient{}
+	client.On("Brokers").Return([]helpers.SaramaBroker{broker2, broker1})
+	client.On("Broker", int32(1)).Return(broker1, nil)
+	client.On("Broker", int32(2)).Return(broker2, nil)
+
+	request := &protocol.ClusterRequest{RequestType: protocol.ClusterFetchBrokerAPIVersions, Cluster: "test", Reply: make(chan interface{})}
+	go module.handleRequest(client, request)
+	response := (<-request.Reply).(*protocol.ClusterAPIVersions)
+
+	assert.True(t, response.Uniform, "Expected the versions to be uniform")
+	assert.Len(t, response.Brokers, 2, "Expected 2 brokers")
+	assert.Equal(t, int32(1), response.Brokers[0].Broker, "Expected brokers to be sorted by ID")
+	assert.Equal(t, []*protocol.APIVersionRange{{APIKey: 0, MinVersion: 0, MaxVersion: 9}, {APIKey: 3, MinVersion: 0, MaxVersion: 12}}, response.Brokers[0].APIs)
+
+	// The second request gets the cached versions without asking the brokers
+	request = &protocol.ClusterRequest{RequestType: protocol.ClusterFetchBrokerAPIVersions, Cluster: "test", Reply: make(chan interface{})}
+	go module.handleRequest(client, request)
+	assert.Equal(t, response, <-request.Reply, "Expected the cached versions")
+	broker1.AssertNumberOfCalls(t, "ApiVersions", 1)
+}
+
+func TestKafkaCluster_fetchAPIVersions_Refresh(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.apiVersions = &protocol.ClusterAPIVersions{Uniform: true}
+
+	// One broker has been upgraded, and the other fails to respond
+	broker1 := &helpers.MockSaramaBroker{}
+	broker1.On("ID").Return(int32(1))
+	broker1.On("ApiVersions", mock.Anything).Return(&sarama.ApiVersionsResponse{ApiKeys: []sarama.ApiVersionsResponseKey{{ApiKey: 0, MaxVersion: 9}}}, nil)
+	broker2 := &helpers.MockSaramaBroker{}
+	broker2.On("ID").Return(int32(2))
+	broker2.On("ApiVersions", mock.Anything).Return(&sarama.ApiVersionsResponse{ApiKeys: []sarama.ApiVersionsResponseKey{{ApiKey: 0, MaxVersion: 8}}}, nil)
+	broker3 := &helpers.MockSaramaBroker{}
+	broker3.On("ID").Return(int32(3))
+	broker3.On("ApiVersions", mock.Anything).Return(&sarama.ApiVersionsResponse{}, errors.New("connection refused"))
+	client := &helpers.MockSaramaClient{}
+	client.On("Brokers").Return([]helpers.SaramaBroker{broker1, broker2, broker3})
+	client.On("Broker", int32(1)).Return(broker1, nil)
+	client.On("Broker", int32(2)).Return(broker2, nil)
+	client.On("Broker", int32(3)).Return(broker3, nil)
+
+	request := &protocol.ClusterRequest{RequestType: protocol.ClusterFetchBrokerAPIVersions, Cluster: "test", Refresh: true, Reply: make(chan interface{})}
+	go module.handleRequest(client, request)
+	response := (<-request.Reply).(*protocol.ClusterAPIVersions)
+
+	assert.False(t, response.Uniform, "Expected the versions to not be uniform")
+	assert.Len(t, response.Brokers, 3, "Expected 3 brokers")
+	assert.Equal(t, int16(8), response.Brokers[1].APIs[0].MaxVersion, "Expected broker 2 to support up to version 8")
+	assert.Equal(t, "connection refused", response.Brokers[2].Error, "Expected the error for broker 3")
+	assert.Equal(t, response, module.apiVersions, "Expected the refreshed versions to be cached")
+	_, ok := <-request.Reply
+	assert.False(t, ok, "Expected reply channel to be closed")
+
+	// Matching versions are not uniform if a broker did not respond
+	assert.False(t, apiVersionsUniform([]*protocol.BrokerAPIVersions{response.Brokers[0], response.Brokers[0], response.Brokers[2]}), "Expected the versions to not be uniform")
+}
+
 func TestKafkaCluster_getOffsets_BrokerFailed(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "cluster.test")
 	module.topicPartitions = make(map[string][]int32)
 	module.topicPartitions["testtopic"] = []int32{0}
-	module.fetchMetadata = false
+	module.topicLeaders = map[string][]int32{"testtopic": {13}}
+	module.fetchMetadata.Store(false)
 
 	// Set up a broker mock
 	broker := &helpers.MockSaramaBroker{}
-	broker.On("ID").Return(int32(13))
 	var offsetResponse *sarama.OffsetResponse
 	broker.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request != nil })).Return(offsetResponse, errors.New("broker failed"))
 	broker.On("Close").Return(nil)
 
 	// Set up the mock to return the leader broker for a test topic and partition
 	client := &helpers.MockSaramaClient{}
-	client.On("Leader", "testtopic", int32(0)).Return(broker, nil)
+	client.On("Broker", int32(13)).Return(broker, nil)
 	client.On("Config").Return(sarama.NewConfig())
+	fixtureClientLeaders(module, client)
 	module.getOffsets(client)
 
 	broker.AssertExpectations(t)
 	client.AssertExpectations(t)
 }
 
+func TestKafkaCluster_getOffsets_BrokerRetry(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("client-profile.p1.offset-retry-max", 2)
+	viper.Set("client-profile.p1.offset-retry-backoff", 1)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = make(map[string][]int32)
+	module.topicPartitions["testtopic"] = []int32{0}
+	module.topicLeaders = map[string][]int32{"testtopic": {13}}
+	module.fetchMetadata.Store(false)
+
+	// Set up a broker mock that fails the first request and then succeeds
+	offsetResponse := &sarama.OffsetResponse{
+		Version: 1,
+	}
+	offsetResponse.AddTopicPartition("testtopic", 0, 8374)
+
+	broker := &helpers.MockSaramaBroker{}
+	var failedResponse *sarama.OffsetResponse
+	broker.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request != nil })).Return(failedResponse, errors.New("broker failed")).Once()
+	broker.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request != nil })).Return(offsetResponse, nil).Once()
+	broker.On("Close").Return(nil)
+	broker.On("Open", mock.Anything).Return(nil)
+
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(13)).Return(broker, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	fixtureClientLeaders(module, client)
+	go module.getOffsets(client)
+	request := <-module.App.StorageChannel
+
+	broker.AssertExpectations(t)
+	broker.AssertNumberOfCalls(t, "GetAvailableOffsets", 2)
+	assert.Equalf(t, protocol.StorageSetBrokerOffset, request.RequestType, "Expected request sent with type StorageSetBrokerOffset, not %v", request.RequestType)
+	assert.Equalf(t, int64(8374), request.Offset, "Expected request sent with offset 8374, not %v", request.Offset)
+}
+
+func TestKafkaCluster_getOffsets_RefreshErrors(t *testing.T) {
+	for _, refreshErrors := range []int{2, 3} {
+		module := fixtureModule()
+		viper.Set("cluster.test.metadata-refresh-errors", refreshErrors)
+		viper.Set("cluster.test.partition-change-refresh", partitionChangeRefreshFull)
+		module.Configure("test", "cluster.test")
+		module.topicPartitions = map[string][]int32{"testtopic": {0, 1, 2}}
+		module.topicLeaders = map[string][]int32{"testtopic": {13, 13, 13}}
+		module.fetchMetadata.Store(false)
+
+		// Two of the three partitions return errors
+		offsetResponse := &sarama.OffsetResponse{Version: 1}
+		offsetResponse.AddTopicPartition("testtopic", 0, 8374)
+		offsetResponse.Blocks["testtopic"][1] = &sarama.OffsetResponseBlock{Err: sarama.ErrNotLeaderForPartition}
+		offsetResponse.Blocks["testtopic"][2] = &sarama.OffsetResponseBlock{Err: sarama.ErrNotLeaderForPartition}
+
+		broker := &helpers.MockSaramaBroker{}
+		broker.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request != nil })).Return(offsetResponse, nil)
+		client := &helpers.MockSaramaClient{}
+		client.On("Broker", int32(13)).Return(broker, nil)
+		client.On("Config").Return(sarama.NewConfig())
+
+		module.App.StorageChannel = make(chan *protocol.StorageRequest, 1)
+		module.getOffsets(client)
+		request := <-module.App.StorageChannel
+		assert.Equalf(t, int64(8374), request.Offset, "Expected request sent with offset 8374, not %v", request.Offset)
+		assert.Equalf(t, refreshErrors <= 2, module.fetchMetadata.Load(), "Expected fetchMetadata to be %v with metadata-refresh-errors %v", refreshErrors <= 2, refreshErrors)
+	}
+}
+
+func TestKafkaCluster_getOffsets_OutOfRangeRetry(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-out-of-range-backoff", 0)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13}}
+	module.fetchMetadata.Store(false)
+
+	// The partition is out of range during an election, and has an offset when it is retried
+	outOfRangeResponse := &sarama.OffsetResponse{Version: 1}
+	outOfRangeResponse.AddTopicPartition("testtopic", 0, 0)
+	outOfRangeResponse.Blocks["testtopic"][0] = &sarama.OffsetResponseBlock{Err: sarama.ErrOffsetOutOfRange}
+	offsetResponse := &sarama.OffsetResponse{Version: 1}
+	offsetResponse.AddTopicPartition("testtopic", 0, 8374)
+
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("GetAvailableOffsets", mock.Anything).Return(outOfRangeResponse, nil).Once()
+	broker.On("GetAvailableOffsets", mock.Anything).Return(offsetResponse, nil).Once()
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(13)).Return(broker, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 1)
+	fixtureClientLeaders(module, client)
+	module.getOffsets(client)
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, int64(8374), request.Offset, "Expected request sent with offset 8374, not %v", request.Offset)
+	assert.False(t, module.fetchMetadata.Load(), "Expected no metadata refresh for a partition that recovered")
+	broker.AssertNumberOfCalls(t, "GetAvailableOffsets", 2)
+}
+
+func TestKafkaCluster_getOffsets_OutOfRangePersists(t *testing.T) {
+	for _, retries := range []int{0, 2} {
+		module := fixtureModule()
+		viper.Set("cluster.test.offset-out-of-range-retries", retries)
+		viper.Set("cluster.test.offset-out-of-range-backoff", 0)
+		module.Configure("test", "cluster.test")
+		module.topicPartitions = map[string][]int32{"testtopic": {0}}
+		module.topicLeaders = map[string][]int32{"testtopic": {13}}
+		module.fetchMetadata.Store(false)
+
+		outOfRangeResponse := &sarama.OffsetResponse{Version: 1}
+		outOfRangeResponse.AddTopicPartition("testtopic", 0, 0)
+		outOfRangeResponse.Blocks["testtopic"][0] = &sarama.OffsetResponseBlock{Err: sarama.ErrOffsetOutOfRange}
+
+		broker := &helpers.MockSaramaBroker{}
+		broker.On("GetAvailableOffsets", mock.Anything).Return(outOfRangeResponse, nil)
+		client := &helpers.MockSaramaClient{}
+		client.On("Broker", int32(13)).Return(broker, nil)
+		client.On("Config").Return(sarama.NewConfig())
+
+		fixtureClientLeaders(module, client)
+		module.getOffsets(client)
+		assert.Truef(t, module.fetchMetadata.Load(), "Expected a metadata refresh with %v retries", retries)
+		broker.AssertNumberOfCalls(t, "GetAvailableOffsets", retries+1)
+	}
+}
+
+func TestKafkaCluster_getBrokerOffsets_MixedPartitionErrors(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.partition-change-refresh", partitionChangeRefreshFull)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0, 1, 2, 3}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13, 13, 13, 13}}
+
+	// The errors are on partitions of the same topic as the healthy ones
+	offsetResponse := &sarama.OffsetResponse{Version: 1}
+	offsetResponse.AddTopicPartition("testtopic", 0, 1000)
+	offsetResponse.AddTopicPartition("testtopic", 1, 0)
+	offsetResponse.Blocks["testtopic"][1].Err = sarama.ErrLeaderNotAvailable
+	offsetResponse.AddTopicPartition("testtopic", 2, 2000)
+	offsetResponse.AddTopicPartition("testtopic", 3, 0)
+	offsetResponse.Blocks["testtopic"][3].Err = sarama.ErrNotLeaderForPartition
+
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("GetAvailableOffsets", mock.Anything).Return(offsetResponse, nil)
+	client := &helpers.MockSaramaClient{}
+	client.On("Config").Return(sarama.NewConfig())
+
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 4)
+	partitionErrors, err := module.getBrokerOffsets(client, 13, broker, &sarama.OffsetRequest{}, protocol.StorageSetBrokerOffset)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, partitionErrors, "Expected only the errored partitions to be counted")
+
+	close(module.App.StorageChannel)
+	stored := make(map[int32]int64)
+	for request := range module.App.StorageChannel {
+		stored[request.Partition] = request.Offset
+	}
+	assert.Equal(t, map[int32]int64{0: 1000, 2: 2000}, stored, "Expected the healthy partitions to be stored")
+	broker.AssertNumberOfCalls(t, "GetAvailableOffsets", 1)
+}
+
+func TestKafkaCluster_Configure_BadOutOfRangeRetries(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-out-of-range-retries", -1)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+
+	module = fixtureModule()
+	viper.Set("cluster.test.offset-out-of-range-backoff", -1)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_getOffsets_MaxConcurrentFetches(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.max-concurrent-offset-fetches", 3)
+	module.Configure("test", "cluster.test")
+	module.fetchMetadata.Store(false)
+
+	// Ten brokers that each lead one partition, and take a while to respond
+	var inFlight, maxInFlight atomic.Int32
+	client := &helpers.MockSaramaClient{}
+	client.On("Config").Return(sarama.NewConfig())
+	module.topicPartitions = map[string][]int32{"testtopic": make([]int32, 10)}
+	module.topicLeaders = map[string][]int32{"testtopic": make([]int32, 10)}
+	brokers := make([]*helpers.MockSaramaBroker, 10)
+	for i := range brokers {
+		module.topicPartitions["testtopic"][i] = int32(i)
+		module.topicLeaders["testtopic"][i] = int32(i)
+
+		offsetResponse := &sarama.OffsetResponse{Version: 1}
+		offsetResponse.AddTopicPartition("testtopic", int32(i), 1000)
+		brokers[i] = &helpers.MockSaramaBroker{}
+		brokers[i].On("GetAvailableOffsets", mock.Anything).Return(offsetResponse, nil).Run(func(mock.Arguments) {
+			current := inFlight.Add(1)
+			for {
+				maxSeen := maxInFlight.Load()
+				if (current <= maxSeen) || maxInFlight.CompareAndSwap(maxSeen, current) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			inFlight.Add(-1)
+		})
+		client.On("Broker", int32(i)).Return(brokers[i], nil)
+	}
+
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+	fixtureClientLeaders(module, client)
+	module.getOffsets(client)
+
+	// Every broker was still asked for its offsets before getOffsets returned
+	assert.Len(t, module.App.StorageChannel, 10, "Expected an offset stored for every partition")
+	for _, broker := range brokers {
+		broker.AssertNumberOfCalls(t, "GetAvailableOffsets", 1)
+	}
+	assert.LessOrEqualf(t, maxInFlight.Load(), int32(3), "Expected no more than 3 offset fetches at a time, not %v", maxInFlight.Load())
+	assert.Equal(t, int32(0), inFlight.Load(), "Expected no offset fetches left running")
+}
+
+func TestKafkaCluster_getOffsets_ConcurrentMetadataRefresh(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-fetch-backoff-min", 0)
+	viper.Set("cluster.test.partition-change-refresh", partitionChangeRefreshFull)
+	module.Configure("test", "cluster.test")
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 100)
+
+	// Several brokers are fetched from their own goroutines while the metadata refresh is forced, as the metadata
+	// ticker does. Run with -race to check that fetchMetadata is synchronized
+	metadata := &sarama.MetadataResponse{}
+	metadataBroker := &helpers.MockSaramaBroker{}
+	metadataBroker.On("GetMetadata", mock.Anything).Return(metadata, nil)
+	client := &helpers.MockSaramaClient{}
+	client.On("Config").Return(sarama.NewConfig())
+	client.On("LeastLoadedBroker").Return(metadataBroker)
+	module.topicPartitions = map[string][]int32{"testtopic": make([]int32, 5)}
+	module.topicLeaders = map[string][]int32{"testtopic": make([]int32, 5)}
+	for i := 0; i < 5; i++ {
+		module.topicPartitions["testtopic"][i] = int32(i)
+		module.topicLeaders["testtopic"][i] = int32(i)
+		metadata.AddTopicPartition("testtopic", int32(i), int32(i), nil, nil, nil, sarama.ErrNoError)
+		response := &sarama.OffsetResponse{Version: 1}
+		response.AddTopicPartition("testtopic", int32(i), 100)
+		response.Blocks["testtopic"][int32(i)].Err = sarama.ErrNotLeaderForPartition
+		broker := &helpers.MockSaramaBroker{}
+		broker.On("GetAvailableOffsets", mock.Anything).Return(response, nil)
+		client.On("Broker", int32(i)).Return(broker, nil)
+	}
+
+	done := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				module.forceMetadataRefresh("test")
+				_ = module.fetchMetadata.Load()
+			}
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		module.fetchMetadata.Store(false)
+		module.getOffsets(client)
+	}
+	close(done)
+	wg.Wait()
+
+	assert.True(t, module.fetchMetadata.Load(), "Expected the partition errors to force a metadata refresh")
+}
+
+func TestKafkaCluster_Configure_BadMaxConcurrentFetches(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.max-concurrent-offset-fetches", 0)
+
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_Configure_BadRefreshErrors(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.metadata-refresh-errors", 0)
+
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_Configure_BadStaleOffsetIntervals(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.stale-offset-intervals", 0)
+
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_metadataBroker(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.metadata-rack", "us-west-1a")
+	module.Configure("test", "cluster.test")
+
+	remoteBroker := &helpers.MockSaramaBroker{}
+	remoteBroker.On("ID").Return(int32(1))
+	remoteBroker.On("Rack").Return("us-east-1a")
+	localBroker := &helpers.MockSaramaBroker{}
+	localBroker.On("ID").Return(int32(2))
+	localBroker.On("Rack").Return("us-west-1a")
+	leastLoaded := &helpers.MockSaramaBroker{}
+
+	client := &helpers.MockSaramaClient{}
+	client.On("Brokers").Return([]helpers.SaramaBroker{remoteBroker, localBroker})
+	client.On("Broker", int32(2)).Return(localBroker, nil)
+	client.On("LeastLoadedBroker").Return(leastLoaded)
+
+	broker := module.metadataBroker(client)
+	assert.Equal(t, localBroker, broker, "Expected the broker in the metadata rack")
+	client.AssertNotCalled(t, "LeastLoadedBroker")
+
+	// Without a broker in the rack, the least loaded broker is used
+	module.metadataRack = "eu-west-1a"
+	broker = module.metadataBroker(client)
+	assert.Equal(t, leastLoaded, broker, "Expected the least loaded broker")
+
+	// Without a rack, the brokers are not checked
+	module.metadataRack = ""
+	client = &helpers.MockSaramaClient{}
+	client.On("LeastLoadedBroker").Return(leastLoaded)
+	broker = module.metadataBroker(client)
+	assert.Equal(t, leastLoaded, broker, "Expected the least loaded broker")
+	client.AssertNotCalled(t, "Brokers")
+}
+
 func TestKafkaCluster_reapNonExistingGroups(t *testing.T) {
 	module := fixtureModule()
+	viper.Set("consumer.testconsumer.class-name", "kafka")
+	viper.Set("consumer.testconsumer.cluster", "test")
 	module.Configure("test", "cluster.test")
 
 	// only group1 exists in kafka
@@ -299,11 +1514,165 @@ func TestKafkaCluster_reapNonExistingGroups(t *testing.T) {
 	assert.Equalf(t, protocol.StorageFetchConsumers, request.RequestType, "Expected request sent with type StorageFetchConsumers, not %v", request.RequestType)
 	assert.Equalf(t, "test", request.Cluster, "Expected request sent with cluster test, not %v", request.Cluster)
 
-	// burrow have group1, and group2, kafka only knows about group1, so group2 will be deleted by the reaper
-	request.Reply <- []string{"group1", "group2"}
+	// burrow have group1, and group2, kafka only knows about group1, so group2 will be deleted by the reaper. Burrow's
+	// own group is never deleted
+	request.Reply <- []string{"group1", "burrow-testconsumer", "group2"}
 	request = <-module.App.StorageChannel
 
 	assert.Equalf(t, protocol.StorageSetDeleteGroup, request.RequestType, "Expected request sent with type StorageFetchConsumers, not %v", request.RequestType)
 	assert.Equalf(t, "test", request.Cluster, "Expected request sent with cluster test, not %v", request.Cluster)
 	assert.Equalf(t, "group2", request.Group, "Expected request sent with group group2, not %v", request.Group)
 }
+
+func TestKafkaCluster_reapNonExistingGroups_TrimTopics(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.groups-reaper-trim-topics", true)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+
+	client := &helpers.MockSaramaClient{}
+	client.On("ListConsumerGroups").Return(map[string]string{"group1": ""}, nil)
+
+	go module.reapNonExistingGroups(client)
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageFetchConsumers, request.RequestType, "Expected request sent with type StorageFetchConsumers, not %v", request.RequestType)
+	request.Reply <- []string{"group1", "group2"}
+
+	// group2 is deleted, and then only the deleted topic is trimmed from group1
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetDeleteGroup, request.RequestType, "Expected request sent with type StorageSetDeleteGroup, not %v", request.RequestType)
+	assert.Equalf(t, "group2", request.Group, "Expected request sent with group group2, not %v", request.Group)
+
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageFetchConsumer, request.RequestType, "Expected request sent with type StorageFetchConsumer, not %v", request.RequestType)
+	assert.Equalf(t, "group1", request.Group, "Expected request sent with group group1, not %v", request.Group)
+	request.Reply <- protocol.ConsumerTopics{
+		"testtopic":    {{CurrentLag: 0}},
+		"deletedtopic": {{CurrentLag: 100}},
+	}
+
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetDeleteGroup, request.RequestType, "Expected request sent with type StorageSetDeleteGroup, not %v", request.RequestType)
+	assert.Equalf(t, "group1", request.Group, "Expected request sent with group group1, not %v", request.Group)
+	assert.Equalf(t, "deletedtopic", request.Topic, "Expected request sent with topic deletedtopic, not %v", request.Topic)
+}
+
+func TestKafkaCluster_reapNonExistingGroups_EmptyList(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	// Kafka returns no groups at all, so nothing is deleted
+	client := &helpers.MockSaramaClient{}
+	client.On("ListConsumerGroups").Return(map[string]string{}, nil)
+
+	go module.reapNonExistingGroups(client)
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageFetchConsumers, request.RequestType, "Expected request sent with type StorageFetchConsumers, not %v", request.RequestType)
+	request.Reply <- []string{"group1", "group2"}
+
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case request = <-module.App.StorageChannel:
+		t.Fatalf("Expected no groups to be deleted, got request %v", request.RequestType)
+	default:
+	}
+}
+
+func TestKafkaCluster_reapNonExistingGroups_MinRatio(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.groups-reaper-min-ratio", 0.5)
+	module.Configure("test", "cluster.test")
+
+	// Kafka only lists one of the three groups in storage, which is less than half of them
+	client := &helpers.MockSaramaClient{}
+	client.On("ListConsumerGroups").Return(map[string]string{"group1": ""}, nil)
+
+	go module.reapNonExistingGroups(client)
+	request := <-module.App.StorageChannel
+	request.Reply <- []string{"group1", "group2", "group3"}
+
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case request = <-module.App.StorageChannel:
+		t.Fatalf("Expected no groups to be deleted, got request %v", request.RequestType)
+	default:
+	}
+}
+
+func TestKafkaCluster_Configure_BadGroupsReaperMinRatio(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.groups-reaper-min-ratio", 1.5)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_trimDeletedTopics_NoMetadata(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	// Nothing is trimmed before the topics in the cluster are known, so no requests are sent to storage
+	module.trimDeletedTopics([]string{"group1"})
+}
+
+// countingListener counts the connections accepted by a mock broker
+type countingListener struct {
+	net.Listener
+	accepted atomic.Int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.accepted.Add(1)
+	}
+	return conn, err
+}
+
+func TestKafkaCluster_getOffsets_ReusesConnections(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err, "Expected listener setup to return no error")
+	counter := &countingListener{Listener: listener}
+	mockBroker := sarama.NewMockBrokerListener(t, 13, counter)
+	defer mockBroker.Close()
+	mockBroker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(mockBroker.Addr(), mockBroker.BrokerID()).
+			SetLeader("testtopic", 0, mockBroker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("testtopic", 0, sarama.OffsetNewest, 8374),
+	})
+
+	module := fixtureModule()
+	viper.Set("client-profile.p1.kafka-version", "1.0.0")
+	viper.Set("client-profile.p1.keepalive", 30)
+	viper.Set("cluster.test.servers", []string{mockBroker.Addr()})
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13}}
+	module.fetchMetadata.Store(false)
+
+	// The mock broker only handles the requests set up above
+	module.saramaConfig.ApiVersionsRequest = false
+	saramaClient, err := sarama.NewClient([]string{mockBroker.Addr()}, module.saramaConfig)
+	assert.NoError(t, err, "Expected client setup to return no error")
+	defer saramaClient.Close()
+	client := &helpers.BurrowSaramaClient{Client: saramaClient}
+
+	// Every offset refresh stores the offset, over the same connections to the broker
+	var accepted int32
+	for cycle := 0; cycle < 3; cycle++ {
+		done := make(chan struct{})
+		go func() {
+			module.getOffsets(client)
+			close(done)
+		}()
+		request := <-module.App.StorageChannel
+		assert.Equalf(t, int64(8374), request.Offset, "Expected offset 8374 in cycle %v, not %v", cycle, request.Offset)
+		<-done
+
+		if cycle == 0 {
+			accepted = counter.accepted.Load()
+		} else {
+			assert.Equalf(t, accepted, counter.accepted.Load(), "Expected no new connections in cycle %v", cycle)
+		}
+	}
+}
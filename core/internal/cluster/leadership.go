@@ -0,0 +1,109 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/internal/httpserver"
+)
+
+type partitionLeader struct {
+	Partition int32 `json:"partition"`
+	Leader    int32 `json:"leader"`
+}
+
+// leadershipSnapshot is the content of the leadership-file. It is the leader for each partition as of the last
+// metadata refresh, which is what the offset requests are sent with
+type leadershipSnapshot struct {
+	Cluster   string                       `json:"cluster"`
+	Timestamp int64                        `json:"timestamp"`
+	Topics    map[string][]partitionLeader `json:"topics"`
+}
+
+// writeLeadershipFile writes the partition leaders to the leadership-file, if one is configured. The file is written
+// to a temporary name and then renamed, so that readers never see a partial file. Errors are logged, and the last
+// file written is left in place until the next interval.
+func (module *KafkaCluster) writeLeadershipFile() {
+	if (module.leadershipFile == "") || (module.topicPartitions == nil) {
+		return
+	}
+
+	snapshot := leadershipSnapshot{
+		Cluster:   module.name,
+		Timestamp: time.Now().Unix() * 1000,
+		Topics:    make(map[string][]partitionLeader, len(module.topicPartitions)),
+	}
+	for topic, partitions := range module.topicPartitions {
+		leaders := make([]partitionLeader, 0, len(partitions))
+		for i, partitionID := range partitions {
+			leaders = append(leaders, partitionLeader{Partition: partitionID, Leader: module.topicLeaders[topic][i]})
+		}
+		sort.Slice(leaders, func(i, j int) bool { return leaders[i].Partition < leaders[j].Partition })
+		snapshot.Topics[topic] = leaders
+	}
+
+	content, err := json.Marshal(snapshot)
+	if err == nil {
+		err = os.WriteFile(module.leadershipFile+".tmp", content, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(module.leadershipFile+".tmp", module.leadershipFile)
+	}
+	if err != nil {
+		module.Log.Error("failed to write leadership file",
+			zap.String("filename", module.leadershipFile),
+			zap.Error(err),
+		)
+		return
+	}
+	module.Log.Debug("wrote leadership file", zap.String("filename", module.leadershipFile), zap.Int("topics", len(snapshot.Topics)))
+}
+
+// leaderPartitionCounts returns the number of tracked partitions that each broker leads as of the last metadata
+// refresh, which is how the offset requests are divided between the brokers. The partitions without a leader are not
+// counted.
+func (module *KafkaCluster) leaderPartitionCounts() map[int32]int {
+	counts := make(map[int32]int)
+	for _, leaders := range module.topicLeaders {
+		for _, leader := range leaders {
+			counts[leader]++
+		}
+	}
+	return counts
+}
+
+// maxLeaderShare returns the largest share (from 0 to 1) of the partitions in the counts that any one broker leads. A
+// broker that leads much more than 1/N of the partitions for N brokers takes on more of the offset requests, and its
+// failure has more impact.
+func maxLeaderShare(counts map[int32]int) float64 {
+	total, largest := 0, 0
+	for _, count := range counts {
+		total += count
+		if count > largest {
+			largest = count
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(largest) / float64(total)
+}
+
+// recordLeadership sets the metrics for how the tracked partitions' leadership is spread between the brokers
+func (module *KafkaCluster) recordLeadership() {
+	counts := module.leaderPartitionCounts()
+	httpserver.SetClusterLeadership(module.name, counts, maxLeaderShare(counts))
+}
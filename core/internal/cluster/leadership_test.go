@@ -0,0 +1,83 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKafkaCluster_writeLeadershipFile(t *testing.T) {
+	leadershipFile := filepath.Join(t.TempDir(), "leaders.json")
+
+	module := fixtureModule()
+	viper.Set("cluster.test.leadership-file", leadershipFile)
+	module.Configure("test", "cluster.test")
+
+	// Nothing is written before the first metadata refresh
+	module.writeLeadershipFile()
+	_, err := os.Stat(leadershipFile)
+	assert.True(t, os.IsNotExist(err), "Expected no leadership file to be written without metadata")
+
+	module.topicPartitions = map[string][]int32{"testtopic": {1, 0}, "othertopic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {12, 13}, "othertopic": {12}}
+	module.writeLeadershipFile()
+
+	content, err := os.ReadFile(leadershipFile)
+	assert.Nil(t, err, "Expected leadership file to be written")
+	var snapshot leadershipSnapshot
+	assert.Nil(t, json.Unmarshal(content, &snapshot), "Expected leadership file to be valid JSON")
+	assert.Equal(t, "test", snapshot.Cluster, "Expected cluster to be test")
+	assert.NotZero(t, snapshot.Timestamp, "Expected timestamp to be set")
+	assert.Equalf(t, map[string][]partitionLeader{
+		"testtopic":  {{Partition: 0, Leader: 13}, {Partition: 1, Leader: 12}},
+		"othertopic": {{Partition: 0, Leader: 12}},
+	}, snapshot.Topics, "Unexpected leaders %v", snapshot.Topics)
+}
+
+func TestKafkaCluster_writeLeadershipFile_BadPath(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.leadership-file", filepath.Join(t.TempDir(), "nodir", "leaders.json"))
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13}}
+
+	// The error is only logged
+	assert.NotPanics(t, module.writeLeadershipFile, "Expected a failed write to not panic")
+}
+
+func TestKafkaCluster_Configure_BadLeadershipFileInterval(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.leadership-file", "leaders.json")
+	viper.Set("cluster.test.leadership-file-interval", 0)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_leaderPartitionCounts(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"topic1": {0, 1, 2}, "topic2": {0, 1, 2, 3, 4}}
+	module.topicLeaders = map[string][]int32{"topic1": {1, 2, 1}, "topic2": {1, 1, 3, 1, 2}}
+
+	counts := module.leaderPartitionCounts()
+	assert.Equal(t, map[int32]int{1: 5, 2: 2, 3: 1}, counts)
+	assert.Equal(t, 0.625, maxLeaderShare(counts), "Expected broker 1 to lead 5 of 8 partitions")
+}
+
+func TestMaxLeaderShare(t *testing.T) {
+	assert.Equal(t, float64(0), maxLeaderShare(map[int32]int{}), "Expected no share without any partitions")
+	assert.Equal(t, float64(1), maxLeaderShare(map[int32]int{1: 10}), "Expected a single broker to lead every partition")
+	assert.Equal(t, 0.25, maxLeaderShare(map[int32]int{1: 3, 2: 3, 3: 3, 4: 3}), "Expected an even share for balanced brokers")
+}
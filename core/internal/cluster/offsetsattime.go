@@ -0,0 +1,114 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// The offsets for a time are looked up on request, for reconstructing what a consumer's lag was at some point in the
+// past, such as after an incident. The OffsetRequests are built, batched by broker and sent the same way as for the
+// regular offset refresh, but with the time in place of sarama.OffsetNewest, and the offsets in the responses are
+// returned rather than sent to storage.
+
+// errTimestampUnsupported is returned by getOffsetsAtTimestamp if the client's Kafka version cannot look up offsets by
+// timestamp. Before 0.10.1, a timestamp only finds the offset of the log segment that contains it.
+var errTimestampUnsupported = errors.New("looking up offsets by timestamp needs Kafka 0.10.1 or later")
+
+// getOffsetsAtTimestamp returns the earliest offset whose timestamp is at or after ts (in milliseconds) for each
+// partition of the topics that the include func returns true for, indexed by partition ID. Kafka returns -1 for a
+// partition with no messages that new, and the partitions that could not be fetched are left at -1. If any were not,
+// the offsets that were fetched are returned with an error.
+func (module *KafkaCluster) getOffsetsAtTimestamp(client helpers.SaramaClient, ts int64, include func(string) bool) (map[string][]int64, error) {
+	offsets := make(map[string][]int64)
+	expected := 0
+	for topic, partitions := range module.topicPartitions {
+		if !include(topic) {
+			continue
+		}
+		offsets[topic] = make([]int64, cap(partitions))
+		for i := range offsets[topic] {
+			offsets[topic][i] = -1
+		}
+		expected += cap(partitions)
+	}
+
+	var lock sync.Mutex
+	fetched := 0
+	err := module.requestOffsetsAtTimestamp(client, ts, include, func(topic string, partition int32, offset int64) {
+		lock.Lock()
+		defer lock.Unlock()
+		if int(partition) >= len(offsets[topic]) {
+			return
+		}
+		offsets[topic][partition] = offset
+		fetched++
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if fetched < expected {
+		return offsets, fmt.Errorf("failed to fetch offsets at timestamp for %v of %v partitions", expected-fetched, expected)
+	}
+	return offsets, nil
+}
+
+// requestOffsetsAtTimestamp sends the OffsetRequests for ts (in milliseconds) for the partitions of the topics that the
+// include func returns true for, and calls the handle func with each offset that is fetched. The brokers are sent
+// their requests in parallel, so the handle func must be safe to call from more than one goroutine. The partitions
+// that could not be fetched are left out.
+func (module *KafkaCluster) requestOffsetsAtTimestamp(client helpers.SaramaClient, ts int64, include func(string) bool, handle func(topic string, partition int32, offset int64)) error {
+	if !client.Config().Version.IsAtLeast(sarama.V0_10_1_0) {
+		return fmt.Errorf("%w (the client uses %v)", errTimestampUnsupported, client.Config().Version)
+	}
+
+	// The requests go through the same path as the regular offset refresh, so they are retried, and the brokers that
+	// fail are backed off from, in the same way
+	requests, brokers := module.generateTopicOffsetRequests(client, ts, include)
+	module.forEachBroker(brokers, func(brokerID int32, broker helpers.SaramaBroker) {
+		module.requestBrokerOffsets(client, brokerID, broker, requests[brokerID], ts, handle)
+	})
+	return nil
+}
+
+// fetchTopicOffsetsAtTime replies with the offsets for the time in the request for each partition of one topic. The
+// reply has no offsets if the topic is not known as of the last metadata refresh.
+func (module *KafkaCluster) fetchTopicOffsetsAtTime(client helpers.SaramaClient, request *protocol.ClusterRequest) {
+	defer close(request.Reply)
+
+	result := &protocol.ClusterTopicOffsets{Topic: request.Topic, Timestamp: request.Timestamp}
+	if _, ok := module.topicPartitions[request.Topic]; !ok {
+		request.Reply <- result
+		return
+	}
+
+	offsets, err := module.getOffsetsAtTimestamp(client, request.Timestamp, func(topic string) bool { return topic == request.Topic })
+	result.Offsets = offsets[request.Topic]
+	if err != nil {
+		result.Error = err.Error()
+		result.Unsupported = errors.Is(err, errTimestampUnsupported)
+	}
+
+	module.Log.Debug("fetched offsets at timestamp",
+		zap.String("topic", request.Topic),
+		zap.Int64("timestamp", request.Timestamp),
+		zap.String("error", result.Error),
+	)
+	request.Reply <- result
+}
@@ -0,0 +1,144 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// fixtureTimestampModule returns a module with testtopic partitions 0 and 2 led by broker 13, and partition 1 by
+// broker 12, and a client for the Kafka version that returns the brokers
+func fixtureTimestampModule(version sarama.KafkaVersion, broker13, broker12 *helpers.MockSaramaBroker) (*KafkaCluster, *helpers.MockSaramaClient) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0, 1, 2}, "othertopic": {0}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13, 12, 13}, "othertopic": {12}}
+
+	config := sarama.NewConfig()
+	config.Version = version
+	client := &helpers.MockSaramaClient{}
+	client.On("Config").Return(config)
+	client.On("Broker", int32(13)).Return(broker13, nil)
+	client.On("Broker", int32(12)).Return(broker12, nil)
+	return module, client
+}
+
+func TestKafkaCluster_getOffsetsAtTimestamp(t *testing.T) {
+	response13 := &sarama.OffsetResponse{Version: 4}
+	response13.AddTopicPartition("testtopic", 0, 8374)
+	response13.AddTopicPartition("testtopic", 2, -1)
+	response12 := &sarama.OffsetResponse{Version: 4}
+	response12.AddTopicPartition("testtopic", 1, 1234)
+
+	broker13 := &helpers.MockSaramaBroker{}
+	broker13.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request.Version == 4 })).Return(response13, nil)
+	broker12 := &helpers.MockSaramaBroker{}
+	broker12.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request.Version == 4 })).Return(response12, nil)
+	module, client := fixtureTimestampModule(sarama.V2_1_0_0, broker13, broker12)
+
+	offsets, err := module.getOffsetsAtTimestamp(client, 1500000000000, func(topic string) bool { return topic == "testtopic" })
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]int64{"testtopic": {8374, 1234, -1}}, offsets, "Expected offsets indexed by partition, with -1 for no newer messages")
+
+	broker13.AssertNumberOfCalls(t, "GetAvailableOffsets", 1)
+	broker12.AssertNumberOfCalls(t, "GetAvailableOffsets", 1)
+}
+
+func TestKafkaCluster_getOffsetsAtTimestamp_BrokerFailed(t *testing.T) {
+	response13 := &sarama.OffsetResponse{Version: 1}
+	response13.AddTopicPartition("testtopic", 0, 8374)
+	response13.AddTopicPartition("testtopic", 2, 9000)
+
+	broker13 := &helpers.MockSaramaBroker{}
+	broker13.On("GetAvailableOffsets", mock.Anything).Return(response13, nil)
+	broker12 := &helpers.MockSaramaBroker{}
+	broker12.On("GetAvailableOffsets", mock.Anything).Return((*sarama.OffsetResponse)(nil), errors.New("broker down"))
+	broker12.On("Close").Return(nil)
+	broker12.On("Open", mock.Anything).Return(nil)
+	module, client := fixtureTimestampModule(sarama.V0_10_1_0, broker13, broker12)
+	module.offsetRetryMax = 2
+	module.offsetRetryBackoff = 0
+
+	offsets, err := module.getOffsetsAtTimestamp(client, 1500000000000, func(topic string) bool { return topic == "testtopic" })
+	assert.EqualError(t, err, "failed to fetch offsets at timestamp for 1 of 3 partitions")
+	assert.Equal(t, map[string][]int64{"testtopic": {8374, -1, 9000}}, offsets, "Expected the offsets that were fetched")
+
+	// The failed broker is retried, and then backed off from like in the regular offset refresh
+	broker12.AssertNumberOfCalls(t, "GetAvailableOffsets", 3)
+	assert.True(t, module.brokerBackedOff(12, time.Now()), "Expected the failed broker to be backed off")
+	assert.False(t, module.brokerBackedOff(13, time.Now()), "Expected the healthy broker not to be backed off")
+}
+
+func TestKafkaCluster_getOffsetsAtTimestamp_OldVersion(t *testing.T) {
+	broker := &helpers.MockSaramaBroker{}
+	module, client := fixtureTimestampModule(sarama.V0_10_0_0, broker, broker)
+
+	_, err := module.getOffsetsAtTimestamp(client, 1500000000000, func(string) bool { return true })
+	assert.ErrorIs(t, err, errTimestampUnsupported)
+	broker.AssertNotCalled(t, "GetAvailableOffsets", mock.Anything)
+}
+
+func TestKafkaCluster_fetchTopicOffsetsAtTime(t *testing.T) {
+	response := &sarama.OffsetResponse{Version: 4}
+	response.AddTopicPartition("othertopic", 0, 42)
+	broker12 := &helpers.MockSaramaBroker{}
+	broker12.On("GetAvailableOffsets", mock.Anything).Return(response, nil)
+	module, client := fixtureTimestampModule(sarama.V2_1_0_0, &helpers.MockSaramaBroker{}, broker12)
+
+	request := &protocol.ClusterRequest{
+		RequestType: protocol.ClusterFetchTopicOffsetsAtTime,
+		Cluster:     "test",
+		Topic:       "othertopic",
+		Timestamp:   1500000000000,
+		Reply:       make(chan interface{}),
+	}
+	go module.handleRequest(client, request)
+	assert.Equal(t, &protocol.ClusterTopicOffsets{Topic: "othertopic", Timestamp: 1500000000000, Offsets: []int64{42}}, <-request.Reply)
+	_, ok := <-request.Reply
+	assert.False(t, ok, "Expected reply channel to be closed")
+
+	// A topic that is not known has no offsets
+	request = &protocol.ClusterRequest{
+		RequestType: protocol.ClusterFetchTopicOffsetsAtTime,
+		Cluster:     "test",
+		Topic:       "notopic",
+		Timestamp:   1500000000000,
+		Reply:       make(chan interface{}),
+	}
+	go module.handleRequest(client, request)
+	assert.Equal(t, &protocol.ClusterTopicOffsets{Topic: "notopic", Timestamp: 1500000000000}, <-request.Reply)
+}
+
+func TestKafkaCluster_fetchTopicOffsetsAtTime_Unsupported(t *testing.T) {
+	broker := &helpers.MockSaramaBroker{}
+	module, client := fixtureTimestampModule(sarama.V0_10_0_0, broker, broker)
+
+	request := &protocol.ClusterRequest{
+		RequestType: protocol.ClusterFetchTopicOffsetsAtTime,
+		Cluster:     "test",
+		Topic:       "testtopic",
+		Timestamp:   1500000000000,
+		Reply:       make(chan interface{}),
+	}
+	go module.handleRequest(client, request)
+	response := (<-request.Reply).(*protocol.ClusterTopicOffsets)
+	assert.True(t, response.Unsupported, "Expected the lookup to be unsupported")
+	assert.Contains(t, response.Error, "0.10.1")
+	assert.Nil(t, response.Offsets)
+}
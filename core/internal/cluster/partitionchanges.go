@@ -0,0 +1,195 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"sort"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+)
+
+// The partitions of a topic can change between the metadata refresh and the offset fetch, such as when partitions are
+// added or a leader moves. By default (partition-change-refresh of "topic"), the metadata for only the topics that
+// changed is fetched again, and their offsets are fetched with the new partitions and leaders in the same refresh.
+// Topics with a leader that the client no longer knows, or that the client's own metadata shows has moved to another
+// broker, are found and refreshed before the offset fetch, and topics that the brokers report errors for are
+// refreshed right after it. If partition-change-refresh is "full", a full metadata refresh is forced on the next
+// offset fetch instead. If more than partition-change-max-topics topics change at once, a full metadata refresh
+// is done instead, as it is cheaper than that many topic refreshes.
+const (
+	partitionChangeRefreshFull  = "full"
+	partitionChangeRefreshTopic = "topic"
+)
+
+// partitionsChanged records that the partitions of the topic do not match the last metadata refresh. It returns false
+// if the cluster does full metadata refreshes for these changes, in which case the caller must handle it as before. It
+// is called from the goroutines that fetch offsets from each broker.
+func (module *KafkaCluster) partitionsChanged(topic string) bool {
+	if module.partitionChangeRefresh != partitionChangeRefreshTopic {
+		return false
+	}
+
+	module.changedTopicsLock.Lock()
+	defer module.changedTopicsLock.Unlock()
+	if module.changedTopics == nil {
+		module.changedTopics = make(map[string]bool)
+	}
+	module.changedTopics[topic] = true
+	return true
+}
+
+// takeChangedTopics returns the topics recorded by partitionsChanged since it was last called, and clears them
+func (module *KafkaCluster) takeChangedTopics() map[string]bool {
+	module.changedTopicsLock.Lock()
+	defer module.changedTopicsLock.Unlock()
+	changed := module.changedTopics
+	module.changedTopics = nil
+	return changed
+}
+
+// movedLeaderTopics returns the topics that the due func returns true for that have a partition whose leader the
+// client no longer knows, or whose leader in the client's metadata is a different broker, such as after a leader
+// election moved it. It returns nil if the cluster does full metadata refreshes for partition changes, in which case
+// the leader lookup fails when the offset requests are generated.
+func (module *KafkaCluster) movedLeaderTopics(client helpers.SaramaClient, due func(string) bool) map[string]bool {
+	if module.partitionChangeRefresh != partitionChangeRefreshTopic {
+		return nil
+	}
+
+	var moved map[string]bool
+	knownLeaders := make(map[int32]bool)
+	for topic, leaders := range module.topicLeaders {
+		if !due(topic) {
+			continue
+		}
+		for i, leaderID := range leaders {
+			known, ok := knownLeaders[leaderID]
+			if !ok {
+				_, err := client.Broker(leaderID)
+				known = err == nil
+				knownLeaders[leaderID] = known
+			}
+			if known {
+				// The leader can also move to another broker that is still up, which the offset request would only
+				// find out about from an error
+				leader, err := client.Leader(topic, module.topicPartitions[topic][i])
+				known = (err != nil) || (leader.ID() == leaderID)
+			}
+			if !known {
+				if moved == nil {
+					moved = make(map[string]bool)
+				}
+				moved[topic] = true
+				break
+			}
+		}
+	}
+	return moved
+}
+
+// refreshChangedTopics fetches the metadata for the topics that changed, as refreshTopicMetadata does, unless there
+// are more than partition-change-max-topics of them, in which case a full refresh is forced on the next offset fetch
+// instead. It returns true if any of the topics were updated.
+func (module *KafkaCluster) refreshChangedTopics(client helpers.SaramaClient, topics map[string]bool) bool {
+	if (module.partitionChangeMaxTopics > 0) && (len(topics) > module.partitionChangeMaxTopics) {
+		module.Log.Info("too many topics changed, forcing full metadata refresh",
+			zap.Int("topics", len(topics)),
+			zap.Int("partition_change_max_topics", module.partitionChangeMaxTopics),
+		)
+		module.forceMetadataRefresh("partitions-changed")
+		return false
+	}
+	return module.refreshTopicMetadata(client, topics)
+}
+
+// refreshTopicMetadata fetches the metadata for only the given topics, and replaces their partitions and leaders. If
+// the metadata cannot be fetched, or a topic has an error (such as having been deleted), a full refresh is forced on
+// the next offset fetch instead. It returns true if any of the topics were updated.
+func (module *KafkaCluster) refreshTopicMetadata(client helpers.SaramaClient, topics map[string]bool) bool {
+	names := make([]string, 0, len(topics))
+	for topic := range topics {
+		names = append(names, topic)
+	}
+	sort.Strings(names)
+
+	broker := module.metadataBroker(client)
+	if broker == nil {
+		module.Log.Warn("failed to fetch topic metadata", zap.Strings("topics", names), zap.String("sarama_error", sarama.ErrOutOfBrokers.Error()))
+		module.forceMetadataRefresh("partitions-changed")
+		return false
+	}
+	metadata, err := broker.GetMetadata(sarama.NewMetadataRequest(client.Config().Version, names))
+	if err != nil {
+		module.Log.Warn("failed to fetch topic metadata", zap.Strings("topics", names), zap.String("sarama_error", err.Error()))
+		module.forceMetadataRefresh("partitions-changed")
+		return false
+	}
+
+	// Offset requests are sent to the leaders via the client, so make sure it knows about any new brokers
+	for _, metadataBroker := range metadata.Brokers {
+		if _, err := client.Broker(metadataBroker.ID()); err != nil {
+			client.RefreshMetadata(names...)
+			break
+		}
+	}
+
+	updated := false
+	for _, topic := range metadata.Topics {
+		if !topics[topic.Name] {
+			continue
+		}
+		if (topic.Err != sarama.ErrNoError) && (topic.Err != sarama.ErrLeaderNotAvailable) {
+			module.Log.Debug("topic metadata error, forcing full refresh",
+				zap.String("topic", topic.Name),
+				zap.String("sarama_error", topic.Err.Error()),
+			)
+			module.forceMetadataRefresh("partitions-changed")
+			continue
+		}
+
+		partitions, leaders := module.topicPartitionLeaders(topic)
+		previous := module.topicPartitions[topic.Name]
+		if cap(previous) != cap(partitions) {
+			module.Log.Info("partition count changed",
+				zap.String("topic", topic.Name),
+				zap.Int("previous", cap(previous)),
+				zap.Int("partitions", cap(partitions)),
+			)
+		}
+		module.reapPartitions(topic.Name, previous, partitions, time.Now())
+		module.topicPartitions[topic.Name] = partitions
+		module.topicLeaders[topic.Name] = leaders
+		updated = true
+	}
+	return updated
+}
+
+// topicPartitionLeaders returns the IDs of the partitions of a topic from its metadata, and the leader of each. The
+// partitions without a leader are left out, but the capacity of the partitions slice is always the partition count.
+func (module *KafkaCluster) topicPartitionLeaders(topic *sarama.TopicMetadata) ([]int32, []int32) {
+	partitions := make([]int32, 0, len(topic.Partitions))
+	leaders := make([]int32, 0, len(topic.Partitions))
+	for _, partition := range topic.Partitions {
+		if (partition.Err == sarama.ErrLeaderNotAvailable) || (partition.Leader < 0) {
+			module.Log.Warn("failed to fetch leader for partition",
+				zap.String("topic", topic.Name),
+				zap.Int32("partition", partition.ID),
+				zap.String("sarama_error", sarama.ErrLeaderNotAvailable.Error()))
+			continue
+		}
+		partitions = append(partitions, partition.ID)
+		leaders = append(leaders, partition.Leader)
+	}
+	return partitions, leaders
+}
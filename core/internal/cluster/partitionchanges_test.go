@@ -0,0 +1,218 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// fixturePartitionsAdded returns a module with testtopic as it was at the last metadata refresh, with partition 1 led
+// by broker 14, and a client whose metadata broker (13) now leads all of testtopic's partitions, including a third
+// that was added after that refresh
+func fixturePartitionsAdded(refresh string) (*KafkaCluster, *helpers.MockSaramaClient, *helpers.MockSaramaBroker) {
+	module := fixtureModule()
+	viper.Set("cluster.test.partition-change-refresh", refresh)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0, 1}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13, 14}}
+	module.fetchMetadata.Store(false)
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("testtopic", 1, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("testtopic", 2, 13, nil, nil, nil, sarama.ErrNoError)
+	client, broker := fixtureMetadataClient(metadata)
+	var nilBroker *helpers.BurrowSaramaBroker
+	client.On("Broker", int32(14)).Return(nilBroker, sarama.ErrBrokerNotFound)
+	fixtureClientLeaders(module, client)
+
+	return module, client, broker
+}
+
+func TestKafkaCluster_getOffsets_PartitionsAddedTopicRefresh(t *testing.T) {
+	module, client, broker := fixturePartitionsAdded(partitionChangeRefreshTopic)
+
+	// Broker 14 is no longer known, so testtopic is refreshed before its offsets are fetched
+	response := &sarama.OffsetResponse{Version: 1}
+	response.AddTopicPartition("testtopic", 0, 100)
+	response.AddTopicPartition("testtopic", 1, 200)
+	response.AddTopicPartition("testtopic", 2, 300)
+	broker.On("GetAvailableOffsets", mock.Anything).Return(response, nil)
+
+	module.getOffsets(client)
+
+	assert.False(t, module.fetchMetadata.Load(), "Expected no full metadata refresh")
+	assert.Equal(t, []int32{0, 1, 2}, module.topicPartitions["testtopic"], "Expected the added partition to be tracked")
+	assert.Equal(t, []int32{13, 13, 13}, module.topicLeaders["testtopic"], "Expected the new leaders to be tracked")
+	assert.Nil(t, module.takeChangedTopics(), "Expected the changed topics to be cleared")
+	broker.AssertNumberOfCalls(t, "GetMetadata", 1)
+	broker.AssertNumberOfCalls(t, "GetAvailableOffsets", 1)
+
+	assert.Len(t, module.App.StorageChannel, 3)
+	offsets := make(map[int32]int64)
+	for len(module.App.StorageChannel) > 0 {
+		request := <-module.App.StorageChannel
+		offsets[request.Partition] = request.Offset
+		assert.Equalf(t, int32(3), request.TopicPartitionCount, "Expected partition count 3, not %v", request.TopicPartitionCount)
+	}
+	assert.Equal(t, map[int32]int64{0: 100, 1: 200, 2: 300}, offsets)
+}
+
+func TestKafkaCluster_getOffsets_NotLeaderTopicRefresh(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.partition-change-refresh", partitionChangeRefreshTopic)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0, 1}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13, 13}}
+	module.fetchMetadata.Store(false)
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+
+	// The leader of partition 1 moved after the last metadata refresh, which the broker only reports in the fetch
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddTopicPartition("testtopic", 0, 13, nil, nil, nil, sarama.ErrNoError)
+	metadata.AddTopicPartition("testtopic", 1, 13, nil, nil, nil, sarama.ErrNoError)
+	client, broker := fixtureMetadataClient(metadata)
+
+	firstResponse := &sarama.OffsetResponse{Version: 1}
+	firstResponse.AddTopicPartition("testtopic", 0, 100)
+	firstResponse.AddTopicPartition("testtopic", 1, 0)
+	firstResponse.Blocks["testtopic"][1] = &sarama.OffsetResponseBlock{Err: sarama.ErrNotLeaderForPartition}
+	refreshedResponse := &sarama.OffsetResponse{Version: 1}
+	refreshedResponse.AddTopicPartition("testtopic", 0, 100)
+	refreshedResponse.AddTopicPartition("testtopic", 1, 200)
+	broker.On("GetAvailableOffsets", mock.Anything).Return(firstResponse, nil).Once()
+	broker.On("GetAvailableOffsets", mock.Anything).Return(refreshedResponse, nil).Once()
+
+	fixtureClientLeaders(module, client)
+	module.getOffsets(client)
+
+	assert.False(t, module.fetchMetadata.Load(), "Expected no full metadata refresh")
+	broker.AssertNumberOfCalls(t, "GetMetadata", 1)
+	broker.AssertNumberOfCalls(t, "GetAvailableOffsets", 2)
+	assert.Len(t, module.App.StorageChannel, 3)
+}
+
+func TestKafkaCluster_getOffsets_TooManyTopicsChanged(t *testing.T) {
+	module, client, broker := fixturePartitionsAdded(partitionChangeRefreshTopic)
+	module.topicPartitions["othertopic"] = []int32{0}
+	module.topicLeaders["othertopic"] = []int32{14}
+	module.partitionChangeMaxTopics = 1
+
+	response := &sarama.OffsetResponse{Version: 1}
+	response.AddTopicPartition("testtopic", 0, 100)
+	broker.On("GetAvailableOffsets", mock.Anything).Return(response, nil)
+
+	module.getOffsets(client)
+
+	assert.True(t, module.fetchMetadata.Load(), "Expected a full metadata refresh on the next run")
+	broker.AssertNotCalled(t, "GetMetadata", mock.Anything)
+	assert.Len(t, module.App.StorageChannel, 1)
+}
+
+func TestKafkaCluster_movedLeaderTopics_FullRefresh(t *testing.T) {
+	module, client, _ := fixturePartitionsAdded(partitionChangeRefreshFull)
+	assert.Nil(t, module.movedLeaderTopics(client, func(string) bool { return true }), "Expected no topics in full refresh mode")
+}
+
+func TestKafkaCluster_movedLeaderTopics(t *testing.T) {
+	module, client, _ := fixturePartitionsAdded(partitionChangeRefreshTopic)
+	module.topicPartitions["othertopic"] = []int32{0}
+	module.topicLeaders["othertopic"] = []int32{13}
+	module.topicPartitions["nottopic"] = []int32{0}
+	module.topicLeaders["nottopic"] = []int32{14}
+	fixtureClientLeaders(module, client)
+
+	moved := module.movedLeaderTopics(client, func(topic string) bool { return topic != "nottopic" })
+	assert.Equal(t, map[string]bool{"testtopic": true}, moved, "Expected only the due topic with an unknown leader")
+}
+
+func TestKafkaCluster_movedLeaderTopics_LeaderMoved(t *testing.T) {
+	module, client, _ := fixturePartitionsAdded(partitionChangeRefreshTopic)
+	module.topicPartitions["othertopic"] = []int32{0}
+	module.topicLeaders["othertopic"] = []int32{12}
+
+	// Broker 12 is still up, but the client's metadata has partition 0 of othertopic led by broker 13 now
+	client.On("Broker", int32(12)).Return(&helpers.MockSaramaBroker{}, nil)
+	leader := &helpers.MockSaramaBroker{}
+	leader.On("ID").Return(int32(13))
+	client.On("Leader", "othertopic", int32(0)).Return(leader, nil)
+
+	moved := module.movedLeaderTopics(client, func(topic string) bool { return topic == "othertopic" })
+	assert.Equal(t, map[string]bool{"othertopic": true}, moved, "Expected the topic whose leader moved to a known broker")
+}
+
+func TestKafkaCluster_getOffsets_PartitionsAddedFullRefresh(t *testing.T) {
+	module, client, broker := fixturePartitionsAdded(partitionChangeRefreshFull)
+
+	firstResponse := &sarama.OffsetResponse{Version: 1}
+	firstResponse.AddTopicPartition("testtopic", 0, 100)
+	broker.On("GetAvailableOffsets", mock.Anything).Return(firstResponse, nil)
+
+	module.getOffsets(client)
+
+	assert.True(t, module.fetchMetadata.Load(), "Expected a full metadata refresh on the next run")
+	assert.Equal(t, []int32{0, 1}, module.topicPartitions["testtopic"], "Expected the partitions to be left until the refresh")
+	broker.AssertNotCalled(t, "GetMetadata", mock.Anything)
+	assert.Len(t, module.App.StorageChannel, 1)
+}
+
+func TestKafkaCluster_getOffsets_UnknownPartitionTopicRefreshFails(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.partition-change-refresh", partitionChangeRefreshTopic)
+	viper.Set("cluster.test.metadata-refresh-errors", 5)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0, 1}}
+	module.topicLeaders = map[string][]int32{"testtopic": {13, 13}}
+	module.fetchMetadata.Store(false)
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+
+	// The broker does not know about partition 1 yet, and the topic metadata cannot be fetched again
+	response := &sarama.OffsetResponse{Version: 1}
+	response.AddTopicPartition("testtopic", 0, 100)
+	response.AddTopicPartition("testtopic", 1, 0)
+	response.Blocks["testtopic"][1] = &sarama.OffsetResponseBlock{Err: sarama.ErrUnknownTopicOrPartition}
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("GetAvailableOffsets", mock.Anything).Return(response, nil)
+	broker.On("GetMetadata", mock.Anything).Return(&sarama.MetadataResponse{}, errors.New("metadata failed"))
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(13)).Return(broker, nil)
+	client.On("LeastLoadedBroker").Return(broker)
+	client.On("Config").Return(sarama.NewConfig())
+
+	fixtureClientLeaders(module, client)
+	module.getOffsets(client)
+
+	assert.True(t, module.fetchMetadata.Load(), "Expected a full metadata refresh when the topic refresh fails")
+	broker.AssertNumberOfCalls(t, "GetMetadata", 1)
+	broker.AssertNumberOfCalls(t, "GetAvailableOffsets", 1)
+	assert.Len(t, module.App.StorageChannel, 1)
+}
+
+func TestKafkaCluster_Configure_BadPartitionChangeRefresh(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.partition-change-refresh", "partial")
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_Configure_BadPartitionChangeMaxTopics(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.partition-change-max-topics", -1)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
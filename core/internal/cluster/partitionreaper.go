@@ -0,0 +1,107 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/internal/httpserver"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// Deleted topics are removed from storage when the metadata is refreshed, but a partition that goes away while its
+// topic stays would keep its last end offset in storage forever, and groups would be evaluated against it. There are
+// two ways a partition can go away, which are handled differently:
+//
+//   - The topic's partition count in the metadata is lower than it was. The partitions past the new count are deleted
+//     right away. A topic that has no partitions at all in the metadata (such as one that is still being created) is
+//     not counted as having shrunk.
+//   - The partition is still in the metadata, but has no leader. This usually lasts only as long as a leader election,
+//     so the partition is only deleted once it has had no leader for leaderless-partition-timeout, and never if that
+//     is zero (the default). The time is counted from the first metadata refresh that found the partition without a
+//     leader, and starts over if it gets one.
+
+// reapPartitions compares the partitions of a topic in new metadata with the ones from the last refresh, and deletes
+// the ones that are gone from storage. Both slices are as returned by topicPartitionLeaders, so their capacity is the
+// partition count, and the partitions without a leader are left out.
+func (module *KafkaCluster) reapPartitions(topic string, previous, partitions []int32, now time.Time) {
+	count := int32(cap(partitions))
+	if count == 0 {
+		return
+	}
+	for partition := count; partition < int32(cap(previous)); partition++ {
+		module.Log.Info("deleting partition past the partition count",
+			zap.String("topic", topic),
+			zap.Int32("partition", partition),
+			zap.Int32("partitions", count),
+		)
+		module.deletePartition(topic, partition, count)
+	}
+
+	if module.leaderlessPartitionTimeout == 0 {
+		return
+	}
+	leaderless := make(map[int32]bool)
+	if int32(len(partitions)) < count {
+		for partition := int32(0); partition < count; partition++ {
+			leaderless[partition] = true
+		}
+		for _, partition := range partitions {
+			delete(leaderless, partition)
+		}
+	}
+
+	since := module.leaderlessSince[topic]
+	for partition := range since {
+		if !leaderless[partition] {
+			delete(since, partition)
+		}
+	}
+	for partition := range leaderless {
+		if since == nil {
+			since = make(map[int32]time.Time)
+			module.leaderlessSince[topic] = since
+		}
+		start, ok := since[partition]
+		if !ok {
+			since[partition] = now
+			continue
+		}
+		// A zero time marks a partition that has already been deleted, while it is still without a leader
+		if start.IsZero() || (now.Sub(start) < module.leaderlessPartitionTimeout) {
+			continue
+		}
+		module.Log.Warn("deleting partition with no leader",
+			zap.String("topic", topic),
+			zap.Int32("partition", partition),
+			zap.Duration("leaderless", now.Sub(start)),
+		)
+		module.deletePartition(topic, partition, count)
+		since[partition] = time.Time{}
+	}
+	if len(since) == 0 {
+		delete(module.leaderlessSince, topic)
+	}
+}
+
+// deletePartition tells storage to delete a partition of a topic that has count partitions, and removes the metrics
+// for it
+func (module *KafkaCluster) deletePartition(topic string, partition, count int32) {
+	module.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType:         protocol.StorageSetDeletePartition,
+		Cluster:             module.name,
+		Topic:               topic,
+		Partition:           partition,
+		TopicPartitionCount: count,
+	}
+	httpserver.DeletePartitionMetrics(module.name, topic, partition)
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// partitionsWithCount returns a partition list as built by topicPartitionLeaders, for a topic with count partitions
+func partitionsWithCount(count int, partitions ...int32) []int32 {
+	return append(make([]int32, 0, count), partitions...)
+}
+
+// deletedPartitions drains the storage channel and returns the StorageSetDeletePartition requests that were sent
+func deletedPartitions(t *testing.T, module *KafkaCluster) []*protocol.StorageRequest {
+	requests := make([]*protocol.StorageRequest, 0)
+	for {
+		select {
+		case request := <-module.App.StorageChannel:
+			assert.Equal(t, protocol.StorageSetDeletePartition, request.RequestType)
+			requests = append(requests, request)
+		default:
+			return requests
+		}
+	}
+}
+
+func TestKafkaCluster_reapPartitions_Shrink(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+
+	module.reapPartitions("testtopic", partitionsWithCount(4, 0, 1, 2, 3), partitionsWithCount(2, 0, 1), time.Now())
+	requests := deletedPartitions(t, module)
+	assert.Len(t, requests, 2, "Expected the partitions past the count to be deleted")
+	for i, request := range requests {
+		assert.Equal(t, "testtopic", request.Topic)
+		assert.Equal(t, int32(2+i), request.Partition)
+		assert.Equal(t, int32(2), request.TopicPartitionCount)
+	}
+
+	// A topic with no partitions in the metadata has not shrunk
+	module.reapPartitions("testtopic", partitionsWithCount(2, 0, 1), partitionsWithCount(0), time.Now())
+	assert.Empty(t, deletedPartitions(t, module), "Expected nothing deleted for a topic with no partitions")
+}
+
+func TestKafkaCluster_reapPartitions_Leaderless(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.leaderless-partition-timeout", 60)
+	module.Configure("test", "cluster.test")
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+	all := partitionsWithCount(3, 0, 1, 2)
+	withoutLeader := partitionsWithCount(3, 0, 2)
+	now := time.Now()
+
+	// A brief loss of the leader does not delete the partition
+	module.reapPartitions("testtopic", all, withoutLeader, now)
+	module.reapPartitions("testtopic", withoutLeader, withoutLeader, now.Add(30*time.Second))
+	assert.Empty(t, deletedPartitions(t, module), "Expected nothing deleted before the timeout")
+	module.reapPartitions("testtopic", withoutLeader, all, now.Add(40*time.Second))
+	assert.Empty(t, module.leaderlessSince, "Expected the partition to not be tracked once it has a leader")
+
+	// The timeout starts over, and the partition is deleted once, after it has passed
+	module.reapPartitions("testtopic", all, withoutLeader, now.Add(50*time.Second))
+	module.reapPartitions("testtopic", withoutLeader, withoutLeader, now.Add(100*time.Second))
+	assert.Empty(t, deletedPartitions(t, module), "Expected nothing deleted before the timeout")
+	module.reapPartitions("testtopic", withoutLeader, withoutLeader, now.Add(110*time.Second))
+	requests := deletedPartitions(t, module)
+	assert.Len(t, requests, 1, "Expected the leaderless partition to be deleted")
+	assert.Equal(t, int32(1), requests[0].Partition)
+	assert.Equal(t, int32(3), requests[0].TopicPartitionCount)
+
+	module.reapPartitions("testtopic", withoutLeader, withoutLeader, now.Add(200*time.Second))
+	assert.Empty(t, deletedPartitions(t, module), "Expected the partition to only be deleted once")
+}
+
+func TestKafkaCluster_reapPartitions_LeaderlessDisabled(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+	withoutLeader := partitionsWithCount(3, 0, 2)
+	now := time.Now()
+
+	module.reapPartitions("testtopic", withoutLeader, withoutLeader, now)
+	module.reapPartitions("testtopic", withoutLeader, withoutLeader, now.Add(24*time.Hour))
+	assert.Empty(t, deletedPartitions(t, module), "Expected leaderless partitions to never be deleted")
+	assert.Empty(t, module.leaderlessSince, "Expected no partitions to be tracked")
+}
+
+func TestKafkaCluster_Configure_BadLeaderlessPartitionTimeout(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.leaderless-partition-timeout", -1)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
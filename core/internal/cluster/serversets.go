@@ -0,0 +1,162 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/internal/httpserver"
+)
+
+// serverSet is a named list of bootstrap servers for the cluster, such as the brokers in one data center
+type serverSet struct {
+	name    string
+	servers []string
+}
+
+// configureServerSets sets up the server sets for the cluster, in order of preference. If server-set-order is not set,
+// there is a single unnamed set with the servers config, and the cluster never fails over.
+func (module *KafkaCluster) configureServerSets(name, configRoot string) {
+	order := viper.GetStringSlice(configRoot + ".server-set-order")
+	if len(order) == 0 {
+		servers := viper.GetStringSlice(configRoot + ".servers")
+		if len(servers) == 0 {
+			panic("No Kafka brokers specified for cluster " + module.name)
+		} else if !helpers.ValidateHostList(servers) {
+			panic("Cluster '" + name + "' has one or more improperly formatted servers (must be host:port)")
+		}
+		module.serverSets = []serverSet{{servers: servers}}
+		module.servers = servers
+		return
+	}
+
+	if viper.IsSet(configRoot + ".servers") {
+		panic("Cluster '" + name + "' cannot set both servers and server-set-order")
+	}
+	module.serverSets = make([]serverSet, 0, len(order))
+	seen := make(map[string]bool)
+	for _, setName := range order {
+		if seen[setName] {
+			panic("Cluster '" + name + "' has server set '" + setName + "' in server-set-order more than once")
+		}
+		seen[setName] = true
+
+		servers := viper.GetStringSlice(configRoot + ".server-set." + setName)
+		if len(servers) == 0 {
+			panic("No Kafka brokers specified for server set '" + setName + "' of cluster " + module.name)
+		} else if !helpers.ValidateHostList(servers) {
+			panic("Cluster '" + name + "' server set '" + setName + "' has one or more improperly formatted servers (must be host:port)")
+		}
+		module.serverSets = append(module.serverSets, serverSet{name: setName, servers: servers})
+	}
+	module.servers = module.serverSets[0].servers
+
+	viper.SetDefault(configRoot+".server-set-failover-errors", 3)
+	viper.SetDefault(configRoot+".server-set-failback", 300)
+	module.failoverErrors = viper.GetInt(configRoot + ".server-set-failover-errors")
+	module.failbackInterval = viper.GetInt(configRoot + ".server-set-failback")
+	if module.failoverErrors < 1 {
+		panic("Cluster '" + name + "' server-set-failover-errors must be at least 1")
+	}
+	if module.failbackInterval < 0 {
+		panic("Cluster '" + name + "' server-set-failback must be zero or greater")
+	}
+}
+
+// connectServerSets connects to the first server set, in order of preference, that the client can connect to, detecting
+// the Kafka version to use as for a single set (see newClient).
+func (module *KafkaCluster) connectServerSets() (sarama.Client, error) {
+	var client sarama.Client
+	var err error
+	configuredVersion := module.saramaConfig.Version
+	for index, set := range module.serverSets {
+		module.servers = set.servers
+		module.saramaConfig.Version = configuredVersion
+		if client, err = module.newClient(); err == nil {
+			module.setActiveServerSet(index)
+			return client, nil
+		}
+		if len(module.serverSets) > 1 {
+			module.Log.Warn("failed to connect to server set", zap.String("server_set", set.name), zap.Error(err))
+		}
+	}
+	return client, err
+}
+
+// switchServerSet connects to the first of the server sets before limit that the client can connect to, using the Kafka
+// version that was already detected. If it connects, the old client is closed and the new one is returned, and the
+// metadata is refreshed on the next offset fetch. Otherwise, the old client is returned, and is still used.
+func (module *KafkaCluster) switchServerSet(client helpers.SaramaClient, limit int) helpers.SaramaClient {
+	for index := 0; index < limit; index++ {
+		set := module.serverSets[index]
+		newClient, err := module.newSaramaClient(set.servers, module.saramaConfig)
+		if err != nil {
+			module.Log.Warn("failed to connect to server set", zap.String("server_set", set.name), zap.Error(err))
+			continue
+		}
+
+		module.Log.Info("switched server set",
+			zap.String("from", module.serverSets[module.activeServerSet].name),
+			zap.String("to", set.name),
+		)
+		client.Close()
+		module.servers = set.servers
+		module.setActiveServerSet(index)
+		module.fetchMetadata.Store(true)
+		return &helpers.BurrowSaramaClient{Client: newClient}
+	}
+	return client
+}
+
+// checkServerSet fails over to another server set, trying them all in order of preference, if the metadata for the
+// cluster could not be fetched server-set-failover-errors times in a row. A cluster with a single server set never fails
+// over, as sarama already retries the servers in it.
+func (module *KafkaCluster) checkServerSet(client helpers.SaramaClient) helpers.SaramaClient {
+	if (len(module.serverSets) < 2) || (module.metadataFailures < module.failoverErrors) {
+		return client
+	}
+	module.Log.Warn("server set unreachable, failing over",
+		zap.String("server_set", module.serverSets[module.activeServerSet].name),
+		zap.Int("metadata_failures", module.metadataFailures),
+	)
+	module.metadataFailures = 0
+	return module.switchServerSet(client, len(module.serverSets))
+}
+
+// failbackServerSet switches back to a more preferred server set, if one can be connected to again
+func (module *KafkaCluster) failbackServerSet(client helpers.SaramaClient) helpers.SaramaClient {
+	if module.activeServerSet == 0 {
+		return client
+	}
+	return module.switchServerSet(client, module.activeServerSet)
+}
+
+// startFailbackTicker starts the ticker for trying to fail back to a more preferred server set. As with the groups
+// reaper, the ticker is stopped if there is nothing to fail back to, so it never fires.
+func (module *KafkaCluster) startFailbackTicker() {
+	if (len(module.serverSets) > 1) && (module.failbackInterval > 0) {
+		module.failbackTicker = time.NewTicker(time.Duration(module.failbackInterval) * time.Second)
+	} else {
+		module.failbackTicker = time.NewTicker(1 * time.Minute)
+		module.failbackTicker.Stop()
+	}
+}
+
+func (module *KafkaCluster) setActiveServerSet(index int) {
+	module.activeServerSet = index
+	if module.serverSets[index].name != "" {
+		httpserver.SetClusterServerSet(module.name, module.serverSets[index].name, module.serverSets[index].servers)
+	}
+}
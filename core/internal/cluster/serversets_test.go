@@ -0,0 +1,136 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+)
+
+// fixtureServerSetModule returns a module with two server sets, primary and secondary, that can only connect to the
+// servers in the reachable map
+func fixtureServerSetModule(t *testing.T) (*KafkaCluster, map[string]bool) {
+	t.Setenv("CLUSTERS_VERSION", "2.8.2")
+	module := fixtureModule()
+	viper.Set("cluster.test.servers", nil)
+	viper.Set("cluster.test.server-set-order", []string{"primary", "secondary"})
+	viper.Set("cluster.test.server-set.primary", []string{"broker1.example.com:1234"})
+	viper.Set("cluster.test.server-set.secondary", []string{"broker2.example.com:1234"})
+	viper.Set("cluster.test.server-set-failover-errors", 2)
+	module.Configure("test", "cluster.test")
+
+	reachable := map[string]bool{"broker1.example.com:1234": true, "broker2.example.com:1234": true}
+	module.newSaramaClient = func(servers []string, _ *sarama.Config) (sarama.Client, error) {
+		if reachable[servers[0]] {
+			return &versionClient{}, nil
+		}
+		return nil, errors.New("cannot connect")
+	}
+	return module, reachable
+}
+
+func TestKafkaCluster_Configure_ServerSets(t *testing.T) {
+	module, _ := fixtureServerSetModule(t)
+
+	assert.Len(t, module.serverSets, 2, "Expected two server sets")
+	assert.Equal(t, []string{"broker1.example.com:1234"}, module.servers, "Expected the first server set to be used")
+	assert.Equal(t, 2, module.failoverErrors)
+	assert.Equal(t, 300, module.failbackInterval, "Expected default failback interval of 300 seconds")
+}
+
+func TestKafkaCluster_Configure_ServerSetsAndServers(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.server-set-order", []string{"primary"})
+	viper.Set("cluster.test.server-set.primary", []string{"broker1.example.com:1234"})
+
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_Configure_BadServerSet(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.servers", nil)
+	viper.Set("cluster.test.server-set-order", []string{"primary", "secondary"})
+	viper.Set("cluster.test.server-set.primary", []string{"broker1.example.com:1234"})
+
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_Configure_BadServerSetFailoverErrors(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.servers", nil)
+	viper.Set("cluster.test.server-set-order", []string{"primary"})
+	viper.Set("cluster.test.server-set.primary", []string{"broker1.example.com:1234"})
+	viper.Set("cluster.test.server-set-failover-errors", 0)
+
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_connectServerSets(t *testing.T) {
+	module, reachable := fixtureServerSetModule(t)
+	reachable["broker1.example.com:1234"] = false
+
+	client, err := module.connectServerSets()
+	assert.NoError(t, err, "Expected client to connect")
+	assert.NotNil(t, client, "Expected a client")
+	assert.Equal(t, 1, module.activeServerSet, "Expected the secondary server set to be active")
+	assert.Equal(t, []string{"broker2.example.com:1234"}, module.servers)
+}
+
+func TestKafkaCluster_connectServerSets_NoneReachable(t *testing.T) {
+	module, reachable := fixtureServerSetModule(t)
+	reachable["broker1.example.com:1234"] = false
+	reachable["broker2.example.com:1234"] = false
+
+	_, err := module.connectServerSets()
+	assert.Error(t, err, "Expected client to fail to connect")
+}
+
+func TestKafkaCluster_checkServerSet(t *testing.T) {
+	module, reachable := fixtureServerSetModule(t)
+	_, err := module.connectServerSets()
+	assert.NoError(t, err, "Expected client to connect")
+	client := &helpers.BurrowSaramaClient{Client: &versionClient{}}
+
+	// A single metadata failure does not fail over
+	module.metadataFailures = 1
+	assert.Same(t, client, module.checkServerSet(client), "Expected the same client")
+	assert.Equal(t, 0, module.activeServerSet)
+
+	// Enough failures in a row fail over to the first server set that can be connected to
+	reachable["broker1.example.com:1234"] = false
+	module.metadataFailures = 2
+	newClient := module.checkServerSet(client)
+	assert.NotSame(t, client, newClient, "Expected a new client")
+	assert.Equal(t, 1, module.activeServerSet, "Expected the secondary server set to be active")
+	assert.Equal(t, 0, module.metadataFailures, "Expected the metadata failures to be reset")
+	assert.True(t, module.fetchMetadata.Load(), "Expected metadata to be fetched with the new client")
+
+	// Failback does nothing until the primary server set can be connected to again
+	assert.Same(t, newClient, module.failbackServerSet(newClient), "Expected the same client")
+	reachable["broker1.example.com:1234"] = true
+	assert.NotSame(t, newClient, module.failbackServerSet(newClient), "Expected a new client")
+	assert.Equal(t, 0, module.activeServerSet, "Expected the primary server set to be active")
+}
+
+func TestKafkaCluster_checkServerSet_SingleSet(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	client := &helpers.MockSaramaClient{}
+
+	module.metadataFailures = 10
+	assert.Equal(t, client, module.checkServerSet(client), "Expected a single server set never to fail over")
+	client.AssertExpectations(t)
+}
@@ -0,0 +1,81 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// Lag in messages is hard to read on its own, as the same lag can be seconds or hours behind depending on how busy the
+// partition is. offset-lookback gives a time lag from the average rate that a partition was produced to over a single
+// window, which is off for partitions whose traffic changes over the day. If time-lag-sampling is set, the offset that
+// each partition was at is instead looked up at each of the time-lag-sample-ages every time-lag-sample-interval, and
+// sent to storage. The evaluator then finds roughly when the message a group is at was produced from the samples that
+// it falls between.
+//
+// Each age is a ListOffsets request to every broker that leads a partition, on top of the regular offset refresh, and
+// looking up an offset by time is more work for the broker than looking up the end offset. This needs Kafka 0.10.1 or
+// later, and the sampling is stopped (with a warning) if the client uses an older version.
+
+// sampleTimeLagOffsets looks up the offset that each tracked partition was at, at each of the sample ages, and sends
+// them to storage. A partition that could not be fetched for an age is left without a sample for it, rather than
+// leaving out the age for all of the partitions.
+func (module *KafkaCluster) sampleTimeLagOffsets(client helpers.SaramaClient) {
+	now := time.Now().Unix()
+	samples := make(map[string][][]protocol.TimeOffset)
+	for topic, partitions := range module.topicPartitions {
+		samples[topic] = make([][]protocol.TimeOffset, cap(partitions))
+	}
+
+	// The ages are sorted from oldest to newest when the module is configured, so the samples are in time order
+	var lock sync.Mutex
+	for _, age := range module.timeLagSampleAges {
+		ts := (now - int64(age)) * 1000
+		err := module.requestOffsetsAtTimestamp(client, ts, func(string) bool { return true }, func(topic string, partition int32, offset int64) {
+			lock.Lock()
+			defer lock.Unlock()
+			if int(partition) < len(samples[topic]) {
+				samples[topic][partition] = append(samples[topic][partition], protocol.TimeOffset{Timestamp: ts, Offset: offset})
+			}
+		})
+		if errors.Is(err, errTimestampUnsupported) {
+			module.Log.Warn("time-lag-sampling needs Kafka 0.10.1 or later, offsets will not be sampled",
+				zap.String("version", client.Config().Version.String()),
+			)
+			module.timeLagTicker.Stop()
+			return
+		}
+	}
+
+	// The partitions that could not be fetched for any age keep the samples from the last time they were
+	for topic, partitions := range samples {
+		for partition, timeOffsets := range partitions {
+			if len(timeOffsets) == 0 {
+				continue
+			}
+			helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+				RequestType:         protocol.StorageSetBrokerTimeOffsets,
+				Cluster:             module.name,
+				Topic:               topic,
+				Partition:           int32(partition),
+				TopicPartitionCount: int32(len(partitions)),
+				TimeOffsets:         timeOffsets,
+			}, 1)
+		}
+	}
+	module.Log.Debug("sampled offsets for time lag", zap.Int("topics", len(samples)), zap.Ints("ages", module.timeLagSampleAges))
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// fixtureTimeLagModule returns a module set up as in fixtureTimestampModule, that samples offsets at the ages given
+func fixtureTimeLagModule(ages []int, broker13, broker12 *helpers.MockSaramaBroker) (*KafkaCluster, *helpers.MockSaramaClient) {
+	module, client := fixtureTimestampModule(sarama.V2_1_0_0, broker13, broker12)
+	viper.Set("cluster.test.time-lag-sampling", true)
+	viper.Set("cluster.test.time-lag-sample-ages", ages)
+	module.Configure("test", "cluster.test")
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+	module.timeLagTicker = time.NewTicker(time.Minute)
+	return module, client
+}
+
+func TestKafkaCluster_sampleTimeLagOffsets(t *testing.T) {
+	older := &sarama.OffsetResponse{Version: 4}
+	older.AddTopicPartition("testtopic", 0, 100)
+	older.AddTopicPartition("testtopic", 2, 300)
+	newer := &sarama.OffsetResponse{Version: 4}
+	newer.AddTopicPartition("testtopic", 0, 150)
+	newer.AddTopicPartition("testtopic", 2, -1)
+	response12 := &sarama.OffsetResponse{Version: 4}
+	response12.AddTopicPartition("testtopic", 1, 200)
+	response12.AddTopicPartition("othertopic", 0, 42)
+
+	// The ages are looked up one after the other, oldest first
+	broker13 := &helpers.MockSaramaBroker{}
+	broker13.On("GetAvailableOffsets", mock.Anything).Return(older, nil).Once()
+	broker13.On("GetAvailableOffsets", mock.Anything).Return(newer, nil).Once()
+	broker12 := &helpers.MockSaramaBroker{}
+	broker12.On("GetAvailableOffsets", mock.Anything).Return(response12, nil)
+	module, client := fixtureTimeLagModule([]int{60, 600}, broker13, broker12)
+	assert.Equal(t, []int{600, 60}, module.timeLagSampleAges, "Expected the ages to be sorted oldest first")
+
+	module.sampleTimeLagOffsets(client)
+	close(module.App.StorageChannel)
+	samples := make(map[string][][]protocol.TimeOffset)
+	for request := range module.App.StorageChannel {
+		assert.Equal(t, protocol.StorageSetBrokerTimeOffsets, request.RequestType)
+		if samples[request.Topic] == nil {
+			samples[request.Topic] = make([][]protocol.TimeOffset, request.TopicPartitionCount)
+		}
+		samples[request.Topic][request.Partition] = request.TimeOffsets
+	}
+
+	assert.Len(t, samples, 2, "Expected samples for both topics")
+	assert.Len(t, samples["testtopic"], 3)
+	for partition, expected := range [][]int64{{100, 150}, {200, 200}, {300, -1}} {
+		timeOffsets := samples["testtopic"][partition]
+		assert.Lenf(t, timeOffsets, 2, "Expected a sample for each age for partition %v", partition)
+		assert.Equal(t, expected, []int64{timeOffsets[0].Offset, timeOffsets[1].Offset})
+		assert.Equal(t, int64(540000), timeOffsets[1].Timestamp-timeOffsets[0].Timestamp, "Expected the samples to be oldest first")
+	}
+	assert.Equal(t, int64(42), samples["othertopic"][0][0].Offset)
+}
+
+func TestKafkaCluster_sampleTimeLagOffsets_Failed(t *testing.T) {
+	response := &sarama.OffsetResponse{Version: 4}
+	response.AddTopicPartition("testtopic", 0, 100)
+	response.AddTopicPartition("testtopic", 2, 300)
+	broker13 := &helpers.MockSaramaBroker{}
+	broker13.On("GetAvailableOffsets", mock.Anything).Return(response, nil)
+	broker12 := &helpers.MockSaramaBroker{}
+	broker12.On("GetAvailableOffsets", mock.Anything).Return((*sarama.OffsetResponse)(nil), errors.New("broker down"))
+	broker12.On("Close").Return(nil)
+	broker12.On("Open", mock.Anything).Return(nil)
+	module, client := fixtureTimeLagModule([]int{60}, broker13, broker12)
+
+	// The partitions led by the healthy broker are still sampled, and the ones that could not be fetched are left out
+	module.sampleTimeLagOffsets(client)
+	close(module.App.StorageChannel)
+	stored := make(map[string]map[int32][]protocol.TimeOffset)
+	for request := range module.App.StorageChannel {
+		if stored[request.Topic] == nil {
+			stored[request.Topic] = make(map[int32][]protocol.TimeOffset)
+		}
+		stored[request.Topic][request.Partition] = request.TimeOffsets
+	}
+	assert.Len(t, stored, 1, "Expected no samples for othertopic")
+	assert.Len(t, stored["testtopic"], 2, "Expected no sample for the partition that could not be fetched")
+	assert.Equal(t, int64(100), stored["testtopic"][0][0].Offset)
+	assert.Equal(t, int64(300), stored["testtopic"][2][0].Offset)
+}
+
+func TestKafkaCluster_sampleTimeLagOffsets_Unsupported(t *testing.T) {
+	broker := &helpers.MockSaramaBroker{}
+	module, client := fixtureTimestampModule(sarama.V0_10_0_0, broker, broker)
+	viper.Set("cluster.test.time-lag-sampling", true)
+	module.Configure("test", "cluster.test")
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 10)
+	module.timeLagTicker = time.NewTicker(time.Minute)
+
+	module.sampleTimeLagOffsets(client)
+	assert.Empty(t, module.App.StorageChannel, "Expected no samples to be sent")
+	broker.AssertNotCalled(t, "GetAvailableOffsets", mock.Anything)
+}
+
+func TestKafkaCluster_Configure_BadTimeLagSampling(t *testing.T) {
+	for name, settings := range map[string]map[string]interface{}{
+		"no ages":      {"cluster.test.time-lag-sample-ages": []int{}},
+		"zero age":     {"cluster.test.time-lag-sample-ages": []int{60, 0}},
+		"bad interval": {"cluster.test.time-lag-sample-interval": 0},
+	} {
+		module := fixtureModule()
+		viper.Set("cluster.test.time-lag-sampling", true)
+		for key, value := range settings {
+			viper.Set(key, value)
+		}
+		assert.Panicsf(t, func() { module.Configure("test", "cluster.test") }, "Expected panic for %v", name)
+	}
+}
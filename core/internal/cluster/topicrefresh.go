@@ -0,0 +1,105 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Topics with a topic-refresh-overrides interval are not fetched on tickers of their own. There is a single offset
+// ticker, at the shortest of the intervals, and on each tick dueTopics picks the topics whose interval has passed.
+// The OffsetRequests are then built for only those topics, bucketed by leader broker as for every refresh, so the
+// partitions of every topic that is due on a tick, overridden or not, go to each broker in one request. A tick where
+// only the overridden topics are due sends requests to just the brokers that lead their partitions.
+
+// topicRefreshOverride is an offset refresh interval for the topics that match a regular expression, which replaces
+// the cluster's offset-refresh for them
+type topicRefreshOverride struct {
+	topics   *regexp.Regexp
+	interval time.Duration
+}
+
+// parseTopicRefreshOverrides parses the topic-refresh-overrides for a cluster, each of the form "REGEX=SECONDS". The
+// overrides are a list rather than a map, as viper lowercases map keys (and splits them at dots), which would change
+// the regular expressions. The spec is split at the last "=", so the regular expression can contain one. Any bad spec
+// will cause this func to panic, as it is called when configuring the module.
+func parseTopicRefreshOverrides(name string, specs []string) []topicRefreshOverride {
+	overrides := make([]topicRefreshOverride, 0, len(specs))
+	for _, spec := range specs {
+		split := strings.LastIndex(spec, "=")
+		if split <= 0 {
+			panic("Cluster '" + name + "' bad topic-refresh-overrides entry '" + spec + "' (must be of the form REGEX=SECONDS)")
+		}
+		re, err := regexp.Compile(spec[:split])
+		if err != nil {
+			panic("Cluster '" + name + "' failed to compile topic-refresh-overrides '" + spec + "': " + err.Error())
+		}
+		seconds, err := strconv.ParseInt(strings.TrimSpace(spec[split+1:]), 10, 64)
+		if (err != nil) || (seconds <= 0) {
+			panic("Cluster '" + name + "' bad interval in topic-refresh-overrides '" + spec + "' (must be a number of seconds greater than zero)")
+		}
+		overrides = append(overrides, topicRefreshOverride{topics: re, interval: time.Duration(seconds) * time.Second})
+	}
+	return overrides
+}
+
+// minRefreshInterval returns the shortest of the offset-refresh and override intervals, which is how often the offset
+// ticker must fire to fetch every topic on time
+func minRefreshInterval(offsetRefresh time.Duration, overrides []topicRefreshOverride) time.Duration {
+	interval := offsetRefresh
+	for _, override := range overrides {
+		if override.interval < interval {
+			interval = override.interval
+		}
+	}
+	return interval
+}
+
+// topicRefreshInterval returns the interval of the first override that matches the topic, or the offset-refresh for
+// the cluster if none of them do
+func (module *KafkaCluster) topicRefreshInterval(topic string) time.Duration {
+	for _, override := range module.topicRefreshOverrides {
+		if override.topics.MatchString(topic) {
+			return override.interval
+		}
+	}
+	return time.Duration(module.offsetRefresh) * time.Second
+}
+
+// dueTopics returns a func that is true for the topics whose offsets should be fetched in this offset refresh, and
+// records that they have been fetched at now. Without any overrides, every topic is fetched each time. A topic is due
+// once its interval has nearly passed, within half of the ticker interval, so that a tick that arrives slightly early
+// does not hold the topic over to the next one.
+func (module *KafkaCluster) dueTopics(now time.Time) func(string) bool {
+	if len(module.topicRefreshOverrides) == 0 {
+		return func(string) bool { return true }
+	}
+
+	due := make(map[string]bool)
+	for topic := range module.topicPartitions {
+		lastFetched, ok := module.topicLastFetched[topic]
+		if ok && (now.Sub(lastFetched) < module.topicRefreshInterval(topic)-module.offsetInterval/2) {
+			continue
+		}
+		due[topic] = true
+		module.topicLastFetched[topic] = now
+	}
+
+	// Topics that are no longer in the metadata do not need to be tracked
+	for topic := range module.topicLastFetched {
+		if _, ok := module.topicPartitions[topic]; !ok {
+			delete(module.topicLastFetched, topic)
+		}
+	}
+	return func(topic string) bool { return due[topic] }
+}
@@ -0,0 +1,158 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+)
+
+func TestParseTopicRefreshOverrides(t *testing.T) {
+	overrides := parseTopicRefreshOverrides("test", []string{"^hot-.*$=2", "^a=b$= 30"})
+	assert.Len(t, overrides, 2)
+	assert.Equal(t, "^hot-.*$", overrides[0].topics.String())
+	assert.Equal(t, 2*time.Second, overrides[0].interval)
+	assert.Equal(t, "^a=b$", overrides[1].topics.String(), "Expected the spec to be split at the last =")
+	assert.Equal(t, 30*time.Second, overrides[1].interval)
+}
+
+func TestParseTopicRefreshOverrides_Bad(t *testing.T) {
+	for _, spec := range []string{"nointerval", "=10", "[bad=10", "topic=abc", "topic=0", "topic=-5"} {
+		assert.Panicsf(t, func() { parseTopicRefreshOverrides("test", []string{spec}) }, "Expected panic for '%v'", spec)
+	}
+}
+
+func TestMinRefreshInterval(t *testing.T) {
+	overrides := parseTopicRefreshOverrides("test", []string{"^hot=2", "^cold=120"})
+	assert.Equal(t, 2*time.Second, minRefreshInterval(60*time.Second, overrides))
+	assert.Equal(t, 60*time.Second, minRefreshInterval(60*time.Second, nil))
+}
+
+func TestKafkaCluster_Configure_TopicRefreshOverrides(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-refresh", 60)
+	viper.Set("cluster.test.topic-refresh-overrides", []string{"^hot-.*$=2"})
+	module.Configure("test", "cluster.test")
+
+	assert.Equal(t, 2*time.Second, module.offsetInterval, "Expected the ticker to fire at the shortest interval")
+	assert.Equal(t, 2*time.Second, module.topicRefreshInterval("hot-topic"))
+	assert.Equal(t, 60*time.Second, module.topicRefreshInterval("cold-topic"))
+}
+
+func TestKafkaCluster_Configure_BadTopicRefreshOverrides(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-refresh-overrides", []string{"^hot-(.*$=2"})
+
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_dueTopics(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-refresh", 60)
+	viper.Set("cluster.test.topic-refresh-overrides", []string{"^hot-.*$=2"})
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"hot-topic": {0}, "cold-topic": {0}}
+
+	// Every topic is due the first time
+	start := time.Unix(1000, 0)
+	due := module.dueTopics(start)
+	assert.True(t, due("hot-topic"))
+	assert.True(t, due("cold-topic"))
+
+	// Only the hot topic is due on the following ticks, even if a tick is a little early
+	due = module.dueTopics(start.Add(1900 * time.Millisecond))
+	assert.True(t, due("hot-topic"))
+	assert.False(t, due("cold-topic"))
+	due = module.dueTopics(start.Add(2 * time.Second))
+	assert.False(t, due("hot-topic"), "Expected the hot topic to wait for its interval")
+
+	// The cold topic is due again once its interval has passed
+	due = module.dueTopics(start.Add(60 * time.Second))
+	assert.True(t, due("hot-topic"))
+	assert.True(t, due("cold-topic"))
+
+	// Topics that are gone are no longer tracked
+	delete(module.topicPartitions, "cold-topic")
+	module.dueTopics(start.Add(62 * time.Second))
+	assert.NotContains(t, module.topicLastFetched, "cold-topic")
+}
+
+func TestKafkaCluster_dueTopics_NoOverrides(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+
+	now := time.Now()
+	assert.True(t, module.dueTopics(now)("testtopic"))
+	assert.True(t, module.dueTopics(now)("testtopic"), "Expected every topic to be due on every refresh")
+	assert.Empty(t, module.topicLastFetched, "Expected no fetch times to be tracked")
+}
+
+func TestKafkaCluster_generateTieredOffsetRequests_DueTopics(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"hot-topic": {0}, "cold-topic": {0}}
+	module.topicLeaders = map[string][]int32{"hot-topic": {13}, "cold-topic": {13}}
+
+	broker13 := &helpers.MockSaramaBroker{}
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(13)).Return(broker13, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	tiers, brokers := module.generateTieredOffsetRequests(client, sarama.OffsetNewest, func(topic string) bool { return topic == "hot-topic" })
+
+	assert.Len(t, brokers, 1, "Expected one broker")
+	assert.Len(t, tiers, 1, "Expected one tier of requests")
+	expected := &sarama.OffsetRequest{Version: tiers[0][13].Version}
+	expected.AddBlock("hot-topic", 0, sarama.OffsetNewest, 1)
+	assert.Equal(t, expected, tiers[0][13], "Expected only the due topic in the request")
+}
+
+func TestKafkaCluster_generateTieredOffsetRequests_DueTopicsCoalesce(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-refresh-overrides", []string{"^hot-.*$=2"})
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"hot-topic": {0, 1}, "cold-topic": {0, 1}}
+	module.topicLeaders = map[string][]int32{"hot-topic": {13, 14}, "cold-topic": {13, 13}}
+
+	broker13 := &helpers.MockSaramaBroker{}
+	broker14 := &helpers.MockSaramaBroker{}
+	client := &helpers.MockSaramaClient{}
+	client.On("Broker", int32(13)).Return(broker13, nil)
+	client.On("Broker", int32(14)).Return(broker14, nil)
+	client.On("Config").Return(sarama.NewConfig())
+
+	// On the first refresh every topic is due, and each broker gets one request for all of its partitions
+	now := time.Now()
+	tiers, brokers := module.generateTieredOffsetRequests(client, sarama.OffsetNewest, module.dueTopics(now))
+	assert.Len(t, brokers, 2, "Expected two brokers")
+	assert.Len(t, tiers, 1, "Expected one tier of requests")
+	expected := &sarama.OffsetRequest{Version: tiers[0][13].Version}
+	expected.AddBlock("hot-topic", 0, sarama.OffsetNewest, 1)
+	expected.AddBlock("cold-topic", 0, sarama.OffsetNewest, 1)
+	expected.AddBlock("cold-topic", 1, sarama.OffsetNewest, 1)
+	assert.Equal(t, expected, tiers[0][13], "Expected the overridden and regular topics in one request")
+
+	// On the next tick only the overridden topic is due, still batched by its leaders
+	tiers, brokers = module.generateTieredOffsetRequests(client, sarama.OffsetNewest, module.dueTopics(now.Add(2*time.Second)))
+	assert.Len(t, brokers, 2, "Expected both leaders of the overridden topic")
+	expected = &sarama.OffsetRequest{Version: tiers[0][13].Version}
+	expected.AddBlock("hot-topic", 0, sarama.OffsetNewest, 1)
+	assert.Equal(t, expected, tiers[0][13], "Expected only the overridden topic")
+	expected = &sarama.OffsetRequest{Version: tiers[0][14].Version}
+	expected.AddBlock("hot-topic", 1, sarama.OffsetNewest, 1)
+	assert.Equal(t, expected, tiers[0][14], "Expected only the overridden topic")
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+)
+
+// pinnedVersion is the content of the version-file. It is the Kafka version that the cluster was last connected with
+// after detecting it, so that the next start can connect with it directly
+type pinnedVersion struct {
+	Cluster   string `json:"cluster"`
+	Version   string `json:"version"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// readPinnedVersion returns the version stored in the version-file, if one is configured and it has a valid version
+// for this cluster. A missing or bad file just means that the version must be detected again.
+func (module *KafkaCluster) readPinnedVersion() (sarama.KafkaVersion, bool) {
+	if (module.versionFile == "") || module.redetectVersion {
+		return sarama.KafkaVersion{}, false
+	}
+
+	content, err := os.ReadFile(module.versionFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			module.Log.Warn("failed to read version file", zap.String("filename", module.versionFile), zap.Error(err))
+		}
+		return sarama.KafkaVersion{}, false
+	}
+
+	var pinned pinnedVersion
+	if err = json.Unmarshal(content, &pinned); err != nil || (pinned.Cluster != module.name) {
+		module.Log.Warn("ignoring bad version file", zap.String("filename", module.versionFile))
+		return sarama.KafkaVersion{}, false
+	}
+	version, err := sarama.ParseKafkaVersion(pinned.Version)
+	if err != nil {
+		module.Log.Warn("ignoring bad version file", zap.String("filename", module.versionFile), zap.Error(err))
+		return sarama.KafkaVersion{}, false
+	}
+	return version, true
+}
+
+// writePinnedVersion writes the version the client connected with to the version-file, if one is configured. The file
+// is written to a temporary name and then renamed, so that a failed write does not leave a partial file.
+func (module *KafkaCluster) writePinnedVersion(version sarama.KafkaVersion) {
+	if module.versionFile == "" {
+		return
+	}
+
+	content, err := json.Marshal(pinnedVersion{
+		Cluster:   module.name,
+		Version:   version.String(),
+		Timestamp: time.Now().Unix() * 1000,
+	})
+	if err == nil {
+		err = os.WriteFile(module.versionFile+".tmp", content, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(module.versionFile+".tmp", module.versionFile)
+	}
+	if err != nil {
+		module.Log.Error("failed to write version file",
+			zap.String("filename", module.versionFile),
+			zap.Error(err),
+		)
+	}
+}
+
+// newClient connects to the cluster. If a version was pinned by an earlier start, it is tried first, and the version is
+// only detected again if the client cannot connect with it. Otherwise, unless the CLUSTERS_VERSION environment variable
+// is set, the supported versions are tried from newest to oldest, and the first that connects is pinned.
+func (module *KafkaCluster) newClient() (sarama.Client, error) {
+	configuredVersion := module.saramaConfig.Version
+	if version, ok := module.readPinnedVersion(); ok {
+		module.saramaConfig.Version = version
+		client, err := module.newSaramaClient(module.servers, module.saramaConfig)
+		if err == nil {
+			module.Log.Info("using pinned client[cluster]version:" + version.String())
+			return client, nil
+		}
+		module.Log.Warn("failed to start client with pinned version, detecting again", zap.String("version", version.String()), zap.Error(err))
+		module.saramaConfig.Version = configuredVersion
+	}
+
+	client, err := module.newSaramaClient(module.servers, module.saramaConfig)
+	if err != nil {
+		module.Log.Error("failed to start client[cluster]version:"+module.saramaConfig.Version.String(), zap.Error(err))
+	}
+	if os.Getenv("CLUSTERS_VERSION") != "" {
+		return client, err
+	}
+	if client != nil {
+		client.Close()
+	}
+
+	vers := len(sarama.SupportedVersions)
+	for index := range vers {
+		module.saramaConfig.Version = sarama.SupportedVersions[vers-index-1]
+		if client, err = module.newSaramaClient(module.servers, module.saramaConfig); err == nil {
+			module.Log.Info("try using client[cluster]version:" + module.saramaConfig.Version.String())
+			module.writePinnedVersion(module.saramaConfig.Version)
+			break
+		}
+	}
+	return client, err
+}
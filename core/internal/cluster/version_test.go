@@ -0,0 +1,98 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package cluster
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// versionClient is a sarama.Client that only supports Close, which is all that newClient calls
+type versionClient struct {
+	sarama.Client
+}
+
+func (c *versionClient) Close() error {
+	return nil
+}
+
+// fixtureVersionModule returns a module that can only connect to the cluster with the given version, and records the
+// versions it tried
+func fixtureVersionModule(t *testing.T, version sarama.KafkaVersion) (*KafkaCluster, *[]sarama.KafkaVersion) {
+	module := fixtureModule()
+	viper.Set("cluster.test.version-file", filepath.Join(t.TempDir(), "version.json"))
+	module.Configure("test", "cluster.test")
+
+	tried := make([]sarama.KafkaVersion, 0)
+	module.newSaramaClient = func(_ []string, config *sarama.Config) (sarama.Client, error) {
+		tried = append(tried, config.Version)
+		if config.Version == version {
+			return &versionClient{}, nil
+		}
+		return nil, errors.New("unsupported version")
+	}
+	return module, &tried
+}
+
+func TestKafkaCluster_newClient_PinsVersion(t *testing.T) {
+	os.Unsetenv("CLUSTERS_VERSION")
+	module, tried := fixtureVersionModule(t, sarama.V2_1_0_0)
+
+	client, err := module.newClient()
+	assert.NoError(t, err, "Expected client to connect")
+	assert.NotNil(t, client, "Expected a client")
+	assert.Equalf(t, sarama.V2_1_0_0, module.saramaConfig.Version, "Expected detected version 2.1.0, not %v", module.saramaConfig.Version)
+
+	version, ok := module.readPinnedVersion()
+	assert.True(t, ok, "Expected version to be pinned")
+	assert.Equalf(t, sarama.V2_1_0_0, version, "Expected pinned version 2.1.0, not %v", version)
+
+	// The next start connects with the pinned version first
+	*tried = (*tried)[:0]
+	module.saramaConfig.Version = sarama.V2_8_2_0
+	_, err = module.newClient()
+	assert.NoError(t, err, "Expected client to connect")
+	assert.Equalf(t, []sarama.KafkaVersion{sarama.V2_1_0_0}, *tried, "Expected only the pinned version to be tried, not %v", *tried)
+}
+
+func TestKafkaCluster_newClient_PinnedVersionFails(t *testing.T) {
+	os.Unsetenv("CLUSTERS_VERSION")
+	module, tried := fixtureVersionModule(t, sarama.V2_1_0_0)
+	module.writePinnedVersion(sarama.V3_0_0_0)
+
+	_, err := module.newClient()
+	assert.NoError(t, err, "Expected client to connect")
+	assert.Equalf(t, sarama.V3_0_0_0, (*tried)[0], "Expected pinned version to be tried first, not %v", (*tried)[0])
+	assert.Truef(t, len(*tried) > 2, "Expected versions to be detected again, only tried %v", *tried)
+
+	version, _ := module.readPinnedVersion()
+	assert.Equalf(t, sarama.V2_1_0_0, version, "Expected newly detected version to be pinned, not %v", version)
+}
+
+func TestKafkaCluster_readPinnedVersion_Redetect(t *testing.T) {
+	module, _ := fixtureVersionModule(t, sarama.V2_1_0_0)
+	module.writePinnedVersion(sarama.V2_1_0_0)
+
+	module.redetectVersion = true
+	_, ok := module.readPinnedVersion()
+	assert.False(t, ok, "Expected pinned version to be ignored")
+
+	// A version pinned for another cluster is also ignored
+	module.redetectVersion = false
+	module.name = "other"
+	_, ok = module.readPinnedVersion()
+	assert.False(t, ok, "Expected version pinned for another cluster to be ignored")
+}
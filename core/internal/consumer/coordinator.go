@@ -19,21 +19,32 @@
 // * kafka - Consume a Kafka cluster's __consumer_offsets topic to get consumer information (new consumer)
 //
 // * kafka_zk - Parse the /consumers tree of a Kafka cluster's metadata to get consumer information (old consumer)
+//
+// * grpc - Stream the offsets committed to another store from a gRPC server for it (see burrowpb.OffsetSource)
 package consumer
 
 import (
 	"errors"
+	"sort"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
 	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/internal/httpserver"
 	"github.com/linkedin/Burrow/core/protocol"
 )
 
 // The consumer module is responsible for fetching information about consumer group status from some external system
 // and forwarding it to the storage module. Each consumer module is associated with a single cluster.
 
+// Module (consumer) is a consumer module that can also take requests from the Coordinator, which are sent on the
+// channel that GetCommunicationChannel returns. Consumer modules that do not implement it only read offsets.
+type Module interface {
+	protocol.Module
+	GetCommunicationChannel() chan *protocol.ConsumerRequest
+}
+
 // Coordinator manages all consumer modules, making sure they are configured, started, and stopped at the appropriate
 // time.
 type Coordinator struct {
@@ -45,6 +56,11 @@ type Coordinator struct {
 	Log *zap.Logger
 
 	modules map[string]protocol.Module
+
+	// Skip consumer modules that fail to configure or start, rather than stopping Burrow
+	isolateFailures bool
+
+	quitChannel chan struct{}
 }
 
 // getModuleForClass returns the correct module based on the passed className. As part of the Configure steps, if there
@@ -68,6 +84,11 @@ func getModuleForClass(app *protocol.ApplicationContext, moduleName, className s
 			App: app,
 			Log: logger,
 		}
+	case "grpc":
+		return &GRPCClient{
+			App: app,
+			Log: logger,
+		}
 	default:
 		panic("Unknown consumer className provided: " + className)
 	}
@@ -76,10 +97,15 @@ func getModuleForClass(app *protocol.ApplicationContext, moduleName, className s
 // Configure is called to create each of the configured consumer modules and call their Configure funcs to validate
 // their individual configurations and set them up. If there are any problems, it is expected that these funcs will
 // panic with a descriptive error message, as configuration failures are not recoverable errors.
+//
+// If general.isolate-cluster-failures is set, a consumer module that fails to configure or start is skipped instead,
+// and its error is recorded against its cluster, as for a failed cluster module.
 func (cc *Coordinator) Configure() {
 	cc.Log.Info("configuring")
 
 	cc.modules = make(map[string]protocol.Module)
+	cc.quitChannel = make(chan struct{})
+	cc.isolateFailures = viper.GetBool("general.isolate-cluster-failures")
 
 	// Create all configured cluster modules, add to list of clusters
 	modules := viper.GetStringMap("consumer")
@@ -88,23 +114,35 @@ func (cc *Coordinator) Configure() {
 		if !viper.IsSet("cluster." + viper.GetString(configRoot+".cluster")) {
 			panic("Consumer '" + name + "' references an unknown cluster '" + viper.GetString(configRoot+".cluster") + "'")
 		}
-		module := getModuleForClass(cc.App, name, viper.GetString(configRoot+".class-name"))
-		module.Configure(name, configRoot)
-		cc.modules[name] = module
+		err := helpers.ConfigureIsolated(configRoot, cc.isolateFailures, func() {
+			module := getModuleForClass(cc.App, name, viper.GetString(configRoot+".class-name"))
+			module.Configure(name, configRoot)
+			cc.modules[name] = module
+		})
+		if err != nil {
+			cc.consumerFailed(name, "configure", err)
+		}
 	}
 }
 
-// Start calls each of the configured consumer modules' underlying Start funcs. As the coordinator itself has no ongoing
-// work to do, it does not start any other goroutines. If any module Start returns an error, this func stops immediately
-// and returns that error to the caller. No further modules will be loaded after that.
+// Start calls each of the configured consumer modules' underlying Start funcs. If any module Start returns an error,
+// this func stops immediately and returns that error to the caller. No further modules will be loaded after that.
+//
+// We also start a request forwarder goroutine, which listens to the ConsumerChannel in the application context and
+// forwards each request to a consumer module for the cluster that it names.
 func (cc *Coordinator) Start() error {
 	cc.Log.Info("starting")
 
 	// Start Consumer modules
-	err := helpers.StartCoordinatorModules(cc.modules)
-	if err != nil {
+	if cc.isolateFailures {
+		helpers.StartIsolatedModules(cc.modules, func(name string, err error) {
+			cc.consumerFailed(name, "start", err)
+		})
+	} else if err := helpers.StartCoordinatorModules(cc.modules); err != nil {
 		return errors.New("Error starting consumer module: " + err.Error())
 	}
+	go cc.forwardRequests()
+
 	// All consumers started, Burrow is ready to serve requests
 	// set the readiness probe
 	cc.App.AppReady = true
@@ -117,7 +155,54 @@ func (cc *Coordinator) Start() error {
 func (cc *Coordinator) Stop() error {
 	cc.Log.Info("stopping")
 
+	close(cc.quitChannel)
+
 	// The individual consumer modules can choose whether or not to implement a wait in the Stop routine
 	helpers.StopCoordinatorModules(cc.modules)
 	return nil
 }
+
+// consumerFailed logs and records a consumer module that failed, and is being skipped, against its cluster
+func (cc *Coordinator) consumerFailed(name, stage string, err error) {
+	cluster := viper.GetString("consumer." + name + ".cluster")
+	cc.Log.Error("skipping failed consumer",
+		zap.String("consumer", name),
+		zap.String("cluster", cluster),
+		zap.String("stage", stage),
+		zap.Error(err),
+	)
+	httpserver.SetClusterFailed(cluster, "consumer module "+name+" failed to "+stage+": "+err.Error())
+}
+
+// moduleForCluster returns the consumer module for the cluster that can take requests. If there is more than one, the
+// first by name is used, so that requests for a cluster always go to the same module.
+func (cc *Coordinator) moduleForCluster(cluster string) Module {
+	names := make([]string, 0, len(cc.modules))
+	for name := range cc.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if module, ok := cc.modules[name].(Module); ok && (viper.GetString("consumer."+name+".cluster") == cluster) {
+			return module
+		}
+	}
+	return nil
+}
+
+func (cc *Coordinator) forwardRequests() {
+	for {
+		select {
+		case request := <-cc.App.ConsumerChannel:
+			if module := cc.moduleForCluster(request.Cluster); module != nil {
+				module.GetCommunicationChannel() <- request
+			} else {
+				// There is no module to respond, so tell the sender there is nothing to handle the request
+				close(request.Reply)
+			}
+		case <-cc.quitChannel:
+			return
+		}
+	}
+}
@@ -26,8 +26,9 @@ func fixtureCoordinator() *Coordinator {
 		Log: zap.NewNop(),
 	}
 	coordinator.App = &protocol.ApplicationContext{
-		Logger:         zap.NewNop(),
-		StorageChannel: make(chan *protocol.StorageRequest),
+		Logger:          zap.NewNop(),
+		StorageChannel:  make(chan *protocol.StorageRequest),
+		ConsumerChannel: make(chan *protocol.ConsumerRequest),
 	}
 
 	viper.Reset()
@@ -83,3 +84,34 @@ func TestCoordinator_StartStop(t *testing.T) {
 	coordinator.Stop()
 	mockModule.AssertCalled(t, "Stop")
 }
+
+func TestCoordinator_forwardRequests(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("consumer.anothertest.class-name", "kafka")
+	viper.Set("consumer.anothertest.servers", []string{"broker1.example.com:1234"})
+	viper.Set("consumer.anothertest.cluster", "test")
+	coordinator.Configure()
+	go coordinator.forwardRequests()
+	defer close(coordinator.quitChannel)
+
+	// Requests for a known cluster go to the first of its modules by name
+	request := &protocol.ConsumerRequest{
+		RequestType: protocol.ConsumerRefreshGroup,
+		Cluster:     "test",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	coordinator.App.ConsumerChannel <- request
+	forwarded := <-coordinator.modules["anothertest"].(Module).GetCommunicationChannel()
+	assert.Equal(t, request, forwarded, "Expected request to be forwarded to the module")
+
+	// Requests for a cluster with no consumer module are closed without a reply
+	request = &protocol.ConsumerRequest{
+		RequestType: protocol.ConsumerRefreshGroup,
+		Cluster:     "nocluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	coordinator.App.ConsumerChannel <- request
+	assert.Nil(t, <-request.Reply, "Expected no reply for an unknown cluster")
+}
@@ -0,0 +1,289 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package consumer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+	"github.com/linkedin/Burrow/core/protocol/burrowpb"
+)
+
+// GRPCClient is a consumer module which reads the offsets that groups commit to a store other than Kafka or
+// Zookeeper, from a gRPC server for the store that implements the OffsetSource service in
+// core/protocol/burrowpb/offsetsource.proto. The server streams the latest offset for every partition of every group,
+// and then each new commit, and the offsets are forwarded to the storage subsystem as for the other consumer modules.
+// If the stream fails, or the server closes it, it is opened again after a backoff.
+type GRPCClient struct {
+	// App is a pointer to the application context. This stores the channel to the storage subsystem
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name           string
+	cluster        string
+	address        string
+	creds          credentials.TransportCredentials
+	backoffMin     time.Duration
+	backoffMax     time.Duration
+	groupAllowlist *regexp.Regexp
+	groupDenylist  *regexp.Regexp
+
+	conn    *grpc.ClientConn
+	client  burrowpb.OffsetSourceClient
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+// Configure validates the configuration for the consumer. There must be a cluster name to which these consumers
+// belong, and the address of the gRPC server, of the form host:port. The connection uses TLS if a tls profile is
+// given. If the cluster name is unknown, the address is missing or invalid, or the TLS files cannot be read, this func
+// will panic.
+func (module *GRPCClient) Configure(name, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.running = sync.WaitGroup{}
+
+	module.cluster = viper.GetString(configRoot + ".cluster")
+	if !viper.IsSet("cluster." + module.cluster) {
+		panic("Consumer '" + name + "' references an unknown cluster '" + module.cluster + "'")
+	}
+
+	module.address = viper.GetString(configRoot + ".address")
+	if module.address == "" {
+		panic("No gRPC server address specified for consumer " + module.name)
+	} else if !helpers.ValidateHostPort(module.address, false) {
+		panic("Consumer '" + name + "' has an improperly formatted address (must be host:port)")
+	}
+
+	module.creds = insecure.NewCredentials()
+	if viper.IsSet(configRoot + ".tls") {
+		module.creds = credentials.NewTLS(grpcTLSConfig(viper.GetString(configRoot + ".tls")))
+	}
+
+	// The stream is opened again after reconnect-backoff-min seconds the first time it fails, doubling each time that
+	// it fails again without having received an offset, up to reconnect-backoff-max seconds
+	viper.SetDefault(configRoot+".reconnect-backoff-min", 1)
+	viper.SetDefault(configRoot+".reconnect-backoff-max", 60)
+	backoffMin := viper.GetInt(configRoot + ".reconnect-backoff-min")
+	backoffMax := viper.GetInt(configRoot + ".reconnect-backoff-max")
+	if backoffMin <= 0 {
+		panic("Consumer '" + name + "' reconnect-backoff-min must be greater than zero")
+	}
+	if backoffMax < backoffMin {
+		panic("Consumer '" + name + "' reconnect-backoff-max must not be less than reconnect-backoff-min")
+	}
+	module.backoffMin = time.Duration(backoffMin) * time.Second
+	module.backoffMax = time.Duration(backoffMax) * time.Second
+
+	allowlist := viper.GetString(configRoot + ".group-allowlist")
+	if allowlist != "" {
+		re, err := regexp.Compile(allowlist)
+		if err != nil {
+			module.Log.Panic("Failed to compile group allowlist")
+			panic(err)
+		}
+		module.groupAllowlist = re
+	}
+
+	denylist := viper.GetString(configRoot + ".group-denylist")
+	if denylist != "" {
+		re, err := regexp.Compile(denylist)
+		if err != nil {
+			module.Log.Panic("Failed to compile group denylist")
+			panic(err)
+		}
+		module.groupDenylist = re
+	}
+}
+
+// grpcTLSConfig returns the TLS config for the connection to the gRPC server from the named tls profile. The CA file
+// is optional, and the certificate and key files are only used if both are given.
+func grpcTLSConfig(tlsName string) *tls.Config {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: viper.GetBool("tls." + tlsName + ".noverify"),
+	}
+
+	caFile := viper.GetString("tls." + tlsName + ".cafile")
+	if caFile != "" {
+		caCert, err := os.ReadFile(caFile)
+		if err != nil {
+			panic("cannot read TLS CA file: " + err.Error())
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	certFile := viper.GetString("tls." + tlsName + ".certfile")
+	keyFile := viper.GetString("tls." + tlsName + ".keyfile")
+	if certFile != "" && keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			panic("cannot read TLS certificate or key file: " + err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig
+}
+
+// Start sets up the client for the gRPC server, and starts the goroutine that streams offsets from it. The connection
+// is made when the stream is opened, so a server that is not up yet is retried with the backoff rather than failing
+// Start. An error is only returned if the client cannot be set up.
+func (module *GRPCClient) Start() error {
+	module.Log.Info("starting")
+
+	conn, err := grpc.NewClient(module.address, grpc.WithTransportCredentials(module.creds))
+	if err != nil {
+		return err
+	}
+	module.conn = conn
+	module.client = burrowpb.NewOffsetSourceClient(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	module.cancel = cancel
+	module.running.Add(1)
+	go module.streamOffsets(ctx)
+
+	return nil
+}
+
+// Stop closes the offset stream and waits for the goroutine reading it to exit, and then closes the connection to the
+// gRPC server.
+func (module *GRPCClient) Stop() error {
+	module.Log.Info("stopping")
+
+	module.cancel()
+	module.running.Wait()
+	return module.conn.Close()
+}
+
+// streamOffsets opens the offset stream and reads it until the context is cancelled, opening it again after a backoff
+// whenever it fails
+func (module *GRPCClient) streamOffsets(ctx context.Context) {
+	defer module.running.Done()
+
+	failures := 0
+	for {
+		received, err := module.readOffsetStream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A stream that delivered offsets before it failed was working, so the backoff starts over
+		if received {
+			failures = 0
+		}
+		failures++
+		delay := module.reconnectDelay(failures)
+		module.Log.Warn("offset stream failed, reopening after backoff",
+			zap.String("address", module.address),
+			zap.Int("failures", failures),
+			zap.Duration("backoff", delay),
+			zap.Error(err),
+		)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// readOffsetStream opens the offset stream and sends each offset from it to storage until it fails. It returns
+// whether any offsets were received, and the error the stream failed with (io.EOF if the server closed it).
+func (module *GRPCClient) readOffsetStream(ctx context.Context) (bool, error) {
+	stream, err := module.client.StreamOffsets(ctx, &burrowpb.StreamOffsetsRequest{Cluster: module.cluster})
+	if err != nil {
+		return false, err
+	}
+	module.Log.Info("opened offset stream", zap.String("address", module.address))
+
+	received := false
+	for {
+		offset, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+		received = true
+		module.sendConsumerOffset(offset)
+	}
+}
+
+// reconnectDelay returns the backoff after the given number of consecutive failures of the stream
+func (module *GRPCClient) reconnectDelay(failures int) time.Duration {
+	delay := module.backoffMin
+	for i := 1; (i < failures) && (delay < module.backoffMax); i++ {
+		delay *= 2
+	}
+	if delay > module.backoffMax {
+		delay = module.backoffMax
+	}
+	return delay
+}
+
+// sendConsumerOffset forwards an offset from the stream to storage, unless the group is filtered out
+func (module *GRPCClient) sendConsumerOffset(offset *burrowpb.CommittedOffset) {
+	if !module.acceptConsumerGroup(offset.GetGroup()) {
+		return
+	}
+
+	// Storage puts the commits for a partition in order by the order field, so the timestamp is used if the server
+	// does not give a sequence
+	order := offset.GetSequence()
+	if order == 0 {
+		order = offset.GetTimestamp()
+	}
+
+	module.Log.Debug("consumer offset",
+		zap.String("group", offset.GetGroup()),
+		zap.String("topic", offset.GetTopic()),
+		zap.Int32("partition", offset.GetPartition()),
+		zap.Int64("offset", offset.GetOffset()),
+		zap.Int64("timestamp", offset.GetTimestamp()),
+	)
+	helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     module.cluster,
+		Topic:       offset.GetTopic(),
+		Partition:   offset.GetPartition(),
+		Group:       offset.GetGroup(),
+		Timestamp:   offset.GetTimestamp(),
+		Offset:      offset.GetOffset(),
+		Order:       order,
+	}, 1)
+}
+
+func (module *GRPCClient) acceptConsumerGroup(group string) bool {
+	if (module.groupAllowlist != nil) && (!module.groupAllowlist.MatchString(group)) {
+		return false
+	}
+	if (module.groupDenylist != nil) && module.groupDenylist.MatchString(group) {
+		return false
+	}
+	return true
+}
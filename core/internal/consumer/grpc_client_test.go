@@ -0,0 +1,192 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package consumer
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/linkedin/Burrow/core/protocol"
+	"github.com/linkedin/Burrow/core/protocol/burrowpb"
+)
+
+func fixtureGRPCModule() *GRPCClient {
+	module := GRPCClient{
+		Log: zap.NewNop(),
+	}
+	module.App = &protocol.ApplicationContext{
+		Logger:         zap.NewNop(),
+		StorageChannel: make(chan *protocol.StorageRequest),
+	}
+
+	viper.Reset()
+	viper.Set("cluster.test.class-name", "kafka")
+	viper.Set("consumer.test.class-name", "grpc")
+	viper.Set("consumer.test.cluster", "test")
+	viper.Set("consumer.test.address", "localhost:8100")
+
+	return &module
+}
+
+// testOffsetSource is an OffsetSource server that sends the offsets for each call to StreamOffsets in turn. The
+// stream fails after the offsets for each call except the last, which is held open until the client closes it.
+type testOffsetSource struct {
+	burrowpb.UnimplementedOffsetSourceServer
+	streams [][]*burrowpb.CommittedOffset
+	calls   chan *burrowpb.StreamOffsetsRequest
+}
+
+func (source *testOffsetSource) StreamOffsets(request *burrowpb.StreamOffsetsRequest, stream burrowpb.OffsetSource_StreamOffsetsServer) error {
+	call := len(source.calls)
+	source.calls <- request
+	for _, offset := range source.streams[call] {
+		if err := stream.Send(offset); err != nil {
+			return err
+		}
+	}
+	if call < len(source.streams)-1 {
+		return errors.New("store unavailable")
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+func TestGRPCClient_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*protocol.Module)(nil), new(GRPCClient))
+}
+
+func TestGRPCClient_Configure(t *testing.T) {
+	module := fixtureGRPCModule()
+	module.Configure("test", "consumer.test")
+	assert.Equal(t, "test", module.cluster)
+	assert.Equal(t, "localhost:8100", module.address)
+	assert.Equal(t, time.Second, module.backoffMin)
+	assert.Equal(t, 60*time.Second, module.backoffMax)
+}
+
+func TestGRPCClient_Configure_BadConfig(t *testing.T) {
+	for key, value := range map[string]interface{}{
+		"cluster":               "nocluster",
+		"address":               "nohost",
+		"reconnect-backoff-min": 0,
+		"reconnect-backoff-max": -1,
+		"group-allowlist":       "[",
+		"group-denylist":        "[",
+	} {
+		module := fixtureGRPCModule()
+		viper.Set("consumer.test."+key, value)
+		assert.Panicsf(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic for bad %v", key)
+	}
+
+	module := fixtureGRPCModule()
+	viper.Set("consumer.test.address", "")
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic for no address")
+}
+
+func TestGRPCClient_reconnectDelay(t *testing.T) {
+	module := fixtureGRPCModule()
+	viper.Set("consumer.test.reconnect-backoff-max", 5)
+	module.Configure("test", "consumer.test")
+
+	for failures, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		assert.Equalf(t, expected, module.reconnectDelay(failures), "Expected the backoff after %v failures to be %v", failures, expected)
+	}
+}
+
+func TestGRPCClient_sendConsumerOffset(t *testing.T) {
+	module := fixtureGRPCModule()
+	viper.Set("consumer.test.group-denylist", "^console-")
+	module.Configure("test", "consumer.test")
+	module.App.StorageChannel = make(chan *protocol.StorageRequest, 2)
+
+	module.sendConsumerOffset(&burrowpb.CommittedOffset{Group: "testgroup", Topic: "testtopic", Partition: 1, Offset: 8374, Timestamp: 1500000000000, Sequence: 42})
+	module.sendConsumerOffset(&burrowpb.CommittedOffset{Group: "console-1", Topic: "testtopic", Partition: 1, Offset: 100, Timestamp: 1500000000000})
+	module.sendConsumerOffset(&burrowpb.CommittedOffset{Group: "testgroup", Topic: "testtopic", Partition: 2, Offset: 9000, Timestamp: 1500000001000})
+	close(module.App.StorageChannel)
+
+	request := <-module.App.StorageChannel
+	assert.Equal(t, &protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "test",
+		Topic:       "testtopic",
+		Partition:   1,
+		Group:       "testgroup",
+		Timestamp:   1500000000000,
+		Offset:      8374,
+		Order:       42,
+	}, request)
+
+	// The denied group is not sent, and the timestamp is the order if there is no sequence
+	request = <-module.App.StorageChannel
+	assert.Equal(t, "testgroup", request.Group)
+	assert.Equal(t, int64(1500000001000), request.Order)
+	_, ok := <-module.App.StorageChannel
+	assert.False(t, ok, "Expected no more storage requests")
+}
+
+func TestGRPCClient_StartStop(t *testing.T) {
+	source := &testOffsetSource{
+		streams: [][]*burrowpb.CommittedOffset{
+			{{Group: "testgroup", Topic: "testtopic", Partition: 0, Offset: 100, Timestamp: 1500000000000, Sequence: 1}},
+			{{Group: "testgroup", Topic: "testtopic", Partition: 0, Offset: 200, Timestamp: 1500000001000, Sequence: 2}},
+		},
+		calls: make(chan *burrowpb.StreamOffsetsRequest, 2),
+	}
+	ln, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err, "Expected listener setup to return no error")
+	server := grpc.NewServer()
+	burrowpb.RegisterOffsetSourceServer(server, source)
+	go server.Serve(ln)
+	defer server.Stop()
+
+	module := fixtureGRPCModule()
+	viper.Set("consumer.test.address", ln.Addr().String())
+	module.Configure("test", "consumer.test")
+	module.backoffMin = 10 * time.Millisecond
+
+	err = module.Start()
+	assert.NoError(t, err, "Expected Start to return no error")
+
+	// The offsets from the stream are sent to storage, and the stream is opened again after it fails
+	request := <-module.App.StorageChannel
+	assert.Equal(t, protocol.StorageSetConsumerOffset, request.RequestType)
+	assert.Equal(t, int64(100), request.Offset)
+	request = <-module.App.StorageChannel
+	assert.Equal(t, int64(200), request.Offset)
+	assert.Len(t, source.calls, 2, "Expected the stream to be opened twice")
+	assert.Equal(t, "test", (<-source.calls).GetCluster())
+
+	err = module.Stop()
+	assert.NoError(t, err, "Expected Stop to return no error")
+}
+
+func TestGRPCClient_Start_ServerDown(t *testing.T) {
+	// Nothing is listening on the address, so the stream fails to open, but Start does not fail
+	ln, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err, "Expected listener setup to return no error")
+	address := ln.Addr().String()
+	ln.Close()
+
+	module := fixtureGRPCModule()
+	viper.Set("consumer.test.address", address)
+	module.Configure("test", "consumer.test")
+
+	err = module.Start()
+	assert.NoError(t, err, "Expected Start to return no error")
+	err = module.Stop()
+	assert.NoError(t, err, "Expected Stop to return no error")
+}
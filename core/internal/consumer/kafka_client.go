@@ -13,6 +13,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"os"
 	"regexp"
 	"sync"
@@ -49,8 +50,19 @@ type KafkaClient struct {
 	groupAllowlist        *regexp.Regexp
 	groupDenylist         *regexp.Regexp
 
-	quitChannel chan struct{}
-	running     sync.WaitGroup
+	// The result of the last fetch of each group's offsets, for troubleshooting. See recordGroupFetch
+	fetchStatus     map[string]*protocol.ConsumerGroupFetchStatus
+	fetchStatusLock sync.Mutex
+
+	// The estimated skew between Burrow's clock and the brokers', checked every clockSkewInterval. See kafka_clockskew.go
+	clockSkew          clockSkew
+	clockSkewInterval  int
+	clockSkewThreshold time.Duration
+	clockSkewTicker    *time.Ticker
+
+	quitChannel    chan struct{}
+	requestChannel chan *protocol.ConsumerRequest
+	running        sync.WaitGroup
 }
 
 type offsetKey struct {
@@ -93,14 +105,16 @@ func (module *KafkaClient) Configure(name, configRoot string) {
 
 	module.name = name
 	module.quitChannel = make(chan struct{})
+	module.requestChannel = make(chan *protocol.ConsumerRequest)
 	module.running = sync.WaitGroup{}
+	module.fetchStatus = make(map[string]*protocol.ConsumerGroupFetchStatus)
 
 	module.cluster = viper.GetString(configRoot + ".cluster")
 	if !viper.IsSet("cluster." + module.cluster) {
 		panic("Consumer '" + name + "' references an unknown cluster '" + module.cluster + "'")
 	}
 
-	profile := viper.GetString(configRoot + ".client-profile")
+	profile := helpers.GetClientProfileName(configRoot)
 	module.saramaConfig = helpers.GetSaramaConfigFromClientProfile(profile)
 
 	module.servers = viper.GetStringSlice(configRoot + ".servers")
@@ -115,7 +129,21 @@ func (module *KafkaClient) Configure(name, configRoot string) {
 	module.offsetsTopic = viper.GetString(configRoot + ".offsets-topic")
 	module.startLatest = viper.GetBool(configRoot + ".start-latest")
 	module.backfillEarliest = module.startLatest && viper.GetBool(configRoot+".backfill-earliest")
-	module.reportedConsumerGroup = "burrow-" + module.name
+	module.reportedConsumerGroup = helpers.ReportedConsumerGroup(module.name)
+
+	// The clock skew between Burrow and the brokers is estimated from the offsets topic messages, and checked every
+	// clock-skew-interval seconds. A warning is logged if it is more than clock-skew-threshold seconds
+	viper.SetDefault(configRoot+".clock-skew-interval", 60)
+	viper.SetDefault(configRoot+".clock-skew-threshold", 5)
+	module.clockSkewInterval = viper.GetInt(configRoot + ".clock-skew-interval")
+	if module.clockSkewInterval <= 0 {
+		panic("Consumer '" + name + "' clock-skew-interval must be greater than zero")
+	}
+	clockSkewThreshold := viper.GetFloat64(configRoot + ".clock-skew-threshold")
+	if clockSkewThreshold <= 0 {
+		panic("Consumer '" + name + "' clock-skew-threshold must be greater than zero")
+	}
+	module.clockSkewThreshold = time.Duration(clockSkewThreshold * float64(time.Second))
 
 	// Check for disallowed config values
 	if viper.IsSet(configRoot+".group-whitelist") || viper.IsSet(configRoot+".group-blacklist") {
@@ -164,15 +192,24 @@ func (module *KafkaClient) Start() error {
 			}
 		}
 	}
+	if err != nil {
+		module.Log.Error("failed to connect to cluster", zap.Error(err))
+		return fmt.Errorf("failed to connect to cluster %v: %w", module.cluster, err)
+	}
 
 	// Start the consumers
-	err = module.startKafkaConsumer(&helpers.BurrowSaramaClient{Client: client})
+	saramaClient := &helpers.BurrowSaramaClient{Client: client}
+	err = module.startKafkaConsumer(saramaClient)
 	if err != nil {
 		module.Log.Error("failed to start consumer", zap.Error(err))
 		client.Close()
 		return err
 	}
 
+	module.clockSkewTicker = time.NewTicker(time.Duration(module.clockSkewInterval) * time.Second)
+	module.running.Add(1)
+	go module.requestLoop(saramaClient)
+
 	return nil
 }
 
@@ -182,6 +219,9 @@ func (module *KafkaClient) Stop() error {
 
 	close(module.quitChannel)
 	module.running.Wait()
+	if module.clockSkewTicker != nil {
+		module.clockSkewTicker.Stop()
+	}
 
 	return nil
 }
@@ -269,6 +309,9 @@ func (module *KafkaClient) partitionConsumer(consumer sarama.PartitionConsumer,
 				helpers.TimeoutSendStorageRequest(module.App.StorageChannel, burrowOffset, 1)
 			}
 
+			if stopAtOffset == nil {
+				module.observeClockSkew(consumer, msg)
+			}
 			module.processConsumerOffsetsMessage(msg)
 
 			if stopAtOffset != nil && msg.Offset >= stopAtOffset.Value {
@@ -491,6 +534,7 @@ func (module *KafkaClient) decodeKeyAndOffset(offsetOrder int64, keyBuffer *byte
 		offsetLogger.Warn("failed to decode",
 			zap.String("reason", "no value version"),
 		)
+		module.recordGroupFetch(offsetKey.Group, errors.New("failed to decode offset commit: no value version"))
 		return
 	}
 
@@ -506,6 +550,7 @@ func (module *KafkaClient) decodeKeyAndOffset(offsetOrder int64, keyBuffer *byte
 			zap.String("reason", "value version"),
 			zap.Int16("version", valueVersion),
 		)
+		module.recordGroupFetch(offsetKey.Group, fmt.Errorf("failed to decode offset commit: unknown value version %v", valueVersion))
 	}
 }
 
@@ -517,6 +562,7 @@ func (module *KafkaClient) decodeAndSendOffset(offsetOrder int64, offsetKey offs
 			zap.Int64("timestamp", offsetValue.Timestamp),
 			zap.String("reason", errorAt),
 		)
+		module.recordGroupFetch(offsetKey.Group, errors.New("failed to decode offset commit: "+errorAt))
 		return
 	}
 
@@ -535,6 +581,7 @@ func (module *KafkaClient) decodeAndSendOffset(offsetOrder int64, offsetKey offs
 		zap.Int64("timestamp", offsetValue.Timestamp),
 	)
 	helpers.TimeoutSendStorageRequest(module.App.StorageChannel, partitionOffset, 1)
+	module.recordGroupFetch(offsetKey.Group, nil)
 }
 
 func (module *KafkaClient) decodeGroupMetadata(keyBuffer *bytes.Buffer, value []byte, logger *zap.Logger) {
@@ -657,6 +704,7 @@ func (module *KafkaClient) decodeAndSendGroupMetadata(valueVersion int16, group
 					Group:       group,
 					Owner:       member.ClientHost,
 					ClientID:    member.ClientID,
+					InstanceID:  member.GroupInstanceID,
 				}, 1)
 			}
 		}
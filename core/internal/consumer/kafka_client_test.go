@@ -588,6 +588,12 @@ func TestKafkaClient_decodeKeyAndOffset(t *testing.T) {
 	assert.Equalf(t, int64(8372), request.Offset, "Expected Offset to be 8372, not %v", request.Offset)
 	assert.Equalf(t, int64(543), request.Order, "Expected Order to be 543, not %v", request.Offset)
 	assert.Equalf(t, int64(1637), request.Timestamp, "Expected Timestamp to be 1637, not %v", request.Timestamp)
+
+	// The commit is recorded as a successful fetch for the group, once it has been sent to storage
+	time.Sleep(10 * time.Millisecond)
+	module.fetchStatusLock.Lock()
+	assert.Equal(t, "no error", module.fetchStatus["testgroup"].LastError)
+	module.fetchStatusLock.Unlock()
 }
 
 func TestKafkaClient_decodeKeyAndOffset_ValueVersion4(t *testing.T) {
@@ -654,6 +660,10 @@ func TestKafkaClient_decodeAndSendOffset_ErrorValue(t *testing.T) {
 
 	module.decodeAndSendOffset(0, offsetKey, valueBuf, zap.NewNop(), decodeOffsetValueV0)
 	// Should not timeout
+
+	// The failure is recorded against the group
+	assert.Equal(t, "failed to decode offset commit: metadata", module.fetchStatus["testgroup"].LastError)
+	assert.NotZero(t, module.fetchStatus["testgroup"].LastFailure, "Expected the time of the failed fetch")
 }
 
 func TestKafkaClient_decodeGroupMetadata(t *testing.T) {
@@ -0,0 +1,86 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package consumer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/internal/httpserver"
+)
+
+// clockSkew estimates how far Burrow's clock is ahead of the brokers' clocks. The messages in the offsets topic are
+// written by the group coordinators, so their timestamps come from the broker clocks, while the cluster module stamps
+// the end offsets with Burrow's clock. If the clocks disagree, the lag in time between the two is off by the skew.
+//
+// For a message that is read as soon as it is written, the difference between the time it is read and its timestamp
+// is the skew plus the time it took to be delivered. The delivery time is never negative, so the smallest difference
+// seen in each interval is used as the estimate.
+type clockSkew struct {
+	lock    sync.Mutex
+	min     time.Duration
+	samples int
+}
+
+// observe records the difference between the time a message was read and its broker timestamp
+func (cs *clockSkew) observe(difference time.Duration) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	if (cs.samples == 0) || (difference < cs.min) {
+		cs.min = difference
+	}
+	cs.samples++
+}
+
+// take returns the estimated skew from the differences observed since the last call, and resets them. If there were
+// none, false is returned.
+func (cs *clockSkew) take() (time.Duration, bool) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	if cs.samples == 0 {
+		return 0, false
+	}
+	skew := cs.min
+	cs.samples = 0
+	return skew, true
+}
+
+// observeClockSkew records the difference between Burrow's clock and the timestamp of a message from the offsets
+// topic. Only messages at the end of the partition are used, as older messages (such as when the consumer starts, or
+// falls behind) were not just written. Messages have no timestamp before Kafka 0.10.
+func (module *KafkaClient) observeClockSkew(consumer sarama.PartitionConsumer, msg *sarama.ConsumerMessage) {
+	if msg.Timestamp.IsZero() || (msg.Offset < consumer.HighWaterMarkOffset()-1) {
+		return
+	}
+	module.clockSkew.observe(time.Since(msg.Timestamp))
+}
+
+// checkClockSkew sets the estimated clock skew for the cluster in the burrow_kafka_cluster_clock_skew_seconds metric,
+// and logs a warning if it is more than the threshold. If no messages were read at the end of the offsets topic since
+// the last check, the metric is left as it is.
+func (module *KafkaClient) checkClockSkew() {
+	skew, ok := module.clockSkew.take()
+	if !ok {
+		return
+	}
+
+	httpserver.SetClusterClockSkew(module.cluster, skew)
+	if (skew > module.clockSkewThreshold) || (skew < -module.clockSkewThreshold) {
+		module.Log.Warn("clock skew between Burrow and the brokers is over the threshold",
+			zap.Duration("skew", skew),
+			zap.Duration("threshold", module.clockSkewThreshold),
+		)
+	}
+}
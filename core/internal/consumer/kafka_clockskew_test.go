@@ -0,0 +1,77 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+)
+
+func TestClockSkew(t *testing.T) {
+	cs := &clockSkew{}
+	_, ok := cs.take()
+	assert.False(t, ok, "Expected no skew with no samples")
+
+	// The smallest difference is the estimate, as the rest include delivery time
+	cs.observe(3 * time.Second)
+	cs.observe(2 * time.Second)
+	cs.observe(5 * time.Second)
+	skew, ok := cs.take()
+	assert.True(t, ok, "Expected a skew")
+	assert.Equal(t, 2*time.Second, skew)
+
+	// Taking the skew resets the samples
+	_, ok = cs.take()
+	assert.False(t, ok, "Expected no skew after taking it")
+	cs.observe(-4 * time.Second)
+	skew, _ = cs.take()
+	assert.Equal(t, -4*time.Second, skew)
+}
+
+func TestKafkaClient_Configure_ClockSkew(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "consumer.test")
+	assert.Equal(t, 60, module.clockSkewInterval, "Expected default clock-skew-interval of 60")
+	assert.Equal(t, 5*time.Second, module.clockSkewThreshold, "Expected default clock-skew-threshold of 5s")
+
+	module = fixtureModule()
+	viper.Set("consumer.test.clock-skew-interval", 0)
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+
+	module = fixtureModule()
+	viper.Set("consumer.test.clock-skew-threshold", -1)
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestKafkaClient_observeClockSkew(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "consumer.test")
+
+	consumer := &helpers.MockSaramaPartitionConsumer{}
+	consumer.On("HighWaterMarkOffset").Return(int64(100))
+
+	// Messages with no timestamp, and messages that are not at the end of the partition, are not used
+	module.observeClockSkew(consumer, &sarama.ConsumerMessage{Offset: 99})
+	module.observeClockSkew(consumer, &sarama.ConsumerMessage{Offset: 50, Timestamp: time.Now().Add(-time.Hour)})
+	_, ok := module.clockSkew.take()
+	assert.False(t, ok, "Expected no skew from old messages")
+
+	// The broker clock is ten seconds behind
+	module.observeClockSkew(consumer, &sarama.ConsumerMessage{Offset: 99, Timestamp: time.Now().Add(-10 * time.Second)})
+	skew, ok := module.clockSkew.take()
+	assert.True(t, ok, "Expected a skew")
+	assert.InDelta(t, 10.0, skew.Seconds(), 1.0, "Expected a skew of about 10 seconds")
+}
@@ -0,0 +1,207 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package consumer
+
+import (
+	"errors"
+	"math"
+	"time"
+	"unicode/utf16"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// GetCommunicationChannel returns the channel that the consumer Coordinator forwards requests for this module's cluster
+// on. The requests are handled one at a time, in a goroutine apart from the offsets topic consumers.
+func (module *KafkaClient) GetCommunicationChannel() chan *protocol.ConsumerRequest {
+	return module.requestChannel
+}
+
+func (module *KafkaClient) requestLoop(client helpers.SaramaClient) {
+	defer module.running.Done()
+
+	for {
+		select {
+		case request := <-module.requestChannel:
+			module.handleRequest(client, request)
+		case <-module.clockSkewTicker.C:
+			module.checkClockSkew()
+		case <-module.quitChannel:
+			return
+		}
+	}
+}
+
+func (module *KafkaClient) handleRequest(client helpers.SaramaClient, request *protocol.ConsumerRequest) {
+	switch request.RequestType {
+	case protocol.ConsumerRefreshGroup:
+		module.refreshGroup(client, request)
+	case protocol.ConsumerFetchGroupStatus:
+		module.fetchGroupStatus(request)
+	default:
+		module.Log.Error("unknown consumer request type", zap.Int("request_type", int(request.RequestType)))
+		close(request.Reply)
+	}
+}
+
+// refreshGroup fetches the committed offsets for a single group from the group's coordinator and sends them to storage,
+// for troubleshooting the group without waiting for its commits to be read from the offsets topic.
+//
+// The fetched offsets are ordered as if they were read from the offsets topic partition that the group commits to, at
+// the end offset of that partition from just before the fetch. Storage orders each partition's commits, so a commit
+// that is read from the offsets topic afterwards is stored after the fetched offset (or dropped, if it is the commit at
+// that same position) rather than being overwritten by it.
+func (module *KafkaClient) refreshGroup(client helpers.SaramaClient, request *protocol.ConsumerRequest) {
+	defer close(request.Reply)
+
+	result := &protocol.ConsumerGroupOffsets{
+		Consumer: module.name,
+		Group:    request.Group,
+	}
+	err := module.fetchGroupOffsets(client, request.Group, result)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	module.recordGroupFetch(request.Group, err)
+
+	module.Log.Info("refreshed group offsets",
+		zap.String("group", result.Group),
+		zap.Int("partitions", result.Partitions),
+		zap.Int("partition_errors", result.PartitionErrors),
+		zap.String("error", result.Error),
+	)
+	request.Reply <- result
+}
+
+// fetchGroupStatus replies with the result of the last fetch of the group's offsets. A group that has not been fetched
+// gets a reply with no error and no fetch times.
+func (module *KafkaClient) fetchGroupStatus(request *protocol.ConsumerRequest) {
+	defer close(request.Reply)
+
+	result := &protocol.ConsumerGroupFetchStatus{
+		Consumer: module.name,
+		Group:    request.Group,
+	}
+	module.fetchStatusLock.Lock()
+	if status, ok := module.fetchStatus[request.Group]; ok {
+		*result = *status
+	}
+	module.fetchStatusLock.Unlock()
+	request.Reply <- result
+}
+
+// recordGroupFetch records the result of fetching offsets for a group, either refreshing them from the coordinator or
+// reading a commit from the offsets topic, so that the last error can be looked up without the logs.
+func (module *KafkaClient) recordGroupFetch(group string, err error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	module.fetchStatusLock.Lock()
+	defer module.fetchStatusLock.Unlock()
+	status, ok := module.fetchStatus[group]
+	if !ok {
+		status = &protocol.ConsumerGroupFetchStatus{
+			Consumer: module.name,
+			Group:    group,
+		}
+		module.fetchStatus[group] = status
+	}
+	if err != nil {
+		status.LastError = err.Error()
+		status.LastFailure = now
+	} else {
+		status.LastError = "no error"
+		status.LastSuccess = now
+	}
+}
+
+func (module *KafkaClient) fetchGroupOffsets(client helpers.SaramaClient, group string, result *protocol.ConsumerGroupOffsets) error {
+	if !module.acceptConsumerGroup(group) {
+		return errors.New("group is excluded by the group-allowlist or group-denylist")
+	}
+
+	// Fetching the offsets for all of the group's topics, without listing them, needs version 2 of the request
+	version := client.Config().Version
+	if !version.IsAtLeast(sarama.V0_10_2_0) {
+		return errors.New("fetching a group's offsets requires Kafka 0.10.2 or later")
+	}
+
+	partitions, err := client.Partitions(module.offsetsTopic)
+	if err != nil {
+		return err
+	}
+	if len(partitions) == 0 {
+		return errors.New("the offsets topic has no partitions")
+	}
+	endOffset, err := client.GetOffset(module.offsetsTopic, groupOffsetsPartition(group, len(partitions)), sarama.OffsetNewest)
+	if err != nil {
+		return err
+	}
+
+	broker, err := client.Coordinator(group)
+	if err != nil {
+		return err
+	}
+	response, err := broker.FetchOffset(sarama.NewOffsetFetchRequest(version, group, nil))
+	if err != nil {
+		return err
+	}
+	if kerr := response.GroupError(); kerr != sarama.ErrNoError {
+		return kerr
+	}
+
+	blocks := response.Blocks
+	if len(response.Groups) > 0 {
+		blocks = response.Groups[0].Blocks
+	}
+	timestamp := time.Now().Unix() * 1000
+	for topic, topicBlocks := range blocks {
+		for partition, block := range topicBlocks {
+			if block.Err != sarama.ErrNoError {
+				result.PartitionErrors++
+				continue
+			}
+			if block.Offset < 0 {
+				// The group has no committed offset for the partition
+				continue
+			}
+			result.Partitions++
+			helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+				RequestType: protocol.StorageSetConsumerOffset,
+				Cluster:     module.cluster,
+				Topic:       topic,
+				Partition:   partition,
+				Group:       group,
+				Timestamp:   timestamp,
+				Offset:      block.Offset,
+				Order:       endOffset - 1,
+			}, 1)
+		}
+	}
+	return nil
+}
+
+// groupOffsetsPartition returns the partition of the offsets topic that a group's commits are written to. Kafka picks
+// it from the Java hash code of the group name.
+func groupOffsetsPartition(group string, partitionCount int) int32 {
+	var hash int32
+	for _, char := range utf16.Encode([]rune(group)) {
+		hash = 31*hash + int32(char)
+	}
+	if hash == math.MinInt32 {
+		hash = 0
+	} else if hash < 0 {
+		hash = -hash
+	}
+	return hash % int32(partitionCount)
+}
@@ -0,0 +1,216 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package consumer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func fixtureRefreshClient(version sarama.KafkaVersion) (*helpers.MockSaramaClient, *helpers.MockSaramaBroker) {
+	config := sarama.NewConfig()
+	config.Version = version
+
+	partitions := make([]int32, 50)
+	for i := range partitions {
+		partitions[i] = int32(i)
+	}
+
+	broker := &helpers.MockSaramaBroker{}
+	client := &helpers.MockSaramaClient{}
+	client.On("Config").Return(config)
+	client.On("Partitions", "__consumer_offsets").Return(partitions, nil)
+	client.On("GetOffset", "__consumer_offsets", int32(27), sarama.OffsetNewest).Return(int64(456), nil)
+	client.On("Coordinator", "testgroup").Return(broker, nil)
+	return client, broker
+}
+
+func TestKafkaClient_ImplementsConsumerModule(t *testing.T) {
+	assert.Implements(t, (*Module)(nil), new(KafkaClient))
+}
+
+func TestKafkaClient_refreshGroup(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "consumer.test")
+
+	client, broker := fixtureRefreshClient(sarama.V2_0_0_0)
+	broker.On("FetchOffset", mock.MatchedBy(func(request *sarama.OffsetFetchRequest) bool {
+		return request.ConsumerGroup == "testgroup"
+	})).Return(&sarama.OffsetFetchResponse{
+		Version: 4,
+		Blocks: map[string]map[int32]*sarama.OffsetFetchResponseBlock{
+			"testtopic": {
+				0: {Offset: 1234},
+				1: {Offset: -1},
+				2: {Err: sarama.ErrUnknownTopicOrPartition},
+			},
+		},
+	}, nil)
+
+	request := &protocol.ConsumerRequest{
+		RequestType: protocol.ConsumerRefreshGroup,
+		Cluster:     "test",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.handleRequest(client, request)
+
+	// Only the partition with a committed offset is stored, ordered at the end of the group's offsets topic partition
+	stored := <-module.App.StorageChannel
+	assert.Equal(t, protocol.StorageSetConsumerOffset, stored.RequestType)
+	assert.Equal(t, "test", stored.Cluster)
+	assert.Equal(t, "testgroup", stored.Group)
+	assert.Equal(t, "testtopic", stored.Topic)
+	assert.Equal(t, int32(0), stored.Partition)
+	assert.Equal(t, int64(1234), stored.Offset)
+	assert.Equal(t, int64(455), stored.Order)
+
+	response := <-request.Reply
+	assert.Equal(t, &protocol.ConsumerGroupOffsets{
+		Consumer:        "test",
+		Group:           "testgroup",
+		Partitions:      1,
+		PartitionErrors: 1,
+	}, response)
+
+	_, ok := <-request.Reply
+	assert.False(t, ok, "Expected the reply channel to be closed")
+	client.AssertExpectations(t)
+	broker.AssertExpectations(t)
+}
+
+func TestKafkaClient_refreshGroup_GroupError(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "consumer.test")
+
+	client, broker := fixtureRefreshClient(sarama.V2_0_0_0)
+	broker.On("FetchOffset", mock.Anything).Return(&sarama.OffsetFetchResponse{
+		Version: 4,
+		Err:     sarama.ErrNotCoordinatorForConsumer,
+	}, nil)
+
+	request := &protocol.ConsumerRequest{
+		RequestType: protocol.ConsumerRefreshGroup,
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.refreshGroup(client, request)
+
+	response := (<-request.Reply).(*protocol.ConsumerGroupOffsets)
+	assert.Equal(t, sarama.ErrNotCoordinatorForConsumer.Error(), response.Error)
+	assert.Equal(t, 0, response.Partitions)
+}
+
+func TestKafkaClient_refreshGroup_OldVersion(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "consumer.test")
+
+	client, broker := fixtureRefreshClient(sarama.V0_10_0_0)
+	request := &protocol.ConsumerRequest{
+		RequestType: protocol.ConsumerRefreshGroup,
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.refreshGroup(client, request)
+
+	response := (<-request.Reply).(*protocol.ConsumerGroupOffsets)
+	assert.NotEmpty(t, response.Error, "Expected an error for a Kafka version that cannot fetch all offsets")
+	broker.AssertNotCalled(t, "FetchOffset", mock.Anything)
+}
+
+func TestKafkaClient_refreshGroup_Denylist(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("consumer.test.group-denylist", "^test.*$")
+	module.Configure("test", "consumer.test")
+
+	client, _ := fixtureRefreshClient(sarama.V2_0_0_0)
+	request := &protocol.ConsumerRequest{
+		RequestType: protocol.ConsumerRefreshGroup,
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.refreshGroup(client, request)
+
+	response := (<-request.Reply).(*protocol.ConsumerGroupOffsets)
+	assert.NotEmpty(t, response.Error, "Expected an error for a denylisted group")
+	client.AssertNotCalled(t, "Coordinator", "testgroup")
+}
+
+func TestGroupOffsetsPartition(t *testing.T) {
+	// These match the partitions that Kafka picks with 50 offsets topic partitions
+	assert.Equal(t, int32(27), groupOffsetsPartition("testgroup", 50))
+	assert.Equal(t, int32(47), groupOffsetsPartition("a", 50))
+	assert.Equal(t, int32(24), groupOffsetsPartition("console-consumer-1", 50))
+}
+
+func TestKafkaClient_fetchGroupStatus(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "consumer.test")
+
+	// A group that has not been fetched has no error and no fetch times
+	request := &protocol.ConsumerRequest{
+		RequestType: protocol.ConsumerFetchGroupStatus,
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.handleRequest(nil, request)
+	response := <-request.Reply
+	assert.Equal(t, &protocol.ConsumerGroupFetchStatus{Consumer: "test", Group: "testgroup"}, response)
+
+	module.recordGroupFetch("testgroup", nil)
+	module.recordGroupFetch("testgroup", errors.New("coordinator down"))
+
+	request = &protocol.ConsumerRequest{
+		RequestType: protocol.ConsumerFetchGroupStatus,
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.handleRequest(nil, request)
+	status := (<-request.Reply).(*protocol.ConsumerGroupFetchStatus)
+	assert.Equal(t, "coordinator down", status.LastError)
+	assert.NotZero(t, status.LastSuccess, "Expected the time of the successful fetch")
+	assert.NotZero(t, status.LastFailure, "Expected the time of the failed fetch")
+
+	// A later success clears the error, but keeps the time of the failure
+	module.recordGroupFetch("testgroup", nil)
+	module.fetchStatusLock.Lock()
+	assert.Equal(t, "no error", module.fetchStatus["testgroup"].LastError)
+	assert.Equal(t, status.LastFailure, module.fetchStatus["testgroup"].LastFailure)
+	module.fetchStatusLock.Unlock()
+}
+
+func TestKafkaClient_refreshGroup_RecordsFetch(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("consumer.test.group-denylist", "^test.*$")
+	module.Configure("test", "consumer.test")
+
+	client, _ := fixtureRefreshClient(sarama.V2_0_0_0)
+	request := &protocol.ConsumerRequest{
+		RequestType: protocol.ConsumerRefreshGroup,
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.refreshGroup(client, request)
+	response := (<-request.Reply).(*protocol.ConsumerGroupOffsets)
+
+	module.fetchStatusLock.Lock()
+	defer module.fetchStatusLock.Unlock()
+	assert.Equal(t, response.Error, module.fetchStatus["testgroup"].LastError)
+	assert.NotZero(t, module.fetchStatus["testgroup"].LastFailure, "Expected the time of the failed fetch")
+	assert.Zero(t, module.fetchStatus["testgroup"].LastSuccess, "Expected no successful fetch")
+}
@@ -0,0 +1,342 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package emitter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// The most metrics that CloudWatch accepts in a single PutMetricData call
+const cloudWatchMaxBatch = 1000
+
+// CloudWatchEmitter is a module that puts the lag for each consumer group to AWS CloudWatch, every interval. Each group
+// has a TotalLag and a MaxLag metric (the lag of its partition with the most lag), with Cluster and ConsumerGroup
+// dimensions, in the configured namespace. The metrics are sent with the PutMetricData API of the AWS SDK, using the
+// credentials from the default AWS credential chain (environment, shared config, or the instance or task role).
+type CloudWatchEmitter struct {
+	// App is a pointer to the application context. This stores the channel to the storage subsystem
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name           string
+	cluster        string
+	groupAllowlist *regexp.Regexp
+	groupDenylist  *regexp.Regexp
+	namespace      string
+	interval       int
+	batchSize      int
+	maxRetries     int
+	backoff        time.Duration
+	client         *cloudwatch.Client
+
+	quitChannel chan struct{}
+	running     sync.WaitGroup
+}
+
+// cloudWatchDatum is a single value for a metric of a group
+type cloudWatchDatum struct {
+	metric  string
+	cluster string
+	group   string
+	value   uint64
+}
+
+// Configure validates the configuration for the module. The AWS region is taken from the region config, or from the
+// default AWS config (such as the AWS_REGION environment variable) if it is not set, and the module panics if there is
+// neither. The lag is put to the namespace (Burrow by default) every interval seconds (60 by default), for all clusters
+// unless cluster is set, and only for the groups that match the group-allowlist and group-denylist, if they are set.
+//
+// The metrics are sent in batches of up to batch-size (1000 by default, which is the most that CloudWatch accepts). A
+// batch that is throttled, or fails with a server error, is retried up to max-retries times (3 by default), waiting for
+// backoff seconds (1 by default) before the first retry and doubling the wait for each retry after it.
+func (module *CloudWatchEmitter) Configure(name, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.cluster = viper.GetString(configRoot + ".cluster")
+	module.quitChannel = make(chan struct{})
+
+	viper.SetDefault(configRoot+".namespace", "Burrow")
+	viper.SetDefault(configRoot+".interval", 60)
+	viper.SetDefault(configRoot+".batch-size", cloudWatchMaxBatch)
+	viper.SetDefault(configRoot+".max-retries", 3)
+	viper.SetDefault(configRoot+".backoff", 1)
+	viper.SetDefault(configRoot+".timeout", 10)
+	module.namespace = viper.GetString(configRoot + ".namespace")
+	module.interval = viper.GetInt(configRoot + ".interval")
+	module.batchSize = viper.GetInt(configRoot + ".batch-size")
+	module.maxRetries = viper.GetInt(configRoot + ".max-retries")
+	module.backoff = time.Duration(viper.GetInt(configRoot+".backoff")) * time.Second
+	if module.namespace == "" {
+		panic("emitter " + name + ": namespace must not be empty")
+	}
+	if module.interval <= 0 {
+		panic("emitter " + name + ": interval must be greater than zero")
+	}
+	if (module.batchSize < 1) || (module.batchSize > cloudWatchMaxBatch) {
+		panic("emitter " + name + ": batch-size must be between 1 and " + strconv.Itoa(cloudWatchMaxBatch))
+	}
+	if module.maxRetries < 0 {
+		panic("emitter " + name + ": max-retries must be zero or greater")
+	}
+	if module.backoff <= 0 {
+		panic("emitter " + name + ": backoff must be greater than zero")
+	}
+
+	allowlist := viper.GetString(configRoot + ".group-allowlist")
+	if allowlist != "" {
+		re, err := regexp.Compile(allowlist)
+		if err != nil {
+			module.Log.Panic("Failed to compile group allowlist")
+			panic(err)
+		}
+		module.groupAllowlist = re
+	}
+
+	denylist := viper.GetString(configRoot + ".group-denylist")
+	if denylist != "" {
+		re, err := regexp.Compile(denylist)
+		if err != nil {
+			module.Log.Panic("Failed to compile group denylist")
+			panic(err)
+		}
+		module.groupDenylist = re
+	}
+
+	// The region and credentials come from the default AWS config, the same as for the AWS CLI
+	loadOptions := make([]func(*config.LoadOptions) error, 0)
+	if region := viper.GetString(configRoot + ".region"); region != "" {
+		loadOptions = append(loadOptions, config.WithRegion(region))
+	}
+	awsConfig, err := config.LoadDefaultConfig(context.Background(), loadOptions...)
+	if err != nil {
+		panic("emitter " + name + ": failed to load the AWS config: " + err.Error())
+	}
+	if awsConfig.Region == "" {
+		panic("emitter " + name + ": region must be set, either in the config or for the AWS SDK")
+	}
+
+	// The endpoint can be set for a VPC endpoint, or for testing
+	endpoint := viper.GetString(configRoot + ".endpoint")
+	if endpoint != "" {
+		if _, err := url.ParseRequestURI(endpoint); err != nil {
+			panic("emitter " + name + ": endpoint is not a valid URL: " + err.Error())
+		}
+	}
+
+	// Throttled and failed requests are retried by the SDK, with the backoff from the config rather than its own. The
+	// retries are not rate limited, as there is only one request at a time
+	module.client = cloudwatch.NewFromConfig(awsConfig, func(options *cloudwatch.Options) {
+		if endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
+		}
+		options.HTTPClient = &http.Client{
+			Timeout: viper.GetDuration(configRoot+".timeout") * time.Second,
+		}
+		options.Retryer = retry.NewStandard(func(retryOptions *retry.StandardOptions) {
+			retryOptions.MaxAttempts = module.maxRetries + 1
+			retryOptions.RateLimiter = ratelimit.None
+			retryOptions.Backoff = retry.BackoffDelayerFunc(func(attempt int, err error) (time.Duration, error) {
+				return module.backoff << (attempt - 1), nil
+			})
+		})
+	})
+}
+
+// Start starts the goroutine that puts the metrics every interval
+func (module *CloudWatchEmitter) Start() error {
+	module.Log.Info("starting")
+
+	module.running.Add(1)
+	go module.mainLoop()
+	return nil
+}
+
+// Stop stops the module, waiting for a batch that is being sent to finish. A batch that is waiting to be retried is
+// dropped.
+func (module *CloudWatchEmitter) Stop() error {
+	module.Log.Info("stopping")
+
+	close(module.quitChannel)
+	module.running.Wait()
+	return nil
+}
+
+func (module *CloudWatchEmitter) mainLoop() {
+	defer module.running.Done()
+
+	ticker := time.NewTicker(time.Duration(module.interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			module.emit()
+		case <-module.quitChannel:
+			return
+		}
+	}
+}
+
+// emit collects the lag for all groups from storage and puts it to CloudWatch in batches
+func (module *CloudWatchEmitter) emit() {
+	datums := module.collectLag()
+	timestamp := time.Now()
+	for start := 0; start < len(datums); start += module.batchSize {
+		end := start + module.batchSize
+		if end > len(datums) {
+			end = len(datums)
+		}
+		if !module.sendBatch(datums[start:end], timestamp) {
+			return
+		}
+	}
+	module.Log.Debug("put lag metrics", zap.Int("metrics", len(datums)))
+}
+
+func (module *CloudWatchEmitter) acceptConsumerGroup(group string) bool {
+	if (module.groupAllowlist != nil) && (!module.groupAllowlist.MatchString(group)) {
+		return false
+	}
+	if (module.groupDenylist != nil) && (module.groupDenylist.MatchString(group)) {
+		return false
+	}
+	return true
+}
+
+func (module *CloudWatchEmitter) fetchFromStorage(request *protocol.StorageRequest) interface{} {
+	request.Reply = make(chan interface{})
+	module.App.StorageChannel <- request
+	return <-request.Reply
+}
+
+// collectLag returns the total and max lag for each group, in order by cluster and group
+func (module *CloudWatchEmitter) collectLag() []*cloudWatchDatum {
+	datums := make([]*cloudWatchDatum, 0)
+
+	clusters, _ := module.fetchFromStorage(&protocol.StorageRequest{RequestType: protocol.StorageFetchClusters}).([]string)
+	sort.Strings(clusters)
+	for _, cluster := range clusters {
+		if (module.cluster != "") && (cluster != module.cluster) {
+			continue
+		}
+
+		groups, _ := module.fetchFromStorage(&protocol.StorageRequest{
+			RequestType: protocol.StorageFetchConsumers,
+			Cluster:     cluster,
+		}).([]string)
+		sort.Strings(groups)
+		for _, group := range groups {
+			if !module.acceptConsumerGroup(group) {
+				continue
+			}
+
+			// The group may have expired since the list was fetched
+			topics, ok := module.fetchFromStorage(&protocol.StorageRequest{
+				RequestType: protocol.StorageFetchConsumer,
+				Cluster:     cluster,
+				Group:       group,
+			}).(protocol.ConsumerTopics)
+			if !ok {
+				continue
+			}
+
+			var totalLag, maxLag uint64
+			for _, partitions := range topics {
+				for _, partition := range partitions {
+					totalLag += partition.CurrentLag
+					if partition.CurrentLag > maxLag {
+						maxLag = partition.CurrentLag
+					}
+				}
+			}
+			datums = append(datums,
+				&cloudWatchDatum{metric: "TotalLag", cluster: cluster, group: group, value: totalLag},
+				&cloudWatchDatum{metric: "MaxLag", cluster: cluster, group: group, value: maxLag},
+			)
+		}
+	}
+	return datums
+}
+
+// sendBatch puts a batch of metrics, which the SDK retries with an exponential backoff if it is throttled or fails with a
+// server or connection error. It returns false if the module was stopped while sending it.
+func (module *CloudWatchEmitter) sendBatch(batch []*cloudWatchDatum, timestamp time.Time) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-module.quitChannel:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	input := &cloudwatch.PutMetricDataInput{
+		Namespace:  aws.String(module.namespace),
+		MetricData: make([]types.MetricDatum, len(batch)),
+	}
+	for i, datum := range batch {
+		input.MetricData[i] = types.MetricDatum{
+			MetricName: aws.String(datum.metric),
+			Value:      aws.Float64(float64(datum.value)),
+			Unit:       types.StandardUnitCount,
+			Timestamp:  aws.Time(timestamp),
+			Dimensions: []types.Dimension{
+				{Name: aws.String("Cluster"), Value: aws.String(datum.cluster)},
+				{Name: aws.String("ConsumerGroup"), Value: aws.String(datum.group)},
+			},
+		}
+	}
+
+	_, err := module.client.PutMetricData(ctx, input)
+	if err == nil {
+		return true
+	}
+	select {
+	case <-module.quitChannel:
+		return false
+	default:
+	}
+
+	var exhausted *retry.MaxAttemptsError
+	attempts := 1
+	if errors.As(err, &exhausted) {
+		attempts = exhausted.Attempt
+	}
+	module.Log.Warn("failed to put lag metrics",
+		zap.Int("metrics", len(batch)),
+		zap.Int("attempts", attempts),
+		zap.Error(err),
+	)
+	return true
+}
@@ -0,0 +1,231 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package emitter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// cloudWatchServer records the PutMetricData requests it receives, and responds to each with the next of the statuses
+// it is given (and then 200 when they run out)
+type cloudWatchServer struct {
+	*httptest.Server
+	lock     sync.Mutex
+	requests []url.Values
+	auth     []string
+}
+
+func newCloudWatchServer(statuses ...int) *cloudWatchServer {
+	server := &cloudWatchServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		server.lock.Lock()
+		defer server.lock.Unlock()
+		server.requests = append(server.requests, r.PostForm)
+		server.auth = append(server.auth, r.Header.Get("Authorization"))
+
+		if len(statuses) > 0 {
+			status := statuses[0]
+			statuses = statuses[1:]
+			if status != http.StatusOK {
+				w.WriteHeader(status)
+				w.Write([]byte(`<ErrorResponse><Error><Code>Throttling</Code><Message>Rate exceeded</Message></Error></ErrorResponse>`))
+				return
+			}
+		}
+		w.Write([]byte(`<PutMetricDataResponse></PutMetricDataResponse>`))
+	}))
+	return server
+}
+
+func fixtureModule(endpoint string) *CloudWatchEmitter {
+	module := CloudWatchEmitter{
+		Log: zap.NewNop(),
+	}
+	module.App = &protocol.ApplicationContext{
+		Logger:         zap.NewNop(),
+		StorageChannel: make(chan *protocol.StorageRequest),
+	}
+
+	viper.Reset()
+	viper.Set("emitter.test.class-name", "cloudwatch")
+	viper.Set("emitter.test.region", "us-east-1")
+	viper.Set("emitter.test.endpoint", endpoint)
+	return &module
+}
+
+// useStaticCredentials replaces the credentials from the default AWS config, which the tests cannot rely on
+func useStaticCredentials(module *CloudWatchEmitter) {
+	module.client = cloudwatch.New(module.client.Options(), func(options *cloudwatch.Options) {
+		options.Credentials = credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")
+	})
+}
+
+// respondToStorage answers the storage requests for a cluster with two groups, one of which has lag on two partitions
+func respondToStorage(module *CloudWatchEmitter) {
+	go func() {
+		for request := range module.App.StorageChannel {
+			switch request.RequestType {
+			case protocol.StorageFetchClusters:
+				request.Reply <- []string{"testcluster"}
+			case protocol.StorageFetchConsumers:
+				request.Reply <- []string{"testgroup2", "testgroup"}
+			case protocol.StorageFetchConsumer:
+				if request.Group == "testgroup" {
+					request.Reply <- protocol.ConsumerTopics{
+						"testtopic": {{CurrentLag: 100}, {CurrentLag: 250}},
+					}
+				} else {
+					request.Reply <- protocol.ConsumerTopics{
+						"testtopic": {{CurrentLag: 0}},
+					}
+				}
+			}
+			close(request.Reply)
+		}
+	}()
+}
+
+func TestCloudWatchEmitter_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*protocol.Module)(nil), new(CloudWatchEmitter))
+}
+
+func TestCloudWatchEmitter_Configure(t *testing.T) {
+	module := fixtureModule("")
+	viper.Set("emitter.test.endpoint", "")
+	module.Configure("test", "emitter.test")
+
+	assert.Equal(t, "Burrow", module.namespace)
+	assert.Equal(t, 60, module.interval)
+	assert.Equal(t, 1000, module.batchSize)
+	assert.Equal(t, "us-east-1", module.client.Options().Region)
+	assert.Nil(t, module.client.Options().BaseEndpoint, "Expected the default CloudWatch endpoint")
+}
+
+func TestCloudWatchEmitter_Configure_BadValues(t *testing.T) {
+	for key, value := range map[string]interface{}{
+		"interval":        0,
+		"batch-size":      1001,
+		"max-retries":     -1,
+		"backoff":         0,
+		"namespace":       "",
+		"endpoint":        "not a url",
+		"group-allowlist": "[",
+	} {
+		module := fixtureModule("http://localhost")
+		viper.Set("emitter.test."+key, value)
+		assert.Panicsf(t, func() { module.Configure("test", "emitter.test") }, "Expected panic for bad %v", key)
+	}
+}
+
+func TestCloudWatchEmitter_emit(t *testing.T) {
+	server := newCloudWatchServer()
+	defer server.Close()
+
+	module := fixtureModule(server.URL)
+	viper.Set("emitter.test.namespace", "TestNamespace")
+	viper.Set("emitter.test.batch-size", 3)
+	module.Configure("test", "emitter.test")
+	useStaticCredentials(module)
+	respondToStorage(module)
+
+	module.emit()
+
+	// The four metrics are sent in two batches, signed for CloudWatch
+	assert.Len(t, server.requests, 2, "Expected two requests")
+	first := server.requests[0]
+	assert.Equal(t, "PutMetricData", first.Get("Action"))
+	assert.Equal(t, "TestNamespace", first.Get("Namespace"))
+	assert.Equal(t, "TotalLag", first.Get("MetricData.member.1.MetricName"))
+	assert.Equal(t, "350", first.Get("MetricData.member.1.Value"))
+	assert.Equal(t, "testcluster", first.Get("MetricData.member.1.Dimensions.member.1.Value"))
+	assert.Equal(t, "testgroup", first.Get("MetricData.member.1.Dimensions.member.2.Value"))
+	assert.Equal(t, "MaxLag", first.Get("MetricData.member.2.MetricName"))
+	assert.Equal(t, "250", first.Get("MetricData.member.2.Value"))
+	assert.Equal(t, "testgroup2", first.Get("MetricData.member.3.Dimensions.member.2.Value"))
+	assert.Empty(t, first.Get("MetricData.member.4.MetricName"), "Expected only three metrics in the first batch")
+	assert.Equal(t, "MaxLag", server.requests[1].Get("MetricData.member.1.MetricName"))
+	assert.True(t, strings.HasPrefix(server.auth[0], "AWS4-HMAC-SHA256 Credential=AKID/"), "Expected request to be signed")
+	assert.Contains(t, server.auth[0], "/us-east-1/monitoring/aws4_request")
+}
+
+func TestCloudWatchEmitter_emit_Denylist(t *testing.T) {
+	server := newCloudWatchServer()
+	defer server.Close()
+
+	module := fixtureModule(server.URL)
+	viper.Set("emitter.test.group-denylist", "2$")
+	module.Configure("test", "emitter.test")
+	useStaticCredentials(module)
+	respondToStorage(module)
+
+	module.emit()
+
+	assert.Len(t, server.requests, 1, "Expected one request")
+	assert.Equal(t, "testgroup", server.requests[0].Get("MetricData.member.2.Dimensions.member.2.Value"))
+	assert.Empty(t, server.requests[0].Get("MetricData.member.3.MetricName"), "Expected only the metrics for testgroup")
+}
+
+func TestCloudWatchEmitter_sendBatch_Throttled(t *testing.T) {
+	server := newCloudWatchServer(http.StatusBadRequest, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	module := fixtureModule(server.URL)
+	module.Configure("test", "emitter.test")
+	useStaticCredentials(module)
+	module.backoff = time.Millisecond
+
+	batch := []*cloudWatchDatum{{metric: "TotalLag", cluster: "testcluster", group: "testgroup", value: 1}}
+	assert.True(t, module.sendBatch(batch, time.Now()), "Expected batch to be sent")
+	assert.Len(t, server.requests, 3, "Expected the batch to be retried twice")
+}
+
+func TestCloudWatchEmitter_sendBatch_RetriesExhausted(t *testing.T) {
+	server := newCloudWatchServer(http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest)
+	defer server.Close()
+
+	module := fixtureModule(server.URL)
+	viper.Set("emitter.test.max-retries", 1)
+	module.Configure("test", "emitter.test")
+	useStaticCredentials(module)
+	module.backoff = time.Millisecond
+
+	batch := []*cloudWatchDatum{{metric: "TotalLag", cluster: "testcluster", group: "testgroup", value: 1}}
+	assert.True(t, module.sendBatch(batch, time.Now()), "Expected the batch to be dropped, and emitting to carry on")
+	assert.Len(t, server.requests, 2, "Expected the batch to be retried once")
+}
+
+func TestCloudWatchEmitter_sendBatch_Stopped(t *testing.T) {
+	server := newCloudWatchServer(http.StatusBadRequest)
+	defer server.Close()
+
+	module := fixtureModule(server.URL)
+	module.Configure("test", "emitter.test")
+	useStaticCredentials(module)
+	module.backoff = time.Minute
+	close(module.quitChannel)
+
+	batch := []*cloudWatchDatum{{metric: "TotalLag", cluster: "testcluster", group: "testgroup", value: 1}}
+	assert.False(t, module.sendBatch(batch, time.Now()), "Expected the retry to be abandoned when stopped")
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+// Package emitter - Metrics export subsystem.
+// The emitter subsystem periodically reads the lag for consumer groups from the storage subsystem and pushes it to an
+// external metrics system, for systems that cannot scrape the Prometheus endpoint.
+//
+// # Modules
+//
+// Currently, only one module is provided:
+//
+// * cloudwatch - Put the lag for each consumer group to AWS CloudWatch as custom metrics
+package emitter
+
+import (
+	"errors"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// Coordinator manages all emitter modules, making sure they are configured, started, and stopped at the appropriate
+// time. The modules run on their own, each sending requests to the storage subsystem on its own interval, so the
+// coordinator does not forward any requests.
+type Coordinator struct {
+	// App is a pointer to the application context. This stores the channel to the storage subsystem
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	modules map[string]protocol.Module
+}
+
+// getModuleForClass returns the correct module based on the passed className. As part of the Configure steps, if there
+// is any error, it will panic with an appropriate message describing the problem.
+func getModuleForClass(app *protocol.ApplicationContext, moduleName, className string) protocol.Module {
+	switch className {
+	case "cloudwatch":
+		return &CloudWatchEmitter{
+			App: app,
+			Log: app.Logger.With(
+				zap.String("type", "module"),
+				zap.String("coordinator", "emitter"),
+				zap.String("class", className),
+				zap.String("name", moduleName),
+			),
+		}
+	default:
+		panic("Unknown emitter className provided: " + className)
+	}
+}
+
+// Configure is called to create each of the configured emitter modules and call their Configure funcs to validate
+// their individual configurations and set them up. If there are any problems, it is expected that these funcs will
+// panic with a descriptive error message, as configuration failures are not recoverable errors.
+func (ec *Coordinator) Configure() {
+	ec.Log.Info("configuring")
+
+	ec.modules = make(map[string]protocol.Module)
+
+	for name := range viper.GetStringMap("emitter") {
+		configRoot := "emitter." + name
+		helpers.RecordConfigError(configRoot, func() {
+			module := getModuleForClass(ec.App, name, viper.GetString(configRoot+".class-name"))
+			module.Configure(name, configRoot)
+			ec.modules[name] = module
+		})
+	}
+}
+
+// Start calls each of the configured emitter modules' underlying Start funcs. As the coordinator itself has no ongoing
+// work to do, it does not start any other goroutines. If any module Start returns an error, this func stops immediately
+// and returns that error to the caller. No further modules will be loaded after that.
+func (ec *Coordinator) Start() error {
+	ec.Log.Info("starting")
+
+	err := helpers.StartCoordinatorModules(ec.modules)
+	if err != nil {
+		return errors.New("Error starting emitter module: " + err.Error())
+	}
+	return nil
+}
+
+// Stop calls each of the configured emitter modules' underlying Stop funcs. It is expected that the module Stop will
+// not return until the module has been completely stopped. While an error can be returned, this func always returns no
+// error, as a failure during stopping is not a critical failure
+func (ec *Coordinator) Stop() error {
+	ec.Log.Info("stopping")
+
+	helpers.StopCoordinatorModules(ec.modules)
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package emitter
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func fixtureCoordinator() *Coordinator {
+	coordinator := Coordinator{
+		Log: zap.NewNop(),
+	}
+	coordinator.App = &protocol.ApplicationContext{
+		Logger:         zap.NewNop(),
+		StorageChannel: make(chan *protocol.StorageRequest),
+	}
+
+	viper.Reset()
+	viper.Set("emitter.test.class-name", "cloudwatch")
+	viper.Set("emitter.test.region", "us-east-1")
+
+	return &coordinator
+}
+
+func TestCoordinator_ImplementsCoordinator(t *testing.T) {
+	assert.Implements(t, (*protocol.Coordinator)(nil), new(Coordinator))
+}
+
+func TestCoordinator_Configure(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.Configure()
+
+	assert.Lenf(t, coordinator.modules, 1, "Expected 1 module configured, not %v", len(coordinator.modules))
+	assert.IsType(t, &CloudWatchEmitter{}, coordinator.modules["test"], "Expected a CloudWatchEmitter")
+}
+
+func TestCoordinator_Configure_BadClass(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("emitter.test.class-name", "nonexistent")
+
+	assert.Panics(t, coordinator.Configure, "The code did not panic")
+}
+
+func TestCoordinator_StartStop(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.Configure()
+
+	// Swap out the coordinator modules with a mock for testing
+	mockModule := &helpers.MockModule{}
+	mockModule.On("Start").Return(nil)
+	mockModule.On("Stop").Return(nil)
+	coordinator.modules["test"] = mockModule
+
+	coordinator.Start()
+	mockModule.AssertCalled(t, "Start")
+
+	coordinator.Stop()
+	mockModule.AssertCalled(t, "Stop")
+}
@@ -10,6 +10,8 @@
 package evaluator
 
 import (
+	"math"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +20,7 @@ import (
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
+	"github.com/linkedin/Burrow/core/internal/httpserver"
 	"github.com/linkedin/Burrow/core/protocol"
 )
 
@@ -36,10 +39,29 @@ type CachingEvaluator struct {
 	expireCache     int
 	minimumComplete float32
 	allowedLag      uint64
+	burstTolerance  int64
+	stallWindow     int64
+	lagPercent      float64
+	staleAfter      map[string]int64
+	memberLag       bool
+
+	baseline           bool
+	baselineDeviations float64
+	baselineMinSamples int64
+
+	statusHistory bool
+
+	misconfiguredShare float64
+
+	activeWindow     int64
+	activeThreshold  float64
+	activePartitions map[string]int
+	activeLock       sync.Mutex
 
 	RequestChannel chan *protocol.EvaluatorRequest
 	running        sync.WaitGroup
 	cache          *goswarm.Simple
+	mutedCache     *goswarm.Simple
 }
 
 type cacheError struct {
@@ -52,8 +74,9 @@ func (e *cacheError) Error() string {
 }
 
 // Configure validates the configuration for the module, creates a channel to receive requests on, and sets up the
-// cache. If no expiration time for cache entries is set, a default value of 10 seconds is used. If there is any problem
-// starting the goswarm cache, this func panics.
+// cache. If no expiration time for cache entries is set, a default value of 10 seconds is used. The muted groups for
+// each cluster are cached for the same time, so a group that is muted or unmuted is returned with its new status
+// within that time. If there is any problem starting the goswarm caches, this func panics.
 func (module *CachingEvaluator) Configure(name, configRoot string) {
 	module.Log.Info("configuring")
 
@@ -64,9 +87,89 @@ func (module *CachingEvaluator) Configure(name, configRoot string) {
 	// Set defaults for configs if needed
 	viper.SetDefault(configRoot+".expire-cache", 10)
 	viper.SetDefault(configRoot+".allowed-lag", 0)
+	viper.SetDefault(configRoot+".burst-tolerance", 0)
 	module.expireCache = viper.GetInt(configRoot + ".expire-cache")
 	module.minimumComplete = float32(viper.GetFloat64(configRoot + ".minimum-complete"))
 	module.allowedLag = viper.GetUint64(configRoot + ".allowed-lag")
+	module.burstTolerance = viper.GetInt64(configRoot + ".burst-tolerance")
+	if module.burstTolerance < 0 {
+		panic("evaluator " + name + ": burst-tolerance must be zero or greater")
+	}
+
+	// A partition whose committed offset has not changed for the stall window, while its end offset has advanced, is
+	// stalled even if the rest of the offsets stored for it would not show that. A window of zero (the default)
+	// disables this
+	viper.SetDefault(configRoot+".stall-window", 0)
+	module.stallWindow = viper.GetInt64(configRoot + ".stall-window")
+	if module.stallWindow < 0 {
+		panic("evaluator " + name + ": stall-window must be zero or greater")
+	}
+
+	// Lag can also be limited to a percentage of the size of the partition (the end offset less the oldest offset). This
+	// needs the cluster to fetch the oldest offsets, and partitions where it is not known are not checked. A percentage
+	// of zero (the default) disables this
+	viper.SetDefault(configRoot+".lag-percent", 0)
+	module.lagPercent = viper.GetFloat64(configRoot + ".lag-percent")
+	if module.lagPercent < 0 {
+		panic("evaluator " + name + ": lag-percent must be zero or greater")
+	}
+
+	// Groups that have not committed within the staleness window are marked stale. The window can be overridden for
+	// each cluster, and a window of zero (the default) disables the check
+	viper.SetDefault(configRoot+".stale-after", 0)
+	module.staleAfter = make(map[string]int64)
+	for cluster := range viper.GetStringMap("cluster") {
+		module.staleAfter[cluster] = viper.GetInt64(configRoot + ".stale-after")
+		if viper.IsSet("cluster." + cluster + ".stale-after") {
+			module.staleAfter[cluster] = viper.GetInt64("cluster." + cluster + ".stale-after")
+		}
+	}
+
+	// Partition owners are only known if a consumer module decodes the group metadata, so attributing lag to the
+	// members of the group is optional
+	module.memberLag = viper.GetBool(configRoot + ".member-lag")
+
+	// In baseline mode, each evaluation adds the group's total lag to a baseline in storage, and lag more than
+	// baseline-deviations standard deviations above the mean for this hour of the day is flagged as anomalous. The
+	// baseline must have baseline-min-samples samples for the hour before anything is flagged
+	viper.SetDefault(configRoot+".baseline-deviations", 3.0)
+	viper.SetDefault(configRoot+".baseline-min-samples", 30)
+	module.baseline = viper.GetBool(configRoot + ".baseline")
+	module.baselineDeviations = viper.GetFloat64(configRoot + ".baseline-deviations")
+	module.baselineMinSamples = viper.GetInt64(configRoot + ".baseline-min-samples")
+	if module.baselineDeviations <= 0 {
+		panic("evaluator " + name + ": baseline-deviations must be greater than zero")
+	}
+	if module.baselineMinSamples < 1 {
+		panic("evaluator " + name + ": baseline-min-samples must be at least 1")
+	}
+
+	// The status of each group can be recorded in storage after every evaluation, which keeps the changes so that the
+	// status of the group at an earlier time can be looked up
+	module.statusHistory = viper.GetBool(configRoot + ".status-history")
+
+	// A partition is active if the group has committed to it within the window. When the count of active partitions
+	// changes by at least the threshold (a percentage of the previous count) between evaluations, the change is logged
+	// and counted. A threshold of zero (the default) disables this
+	viper.SetDefault(configRoot+".active-partition-window", 600)
+	viper.SetDefault(configRoot+".active-partition-change", 0)
+	module.activeWindow = viper.GetInt64(configRoot + ".active-partition-window")
+	module.activeThreshold = viper.GetFloat64(configRoot + ".active-partition-change")
+	if module.activeWindow <= 0 {
+		panic("evaluator " + name + ": active-partition-window must be greater than zero")
+	}
+	if module.activeThreshold < 0 {
+		panic("evaluator " + name + ": active-partition-change must be zero or greater")
+	}
+	module.activePartitions = make(map[string]int)
+
+	// A group is flagged as possibly misconfigured (such as a consumer committing to the wrong cluster) if at least
+	// this share of the topics it commits to do not exist in the cluster. A share of zero (the default) disables this
+	module.misconfiguredShare = viper.GetFloat64(configRoot + ".misconfigured-topic-share")
+	if (module.misconfiguredShare < 0) || (module.misconfiguredShare > 1) {
+		panic("evaluator " + name + ": misconfigured-topic-share must be between 0 and 1")
+	}
+
 	cacheExpire := time.Duration(module.expireCache) * time.Second
 
 	newCache, err := goswarm.NewSimple(&goswarm.Config{
@@ -79,6 +182,17 @@ func (module *CachingEvaluator) Configure(name, configRoot string) {
 		panic(err)
 	}
 	module.cache = newCache
+
+	mutedCache, err := goswarm.NewSimple(&goswarm.Config{
+		GoodExpiryDuration: cacheExpire,
+		BadExpiryDuration:  cacheExpire,
+		Lookup:             module.fetchMutedGroups,
+	})
+	if err != nil {
+		module.Log.Panic("Failed to start muted groups cache")
+		panic(err)
+	}
+	module.mutedCache = mutedCache
 }
 
 // GetCommunicationChannel returns the RequestChannel that has been setup for this module.
@@ -135,6 +249,7 @@ func (module *CachingEvaluator) getConsumerStatus(request *protocol.EvaluatorReq
 			Partitions: make([]*protocol.PartitionStatus, 0),
 			Maxlag:     nil,
 			TotalLag:   0,
+			MaxTimeLag: -1,
 		}
 	} else {
 		status := result.(*protocol.ConsumerGroupStatus)
@@ -144,14 +259,23 @@ func (module *CachingEvaluator) getConsumerStatus(request *protocol.EvaluatorReq
 			// returning it. However, we can't modify the original, so we need to make a new copy
 			cachedStatus := status
 			status = &protocol.ConsumerGroupStatus{
-				Cluster:         cachedStatus.Cluster,
-				Group:           cachedStatus.Group,
-				Status:          cachedStatus.Status,
-				Complete:        cachedStatus.Complete,
-				Maxlag:          cachedStatus.Maxlag,
-				TotalLag:        cachedStatus.TotalLag,
-				TotalPartitions: cachedStatus.TotalPartitions,
-				Partitions:      make([]*protocol.PartitionStatus, cachedStatus.TotalPartitions),
+				Cluster:               cachedStatus.Cluster,
+				Group:                 cachedStatus.Group,
+				Status:                cachedStatus.Status,
+				Stale:                 cachedStatus.Stale,
+				Anomalous:             cachedStatus.Anomalous,
+				Baseline:              cachedStatus.Baseline,
+				Complete:              cachedStatus.Complete,
+				Maxlag:                cachedStatus.Maxlag,
+				TotalLag:              cachedStatus.TotalLag,
+				MaxTimeLag:            cachedStatus.MaxTimeLag,
+				TotalPartitions:       cachedStatus.TotalPartitions,
+				ActivePartitions:      cachedStatus.ActivePartitions,
+				Members:               cachedStatus.Members,
+				StalledPartitions:     cachedStatus.StalledPartitions,
+				PossiblyMisconfigured: cachedStatus.PossiblyMisconfigured,
+				MissingTopics:         cachedStatus.MissingTopics,
+				Partitions:            make([]*protocol.PartitionStatus, cachedStatus.TotalPartitions),
 			}
 
 			// Copy over any partitions that do not have the status StatusOK
@@ -165,11 +289,44 @@ func (module *CachingEvaluator) getConsumerStatus(request *protocol.EvaluatorReq
 			status.Partitions = status.Partitions[0:count]
 		}
 
+		// Mutes are cached separately from the status, for each cluster rather than each group, so that evaluating
+		// every group does not fetch them from storage each time. The cached status is not modified, so the group's
+		// real status is returned again once it is unmuted
+		if module.isGroupMuted(request.Cluster, request.Group) {
+			mutedStatus := *status
+			mutedStatus.Status = protocol.StatusMuted
+			status = &mutedStatus
+		}
+
 		requestLogger.Debug("ok")
 		request.Reply <- status
 	}
 }
 
+func (module *CachingEvaluator) isGroupMuted(cluster, group string) bool {
+	result, err := module.mutedCache.Query(cluster)
+	if err != nil {
+		return false
+	}
+	_, ok := result.(map[string]int64)[group]
+	return ok
+}
+
+// fetchMutedGroups is the lookup for the muted groups cache, which fetches the muted groups for a cluster from storage
+func (module *CachingEvaluator) fetchMutedGroups(cluster string) (interface{}, error) {
+	storageRequest := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchMutedGroups,
+		Cluster:     cluster,
+		Reply:       make(chan interface{}),
+	}
+	module.App.StorageChannel <- storageRequest
+	mutedGroups, _ := (<-storageRequest.Reply).(map[string]int64)
+	if mutedGroups == nil {
+		mutedGroups = make(map[string]int64)
+	}
+	return mutedGroups, nil
+}
+
 func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string) (interface{}, error) {
 	// First off, we need to separate the cluster and consumer values from the string provided
 	parts := strings.SplitN(clusterAndConsumer, " ", 2)
@@ -179,6 +336,7 @@ func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string
 	}
 	cluster := parts[0]
 	consumer := parts[1]
+	startTime := time.Now()
 
 	// Fetch all the consumer offset and lag information from storage
 	storageRequest := &protocol.StorageRequest{
@@ -191,7 +349,29 @@ func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string
 	response := <-storageRequest.Reply
 
 	if response == nil {
+		// A group that only commits to topics that the cluster does not have has no offsets stored, but is reported
+		// (with no partitions) if it is possibly misconfigured
+		if module.misconfiguredShare > 0 {
+			status := &protocol.ConsumerGroupStatus{
+				Cluster:    cluster,
+				Group:      consumer,
+				Status:     protocol.StatusOK,
+				Partitions: make([]*protocol.PartitionStatus, 0),
+				MaxTimeLag: -1,
+			}
+			if module.checkMisconfigured(status, 0) {
+				module.Log.Debug("evaluation result",
+					zap.String("cluster", cluster),
+					zap.String("consumer", consumer),
+					zap.String("status", status.Status.String()),
+					zap.Strings("missing_topics", status.MissingTopics),
+				)
+				return status, nil
+			}
+		}
+
 		// Either the cluster or the consumer doesn't exist. In either case, return an error
+		module.forgetActivePartitions(clusterAndConsumer)
 		module.Log.Debug("evaluation result",
 			zap.String("cluster", cluster),
 			zap.String("consumer", consumer),
@@ -208,6 +388,7 @@ func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string
 		Complete:        1.0,
 		Maxlag:          nil,
 		TotalLag:        0,
+		MaxTimeLag:      -1,
 		TotalPartitions: 0,
 	}
 
@@ -223,13 +404,39 @@ func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string
 
 	count := 0
 	completePartitions := 0
+	var lastCommit int64
+	activeSince := (time.Now().Unix() - module.activeWindow) * 1000
 	for topic, partitions := range topics {
 		for partitionID, partition := range partitions {
-			partitionStatus := evaluatePartitionStatus(partition, module.minimumComplete, module.allowedLag)
+			partitionStatus := evaluatePartitionStatus(partition, module.minimumComplete, module.allowedLag, module.burstTolerance)
 			partitionStatus.Topic = topic
 			partitionStatus.Partition = int32(partitionID)
 			partitionStatus.Owner = partition.Owner
 			partitionStatus.ClientID = partition.ClientID
+			partitionStatus.InstanceID = partition.InstanceID
+			partitionStatus.Compacted = partition.Compacted
+
+			// The end offset of a compacted topic does not track how many messages are produced to it, and the offsets
+			// between the oldest and end offset are not all messages, so the checks that assume they are are skipped
+			if (module.stallWindow > 0) && (!partition.Compacted) && (partitionStatus.Status < protocol.StatusStop) && (partitionStatus.Complete >= module.minimumComplete) &&
+				checkIfOffsetStalledFor(partition, module.allowedLag, module.stallWindow, time.Now().Unix()) {
+				partitionStatus.Status = protocol.StatusStall
+			}
+			if (module.lagPercent > 0) && (!partition.Compacted) && (partitionStatus.Status == protocol.StatusOK) && (partitionStatus.Complete >= module.minimumComplete) {
+				if percent, ok := lagPercentOfPartition(partition); ok && (percent > module.lagPercent) {
+					partitionStatus.Status = protocol.StatusWarning
+				}
+			}
+			if offsetBelowLogStart(partition, partitionStatus) {
+				partitionStatus.OffsetOutOfRange = true
+				status.OffsetOutOfRange = true
+				if partitionStatus.Status == protocol.StatusOK {
+					partitionStatus.Status = protocol.StatusWarning
+				}
+			}
+			if partitionStatus.Status == protocol.StatusStall {
+				status.StalledPartitions = append(status.StalledPartitions, partitionStatus)
+			}
 
 			if partitionStatus.Status > status.Status {
 				// If the partition status is greater than StatusError, we just mark it as StatusError
@@ -243,14 +450,27 @@ func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string
 			if (status.Maxlag == nil) || (partitionStatus.CurrentLag > status.Maxlag.CurrentLag) {
 				status.Maxlag = partitionStatus
 			}
+			if partitionStatus.TimeLag > status.MaxTimeLag {
+				status.MaxTimeLag = partitionStatus.TimeLag
+			}
 			if partitionStatus.Complete == 1.0 {
 				completePartitions++
 			}
+			if (partitionStatus.End != nil) && (partitionStatus.End.Timestamp > lastCommit) {
+				lastCommit = partitionStatus.End.Timestamp
+			}
+			if (partitionStatus.End != nil) && (partitionStatus.End.Timestamp > activeSince) {
+				status.ActivePartitions++
+			}
 			status.Partitions[count] = partitionStatus
 			count++
 		}
 	}
 
+	if module.memberLag {
+		status.Members = evaluateMemberStatus(status.Partitions)
+	}
+
 	// Calculate completeness as a percentage of the number of partitions that are complete
 	if status.TotalPartitions > 0 {
 		status.Complete = float32(completePartitions) / float32(status.TotalPartitions)
@@ -258,21 +478,208 @@ func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string
 		status.Complete = 0
 	}
 
+	// The group is stale if its newest commit on any partition is older than the staleness window for the cluster
+	if staleAfter := module.staleAfter[cluster]; (staleAfter > 0) && (lastCommit > 0) {
+		status.Stale = ((time.Now().Unix() * 1000) - lastCommit) > (staleAfter * 1000)
+	}
+
+	if module.baseline {
+		module.checkLagBaseline(status)
+	}
+
+	if module.misconfiguredShare > 0 {
+		module.checkMisconfigured(status, len(topics))
+	}
+
+	if module.statusHistory {
+		module.App.StorageChannel <- &protocol.StorageRequest{
+			RequestType: protocol.StorageSetGroupStatus,
+			Cluster:     cluster,
+			Group:       consumer,
+			Status:      status.Status,
+			Timestamp:   time.Now().UnixNano() / int64(time.Millisecond),
+		}
+	}
+
+	if previous, changed := module.checkActivePartitions(clusterAndConsumer, status.ActivePartitions); changed {
+		module.Log.Warn("active partition count changed",
+			zap.String("cluster", cluster),
+			zap.String("consumer", consumer),
+			zap.Int("previous", previous),
+			zap.Int("active_partitions", status.ActivePartitions),
+			zap.Int("total_partitions", status.TotalPartitions),
+		)
+		httpserver.CountActivePartitionChange(cluster, consumer)
+	}
+
+	// Only groups that were found are timed, so that requests for unknown groups do not create metrics
+	duration := time.Since(startTime)
+	httpserver.ObserveConsumerEvaluation(cluster, consumer, duration)
+	httpserver.PublishConsumerStatus(status)
+
 	module.Log.Debug("evaluation result",
 		zap.String("cluster", cluster),
 		zap.String("consumer", consumer),
 		zap.String("status", status.Status.String()),
+		zap.Bool("stale", status.Stale),
+		zap.Bool("anomalous", status.Anomalous),
+		zap.Bool("possibly_misconfigured", status.PossiblyMisconfigured),
+		zap.Duration("duration", duration),
 		zap.Float32("complete", status.Complete),
 		zap.Uint64("total_lag", status.TotalLag),
 		zap.Int("total_partitions", status.TotalPartitions),
+		zap.Int("active_partitions", status.ActivePartitions),
 	)
 	return status, nil
 }
 
-func evaluatePartitionStatus(partition *protocol.ConsumerPartition, minimumComplete float32, allowedLag uint64) *protocol.PartitionStatus {
+// checkLagBaseline compares the group's total lag with its baseline for the current hour, and then adds the lag to the
+// baseline as a new sample. If the baseline has enough samples, and the lag is over both the allowed lag and the
+// baseline-deviations band above the mean, the group is marked anomalous, and an OK group is raised to WARN.
+func (module *CachingEvaluator) checkLagBaseline(status *protocol.ConsumerGroupStatus) {
+	storageRequest := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchGroupBaseline,
+		Cluster:     status.Cluster,
+		Group:       status.Group,
+		Reply:       make(chan interface{}),
+	}
+	module.App.StorageChannel <- storageRequest
+	baseline, ok := (<-storageRequest.Reply).(*protocol.LagBaseline)
+
+	timeNow := time.Now()
+	if ok {
+		hour := baseline.Hours[timeNow.UTC().Hour()]
+		status.Baseline = &hour
+		if (hour.Samples >= module.baselineMinSamples) && (status.TotalLag > module.allowedLag) &&
+			(float64(status.TotalLag) > hour.Mean+(module.baselineDeviations*hour.StdDev)) {
+			status.Anomalous = true
+			if status.Status == protocol.StatusOK {
+				status.Status = protocol.StatusWarning
+			}
+		}
+	}
+
+	module.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetGroupBaselineSample,
+		Cluster:     status.Cluster,
+		Group:       status.Group,
+		Lag:         status.TotalLag,
+		Timestamp:   timeNow.UnixNano() / int64(time.Millisecond),
+	}
+}
+
+// checkMisconfigured fetches the topics that the group recently committed to that the cluster does not have, and marks
+// the group as possibly misconfigured if they are at least misconfigured-topic-share of its topics, counting the
+// knownTopics that it has offsets stored for. It returns true if the group was marked.
+func (module *CachingEvaluator) checkMisconfigured(status *protocol.ConsumerGroupStatus, knownTopics int) bool {
+	storageRequest := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchGroupUnknownTopics,
+		Cluster:     status.Cluster,
+		Group:       status.Group,
+		Reply:       make(chan interface{}),
+	}
+	module.App.StorageChannel <- storageRequest
+	unknownTopics, _ := (<-storageRequest.Reply).([]string)
+	if len(unknownTopics) == 0 {
+		return false
+	}
+
+	if float64(len(unknownTopics))/float64(len(unknownTopics)+knownTopics) < module.misconfiguredShare {
+		return false
+	}
+	status.PossiblyMisconfigured = true
+	status.MissingTopics = unknownTopics
+	return true
+}
+
+// checkActivePartitions stores the count of active partitions for the group, and returns the count from the last
+// evaluation and whether the count has changed by at least the active-partition-change threshold since then. The
+// first evaluation of a group is never a change.
+func (module *CachingEvaluator) checkActivePartitions(clusterAndConsumer string, count int) (int, bool) {
+	module.activeLock.Lock()
+	defer module.activeLock.Unlock()
+
+	previous, ok := module.activePartitions[clusterAndConsumer]
+	module.activePartitions[clusterAndConsumer] = count
+	if (!ok) || (module.activeThreshold == 0) || (count == previous) {
+		return previous, false
+	}
+	if previous == 0 {
+		// Any change from no active partitions is a change of 100%
+		return previous, true
+	}
+	return previous, math.Abs(float64(count-previous))*100 >= module.activeThreshold*float64(previous)
+}
+
+func (module *CachingEvaluator) forgetActivePartitions(clusterAndConsumer string) {
+	module.activeLock.Lock()
+	defer module.activeLock.Unlock()
+	delete(module.activePartitions, clusterAndConsumer)
+}
+
+// evaluateMemberStatus groups the partition statuses by the member of the group that owns them, and returns a status
+// for each member, with the members with the most lag first. Partitions without an owner are left out.
+func evaluateMemberStatus(partitions []*protocol.PartitionStatus) []*protocol.MemberStatus {
+	type memberKey struct {
+		instanceID string
+		clientID   string
+		owner      string
+	}
+
+	members := make(map[memberKey]*protocol.MemberStatus)
+	for _, partition := range partitions {
+		if (partition.Owner == "") && (partition.ClientID == "") && (partition.InstanceID == "") {
+			continue
+		}
+
+		key := memberKey{instanceID: partition.InstanceID, clientID: partition.ClientID, owner: partition.Owner}
+		member, ok := members[key]
+		if !ok {
+			member = &protocol.MemberStatus{
+				InstanceID: partition.InstanceID,
+				ClientID:   partition.ClientID,
+				Owner:      partition.Owner,
+				Status:     protocol.StatusOK,
+			}
+			members[key] = member
+		}
+		member.PartitionCount++
+		member.TotalLag += partition.CurrentLag
+
+		// As for the group, partition statuses above StatusError are StatusError for the member
+		partitionStatus := partition.Status
+		if partitionStatus > protocol.StatusError {
+			partitionStatus = protocol.StatusError
+		}
+		if partitionStatus > member.Status {
+			member.Status = partitionStatus
+		}
+	}
+
+	memberList := make([]*protocol.MemberStatus, 0, len(members))
+	for _, member := range members {
+		memberList = append(memberList, member)
+	}
+	sort.Slice(memberList, func(i, j int) bool {
+		if memberList[i].TotalLag != memberList[j].TotalLag {
+			return memberList[i].TotalLag > memberList[j].TotalLag
+		}
+		if memberList[i].InstanceID != memberList[j].InstanceID {
+			return memberList[i].InstanceID < memberList[j].InstanceID
+		}
+		if memberList[i].ClientID != memberList[j].ClientID {
+			return memberList[i].ClientID < memberList[j].ClientID
+		}
+		return memberList[i].Owner < memberList[j].Owner
+	})
+	return memberList
+}
+
+func evaluatePartitionStatus(partition *protocol.ConsumerPartition, minimumComplete float32, allowedLag uint64, burstTolerance int64) *protocol.PartitionStatus {
 	status := &protocol.PartitionStatus{
 		Status:     protocol.StatusOK,
 		CurrentLag: partition.CurrentLag,
+		TimeLag:    partitionTimeLag(partition, time.Now().Unix()*1000),
 	}
 
 	// If there are no offsets, we can't do anything
@@ -280,8 +687,9 @@ func evaluatePartitionStatus(partition *protocol.ConsumerPartition, minimumCompl
 		return status
 	}
 
-	// Slice the offsets to remove all nil entries (they'll be at the start)
-	firstOffset := len(partition.Offsets) - 1
+	// Slice the offsets to remove all nil entries (they'll be at the start). Every entry is nil if storage has evicted
+	// all the partition's offsets for being too old
+	firstOffset := len(partition.Offsets)
 	for i, offset := range partition.Offsets {
 		if offset != nil {
 			firstOffset = i
@@ -304,9 +712,16 @@ func evaluatePartitionStatus(partition *protocol.ConsumerPartition, minimumCompl
 	status.Start = offsets[0]
 	status.End = offsets[len(offsets)-1]
 
-	// If the partition does not meet the completeness threshold, just return it as OK
-	if status.Complete >= minimumComplete {
-		status.Status = calculatePartitionStatus(offsets, partition.BrokerOffsets, partition.CurrentLag, time.Now().Unix(), allowedLag)
+	// If storage does not know the end offset for the partition yet, the lag can't be evaluated, so return it as OK
+	if len(partition.BrokerOffsets) == 0 {
+		return status
+	}
+
+	// If the partition does not meet the completeness threshold, or the lag has not been above the allowed lag for
+	// longer than the burst tolerance, just return it as OK
+	timeNow := time.Now().Unix()
+	if (status.Complete >= minimumComplete) && (!checkIfLagBurst(offsets, allowedLag, burstTolerance, timeNow)) {
+		status.Status = calculatePartitionStatus(offsets, partition.BrokerOffsets, partition.CurrentLag, timeNow, allowedLag)
 	}
 
 	return status
@@ -409,6 +824,132 @@ func checkIfOffsetsStalled(offsets []*protocol.ConsumerOffset) bool {
 	return true
 }
 
+// checkIfOffsetStalledFor returns true if the committed offset for the partition has been the same for at least the
+// stall window (in seconds), and the end offset has advanced since it was first committed, so that the lag has grown.
+// Unlike Rule 4, this does not need all the stored offsets to be the same, so it finds a single stuck partition in a
+// group that was making progress on it earlier in the stored period.
+func checkIfOffsetStalledFor(partition *protocol.ConsumerPartition, allowedLag uint64, stallWindow, timeNow int64) bool {
+	if (partition.CurrentLag <= allowedLag) || (len(partition.Offsets) == 0) {
+		return false
+	}
+	last := partition.Offsets[len(partition.Offsets)-1]
+	if last == nil {
+		return false
+	}
+
+	// Find the first commit of the current offset
+	stalledSince := last
+	for i := len(partition.Offsets) - 2; i >= 0; i-- {
+		offset := partition.Offsets[i]
+		if (offset == nil) || (offset.Offset != last.Offset) {
+			break
+		}
+		stalledSince = offset
+	}
+	if ((timeNow * 1000) - stalledSince.Timestamp) < (stallWindow * 1000) {
+		return false
+	}
+
+	// The committed offset is the same, so the lag only grows if the end offset advanced
+	var stalledLag uint64
+	if stalledSince.Lag != nil {
+		stalledLag = stalledSince.Lag.Value
+	}
+	return partition.CurrentLag > stalledLag
+}
+
+// lagPercentOfPartition returns the current lag for the partition as a percentage of the size of the partition, which
+// is the number of offsets between the oldest offset and the end offset. It returns false if the oldest offset or the
+// end offset is not known, or the partition is empty.
+func lagPercentOfPartition(partition *protocol.ConsumerPartition) (float64, bool) {
+	if (partition.OldestOffset < 0) || (len(partition.BrokerOffsets) == 0) {
+		return 0, false
+	}
+	size := partition.BrokerOffsets[len(partition.BrokerOffsets)-1] - partition.OldestOffset
+	if size <= 0 {
+		return 0, false
+	}
+	return float64(partition.CurrentLag) * 100 / float64(size), true
+}
+
+// offsetBelowLogStart returns true if the last offset the group committed for the partition is below the oldest offset
+// the broker has for it. It returns false if the oldest offset is not known.
+func offsetBelowLogStart(partition *protocol.ConsumerPartition, partitionStatus *protocol.PartitionStatus) bool {
+	if (partition.OldestOffset < 0) || (partitionStatus.End == nil) {
+		return false
+	}
+	return partitionStatus.End.Offset < partition.OldestOffset
+}
+
+// partitionTimeLag returns an estimate of how far behind the consumer is for the partition in time (in milliseconds), at
+// timeNow (in milliseconds). The current lag is divided by the rate that messages were produced to the partition
+// between the lookback offset and the end offset. If nothing was produced in that time, the consumer is at least as far
+// behind as the lookback. It returns -1 if the lookback offset or the end offset is not known. If the cluster module
+// samples the offsets at several times, those are used instead (see sampledTimeLag).
+func partitionTimeLag(partition *protocol.ConsumerPartition, timeNow int64) int64 {
+	if (len(partition.TimeOffsets) > 0) && (len(partition.BrokerOffsets) > 0) {
+		return sampledTimeLag(partition, timeNow)
+	}
+	if (partition.LookbackOffset < 0) || (partition.LookbackTimestamp <= 0) || (len(partition.BrokerOffsets) == 0) {
+		return -1
+	}
+	if partition.CurrentLag == 0 {
+		return 0
+	}
+	window := timeNow - partition.LookbackTimestamp
+	if window <= 0 {
+		return -1
+	}
+	produced := partition.BrokerOffsets[len(partition.BrokerOffsets)-1] - partition.LookbackOffset
+	if produced <= 0 {
+		return window
+	}
+	return int64(float64(partition.CurrentLag) * float64(window) / float64(produced))
+}
+
+// sampledTimeLag returns an estimate of how far behind the consumer is for the partition in time (in milliseconds), at
+// timeNow, from the offsets the partition was at at each sample time. The samples, with the end offset taken as being
+// at timeNow, mark out when each range of offsets was produced, and the time the next message for the consumer was
+// produced is interpolated within the range it falls in. If it is older than the oldest sample, it is extrapolated
+// from the rate between the oldest sample and the end offset, or if nothing was produced in that time, the consumer is
+// at least as far behind as the oldest sample.
+func sampledTimeLag(partition *protocol.ConsumerPartition, timeNow int64) int64 {
+	if partition.CurrentLag == 0 {
+		return 0
+	}
+	end := partition.BrokerOffsets[len(partition.BrokerOffsets)-1]
+	next := end - int64(partition.CurrentLag)
+	samples := append(partition.TimeOffsets[:len(partition.TimeOffsets):len(partition.TimeOffsets)], protocol.TimeOffset{Timestamp: timeNow, Offset: end})
+
+	var produced int64
+	found := false
+	for i := len(samples) - 2; (i >= 0) && !found; i-- {
+		older, newer := samples[i], samples[i+1]
+		if next < older.Offset {
+			continue
+		}
+		found = true
+		if next < newer.Offset {
+			produced = older.Timestamp + int64(float64(next-older.Offset)*float64(newer.Timestamp-older.Timestamp)/float64(newer.Offset-older.Offset))
+		} else {
+			// The samples are out of order, such as if the topic was recreated, so this is the best that is known
+			produced = newer.Timestamp
+		}
+	}
+	if !found {
+		oldest := samples[0]
+		produced = oldest.Timestamp
+		if end > oldest.Offset {
+			produced -= int64(float64(oldest.Offset-next) * float64(timeNow-oldest.Timestamp) / float64(end-oldest.Offset))
+		}
+	}
+
+	if produced > timeNow {
+		return 0
+	}
+	return timeNow - produced
+}
+
 // Rule 5 - If the consumer offsets are advancing, but the lag is not decreasing somewhere, it's a warning (consumer is slow)
 func checkIfLagNotDecreasing(offsets []*protocol.ConsumerOffset) bool {
 	var lastLag *protocol.Lag
@@ -436,3 +977,22 @@ func checkIfRecentLagZero(offsets []*protocol.ConsumerOffset, brokerOffsets []in
 	}
 	return false
 }
+
+// Return true if the lag has been over the allowed lag for less than the burst tolerance (in seconds). The lag is
+// measured from the stored offsets: it has been over the allowed lag since the oldest offset in the most recent run of
+// offsets with lag over the allowed lag, or since the last offset if it was not lagging. This ignores short spikes in
+// lag that resolve themselves within the tolerance. A tolerance of zero disables the check.
+func checkIfLagBurst(offsets []*protocol.ConsumerOffset, allowedLag uint64, burstTolerance, timeNow int64) bool {
+	if burstTolerance <= 0 {
+		return false
+	}
+
+	lagStart := offsets[len(offsets)-1].Timestamp
+	for i := len(offsets) - 1; i >= 0; i-- {
+		if (offsets[i].Lag == nil) || (offsets[i].Lag.Value <= allowedLag) {
+			break
+		}
+		lagStart = offsets[i].Timestamp
+	}
+	return ((timeNow * 1000) - lagStart) < (burstTolerance * 1000)
+}
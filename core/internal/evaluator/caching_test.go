@@ -140,6 +140,786 @@ func TestCachingEvaluator_SingleRequest_ShowAll(t *testing.T) {
 	stopTestCluster(storageCoordinator, module)
 }
 
+func TestCachingEvaluator_SingleRequest_Muted(t *testing.T) {
+	storageCoordinator, module := startWithTestCluster()
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: true,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+	assert.Equalf(t, protocol.StatusOK, response.Status, "Expected status to be OK, not %v", response.Status.String())
+
+	// Mute the group, and wait for the storage module to process it
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetMuteGroup,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+	}
+	// The muted groups were cached by the first request, so the mute is not seen until they expire
+	assert.False(t, module.isGroupMuted("testcluster", "testgroup"), "Expected the cached muted groups to be used")
+	assert.Eventually(t, func() bool {
+		module.mutedCache.Delete("testcluster")
+		return module.isGroupMuted("testcluster", "testgroup")
+	}, time.Second, 10*time.Millisecond, "Expected group to be muted")
+
+	// The cached status is returned as muted, with the partitions still evaluated
+	module.GetCommunicationChannel() <- request
+	response = <-request.Reply
+	assert.Equalf(t, protocol.StatusMuted, response.Status, "Expected status to be MUTED, not %v", response.Status.String())
+	assert.Lenf(t, response.Partitions, 1, "Expected 1 partition status objects, not %v", len(response.Partitions))
+	assert.Equalf(t, uint64(2421), response.TotalLag, "Expected total_lag to be 2421, not %v", response.TotalLag)
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_SingleRequest_Stale(t *testing.T) {
+	testCases := []struct {
+		moduleStaleAfter  int
+		clusterStaleAfter int
+		expected          bool
+	}{
+		{0, 0, false},
+		{5, 0, true},
+		{60, 0, false},
+		{60, 5, true},
+		{5, 60, false},
+	}
+
+	for i, testCase := range testCases {
+		storageCoordinator, module := fixtureModule()
+		viper.Set("cluster.testcluster.class-name", "kafka")
+		viper.Set("evaluator.test.stale-after", testCase.moduleStaleAfter)
+		if testCase.clusterStaleAfter != 0 {
+			viper.Set("cluster.testcluster.stale-after", testCase.clusterStaleAfter)
+		}
+		module.Configure("test", "evaluator.test")
+		module.Start()
+
+		// The newest commit for the test group is 10 seconds old
+		request := &protocol.EvaluatorRequest{
+			Reply:   make(chan *protocol.ConsumerGroupStatus),
+			Cluster: "testcluster",
+			Group:   "testgroup",
+			ShowAll: false,
+		}
+		module.GetCommunicationChannel() <- request
+		response := <-request.Reply
+
+		assert.Equalf(t, protocol.StatusOK, response.Status, "TEST %v: Expected status to be OK, not %v", i, response.Status.String())
+		assert.Equalf(t, testCase.expected, response.Stale, "TEST %v: Expected stale to be %v, not %v", i, testCase.expected, response.Stale)
+
+		stopTestCluster(storageCoordinator, module)
+	}
+}
+
+func TestCachingEvaluator_SingleRequest_ActivePartitions(t *testing.T) {
+	testCases := []struct {
+		window   int
+		expected int
+	}{
+		{0, 1},
+		{60, 1},
+		{5, 0},
+	}
+
+	for i, testCase := range testCases {
+		storageCoordinator, module := fixtureModule()
+		if testCase.window != 0 {
+			viper.Set("evaluator.test.active-partition-window", testCase.window)
+		}
+		module.Configure("test", "evaluator.test")
+		module.Start()
+
+		// The newest commit for the test group is 10 seconds old
+		request := &protocol.EvaluatorRequest{
+			Reply:   make(chan *protocol.ConsumerGroupStatus),
+			Cluster: "testcluster",
+			Group:   "testgroup",
+			ShowAll: false,
+		}
+		module.GetCommunicationChannel() <- request
+		response := <-request.Reply
+
+		assert.Equalf(t, 1, response.TotalPartitions, "TEST %v: Expected total_partitions to be 1, not %v", i, response.TotalPartitions)
+		assert.Equalf(t, testCase.expected, response.ActivePartitions, "TEST %v: Expected active_partition_count to be %v, not %v", i, testCase.expected, response.ActivePartitions)
+
+		stopTestCluster(storageCoordinator, module)
+	}
+}
+
+func TestCachingEvaluator_checkActivePartitions(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.active-partition-change", 25)
+	module.Configure("test", "evaluator.test")
+	storageCoordinator.Stop()
+
+	testCases := []struct {
+		count    int
+		previous int
+		changed  bool
+	}{
+		{8, 0, false},
+		{7, 8, false},
+		{5, 7, true},
+		{5, 5, false},
+		{0, 5, true},
+		{2, 0, true},
+	}
+
+	for i, testCase := range testCases {
+		previous, changed := module.checkActivePartitions("testcluster testgroup", testCase.count)
+		assert.Equalf(t, testCase.previous, previous, "TEST %v: Expected previous count to be %v, not %v", i, testCase.previous, previous)
+		assert.Equalf(t, testCase.changed, changed, "TEST %v: Expected changed to be %v, not %v", i, testCase.changed, changed)
+	}
+
+	// A group that is no longer found starts again
+	module.forgetActivePartitions("testcluster testgroup")
+	_, changed := module.checkActivePartitions("testcluster testgroup", 10)
+	assert.False(t, changed, "Expected first evaluation after the group was forgotten to not be a change")
+}
+
+func TestCachingEvaluator_Configure_BadActivePartitions(t *testing.T) {
+	for _, key := range []string{"active-partition-window", "active-partition-change"} {
+		storageCoordinator, module := fixtureModule()
+		viper.Set("evaluator.test."+key, -1)
+		assert.Panicsf(t, func() { module.Configure("test", "evaluator.test") }, "Expected panic for negative %v", key)
+		storageCoordinator.Stop()
+	}
+}
+
+func TestCachingEvaluator_SingleRequest_StallWindow(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.stall-window", 60)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	// The group makes progress on a second topic, and then stops advancing its offset while the end offset advances
+	sendBrokerOffset := func(offset int64) {
+		storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+			RequestType:         protocol.StorageSetBrokerOffset,
+			Cluster:             "testcluster",
+			Topic:               "stucktopic",
+			Partition:           0,
+			TopicPartitionCount: 1,
+			Offset:              offset,
+			Timestamp:           time.Now().Unix() * 1000,
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	startTime := (time.Now().Unix() * 1000) - 100000
+	sendBrokerOffset(1000)
+	for i, offset := range []int64{100, 200, 300, 300, 300, 300, 300, 300, 300, 300} {
+		if i == 3 {
+			sendBrokerOffset(2000)
+		}
+		storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+			RequestType: protocol.StorageSetConsumerOffset,
+			Cluster:     "testcluster",
+			Topic:       "stucktopic",
+			Group:       "testgroup",
+			Partition:   0,
+			Order:       int64(i + 1),
+			Offset:      offset,
+			Timestamp:   startTime + int64(i*10000),
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.Equalf(t, protocol.StatusError, response.Status, "Expected status to be ERR, not %v", response.Status.String())
+	assert.Lenf(t, response.Partitions, 1, "Expected 1 partition with a bad status, not %v", len(response.Partitions))
+	assert.Lenf(t, response.StalledPartitions, 1, "Expected 1 stalled partition, not %v", len(response.StalledPartitions))
+	assert.Equalf(t, "stucktopic", response.StalledPartitions[0].Topic, "Expected stalled partition to be in stucktopic, not %v", response.StalledPartitions[0].Topic)
+	assert.Equalf(t, protocol.StatusStall, response.StalledPartitions[0].Status, "Expected partition status to be STALL, not %v", response.StalledPartitions[0].Status.String())
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_Configure_BadStallWindow(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.stall-window", -1)
+	assert.Panics(t, func() { module.Configure("test", "evaluator.test") }, "The code did not panic")
+	storageCoordinator.Stop()
+}
+
+func TestCheckIfOffsetStalledFor(t *testing.T) {
+	timeNow := int64(1000)
+	partition := &protocol.ConsumerPartition{
+		Offsets: []*protocol.ConsumerOffset{
+			nil,
+			{Offset: 100, Timestamp: 880000, Lag: &protocol.Lag{Value: 900}},
+			{Offset: 300, Timestamp: 900000, Lag: &protocol.Lag{Value: 700}},
+			{Offset: 300, Timestamp: 950000, Lag: &protocol.Lag{Value: 1200}},
+			{Offset: 300, Timestamp: 990000, Lag: &protocol.Lag{Value: 1700}},
+		},
+		CurrentLag: 1700,
+	}
+
+	// The offset has been 300 for 100 seconds, and the lag has grown from 700
+	assert.True(t, checkIfOffsetStalledFor(partition, 0, 60, timeNow), "Expected partition to be stalled for 60 seconds")
+	assert.False(t, checkIfOffsetStalledFor(partition, 0, 120, timeNow), "Expected partition to not be stalled for 120 seconds")
+	assert.False(t, checkIfOffsetStalledFor(partition, 2000, 60, timeNow), "Expected partition under the allowed lag to not be stalled")
+
+	// If the end offset has not advanced, the consumer is just idle
+	partition.CurrentLag = 700
+	assert.False(t, checkIfOffsetStalledFor(partition, 0, 60, timeNow), "Expected idle partition to not be stalled")
+
+	assert.False(t, checkIfOffsetStalledFor(&protocol.ConsumerPartition{CurrentLag: 100}, 0, 60, timeNow), "Expected partition with no offsets to not be stalled")
+}
+
+func TestCachingEvaluator_SingleRequest_LagPercent(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.lag-percent", 50)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	getStatus := func() *protocol.ConsumerGroupStatus {
+		request := &protocol.EvaluatorRequest{
+			Reply:   make(chan *protocol.ConsumerGroupStatus),
+			Cluster: "testcluster",
+			Group:   "testgroup",
+			ShowAll: true,
+		}
+		module.GetCommunicationChannel() <- request
+		return <-request.Reply
+	}
+
+	// Without the oldest offset, the size of the partition is not known
+	response := getStatus()
+	assert.Equalf(t, protocol.StatusOK, response.Status, "Expected status to be OK, not %v", response.Status.String())
+
+	// The lag of 2421 is 56% of the 4321 offsets in the partition
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOldestOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              0,
+	}
+	time.Sleep(50 * time.Millisecond)
+	module.cache.Delete("testcluster testgroup")
+
+	response = getStatus()
+	assert.Equalf(t, protocol.StatusWarning, response.Status, "Expected status to be WARN, not %v", response.Status.String())
+	assert.Equalf(t, protocol.StatusWarning, response.Partitions[0].Status, "Expected partition status to be WARN, not %v", response.Partitions[0].Status.String())
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_SingleRequest_OffsetOutOfRange(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	getStatus := func() *protocol.ConsumerGroupStatus {
+		request := &protocol.EvaluatorRequest{
+			Reply:   make(chan *protocol.ConsumerGroupStatus),
+			Cluster: "testcluster",
+			Group:   "testgroup",
+			ShowAll: true,
+		}
+		module.GetCommunicationChannel() <- request
+		return <-request.Reply
+	}
+
+	// Without the oldest offset, the start of the log is not known
+	response := getStatus()
+	assert.False(t, response.OffsetOutOfRange, "Expected group not to be out of range")
+
+	// The last commit, 1900, has been removed by retention
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOldestOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              2000,
+	}
+	time.Sleep(50 * time.Millisecond)
+	module.cache.Delete("testcluster testgroup")
+
+	response = getStatus()
+	assert.True(t, response.OffsetOutOfRange, "Expected group to be out of range")
+	assert.True(t, response.Partitions[0].OffsetOutOfRange, "Expected partition to be out of range")
+	assert.Equalf(t, protocol.StatusWarning, response.Status, "Expected status to be WARN, not %v", response.Status.String())
+	assert.Equalf(t, protocol.StatusWarning, response.Partitions[0].Status, "Expected partition status to be WARN, not %v", response.Partitions[0].Status.String())
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestOffsetBelowLogStart(t *testing.T) {
+	end := &protocol.PartitionStatus{End: &protocol.ConsumerOffset{Offset: 1000}}
+	assert.False(t, offsetBelowLogStart(&protocol.ConsumerPartition{OldestOffset: -1}, end), "Expected unknown log start not to be out of range")
+	assert.False(t, offsetBelowLogStart(&protocol.ConsumerPartition{OldestOffset: 1000}, end), "Expected commit at the log start not to be out of range")
+	assert.True(t, offsetBelowLogStart(&protocol.ConsumerPartition{OldestOffset: 1001}, end), "Expected commit below the log start to be out of range")
+	assert.False(t, offsetBelowLogStart(&protocol.ConsumerPartition{OldestOffset: 1001}, &protocol.PartitionStatus{}), "Expected no commits not to be out of range")
+}
+
+func TestCachingEvaluator_SingleRequest_LagPercentCompacted(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.lag-percent", 50)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	// The lag would be 56% of the partition, but the offsets in a compacted topic are not all messages
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOldestOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              0,
+	}
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetTopicCompacted,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Compacted:   true,
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: true,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+	assert.Equalf(t, protocol.StatusOK, response.Status, "Expected status to be OK, not %v", response.Status.String())
+	assert.True(t, response.Partitions[0].Compacted, "Expected partition to be flagged as compacted")
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_Configure_BadLagPercent(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.lag-percent", -1)
+	assert.Panics(t, func() { module.Configure("test", "evaluator.test") }, "The code did not panic")
+	storageCoordinator.Stop()
+}
+
+func TestLagPercentOfPartition(t *testing.T) {
+	partition := &protocol.ConsumerPartition{BrokerOffsets: []int64{900, 1000}, OldestOffset: 600, CurrentLag: 100}
+	percent, ok := lagPercentOfPartition(partition)
+	assert.True(t, ok, "Expected lag percent to be known")
+	assert.Equalf(t, 25.0, percent, "Expected lag percent to be 25, not %v", percent)
+
+	partition.OldestOffset = -1
+	_, ok = lagPercentOfPartition(partition)
+	assert.False(t, ok, "Expected lag percent to not be known without the oldest offset")
+
+	partition.OldestOffset = 1000
+	_, ok = lagPercentOfPartition(partition)
+	assert.False(t, ok, "Expected lag percent to not be known for an empty partition")
+}
+
+func TestPartitionTimeLag(t *testing.T) {
+	// 1000 messages were produced in the 100 seconds before now, so a lag of 100 is 10 seconds behind
+	partition := &protocol.ConsumerPartition{BrokerOffsets: []int64{1900, 2000}, LookbackOffset: 1000, LookbackTimestamp: 100000, CurrentLag: 100}
+	timeLag := partitionTimeLag(partition, 200000)
+	assert.Equalf(t, int64(10000), timeLag, "Expected time lag to be 10000, not %v", timeLag)
+
+	partition.CurrentLag = 0
+	timeLag = partitionTimeLag(partition, 200000)
+	assert.Equalf(t, int64(0), timeLag, "Expected time lag to be 0 with no lag, not %v", timeLag)
+
+	// Nothing produced since the lookback time means the consumer is at least that far behind
+	partition.CurrentLag = 100
+	partition.LookbackOffset = 2000
+	timeLag = partitionTimeLag(partition, 200000)
+	assert.Equalf(t, int64(100000), timeLag, "Expected time lag to be 100000, not %v", timeLag)
+
+	partition.LookbackOffset = -1
+	timeLag = partitionTimeLag(partition, 200000)
+	assert.Equalf(t, int64(-1), timeLag, "Expected time lag to not be known without the lookback offset, not %v", timeLag)
+}
+
+func TestPartitionTimeLag_Sampled(t *testing.T) {
+	// 100 messages a second were produced for the first 100 seconds, 10 a second for the next 100, and 5 a second since
+	partition := &protocol.ConsumerPartition{
+		BrokerOffsets: []int64{11400, 11500},
+		TimeOffsets: []protocol.TimeOffset{
+			{Timestamp: 0, Offset: 0},
+			{Timestamp: 100000, Offset: 10000},
+			{Timestamp: 200000, Offset: 11000},
+		},
+		LookbackOffset:    10000,
+		LookbackTimestamp: 100000,
+		CurrentLag:        250,
+	}
+	timeLag := partitionTimeLag(partition, 300000)
+	assert.Equalf(t, int64(50000), timeLag, "Expected time lag to be 50000 from the newest samples, not %v", timeLag)
+
+	// The offset is found in the range of samples it falls in, rather than from the rate since the lookback
+	partition.BrokerOffsets = []int64{12000}
+	partition.CurrentLag = 7000
+	timeLag = partitionTimeLag(partition, 300000)
+	assert.Equalf(t, int64(250000), timeLag, "Expected time lag to be 250000, not %v", timeLag)
+
+	// Older than the oldest sample, the rate since then (10 a second) is used
+	partition.TimeOffsets = partition.TimeOffsets[1:]
+	partition.CurrentLag = 3000
+	timeLag = partitionTimeLag(partition, 300000)
+	assert.Equalf(t, int64(300000), timeLag, "Expected time lag to be 300000, not %v", timeLag)
+
+	partition.CurrentLag = 0
+	timeLag = partitionTimeLag(partition, 300000)
+	assert.Equalf(t, int64(0), timeLag, "Expected time lag to be 0 with no lag, not %v", timeLag)
+
+	// Nothing produced since the oldest sample means the consumer is at least that far behind
+	partition.TimeOffsets = []protocol.TimeOffset{{Timestamp: 100000, Offset: 12000}}
+	partition.CurrentLag = 100
+	timeLag = partitionTimeLag(partition, 300000)
+	assert.Equalf(t, int64(200000), timeLag, "Expected time lag to be 200000, not %v", timeLag)
+}
+
+func TestCachingEvaluator_SingleRequest_DeletedTopic(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.expire-cache", 1)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	// The group has stopped committing offsets for a second topic, so that partition is in error
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOffset,
+		Cluster:             "testcluster",
+		Topic:               "deadtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              5000,
+		Timestamp:           time.Now().Unix() * 1000,
+	}
+	time.Sleep(50 * time.Millisecond)
+	startTime := (time.Now().Unix() * 1000) - 100000
+	for i := 0; i < 10; i++ {
+		storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+			RequestType: protocol.StorageSetConsumerOffset,
+			Cluster:     "testcluster",
+			Topic:       "deadtopic",
+			Group:       "testgroup",
+			Partition:   0,
+			Offset:      1000,
+			Timestamp:   startTime + int64(i*1000),
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: true,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+	assert.Equalf(t, protocol.StatusError, response.Status, "Expected status to be ERR, not %v", response.Status.String())
+	assert.Lenf(t, response.Partitions, 2, "Expected 2 partitions, not %v", len(response.Partitions))
+
+	// Once the topic is deleted, the group recovers when it is next evaluated
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteTopic,
+		Cluster:     "testcluster",
+		Topic:       "deadtopic",
+	}
+	time.Sleep(1100 * time.Millisecond)
+
+	request.Reply = make(chan *protocol.ConsumerGroupStatus)
+	module.GetCommunicationChannel() <- request
+	response = <-request.Reply
+	assert.Equalf(t, protocol.StatusOK, response.Status, "Expected status to be OK, not %v", response.Status.String())
+	assert.Lenf(t, response.Partitions, 1, "Expected 1 partition, not %v", len(response.Partitions))
+	assert.Equalf(t, "testtopic", response.Partitions[0].Topic, "Expected remaining partition to be testtopic, not %v", response.Partitions[0].Topic)
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_SingleRequest_Baseline(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.baseline", true)
+	viper.Set("evaluator.test.baseline-min-samples", 5)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	// The test group has a total lag of 2421, far above a baseline of 100 to 120
+	timestamp := time.Now().Unix() * 1000
+	for i := 0; i < 5; i++ {
+		storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+			RequestType: protocol.StorageSetGroupBaselineSample,
+			Cluster:     "testcluster",
+			Group:       "testgroup",
+			Lag:         uint64(100 + (i%2)*20),
+			Timestamp:   timestamp,
+		}
+	}
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.True(t, response.Anomalous, "Expected group to be anomalous")
+	assert.Equalf(t, protocol.StatusWarning, response.Status, "Expected status to be WARN, not %v", response.Status.String())
+	assert.NotNil(t, response.Baseline, "Expected baseline to be returned")
+	assert.Equalf(t, int64(5), response.Baseline.Samples, "Expected baseline to have 5 samples, not %v", response.Baseline.Samples)
+
+	// The evaluation added its own sample to the baseline
+	storageRequest := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchGroupBaseline,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	storageCoordinator.App.StorageChannel <- storageRequest
+	baseline := (<-storageRequest.Reply).(*protocol.LagBaseline)
+	assert.Equalf(t, int64(6), baseline.Hours[time.Now().UTC().Hour()].Samples, "Expected baseline to have 6 samples, not %v", baseline.Hours[time.Now().UTC().Hour()].Samples)
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_SingleRequest_StatusHistory(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.status-history", true)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	// The evaluation recorded the status of the group
+	storageRequest := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchGroupStatusHistory,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	storageCoordinator.App.StorageChannel <- storageRequest
+	history := (<-storageRequest.Reply).([]protocol.StatusTransition)
+	assert.Lenf(t, history, 1, "Expected 1 status change, not %v", len(history))
+	assert.Equalf(t, response.Status, history[0].Status, "Expected recorded status to be %v, not %v", response.Status.String(), history[0].Status.String())
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_SingleRequest_BaselineTooFewSamples(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.baseline", true)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.False(t, response.Anomalous, "Expected group to not be anomalous without a baseline")
+	assert.Equalf(t, protocol.StatusOK, response.Status, "Expected status to be OK, not %v", response.Status.String())
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_Configure_BadBaseline(t *testing.T) {
+	for _, key := range []string{"baseline-deviations", "baseline-min-samples"} {
+		storageCoordinator, module := fixtureModule()
+		viper.Set("evaluator.test."+key, 0)
+		assert.Panicsf(t, func() { module.Configure("test", "evaluator.test") }, "Expected panic for zero %v", key)
+		storageCoordinator.Stop()
+	}
+}
+
+func TestCachingEvaluator_SingleRequest_Misconfigured(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.misconfigured-topic-share", 0.5)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	// testgroup commits to testtopic, and to a topic that the cluster does not have
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "missingtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      100,
+		Timestamp:   time.Now().Unix() * 1000,
+	}
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.True(t, response.PossiblyMisconfigured, "Expected group to be possibly misconfigured")
+	assert.Equalf(t, []string{"missingtopic"}, response.MissingTopics, "Expected missingtopic, not %v", response.MissingTopics)
+	assert.Equalf(t, protocol.StatusOK, response.Status, "Expected status to be OK, not %v", response.Status.String())
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_SingleRequest_MisconfiguredOnlyUnknownTopics(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.misconfigured-topic-share", 0.5)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	// wronggroup only commits to a topic that the cluster does not have, so it has no offsets stored
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "missingtopic",
+		Group:       "wronggroup",
+		Partition:   0,
+		Offset:      100,
+		Timestamp:   time.Now().Unix() * 1000,
+	}
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "wronggroup",
+		ShowAll: true,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.True(t, response.PossiblyMisconfigured, "Expected group to be possibly misconfigured")
+	assert.Equalf(t, []string{"missingtopic"}, response.MissingTopics, "Expected missingtopic, not %v", response.MissingTopics)
+	assert.Equalf(t, protocol.StatusOK, response.Status, "Expected status to be OK, not %v", response.Status.String())
+	assert.Empty(t, response.Partitions, "Expected no partitions")
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_SingleRequest_MisconfiguredBelowShare(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.misconfigured-topic-share", 0.75)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "missingtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      100,
+		Timestamp:   time.Now().Unix() * 1000,
+	}
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.False(t, response.PossiblyMisconfigured, "Expected group to not be flagged with half of its topics missing")
+	assert.Nil(t, response.MissingTopics, "Expected no missing topics")
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_Configure_BadMisconfiguredTopicShare(t *testing.T) {
+	for _, share := range []float64{-0.1, 1.5} {
+		storageCoordinator, module := fixtureModule()
+		viper.Set("evaluator.test.misconfigured-topic-share", share)
+		assert.Panicsf(t, func() { module.Configure("test", "evaluator.test") }, "Expected panic for share %v", share)
+		storageCoordinator.Stop()
+	}
+}
+
+func TestCachingEvaluator_SingleRequest_MemberLag(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.member-lag", true)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	// Requests for the same group are handled in order, so the owner is set before the consumer is fetched
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOwner,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Topic:       "testtopic",
+		Partition:   0,
+		Owner:       "testhost.example.com",
+		ClientID:    "testclient",
+		InstanceID:  "testinstance",
+	}
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.Lenf(t, response.Members, 1, "Expected 1 member status object, not %v", len(response.Members))
+	assert.Equal(t, &protocol.MemberStatus{
+		InstanceID:     "testinstance",
+		ClientID:       "testclient",
+		Owner:          "testhost.example.com",
+		Status:         protocol.StatusOK,
+		PartitionCount: 1,
+		TotalLag:       2421,
+	}, response.Members[0])
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_evaluateMemberStatus(t *testing.T) {
+	partitions := []*protocol.PartitionStatus{
+		{Topic: "topic1", Partition: 0, InstanceID: "instance1", ClientID: "client", Owner: "host1", Status: protocol.StatusOK, CurrentLag: 10},
+		{Topic: "topic1", Partition: 1, InstanceID: "instance2", ClientID: "client", Owner: "host2", Status: protocol.StatusStop, CurrentLag: 500},
+		{Topic: "topic2", Partition: 0, InstanceID: "instance1", ClientID: "client", Owner: "host1", Status: protocol.StatusWarning, CurrentLag: 20},
+		{Topic: "topic2", Partition: 1, ClientID: "client", Owner: "host3", Status: protocol.StatusOK, CurrentLag: 30},
+		{Topic: "topic2", Partition: 2, Status: protocol.StatusOK, CurrentLag: 1000},
+	}
+
+	members := evaluateMemberStatus(partitions)
+	assert.Equal(t, []*protocol.MemberStatus{
+		{InstanceID: "instance2", ClientID: "client", Owner: "host2", Status: protocol.StatusError, PartitionCount: 1, TotalLag: 500},
+		{InstanceID: "", ClientID: "client", Owner: "host3", Status: protocol.StatusOK, PartitionCount: 1, TotalLag: 30},
+		{InstanceID: "instance1", ClientID: "client", Owner: "host1", Status: protocol.StatusWarning, PartitionCount: 2, TotalLag: 30},
+	}, members)
+}
+
 func TestCachingEvaluator_SingleRequest_Incomplete(t *testing.T) {
 	storageCoordinator, module := startWithTestCluster()
 
@@ -632,3 +1412,102 @@ func TestCachingEvaluator_TopicDeleted(t *testing.T) {
 	assert.Emptyf(t, evalResponse.Partitions, "Expected no partitions to be returned")
 	assert.Equalf(t, float32(0.0), evalResponse.Complete, "Expected 'Complete' to be 0.0")
 }
+
+// Spiky lag: a single burst in the middle of the window, and then a burst that is still ongoing at the end
+var spikyOffsets = []*protocol.ConsumerOffset{
+	{Offset: 1000, Order: 1, Timestamp: 100000, Lag: &protocol.Lag{Value: 0}},
+	{Offset: 2000, Order: 2, Timestamp: 200000, Lag: &protocol.Lag{Value: 5000}},
+	{Offset: 3000, Order: 3, Timestamp: 300000, Lag: &protocol.Lag{Value: 0}},
+	{Offset: 4000, Order: 4, Timestamp: 400000, Lag: &protocol.Lag{Value: 0}},
+	{Offset: 5000, Order: 5, Timestamp: 500000, Lag: &protocol.Lag{Value: 4000}},
+	{Offset: 6000, Order: 6, Timestamp: 600000, Lag: &protocol.Lag{Value: 6000}},
+}
+
+func TestCachingEvaluator_checkIfLagBurst(t *testing.T) {
+	testCases := []struct {
+		offsets        []*protocol.ConsumerOffset
+		allowedLag     uint64
+		burstTolerance int64
+		timeNow        int64
+		expected       bool
+	}{
+		// Disabled
+		{spikyOffsets, 0, 0, 650, false},
+		// Lag has been over since 500s, which is less than 200s ago
+		{spikyOffsets, 0, 200, 650, true},
+		// Lag has been over since 500s, which is more than 100s ago
+		{spikyOffsets, 0, 100, 650, false},
+		// With an allowed lag of 4000, the lag has only been over since 600s
+		{spikyOffsets, 4000, 100, 650, true},
+		// With an allowed lag over all the spikes, the lag started after the last commit
+		{spikyOffsets, 10000, 100, 650, true},
+		{spikyOffsets, 10000, 100, 800, false},
+		// Offsets with no lag information end the run
+		{[]*protocol.ConsumerOffset{
+			{Offset: 1000, Order: 1, Timestamp: 100000, Lag: &protocol.Lag{Value: 5000}},
+			{Offset: 2000, Order: 2, Timestamp: 200000, Lag: nil},
+			{Offset: 3000, Order: 3, Timestamp: 300000, Lag: &protocol.Lag{Value: 5000}},
+		}, 0, 100, 350, true},
+	}
+
+	for i, testCase := range testCases {
+		result := checkIfLagBurst(testCase.offsets, testCase.allowedLag, testCase.burstTolerance, testCase.timeNow)
+		assert.Equalf(t, testCase.expected, result, "TEST %v: Expected checkIfLagBurst to return %v, not %v", i, testCase.expected, result)
+	}
+}
+
+func TestCachingEvaluator_evaluatePartitionStatus_BurstTolerance(t *testing.T) {
+	// Build partitions with each commit 10 seconds apart ending now. One has lag that is not decreasing over the whole
+	// window, and the other has no lag until a single spike in the last commit, caused by a rewind
+	timeNow := time.Now().Unix() * 1000
+	spike := &protocol.ConsumerPartition{CurrentLag: 5000, BrokerOffsets: []int64{10000}}
+	sustained := &protocol.ConsumerPartition{CurrentLag: 5000, BrokerOffsets: []int64{10000}}
+	for i := 0; i < 10; i++ {
+		timestamp := timeNow - int64((9-i)*10000)
+		spikeOffset, spikeLag := int64(i*1000), uint64(0)
+		if i == 9 {
+			spikeOffset, spikeLag = 5000, 5000
+		}
+		spike.Offsets = append(spike.Offsets, &protocol.ConsumerOffset{Offset: spikeOffset, Order: int64(i), Timestamp: timestamp, Lag: &protocol.Lag{Value: spikeLag}})
+		sustained.Offsets = append(sustained.Offsets, &protocol.ConsumerOffset{Offset: int64(i * 1000), Order: int64(i), Timestamp: timestamp, Lag: &protocol.Lag{Value: uint64(1000 + (i * 100))}})
+	}
+
+	status := evaluatePartitionStatus(sustained, 0, 0, 0)
+	assert.Equalf(t, protocol.StatusWarning, status.Status, "Expected sustained lag without tolerance to be WARN, not %v", status.Status.String())
+	status = evaluatePartitionStatus(sustained, 0, 0, 60)
+	assert.Equalf(t, protocol.StatusWarning, status.Status, "Expected sustained lag with tolerance to be WARN, not %v", status.Status.String())
+	status = evaluatePartitionStatus(spike, 0, 0, 0)
+	assert.Equalf(t, protocol.StatusRewind, status.Status, "Expected lag spike without tolerance to be REWIND, not %v", status.Status.String())
+	status = evaluatePartitionStatus(spike, 0, 0, 60)
+	assert.Equalf(t, protocol.StatusOK, status.Status, "Expected lag spike with tolerance to be OK, not %v", status.Status.String())
+}
+
+func TestCachingEvaluator_evaluatePartitionStatus_NoBrokerOffsets(t *testing.T) {
+	// A partition that has stopped committing, with lag, but with no known end offset is skipped
+	partition := &protocol.ConsumerPartition{
+		Offsets: []*protocol.ConsumerOffset{
+			{Offset: 1000, Order: 1, Timestamp: 100000, Lag: &protocol.Lag{Value: 100}},
+			{Offset: 1000, Order: 2, Timestamp: 200000, Lag: &protocol.Lag{Value: 200}},
+		},
+	}
+
+	status := evaluatePartitionStatus(partition, 0, 0, 0)
+	assert.Equalf(t, protocol.StatusOK, status.Status, "Expected status to be OK, not %v", status.Status.String())
+	assert.Equalf(t, float32(1.0), status.Complete, "Expected complete to be 1.0, not %v", status.Complete)
+	assert.Equal(t, partition.Offsets[1], status.End, "Expected end offset to be set")
+}
+
+func TestCachingEvaluator_evaluatePartitionStatus_AllOffsetsEvicted(t *testing.T) {
+	// Storage has removed every offset for the partition for being too old, so it can't be evaluated
+	partition := &protocol.ConsumerPartition{
+		Offsets:       []*protocol.ConsumerOffset{nil, nil, nil},
+		BrokerOffsets: []int64{5000},
+		CurrentLag:    0,
+	}
+
+	status := evaluatePartitionStatus(partition, 0, 0, 0)
+	assert.Equalf(t, protocol.StatusOK, status.Status, "Expected status to be OK, not %v", status.Status.String())
+	assert.Equalf(t, float32(0), status.Complete, "Expected complete to be 0, not %v", status.Complete)
+	assert.Nil(t, status.Start, "Expected no start offset")
+	assert.Nil(t, status.End, "Expected no end offset")
+}
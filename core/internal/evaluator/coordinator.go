@@ -99,9 +99,11 @@ func (ec *Coordinator) Configure() {
 	// Create all configured evaluator modules, add to list of evaluators
 	for name := range modules {
 		configRoot := "evaluator." + name
-		module := getModuleForClass(ec.App, name, viper.GetString(configRoot+".class-name"))
-		module.Configure(name, configRoot)
-		ec.modules[name] = module
+		helpers.RecordConfigError(configRoot, func() {
+			module := getModuleForClass(ec.App, name, viper.GetString(configRoot+".class-name"))
+			module.Configure(name, configRoot)
+			ec.modules[name] = module
+		})
 	}
 }
 
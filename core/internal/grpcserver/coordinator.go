@@ -0,0 +1,95 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+// Package grpcserver - gRPC API endpoint
+// The grpcserver subsystem provides a gRPC interface to Burrow, alongside the HTTP interface, for clients that prefer
+// generated clients to JSON. It serves the core read operations of the HTTP API (the cluster list, group status, and
+// consumer detail), with the messages defined in core/protocol/burrowpb mirroring the JSON responses.
+package grpcserver
+
+import (
+	"net"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+	"github.com/linkedin/Burrow/core/protocol/burrowpb"
+)
+
+// Coordinator runs the gRPC interface for Burrow on a single listener. It is only started if the grpcserver section
+// is configured.
+type Coordinator struct {
+	// App is a pointer to the application context. This stores the channels to the storage and evaluator subsystems
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	address  string
+	server   *grpc.Server
+	listener net.Listener
+}
+
+// Configure is called to configure the gRPC server. The address to listen on is required, and a TLS profile may be
+// given to serve with TLS. Any configuration failure will cause the func to panic with an appropriate error message.
+func (gc *Coordinator) Configure() {
+	gc.Log.Info("configuring")
+
+	gc.address = viper.GetString("grpcserver.address")
+	if !helpers.ValidateHostPort(gc.address, true) {
+		panic("invalid gRPC server listener address")
+	}
+
+	var options []grpc.ServerOption
+	if viper.IsSet("grpcserver.tls") {
+		tlsName := viper.GetString("grpcserver.tls")
+		certFile := viper.GetString("tls." + tlsName + ".certfile")
+		keyFile := viper.GetString("tls." + tlsName + ".keyfile")
+		if certFile == "" || keyFile == "" {
+			panic("TLS gRPC server specified with missing certificate or key")
+		}
+		creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+		if err != nil {
+			panic("cannot read TLS certificate or key file: " + err.Error())
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+
+	gc.server = grpc.NewServer(options...)
+	burrowpb.RegisterBurrowServer(gc.server, &burrowServer{App: gc.App})
+}
+
+// Start starts the listener on the configured address, and then serves gRPC requests on it. If the listener cannot
+// be started, the error is returned to the caller.
+func (gc *Coordinator) Start() error {
+	gc.Log.Info("starting")
+
+	ln, err := net.Listen("tcp", gc.address)
+	if err != nil {
+		gc.Log.Error("failed to listen", zap.String("listener", gc.address), zap.Error(err))
+		return err
+	}
+	gc.Log.Info("started listener", zap.String("listener", ln.Addr().String()))
+	gc.listener = ln
+
+	go gc.server.Serve(ln)
+	return nil
+}
+
+// Stop stops the gRPC server, closing the listener and any open connections without waiting for calls to complete.
+func (gc *Coordinator) Stop() error {
+	gc.Log.Info("shutdown")
+	gc.server.Stop()
+	return nil
+}
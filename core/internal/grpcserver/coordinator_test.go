@@ -0,0 +1,80 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/linkedin/Burrow/core/protocol"
+	"github.com/linkedin/Burrow/core/protocol/burrowpb"
+)
+
+func fixtureCoordinator() *Coordinator {
+	coordinator := Coordinator{
+		Log: zap.NewNop(),
+		App: &protocol.ApplicationContext{
+			Logger:           zap.NewNop(),
+			StorageChannel:   make(chan *protocol.StorageRequest),
+			EvaluatorChannel: make(chan *protocol.EvaluatorRequest),
+		},
+	}
+
+	viper.Reset()
+	viper.Set("grpcserver.address", "localhost:0")
+	return &coordinator
+}
+
+func TestCoordinator_ImplementsCoordinator(t *testing.T) {
+	assert.Implements(t, (*protocol.Coordinator)(nil), new(Coordinator))
+}
+
+func TestCoordinator_Configure_BadAddress(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("grpcserver.address", "nohost")
+	assert.Panics(t, func() { coordinator.Configure() }, "The code did not panic")
+}
+
+func TestCoordinator_Configure_MissingTLSFiles(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("grpcserver.tls", "notls")
+	assert.Panics(t, func() { coordinator.Configure() }, "The code did not panic")
+}
+
+func TestCoordinator_StartStop(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.Configure()
+	err := coordinator.Start()
+	assert.NoError(t, err, "Expected Start to return no error")
+
+	// Respond to the storage request for the cluster list
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchClusters, request.RequestType, "Expected request of type StorageFetchClusters, not %v", request.RequestType)
+		request.Reply <- []string{"testcluster"}
+	}()
+
+	conn, err := grpc.NewClient(coordinator.listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err, "Expected client setup to return no error")
+	defer conn.Close()
+
+	response, err := burrowpb.NewBurrowClient(conn).ListClusters(context.Background(), &burrowpb.ListClustersRequest{})
+	assert.NoError(t, err, "Expected ListClusters to return no error")
+	assert.Equal(t, []string{"testcluster"}, response.GetClusters())
+
+	err = coordinator.Stop()
+	assert.NoError(t, err, "Expected Stop to return no error")
+}
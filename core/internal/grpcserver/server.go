@@ -0,0 +1,164 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package grpcserver
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+	"github.com/linkedin/Burrow/core/protocol/burrowpb"
+)
+
+// burrowServer implements the Burrow gRPC service. The requests to the storage and evaluator subsystems are the same
+// ones that the HTTP server makes, and the responses are converted to the protobuf messages.
+type burrowServer struct {
+	burrowpb.UnimplementedBurrowServer
+
+	App *protocol.ApplicationContext
+}
+
+func (s *burrowServer) ListClusters(_ context.Context, _ *burrowpb.ListClustersRequest) (*burrowpb.ListClustersResponse, error) {
+	return &burrowpb.ListClustersResponse{Clusters: helpers.FetchClusterList(s.App)}, nil
+}
+
+func (s *burrowServer) GetConsumerStatus(_ context.Context, request *burrowpb.GetConsumerStatusRequest) (*burrowpb.ConsumerGroupStatus, error) {
+	groupStatus := helpers.FetchGroupStatus(s.App, request.GetCluster(), request.GetGroup(), request.GetShowAll())
+	if groupStatus.Status == protocol.StatusNotFound {
+		return nil, status.Error(codes.NotFound, "cluster or consumer not found")
+	}
+	return convertGroupStatus(groupStatus), nil
+}
+
+func (s *burrowServer) GetConsumerDetail(_ context.Context, request *burrowpb.GetConsumerDetailRequest) (*burrowpb.GetConsumerDetailResponse, error) {
+	topics, ok := helpers.FetchConsumerDetail(s.App, request.GetCluster(), request.GetGroup())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "cluster or consumer not found")
+	}
+
+	response := &burrowpb.GetConsumerDetailResponse{Topics: make(map[string]*burrowpb.ConsumerPartitions, len(topics))}
+	for topic, partitions := range topics {
+		converted := make([]*burrowpb.ConsumerPartition, len(partitions))
+		for i, partition := range partitions {
+			converted[i] = convertConsumerPartition(partition)
+		}
+		response.Topics[topic] = &burrowpb.ConsumerPartitions{Partitions: converted}
+	}
+	return response, nil
+}
+
+func convertGroupStatus(groupStatus *protocol.ConsumerGroupStatus) *burrowpb.ConsumerGroupStatus {
+	converted := &burrowpb.ConsumerGroupStatus{
+		Cluster:               groupStatus.Cluster,
+		Group:                 groupStatus.Group,
+		Status:                burrowpb.Status(groupStatus.Status),
+		Stale:                 groupStatus.Stale,
+		Anomalous:             groupStatus.Anomalous,
+		Complete:              groupStatus.Complete,
+		Partitions:            convertPartitionStatuses(groupStatus.Partitions),
+		PartitionCount:        int32(groupStatus.TotalPartitions),
+		ActivePartitionCount:  int32(groupStatus.ActivePartitions),
+		Maxlag:                convertPartitionStatus(groupStatus.Maxlag),
+		Totallag:              groupStatus.TotalLag,
+		MaxTimeLag:            groupStatus.MaxTimeLag,
+		StalledPartitions:     convertPartitionStatuses(groupStatus.StalledPartitions),
+		OffsetOutOfRange:      groupStatus.OffsetOutOfRange,
+		PossiblyMisconfigured: groupStatus.PossiblyMisconfigured,
+		MissingTopics:         groupStatus.MissingTopics,
+	}
+	if groupStatus.Baseline != nil {
+		converted.Baseline = &burrowpb.LagBaselineHour{
+			Samples: groupStatus.Baseline.Samples,
+			Mean:    groupStatus.Baseline.Mean,
+			StdDev:  groupStatus.Baseline.StdDev,
+		}
+	}
+	for _, member := range groupStatus.Members {
+		converted.Members = append(converted.Members, &burrowpb.MemberStatus{
+			InstanceId:     member.InstanceID,
+			ClientId:       member.ClientID,
+			Owner:          member.Owner,
+			Status:         burrowpb.Status(member.Status),
+			PartitionCount: int32(member.PartitionCount),
+			Totallag:       member.TotalLag,
+		})
+	}
+	return converted
+}
+
+func convertPartitionStatuses(partitions []*protocol.PartitionStatus) []*burrowpb.PartitionStatus {
+	converted := make([]*burrowpb.PartitionStatus, 0, len(partitions))
+	for _, partition := range partitions {
+		converted = append(converted, convertPartitionStatus(partition))
+	}
+	return converted
+}
+
+func convertPartitionStatus(partition *protocol.PartitionStatus) *burrowpb.PartitionStatus {
+	if partition == nil {
+		return nil
+	}
+	return &burrowpb.PartitionStatus{
+		Topic:            partition.Topic,
+		Partition:        partition.Partition,
+		Owner:            partition.Owner,
+		ClientId:         partition.ClientID,
+		InstanceId:       partition.InstanceID,
+		Status:           burrowpb.Status(partition.Status),
+		Start:            convertConsumerOffset(partition.Start),
+		End:              convertConsumerOffset(partition.End),
+		CurrentLag:       partition.CurrentLag,
+		TimeLag:          partition.TimeLag,
+		Complete:         partition.Complete,
+		Compacted:        partition.Compacted,
+		OffsetOutOfRange: partition.OffsetOutOfRange,
+	}
+}
+
+// convertConsumerPartition converts the offsets stored for a partition. The offsets that have not been stored yet
+// (nil in the JSON response) are left out, as a repeated field cannot hold them.
+func convertConsumerPartition(partition *protocol.ConsumerPartition) *burrowpb.ConsumerPartition {
+	if partition == nil {
+		return &burrowpb.ConsumerPartition{}
+	}
+	converted := &burrowpb.ConsumerPartition{
+		Offsets:    make([]*burrowpb.ConsumerOffset, 0, len(partition.Offsets)),
+		Owner:      partition.Owner,
+		ClientId:   partition.ClientID,
+		InstanceId: partition.InstanceID,
+		CurrentLag: partition.CurrentLag,
+		Compacted:  partition.Compacted,
+	}
+	for _, offset := range partition.Offsets {
+		if offset != nil {
+			converted.Offsets = append(converted.Offsets, convertConsumerOffset(offset))
+		}
+	}
+	return converted
+}
+
+func convertConsumerOffset(offset *protocol.ConsumerOffset) *burrowpb.ConsumerOffset {
+	if offset == nil {
+		return nil
+	}
+	converted := &burrowpb.ConsumerOffset{
+		Offset:     offset.Offset,
+		Timestamp:  offset.Timestamp,
+		ObservedAt: offset.ObservedTimestamp,
+	}
+	if offset.Lag != nil {
+		lag := offset.Lag.Value
+		converted.Lag = &lag
+	}
+	return converted
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/linkedin/Burrow/core/protocol"
+	"github.com/linkedin/Burrow/core/protocol/burrowpb"
+)
+
+func TestBurrowServer_GetConsumerStatus(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	server := &burrowServer{App: coordinator.App}
+
+	// Respond to the expected evaluator requests
+	go func() {
+		request := <-coordinator.App.EvaluatorChannel
+		assert.True(t, request.ShowAll, "Expected request ShowAll to be true")
+		request.Reply <- &protocol.ConsumerGroupStatus{
+			Cluster:         request.Cluster,
+			Group:           request.Group,
+			Status:          protocol.StatusWarning,
+			Complete:        1.0,
+			Partitions:      []*protocol.PartitionStatus{{Topic: "testtopic", Partition: 0, Status: protocol.StatusWarning, CurrentLag: 100, End: &protocol.ConsumerOffset{Offset: 900, Lag: &protocol.Lag{Value: 50}}}},
+			TotalPartitions: 1,
+			TotalLag:        100,
+			Members:         []*protocol.MemberStatus{{ClientID: "testclient", Status: protocol.StatusWarning, PartitionCount: 1, TotalLag: 100}},
+		}
+
+		// Second request is a 404
+		request = <-coordinator.App.EvaluatorChannel
+		request.Reply <- &protocol.ConsumerGroupStatus{Cluster: request.Cluster, Group: request.Group, Status: protocol.StatusNotFound}
+	}()
+
+	response, err := server.GetConsumerStatus(context.Background(), &burrowpb.GetConsumerStatusRequest{Cluster: "testcluster", Group: "testgroup", ShowAll: true})
+	assert.NoError(t, err, "Expected GetConsumerStatus to return no error")
+	assert.Equalf(t, burrowpb.Status_WARN, response.GetStatus(), "Expected status to be WARN, not %v", response.GetStatus())
+	assert.Equalf(t, uint64(100), response.GetTotallag(), "Expected totallag to be 100, not %v", response.GetTotallag())
+	assert.Len(t, response.GetPartitions(), 1, "Expected one partition")
+	assert.Equalf(t, uint64(50), response.GetPartitions()[0].GetEnd().GetLag(), "Expected end lag to be 50, not %v", response.GetPartitions()[0].GetEnd().GetLag())
+	assert.Nil(t, response.GetMaxlag(), "Expected no maxlag")
+	assert.Equalf(t, "testclient", response.GetMembers()[0].GetClientId(), "Expected member client ID to be testclient, not %v", response.GetMembers()[0].GetClientId())
+
+	_, err = server.GetConsumerStatus(context.Background(), &burrowpb.GetConsumerStatusRequest{Cluster: "testcluster", Group: "nogroup"})
+	assert.Equalf(t, codes.NotFound, status.Code(err), "Expected error code NotFound, not %v", status.Code(err))
+}
+
+func TestBurrowServer_GetConsumerDetail(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	server := &burrowServer{App: coordinator.App}
+
+	// Respond to the expected storage requests
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchConsumer, request.RequestType, "Expected request of type StorageFetchConsumer, not %v", request.RequestType)
+		request.Reply <- protocol.ConsumerTopics{
+			"testtopic": {
+				{Offsets: []*protocol.ConsumerOffset{nil, {Offset: 100, Timestamp: 1000}, {Offset: 200, Timestamp: 2000, Lag: &protocol.Lag{Value: 10}}}, CurrentLag: 10},
+			},
+		}
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		close(request.Reply)
+	}()
+
+	response, err := server.GetConsumerDetail(context.Background(), &burrowpb.GetConsumerDetailRequest{Cluster: "testcluster", Group: "testgroup"})
+	assert.NoError(t, err, "Expected GetConsumerDetail to return no error")
+	partitions := response.GetTopics()["testtopic"].GetPartitions()
+	assert.Len(t, partitions, 1, "Expected one partition")
+	assert.Len(t, partitions[0].GetOffsets(), 2, "Expected the offset that was not stored to be left out")
+	assert.Nil(t, partitions[0].GetOffsets()[0].Lag, "Expected the lag to not be set when it is not known")
+	assert.Equalf(t, uint64(10), partitions[0].GetOffsets()[1].GetLag(), "Expected lag to be 10, not %v", partitions[0].GetOffsets()[1].GetLag())
+
+	_, err = server.GetConsumerDetail(context.Background(), &burrowpb.GetConsumerDetailRequest{Cluster: "testcluster", Group: "nogroup"})
+	assert.Equalf(t, codes.NotFound, status.Code(err), "Expected error code NotFound, not %v", status.Code(err))
+}
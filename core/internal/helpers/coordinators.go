@@ -14,7 +14,10 @@
 package helpers
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/stretchr/testify/mock"
@@ -36,13 +39,112 @@ func StartCoordinatorModules(modules map[string]protocol.Module) error {
 	return nil
 }
 
+// StartIsolatedModules is a helper func for coordinators to start a list of modules, where a module failing to start
+// should not stop the others. Given a map of protocol.Module, it calls the Start func on each one. If a module returns
+// an error, it is removed from the map and passed to failed, and the rest of the modules are still started.
+func StartIsolatedModules(modules map[string]protocol.Module, failed func(name string, err error)) {
+	for name, module := range modules {
+		if err := module.Start(); err != nil {
+			delete(modules, name)
+			failed(name, err)
+		}
+	}
+}
+
+// The modules that StopCoordinatorModules is waiting on, so that if shutdown times out, the modules that did not stop
+// can be reported
+var stoppingModules sync.Map
+
 // StopCoordinatorModules is a helper func for coordinators to stop a list of modules. Given a map of protocol.Module,
 // it calls the Stop func on each one. Any errors that are returned are ignored.
 func StopCoordinatorModules(modules map[string]protocol.Module) {
 	// Stop all the modules passed in
-	for _, module := range modules {
+	for name, module := range modules {
+		key := fmt.Sprintf("%v (%T)", name, module)
+		stoppingModules.Store(key, struct{}{})
 		module.Stop()
+		stoppingModules.Delete(key)
+	}
+}
+
+// StoppingModules returns the modules that StopCoordinatorModules has called Stop on, but that have not yet returned.
+// Each module is given as its name and type, such as "local (*cluster.KafkaCluster)".
+func StoppingModules() []string {
+	modules := make([]string, 0)
+	stoppingModules.Range(func(key, _ interface{}) bool {
+		modules = append(modules, key.(string))
+		return true
+	})
+	sort.Strings(modules)
+	return modules
+}
+
+// The errors collected by CollectConfigErrors, or nil when the configuration is not being validated
+var (
+	configErrorsLock sync.Mutex
+	configErrors     []string
+)
+
+// CollectConfigErrors calls configure, which is expected to call the Configure funcs of the coordinators, and returns
+// the errors that RecordConfigError recorded while it ran, rather than stopping at the first one. This is used to
+// validate the configuration without running Burrow.
+func CollectConfigErrors(configure func()) []string {
+	configErrorsLock.Lock()
+	configErrors = make([]string, 0)
+	configErrorsLock.Unlock()
+
+	configure()
+
+	configErrorsLock.Lock()
+	defer configErrorsLock.Unlock()
+	collected := configErrors
+	configErrors = nil
+	return collected
+}
+
+// RecordConfigError calls configure, which sets up part of the configuration (such as a module) and panics if that
+// part is not valid. Normally the panic is left alone, as a bad configuration cannot be recovered from. While
+// CollectConfigErrors is running, the panic is instead recorded as an error for source (such as the module's config
+// root), and RecordConfigError returns so that the rest of the configuration can be checked.
+func RecordConfigError(source string, configure func()) {
+	configErrorsLock.Lock()
+	collecting := configErrors != nil
+	configErrorsLock.Unlock()
+	if !collecting {
+		configure()
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			configErrorsLock.Lock()
+			configErrors = append(configErrors, fmt.Sprintf("%v: %v", source, r))
+			configErrorsLock.Unlock()
+		}
+	}()
+	configure()
+}
+
+// ConfigureIsolated calls configure, as RecordConfigError does, unless isolate is set. In that case, a panic is
+// recovered and returned as an error, so that the caller can leave out the part of the configuration that failed (such
+// as a single cluster) and carry on. Panics are never isolated while CollectConfigErrors is running, so that they are
+// still reported as configuration errors.
+func ConfigureIsolated(source string, isolate bool, configure func()) (err error) {
+	configErrorsLock.Lock()
+	collecting := configErrors != nil
+	configErrorsLock.Unlock()
+	if !isolate || collecting {
+		RecordConfigError(source, configure)
+		return nil
 	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	configure()
+	return nil
 }
 
 // MockModule is a mock of protocol.Module that also satisfies the various subsystem Module variants, and is used in
@@ -105,6 +207,7 @@ func (m *MockModule) AcceptConsumerGroup(status *protocol.ConsumerGroupStatus) b
 }
 
 // Notify mocks the notifier.Module Notify func
-func (m *MockModule) Notify(status *protocol.ConsumerGroupStatus, eventID string, startTime time.Time, stateGood bool) {
-	m.Called(status, eventID, startTime, stateGood)
+func (m *MockModule) Notify(status *protocol.ConsumerGroupStatus, eventID string, startTime time.Time, stateGood bool) error {
+	args := m.Called(status, eventID, startTime, stateGood)
+	return args.Error(0)
 }
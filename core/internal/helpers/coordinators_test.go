@@ -12,6 +12,7 @@ package helpers
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -66,3 +67,43 @@ func TestStopCoordinatorModules(t *testing.T) {
 	mock1.AssertExpectations(t)
 	mock2.AssertExpectations(t)
 }
+
+func TestStopCoordinatorModules_StoppingModules(t *testing.T) {
+	mock1 := &MockModule{}
+	modules := map[string]protocol.Module{
+		"mock1": mock1,
+	}
+
+	// The module does not return from Stop until it is released
+	release := make(chan time.Time)
+	mock1.On("Stop").WaitUntil(release).Return(nil)
+	go StopCoordinatorModules(modules)
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"mock1 (*helpers.MockModule)"}, StoppingModules())
+	}, time.Second, 10*time.Millisecond, "Expected mock1 to be stopping")
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return len(StoppingModules()) == 0
+	}, time.Second, 10*time.Millisecond, "Expected no modules to be stopping")
+	mock1.AssertExpectations(t)
+}
+
+func TestRecordConfigError(t *testing.T) {
+	// Without CollectConfigErrors, the panic is left alone
+	assert.Panics(t, func() { RecordConfigError("test", func() { panic("bad config") }) }, "Expected panic")
+
+	configured := make([]string, 0)
+	errs := CollectConfigErrors(func() {
+		RecordConfigError("module.one", func() { panic("bad server list") })
+		RecordConfigError("module.two", func() { configured = append(configured, "two") })
+		RecordConfigError("module.three", func() { panic(errors.New("bad regex")) })
+	})
+	assert.Equal(t, []string{"module.one: bad server list", "module.three: bad regex"}, errs)
+	assert.Equal(t, []string{"two"}, configured, "Expected configuration to continue after an error")
+
+	// Collection stops when CollectConfigErrors returns
+	assert.Panics(t, func() { RecordConfigError("test", func() { panic("bad config") }) }, "Expected panic")
+	assert.Empty(t, CollectConfigErrors(func() {}), "Expected no errors")
+}
@@ -0,0 +1,33 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package helpers
+
+import (
+	"github.com/spf13/viper"
+)
+
+// ReportedConsumerGroup returns the name of the group that a kafka consumer module reports its own progress reading
+// the offsets topic under.
+func ReportedConsumerGroup(consumerName string) string {
+	return "burrow-" + consumerName
+}
+
+// GetReportedConsumerGroups returns the set of groups that the configured kafka consumer modules for the named cluster
+// report their own progress under. These are Burrow's own groups, and not groups that exist in the cluster.
+func GetReportedConsumerGroups(cluster string) map[string]bool {
+	groups := make(map[string]bool)
+	for name := range viper.GetStringMap("consumer") {
+		configRoot := "consumer." + name
+		if (viper.GetString(configRoot+".class-name") == "kafka") && (viper.GetString(configRoot+".cluster") == cluster) {
+			groups[ReportedConsumerGroup(name)] = true
+		}
+	}
+	return groups
+}
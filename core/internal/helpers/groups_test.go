@@ -0,0 +1,35 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportedConsumerGroup(t *testing.T) {
+	assert.Equal(t, "burrow-testconsumer", ReportedConsumerGroup("testconsumer"))
+}
+
+func TestGetReportedConsumerGroups(t *testing.T) {
+	viper.Reset()
+	viper.Set("consumer.kafka1.class-name", "kafka")
+	viper.Set("consumer.kafka1.cluster", "testcluster")
+	viper.Set("consumer.kafka2.class-name", "kafka")
+	viper.Set("consumer.kafka2.cluster", "othercluster")
+	viper.Set("consumer.zk1.class-name", "kafka_zk")
+	viper.Set("consumer.zk1.cluster", "testcluster")
+
+	groups := GetReportedConsumerGroups("testcluster")
+	assert.Equal(t, map[string]bool{"burrow-kafka1": true}, groups)
+	assert.Empty(t, GetReportedConsumerGroups("nocluster"), "Expected no groups for an unknown cluster")
+}
@@ -0,0 +1,123 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// A client-profile with an oauth-token-url authenticates with SASL/OAUTHBEARER, using tokens fetched from that URL
+// with the OAuth client credentials grant (oauth-client-id and oauth-client-secret, and oauth-scopes if the token
+// endpoint needs them). Sarama asks for a token each time it opens a connection to a broker, so the token is cached
+// and only fetched again once most of its lifetime has passed, so that a connection is never opened with a token that
+// is about to expire. Tokens that are returned without an expires_in are not cached.
+
+// oauthRefreshShare is how much of a token's lifetime can pass before it is fetched again
+const oauthRefreshShare = 0.8
+
+// oauthTokenProvider is a sarama.AccessTokenProvider for the client credentials grant. A single provider is shared by
+// every client created from the same sarama.Config, so it is safe for concurrent use.
+type oauthTokenProvider struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	httpClient   *http.Client
+
+	// now returns the current time, and is only replaced in tests
+	now func() time.Time
+
+	lock      sync.Mutex
+	token     *sarama.AccessToken
+	refreshAt time.Time
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func newOAuthTokenProvider(tokenURL, clientID, clientSecret string, scopes []string) *oauthTokenProvider {
+	return &oauthTokenProvider{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		now:          time.Now,
+	}
+}
+
+// Token returns the cached token, or fetches a new one if there is none or it is due to be refreshed. Callers wait for
+// a fetch that is already in progress, rather than each fetching a token.
+func (p *oauthTokenProvider) Token() (*sarama.AccessToken, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.now()
+	if (p.token != nil) && now.Before(p.refreshAt) {
+		return p.token, nil
+	}
+
+	response, err := p.fetchToken()
+	if err != nil {
+		return nil, err
+	}
+	token := &sarama.AccessToken{Token: response.AccessToken}
+	if response.ExpiresIn > 0 {
+		p.token = token
+		p.refreshAt = now.Add(time.Duration(float64(response.ExpiresIn)*oauthRefreshShare) * time.Second)
+	} else {
+		p.token = nil
+	}
+	return token, nil
+}
+
+// fetchToken requests a token from the token endpoint, authenticating with the client ID and secret as HTTP basic auth
+// (as RFC 6749 section 2.3.1 requires the endpoint to support)
+func (p *oauthTokenProvider) fetchToken() (*oauthTokenResponse, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(p.scopes) > 0 {
+		form.Set("scope", strings.Join(p.scopes, " "))
+	}
+	request, err := http.NewRequest(http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("oauth token request failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oauth token request failed: %v", response.Status)
+	}
+
+	tokenResponse := &oauthTokenResponse{}
+	if err := json.NewDecoder(response.Body).Decode(tokenResponse); err != nil {
+		return nil, fmt.Errorf("cannot decode oauth token response: %w", err)
+	}
+	if tokenResponse.AccessToken == "" {
+		return nil, fmt.Errorf("oauth token response has no access_token")
+	}
+	return tokenResponse, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// fixtureTokenServer returns a stub token endpoint that issues token-1, token-2, and so on, each expiring in expiresIn
+// seconds, and counts the requests for tokens
+func fixtureTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || (clientID != "burrow") || (clientSecret != "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if (r.FormValue("grant_type") != "client_credentials") || (r.FormValue("scope") != "kafka read") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		count := requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%v","token_type":"Bearer","expires_in":%v}`, count, expiresIn)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestOAuthTokenProvider_RefreshOnExpiry(t *testing.T) {
+	server, requests := fixtureTokenServer(t, 3600)
+	provider := newOAuthTokenProvider(server.URL, "burrow", "secret", []string{"kafka", "read"})
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+
+	token, err := provider.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token.Token)
+
+	// The token is reused until most of its lifetime has passed
+	now = now.Add(45 * time.Minute)
+	token, err = provider.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token.Token, "Expected the cached token")
+	assert.Equal(t, int32(1), requests.Load())
+
+	// And then refreshed before it expires
+	now = now.Add(5 * time.Minute)
+	token, err = provider.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-2", token.Token, "Expected a new token")
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestOAuthTokenProvider_NoExpiry(t *testing.T) {
+	server, requests := fixtureTokenServer(t, 0)
+	provider := newOAuthTokenProvider(server.URL, "burrow", "secret", []string{"kafka", "read"})
+
+	for i := 1; i <= 2; i++ {
+		token, err := provider.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("token-%v", i), token.Token, "Expected a token with no expiry to not be cached")
+	}
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestOAuthTokenProvider_Concurrent(t *testing.T) {
+	server, requests := fixtureTokenServer(t, 3600)
+	provider := newOAuthTokenProvider(server.URL, "burrow", "secret", []string{"kafka", "read"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := provider.Token()
+			assert.NoError(t, err)
+			assert.Equal(t, "token-1", token.Token)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), requests.Load(), "Expected the token to be fetched once")
+}
+
+func TestOAuthTokenProvider_Errors(t *testing.T) {
+	server, _ := fixtureTokenServer(t, 3600)
+	provider := newOAuthTokenProvider(server.URL, "burrow", "wrong", []string{"kafka", "read"})
+	_, err := provider.Token()
+	assert.Error(t, err, "Expected an error for bad credentials")
+
+	noToken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"expires_in":3600}`)
+	}))
+	defer noToken.Close()
+	provider = newOAuthTokenProvider(noToken.URL, "burrow", "secret", nil)
+	_, err = provider.Token()
+	assert.Error(t, err, "Expected an error for a response with no token")
+}
+
+func TestGetSaramaConfigFromClientProfile_OAuth(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.oauth-token-url", "https://auth.example.com/token")
+	viper.Set("client-profile.test.oauth-client-id", "burrow")
+	viper.Set("client-profile.test.oauth-client-secret", "secret")
+	viper.Set("client-profile.test.oauth-scopes", []string{"kafka"})
+
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	assert.True(t, saramaConfig.Net.SASL.Enable)
+	assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeOAuth), saramaConfig.Net.SASL.Mechanism)
+	provider, ok := saramaConfig.Net.SASL.TokenProvider.(*oauthTokenProvider)
+	assert.True(t, ok, "Expected an oauth token provider")
+	assert.Equal(t, "https://auth.example.com/token", provider.tokenURL)
+	assert.Equal(t, []string{"kafka"}, provider.scopes)
+}
+
+func TestGetSaramaConfigFromClientProfile_BadOAuth(t *testing.T) {
+	for name, settings := range map[string]map[string]interface{}{
+		"no client ID": {"client-profile.test.oauth-token-url": "https://auth.example.com/token"},
+		"bad URL":      {"client-profile.test.oauth-token-url": "not a url", "client-profile.test.oauth-client-id": "burrow"},
+		"with sasl": {
+			"client-profile.test.oauth-token-url": "https://auth.example.com/token",
+			"client-profile.test.oauth-client-id": "burrow",
+			"client-profile.test.sasl":            "default",
+			"sasl.default.mechanism":              "PLAIN",
+		},
+		"with iam": {
+			"client-profile.test.oauth-token-url": "https://auth.example.com/token",
+			"client-profile.test.oauth-client-id": "burrow",
+			"client-profile.test.tls":             "default",
+			"client-profile.test.iam":             "eks",
+			"iam.eks.region":                      "us-west-1",
+		},
+	} {
+		viper.Reset()
+		for key, value := range settings {
+			viper.Set(key, value)
+		}
+		assert.Panicsf(t, func() { GetSaramaConfigFromClientProfile("test") }, "Expected panic for %v", name)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"net/url"
 	"os"
 	"time"
 
@@ -61,6 +62,60 @@ func parseKafkaVersion(kafkaVersion string) sarama.KafkaVersion {
 	return version
 }
 
+// GetClientProfileName returns the name of the client-profile for the cluster or consumer module at the config root.
+// This is the client-profile set for the module, if there is one, and otherwise general.client-profile, so that the
+// modules that share a profile do not all need to name it. If neither is set, the name is empty, which is the default
+// profile.
+func GetClientProfileName(configRoot string) string {
+	if viper.IsSet(configRoot + ".client-profile") {
+		return viper.GetString(configRoot + ".client-profile")
+	}
+	return viper.GetString("general.client-profile")
+}
+
+// The client ID used when a client-profile does not set one
+const defaultClientID = "burrow-lagchecker"
+
+// GetClientIDFromClientProfile returns the client-id configured for the named client-profile, or the default client ID
+// if the profile does not set one. There is no viper default for the client-id, so that GetClusterClientID can tell
+// whether the profile set it. An invalid client-id will cause this func to panic.
+func GetClientIDFromClientProfile(profileName string) string {
+	configRoot := "client-profile." + profileName
+	if !viper.IsSet(configRoot + ".client-id") {
+		return defaultClientID
+	}
+
+	clientID := viper.GetString(configRoot + ".client-id")
+	if !ValidateClientID(clientID) {
+		panic("client-profile '" + profileName + "' has an invalid client-id '" + clientID + "'")
+	}
+	return clientID
+}
+
+// GetClusterClientID returns the client ID to use for the named cluster's own client, so that its requests can be told
+// apart from those of other clusters in the broker logs and quotas. This is the client-id set for the cluster, if there
+// is one, then the client-id set in its client-profile, and otherwise "burrow-<cluster>". An invalid client-id will
+// cause this func to panic.
+func GetClusterClientID(clusterName, profileName string) string {
+	configRoot := "cluster." + clusterName
+	if viper.IsSet(configRoot + ".client-id") {
+		clientID := viper.GetString(configRoot + ".client-id")
+		if !ValidateClientID(clientID) {
+			panic("Cluster '" + clusterName + "' has an invalid client-id '" + clientID + "'")
+		}
+		return clientID
+	}
+	if viper.IsSet("client-profile." + profileName + ".client-id") {
+		return GetClientIDFromClientProfile(profileName)
+	}
+
+	clientID := "burrow-" + clusterName
+	if !ValidateClientID(clientID) {
+		panic("Cluster '" + clusterName + "' needs a client-id, as burrow-<cluster> is not a valid client ID")
+	}
+	return clientID
+}
+
 // GetSaramaConfigFromClientProfile takes the name of a client-profile configuration entry and returns a sarama.Config
 // object that can be used to create a Sarama client with the specified configuration. This includes the Kafka version,
 // client ID, TLS, and SASL configs. If there is any error in the configuration, such as a bad TLS certificate file,
@@ -72,11 +127,10 @@ func GetSaramaConfigFromClientProfile(profileName string) *sarama.Config {
 		panic("unknown client-profile '" + profileName + "'")
 	}
 
-	viper.SetDefault(configRoot+".client-id", "burrow-lagchecker")
 	viper.SetDefault(configRoot+".kafka-version", sarama.V2_8_2_0)
 
 	saramaConfig := sarama.NewConfig()
-	saramaConfig.ClientID = viper.GetString(configRoot + ".client-id")
+	saramaConfig.ClientID = GetClientIDFromClientProfile(profileName)
 	saramaConfig.Version = parseKafkaVersion(viper.GetString(configRoot + ".kafka-version"))
 	saramaConfig.Consumer.Return.Errors = true
 
@@ -161,6 +215,26 @@ func GetSaramaConfigFromClientProfile(profileName string) *sarama.Config {
 		}
 	}
 
+	// OAUTHBEARER tokens can be fetched from a token endpoint with the client credentials grant. See oauth.go
+	if tokenURL := viper.GetString(configRoot + ".oauth-token-url"); tokenURL != "" {
+		if viper.IsSet(configRoot+".sasl") || (viper.GetString(configRoot+".iam") != "") {
+			panic("client-profile " + profileName + ": cannot use sasl or iam with oauth-token-url")
+		}
+		clientID := viper.GetString(configRoot + ".oauth-client-id")
+		if clientID == "" {
+			panic("client-profile " + profileName + ": oauth-client-id is required with oauth-token-url")
+		}
+		if _, err := url.ParseRequestURI(tokenURL); err != nil {
+			panic("client-profile " + profileName + ": bad oauth-token-url: " + err.Error())
+		}
+
+		saramaConfig.Net.SASL.Enable = true
+		saramaConfig.Net.SASL.Handshake = true
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeOAuth
+		saramaConfig.Net.SASL.TokenProvider = newOAuthTokenProvider(tokenURL, clientID,
+			viper.GetString(configRoot+".oauth-client-secret"), viper.GetStringSlice(configRoot+".oauth-scopes"))
+	}
+
 	// Timeout for the initial connection
 	if viper.IsSet(configRoot + ".dial-timeout") {
 		saramaConfig.Net.DialTimeout = time.Duration(viper.GetInt(configRoot+".dial-timeout")) * time.Second
@@ -171,9 +245,65 @@ func GetSaramaConfigFromClientProfile(profileName string) *sarama.Config {
 		saramaConfig.Net.ReadTimeout = time.Duration(viper.GetInt(configRoot+".read-timeout")) * time.Second
 	}
 
+	// The client keeps a connection open to each broker it talks to, and reuses it for every request. These control how
+	// many requests can be in flight on each connection, and the TCP keepalive that keeps idle connections (such as
+	// between offset refreshes) from being dropped by firewalls and load balancers
+	if viper.IsSet(configRoot + ".max-open-requests") {
+		maxOpenRequests := viper.GetInt(configRoot + ".max-open-requests")
+		if maxOpenRequests < 1 {
+			panic("client-profile " + profileName + ": max-open-requests must be at least 1")
+		}
+		saramaConfig.Net.MaxOpenRequests = maxOpenRequests
+	}
+	if viper.IsSet(configRoot + ".keepalive") {
+		keepalive := viper.GetInt(configRoot + ".keepalive")
+		if keepalive < 0 {
+			panic("client-profile " + profileName + ": keepalive must be zero or greater")
+		}
+		saramaConfig.Net.KeepAlive = time.Duration(keepalive) * time.Second
+	}
+
+	// Retries for the metadata requests that the client makes on its own, such as when looking up partition leaders.
+	// These happen inside the client before an error is ever returned to a module.
+	if viper.IsSet(configRoot + ".metadata-retry-max") {
+		metadataRetryMax := viper.GetInt(configRoot + ".metadata-retry-max")
+		if metadataRetryMax < 0 {
+			panic("client-profile " + profileName + ": metadata-retry-max must be zero or greater")
+		}
+		saramaConfig.Metadata.Retry.Max = metadataRetryMax
+	}
+	if viper.IsSet(configRoot + ".metadata-retry-backoff") {
+		metadataRetryBackoff := viper.GetInt(configRoot + ".metadata-retry-backoff")
+		if metadataRetryBackoff < 0 {
+			panic("client-profile " + profileName + ": metadata-retry-backoff must be zero or greater")
+		}
+		saramaConfig.Metadata.Retry.Backoff = time.Duration(metadataRetryBackoff) * time.Millisecond
+	}
+
+	// Retries for offset requests are not handled by sarama, so only validate them here. They are read by the modules
+	// that send offset requests with GetOffsetRetryFromClientProfile
+	viper.SetDefault(configRoot+".offset-retry-max", 0)
+	viper.SetDefault(configRoot+".offset-retry-backoff", 250)
+	if viper.GetInt(configRoot+".offset-retry-max") < 0 {
+		panic("client-profile " + profileName + ": offset-retry-max must be zero or greater")
+	}
+	if viper.GetInt(configRoot+".offset-retry-backoff") < 0 {
+		panic("client-profile " + profileName + ": offset-retry-backoff must be zero or greater")
+	}
+
 	return saramaConfig
 }
 
+// GetOffsetRetryFromClientProfile returns the number of times a failed offset request to a broker should be retried,
+// and how long to wait between attempts, for the named client-profile. The profile must already have been validated by
+// GetSaramaConfigFromClientProfile. These retries happen within a single offset refresh, before the failure is left
+// for the next refresh to recover from, so the total backoff should be kept well under the offset-refresh interval of
+// the cluster using the profile.
+func GetOffsetRetryFromClientProfile(profileName string) (int, time.Duration) {
+	configRoot := "client-profile." + profileName
+	return viper.GetInt(configRoot + ".offset-retry-max"), time.Duration(viper.GetInt(configRoot+".offset-retry-backoff")) * time.Millisecond
+}
+
 // SaramaClient is an internal interface to the sarama.Client. We use our own interface because while sarama.Client is
 // an interface, sarama.Broker is not. This makes it difficult to test code which uses the Broker objects. This
 // interface operates in the same way, with the addition of an interface function for creating consumers on the client.
@@ -184,6 +314,12 @@ type SaramaClient interface {
 	// Brokers returns the current set of active brokers as retrieved from cluster metadata.
 	Brokers() []SaramaBroker
 
+	// Broker returns the active broker with the given ID, as retrieved from cluster metadata.
+	Broker(brokerID int32) (SaramaBroker, error)
+
+	// LeastLoadedBroker returns the broker with the fewest pending requests, or nil if there are no brokers available.
+	LeastLoadedBroker() SaramaBroker
+
 	// Topics returns the set of available topics as retrieved from cluster metadata.
 	Topics() ([]string, error)
 
@@ -262,6 +398,25 @@ func (c *BurrowSaramaClient) Brokers() []SaramaBroker {
 	return shimBrokers
 }
 
+// Broker returns the active broker with the given ID, as retrieved from cluster metadata.
+func (c *BurrowSaramaClient) Broker(brokerID int32) (SaramaBroker, error) {
+	broker, err := c.Client.Broker(brokerID)
+	var shimBroker *BurrowSaramaBroker
+	if broker != nil {
+		shimBroker = &BurrowSaramaBroker{broker}
+	}
+	return shimBroker, err
+}
+
+// LeastLoadedBroker returns the broker with the fewest pending requests, or nil if there are no brokers available.
+func (c *BurrowSaramaClient) LeastLoadedBroker() SaramaBroker {
+	broker := c.Client.LeastLoadedBroker()
+	if broker == nil {
+		return nil
+	}
+	return &BurrowSaramaBroker{broker}
+}
+
 // Topics returns the set of available topics as retrieved from cluster metadata.
 func (c *BurrowSaramaClient) Topics() ([]string, error) {
 	return c.Client.Topics()
@@ -357,11 +512,30 @@ type SaramaBroker interface {
 	// ID returns the broker ID retrieved from Kafka's metadata, or -1 if that is not known.
 	ID() int32
 
+	// Rack returns the broker's rack as retrieved from Kafka's metadata, or an empty string if that is not known.
+	Rack() string
+
 	// Close closes the connection associated with the broker
 	Close() error
 
+	// Open tries to connect to the broker if it is not already connected, using the provided config
+	Open(conf *sarama.Config) error
+
 	// GetAvailableOffsets sends an OffsetRequest to the broker and returns the OffsetResponse that was received
 	GetAvailableOffsets(*sarama.OffsetRequest) (*sarama.OffsetResponse, error)
+
+	// GetMetadata sends a MetadataRequest to the broker and returns the MetadataResponse that was received
+	GetMetadata(*sarama.MetadataRequest) (*sarama.MetadataResponse, error)
+
+	// FetchOffset sends an OffsetFetchRequest to the broker and returns the OffsetFetchResponse that was received
+	FetchOffset(*sarama.OffsetFetchRequest) (*sarama.OffsetFetchResponse, error)
+
+	// DescribeConfigs sends a DescribeConfigsRequest to the broker and returns the DescribeConfigsResponse that was
+	// received
+	DescribeConfigs(*sarama.DescribeConfigsRequest) (*sarama.DescribeConfigsResponse, error)
+
+	// ApiVersions sends an ApiVersionsRequest to the broker and returns the ApiVersionsResponse that was received
+	ApiVersions(*sarama.ApiVersionsRequest) (*sarama.ApiVersionsResponse, error)
 }
 
 // BurrowSaramaBroker is an implementation of the SaramaBroker interface that is used with SaramaClient
@@ -374,16 +548,47 @@ func (b *BurrowSaramaBroker) ID() int32 {
 	return b.broker.ID()
 }
 
+// Rack returns the broker's rack as retrieved from Kafka's metadata, or an empty string if that is not known.
+func (b *BurrowSaramaBroker) Rack() string {
+	return b.broker.Rack()
+}
+
 // Close closes the connection associated with the broker
 func (b *BurrowSaramaBroker) Close() error {
 	return b.broker.Close()
 }
 
+// Open tries to connect to the broker if it is not already connected, using the provided config
+func (b *BurrowSaramaBroker) Open(conf *sarama.Config) error {
+	return b.broker.Open(conf)
+}
+
 // GetAvailableOffsets sends an OffsetRequest to the broker and returns the OffsetResponse that was received
 func (b *BurrowSaramaBroker) GetAvailableOffsets(request *sarama.OffsetRequest) (*sarama.OffsetResponse, error) {
 	return b.broker.GetAvailableOffsets(request)
 }
 
+// GetMetadata sends a MetadataRequest to the broker and returns the MetadataResponse that was received
+func (b *BurrowSaramaBroker) GetMetadata(request *sarama.MetadataRequest) (*sarama.MetadataResponse, error) {
+	return b.broker.GetMetadata(request)
+}
+
+// FetchOffset sends an OffsetFetchRequest to the broker and returns the OffsetFetchResponse that was received
+func (b *BurrowSaramaBroker) FetchOffset(request *sarama.OffsetFetchRequest) (*sarama.OffsetFetchResponse, error) {
+	return b.broker.FetchOffset(request)
+}
+
+// DescribeConfigs sends a DescribeConfigsRequest to the broker and returns the DescribeConfigsResponse that was
+// received
+func (b *BurrowSaramaBroker) DescribeConfigs(request *sarama.DescribeConfigsRequest) (*sarama.DescribeConfigsResponse, error) {
+	return b.broker.DescribeConfigs(request)
+}
+
+// ApiVersions sends an ApiVersionsRequest to the broker and returns the ApiVersionsResponse that was received
+func (b *BurrowSaramaBroker) ApiVersions(request *sarama.ApiVersionsRequest) (*sarama.ApiVersionsResponse, error) {
+	return b.broker.ApiVersions(request)
+}
+
 // ListConsumerGroups List the consumer groups available in the cluster.
 func (c *BurrowSaramaClient) ListConsumerGroups() (map[string]string, error) {
 	admin, err := sarama.NewClusterAdminFromClient(c.Client)
@@ -411,6 +616,19 @@ func (m *MockSaramaClient) Brokers() []SaramaBroker {
 	return args.Get(0).([]SaramaBroker)
 }
 
+// Broker mocks SaramaClient.Broker
+func (m *MockSaramaClient) Broker(brokerID int32) (SaramaBroker, error) {
+	args := m.Called(brokerID)
+	return args.Get(0).(SaramaBroker), args.Error(1)
+}
+
+// LeastLoadedBroker mocks SaramaClient.LeastLoadedBroker
+func (m *MockSaramaClient) LeastLoadedBroker() SaramaBroker {
+	args := m.Called()
+	broker, _ := args.Get(0).(SaramaBroker)
+	return broker
+}
+
 // Topics mocks SaramaClient.Topics
 func (m *MockSaramaClient) Topics() ([]string, error) {
 	args := m.Called()
@@ -511,18 +729,54 @@ func (m *MockSaramaBroker) ID() int32 {
 	return args.Get(0).(int32)
 }
 
+// Rack mocks SaramaBroker.Rack
+func (m *MockSaramaBroker) Rack() string {
+	args := m.Called()
+	return args.String(0)
+}
+
 // Close mocks SaramaBroker.Close
 func (m *MockSaramaBroker) Close() error {
 	args := m.Called()
 	return args.Error(0)
 }
 
+// Open mocks SaramaBroker.Open
+func (m *MockSaramaBroker) Open(conf *sarama.Config) error {
+	args := m.Called(conf)
+	return args.Error(0)
+}
+
 // GetAvailableOffsets mocks SaramaBroker.GetAvailableOffsets
 func (m *MockSaramaBroker) GetAvailableOffsets(request *sarama.OffsetRequest) (*sarama.OffsetResponse, error) {
 	args := m.Called(request)
 	return args.Get(0).(*sarama.OffsetResponse), args.Error(1)
 }
 
+// GetMetadata mocks SaramaBroker.GetMetadata
+func (m *MockSaramaBroker) GetMetadata(request *sarama.MetadataRequest) (*sarama.MetadataResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(*sarama.MetadataResponse), args.Error(1)
+}
+
+// FetchOffset mocks SaramaBroker.FetchOffset
+func (m *MockSaramaBroker) FetchOffset(request *sarama.OffsetFetchRequest) (*sarama.OffsetFetchResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(*sarama.OffsetFetchResponse), args.Error(1)
+}
+
+// DescribeConfigs mocks SaramaBroker.DescribeConfigs
+func (m *MockSaramaBroker) DescribeConfigs(request *sarama.DescribeConfigsRequest) (*sarama.DescribeConfigsResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(*sarama.DescribeConfigsResponse), args.Error(1)
+}
+
+// ApiVersions mocks SaramaBroker.ApiVersions
+func (m *MockSaramaBroker) ApiVersions(request *sarama.ApiVersionsRequest) (*sarama.ApiVersionsResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(*sarama.ApiVersionsResponse), args.Error(1)
+}
+
 // MockSaramaConsumer is a mock of sarama.Consumer. It is used in tests by multiple packages. It should never be used
 // in the normal code.
 type MockSaramaConsumer struct {
@@ -11,11 +11,13 @@ package helpers
 
 import (
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -93,3 +95,105 @@ func TestVersionMapping(t *testing.T) {
 	// or for other unknown/unsupported versions
 	shouldPanicForVersion(t, "foo")
 }
+
+func TestGetSaramaConfigFromClientProfile_Retries(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.metadata-retry-max", 5)
+	viper.Set("client-profile.test.metadata-retry-backoff", 500)
+	viper.Set("client-profile.test.offset-retry-max", 2)
+	viper.Set("client-profile.test.offset-retry-backoff", 100)
+
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	assert.Equal(t, 5, saramaConfig.Metadata.Retry.Max)
+	assert.Equal(t, 500*time.Millisecond, saramaConfig.Metadata.Retry.Backoff)
+
+	retryMax, retryBackoff := GetOffsetRetryFromClientProfile("test")
+	assert.Equal(t, 2, retryMax)
+	assert.Equal(t, 100*time.Millisecond, retryBackoff)
+}
+
+func TestGetSaramaConfigFromClientProfile_RetryDefaults(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.client-id", "testid")
+
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	assert.Equal(t, sarama.NewConfig().Metadata.Retry, saramaConfig.Metadata.Retry)
+
+	retryMax, retryBackoff := GetOffsetRetryFromClientProfile("test")
+	assert.Equal(t, 0, retryMax)
+	assert.Equal(t, 250*time.Millisecond, retryBackoff)
+}
+
+func TestGetSaramaConfigFromClientProfile_Net(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.max-open-requests", 10)
+	viper.Set("client-profile.test.keepalive", 30)
+	viper.Set("client-profile.test.dial-timeout", 5)
+
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	assert.Equal(t, 10, saramaConfig.Net.MaxOpenRequests)
+	assert.Equal(t, 30*time.Second, saramaConfig.Net.KeepAlive)
+	assert.Equal(t, 5*time.Second, saramaConfig.Net.DialTimeout)
+
+	for key, value := range map[string]int{"max-open-requests": 0, "keepalive": -1} {
+		viper.Reset()
+		viper.Set("client-profile.test."+key, value)
+		assert.Panicsf(t, func() { GetSaramaConfigFromClientProfile("test") }, "Expected panic for bad %v", key)
+	}
+}
+
+func TestGetSaramaConfigFromClientProfile_BadRetries(t *testing.T) {
+	for _, key := range []string{"metadata-retry-max", "metadata-retry-backoff", "offset-retry-max", "offset-retry-backoff"} {
+		viper.Reset()
+		viper.Set("client-profile.test."+key, -1)
+		assert.Panicsf(t, func() { GetSaramaConfigFromClientProfile("test") }, "Expected panic for negative %v", key)
+	}
+}
+
+func TestGetClientProfileName(t *testing.T) {
+	viper.Reset()
+	assert.Equal(t, "", GetClientProfileName("cluster.testcluster"), "Expected the default profile when none is set")
+
+	viper.Set("general.client-profile", "shared")
+	assert.Equal(t, "shared", GetClientProfileName("cluster.testcluster"), "Expected general.client-profile to be used")
+
+	viper.Set("cluster.testcluster.client-profile", "own")
+	assert.Equal(t, "own", GetClientProfileName("cluster.testcluster"), "Expected the cluster client-profile to be used")
+
+	// A module can set the default profile explicitly, even when there is a general.client-profile
+	viper.Set("consumer.testconsumer.client-profile", "")
+	assert.Equal(t, "", GetClientProfileName("consumer.testconsumer"), "Expected the consumer client-profile to be used")
+}
+
+func TestGetSaramaConfigFromClientProfile_UnknownDefault(t *testing.T) {
+	viper.Reset()
+	viper.Set("general.client-profile", "missing")
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile(GetClientProfileName("cluster.testcluster")) }, "Expected panic for unknown default client-profile")
+}
+
+func TestGetClusterClientID(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.noid.kafka-version", "0.10.2")
+	viper.Set("client-profile.withid.client-id", "profileid")
+
+	assert.Equal(t, "burrow-lagchecker", GetSaramaConfigFromClientProfile("noid").ClientID)
+	assert.Equal(t, "burrow-testcluster", GetClusterClientID("testcluster", "noid"), "Expected burrow-<cluster> when no client-id is set")
+	assert.Equal(t, "profileid", GetClusterClientID("testcluster", "withid"), "Expected the client-profile client-id to be used")
+
+	viper.Set("cluster.testcluster.client-id", "clusterid")
+	assert.Equal(t, "clusterid", GetClusterClientID("testcluster", "withid"), "Expected the cluster client-id to be used")
+}
+
+func TestGetClusterClientID_BadClientID(t *testing.T) {
+	viper.Reset()
+	viper.Set("cluster.testcluster.client-id", "bad client id")
+	assert.Panics(t, func() { GetClusterClientID("testcluster", "") }, "Expected panic for bad cluster client-id")
+
+	viper.Reset()
+	viper.Set("client-profile.test.client-id", "bad/client/id")
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "Expected panic for bad client-profile client-id")
+	assert.Panics(t, func() { GetClusterClientID("testcluster", "test") }, "Expected panic for bad client-profile client-id")
+
+	viper.Reset()
+	assert.Panics(t, func() { GetClusterClientID("bad cluster", "") }, "Expected panic for cluster name that is not a valid client ID")
+}
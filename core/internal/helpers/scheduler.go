@@ -0,0 +1,115 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package helpers
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// OffsetFetchScheduler is an implementation of protocol.OffsetScheduler. If it staggers the clusters, the clusters are
+// sorted by name, and each one's fetches are shifted by an equal share of its interval, so that N clusters with the
+// same interval fetch interval/N apart. Otherwise, every cluster fetches on its own interval from when it starts.
+type OffsetFetchScheduler struct {
+	stagger bool
+	lock    sync.RWMutex
+	entries map[string]*scheduleEntry
+}
+
+type scheduleEntry struct {
+	interval time.Duration
+	started  time.Time
+	delay    time.Duration
+}
+
+// NewOffsetFetchScheduler returns an OffsetFetchScheduler with no clusters registered
+func NewOffsetFetchScheduler(stagger bool) *OffsetFetchScheduler {
+	return &OffsetFetchScheduler{
+		stagger: stagger,
+		entries: make(map[string]*scheduleEntry),
+	}
+}
+
+// Register adds a cluster that fetches offsets every interval to the schedule. Registering a cluster again replaces
+// its interval.
+func (scheduler *OffsetFetchScheduler) Register(cluster string, interval time.Duration) {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	scheduler.entries[cluster] = &scheduleEntry{interval: interval}
+}
+
+// Start returns the delay before the cluster's first regular fetch, which is its interval plus its offset. A cluster
+// that has not been registered has no offset.
+func (scheduler *OffsetFetchScheduler) Start(cluster string) time.Duration {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+
+	entry, ok := scheduler.entries[cluster]
+	if !ok {
+		return 0
+	}
+	entry.started = time.Now()
+	entry.delay = entry.interval + scheduler.offset(cluster)
+	return entry.delay
+}
+
+// offset returns how far into its interval the cluster's fetches are shifted. The lock must be held by the caller.
+func (scheduler *OffsetFetchScheduler) offset(cluster string) time.Duration {
+	if !scheduler.stagger {
+		return 0
+	}
+	names := scheduler.sortedClusters()
+	index := sort.SearchStrings(names, cluster)
+	return scheduler.entries[cluster].interval * time.Duration(index) / time.Duration(len(names))
+}
+
+func (scheduler *OffsetFetchScheduler) sortedClusters() []string {
+	names := make([]string, 0, len(scheduler.entries))
+	for name := range scheduler.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Schedule returns the effective schedule for every registered cluster, sorted by cluster name
+func (scheduler *OffsetFetchScheduler) Schedule() []*protocol.ScheduledOffsetFetch {
+	scheduler.lock.RLock()
+	defer scheduler.lock.RUnlock()
+
+	now := time.Now()
+	names := scheduler.sortedClusters()
+	schedule := make([]*protocol.ScheduledOffsetFetch, len(names))
+	for i, name := range names {
+		entry := scheduler.entries[name]
+		schedule[i] = &protocol.ScheduledOffsetFetch{
+			Cluster:  name,
+			Interval: entry.interval.Seconds(),
+			Offset:   scheduler.offset(name).Seconds(),
+		}
+		if !entry.started.IsZero() {
+			schedule[i].NextFetch = nextFetch(entry, now).UnixNano() / int64(time.Millisecond)
+		}
+	}
+	return schedule
+}
+
+// nextFetch returns the first regular fetch of the cluster that is after now
+func nextFetch(entry *scheduleEntry, now time.Time) time.Time {
+	next := entry.started.Add(entry.delay)
+	if next.After(now) || (entry.interval <= 0) {
+		return next
+	}
+	missed := now.Sub(next)/entry.interval + 1
+	return next.Add(missed * entry.interval)
+}
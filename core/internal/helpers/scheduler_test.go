@@ -0,0 +1,74 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func TestOffsetFetchScheduler_ImplementsOffsetScheduler(t *testing.T) {
+	assert.Implements(t, (*protocol.OffsetScheduler)(nil), new(OffsetFetchScheduler))
+}
+
+func TestOffsetFetchScheduler_Start_Staggered(t *testing.T) {
+	scheduler := NewOffsetFetchScheduler(true)
+	scheduler.Register("clusterC", 60*time.Second)
+	scheduler.Register("clusterA", 60*time.Second)
+	scheduler.Register("clusterB", 60*time.Second)
+	scheduler.Register("clusterD", 10*time.Second)
+
+	// Each cluster is shifted by its share of its own interval, in name order
+	assert.Equal(t, 60*time.Second, scheduler.Start("clusterA"))
+	assert.Equal(t, 75*time.Second, scheduler.Start("clusterB"))
+	assert.Equal(t, 90*time.Second, scheduler.Start("clusterC"))
+	assert.Equal(t, 17500*time.Millisecond, scheduler.Start("clusterD"))
+	assert.Equal(t, time.Duration(0), scheduler.Start("unregistered"))
+}
+
+func TestOffsetFetchScheduler_Start_NotStaggered(t *testing.T) {
+	scheduler := NewOffsetFetchScheduler(false)
+	scheduler.Register("clusterA", 60*time.Second)
+	scheduler.Register("clusterB", 30*time.Second)
+
+	assert.Equal(t, 60*time.Second, scheduler.Start("clusterA"))
+	assert.Equal(t, 30*time.Second, scheduler.Start("clusterB"))
+}
+
+func TestOffsetFetchScheduler_Schedule(t *testing.T) {
+	scheduler := NewOffsetFetchScheduler(true)
+	scheduler.Register("clusterB", 60*time.Second)
+	scheduler.Register("clusterA", 60*time.Second)
+
+	before := time.Now()
+	scheduler.Start("clusterB")
+
+	schedule := scheduler.Schedule()
+	assert.Len(t, schedule, 2)
+	assert.Equal(t, &protocol.ScheduledOffsetFetch{Cluster: "clusterA", Interval: 60, Offset: 0}, schedule[0])
+	assert.Equal(t, "clusterB", schedule[1].Cluster)
+	assert.Equal(t, float64(30), schedule[1].Offset)
+	assert.GreaterOrEqual(t, schedule[1].NextFetch, before.Add(90*time.Second).UnixNano()/int64(time.Millisecond))
+	assert.LessOrEqual(t, schedule[1].NextFetch, time.Now().Add(90*time.Second).UnixNano()/int64(time.Millisecond))
+}
+
+func TestNextFetch(t *testing.T) {
+	started := time.Unix(1000, 0)
+	entry := &scheduleEntry{interval: 60 * time.Second, started: started, delay: 90 * time.Second}
+
+	// Before the first fetch, and after some fetches have happened
+	assert.Equal(t, started.Add(90*time.Second), nextFetch(entry, started.Add(10*time.Second)))
+	assert.Equal(t, started.Add(150*time.Second), nextFetch(entry, started.Add(90*time.Second)))
+	assert.Equal(t, started.Add(270*time.Second), nextFetch(entry, started.Add(215*time.Second)))
+}
@@ -26,3 +26,45 @@ func TimeoutSendStorageRequest(storageChannel chan *protocol.StorageRequest, req
 		return false
 	}
 }
+
+// FetchClusterList returns the names of the clusters that the storage module has, sorted. This is shared by the HTTP
+// and gRPC servers.
+func FetchClusterList(app *protocol.ApplicationContext) []string {
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusters,
+		Reply:       make(chan interface{}),
+	}
+	app.StorageChannel <- request
+	return (<-request.Reply).([]string)
+}
+
+// FetchConsumerDetail returns the offsets that the storage module has for a group, and false if the cluster or the
+// group is not known. This is shared by the HTTP and gRPC servers.
+func FetchConsumerDetail(app *protocol.ApplicationContext, cluster, group string) (protocol.ConsumerTopics, bool) {
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     cluster,
+		Group:       group,
+		Reply:       make(chan interface{}),
+	}
+	app.StorageChannel <- request
+	response := <-request.Reply
+	if response == nil {
+		return nil, false
+	}
+	return response.(protocol.ConsumerTopics), true
+}
+
+// FetchGroupStatus returns the status of a group from the evaluator, which has the status StatusNotFound if the
+// cluster or the group is not known. If showAll is false, only the partitions that are not OK are included. This is
+// shared by the HTTP and gRPC servers.
+func FetchGroupStatus(app *protocol.ApplicationContext, cluster, group string, showAll bool) *protocol.ConsumerGroupStatus {
+	request := &protocol.EvaluatorRequest{
+		Cluster: cluster,
+		Group:   group,
+		ShowAll: showAll,
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+	}
+	app.EvaluatorChannel <- request
+	return <-request.Reply
+}
@@ -46,3 +46,47 @@ func TestTimeoutSendStorageRequest_Timeout(t *testing.T) {
 	default:
 	}
 }
+
+func TestFetchClusterList(t *testing.T) {
+	app := &protocol.ApplicationContext{StorageChannel: make(chan *protocol.StorageRequest)}
+	go func() {
+		request := <-app.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchClusters, request.RequestType, "Expected request of type StorageFetchClusters, not %v", request.RequestType)
+		request.Reply <- []string{"testcluster"}
+	}()
+
+	assert.Equal(t, []string{"testcluster"}, FetchClusterList(app))
+}
+
+func TestFetchConsumerDetail(t *testing.T) {
+	app := &protocol.ApplicationContext{StorageChannel: make(chan *protocol.StorageRequest)}
+	go func() {
+		request := <-app.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchConsumer, request.RequestType, "Expected request of type StorageFetchConsumer, not %v", request.RequestType)
+		assert.Equalf(t, "testgroup", request.Group, "Expected request Group to be testgroup, not %v", request.Group)
+		request.Reply <- protocol.ConsumerTopics{"testtopic": {{CurrentLag: 10}}}
+
+		// Second request is for an unknown group
+		request = <-app.StorageChannel
+		close(request.Reply)
+	}()
+
+	topics, ok := FetchConsumerDetail(app, "testcluster", "testgroup")
+	assert.True(t, ok, "Expected the group to be found")
+	assert.Equalf(t, uint64(10), topics["testtopic"][0].CurrentLag, "Expected CurrentLag to be 10, not %v", topics["testtopic"][0].CurrentLag)
+
+	_, ok = FetchConsumerDetail(app, "testcluster", "nogroup")
+	assert.False(t, ok, "Expected the group to not be found")
+}
+
+func TestFetchGroupStatus(t *testing.T) {
+	app := &protocol.ApplicationContext{EvaluatorChannel: make(chan *protocol.EvaluatorRequest)}
+	go func() {
+		request := <-app.EvaluatorChannel
+		assert.True(t, request.ShowAll, "Expected request ShowAll to be true")
+		request.Reply <- &protocol.ConsumerGroupStatus{Cluster: request.Cluster, Group: request.Group, Status: protocol.StatusOK}
+	}()
+
+	status := FetchGroupStatus(app, "testcluster", "testgroup", true)
+	assert.Equalf(t, protocol.StatusOK, status.Status, "Expected status to be OK, not %v", status.Status)
+}
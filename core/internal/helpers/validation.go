@@ -79,6 +79,12 @@ func ValidateTopic(topic string) bool {
 	return matches
 }
 
+// ValidateClientID returns true if the provided string is a valid Kafka client ID. Sarama accepts the same characters as
+// for a topic name, so this is the same as ValidateTopic.
+func ValidateClientID(clientID string) bool {
+	return ValidateTopic(clientID)
+}
+
 // ValidateFilename returns true if the provided string is a sane-looking filename (not just a valid filename, which
 // could be almost anything). Right now, this is defined to be the same thing as ValidateTopic.
 func ValidateFilename(filename string) bool {
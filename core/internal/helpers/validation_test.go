@@ -103,6 +103,13 @@ func TestValidateTopic(t *testing.T) {
 	}
 }
 
+func TestValidateClientID(t *testing.T) {
+	for i, testSet := range testTopics {
+		result := ValidateClientID(testSet.TestValue)
+		assert.Equalf(t, testSet.Result, result, "Test %v - Expected '%v' to return %v, not %v", i, testSet.TestValue, testSet.Result, result)
+	}
+}
+
 var testEmails = []TestSet{
 	{"ok@example.com", true},
 	{"need@domain", false},
@@ -14,6 +14,8 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/spf13/viper"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
 )
 
 func (hc *Coordinator) configMain(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -43,9 +45,11 @@ func (hc *Coordinator) configMain(w http.ResponseWriter, r *http.Request, _ http
 	for name := range servers {
 		configRoot := "httpserver." + name
 		configHTTPServer[name] = httpResponseConfigHTTPServer{
-			Address: viper.GetString(configRoot + ".address"),
-			Timeout: viper.GetInt(configRoot + ".timeout"),
-			TLS:     viper.GetString(configRoot + ".tls"),
+			Address:        viper.GetString(configRoot + ".address"),
+			Timeout:        viper.GetInt(configRoot + ".timeout"),
+			TLS:            viper.GetString(configRoot + ".tls"),
+			RouteGroups:    viper.GetStringSlice(configRoot + ".route-groups"),
+			DisabledRoutes: viper.GetStringSlice(configRoot + ".disabled-routes"),
 		}
 	}
 
@@ -164,7 +168,7 @@ func (hc *Coordinator) configConsumerDetail(w http.ResponseWriter, r *http.Reque
 				GroupAllowlist:   viper.GetString(configRoot + ".group-allowlist"),
 				ZookeeperPath:    viper.GetString(configRoot + ".zookeeper-path"),
 				ZookeeperTimeout: int32(viper.GetInt64(configRoot + ".zookeeper-timeout")),
-				ClientProfile:    getClientProfile(viper.GetString(configRoot + ".client-profile")),
+				ClientProfile:    getClientProfile(helpers.GetClientProfileName(configRoot)),
 				OffsetsTopic:     viper.GetString(configRoot + ".offsets-topic"),
 				StartLatest:      viper.GetBool(configRoot + ".start-latest"),
 			},
@@ -14,6 +14,7 @@
 package httpserver
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
@@ -86,6 +87,46 @@ func (hc *Coordinator) Configure() {
 		server.ReadHeaderTimeout = time.Duration(timeout) * time.Second
 		server.WriteTimeout = time.Duration(timeout) * time.Second
 		server.IdleTimeout = time.Duration(timeout) * time.Second
+
+		// Long-poll requests must respond before the listener times out the write. If not set, the long-poll timeout
+		// is 30 seconds, or half of the timeout if that is shorter
+		longPollTimeout := timeout / 2
+		if viper.IsSet(configRoot + ".long-poll-timeout") {
+			longPollTimeout = viper.GetInt(configRoot + ".long-poll-timeout")
+			if (longPollTimeout <= 0) || (longPollTimeout >= timeout) {
+				panic("HTTP server " + name + " long-poll-timeout must be greater than zero and less than timeout")
+			}
+		} else if longPollTimeout > 30 {
+			longPollTimeout = 30
+		}
+
+		// Restrict the routes served by this listener, if configured. The filter is carried in the base context of
+		// every request so that the (shared) router can check it before calling the handler. The long-poll timeout is
+		// carried the same way
+		filter := newRouteFilter(viper.GetStringSlice(configRoot+".route-groups"), viper.GetStringSlice(configRoot+".disabled-routes"))
+		server.BaseContext = func(net.Listener) context.Context {
+			ctx := context.WithValue(context.Background(), routeFilterKey{}, filter)
+			return context.WithValue(ctx, longPollTimeoutKey{}, time.Duration(longPollTimeout)*time.Second)
+		}
+
+		// Limit the rate of requests to this listener, if configured, to protect the evaluator from runaway clients
+		server.Handler = newRateLimitHandler(hc, name, configRoot, hc.router)
+
+		// Serve all routes under a base path, for a listener that is mounted under a path by a reverse proxy
+		basePath := strings.TrimSuffix(viper.GetString(configRoot+".base-path"), "/")
+		if basePath != "" {
+			if !strings.HasPrefix(basePath, "/") {
+				panic("HTTP server " + name + " base-path must start with /")
+			}
+			server.Handler = &basePathHandler{basePath: basePath, handler: server.Handler}
+		}
+
+		// Add static headers to every response from this listener (including errors), such as for security policies
+		headers := viper.GetStringMapString(configRoot + ".headers")
+		if len(headers) > 0 {
+			server.Handler = &headerHandler{headers: headers, handler: server.Handler}
+		}
+
 		keyFile := ""
 		certFile := ""
 		if viper.IsSet(configRoot + ".tls") {
@@ -119,45 +160,84 @@ func (hc *Coordinator) Configure() {
 		hc.theKey[name] = keyFile
 	}
 
+	// The label rules for the Prometheus metrics are shared by all listeners, as the metrics are
+	metricLabels = newMetricLabelRules("metrics")
+
 	// Configure URL routes here
 
 	// This is a catchall for undefined URLs
 	hc.router.NotFound = &defaultHandler{}
 
 	// This is a healthcheck and readiness URLs. Please don't change it
-	hc.router.GET("/burrow/admin", hc.handleAdmin)
-	hc.router.GET("/burrow/admin/ready", hc.handleReady)
+	hc.handle(routeGroupHealth, http.MethodGet, "/burrow/admin", hc.handleAdmin)
+	hc.handle(routeGroupHealth, http.MethodGet, "/burrow/admin/ready", hc.handleReady)
 
-	hc.router.Handler(http.MethodGet, "/metrics", hc.handlePrometheusMetrics())
+	promHandler := hc.handlePrometheusMetrics()
+	hc.handle(routeGroupMetrics, http.MethodGet, "/metrics", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		promHandler.ServeHTTP(w, r)
+	})
 
 	// All valid paths go here
-	hc.router.GET("/v3/kafka", hc.handleClusterList)
-	hc.router.GET("/v3/kafka/:cluster", hc.handleClusterDetail)
-	hc.router.GET("/v3/kafka/:cluster/topic", hc.handleTopicList)
-	hc.router.GET("/v3/kafka/:cluster/topic/:topic", hc.handleTopicDetail)
-	hc.router.GET("/v3/kafka/:cluster/topic/:topic/consumers", hc.handleTopicConsumerList)
-	hc.router.GET("/v3/kafka/:cluster/consumer", hc.handleConsumerList)
-	hc.router.GET("/v3/kafka/:cluster/consumer/:consumer", hc.handleConsumerDetail)
-	hc.router.GET("/v3/kafka/:cluster/consumer/:consumer/status", hc.handleConsumerStatus)
-	hc.router.GET("/v3/kafka/:cluster/consumer/:consumer/lag", hc.handleConsumerStatusComplete)
-
-	hc.router.GET("/v3/config", hc.configMain)
-	hc.router.GET("/v3/config/storage", hc.configStorageList)
-	hc.router.GET("/v3/config/storage/:name", hc.configStorageDetail)
-	hc.router.GET("/v3/config/evaluator", hc.configEvaluatorList)
-	hc.router.GET("/v3/config/evaluator/:name", hc.configEvaluatorDetail)
-	hc.router.GET("/v3/config/cluster", hc.configClusterList)
-	hc.router.GET("/v3/config/cluster/:cluster", hc.handleClusterDetail)
-	hc.router.GET("/v3/config/consumer", hc.configConsumerList)
-	hc.router.GET("/v3/config/consumer/:name", hc.configConsumerDetail)
-	hc.router.GET("/v3/config/notifier", hc.configNotifierList)
-	hc.router.GET("/v3/config/notifier/:name", hc.configNotifierDetail)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka", hc.handleClusterList)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster", hc.handleClusterDetail)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/topic", hc.handleTopicList)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/topic/:topic", hc.handleTopicDetail)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/topic/:topic/consumers", hc.handleTopicConsumerList)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/topic/:topic/lag", hc.handleTopicLag)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/topic/:topic/offsets", hc.handleTopicOffsetsAtTime)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/consumer", hc.handleConsumerList)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/consumer/:consumer", hc.handleConsumerDetail)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/consumer/:consumer/status", hc.handleConsumerStatus)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/consumer/:consumer/status/wait", hc.handleConsumerStatusWait)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/consumer/:consumer/status/history", hc.handleConsumerStatusHistory)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/consumer/:consumer/lag", hc.handleConsumerStatusComplete)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/consumer/:consumer/baseline", hc.handleConsumerBaseline)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/consumer/:consumer/time-lag", hc.handleConsumerTimeLag)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/consumer/:consumer/fetch-status", hc.handleConsumerFetchStatus)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/top", hc.handleConsumerTop)
+	hc.handle(routeGroupRead, http.MethodPost, "/v3/kafka/:cluster/consumers/status", hc.handleConsumerStatusBulk)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/stale-partitions", hc.handleStalePartitions)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/compacted-topics", hc.handleCompactedTopics)
+	hc.handle(routeGroupRead, http.MethodGet, "/v3/kafka/:cluster/status/stream", hc.handleStatusStream)
+
+	hc.handle(routeGroupConfig, http.MethodGet, "/v3/config", hc.configMain)
+	hc.handle(routeGroupConfig, http.MethodGet, "/v3/config/storage", hc.configStorageList)
+	hc.handle(routeGroupConfig, http.MethodGet, "/v3/config/storage/:name", hc.configStorageDetail)
+	hc.handle(routeGroupConfig, http.MethodGet, "/v3/config/evaluator", hc.configEvaluatorList)
+	hc.handle(routeGroupConfig, http.MethodGet, "/v3/config/evaluator/:name", hc.configEvaluatorDetail)
+	hc.handle(routeGroupConfig, http.MethodGet, "/v3/config/cluster", hc.configClusterList)
+	hc.handle(routeGroupConfig, http.MethodGet, "/v3/config/cluster/:cluster", hc.handleClusterDetail)
+	hc.handle(routeGroupConfig, http.MethodGet, "/v3/config/consumer", hc.configConsumerList)
+	hc.handle(routeGroupConfig, http.MethodGet, "/v3/config/consumer/:name", hc.configConsumerDetail)
+	hc.handle(routeGroupConfig, http.MethodGet, "/v3/config/notifier", hc.configNotifierList)
+	hc.handle(routeGroupConfig, http.MethodGet, "/v3/config/notifier/:name", hc.configNotifierDetail)
 
 	// TODO: This should really have authentication protecting it
-	hc.router.DELETE("/v3/kafka/:cluster/consumer/:consumer", hc.handleConsumerDelete)
-	hc.router.DELETE("/v3/kafka/:cluster/consumer/:consumer/topic/:topic", hc.handleConsumerDelete)
-	hc.router.GET("/v3/admin/loglevel", hc.getLogLevel)
-	hc.router.POST("/v3/admin/loglevel", hc.setLogLevel)
+	hc.handle(routeGroupDelete, http.MethodDelete, "/v3/kafka/:cluster/consumer/:consumer", hc.handleConsumerDelete)
+	hc.handle(routeGroupDelete, http.MethodDelete, "/v3/kafka/:cluster/consumer/:consumer/topic/:topic", hc.handleConsumerDelete)
+	hc.handle(routeGroupAdmin, http.MethodGet, "/v3/admin/loglevel", hc.getLogLevel)
+	hc.handle(routeGroupAdmin, http.MethodPost, "/v3/admin/loglevel", hc.setLogLevel)
+	hc.handle(routeGroupAdmin, http.MethodGet, "/v3/admin/stats", hc.getAdminStats)
+	hc.handle(routeGroupAdmin, http.MethodGet, "/v3/admin/offset-schedule", hc.getOffsetSchedule)
+	hc.handle(routeGroupAdmin, http.MethodPost, "/v3/kafka/:cluster/broker/:broker/refresh-offsets", hc.handleBrokerRefreshOffsets)
+	hc.handle(routeGroupAdmin, http.MethodGet, "/v3/kafka/:cluster/api-versions", hc.handleBrokerAPIVersions)
+	hc.handle(routeGroupAdmin, http.MethodPost, "/v3/kafka/:cluster/consumer/:consumer/refresh", hc.handleConsumerRefresh)
+	hc.handle(routeGroupAdmin, http.MethodGet, "/v3/admin/mute/:cluster", hc.handleMuteList)
+	hc.handle(routeGroupAdmin, http.MethodPost, "/v3/admin/mute/:cluster/:consumer", hc.handleMuteSet)
+	hc.handle(routeGroupAdmin, http.MethodDelete, "/v3/admin/mute/:cluster/:consumer", hc.handleMuteDelete)
+}
+
+// handle registers a route with the router as a member of the named route group. The handler is wrapped so that, if
+// the listener that received the request has disabled the group (or the route itself), the request is refused with a
+// 403 instead of being served.
+func (hc *Coordinator) handle(group, method, path string, handle httprouter.Handle) {
+	hc.router.Handle(method, path, func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		if filter, ok := r.Context().Value(routeFilterKey{}).(*routeFilter); ok && !filter.allows(group, method, path) {
+			hc.writeErrorResponse(w, r, http.StatusForbidden, "endpoint disabled")
+			return
+		}
+		handle(w, r, params)
+	})
 }
 
 // Start is responsible for starting the listener on each configured address. If any listener fails to start, the error
@@ -359,3 +439,37 @@ func (hc *Coordinator) setLogLevel(w http.ResponseWriter, r *http.Request, _ htt
 		Request: requestInfo,
 	})
 }
+
+// basePathHandler removes the base path from the request URL before passing the request to the router. Requests for
+// URLs that are not under the base path get the same response as an unknown URL.
+type basePathHandler struct {
+	basePath string
+	handler  http.Handler
+}
+
+func (bh *basePathHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, bh.basePath)
+	if (len(path) == len(r.URL.Path)) || !strings.HasPrefix(path, "/") {
+		(&defaultHandler{}).ServeHTTP(w, r)
+		return
+	}
+
+	stripped := r.Clone(r.Context())
+	stripped.URL.Path = path
+	stripped.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, bh.basePath)
+	bh.handler.ServeHTTP(w, stripped)
+}
+
+// headerHandler sets the configured headers on the response before passing the request on. Handlers may still replace
+// any of them, such as Content-Type.
+type headerHandler struct {
+	headers map[string]string
+	handler http.Handler
+}
+
+func (hh *headerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for name, value := range hh.headers {
+		w.Header().Set(name, value)
+	}
+	hh.handler.ServeHTTP(w, r)
+}
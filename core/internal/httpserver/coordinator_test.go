@@ -34,6 +34,8 @@ func fixtureConfiguredCoordinator() *Coordinator {
 			LogLevel:         &logLevel,
 			StorageChannel:   make(chan *protocol.StorageRequest),
 			EvaluatorChannel: make(chan *protocol.EvaluatorRequest),
+			ClusterChannel:   make(chan *protocol.ClusterRequest),
+			ConsumerChannel:  make(chan *protocol.ConsumerRequest),
 			AppReady:         false,
 		},
 	}
@@ -154,3 +156,145 @@ func TestHttpServer_DefaultHandler(t *testing.T) {
 
 	assert.True(t, resp.Error, "Expected response Error to be true")
 }
+
+func TestHttpServer_RouteGroups(t *testing.T) {
+	logLevel := zap.NewAtomicLevelAt(zap.InfoLevel)
+	coordinator := Coordinator{
+		Log: zap.NewNop(),
+		App: &protocol.ApplicationContext{
+			Logger:   zap.NewNop(),
+			LogLevel: &logLevel,
+		},
+	}
+
+	viper.Reset()
+	viper.Set("httpserver.readonly.address", ":0")
+	viper.Set("httpserver.readonly.route-groups", []string{"health", "read"})
+	viper.Set("httpserver.readonly.disabled-routes", []string{"GET /burrow/admin/ready", "POST /v3/kafka/:cluster/consumers/status"})
+	coordinator.Configure()
+
+	server := coordinator.servers["readonly"]
+	assert.NotNil(t, server.BaseContext, "Expected listener to have a base context set")
+
+	testCases := []struct {
+		method string
+		uri    string
+		code   int
+	}{
+		{"GET", "/burrow/admin", http.StatusOK},
+		{"GET", "/burrow/admin/ready", http.StatusForbidden},
+		{"GET", "/v3/admin/loglevel", http.StatusForbidden},
+		{"POST", "/v3/admin/loglevel", http.StatusForbidden},
+		{"DELETE", "/v3/kafka/testcluster/consumer/testgroup", http.StatusForbidden},
+		{"POST", "/v3/kafka/testcluster/consumers/status", http.StatusForbidden},
+		{"GET", "/v3/config", http.StatusForbidden},
+		{"GET", "/metrics", http.StatusForbidden},
+	}
+
+	for _, testCase := range testCases {
+		req, err := http.NewRequest(testCase.method, testCase.uri, strings.NewReader("{\"level\": \"debug\"}"))
+		assert.NoError(t, err, "Expected request setup to return no error")
+		req = req.WithContext(server.BaseContext(nil))
+
+		rr := httptest.NewRecorder()
+		coordinator.router.ServeHTTP(rr, req)
+		assert.Equalf(t, testCase.code, rr.Code, "Expected response code for %v %v to be %v, not %v", testCase.method, testCase.uri, testCase.code, rr.Code)
+	}
+	assert.Equalf(t, zap.InfoLevel, coordinator.App.LogLevel.Level(), "Expected log level to be unchanged, not %v", coordinator.App.LogLevel.Level().String())
+}
+
+func TestHttpServer_RouteGroups_Unknown(t *testing.T) {
+	coordinator := Coordinator{
+		Log: zap.NewNop(),
+		App: &protocol.ApplicationContext{
+			Logger: zap.NewNop(),
+		},
+	}
+
+	viper.Reset()
+	viper.Set("httpserver.default.address", ":0")
+	viper.Set("httpserver.default.route-groups", []string{"nosuchgroup"})
+	assert.Panics(t, coordinator.Configure, "The code did not panic")
+}
+
+func TestHttpServer_BasePath(t *testing.T) {
+	logLevel := zap.NewAtomicLevelAt(zap.InfoLevel)
+	coordinator := &Coordinator{
+		Log: zap.NewNop(),
+		App: &protocol.ApplicationContext{
+			Logger:   zap.NewNop(),
+			LogLevel: &logLevel,
+		},
+	}
+
+	viper.Reset()
+	viper.Set("httpserver.proxied.address", ":0")
+	viper.Set("httpserver.proxied.base-path", "/monitoring/burrow/")
+	viper.Set("httpserver.direct.address", ":0")
+	coordinator.Configure()
+
+	testCases := []struct {
+		server string
+		uri    string
+		code   int
+	}{
+		{"proxied", "/monitoring/burrow/burrow/admin", http.StatusOK},
+		{"proxied", "/monitoring/burrow/v3/admin/loglevel", http.StatusOK},
+		{"proxied", "/burrow/admin", http.StatusNotFound},
+		{"proxied", "/monitoring/burrowadmin", http.StatusNotFound},
+		{"direct", "/burrow/admin", http.StatusOK},
+		{"direct", "/monitoring/burrow/burrow/admin", http.StatusNotFound},
+	}
+
+	for _, testCase := range testCases {
+		req, err := http.NewRequest("GET", testCase.uri, http.NoBody)
+		assert.NoError(t, err, "Expected request setup to return no error")
+
+		rr := httptest.NewRecorder()
+		coordinator.servers[testCase.server].Handler.ServeHTTP(rr, req)
+		assert.Equalf(t, testCase.code, rr.Code, "Expected response code for %v on %v to be %v, not %v", testCase.uri, testCase.server, testCase.code, rr.Code)
+	}
+}
+
+func TestHttpServer_BasePath_Bad(t *testing.T) {
+	coordinator := &Coordinator{
+		Log: zap.NewNop(),
+		App: &protocol.ApplicationContext{
+			Logger: zap.NewNop(),
+		},
+	}
+
+	viper.Reset()
+	viper.Set("httpserver.default.address", ":0")
+	viper.Set("httpserver.default.base-path", "monitoring/burrow")
+	assert.Panics(t, coordinator.Configure, "The code did not panic")
+}
+
+func TestHttpServer_Headers(t *testing.T) {
+	logLevel := zap.NewAtomicLevelAt(zap.InfoLevel)
+	coordinator := &Coordinator{
+		Log: zap.NewNop(),
+		App: &protocol.ApplicationContext{
+			Logger:   zap.NewNop(),
+			LogLevel: &logLevel,
+		},
+	}
+
+	viper.Reset()
+	viper.Set("httpserver.default.address", ":0")
+	viper.Set("httpserver.default.headers", map[string]string{
+		"Strict-Transport-Security": "max-age=31536000",
+		"X-Content-Type-Options":    "nosniff",
+	})
+	coordinator.Configure()
+
+	for _, uri := range []string{"/burrow/admin", "/no/such/url"} {
+		req, err := http.NewRequest("GET", uri, http.NoBody)
+		assert.NoError(t, err, "Expected request setup to return no error")
+
+		rr := httptest.NewRecorder()
+		coordinator.servers["default"].Handler.ServeHTTP(rr, req)
+		assert.Equalf(t, "max-age=31536000", rr.Header().Get("Strict-Transport-Security"), "Expected HSTS header on %v", uri)
+		assert.Equalf(t, "nosniff", rr.Header().Get("X-Content-Type-Options"), "Expected X-Content-Type-Options header on %v", uri)
+	}
+}
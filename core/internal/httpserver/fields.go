@@ -0,0 +1,84 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// The names of the fields in the JSON form of a protocol.ConsumerGroupStatus, which a client can select with the
+// "fields" query parameter
+var consumerStatusFields = jsonFieldNames(reflect.TypeOf(protocol.ConsumerGroupStatus{}))
+
+func jsonFieldNames(structType reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < structType.NumField(); i++ {
+		name, _, _ := strings.Cut(structType.Field(i).Tag.Get("json"), ",")
+		if (name != "") && (name != "-") {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// parseStatusFields parses the "fields" query parameter, which is a comma-separated list of consumer status fields.
+// An empty parameter returns nil, which selects every field. If any field name is unknown, it is returned as the
+// second value.
+func parseStatusFields(param string) (fields []string, unknown string) {
+	if param == "" {
+		return nil, ""
+	}
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !consumerStatusFields[field] {
+			return nil, field
+		}
+		fields = append(fields, field)
+	}
+	return fields, ""
+}
+
+// selectStatusFields returns the JSON form of the status with only the given fields. Fields that are omitted when
+// empty (such as members) are left out if they are empty, as they would be in the full status.
+func selectStatusFields(status *protocol.ConsumerGroupStatus, fields []string) (map[string]json.RawMessage, error) {
+	jsonBytes, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	var allFields map[string]json.RawMessage
+	if err := json.Unmarshal(jsonBytes, &allFields); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := allFields[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
+
+func statusFieldList() string {
+	names := make([]string, 0, len(consumerStatusFields))
+	for name := range consumerStatusFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -10,29 +10,30 @@
 package httpserver
 
 import (
+	"encoding/json"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/spf13/viper"
 
+	"github.com/linkedin/Burrow/core/internal/helpers"
 	"github.com/linkedin/Burrow/core/protocol"
 )
 
 func (hc *Coordinator) handleClusterList(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	// Fetch cluster list from the storage module
-	request := &protocol.StorageRequest{
-		RequestType: protocol.StorageFetchClusters,
-		Reply:       make(chan interface{}),
-	}
-	hc.App.StorageChannel <- request
-	response := <-request.Reply
+	clusters := helpers.FetchClusterList(hc.App)
 
 	requestInfo := makeRequestInfo(r)
 	hc.writeResponse(w, r, http.StatusOK, httpResponseClusterList{
-		Error:    false,
-		Message:  "cluster list returned",
-		Clusters: response.([]string),
-		Request:  requestInfo,
+		Error:          false,
+		Message:        "cluster list returned",
+		Clusters:       clusters,
+		FailedClusters: getFailedClusters(),
+		Request:        requestInfo,
 	})
 }
 
@@ -68,7 +69,7 @@ func getClientProfile(name string) httpResponseClientProfile {
 	configRoot := "client-profile." + name
 	return httpResponseClientProfile{
 		Name:         name,
-		ClientID:     viper.GetString(configRoot + ".client-id"),
+		ClientID:     helpers.GetClientIDFromClientProfile(name),
 		KafkaVersion: viper.GetString(configRoot + ".kafka-version"),
 		TLS:          getTLSProfile(viper.GetString(configRoot + ".tls")),
 		SASL:         getSASLProfile(viper.GetString(configRoot + ".sasl")),
@@ -81,16 +82,27 @@ func (hc *Coordinator) handleClusterDetail(w http.ResponseWriter, r *http.Reques
 	if !viper.IsSet(configRoot) {
 		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster module not found")
 	} else {
+		// A cluster with server sets shows the servers of the set it is connected to
+		servers := viper.GetStringSlice(configRoot + ".servers")
+		serverSet, setServers := getClusterServerSet(params.ByName("cluster"))
+		if serverSet != "" {
+			servers = setServers
+		}
+
 		requestInfo := makeRequestInfo(r)
 		hc.writeResponse(w, r, http.StatusOK, httpResponseConfigModuleDetail{
 			Error:   false,
 			Message: "cluster module detail returned",
 			Module: httpResponseConfigModuleCluster{
 				ClassName:     viper.GetString(configRoot + ".class-name"),
-				Servers:       viper.GetStringSlice(configRoot + ".servers"),
+				Servers:       servers,
+				ServerSet:     serverSet,
 				TopicRefresh:  viper.GetInt64(configRoot + ".topic-refresh"),
 				OffsetRefresh: viper.GetInt64(configRoot + ".offset-refresh"),
-				ClientProfile: getClientProfile(viper.GetString(configRoot + ".client-profile")),
+				ClientProfile: getClientProfile(helpers.GetClientProfileName(configRoot)),
+				KafkaVersion:  getClusterKafkaVersion(params.ByName("cluster")),
+				MetadataState: getClusterMetadataState(params.ByName("cluster")),
+				Failures:      getClusterFailures(params.ByName("cluster")),
 			},
 			Request: requestInfo,
 		})
@@ -134,12 +146,24 @@ func (hc *Coordinator) handleTopicDetail(w http.ResponseWriter, r *http.Request,
 	if response == nil {
 		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster or topic not found")
 	} else {
+		// The oldest offsets are only stored if the cluster fetches them (fetch-oldest-offsets), and are left out of the
+		// response otherwise. Each partition's end offset less its oldest offset is roughly the messages on disk
+		oldestRequest := &protocol.StorageRequest{
+			RequestType: protocol.StorageFetchTopicOldestOffsets,
+			Cluster:     params.ByName("cluster"),
+			Topic:       params.ByName("topic"),
+			Reply:       make(chan interface{}),
+		}
+		hc.App.StorageChannel <- oldestRequest
+		oldestOffsets, _ := (<-oldestRequest.Reply).([]int64)
+
 		requestInfo := makeRequestInfo(r)
 		hc.writeResponse(w, r, http.StatusOK, httpResponseTopicDetail{
-			Error:   false,
-			Message: "topic offsets returned",
-			Offsets: response.([]int64),
-			Request: requestInfo,
+			Error:         false,
+			Message:       "topic offsets returned",
+			Offsets:       response.([]int64),
+			OldestOffsets: oldestOffsets,
+			Request:       requestInfo,
 		})
 	}
 }
@@ -168,6 +192,144 @@ func (hc *Coordinator) handleTopicConsumerList(w http.ResponseWriter, r *http.Re
 	}
 }
 
+// handleTopicLag returns the lag of every consumer group that consumes a topic, counting only the topic's partitions,
+// and the total across all of them. This is the topic owner's view of the group statuses, so the groups are sorted
+// with the most lag first. Groups that are muted are included, as the lag is still real.
+func (hc *Coordinator) handleTopicLag(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	cluster := params.ByName("cluster")
+	topic := params.ByName("topic")
+
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumersForTopic,
+		Cluster:     cluster,
+		Topic:       topic,
+		Reply:       make(chan interface{}),
+	}
+	hc.App.StorageChannel <- request
+	response := <-request.Reply
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+	groups := response.([]string)
+
+	// Every partition is needed to add up the lag, not only the ones that are not OK
+	replyChannel := make(chan *protocol.ConsumerGroupStatus, len(groups))
+	for _, group := range groups {
+		hc.App.EvaluatorChannel <- &protocol.EvaluatorRequest{
+			Cluster: cluster,
+			Group:   group,
+			ShowAll: true,
+			Reply:   replyChannel,
+		}
+	}
+
+	consumers := make([]*httpResponseTopicConsumerLag, 0, len(groups))
+	var totalLag uint64
+	for range groups {
+		status := <-replyChannel
+		if status.Status == protocol.StatusNotFound {
+			continue
+		}
+		consumer := topicConsumerLag(status, topic)
+		totalLag += consumer.Lag
+		consumers = append(consumers, consumer)
+	}
+	sort.Slice(consumers, func(i, j int) bool {
+		if consumers[i].Lag != consumers[j].Lag {
+			return consumers[i].Lag > consumers[j].Lag
+		}
+		return consumers[i].Group < consumers[j].Group
+	})
+
+	hc.writeResponse(w, r, http.StatusOK, httpResponseTopicLag{
+		Error:     false,
+		Message:   "topic lag returned",
+		Topic:     topic,
+		TotalLag:  totalLag,
+		Consumers: consumers,
+		Request:   makeRequestInfo(r),
+	})
+}
+
+// handleTopicOffsetsAtTime has the cluster module look up, for each partition of a topic, the earliest offset whose
+// timestamp is at or after the timestamp parameter (in milliseconds), for reconstructing what a consumer's lag was at
+// that time
+func (hc *Coordinator) handleTopicOffsetsAtTime(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	timestamp, err := strconv.ParseInt(r.URL.Query().Get("timestamp"), 10, 64)
+	if (err != nil) || (timestamp < 0) {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "timestamp must be a time in milliseconds")
+		return
+	}
+
+	request := &protocol.ClusterRequest{
+		RequestType: protocol.ClusterFetchTopicOffsetsAtTime,
+		Cluster:     params.ByName("cluster"),
+		Topic:       params.ByName("topic"),
+		Timestamp:   timestamp,
+		Reply:       make(chan interface{}),
+	}
+	select {
+	case hc.App.ClusterChannel <- request:
+	case <-r.Context().Done():
+		// The client has gone away (or Burrow is stopping the cluster modules)
+		return
+	}
+	response := <-request.Reply
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+	offsets := response.(*protocol.ClusterTopicOffsets)
+	switch {
+	case offsets.Unsupported:
+		hc.writeErrorResponse(w, r, http.StatusNotImplemented, offsets.Error)
+		return
+	case offsets.Offsets == nil:
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "topic not found")
+		return
+	}
+
+	responseCode := http.StatusOK
+	message := "topic offsets at timestamp returned"
+	if offsets.Error != "" {
+		responseCode = http.StatusInternalServerError
+		message = "failed to fetch offsets for every partition"
+	}
+	hc.writeResponse(w, r, responseCode, httpResponseTopicOffsets{
+		Error:   offsets.Error != "",
+		Message: message,
+		Offsets: offsets,
+		Request: makeRequestInfo(r),
+	})
+}
+
+// topicConsumerLag sums up the lag of a group for only the partitions of one topic. The topic status is the worst
+// status of those partitions, which can be better than the group's status if the group is behind on another topic. The
+// max time lag is -1, as it is for the group, unless the time lag is known for at least one of those partitions.
+func topicConsumerLag(status *protocol.ConsumerGroupStatus, topic string) *httpResponseTopicConsumerLag {
+	consumer := &httpResponseTopicConsumerLag{
+		Group:       status.Group,
+		Status:      status.Status,
+		TopicStatus: protocol.StatusOK,
+		MaxTimeLag:  -1,
+	}
+	for _, partition := range status.Partitions {
+		if partition.Topic != topic {
+			continue
+		}
+		consumer.Partitions++
+		consumer.Lag += partition.CurrentLag
+		if partition.TimeLag > consumer.MaxTimeLag {
+			consumer.MaxTimeLag = partition.TimeLag
+		}
+		if partition.Status > consumer.TopicStatus {
+			consumer.TopicStatus = partition.Status
+		}
+	}
+	return consumer
+}
+
 func (hc *Coordinator) handleConsumerList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	// Fetch consumer list from the storage module
 	request := &protocol.StorageRequest{
@@ -193,8 +355,27 @@ func (hc *Coordinator) handleConsumerList(w http.ResponseWriter, r *http.Request
 
 func (hc *Coordinator) handleConsumerDetail(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	// Fetch consumer data from the storage module
+	topics, ok := helpers.FetchConsumerDetail(hc.App, params.ByName("cluster"), params.ByName("consumer"))
+
+	if !ok {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster or consumer not found")
+	} else {
+		requestInfo := makeRequestInfo(r)
+		hc.writeResponse(w, r, http.StatusOK, httpResponseConsumerDetail{
+			Error:   false,
+			Message: "consumer detail returned",
+			Topics:  topics,
+			Request: requestInfo,
+		})
+	}
+}
+
+// handleConsumerBaseline returns the lag baseline that has been learned for the group, if the evaluator is configured
+// to learn baselines. Otherwise, the baseline is empty.
+func (hc *Coordinator) handleConsumerBaseline(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// Fetch the baseline from the storage module
 	request := &protocol.StorageRequest{
-		RequestType: protocol.StorageFetchConsumer,
+		RequestType: protocol.StorageFetchGroupBaseline,
 		Cluster:     params.ByName("cluster"),
 		Group:       params.ByName("consumer"),
 		Reply:       make(chan interface{}),
@@ -206,65 +387,428 @@ func (hc *Coordinator) handleConsumerDetail(w http.ResponseWriter, r *http.Reque
 		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster or consumer not found")
 	} else {
 		requestInfo := makeRequestInfo(r)
-		hc.writeResponse(w, r, http.StatusOK, httpResponseConsumerDetail{
-			Error:   false,
-			Message: "consumer detail returned",
-			Topics:  response.(protocol.ConsumerTopics),
-			Request: requestInfo,
+		hc.writeResponse(w, r, http.StatusOK, httpResponseConsumerBaseline{
+			Error:    false,
+			Message:  "consumer baseline returned",
+			Baseline: response.(*protocol.LagBaseline),
+			Request:  requestInfo,
 		})
 	}
 }
 
-func (hc *Coordinator) handleConsumerStatus(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	// Fetch consumer data from the storage module
+// handleConsumerTimeLag returns how far behind the group is in time (in milliseconds) for each of its partitions, in
+// order by topic and partition. The time lag is only known for clusters that have offset-lookback set, and is -1
+// otherwise.
+func (hc *Coordinator) handleConsumerTimeLag(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	request := &protocol.EvaluatorRequest{
 		Cluster: params.ByName("cluster"),
 		Group:   params.ByName("consumer"),
-		ShowAll: false,
+		ShowAll: true,
 		Reply:   make(chan *protocol.ConsumerGroupStatus),
 	}
 	hc.App.EvaluatorChannel <- request
 	response := <-request.Reply
 
+	if response.Status == protocol.StatusNotFound {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster or consumer not found")
+		return
+	}
+
+	partitions := make([]*httpResponsePartitionTime, 0, len(response.Partitions))
+	for _, partition := range response.Partitions {
+		partitions = append(partitions, &httpResponsePartitionTime{
+			Topic:      partition.Topic,
+			Partition:  partition.Partition,
+			CurrentLag: partition.CurrentLag,
+			TimeLag:    partition.TimeLag,
+		})
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].Topic != partitions[j].Topic {
+			return partitions[i].Topic < partitions[j].Topic
+		}
+		return partitions[i].Partition < partitions[j].Partition
+	})
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseConsumerTimeLag{
+		Error:      false,
+		Message:    "consumer time lag returned",
+		MaxTimeLag: response.MaxTimeLag,
+		Partitions: partitions,
+		Request:    requestInfo,
+	})
+}
+
+// handleConsumerStatusHistory returns the status changes recorded for the group between the "from" and "to" query
+// parameters, which are either RFC 3339 times or milliseconds since the epoch. If not given, the range starts with the
+// oldest change kept and ends now. The changes are only recorded if the evaluator is configured to record them.
+func (hc *Coordinator) handleConsumerStatusHistory(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	from, ok := parseHistoryTime(r.URL.Query().Get("from"))
+	if !ok {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "from must be an RFC 3339 time or milliseconds since the epoch")
+		return
+	}
+	to, ok := parseHistoryTime(r.URL.Query().Get("to"))
+	if !ok {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "to must be an RFC 3339 time or milliseconds since the epoch")
+		return
+	}
+	if (to != 0) && (to < from) {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "to must not be before from")
+		return
+	}
+
+	// Fetch the history from the storage module
+	request := &protocol.StorageRequest{
+		RequestType:  protocol.StorageFetchGroupStatusHistory,
+		Cluster:      params.ByName("cluster"),
+		Group:        params.ByName("consumer"),
+		Timestamp:    from,
+		EndTimestamp: to,
+		Reply:        make(chan interface{}),
+	}
+	hc.App.StorageChannel <- request
+	response := <-request.Reply
+
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+	} else {
+		requestInfo := makeRequestInfo(r)
+		hc.writeResponse(w, r, http.StatusOK, httpResponseConsumerStatusHistory{
+			Error:   false,
+			Message: "consumer status history returned",
+			History: response.([]protocol.StatusTransition),
+			Request: requestInfo,
+		})
+	}
+}
+
+// parseHistoryTime parses a time given as either RFC 3339 or milliseconds since the epoch, and returns it in
+// milliseconds. An empty value is zero.
+func parseHistoryTime(value string) (int64, bool) {
+	if value == "" {
+		return 0, true
+	}
+	if timestamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return timestamp, timestamp >= 0
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, false
+	}
+	return parsed.UnixNano() / int64(time.Millisecond), true
+}
+
+func (hc *Coordinator) handleConsumerStatus(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	hc.writeConsumerStatus(w, r, params, false)
+}
+
+func (hc *Coordinator) handleConsumerStatusComplete(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	hc.writeConsumerStatus(w, r, params, true)
+}
+
+// writeConsumerStatus evaluates the group and writes its status. If the "fields" query parameter is given, only those
+// fields of the status are returned (such as "status,totallag"), so that clients that do not need the partitions do
+// not have to receive them. Partitions that are OK with less lag than the minimum (see requestMinLag) are left out.
+func (hc *Coordinator) writeConsumerStatus(w http.ResponseWriter, r *http.Request, params httprouter.Params, showAll bool) {
+	fields, unknown := parseStatusFields(r.URL.Query().Get("fields"))
+	if unknown != "" {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "unknown field '"+unknown+"' (must be one of "+statusFieldList()+")")
+		return
+	}
+	minLag, ok := requestMinLag(r)
+	if !ok {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "min-lag must be a non-negative integer")
+		return
+	}
+
+	// Fetch consumer status from the evaluator
+	response := filterPartitionsByLag(helpers.FetchGroupStatus(hc.App, params.ByName("cluster"), params.ByName("consumer"), showAll), minLag)
+
 	responseCode := http.StatusOK
 	if response.Status == protocol.StatusNotFound {
 		responseCode = http.StatusNotFound
 	}
 
 	requestInfo := makeRequestInfo(r)
-	hc.writeResponse(w, r, responseCode, httpResponseConsumerStatus{
+	if fields == nil {
+		hc.writeResponse(w, r, responseCode, httpResponseConsumerStatus{
+			Error:   false,
+			Message: "consumer status returned",
+			Status:  *response,
+			Request: requestInfo,
+		})
+		return
+	}
+
+	status, err := selectStatusFields(response, fields)
+	if err != nil {
+		hc.writeErrorResponse(w, r, http.StatusInternalServerError, "could not encode consumer status")
+		return
+	}
+	hc.writeResponse(w, r, responseCode, httpResponseConsumerStatusFields{
 		Error:   false,
 		Message: "consumer status returned",
-		Status:  *response,
+		Status:  status,
 		Request: requestInfo,
 	})
 }
 
-func (hc *Coordinator) handleConsumerStatusComplete(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	// Fetch consumer data from the storage module
-	request := &protocol.EvaluatorRequest{
-		Cluster: params.ByName("cluster"),
-		Group:   params.ByName("consumer"),
-		ShowAll: true,
-		Reply:   make(chan *protocol.ConsumerGroupStatus),
+// handleConsumerTop evaluates every group in the cluster and returns the worst ones. The "sort" query parameter is
+// either "lag" (the default), to order groups by total lag, or "status", to order them by status with total lag as the
+// tiebreaker. The "count" query parameter is the number of groups to return, and defaults to 10. Muted groups are not
+// included, and neither are groups that are OK with less total lag than the minimum (see requestMinLag).
+func (hc *Coordinator) handleConsumerTop(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	sortKey := r.URL.Query().Get("sort")
+	if sortKey == "" {
+		sortKey = "lag"
 	}
-	hc.App.EvaluatorChannel <- request
+	if (sortKey != "lag") && (sortKey != "status") {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "sort must be lag or status")
+		return
+	}
+	count := 10
+	if countParam := r.URL.Query().Get("count"); countParam != "" {
+		var err error
+		count, err = strconv.Atoi(countParam)
+		if (err != nil) || (count <= 0) {
+			hc.writeErrorResponse(w, r, http.StatusBadRequest, "count must be a positive integer")
+			return
+		}
+	}
+	minLag, ok := requestMinLag(r)
+	if !ok {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "min-lag must be a non-negative integer")
+		return
+	}
+
+	// Fetch consumer list from the storage module
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumers,
+		Cluster:     params.ByName("cluster"),
+		Reply:       make(chan interface{}),
+	}
+	hc.App.StorageChannel <- request
 	response := <-request.Reply
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+	groups := response.([]string)
 
-	responseCode := http.StatusOK
-	if response.Status == protocol.StatusNotFound {
-		responseCode = http.StatusNotFound
+	// Evaluate all the groups at once, using a single reply channel as the notifier does
+	replyChannel := make(chan *protocol.ConsumerGroupStatus, len(groups))
+	for _, group := range groups {
+		hc.App.EvaluatorChannel <- &protocol.EvaluatorRequest{
+			Cluster: params.ByName("cluster"),
+			Group:   group,
+			ShowAll: false,
+			Reply:   replyChannel,
+		}
+	}
+	statuses := make([]*protocol.ConsumerGroupStatus, 0, len(groups))
+	for range groups {
+		status := <-replyChannel
+		if (status.Status == protocol.StatusNotFound) || (status.Status == protocol.StatusMuted) {
+			continue
+		}
+		if (status.Status == protocol.StatusOK) && (status.TotalLag < minLag) {
+			continue
+		}
+		statuses = append(statuses, filterPartitionsByLag(status, minLag))
+	}
+
+	sortConsumerStatuses(statuses, sortKey)
+	if len(statuses) > count {
+		statuses = statuses[:count]
+	}
+
+	hc.writeResponse(w, r, http.StatusOK, httpResponseConsumerTop{
+		Error:     false,
+		Message:   "consumer top returned",
+		Consumers: statuses,
+		Request:   makeRequestInfo(r),
+	})
+}
+
+// handleConsumerStatusBulk evaluates a list of groups in the cluster, given as a JSON array of group names in the body,
+// and returns their statuses in the order given, so that a client does not need a request for each group. Groups that
+// are not found are listed separately rather than failing the request. Partitions that are OK with less lag than the
+// minimum (see requestMinLag) are left out, as for a single group.
+func (hc *Coordinator) handleConsumerStatusBulk(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if !viper.IsSet("cluster." + params.ByName("cluster")) {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+	minLag, ok := requestMinLag(r)
+	if !ok {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "min-lag must be a non-negative integer")
+		return
+	}
+
+	var groups []string
+	err := json.NewDecoder(r.Body).Decode(&groups)
+	if err != nil {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "could not decode message body (must be a list of groups)")
+		return
+	}
+	r.Body.Close()
+	if len(groups) == 0 {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "no groups given")
+		return
+	}
+
+	// Evaluate all the groups at once, using a single reply channel as for the top groups. A group that is listed more
+	// than once is only evaluated once
+	requested := make(map[string]bool, len(groups))
+	replyChannel := make(chan *protocol.ConsumerGroupStatus, len(groups))
+	for _, group := range groups {
+		if requested[group] {
+			continue
+		}
+		requested[group] = true
+		hc.App.EvaluatorChannel <- &protocol.EvaluatorRequest{
+			Cluster: params.ByName("cluster"),
+			Group:   group,
+			ShowAll: false,
+			Reply:   replyChannel,
+		}
+	}
+	results := make(map[string]*protocol.ConsumerGroupStatus, len(requested))
+	for range requested {
+		status := <-replyChannel
+		results[status.Group] = status
+	}
+
+	statuses := make([]*protocol.ConsumerGroupStatus, 0, len(results))
+	notFound := make([]string, 0)
+	for _, group := range groups {
+		status, ok := results[group]
+		if !ok {
+			// Already added
+			continue
+		}
+		delete(results, group)
+		if status.Status == protocol.StatusNotFound {
+			notFound = append(notFound, group)
+			continue
+		}
+		statuses = append(statuses, filterPartitionsByLag(status, minLag))
+	}
+
+	hc.writeResponse(w, r, http.StatusOK, httpResponseConsumerStatusBulk{
+		Error:     false,
+		Message:   "consumer statuses returned",
+		Consumers: statuses,
+		NotFound:  notFound,
+		Request:   makeRequestInfo(r),
+	})
+}
+
+// sortConsumerStatuses orders the group statuses worst first, either by total lag or by status, with the other as the
+// tiebreaker. Groups that are otherwise equal are ordered by name so the result is stable between calls.
+func sortConsumerStatuses(statuses []*protocol.ConsumerGroupStatus, sortKey string) {
+	sort.Slice(statuses, func(i, j int) bool {
+		a, b := statuses[i], statuses[j]
+		if sortKey == "status" {
+			if a.Status != b.Status {
+				return a.Status > b.Status
+			}
+			if a.TotalLag != b.TotalLag {
+				return a.TotalLag > b.TotalLag
+			}
+		} else {
+			if a.TotalLag != b.TotalLag {
+				return a.TotalLag > b.TotalLag
+			}
+			if a.Status != b.Status {
+				return a.Status > b.Status
+			}
+		}
+		return a.Group < b.Group
+	})
+}
+
+// staleEndOffsetAge returns the age, in seconds, after which the end offset for a partition in the cluster is stale. This
+// is the stale-offset-intervals config for the cluster multiplied by its offset-refresh interval.
+func staleEndOffsetAge(cluster string) int64 {
+	configRoot := "cluster." + cluster
+	return viper.GetInt64(configRoot+".offset-refresh") * viper.GetInt64(configRoot+".stale-offset-intervals")
+}
+
+// handleStalePartitions returns the partitions in the cluster whose end offsets have not been fetched within the stale
+// age for the cluster. The lag for groups consuming these partitions is calculated against an old end offset, and so
+// can be much lower than the real lag.
+// handleCompactedTopics lists the topics in the cluster that are compacted. This is only known for clusters that have
+// detect-compacted-topics set, and is empty for the rest.
+func (hc *Coordinator) handleCompactedTopics(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchCompactedTopics,
+		Cluster:     params.ByName("cluster"),
+		Reply:       make(chan interface{}),
+	}
+	hc.App.StorageChannel <- request
+	response := <-request.Reply
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
 	}
 
 	requestInfo := makeRequestInfo(r)
-	hc.writeResponse(w, r, responseCode, httpResponseConsumerStatus{
+	hc.writeResponse(w, r, http.StatusOK, httpResponseTopicList{
 		Error:   false,
-		Message: "consumer status returned",
-		Status:  *response,
+		Message: "compacted topic list returned",
+		Topics:  response.([]string),
 		Request: requestInfo,
 	})
 }
 
+func (hc *Coordinator) handleStalePartitions(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// Fetch the end offsets from the storage module
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchEndOffsets,
+		Cluster:     params.ByName("cluster"),
+		Reply:       make(chan interface{}),
+	}
+	hc.App.StorageChannel <- request
+	response := <-request.Reply
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+
+	staleAge := staleEndOffsetAge(params.ByName("cluster"))
+	timeNow := time.Now().UnixNano() / int64(time.Millisecond)
+	partitions := make([]*httpResponseStalePartition, 0)
+	for _, endOffset := range response.([]*protocol.EndOffset) {
+		if age := (timeNow - endOffset.Timestamp) / 1000; age > staleAge {
+			partitions = append(partitions, &httpResponseStalePartition{
+				Topic:     endOffset.Topic,
+				Partition: endOffset.Partition,
+				Offset:    endOffset.Offset,
+				Timestamp: endOffset.Timestamp,
+				Age:       age,
+			})
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].Topic != partitions[j].Topic {
+			return partitions[i].Topic < partitions[j].Topic
+		}
+		return partitions[i].Partition < partitions[j].Partition
+	})
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseStalePartitions{
+		Error:      false,
+		Message:    "stale partitions returned",
+		StaleAfter: staleAge,
+		Partitions: partitions,
+		Request:    requestInfo,
+	})
+}
+
 func (hc *Coordinator) handleConsumerDelete(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	// Delete consumer from the storage module
 	request := &protocol.StorageRequest{
@@ -282,3 +826,157 @@ func (hc *Coordinator) handleConsumerDelete(w http.ResponseWriter, r *http.Reque
 		Request: requestInfo,
 	})
 }
+
+// handleConsumerRefresh has a consumer module for the cluster fetch the committed offsets for one group from Kafka,
+// right away, and store them, for troubleshooting that group
+func (hc *Coordinator) handleConsumerRefresh(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	request := &protocol.ConsumerRequest{
+		RequestType: protocol.ConsumerRefreshGroup,
+		Cluster:     params.ByName("cluster"),
+		Group:       params.ByName("consumer"),
+		Reply:       make(chan interface{}),
+	}
+	select {
+	case hc.App.ConsumerChannel <- request:
+	case <-r.Context().Done():
+		// The client has gone away (or Burrow is stopping the consumer modules)
+		return
+	}
+	response := <-request.Reply
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "no consumer module for the cluster can refresh offsets")
+		return
+	}
+	offsets := response.(*protocol.ConsumerGroupOffsets)
+
+	responseCode := http.StatusOK
+	message := "consumer offsets refreshed"
+	if offsets.Error != "" {
+		responseCode = http.StatusInternalServerError
+		message = "failed to fetch consumer offsets"
+	}
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, responseCode, httpResponseGroupOffsets{
+		Error:   offsets.Error != "",
+		Message: message,
+		Offsets: offsets,
+		Request: requestInfo,
+	})
+}
+
+// handleConsumerFetchStatus returns the result of the consumer module's last fetch of one group's offsets, with the
+// times of the last successful and failed fetches, for troubleshooting a group whose offsets have stopped updating
+func (hc *Coordinator) handleConsumerFetchStatus(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	request := &protocol.ConsumerRequest{
+		RequestType: protocol.ConsumerFetchGroupStatus,
+		Cluster:     params.ByName("cluster"),
+		Group:       params.ByName("consumer"),
+		Reply:       make(chan interface{}),
+	}
+	select {
+	case hc.App.ConsumerChannel <- request:
+	case <-r.Context().Done():
+		// The client has gone away (or Burrow is stopping the consumer modules)
+		return
+	}
+	response := <-request.Reply
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "no consumer module for the cluster records fetches")
+		return
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseGroupFetchStatus{
+		Error:       false,
+		Message:     "consumer fetch status returned",
+		FetchStatus: response.(*protocol.ConsumerGroupFetchStatus),
+		Request:     requestInfo,
+	})
+}
+
+// handleBrokerRefreshOffsets has the cluster module fetch the end offsets for only the partitions that one broker
+// leads, right away, for troubleshooting that broker
+func (hc *Coordinator) handleBrokerRefreshOffsets(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	brokerID, err := strconv.ParseInt(params.ByName("broker"), 10, 32)
+	if err != nil {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "broker must be a broker ID")
+		return
+	}
+
+	request := &protocol.ClusterRequest{
+		RequestType: protocol.ClusterRefreshBrokerOffsets,
+		Cluster:     params.ByName("cluster"),
+		Broker:      int32(brokerID),
+		Reply:       make(chan interface{}),
+	}
+	select {
+	case hc.App.ClusterChannel <- request:
+	case <-r.Context().Done():
+		// The client has gone away (or Burrow is stopping the cluster modules)
+		return
+	}
+	response := <-request.Reply
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+	offsets := response.(*protocol.ClusterBrokerOffsets)
+	if offsets.Partitions == 0 {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "broker is not the leader for any partitions")
+		return
+	}
+
+	responseCode := http.StatusOK
+	message := "broker offsets refreshed"
+	if offsets.Error != "" {
+		responseCode = http.StatusInternalServerError
+		message = "failed to fetch offsets from broker"
+	}
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, responseCode, httpResponseBrokerOffsets{
+		Error:   offsets.Error != "",
+		Message: message,
+		Offsets: offsets,
+		Request: requestInfo,
+	})
+}
+
+// handleBrokerAPIVersions returns the API versions that each broker in the cluster supports, for checking that every
+// broker has been upgraded before changing the Kafka version that Burrow uses. The versions are cached by the cluster
+// module, and are fetched from the brokers again if the refresh parameter is "true"
+func (hc *Coordinator) handleBrokerAPIVersions(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	refresh := false
+	if refreshParam := r.URL.Query().Get("refresh"); refreshParam != "" {
+		var err error
+		if refresh, err = strconv.ParseBool(refreshParam); err != nil {
+			hc.writeErrorResponse(w, r, http.StatusBadRequest, "refresh must be true or false")
+			return
+		}
+	}
+
+	request := &protocol.ClusterRequest{
+		RequestType: protocol.ClusterFetchBrokerAPIVersions,
+		Cluster:     params.ByName("cluster"),
+		Refresh:     refresh,
+		Reply:       make(chan interface{}),
+	}
+	select {
+	case hc.App.ClusterChannel <- request:
+	case <-r.Context().Done():
+		// The client has gone away (or Burrow is stopping the cluster modules)
+		return
+	}
+	response := <-request.Reply
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseAPIVersions{
+		Error:       false,
+		Message:     "broker API versions returned",
+		APIVersions: response.(*protocol.ClusterAPIVersions),
+		Request:     requestInfo,
+	})
+}
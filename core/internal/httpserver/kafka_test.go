@@ -12,6 +12,7 @@ package httpserver
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -58,6 +59,7 @@ func TestHttpServer_handleClusterDetail(t *testing.T) {
 	viper.Set("client-profile.test.client-id", "testid")
 	viper.Set("cluster.testcluster.class-name", "kafka")
 	viper.Set("cluster.testcluster.client-profile", "test")
+	SetClusterKafkaVersion("testcluster", "2.8.2")
 
 	// Set up a request
 	req, err := http.NewRequest("GET", "/v3/kafka/testcluster", http.NoBody)
@@ -84,6 +86,7 @@ func TestHttpServer_handleClusterDetail(t *testing.T) {
 	assert.NoError(t, err, "Expected body decode to return no error")
 	assert.False(t, resp.Error, "Expected response Error to be false")
 	assert.Equalf(t, "kafka", resp.Module.ClassName, "Expected response to contain a module with type kafka, not %v", resp.Module.ClassName)
+	assert.Equalf(t, "2.8.2", resp.Module.KafkaVersion, "Expected response to contain Kafka version 2.8.2, not %v", resp.Module.KafkaVersion)
 
 	// Call again for a 404
 	req, err = http.NewRequest("GET", "/v3/kafka/nocluster", http.NoBody)
@@ -137,6 +140,104 @@ func TestHttpServer_handleTopicList(t *testing.T) {
 	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
 }
 
+func TestHttpServer_handleTopicLag(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	groupStatuses := map[string]*protocol.ConsumerGroupStatus{
+		"group1": {Cluster: "testcluster", Group: "group1", Status: protocol.StatusWarning, Partitions: []*protocol.PartitionStatus{
+			{Topic: "testtopic", Partition: 0, Status: protocol.StatusOK, CurrentLag: 100, TimeLag: 5},
+			{Topic: "testtopic", Partition: 1, Status: protocol.StatusOK, CurrentLag: 50, TimeLag: 20},
+			{Topic: "othertopic", Partition: 0, Status: protocol.StatusWarning, CurrentLag: 10000},
+		}},
+		"group2": {Cluster: "testcluster", Group: "group2", Status: protocol.StatusError, Partitions: []*protocol.PartitionStatus{
+			{Topic: "testtopic", Partition: 0, Status: protocol.StatusStop, CurrentLag: 300, TimeLag: 60},
+		}},
+		"nogroup": {Cluster: "testcluster", Group: "nogroup", Status: protocol.StatusNotFound},
+	}
+
+	// Respond to the expected storage and evaluator requests
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchConsumersForTopic, request.RequestType, "Expected request of type StorageFetchConsumersForTopic, not %v", request.RequestType)
+		assert.Equalf(t, "testtopic", request.Topic, "Expected request Topic to be testtopic, not %v", request.Topic)
+		request.Reply <- []string{"group1", "group2", "nogroup"}
+		close(request.Reply)
+
+		for range groupStatuses {
+			evalRequest := <-coordinator.App.EvaluatorChannel
+			assert.True(t, evalRequest.ShowAll, "Expected request ShowAll to be True")
+			evalRequest.Reply <- groupStatuses[evalRequest.Group]
+		}
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/topic/testtopic/lag", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Need a custom type for the test, due to conversions
+	type ResponseType struct {
+		Error     bool   `json:"error"`
+		Topic     string `json:"topic"`
+		TotalLag  uint64 `json:"total_lag"`
+		Consumers []struct {
+			Group       string `json:"group"`
+			Status      string `json:"status"`
+			TopicStatus string `json:"topic_status"`
+			Partitions  int    `json:"partitions"`
+			Lag         uint64 `json:"lag"`
+			MaxTimeLag  int64  `json:"max_time_lag"`
+		} `json:"consumers"`
+	}
+	var resp ResponseType
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equal(t, "testtopic", resp.Topic)
+	assert.Equalf(t, uint64(450), resp.TotalLag, "Expected only the topic's partitions in the total, not %v", resp.TotalLag)
+	assert.Len(t, resp.Consumers, 2, "Expected the group that was not found to be left out")
+	assert.Equal(t, "group2", resp.Consumers[0].Group, "Expected the group with the most lag first")
+	assert.Equal(t, "STOP", resp.Consumers[0].TopicStatus)
+	assert.Equal(t, uint64(300), resp.Consumers[0].Lag)
+	assert.Equal(t, "group1", resp.Consumers[1].Group)
+	assert.Equal(t, "WARN", resp.Consumers[1].Status)
+	assert.Equal(t, "OK", resp.Consumers[1].TopicStatus, "Expected the topic status to only use the topic's partitions")
+	assert.Equal(t, 2, resp.Consumers[1].Partitions)
+	assert.Equal(t, uint64(150), resp.Consumers[1].Lag)
+	assert.Equal(t, int64(20), resp.Consumers[1].MaxTimeLag)
+
+	req, err = http.NewRequest("GET", "/v3/kafka/nocluster/topic/testtopic/lag", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_topicConsumerLag_UnknownTimeLag(t *testing.T) {
+	status := &protocol.ConsumerGroupStatus{Group: "testgroup", Status: protocol.StatusOK, Partitions: []*protocol.PartitionStatus{
+		{Topic: "testtopic", Partition: 0, Status: protocol.StatusOK, CurrentLag: 10, TimeLag: -1},
+		{Topic: "testtopic", Partition: 1, Status: protocol.StatusOK, CurrentLag: 20, TimeLag: -1},
+		{Topic: "othertopic", Partition: 0, Status: protocol.StatusOK, CurrentLag: 30, TimeLag: 50},
+	}}
+
+	// The time lag of another topic is not used, so the max is not known
+	consumer := topicConsumerLag(status, "testtopic")
+	assert.Equal(t, int64(-1), consumer.MaxTimeLag, "Expected the max time lag to not be known")
+
+	// A known time lag replaces the unknown ones, even if it is zero
+	status.Partitions[1].TimeLag = 0
+	consumer = topicConsumerLag(status, "testtopic")
+	assert.Equal(t, int64(0), consumer.MaxTimeLag)
+
+	// A topic with no partitions for the group has no time lag either
+	consumer = topicConsumerLag(status, "notopic")
+	assert.Equal(t, int64(-1), consumer.MaxTimeLag)
+}
+
 func TestHttpServer_handleConsumerList(t *testing.T) {
 	coordinator := fixtureConfiguredCoordinator()
 
@@ -193,6 +294,13 @@ func TestHttpServer_handleTopicDetail(t *testing.T) {
 		request.Reply <- []int64{345, 921}
 		close(request.Reply)
 
+		// The oldest offsets are fetched for a topic that exists
+		request = <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchTopicOldestOffsets, request.RequestType, "Expected request of type StorageFetchTopicOldestOffsets, not %v", request.RequestType)
+		assert.Equalf(t, "testtopic", request.Topic, "Expected request Topic to be testtopic, not %v", request.Topic)
+		request.Reply <- []int64{100, -1}
+		close(request.Reply)
+
 		// Second request is a 404
 		request = <-coordinator.App.StorageChannel
 		assert.Equalf(t, protocol.StorageFetchTopic, request.RequestType, "Expected request of type StorageFetchTopic, not %v", request.RequestType)
@@ -225,6 +333,7 @@ func TestHttpServer_handleTopicDetail(t *testing.T) {
 	assert.NoError(t, err, "Expected body decode to return no error")
 	assert.False(t, resp.Error, "Expected response Error to be false")
 	assert.Equalf(t, []int64{345, 921}, resp.Offsets, "Expected Offsets list to contain [345, 921], not %v", resp.Offsets)
+	assert.Equalf(t, []int64{100, -1}, resp.OldestOffsets, "Expected OldestOffsets list to contain [100, -1], not %v", resp.OldestOffsets)
 
 	// Call again for a 404
 	req, err = http.NewRequest("GET", "/v3/kafka/nocluster/topic/testtopic", http.NoBody)
@@ -241,6 +350,28 @@ func TestHttpServer_handleTopicDetail(t *testing.T) {
 	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
 }
 
+func TestHttpServer_handleTopicDetail_NoOldestOffsets(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		request.Reply <- []int64{345, 921}
+		close(request.Reply)
+
+		// The cluster does not fetch the oldest offsets
+		request = <-coordinator.App.StorageChannel
+		request.Reply <- []int64{}
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/topic/testtopic", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+	assert.NotContains(t, rr.Body.String(), "oldest_offsets", "Expected oldest_offsets to be left out of the response")
+}
+
 func TestHttpServer_handleConsumerDetail(t *testing.T) {
 	coordinator := fixtureConfiguredCoordinator()
 
@@ -322,6 +453,489 @@ func TestHttpServer_handleConsumerDetail(t *testing.T) {
 	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
 }
 
+func TestHttpServer_handleConsumerBaseline(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage requests
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchGroupBaseline, request.RequestType, "Expected request of type StorageFetchGroupBaseline, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.Equalf(t, "testgroup", request.Group, "Expected request Group to be testgroup, not %v", request.Group)
+		response := &protocol.LagBaseline{}
+		response.Hours[3] = protocol.LagBaselineHour{Samples: 40, Mean: 1200, StdDev: 150}
+		request.Reply <- response
+		close(request.Reply)
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		assert.Equalf(t, "nogroup", request.Group, "Expected request Group to be nogroup, not %v", request.Group)
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/consumer/testgroup/baseline", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	decoder := json.NewDecoder(rr.Body)
+	var resp httpResponseConsumerBaseline
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equal(t, protocol.LagBaselineHour{Samples: 40, Mean: 1200, StdDev: 150}, resp.Baseline.Hours[3])
+	assert.Equal(t, protocol.LagBaselineHour{}, resp.Baseline.Hours[4])
+
+	req, err = http.NewRequest("GET", "/v3/kafka/testcluster/consumer/nogroup/baseline", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleConsumerStatusHistory(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage requests
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchGroupStatusHistory, request.RequestType, "Expected request of type StorageFetchGroupStatusHistory, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.Equalf(t, "testgroup", request.Group, "Expected request Group to be testgroup, not %v", request.Group)
+		assert.Equalf(t, int64(1500000000000), request.Timestamp, "Expected request Timestamp to be 1500000000000, not %v", request.Timestamp)
+		assert.Equalf(t, int64(1500003600000), request.EndTimestamp, "Expected request EndTimestamp to be 1500003600000, not %v", request.EndTimestamp)
+		request.Reply <- []protocol.StatusTransition{
+			{Timestamp: 1499999000000, Status: protocol.StatusOK},
+			{Timestamp: 1500001000000, Status: protocol.StatusError},
+		}
+		close(request.Reply)
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		assert.Equalf(t, "nocluster", request.Cluster, "Expected request Cluster to be nocluster, not %v", request.Cluster)
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/consumer/testgroup/status/history?from=1500000000000&to=2017-07-14T03:40:00Z", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	decoder := json.NewDecoder(rr.Body)
+	var resp map[string]interface{}
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp["error"].(bool), "Expected response Error to be false")
+	history := resp["history"].([]interface{})
+	assert.Lenf(t, history, 2, "Expected 2 status changes, not %v", len(history))
+	assert.Equalf(t, "ERR", history[1].(map[string]interface{})["status"], "Expected second status to be ERR, not %v", history[1])
+
+	req, err = http.NewRequest("GET", "/v3/kafka/nocluster/consumer/testgroup/status/history", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+
+	// Bad times are refused before storage is asked
+	for _, query := range []string{"from=yesterday", "to=-1", "from=1500003600000&to=1500000000000"} {
+		req, err = http.NewRequest("GET", "/v3/kafka/testcluster/consumer/testgroup/status/history?"+query, http.NoBody)
+		assert.NoError(t, err, "Expected request setup to return no error")
+		rr = httptest.NewRecorder()
+		coordinator.router.ServeHTTP(rr, req)
+		assert.Equalf(t, http.StatusBadRequest, rr.Code, "Expected response code for %v to be 400, not %v", query, rr.Code)
+	}
+}
+
+func TestHttpServer_handleBrokerRefreshOffsets(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected cluster requests
+	go func() {
+		request := <-coordinator.App.ClusterChannel
+		assert.Equalf(t, protocol.ClusterRefreshBrokerOffsets, request.RequestType, "Expected request of type ClusterRefreshBrokerOffsets, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.Equalf(t, int32(13), request.Broker, "Expected request Broker to be 13, not %v", request.Broker)
+		request.Reply <- &protocol.ClusterBrokerOffsets{Broker: 13, Partitions: 4, PartitionErrors: 1}
+		close(request.Reply)
+
+		// The broker request fails
+		request = <-coordinator.App.ClusterChannel
+		request.Reply <- &protocol.ClusterBrokerOffsets{Broker: 13, Partitions: 4, Error: "broker down"}
+		close(request.Reply)
+
+		// The broker does not lead any partitions
+		request = <-coordinator.App.ClusterChannel
+		request.Reply <- &protocol.ClusterBrokerOffsets{Broker: 14}
+		close(request.Reply)
+
+		// The cluster does not exist
+		request = <-coordinator.App.ClusterChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("POST", "/v3/kafka/testcluster/broker/13/refresh-offsets", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseBrokerOffsets
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equal(t, &protocol.ClusterBrokerOffsets{Broker: 13, Partitions: 4, PartitionErrors: 1}, resp.Offsets)
+
+	req, _ = http.NewRequest("POST", "/v3/kafka/testcluster/broker/13/refresh-offsets", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusInternalServerError, rr.Code, "Expected response code to be 500, not %v", rr.Code)
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.True(t, resp.Error, "Expected response Error to be true")
+	assert.Equal(t, "broker down", resp.Offsets.Error)
+
+	req, _ = http.NewRequest("POST", "/v3/kafka/testcluster/broker/14/refresh-offsets", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+
+	req, _ = http.NewRequest("POST", "/v3/kafka/nocluster/broker/13/refresh-offsets", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+
+	// A bad broker ID is refused without a cluster request
+	req, _ = http.NewRequest("POST", "/v3/kafka/testcluster/broker/notabroker/refresh-offsets", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusBadRequest, rr.Code, "Expected response code to be 400, not %v", rr.Code)
+}
+
+func TestHttpServer_handleTopicOffsetsAtTime(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected cluster requests
+	go func() {
+		request := <-coordinator.App.ClusterChannel
+		assert.Equalf(t, protocol.ClusterFetchTopicOffsetsAtTime, request.RequestType, "Expected request of type ClusterFetchTopicOffsetsAtTime, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.Equalf(t, "testtopic", request.Topic, "Expected request Topic to be testtopic, not %v", request.Topic)
+		assert.Equalf(t, int64(1500000000000), request.Timestamp, "Expected request Timestamp to be 1500000000000, not %v", request.Timestamp)
+		request.Reply <- &protocol.ClusterTopicOffsets{Topic: "testtopic", Timestamp: 1500000000000, Offsets: []int64{1000, -1}}
+		close(request.Reply)
+
+		// Some partitions failed
+		request = <-coordinator.App.ClusterChannel
+		request.Reply <- &protocol.ClusterTopicOffsets{Topic: "testtopic", Timestamp: 1500000000000, Offsets: []int64{1000, -1}, Error: "failed"}
+		close(request.Reply)
+
+		// The Kafka version cannot look up offsets by timestamp
+		request = <-coordinator.App.ClusterChannel
+		request.Reply <- &protocol.ClusterTopicOffsets{Topic: "testtopic", Timestamp: 1500000000000, Error: "unsupported", Unsupported: true}
+		close(request.Reply)
+
+		// The topic does not exist
+		request = <-coordinator.App.ClusterChannel
+		request.Reply <- &protocol.ClusterTopicOffsets{Topic: "notopic", Timestamp: 1500000000000}
+		close(request.Reply)
+
+		// The cluster does not exist
+		request = <-coordinator.App.ClusterChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/topic/testtopic/offsets?timestamp=1500000000000", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseTopicOffsets
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equal(t, &protocol.ClusterTopicOffsets{Topic: "testtopic", Timestamp: 1500000000000, Offsets: []int64{1000, -1}}, resp.Offsets)
+
+	req, _ = http.NewRequest("GET", "/v3/kafka/testcluster/topic/testtopic/offsets?timestamp=1500000000000", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusInternalServerError, rr.Code, "Expected response code to be 500, not %v", rr.Code)
+
+	req, _ = http.NewRequest("GET", "/v3/kafka/testcluster/topic/testtopic/offsets?timestamp=1500000000000", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotImplemented, rr.Code, "Expected response code to be 501, not %v", rr.Code)
+
+	req, _ = http.NewRequest("GET", "/v3/kafka/testcluster/topic/notopic/offsets?timestamp=1500000000000", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+
+	req, _ = http.NewRequest("GET", "/v3/kafka/nocluster/topic/testtopic/offsets?timestamp=1500000000000", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+
+	// A missing or bad timestamp is refused without a cluster request
+	for _, query := range []string{"", "?timestamp=yesterday", "?timestamp=-1"} {
+		req, _ = http.NewRequest("GET", "/v3/kafka/testcluster/topic/testtopic/offsets"+query, http.NoBody)
+		rr = httptest.NewRecorder()
+		coordinator.router.ServeHTTP(rr, req)
+		assert.Equalf(t, http.StatusBadRequest, rr.Code, "Expected response code for %v to be 400, not %v", query, rr.Code)
+	}
+}
+
+func TestHttpServer_handleBrokerAPIVersions(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	versions := &protocol.ClusterAPIVersions{
+		Fetched: 1000,
+		Uniform: true,
+		Brokers: []*protocol.BrokerAPIVersions{{Broker: 1, APIs: []*protocol.APIVersionRange{{APIKey: 0, MinVersion: 0, MaxVersion: 9}}}},
+	}
+
+	// Respond to the expected cluster requests
+	go func() {
+		request := <-coordinator.App.ClusterChannel
+		assert.Equalf(t, protocol.ClusterFetchBrokerAPIVersions, request.RequestType, "Expected request of type ClusterFetchBrokerAPIVersions, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.False(t, request.Refresh, "Expected request Refresh to be false")
+		request.Reply <- versions
+		close(request.Reply)
+
+		request = <-coordinator.App.ClusterChannel
+		assert.True(t, request.Refresh, "Expected request Refresh to be true")
+		request.Reply <- versions
+		close(request.Reply)
+
+		// The cluster does not exist
+		request = <-coordinator.App.ClusterChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/api-versions", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseAPIVersions
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equal(t, versions, resp.APIVersions)
+
+	req, _ = http.NewRequest("GET", "/v3/kafka/testcluster/api-versions?refresh=true", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	req, _ = http.NewRequest("GET", "/v3/kafka/nocluster/api-versions", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+
+	// A bad refresh parameter is refused without a cluster request
+	req, _ = http.NewRequest("GET", "/v3/kafka/testcluster/api-versions?refresh=maybe", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusBadRequest, rr.Code, "Expected response code to be 400, not %v", rr.Code)
+}
+
+func TestHttpServer_handleConsumerRefresh(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected consumer requests
+	go func() {
+		request := <-coordinator.App.ConsumerChannel
+		assert.Equalf(t, protocol.ConsumerRefreshGroup, request.RequestType, "Expected request of type ConsumerRefreshGroup, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.Equalf(t, "testgroup", request.Group, "Expected request Group to be testgroup, not %v", request.Group)
+		request.Reply <- &protocol.ConsumerGroupOffsets{Consumer: "testconsumer", Group: "testgroup", Partitions: 3}
+		close(request.Reply)
+
+		// The fetch fails
+		request = <-coordinator.App.ConsumerChannel
+		request.Reply <- &protocol.ConsumerGroupOffsets{Consumer: "testconsumer", Group: "testgroup", Error: "coordinator down"}
+		close(request.Reply)
+
+		// There is no consumer module for the cluster
+		request = <-coordinator.App.ConsumerChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("POST", "/v3/kafka/testcluster/consumer/testgroup/refresh", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseGroupOffsets
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equal(t, &protocol.ConsumerGroupOffsets{Consumer: "testconsumer", Group: "testgroup", Partitions: 3}, resp.Offsets)
+
+	req, _ = http.NewRequest("POST", "/v3/kafka/testcluster/consumer/testgroup/refresh", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusInternalServerError, rr.Code, "Expected response code to be 500, not %v", rr.Code)
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.True(t, resp.Error, "Expected response Error to be true")
+	assert.Equal(t, "coordinator down", resp.Offsets.Error)
+
+	req, _ = http.NewRequest("POST", "/v3/kafka/nocluster/consumer/testgroup/refresh", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleConsumerFetchStatus(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected consumer requests
+	go func() {
+		request := <-coordinator.App.ConsumerChannel
+		assert.Equalf(t, protocol.ConsumerFetchGroupStatus, request.RequestType, "Expected request of type ConsumerFetchGroupStatus, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.Equalf(t, "testgroup", request.Group, "Expected request Group to be testgroup, not %v", request.Group)
+		request.Reply <- &protocol.ConsumerGroupFetchStatus{
+			Consumer:    "testconsumer",
+			Group:       "testgroup",
+			LastError:   "coordinator down",
+			LastSuccess: 1000,
+			LastFailure: 2000,
+		}
+		close(request.Reply)
+
+		// There is no consumer module for the cluster
+		request = <-coordinator.App.ConsumerChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/consumer/testgroup/fetch-status", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseGroupFetchStatus
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equal(t, "coordinator down", resp.FetchStatus.LastError)
+	assert.Equal(t, int64(1000), resp.FetchStatus.LastSuccess)
+	assert.Equal(t, int64(2000), resp.FetchStatus.LastFailure)
+
+	req, _ = http.NewRequest("GET", "/v3/kafka/nocluster/consumer/testgroup/fetch-status", http.NoBody)
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleConsumerTimeLag(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected evaluator requests
+	go func() {
+		request := <-coordinator.App.EvaluatorChannel
+		assert.True(t, request.ShowAll, "Expected request ShowAll to be True")
+		request.Reply <- &protocol.ConsumerGroupStatus{
+			Cluster:    request.Cluster,
+			Group:      request.Group,
+			Status:     protocol.StatusOK,
+			MaxTimeLag: 30000,
+			Partitions: []*protocol.PartitionStatus{
+				{Topic: "testtopic", Partition: 1, CurrentLag: 300, TimeLag: 30000},
+				{Topic: "testtopic", Partition: 0, CurrentLag: 100, TimeLag: 10000},
+			},
+		}
+		close(request.Reply)
+
+		// Second request is a 404
+		request = <-coordinator.App.EvaluatorChannel
+		request.Reply <- &protocol.ConsumerGroupStatus{Cluster: request.Cluster, Group: request.Group, Status: protocol.StatusNotFound}
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/consumer/testgroup/time-lag", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseConsumerTimeLag
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equalf(t, int64(30000), resp.MaxTimeLag, "Expected MaxTimeLag to be 30000, not %v", resp.MaxTimeLag)
+	assert.Len(t, resp.Partitions, 2, "Expected two partitions")
+	assert.Equalf(t, int32(0), resp.Partitions[0].Partition, "Expected partition 0 first, not %v", resp.Partitions[0].Partition)
+	assert.Equalf(t, int64(10000), resp.Partitions[0].TimeLag, "Expected TimeLag to be 10000, not %v", resp.Partitions[0].TimeLag)
+
+	req, err = http.NewRequest("GET", "/v3/kafka/testcluster/consumer/nogroup/time-lag", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleConsumerStatus_Fields(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected evaluator request
+	go func() {
+		request := <-coordinator.App.EvaluatorChannel
+		assert.True(t, request.ShowAll, "Expected request ShowAll to be True")
+		request.Reply <- &protocol.ConsumerGroupStatus{
+			Cluster:         request.Cluster,
+			Group:           request.Group,
+			Status:          protocol.StatusWarning,
+			Complete:        1.0,
+			Partitions:      []*protocol.PartitionStatus{{Topic: "testtopic", Partition: 0, Status: protocol.StatusWarning}},
+			TotalPartitions: 1,
+			TotalLag:        2345,
+		}
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/consumer/testgroup/lag?fields=status,totallag,members", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp map[string]interface{}
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.Equal(t, false, resp["error"], "Expected response Error to be false")
+
+	// Members is empty, so it is left out as it would be in the full status
+	assert.Equal(t, map[string]interface{}{"status": "WARN", "totallag": float64(2345)}, resp["status"])
+
+	// An unknown field is refused without an evaluator request
+	req, err = http.NewRequest("GET", "/v3/kafka/testcluster/consumer/testgroup/status?fields=status,nofield", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusBadRequest, rr.Code, "Expected response code to be 400, not %v", rr.Code)
+}
+
+func TestParseStatusFields(t *testing.T) {
+	fields, unknown := parseStatusFields("")
+	assert.Nil(t, fields, "Expected no fields to select all of them")
+	assert.Empty(t, unknown)
+
+	fields, unknown = parseStatusFields(" status, totallag,,maxlag")
+	assert.Equal(t, []string{"status", "totallag", "maxlag"}, fields)
+	assert.Empty(t, unknown)
+
+	_, unknown = parseStatusFields("status,lag")
+	assert.Equal(t, "lag", unknown)
+}
+
 // Custom response types for consumer status, as the status field will be a string
 type ResponsePartition struct {
 	Topic      string                   `json:"topic"`
@@ -561,3 +1175,255 @@ func TestHttpServer_handleConsumerDelete(t *testing.T) {
 	assert.NoError(t, err, "Expected body decode to return no error")
 	assert.False(t, resp.Error, "Expected response Error to be false")
 }
+
+func TestHttpServer_handleStalePartitions(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	viper.Set("cluster.testcluster.offset-refresh", 10)
+	viper.Set("cluster.testcluster.stale-offset-intervals", 3)
+	timeNow := time.Now().UnixNano() / int64(time.Millisecond)
+
+	// Respond to the expected storage request
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchEndOffsets, request.RequestType, "Expected request of type StorageFetchEndOffsets, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		request.Reply <- []*protocol.EndOffset{
+			{Topic: "testtopic", Partition: 1, Offset: 2345, Timestamp: timeNow - 60000},
+			{Topic: "testtopic", Partition: 0, Offset: 1234, Timestamp: timeNow - 40000},
+			{Topic: "testtopic", Partition: 2, Offset: 3456, Timestamp: timeNow - 20000},
+			{Topic: "othertopic", Partition: 0, Offset: 4567, Timestamp: timeNow},
+		}
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/stale-partitions", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Parse response body
+	decoder := json.NewDecoder(rr.Body)
+	var resp httpResponseStalePartitions
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equalf(t, int64(30), resp.StaleAfter, "Expected stale-after to be 30, not %v", resp.StaleAfter)
+	assert.Equal(t, []*httpResponseStalePartition{
+		{Topic: "testtopic", Partition: 0, Offset: 1234, Timestamp: timeNow - 40000, Age: 40},
+		{Topic: "testtopic", Partition: 1, Offset: 2345, Timestamp: timeNow - 60000, Age: 60},
+	}, resp.Partitions)
+
+	// Respond to the expected storage request for a missing cluster
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		close(request.Reply)
+	}()
+
+	req, err = http.NewRequest("GET", "/v3/kafka/nocluster/stale-partitions", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleCompactedTopics(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage request
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchCompactedTopics, request.RequestType, "Expected request of type StorageFetchCompactedTopics, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		request.Reply <- []string{"compacttopic"}
+		close(request.Reply)
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/compacted-topics", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	decoder := json.NewDecoder(rr.Body)
+	var resp httpResponseTopicList
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equal(t, []string{"compacttopic"}, resp.Topics)
+
+	req, err = http.NewRequest("GET", "/v3/kafka/nocluster/compacted-topics", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleConsumerTop(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	groupStatuses := map[string]*protocol.ConsumerGroupStatus{
+		"group1":     {Cluster: "testcluster", Group: "group1", Status: protocol.StatusOK, TotalLag: 500},
+		"group2":     {Cluster: "testcluster", Group: "group2", Status: protocol.StatusError, TotalLag: 100},
+		"group3":     {Cluster: "testcluster", Group: "group3", Status: protocol.StatusWarning, TotalLag: 1000},
+		"nogroup":    {Cluster: "testcluster", Group: "nogroup", Status: protocol.StatusNotFound},
+		"quietgroup": {Cluster: "testcluster", Group: "quietgroup", Status: protocol.StatusOK, TotalLag: 0},
+		"mutedgroup": {Cluster: "testcluster", Group: "mutedgroup", Status: protocol.StatusMuted, TotalLag: 5000},
+	}
+
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"group3", "group1", "group2", "quietgroup"}},
+		{"?sort=lag&count=2", []string{"group3", "group1"}},
+		{"?sort=status", []string{"group2", "group3", "group1", "quietgroup"}},
+		{"?sort=status&count=1", []string{"group2"}},
+		{"?min-lag=200", []string{"group3", "group1", "group2"}},
+	}
+
+	// Need a custom type for the test, due to conversions
+	type ResponseType struct {
+		Error     bool   `json:"error"`
+		Message   string `json:"message"`
+		Consumers []struct {
+			Group    string `json:"group"`
+			Status   string `json:"status"`
+			TotalLag uint64 `json:"totallag"`
+		} `json:"consumers"`
+	}
+
+	for _, testCase := range testCases {
+		// Respond to the expected storage and evaluator requests
+		go func() {
+			request := <-coordinator.App.StorageChannel
+			assert.Equalf(t, protocol.StorageFetchConsumers, request.RequestType, "Expected request of type StorageFetchConsumers, not %v", request.RequestType)
+			assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+			request.Reply <- []string{"group1", "group2", "group3", "nogroup", "quietgroup", "mutedgroup"}
+			close(request.Reply)
+
+			for range groupStatuses {
+				evalRequest := <-coordinator.App.EvaluatorChannel
+				assert.False(t, evalRequest.ShowAll, "Expected request ShowAll to be False")
+				evalRequest.Reply <- groupStatuses[evalRequest.Group]
+			}
+		}()
+
+		req, err := http.NewRequest("GET", "/v3/kafka/testcluster/top"+testCase.query, http.NoBody)
+		assert.NoError(t, err, "Expected request setup to return no error")
+		rr := httptest.NewRecorder()
+		coordinator.router.ServeHTTP(rr, req)
+		assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200 for query '%v', not %v", testCase.query, rr.Code)
+
+		// Parse response body
+		decoder := json.NewDecoder(rr.Body)
+		var resp ResponseType
+		err = decoder.Decode(&resp)
+		assert.NoError(t, err, "Expected body decode to return no error")
+		assert.False(t, resp.Error, "Expected response Error to be false")
+		groups := make([]string, len(resp.Consumers))
+		for i, status := range resp.Consumers {
+			groups[i] = status.Group
+		}
+		assert.Equalf(t, testCase.expected, groups, "Expected groups %v for query '%v', not %v", testCase.expected, testCase.query, groups)
+	}
+}
+
+func TestHttpServer_handleConsumerStatusBulk(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	groupStatuses := map[string]*protocol.ConsumerGroupStatus{
+		"group1":  {Cluster: "testcluster", Group: "group1", Status: protocol.StatusOK, TotalLag: 500},
+		"group2":  {Cluster: "testcluster", Group: "group2", Status: protocol.StatusError, TotalLag: 100},
+		"nogroup": {Cluster: "testcluster", Group: "nogroup", Status: protocol.StatusNotFound},
+	}
+
+	// Respond to the expected evaluator requests, once for each group even though group2 is asked for twice
+	go func() {
+		for range groupStatuses {
+			evalRequest := <-coordinator.App.EvaluatorChannel
+			assert.Equalf(t, "testcluster", evalRequest.Cluster, "Expected request Cluster to be testcluster, not %v", evalRequest.Cluster)
+			evalRequest.Reply <- groupStatuses[evalRequest.Group]
+		}
+	}()
+
+	body := strings.NewReader(`["group2", "nogroup", "group1", "group2"]`)
+	req, err := http.NewRequest("POST", "/v3/kafka/testcluster/consumers/status", body)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Need a custom type for the test, due to conversions
+	var resp struct {
+		Error     bool   `json:"error"`
+		Message   string `json:"message"`
+		Consumers []struct {
+			Group    string `json:"group"`
+			Status   string `json:"status"`
+			TotalLag uint64 `json:"totallag"`
+		} `json:"consumers"`
+		NotFound []string `json:"not_found"`
+	}
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Len(t, resp.Consumers, 2, "Expected the two groups that were found")
+	assert.Equal(t, "group2", resp.Consumers[0].Group, "Expected the groups in the order given")
+	assert.Equal(t, "ERR", resp.Consumers[0].Status)
+	assert.Equal(t, "group1", resp.Consumers[1].Group, "Expected the groups in the order given")
+	assert.Equal(t, []string{"nogroup"}, resp.NotFound)
+}
+
+func TestHttpServer_handleConsumerStatusBulk_BadRequest(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	viper.Set("cluster.testcluster.class-name", "kafka")
+
+	for _, body := range []string{"", "{\"groups\": []}", "[]"} {
+		req, err := http.NewRequest("POST", "/v3/kafka/testcluster/consumers/status", strings.NewReader(body))
+		assert.NoError(t, err, "Expected request setup to return no error")
+		rr := httptest.NewRecorder()
+		coordinator.router.ServeHTTP(rr, req)
+		assert.Equalf(t, http.StatusBadRequest, rr.Code, "Expected response code to be 400 for body '%v', not %v", body, rr.Code)
+	}
+
+	req, err := http.NewRequest("POST", "/v3/kafka/nocluster/consumers/status", strings.NewReader(`["group1"]`))
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+
+	// There is no POST for a single group
+	req, err = http.NewRequest("POST", "/v3/kafka/testcluster/consumer/group1", strings.NewReader(`["group1"]`))
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusMethodNotAllowed, rr.Code, "Expected response code to be 405, not %v", rr.Code)
+}
+
+func TestHttpServer_handleConsumerTop_BadRequest(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	for _, query := range []string{"?sort=foo", "?count=0", "?count=bar", "?min-lag=-1"} {
+		req, err := http.NewRequest("GET", "/v3/kafka/testcluster/top"+query, http.NoBody)
+		assert.NoError(t, err, "Expected request setup to return no error")
+		rr := httptest.NewRecorder()
+		coordinator.router.ServeHTTP(rr, req)
+		assert.Equalf(t, http.StatusBadRequest, rr.Code, "Expected response code to be 400 for query '%v', not %v", query, rr.Code)
+	}
+
+	// Respond to the expected storage request for a missing cluster
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/nocluster/top", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
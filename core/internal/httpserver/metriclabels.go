@@ -0,0 +1,195 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/spf13/viper"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// metricLabelRules bounds the number of Prometheus series that are created for consumer groups and topics. Group and
+// topic label values can have parts stripped (such as a generated numeric suffix) and be truncated, and groups that
+// are rewritten to the same label value are aggregated into a single series. Groups that are OK and have less lag
+// than a threshold can also be left out of the per-group metrics entirely, as can partitions that are OK and have less
+// lag than a separate threshold.
+//
+// Each cluster can also have constant labels (such as env or region), which are added to every metric that has the
+// cluster's name as its cluster label. A constant label is not added to a metric that already has a label of the same
+// name, so the metric's own value is kept.
+type metricLabelRules struct {
+	groupStrip      []*regexp.Regexp
+	topicStrip      []*regexp.Regexp
+	maxLength       int
+	minGroupLag     uint64
+	minPartitionLag uint64
+
+	clusterLabels map[string][]*dto.LabelPair
+}
+
+// Label names must be valid Prometheus label names. They cannot be cluster, which every metric they are added to
+// already has, or the labels that Prometheus adds to histogram and summary samples
+var (
+	labelNameRegex     = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+	reservedLabelNames = map[string]bool{
+		"cluster":  true,
+		"le":       true,
+		"quantile": true,
+	}
+)
+
+// The label rules used for all metrics. These are global, as the metrics themselves are, and the zero value leaves
+// all label values as they are
+var metricLabels = &metricLabelRules{}
+
+// newMetricLabelRules reads the label rules from the configuration under configRoot. A bad regular expression, or a
+// negative max-label-length, will cause this func to panic. The constant labels for each cluster are read from the
+// cluster's metric-labels, and an invalid or reserved label name will also cause a panic.
+func newMetricLabelRules(configRoot string) *metricLabelRules {
+	rules := &metricLabelRules{
+		groupStrip:      compileLabelStrip(configRoot+".group-label-strip", viper.GetStringSlice(configRoot+".group-label-strip")),
+		topicStrip:      compileLabelStrip(configRoot+".topic-label-strip", viper.GetStringSlice(configRoot+".topic-label-strip")),
+		maxLength:       viper.GetInt(configRoot + ".max-label-length"),
+		minGroupLag:     viper.GetUint64(configRoot + ".min-group-lag"),
+		minPartitionLag: viper.GetUint64(configRoot + ".min-partition-lag"),
+		clusterLabels:   make(map[string][]*dto.LabelPair),
+	}
+	if rules.maxLength < 0 {
+		panic(configRoot + ".max-label-length must be zero or greater")
+	}
+
+	for cluster := range viper.GetStringMap("cluster") {
+		labels := viper.GetStringMapString("cluster." + cluster + ".metric-labels")
+		if len(labels) == 0 {
+			continue
+		}
+		pairs := make([]*dto.LabelPair, 0, len(labels))
+		for name, value := range labels {
+			if !labelNameRegex.MatchString(name) || (len(name) > 1 && name[:2] == "__") {
+				panic("Cluster '" + cluster + "' metric-labels has an invalid label name: " + name)
+			}
+			if reservedLabelNames[name] {
+				panic("Cluster '" + cluster + "' metric-labels cannot set the " + name + " label, as Burrow sets it")
+			}
+			pairs = append(pairs, &dto.LabelPair{Name: &name, Value: &value})
+		}
+		rules.clusterLabels[cluster] = pairs
+	}
+	return rules
+}
+
+func compileLabelStrip(key string, patterns []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			panic("Failed to compile " + key + " '" + pattern + "': " + err.Error())
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled
+}
+
+// aggregates returns true if different groups or topics can be given the same label value, or groups can be left out,
+// in which case the per-group series must be rebuilt on each scrape rather than updated in place
+func (rules *metricLabelRules) aggregates() bool {
+	return (len(rules.groupStrip) > 0) || (len(rules.topicStrip) > 0) || (rules.maxLength > 0) || (rules.minGroupLag > 0) ||
+		(rules.minPartitionLag > 0)
+}
+
+func (rules *metricLabelRules) rewrite(value string, strip []*regexp.Regexp) string {
+	for _, re := range strip {
+		value = re.ReplaceAllString(value, "")
+	}
+	if (rules.maxLength > 0) && (len(value) > rules.maxLength) {
+		value = value[:rules.maxLength]
+	}
+	return value
+}
+
+// group returns the label value to use for the named consumer group
+func (rules *metricLabelRules) group(name string) string {
+	return rules.rewrite(name, rules.groupStrip)
+}
+
+// topic returns the label value to use for the named topic
+func (rules *metricLabelRules) topic(name string) string {
+	return rules.rewrite(name, rules.topicStrip)
+}
+
+// emitGroup returns false if the group is OK and its total lag is below the min-group-lag, so that it is left out of
+// the per-group metrics. Groups that are not OK are always included, so that a problem is never hidden.
+func (rules *metricLabelRules) emitGroup(status *protocol.ConsumerGroupStatus) bool {
+	return (status.Status != protocol.StatusOK) || (status.TotalLag >= rules.minGroupLag)
+}
+
+// emitPartition returns false if the partition is OK and its lag is below the min-partition-lag, so that it is left
+// out of the per-partition metrics. As with groups, partitions that are not OK are always included.
+func (rules *metricLabelRules) emitPartition(partition *protocol.PartitionStatus) bool {
+	return (partition.Status != protocol.StatusOK) || (partition.CurrentLag >= rules.minPartitionLag)
+}
+
+// withMinLag returns a copy of the rules with both the group and partition lag thresholds set to minLag, for a scrape
+// that overrides them
+func (rules *metricLabelRules) withMinLag(minLag uint64) *metricLabelRules {
+	override := *rules
+	override.minGroupLag = minLag
+	override.minPartitionLag = minLag
+	return &override
+}
+
+// addClusterLabels adds the constant labels for the metric's cluster to it, keeping the labels sorted by name as the
+// registry does. Metrics with no cluster label, or for a cluster with no constant labels, are left as they are, and a
+// constant label is skipped for a metric that has its own label of that name (such as state or broker).
+func (rules *metricLabelRules) addClusterLabels(metric *dto.Metric) {
+	var extra []*dto.LabelPair
+	own := make(map[string]bool, len(metric.GetLabel()))
+	for _, pair := range metric.GetLabel() {
+		own[pair.GetName()] = true
+		if pair.GetName() == "cluster" {
+			extra = rules.clusterLabels[pair.GetValue()]
+		}
+	}
+	if len(extra) == 0 {
+		return
+	}
+
+	for _, pair := range extra {
+		if !own[pair.GetName()] {
+			metric.Label = append(metric.Label, pair)
+		}
+	}
+	sort.Slice(metric.Label, func(i, j int) bool {
+		return metric.Label[i].GetName() < metric.Label[j].GetName()
+	})
+}
+
+// clusterLabelGatherer adds the constant labels for each cluster to the metrics gathered from the wrapped Gatherer
+type clusterLabelGatherer struct {
+	gatherer prometheus.Gatherer
+}
+
+func (g clusterLabelGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	if len(metricLabels.clusterLabels) == 0 {
+		return families, err
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			metricLabels.addClusterLabels(metric)
+		}
+	}
+	return families, err
+}
@@ -0,0 +1,209 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func TestMetricLabelRules(t *testing.T) {
+	viper.Reset()
+	viper.Set("metrics.group-label-strip", []string{"-[0-9]+$", "^tmp-"})
+	viper.Set("metrics.topic-label-strip", []string{"\\.v[0-9]+$"})
+	viper.Set("metrics.max-label-length", 10)
+	viper.Set("metrics.min-group-lag", 100)
+	rules := newMetricLabelRules("metrics")
+
+	assert.True(t, rules.aggregates(), "Expected rules to aggregate")
+	assert.Equal(t, "app", rules.group("tmp-app-1234"))
+	assert.Equal(t, "averylongg", rules.group("averylonggroupname"))
+	assert.Equal(t, "events", rules.topic("events.v2"))
+	assert.Equal(t, "events-1", rules.topic("events-1"), "Expected group rules to not apply to topics")
+
+	assert.False(t, rules.emitGroup(&protocol.ConsumerGroupStatus{Status: protocol.StatusOK, TotalLag: 99}))
+	assert.True(t, rules.emitGroup(&protocol.ConsumerGroupStatus{Status: protocol.StatusOK, TotalLag: 100}))
+	assert.True(t, rules.emitGroup(&protocol.ConsumerGroupStatus{Status: protocol.StatusWarning, TotalLag: 0}), "Expected groups that are not OK to always be emitted")
+}
+
+func TestMetricLabelRules_MinPartitionLag(t *testing.T) {
+	viper.Reset()
+	viper.Set("metrics.min-partition-lag", 50)
+	rules := newMetricLabelRules("metrics")
+
+	assert.True(t, rules.aggregates(), "Expected rules to aggregate")
+	assert.False(t, rules.emitPartition(&protocol.PartitionStatus{Status: protocol.StatusOK, CurrentLag: 49}))
+	assert.True(t, rules.emitPartition(&protocol.PartitionStatus{Status: protocol.StatusOK, CurrentLag: 50}))
+	assert.True(t, rules.emitPartition(&protocol.PartitionStatus{Status: protocol.StatusStop, CurrentLag: 0}), "Expected partitions that are not OK to always be emitted")
+
+	override := rules.withMinLag(0)
+	assert.True(t, override.emitPartition(&protocol.PartitionStatus{Status: protocol.StatusOK, CurrentLag: 0}), "Expected override to emit all partitions")
+	assert.True(t, override.emitGroup(&protocol.ConsumerGroupStatus{Status: protocol.StatusOK, TotalLag: 0}), "Expected override to emit all groups")
+	assert.Equal(t, uint64(50), rules.minPartitionLag, "Expected the configured rules to be unchanged")
+}
+
+func TestMetricLabelRules_Default(t *testing.T) {
+	viper.Reset()
+	rules := newMetricLabelRules("metrics")
+
+	assert.False(t, rules.aggregates(), "Expected default rules to not aggregate")
+	assert.Equal(t, "app-1234", rules.group("app-1234"))
+	assert.Equal(t, "events.v2", rules.topic("events.v2"))
+	assert.True(t, rules.emitGroup(&protocol.ConsumerGroupStatus{Status: protocol.StatusOK, TotalLag: 0}))
+}
+
+func TestMetricLabelRules_BadConfig(t *testing.T) {
+	viper.Reset()
+	viper.Set("metrics.group-label-strip", []string{"("})
+	assert.Panics(t, func() { newMetricLabelRules("metrics") }, "Expected panic for bad group-label-strip")
+
+	viper.Reset()
+	viper.Set("metrics.max-label-length", -1)
+	assert.Panics(t, func() { newMetricLabelRules("metrics") }, "Expected panic for negative max-label-length")
+}
+
+func TestMetricLabelRules_ClusterLabels(t *testing.T) {
+	viper.Reset()
+	viper.Set("cluster.labeled.class-name", "kafka")
+	viper.Set("cluster.labeled.metric-labels", map[string]string{"env": "prod", "region": "us-east-1"})
+	viper.Set("cluster.plain.class-name", "kafka")
+	metricLabels = newMetricLabelRules("metrics")
+	defer func() { metricLabels = &metricLabelRules{} }()
+
+	assert.False(t, metricLabels.aggregates(), "Expected cluster labels to not aggregate")
+	clusterStalePartitionsGauge.With(map[string]string{"cluster": "labeled"}).Set(3)
+	clusterStalePartitionsGauge.With(map[string]string{"cluster": "plain"}).Set(1)
+	defer clusterStalePartitionsGauge.Reset()
+
+	families, err := clusterLabelGatherer{gatherer: prometheus.DefaultGatherer}.Gather()
+	assert.NoError(t, err)
+	labels := make(map[string]map[string]string)
+	for _, family := range families {
+		if family.GetName() != "burrow_kafka_cluster_stale_partitions" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			names := make([]string, 0)
+			values := make(map[string]string)
+			for _, pair := range metric.GetLabel() {
+				names = append(names, pair.GetName())
+				values[pair.GetName()] = pair.GetValue()
+			}
+			assert.IsIncreasing(t, names, "Expected labels to be sorted by name")
+			labels[values["cluster"]] = values
+		}
+	}
+	assert.Equal(t, map[string]string{"cluster": "labeled", "env": "prod", "region": "us-east-1"}, labels["labeled"])
+	assert.Equal(t, map[string]string{"cluster": "plain"}, labels["plain"], "Expected no labels added for a cluster without metric-labels")
+}
+
+func TestMetricLabelRules_ClusterLabels_OwnLabel(t *testing.T) {
+	viper.Reset()
+	viper.Set("cluster.labeled.class-name", "kafka")
+	viper.Set("cluster.labeled.metric-labels", map[string]string{"env": "prod", "state": "constant"})
+	metricLabels = newMetricLabelRules("metrics")
+	defer func() { metricLabels = &metricLabelRules{} }()
+
+	// The metric's own state label is kept, and the other constant labels are still added
+	metric := &dto.Metric{Label: []*dto.LabelPair{
+		{Name: proto.String("cluster"), Value: proto.String("labeled")},
+		{Name: proto.String("state"), Value: proto.String("ok")},
+	}}
+	metricLabels.addClusterLabels(metric)
+
+	labels := make(map[string]string)
+	for _, pair := range metric.GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	assert.Equal(t, map[string]string{"cluster": "labeled", "env": "prod", "state": "ok"}, labels)
+	assert.Len(t, metric.GetLabel(), 3, "Expected no duplicate labels")
+}
+
+func TestMetricLabelRules_BadClusterLabels(t *testing.T) {
+	for _, name := range []string{"1env", "env-name", "__env", "cluster", "le"} {
+		viper.Reset()
+		viper.Set("cluster.labeled.class-name", "kafka")
+		viper.Set("cluster.labeled.metric-labels", map[string]string{name: "value"})
+		assert.Panicsf(t, func() { newMetricLabelRules("metrics") }, "Expected panic for label name %v", name)
+	}
+}
+
+func TestHttpServer_handlePrometheusMetrics_Aggregated(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	viper.Set("metrics.group-label-strip", []string{"-[0-9]+$"})
+	viper.Set("metrics.min-group-lag", 100)
+	coordinator.Configure()
+	defer func() { metricLabels = &metricLabelRules{} }()
+
+	groups := map[string]*protocol.ConsumerGroupStatus{
+		"app-1": {Status: protocol.StatusOK, TotalLag: 50},
+		"app-2": {Status: protocol.StatusWarning, TotalLag: 20, Partitions: []*protocol.PartitionStatus{
+			{Topic: "testtopic", Partition: 0, Status: protocol.StatusWarning, CurrentLag: 20, Complete: 1.0, End: &protocol.ConsumerOffset{Offset: 100}},
+		}},
+		"app-3": {Status: protocol.StatusOK, TotalLag: 500, Partitions: []*protocol.PartitionStatus{
+			{Topic: "testtopic", Partition: 0, Status: protocol.StatusOK, CurrentLag: 500, Complete: 1.0, End: &protocol.ConsumerOffset{Offset: 80}},
+		}},
+		"quiet": {Status: protocol.StatusOK, TotalLag: 5},
+	}
+
+	// Respond to the expected storage requests
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		request.Reply <- []string{"aggcluster"}
+		close(request.Reply)
+
+		request = <-coordinator.App.StorageChannel
+		request.Reply <- []string{"app-1", "app-2", "app-3", "quiet"}
+		close(request.Reply)
+
+		request = <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchTopics, request.RequestType, "Expected request of type StorageFetchTopics, not %v", request.RequestType)
+		request.Reply <- []string{}
+		close(request.Reply)
+
+		request = <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchEndOffsets, request.RequestType, "Expected request of type StorageFetchEndOffsets, not %v", request.RequestType)
+		request.Reply <- []*protocol.EndOffset{}
+		close(request.Reply)
+	}()
+
+	// Respond to the expected evaluator requests
+	go func() {
+		for i := 0; i < len(groups); i++ {
+			request := <-coordinator.App.EvaluatorChannel
+			request.Reply <- groups[request.Group]
+			close(request.Reply)
+		}
+	}()
+
+	req, err := http.NewRequest("GET", "/metrics", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// app-1 is left out for having too little lag, and the other app groups are summed, with the worst status
+	promExp := rr.Body.String()
+	assert.Contains(t, promExp, `burrow_kafka_consumer_lag_total{cluster="aggcluster",consumer_group="app"} 520`)
+	assert.Contains(t, promExp, `burrow_kafka_consumer_status{cluster="aggcluster",consumer_group="app"} 2`)
+	assert.Contains(t, promExp, `burrow_kafka_consumer_partition_lag{cluster="aggcluster",consumer_group="app",partition="0",topic="testtopic"} 520`)
+	assert.Contains(t, promExp, `burrow_kafka_consumer_current_offset{cluster="aggcluster",consumer_group="app",partition="0",topic="testtopic"} 100`)
+	assert.NotContains(t, promExp, `consumer_group="app-`)
+	assert.NotContains(t, promExp, `consumer_group="quiet"`)
+}
@@ -0,0 +1,48 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/spf13/viper"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// requestMinLag returns the minimum lag for the partitions and groups in a response. This is the general api-min-lag
+// config, unless the request has a "min-lag" query parameter, which overrides it (so "min-lag=0" returns everything).
+// It returns false if the parameter is not a valid number.
+func requestMinLag(r *http.Request) (uint64, bool) {
+	param := r.URL.Query().Get("min-lag")
+	if param == "" {
+		return viper.GetUint64("general.api-min-lag"), true
+	}
+	minLag, err := strconv.ParseUint(param, 10, 64)
+	return minLag, err == nil
+}
+
+// filterPartitionsByLag returns a copy of the status without the partitions that are OK and have less than minLag lag.
+// Partitions that are not OK are always kept, so that a problem is never hidden. The totals for the group are not
+// changed. The status itself is shared with the evaluator cache, so it cannot be modified.
+func filterPartitionsByLag(status *protocol.ConsumerGroupStatus, minLag uint64) *protocol.ConsumerGroupStatus {
+	if minLag == 0 {
+		return status
+	}
+	filtered := *status
+	filtered.Partitions = make([]*protocol.PartitionStatus, 0, len(status.Partitions))
+	for _, partition := range status.Partitions {
+		if (partition.Status != protocol.StatusOK) || (partition.CurrentLag >= minLag) {
+			filtered.Partitions = append(filtered.Partitions, partition)
+		}
+	}
+	return &filtered
+}
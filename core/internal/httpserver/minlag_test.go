@@ -0,0 +1,67 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func TestRequestMinLag(t *testing.T) {
+	viper.Reset()
+	viper.Set("general.api-min-lag", 100)
+
+	testCases := []struct {
+		query    string
+		expected uint64
+		ok       bool
+	}{
+		{"", 100, true},
+		{"?min-lag=0", 0, true},
+		{"?min-lag=250", 250, true},
+		{"?min-lag=-1", 0, false},
+		{"?min-lag=lots", 0, false},
+	}
+	for _, testCase := range testCases {
+		req, err := http.NewRequest("GET", "/v3/kafka/testcluster/top"+testCase.query, http.NoBody)
+		assert.NoError(t, err, "Expected request setup to return no error")
+		minLag, ok := requestMinLag(req)
+		assert.Equalf(t, testCase.ok, ok, "Unexpected result for query '%v'", testCase.query)
+		if testCase.ok {
+			assert.Equalf(t, testCase.expected, minLag, "Unexpected min-lag for query '%v'", testCase.query)
+		}
+	}
+}
+
+func TestFilterPartitionsByLag(t *testing.T) {
+	status := &protocol.ConsumerGroupStatus{
+		Status:   protocol.StatusWarning,
+		TotalLag: 215,
+		Partitions: []*protocol.PartitionStatus{
+			{Topic: "testtopic", Partition: 0, Status: protocol.StatusOK, CurrentLag: 5},
+			{Topic: "testtopic", Partition: 1, Status: protocol.StatusOK, CurrentLag: 200},
+			{Topic: "testtopic", Partition: 2, Status: protocol.StatusWarning, CurrentLag: 10},
+		},
+	}
+
+	filtered := filterPartitionsByLag(status, 100)
+	assert.Len(t, filtered.Partitions, 2, "Expected the OK partition with low lag to be left out")
+	assert.Equal(t, int32(1), filtered.Partitions[0].Partition)
+	assert.Equal(t, int32(2), filtered.Partitions[1].Partition, "Expected a partition that is not OK to be kept")
+	assert.Equal(t, uint64(215), filtered.TotalLag, "Expected the group totals to be unchanged")
+	assert.Len(t, status.Partitions, 3, "Expected the original status to be unchanged")
+
+	assert.Same(t, status, filterPartitionsByLag(status, 0), "Expected no copy with no minimum")
+}
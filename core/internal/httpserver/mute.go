@@ -0,0 +1,108 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/viper"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func (hc *Coordinator) handleMuteList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// Fetch the muted groups from the storage module
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchMutedGroups,
+		Cluster:     params.ByName("cluster"),
+		Reply:       make(chan interface{}),
+	}
+	hc.App.StorageChannel <- request
+	response := <-request.Reply
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseMuteList{
+		Error:   false,
+		Message: "muted consumer groups returned",
+		Muted:   response.(map[string]int64),
+		Request: requestInfo,
+	})
+}
+
+// handleMuteSet mutes a group, so that the evaluator returns a MUTED status for it and notifiers skip it. The body is
+// optional. If it has a duration (in seconds), the mute expires after that long. Otherwise, it lasts until the group is
+// unmuted.
+func (hc *Coordinator) handleMuteSet(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if !viper.IsSet("cluster." + params.ByName("cluster")) {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+
+	// Decode the JSON body, if there is one
+	var req muteRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if (err != nil) && (!errors.Is(err, io.EOF)) {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "could not decode message body")
+		return
+	}
+	r.Body.Close()
+	if req.Duration < 0 {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "duration must not be negative")
+		return
+	}
+
+	var expires int64
+	if req.Duration > 0 {
+		expires = time.Now().Add(time.Duration(req.Duration)*time.Second).UnixNano() / int64(time.Millisecond)
+	}
+	hc.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetMuteGroup,
+		Cluster:     params.ByName("cluster"),
+		Group:       params.ByName("consumer"),
+		Timestamp:   expires,
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseMuteSet{
+		Error:   false,
+		Message: "consumer group muted",
+		Expires: expires,
+		Request: requestInfo,
+	})
+}
+
+func (hc *Coordinator) handleMuteDelete(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if !viper.IsSet("cluster." + params.ByName("cluster")) {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+
+	hc.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetUnmuteGroup,
+		Cluster:     params.ByName("cluster"),
+		Group:       params.ByName("consumer"),
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseError{
+		Error:   false,
+		Message: "consumer group unmuted",
+		Request: requestInfo,
+	})
+}
@@ -0,0 +1,158 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func TestHttpServer_handleMuteList(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage request
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchMutedGroups, request.RequestType, "Expected request of type StorageFetchMutedGroups, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		request.Reply <- map[string]int64{"testgroup": 0, "othergroup": 1234}
+		close(request.Reply)
+	}()
+
+	// Set up a request
+	req, err := http.NewRequest("GET", "/v3/admin/mute/testcluster", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+
+	// Call the handler via httprouter
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Parse response body
+	decoder := json.NewDecoder(rr.Body)
+	var resp httpResponseMuteList
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equalf(t, map[string]int64{"testgroup": 0, "othergroup": 1234}, resp.Muted, "Expected muted groups to be returned, not %v", resp.Muted)
+
+	// Respond to the expected storage request for a missing cluster
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		close(request.Reply)
+	}()
+
+	req, err = http.NewRequest("GET", "/v3/admin/mute/nocluster", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleMuteSet(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	viper.Set("cluster.testcluster.class-name", "kafka")
+
+	testCases := []struct {
+		body    string
+		expires bool
+	}{
+		{"", false},
+		{"{}", false},
+		{"{\"duration\": 3600}", true},
+	}
+
+	for i, testCase := range testCases {
+		// Respond to the expected storage request
+		requestChannel := make(chan *protocol.StorageRequest, 1)
+		go func() {
+			requestChannel <- <-coordinator.App.StorageChannel
+		}()
+
+		req, err := http.NewRequest("POST", "/v3/admin/mute/testcluster/testgroup", strings.NewReader(testCase.body))
+		assert.NoError(t, err, "Expected request setup to return no error")
+		rr := httptest.NewRecorder()
+		coordinator.router.ServeHTTP(rr, req)
+		assert.Equalf(t, http.StatusOK, rr.Code, "TEST %v: Expected response code to be 200, not %v", i, rr.Code)
+
+		request := <-requestChannel
+		assert.Equalf(t, protocol.StorageSetMuteGroup, request.RequestType, "TEST %v: Expected request of type StorageSetMuteGroup, not %v", i, request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "TEST %v: Expected request Cluster to be testcluster, not %v", i, request.Cluster)
+		assert.Equalf(t, "testgroup", request.Group, "TEST %v: Expected request Group to be testgroup, not %v", i, request.Group)
+
+		decoder := json.NewDecoder(rr.Body)
+		var resp httpResponseMuteSet
+		err = decoder.Decode(&resp)
+		assert.NoError(t, err, "Expected body decode to return no error")
+		assert.Equalf(t, request.Timestamp, resp.Expires, "TEST %v: Expected response expiry to match the request", i)
+		if testCase.expires {
+			expected := time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond)
+			assert.InDeltaf(t, expected, request.Timestamp, 5000, "TEST %v: Expected expiry to be an hour from now, not %v", i, request.Timestamp)
+		} else {
+			assert.Equalf(t, int64(0), request.Timestamp, "TEST %v: Expected no expiry, not %v", i, request.Timestamp)
+		}
+	}
+}
+
+func TestHttpServer_handleMuteSet_BadRequest(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	viper.Set("cluster.testcluster.class-name", "kafka")
+
+	testCases := []struct {
+		path     string
+		body     string
+		expected int
+	}{
+		{"/v3/admin/mute/nocluster/testgroup", "", http.StatusNotFound},
+		{"/v3/admin/mute/testcluster/testgroup", "notjson", http.StatusBadRequest},
+		{"/v3/admin/mute/testcluster/testgroup", "{\"duration\": -1}", http.StatusBadRequest},
+	}
+
+	for i, testCase := range testCases {
+		req, err := http.NewRequest("POST", testCase.path, strings.NewReader(testCase.body))
+		assert.NoError(t, err, "Expected request setup to return no error")
+		rr := httptest.NewRecorder()
+		coordinator.router.ServeHTTP(rr, req)
+		assert.Equalf(t, testCase.expected, rr.Code, "TEST %v: Expected response code to be %v, not %v", i, testCase.expected, rr.Code)
+	}
+}
+
+func TestHttpServer_handleMuteDelete(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	viper.Set("cluster.testcluster.class-name", "kafka")
+
+	// Respond to the expected storage request
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageSetUnmuteGroup, request.RequestType, "Expected request of type StorageSetUnmuteGroup, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.Equalf(t, "testgroup", request.Group, "Expected request Group to be testgroup, not %v", request.Group)
+		// No response expected
+	}()
+
+	req, err := http.NewRequest("DELETE", "/v3/admin/mute/testcluster/testgroup", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Sleep briefly just to catch the goroutine above throwing a failure
+	time.Sleep(100 * time.Millisecond)
+}
@@ -2,8 +2,12 @@ package httpserver
 
 import (
 	"net/http"
+	"sort"
 	"strconv"
+	"sync"
+	"time"
 
+	"github.com/IBM/sarama"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/linkedin/Burrow/core/protocol"
@@ -29,6 +33,22 @@ var (
 		[]string{"cluster", "consumer_group"},
 	)
 
+	consumerActivePartitionsGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_consumer_active_partitions",
+			Help: "The number of partitions the group has committed offsets for within the evaluator active-partition-window",
+		},
+		[]string{"cluster", "consumer_group"},
+	)
+
+	consumerActivePartitionChangeCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "burrow_kafka_consumer_active_partition_changes_total",
+			Help: "Number of times the number of active partitions for the group changed by at least the evaluator active-partition-change threshold",
+		},
+		[]string{"cluster", "consumer_group"},
+	)
+
 	partitionStatusGauge = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "burrow_kafka_topic_partition_status",
@@ -60,17 +80,447 @@ var (
 		},
 		[]string{"cluster", "topic", "partition"},
 	)
+
+	topicPartitionOffsetAgeGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_topic_partition_offset_age_seconds",
+			Help: "Time since the latest offset the topic that Burrow is storing for this partition was fetched",
+		},
+		[]string{"cluster", "topic", "partition"},
+	)
+
+	clusterStalePartitionsGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_cluster_stale_partitions",
+			Help: "Number of partitions whose latest offset has not been fetched within stale-offset-intervals offset refreshes",
+		},
+		[]string{"cluster"},
+	)
+
+	consumerEvaluationDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "burrow_evaluator_consumer_evaluation_seconds",
+			Help:    "Time taken by the evaluator to compute the status of the consumer group, including fetching its offsets from storage",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 2, 16),
+		},
+		[]string{"cluster", "consumer_group"},
+	)
+
+	clusterKafkaVersionGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_cluster_version_info",
+			Help: "The Kafka protocol version that Burrow's client for the cluster is using. The value is always 1",
+		},
+		[]string{"cluster", "version"},
+	)
+
+	clusterServerSetGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_cluster_server_set_info",
+			Help: "The server set that Burrow's client for the cluster is connected to, for clusters with more than one. The value is always 1",
+		},
+		[]string{"cluster", "server_set"},
+	)
+
+	clusterMetadataStateGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_cluster_metadata_state_info",
+			Help: "The result of the cluster module's last metadata refresh: \"ok\", \"empty\" (the cluster has no topics), or \"failed\". The value is always 1",
+		},
+		[]string{"cluster", "state"},
+	)
+
+	clusterFailedGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_cluster_failed",
+			Help: "Set to 1 for a cluster whose cluster or consumer modules failed to configure or start, and were skipped",
+		},
+		[]string{"cluster"},
+	)
+
+	metadataRefreshForcedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "burrow_kafka_cluster_metadata_refresh_forced_total",
+			Help: "The number of metadata refreshes forced outside of the regular topic refresh, by reason",
+		},
+		[]string{"cluster", "reason"},
+	)
+
+	topicDiscoveredCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "burrow_kafka_cluster_topics_discovered_total",
+			Help: "The number of topics that a cluster module found in a metadata refresh that were not in the previous one",
+		},
+		[]string{"cluster"},
+	)
+
+	httpRateLimitedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "burrow_http_requests_rate_limited_total",
+			Help: "The number of HTTP requests refused for being over the rate limit for the listener, by limit (client or global)",
+		},
+		[]string{"listener", "limit"},
+	)
+
+	clusterClockSkewGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_cluster_clock_skew_seconds",
+			Help: "The estimated number of seconds that Burrow's clock is ahead of the brokers' clocks (negative if it is behind)",
+		},
+		[]string{"cluster"},
+	)
+
+	lagSamplesDroppedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "burrow_storage_lag_samples_dropped_total",
+			Help: "The number of lag samples dropped for a lag sink because its queue was full",
+		},
+		[]string{"sink"},
+	)
+
+	endOffsetRegressionCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "burrow_storage_end_offset_regressions_total",
+			Help: "The number of end offsets that were lower than the last one stored for the partition, by action (flagged or rejected)",
+		},
+		[]string{"cluster", "action"},
+	)
+
+	offsetRefreshDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "burrow_kafka_cluster_offset_refresh_seconds",
+			Help:    "Time taken by a cluster module to refresh the broker offsets, including any metadata refresh",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+		},
+		[]string{"cluster"},
+	)
+
+	offsetRefreshIntervalGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_cluster_offset_refresh_interval_seconds",
+			Help: "The configured offset-refresh interval for the cluster. A refresh that takes longer than this is falling behind",
+		},
+		[]string{"cluster"},
+	)
+
+	offsetFetchErrorsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "burrow_kafka_cluster_offset_fetch_errors_total",
+			Help: "The number of offset requests to a broker that failed after all of their retries, by broker ID",
+		},
+		[]string{"cluster", "broker"},
+	)
+
+	partitionOffsetErrorsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "burrow_kafka_cluster_partition_offset_errors_total",
+			Help: "The number of partitions that a broker returned an error for in an offset response, by the kind of error (no_leader, unknown_partition, out_of_range, timeout, or other)",
+		},
+		[]string{"cluster", "error"},
+	)
+
+	clusterTopicsGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_cluster_topics",
+			Help: "The number of topics that the cluster module is fetching offsets for",
+		},
+		[]string{"cluster"},
+	)
+
+	clusterPartitionsGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_cluster_partitions",
+			Help: "The number of partitions, of the topics that the cluster module is fetching offsets for",
+		},
+		[]string{"cluster"},
+	)
+
+	brokerLeaderPartitionsGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_cluster_broker_leader_partitions",
+			Help: "The number of partitions, of the topics that the cluster module is fetching offsets for, that the broker leads",
+		},
+		[]string{"cluster", "broker"},
+	)
+
+	leaderPartitionShareGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "burrow_kafka_cluster_max_broker_partition_share",
+			Help: "The largest share (0 to 1) of the partitions with a leader, of the topics that the cluster module is fetching offsets for, that any one broker leads",
+		},
+		[]string{"cluster"},
+	)
+)
+
+// The Kafka version each cluster module's client is using, for the cluster detail
+var (
+	clusterVersionsLock sync.RWMutex
+	clusterVersions     = make(map[string]string)
+)
+
+// SetClusterKafkaVersion records the Kafka version that a cluster module's client connected with, which is shown in the
+// cluster detail and the burrow_kafka_cluster_version_info metric
+func SetClusterKafkaVersion(cluster, version string) {
+	clusterVersionsLock.Lock()
+	defer clusterVersionsLock.Unlock()
+
+	clusterVersions[cluster] = version
+	clusterKafkaVersionGauge.DeletePartialMatch(map[string]string{"cluster": cluster})
+	clusterKafkaVersionGauge.With(map[string]string{
+		"cluster": cluster,
+		"version": version,
+	}).Set(1)
+}
+
+func getClusterKafkaVersion(cluster string) string {
+	clusterVersionsLock.RLock()
+	defer clusterVersionsLock.RUnlock()
+	return clusterVersions[cluster]
+}
+
+// The server set each cluster module's client is connected to, for clusters that have more than one
+var (
+	clusterServerSetsLock sync.RWMutex
+	clusterServerSets     = make(map[string]clusterServerSet)
+)
+
+type clusterServerSet struct {
+	name    string
+	servers []string
+}
+
+// SetClusterServerSet records the server set that a cluster module's client is connected to, which is shown in the
+// cluster detail and the burrow_kafka_cluster_server_set_info metric
+func SetClusterServerSet(cluster, name string, servers []string) {
+	clusterServerSetsLock.Lock()
+	defer clusterServerSetsLock.Unlock()
+
+	clusterServerSets[cluster] = clusterServerSet{name: name, servers: servers}
+	clusterServerSetGauge.DeletePartialMatch(map[string]string{"cluster": cluster})
+	clusterServerSetGauge.With(map[string]string{
+		"cluster":    cluster,
+		"server_set": name,
+	}).Set(1)
+}
+
+func getClusterServerSet(cluster string) (string, []string) {
+	clusterServerSetsLock.RLock()
+	defer clusterServerSetsLock.RUnlock()
+	set := clusterServerSets[cluster]
+	return set.name, set.servers
+}
+
+// The results of the last metadata refresh of each cluster module, for the cluster detail
+const (
+	// ClusterMetadataOK is the metadata state of a cluster whose last metadata refresh returned topics
+	ClusterMetadataOK = "ok"
+
+	// ClusterMetadataEmpty is the metadata state of a cluster whose last metadata refresh succeeded, but returned no
+	// topics at all, such as a new cluster that is still being set up
+	ClusterMetadataEmpty = "empty"
+
+	// ClusterMetadataFailed is the metadata state of a cluster whose last metadata refresh failed
+	ClusterMetadataFailed = "failed"
+)
+
+var (
+	clusterMetadataStatesLock sync.RWMutex
+	clusterMetadataStates     = make(map[string]string)
+)
+
+// SetClusterMetadataState records the result of a cluster module's last metadata refresh (one of the ClusterMetadata
+// states), which is shown in the cluster detail and the burrow_kafka_cluster_metadata_state_info metric. This tells a
+// cluster with no topics apart from one whose metadata cannot be fetched, which otherwise both have nothing to show.
+func SetClusterMetadataState(cluster, state string) {
+	clusterMetadataStatesLock.Lock()
+	defer clusterMetadataStatesLock.Unlock()
+
+	clusterMetadataStates[cluster] = state
+	clusterMetadataStateGauge.DeletePartialMatch(map[string]string{"cluster": cluster})
+	clusterMetadataStateGauge.With(map[string]string{
+		"cluster": cluster,
+		"state":   state,
+	}).Set(1)
+}
+
+func getClusterMetadataState(cluster string) string {
+	clusterMetadataStatesLock.RLock()
+	defer clusterMetadataStatesLock.RUnlock()
+	return clusterMetadataStates[cluster]
+}
+
+// The errors for the clusters whose modules failed and were skipped, for the cluster list and detail
+var (
+	clusterFailuresLock sync.RWMutex
+	clusterFailures     = make(map[string][]string)
 )
 
-// DeleteConsumerMetrics deletes all metrics that are labeled with a consumer group
+// SetClusterFailed records an error from a cluster or consumer module for the cluster that failed to configure or start,
+// and was skipped so that the rest of Burrow could run. The errors are shown in the cluster list and detail, and the
+// cluster is marked in the burrow_kafka_cluster_failed metric.
+func SetClusterFailed(cluster, message string) {
+	clusterFailuresLock.Lock()
+	defer clusterFailuresLock.Unlock()
+
+	clusterFailures[cluster] = append(clusterFailures[cluster], message)
+	clusterFailedGauge.With(map[string]string{"cluster": cluster}).Set(1)
+}
+
+func getClusterFailures(cluster string) []string {
+	clusterFailuresLock.RLock()
+	defer clusterFailuresLock.RUnlock()
+	return clusterFailures[cluster]
+}
+
+func getFailedClusters() []string {
+	clusterFailuresLock.RLock()
+	defer clusterFailuresLock.RUnlock()
+
+	clusters := make([]string, 0, len(clusterFailures))
+	for cluster := range clusterFailures {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	return clusters
+}
+
+// IncMetadataRefreshForced counts a metadata refresh that a cluster module forced outside of its regular topic refresh
+func IncMetadataRefreshForced(cluster, reason string) {
+	metadataRefreshForcedCounter.With(map[string]string{
+		"cluster": cluster,
+		"reason":  reason,
+	}).Inc()
+}
+
+// IncTopicDiscovered counts a topic that a cluster module found for the first time since it started
+func IncTopicDiscovered(cluster string) {
+	topicDiscoveredCounter.With(map[string]string{"cluster": cluster}).Inc()
+}
+
+// SetClusterClockSkew records the estimated skew between Burrow's clock and the brokers' clocks for a cluster
+func SetClusterClockSkew(cluster string, skew time.Duration) {
+	clusterClockSkewGauge.With(map[string]string{"cluster": cluster}).Set(skew.Seconds())
+}
+
+// CountRateLimitedRequest counts an HTTP request that a listener refused for being over one of its rate limits
+func CountRateLimitedRequest(listener, limit string) {
+	httpRateLimitedCounter.With(map[string]string{
+		"listener": listener,
+		"limit":    limit,
+	}).Inc()
+}
+
+// CountDroppedLagSample counts a lag sample that storage dropped because the queue for the sink was full
+func CountDroppedLagSample(sink string) {
+	lagSamplesDroppedCounter.With(map[string]string{
+		"sink": sink,
+	}).Inc()
+}
+
+// CountEndOffsetRegression counts an end offset that storage flagged or rejected for being lower than the last one
+// stored for the partition
+func CountEndOffsetRegression(cluster, action string) {
+	endOffsetRegressionCounter.With(map[string]string{
+		"cluster": cluster,
+		"action":  action,
+	}).Inc()
+}
+
+// ObserveOffsetRefresh records how long a cluster module took to refresh the broker offsets
+func ObserveOffsetRefresh(cluster string, duration time.Duration) {
+	offsetRefreshDuration.With(map[string]string{"cluster": cluster}).Observe(duration.Seconds())
+}
+
+// SetOffsetRefreshInterval records the offset-refresh interval for a cluster, so that the refresh durations can be
+// compared against it
+func SetOffsetRefreshInterval(cluster string, interval time.Duration) {
+	offsetRefreshIntervalGauge.With(map[string]string{"cluster": cluster}).Set(interval.Seconds())
+}
+
+// CountOffsetFetchError counts an offset request to a broker that failed after all of its retries
+func CountOffsetFetchError(cluster string, broker int32) {
+	offsetFetchErrorsCounter.With(map[string]string{
+		"cluster": cluster,
+		"broker":  strconv.FormatInt(int64(broker), 10),
+	}).Inc()
+}
+
+// CountPartitionOffsetError counts a partition that a broker returned an error for in an offset response. The error
+// label is one of a short, fixed set of kinds of error, rather than the error's text, so that it is stable across
+// sarama versions.
+func CountPartitionOffsetError(cluster string, err error) {
+	partitionOffsetErrorsCounter.With(map[string]string{
+		"cluster": cluster,
+		"error":   partitionOffsetErrorLabel(err),
+	}).Inc()
+}
+
+// partitionOffsetErrorLabel returns the kind of an error in an offset response, for the error label
+func partitionOffsetErrorLabel(err error) string {
+	switch err {
+	case sarama.ErrNotLeaderForPartition, sarama.ErrLeaderNotAvailable, sarama.ErrFencedLeaderEpoch, sarama.ErrUnknownLeaderEpoch:
+		return "no_leader"
+	case sarama.ErrUnknownTopicOrPartition:
+		return "unknown_partition"
+	case sarama.ErrOffsetOutOfRange:
+		return "out_of_range"
+	case sarama.ErrRequestTimedOut:
+		return "timeout"
+	default:
+		return "other"
+	}
+}
+
+// SetClusterTrackedPartitions records the number of topics, and their partitions, that a cluster module is fetching
+// offsets for
+func SetClusterTrackedPartitions(cluster string, topics, partitions int) {
+	clusterTopicsGauge.With(map[string]string{"cluster": cluster}).Set(float64(topics))
+	clusterPartitionsGauge.With(map[string]string{"cluster": cluster}).Set(float64(partitions))
+}
+
+// SetClusterLeadership records the number of tracked partitions that each broker of a cluster leads, and the largest
+// share of them that any one broker leads. The brokers that no longer lead any partitions are removed.
+func SetClusterLeadership(cluster string, leaderPartitions map[int32]int, maxShare float64) {
+	brokerLeaderPartitionsGauge.DeletePartialMatch(map[string]string{"cluster": cluster})
+	for broker, partitions := range leaderPartitions {
+		brokerLeaderPartitionsGauge.With(map[string]string{
+			"cluster": cluster,
+			"broker":  strconv.FormatInt(int64(broker), 10),
+		}).Set(float64(partitions))
+	}
+	leaderPartitionShareGauge.With(map[string]string{"cluster": cluster}).Set(maxShare)
+}
+
+// ObserveConsumerEvaluation records how long the evaluator took to compute the status of a consumer group
+func ObserveConsumerEvaluation(cluster, consumer string, duration time.Duration) {
+	consumerEvaluationDuration.With(map[string]string{
+		"cluster":        cluster,
+		"consumer_group": metricLabels.group(consumer),
+	}).Observe(duration.Seconds())
+}
+
+// CountActivePartitionChange records that the number of active partitions for a consumer group changed by more than the
+// evaluator threshold
+func CountActivePartitionChange(cluster, consumer string) {
+	consumerActivePartitionChangeCounter.With(map[string]string{
+		"cluster":        cluster,
+		"consumer_group": metricLabels.group(consumer),
+	}).Inc()
+}
+
+// DeleteConsumerMetrics deletes all metrics that are labeled with a consumer group. If the group shares its label value
+// with other groups, the series for all of them are deleted, and are set again on the next scrape.
 func DeleteConsumerMetrics(cluster, consumer string) {
 	labels := map[string]string{
 		"cluster":        cluster,
-		"consumer_group": consumer,
+		"consumer_group": metricLabels.group(consumer),
 	}
 
 	consumerTotalLagGauge.Delete(labels)
 	consumerStatusGauge.Delete(labels)
+	consumerActivePartitionsGauge.Delete(labels)
+	consumerActivePartitionChangeCounter.Delete(labels)
+	consumerEvaluationDuration.Delete(labels)
 	consumerPartitionLagGauge.DeletePartialMatch(labels)
 	consumerPartitionCurrentOffset.DeletePartialMatch(labels)
 	partitionStatusGauge.DeletePartialMatch(labels)
@@ -80,11 +530,12 @@ func DeleteConsumerMetrics(cluster, consumer string) {
 func DeleteTopicMetrics(cluster, topic string) {
 	labels := map[string]string{
 		"cluster": cluster,
-		"topic":   topic,
+		"topic":   metricLabels.topic(topic),
 	}
 
 	partitionStatusGauge.DeletePartialMatch(labels)
 	topicPartitionOffsetGauge.DeletePartialMatch(labels)
+	topicPartitionOffsetAgeGauge.DeletePartialMatch(labels)
 
 	// If a topic is deleted there cannot be any consumers, so delete all consumer metrics too
 	// Not strictly necessary as Kafka will delete the consumer groups, which will eventually trigger DeleteConsumerMetrics
@@ -92,14 +543,32 @@ func DeleteTopicMetrics(cluster, topic string) {
 	consumerPartitionCurrentOffset.DeletePartialMatch(labels)
 	consumerTotalLagGauge.DeletePartialMatch(labels)
 	consumerStatusGauge.DeletePartialMatch(labels)
+	consumerActivePartitionsGauge.DeletePartialMatch(labels)
+	consumerActivePartitionChangeCounter.DeletePartialMatch(labels)
+	consumerEvaluationDuration.DeletePartialMatch(labels)
+}
+
+// DeletePartitionMetrics deletes all metrics that are labeled with a single partition of a topic
+func DeletePartitionMetrics(cluster, topic string, partition int32) {
+	labels := map[string]string{
+		"cluster":   cluster,
+		"topic":     metricLabels.topic(topic),
+		"partition": strconv.FormatInt(int64(partition), 10),
+	}
+
+	partitionStatusGauge.DeletePartialMatch(labels)
+	topicPartitionOffsetGauge.DeletePartialMatch(labels)
+	topicPartitionOffsetAgeGauge.DeletePartialMatch(labels)
+	consumerPartitionLagGauge.DeletePartialMatch(labels)
+	consumerPartitionCurrentOffset.DeletePartialMatch(labels)
 }
 
 // DeleteConsumerTopicMetrics deletes all metrics that are labeled with the provided consumer group AND topic
 func DeleteConsumerTopicMetrics(cluster, consumer, topic string) {
 	labels := map[string]string{
 		"cluster":        cluster,
-		"consumer_group": consumer,
-		"topic":          topic,
+		"consumer_group": metricLabels.group(consumer),
+		"topic":          metricLabels.topic(topic),
 	}
 
 	partitionStatusGauge.DeletePartialMatch(labels)
@@ -107,54 +576,178 @@ func DeleteConsumerTopicMetrics(cluster, consumer, topic string) {
 	consumerPartitionLagGauge.DeletePartialMatch(labels)
 }
 
+// consumerSeries and partitionSeries hold the values for a single consumer group series (and a single consumer group
+// partition series) while they are aggregated over all the groups and topics that have the same label values
+type consumerSeries struct {
+	totalLag         uint64
+	status           protocol.StatusConstant
+	activePartitions int
+}
+
+type partitionKey struct {
+	group     string
+	topic     string
+	partition int32
+}
+
+type partitionSeries struct {
+	lag      uint64
+	complete bool
+	offset   int64
+	status   protocol.StatusConstant
+}
+
+type topicPartitionKey struct {
+	topic     string
+	partition int32
+}
+
 func (hc *Coordinator) handlePrometheusMetrics() http.HandlerFunc {
-	promHandler := promhttp.Handler()
+	// This is the same as promhttp.Handler(), with the constant labels for each cluster added to its metrics
+	promHandler := promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(clusterLabelGatherer{gatherer: prometheus.DefaultGatherer}, promhttp.HandlerOpts{}))
 
 	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		// A "min-lag" query parameter overrides the min-group-lag and min-partition-lag for this scrape
+		rules := metricLabels
+		if req.URL.Query().Get("min-lag") != "" {
+			minLag, ok := requestMinLag(req)
+			if !ok {
+				http.Error(resp, "min-lag must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			rules = metricLabels.withMinLag(minLag)
+		}
+
 		for _, cluster := range listClusters(hc.App) {
+			// Groups and topics that have the same label values are summed into one series, with the worst status
+			consumers := make(map[string]*consumerSeries)
+			partitions := make(map[partitionKey]*partitionSeries)
 			for _, consumer := range listConsumers(hc.App, cluster) {
 				consumerStatus := getFullConsumerStatus(hc.App, cluster, consumer)
 
 				if consumerStatus == nil ||
-					consumerStatus.Status == protocol.StatusNotFound {
+					consumerStatus.Status == protocol.StatusNotFound ||
+					!rules.emitGroup(consumerStatus) {
 					continue
 				}
 
-				labels := map[string]string{
-					"cluster":        cluster,
-					"consumer_group": consumer,
+				group := metricLabels.group(consumer)
+				series, ok := consumers[group]
+				if !ok {
+					series = &consumerSeries{}
+					consumers[group] = series
+				}
+				series.totalLag += consumerStatus.TotalLag
+				series.activePartitions += consumerStatus.ActivePartitions
+				if consumerStatus.Status > series.status {
+					series.status = consumerStatus.Status
 				}
-
-				consumerTotalLagGauge.With(labels).Set(float64(consumerStatus.TotalLag))
-				consumerStatusGauge.With(labels).Set(float64(consumerStatus.Status))
 
 				for _, partition := range consumerStatus.Partitions {
-					labels := map[string]string{
-						"cluster":        cluster,
-						"consumer_group": consumer,
-						"topic":          partition.Topic,
-						"partition":      strconv.FormatInt(int64(partition.Partition), 10),
+					if !rules.emitPartition(partition) {
+						continue
 					}
-
-					consumerPartitionLagGauge.With(labels).Set(float64(partition.CurrentLag))
+					key := partitionKey{group: group, topic: metricLabels.topic(partition.Topic), partition: partition.Partition}
+					partSeries, ok := partitions[key]
+					if !ok {
+						partSeries = &partitionSeries{}
+						partitions[key] = partSeries
+					}
+					partSeries.lag += partition.CurrentLag
 
 					if partition.Complete == 1.0 {
-						consumerPartitionCurrentOffset.With(labels).Set(float64(partition.End.Offset))
-						partitionStatusGauge.With(labels).Set(float64(partition.Status))
+						partSeries.complete = true
+						if partition.End.Offset > partSeries.offset {
+							partSeries.offset = partition.End.Offset
+						}
+						if partition.Status > partSeries.status {
+							partSeries.status = partition.Status
+						}
 					}
 				}
 			}
 
+			// If groups can be aggregated or left out, a series may no longer have any group behind it, so all of the
+			// series for the cluster are rebuilt, as they are when a scrape overrides the lag thresholds
+			if metricLabels.aggregates() || rules.aggregates() {
+				labels := map[string]string{"cluster": cluster}
+				consumerTotalLagGauge.DeletePartialMatch(labels)
+				consumerStatusGauge.DeletePartialMatch(labels)
+				consumerActivePartitionsGauge.DeletePartialMatch(labels)
+				consumerPartitionLagGauge.DeletePartialMatch(labels)
+				consumerPartitionCurrentOffset.DeletePartialMatch(labels)
+				partitionStatusGauge.DeletePartialMatch(labels)
+				topicPartitionOffsetGauge.DeletePartialMatch(labels)
+				topicPartitionOffsetAgeGauge.DeletePartialMatch(labels)
+			}
+
+			for group, series := range consumers {
+				labels := map[string]string{
+					"cluster":        cluster,
+					"consumer_group": group,
+				}
+
+				consumerTotalLagGauge.With(labels).Set(float64(series.totalLag))
+				consumerStatusGauge.With(labels).Set(float64(series.status))
+				consumerActivePartitionsGauge.With(labels).Set(float64(series.activePartitions))
+			}
+
+			for key, series := range partitions {
+				labels := map[string]string{
+					"cluster":        cluster,
+					"consumer_group": key.group,
+					"topic":          key.topic,
+					"partition":      strconv.FormatInt(int64(key.partition), 10),
+				}
+
+				consumerPartitionLagGauge.With(labels).Set(float64(series.lag))
+
+				if series.complete {
+					consumerPartitionCurrentOffset.With(labels).Set(float64(series.offset))
+					partitionStatusGauge.With(labels).Set(float64(series.status))
+				}
+			}
+
 			// Topics
+			topicOffsets := make(map[topicPartitionKey]int64)
 			for _, topic := range listTopics(hc.App, cluster) {
 				for partitionNumber, offset := range getTopicDetail(hc.App, cluster, topic) {
-					topicPartitionOffsetGauge.With(map[string]string{
-						"cluster":   cluster,
-						"topic":     topic,
-						"partition": strconv.FormatInt(int64(partitionNumber), 10),
-					}).Set(float64(offset))
+					topicOffsets[topicPartitionKey{topic: metricLabels.topic(topic), partition: int32(partitionNumber)}] += offset
+				}
+			}
+			for key, offset := range topicOffsets {
+				topicPartitionOffsetGauge.With(map[string]string{
+					"cluster":   cluster,
+					"topic":     key.topic,
+					"partition": strconv.FormatInt(int64(key.partition), 10),
+				}).Set(float64(offset))
+			}
+
+			// End offset ages, to show partitions that are not being refreshed. Topics with the same label value show
+			// the oldest age
+			staleAge := staleEndOffsetAge(cluster)
+			staleCount := 0
+			timeNow := time.Now().UnixNano() / int64(time.Millisecond)
+			topicAges := make(map[topicPartitionKey]int64)
+			for _, endOffset := range getEndOffsets(hc.App, cluster) {
+				age := (timeNow - endOffset.Timestamp) / 1000
+				if age > staleAge {
+					staleCount++
+				}
+				key := topicPartitionKey{topic: metricLabels.topic(endOffset.Topic), partition: endOffset.Partition}
+				if oldest, ok := topicAges[key]; !ok || (age > oldest) {
+					topicAges[key] = age
 				}
 			}
+			for key, age := range topicAges {
+				topicPartitionOffsetAgeGauge.With(map[string]string{
+					"cluster":   cluster,
+					"topic":     key.topic,
+					"partition": strconv.FormatInt(int64(key.partition), 10),
+				}).Set(float64(age))
+			}
+			clusterStalePartitionsGauge.With(map[string]string{"cluster": cluster}).Set(float64(staleCount))
 		}
 
 		promHandler.ServeHTTP(resp, req)
@@ -217,6 +810,21 @@ func listTopics(app *protocol.ApplicationContext, cluster string) []string {
 	return response.([]string)
 }
 
+func getEndOffsets(app *protocol.ApplicationContext, cluster string) []*protocol.EndOffset {
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchEndOffsets,
+		Cluster:     cluster,
+		Reply:       make(chan interface{}),
+	}
+	app.StorageChannel <- request
+	response := <-request.Reply
+	if response == nil {
+		return []*protocol.EndOffset{}
+	}
+
+	return response.([]*protocol.EndOffset)
+}
+
 func getTopicDetail(app *protocol.ApplicationContext, cluster, topic string) []int64 {
 	request := &protocol.StorageRequest{
 		RequestType: protocol.StorageFetchTopic,
@@ -4,7 +4,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/linkedin/Burrow/core/protocol"
@@ -12,6 +16,9 @@ import (
 
 func TestHttpServer_handlePrometheusMetrics(t *testing.T) {
 	coordinator := fixtureConfiguredCoordinator()
+	viper.Set("cluster.testcluster.offset-refresh", 10)
+	viper.Set("cluster.testcluster.stale-offset-intervals", 3)
+	timeNow := time.Now().UnixNano() / int64(time.Millisecond)
 
 	// Respond to the expected storage requests
 	go func() {
@@ -48,6 +55,17 @@ func TestHttpServer_handlePrometheusMetrics(t *testing.T) {
 		assert.Equalf(t, "testtopic1", request.Topic, "Expected request Topic to be testtopic, not %v", request.Topic)
 		request.Reply <- []int64{54}
 		close(request.Reply)
+
+		// End offsets, with one that has not been fetched in 100 seconds
+		request = <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchEndOffsets, request.RequestType, "Expected request of type StorageFetchEndOffsets, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		request.Reply <- []*protocol.EndOffset{
+			{Topic: "testtopic", Partition: 0, Offset: 6556, Timestamp: timeNow},
+			{Topic: "testtopic", Partition: 1, Offset: 5566, Timestamp: timeNow - 100000},
+			{Topic: "testtopic1", Partition: 0, Offset: 54, Timestamp: timeNow},
+		}
+		close(request.Reply)
 	}()
 
 	// Respond to the expected evaluator requests
@@ -114,9 +132,10 @@ func TestHttpServer_handlePrometheusMetrics(t *testing.T) {
 					},
 				},
 			},
-			TotalPartitions: 2134,
-			Maxlag:          &protocol.PartitionStatus{},
-			TotalLag:        2345,
+			TotalPartitions:  2134,
+			ActivePartitions: 4,
+			Maxlag:           &protocol.PartitionStatus{},
+			TotalLag:         2345,
 		}
 		request.Reply <- response
 		close(request.Reply)
@@ -148,6 +167,7 @@ func TestHttpServer_handlePrometheusMetrics(t *testing.T) {
 	promExp := rr.Body.String()
 	assert.Contains(t, promExp, `burrow_kafka_consumer_status{cluster="testcluster",consumer_group="testgroup"} 1`)
 	assert.Contains(t, promExp, `burrow_kafka_consumer_lag_total{cluster="testcluster",consumer_group="testgroup"} 2345`)
+	assert.Contains(t, promExp, `burrow_kafka_consumer_active_partitions{cluster="testcluster",consumer_group="testgroup"} 4`)
 
 	assert.Contains(t, promExp, `burrow_kafka_consumer_partition_lag{cluster="testcluster",consumer_group="testgroup",partition="0",topic="testtopic"} 100`)
 	assert.Contains(t, promExp, `burrow_kafka_consumer_partition_lag{cluster="testcluster",consumer_group="testgroup",partition="1",topic="testtopic"} 10`)
@@ -169,4 +189,127 @@ func TestHttpServer_handlePrometheusMetrics(t *testing.T) {
 
 	assert.Contains(t, promExp, `burrow_kafka_consumer_partition_lag{cluster="testcluster",consumer_group="testgroup",partition="0",topic="incomplete"} 0`)
 	assert.NotContains(t, promExp, "testgroup2")
+
+	assert.Contains(t, promExp, `burrow_kafka_topic_partition_offset_age_seconds{cluster="testcluster",partition="1",topic="testtopic"} 100`)
+	assert.Contains(t, promExp, `burrow_kafka_cluster_stale_partitions{cluster="testcluster"} 1`)
+}
+
+func TestHttpServer_ObserveConsumerEvaluation(t *testing.T) {
+	ObserveConsumerEvaluation("evalcluster", "evalgroup", 5*time.Millisecond)
+	ObserveConsumerEvaluation("evalcluster", "evalgroup", 10*time.Millisecond)
+	ObserveConsumerEvaluation("evalcluster", "evalgroup2", 10*time.Millisecond)
+	assert.Equal(t, 2, testutil.CollectAndCount(consumerEvaluationDuration, "burrow_evaluator_consumer_evaluation_seconds"))
+
+	DeleteConsumerMetrics("evalcluster", "evalgroup")
+	assert.Equal(t, 1, testutil.CollectAndCount(consumerEvaluationDuration, "burrow_evaluator_consumer_evaluation_seconds"))
+
+	DeleteTopicMetrics("evalcluster", "unknowntopic")
+	assert.Equal(t, 1, testutil.CollectAndCount(consumerEvaluationDuration, "burrow_evaluator_consumer_evaluation_seconds"))
+	DeleteConsumerMetrics("evalcluster", "evalgroup2")
+}
+
+func TestHttpServer_SetClusterKafkaVersion(t *testing.T) {
+	// Other tests may have set versions for their own clusters
+	count := testutil.CollectAndCount(clusterKafkaVersionGauge, "burrow_kafka_cluster_version_info")
+	SetClusterKafkaVersion("versioncluster", "2.1.0")
+	SetClusterKafkaVersion("versioncluster", "2.8.2")
+
+	// Only the latest version is reported for the cluster
+	assert.Equal(t, count+1, testutil.CollectAndCount(clusterKafkaVersionGauge, "burrow_kafka_cluster_version_info"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(clusterKafkaVersionGauge.With(map[string]string{"cluster": "versioncluster", "version": "2.8.2"})))
+	assert.Equal(t, "2.8.2", getClusterKafkaVersion("versioncluster"))
+}
+
+func TestHttpServer_SetClusterServerSet(t *testing.T) {
+	count := testutil.CollectAndCount(clusterServerSetGauge, "burrow_kafka_cluster_server_set_info")
+	SetClusterServerSet("setcluster", "primary", []string{"broker1:9092"})
+	SetClusterServerSet("setcluster", "secondary", []string{"broker2:9092"})
+
+	// Only the active server set is reported for the cluster
+	assert.Equal(t, count+1, testutil.CollectAndCount(clusterServerSetGauge, "burrow_kafka_cluster_server_set_info"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(clusterServerSetGauge.With(map[string]string{"cluster": "setcluster", "server_set": "secondary"})))
+	name, servers := getClusterServerSet("setcluster")
+	assert.Equal(t, "secondary", name)
+	assert.Equal(t, []string{"broker2:9092"}, servers)
+}
+
+func TestHttpServer_SetClusterMetadataState(t *testing.T) {
+	count := testutil.CollectAndCount(clusterMetadataStateGauge, "burrow_kafka_cluster_metadata_state_info")
+	SetClusterMetadataState("metadatacluster", ClusterMetadataFailed)
+	SetClusterMetadataState("metadatacluster", ClusterMetadataEmpty)
+
+	// Only the latest state is reported for the cluster
+	assert.Equal(t, count+1, testutil.CollectAndCount(clusterMetadataStateGauge, "burrow_kafka_cluster_metadata_state_info"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(clusterMetadataStateGauge.With(map[string]string{"cluster": "metadatacluster", "state": "empty"})))
+	assert.Equal(t, ClusterMetadataEmpty, getClusterMetadataState("metadatacluster"))
+	assert.Empty(t, getClusterMetadataState("testcluster"), "Expected no state for a cluster that has not refreshed metadata")
+}
+
+func TestHttpServer_SetClusterFailed(t *testing.T) {
+	SetClusterFailed("failedcluster", "cluster module failed to start: cannot connect")
+	SetClusterFailed("failedcluster", "consumer module failedconsumer failed to start: cannot connect")
+	defer func() {
+		clusterFailures = make(map[string][]string)
+		clusterFailedGauge.Reset()
+	}()
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(clusterFailedGauge.With(map[string]string{"cluster": "failedcluster"})))
+	assert.Equal(t, []string{"failedcluster"}, getFailedClusters())
+	assert.Len(t, getClusterFailures("failedcluster"), 2)
+	assert.Empty(t, getClusterFailures("testcluster"), "Expected no failures for a cluster that did not fail")
+}
+
+func TestHttpServer_SetClusterClockSkew(t *testing.T) {
+	SetClusterClockSkew("skewcluster", -1500*time.Millisecond)
+	defer clusterClockSkewGauge.Reset()
+
+	assert.Equal(t, -1.5, testutil.ToFloat64(clusterClockSkewGauge.With(map[string]string{"cluster": "skewcluster"})))
+}
+
+func TestHttpServer_OffsetRefreshMetrics(t *testing.T) {
+	defer offsetRefreshDuration.Reset()
+	defer offsetRefreshIntervalGauge.Reset()
+	defer offsetFetchErrorsCounter.Reset()
+	defer partitionOffsetErrorsCounter.Reset()
+	defer clusterTopicsGauge.Reset()
+	defer clusterPartitionsGauge.Reset()
+
+	ObserveOffsetRefresh("refreshcluster", 2*time.Second)
+	ObserveOffsetRefresh("refreshcluster", 12*time.Second)
+	SetOffsetRefreshInterval("refreshcluster", 10*time.Second)
+	CountOffsetFetchError("refreshcluster", 13)
+	CountOffsetFetchError("refreshcluster", 13)
+	CountOffsetFetchError("refreshcluster", 14)
+	CountPartitionOffsetError("refreshcluster", sarama.ErrNotLeaderForPartition)
+	CountPartitionOffsetError("refreshcluster", sarama.ErrNotLeaderForPartition)
+	CountPartitionOffsetError("refreshcluster", sarama.ErrUnknownTopicOrPartition)
+	CountPartitionOffsetError("refreshcluster", sarama.ErrLeaderNotAvailable)
+	CountPartitionOffsetError("refreshcluster", sarama.ErrInvalidMessage)
+	SetClusterTrackedPartitions("refreshcluster", 3, 24)
+
+	assert.Equal(t, 1, testutil.CollectAndCount(offsetRefreshDuration, "burrow_kafka_cluster_offset_refresh_seconds"))
+	assert.Equal(t, float64(10), testutil.ToFloat64(offsetRefreshIntervalGauge.With(map[string]string{"cluster": "refreshcluster"})))
+	assert.Equal(t, float64(2), testutil.ToFloat64(offsetFetchErrorsCounter.With(map[string]string{"cluster": "refreshcluster", "broker": "13"})))
+	assert.Equal(t, float64(1), testutil.ToFloat64(offsetFetchErrorsCounter.With(map[string]string{"cluster": "refreshcluster", "broker": "14"})))
+	assert.Equal(t, float64(3), testutil.ToFloat64(partitionOffsetErrorsCounter.With(map[string]string{"cluster": "refreshcluster", "error": "no_leader"})))
+	assert.Equal(t, float64(1), testutil.ToFloat64(partitionOffsetErrorsCounter.With(map[string]string{"cluster": "refreshcluster", "error": "unknown_partition"})))
+	assert.Equal(t, float64(1), testutil.ToFloat64(partitionOffsetErrorsCounter.With(map[string]string{"cluster": "refreshcluster", "error": "other"})))
+	assert.Equal(t, float64(3), testutil.ToFloat64(clusterTopicsGauge.With(map[string]string{"cluster": "refreshcluster"})))
+	assert.Equal(t, float64(24), testutil.ToFloat64(clusterPartitionsGauge.With(map[string]string{"cluster": "refreshcluster"})))
+}
+
+func TestHttpServer_SetClusterLeadership(t *testing.T) {
+	defer brokerLeaderPartitionsGauge.Reset()
+	defer leaderPartitionShareGauge.Reset()
+
+	SetClusterLeadership("leadercluster", map[int32]int{1: 6, 2: 2}, 0.75)
+	assert.Equal(t, 2, testutil.CollectAndCount(brokerLeaderPartitionsGauge, "burrow_kafka_cluster_broker_leader_partitions"))
+	assert.Equal(t, float64(6), testutil.ToFloat64(brokerLeaderPartitionsGauge.With(map[string]string{"cluster": "leadercluster", "broker": "1"})))
+	assert.Equal(t, 0.75, testutil.ToFloat64(leaderPartitionShareGauge.With(map[string]string{"cluster": "leadercluster"})))
+
+	// Broker 2 no longer leads any partitions
+	SetClusterLeadership("leadercluster", map[int32]int{1: 4, 3: 4}, 0.5)
+	assert.Equal(t, 2, testutil.CollectAndCount(brokerLeaderPartitionsGauge, "burrow_kafka_cluster_broker_leader_partitions"))
+	assert.Equal(t, float64(4), testutil.ToFloat64(brokerLeaderPartitionsGauge.With(map[string]string{"cluster": "leadercluster", "broker": "3"})))
+	assert.Equal(t, 0.5, testutil.ToFloat64(leaderPartitionShareGauge.With(map[string]string{"cluster": "leadercluster"})))
 }
@@ -0,0 +1,178 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// How often idle client buckets are removed, so that the buckets for clients that have gone away do not build up
+var rateLimitSweepInterval = time.Minute
+
+// tokenBucket allows rate requests per second on average, with bursts of up to burst requests. It must be used with
+// the lock of the rateLimitHandler held.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+// refill adds the tokens for the time since the last refill, up to the burst
+func (bucket *tokenBucket) refill(now time.Time) {
+	bucket.tokens += now.Sub(bucket.last).Seconds() * bucket.rate
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+	bucket.last = now
+}
+
+// take removes a token and returns true, or returns false if there are none left
+func (bucket *tokenBucket) take(now time.Time) bool {
+	bucket.refill(now)
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// rateLimitHandler refuses requests with a 429 once either the listener as a whole, or the client address that sent
+// them, is over its rate limit. Clients are identified by the remote address of the connection, so all clients behind
+// a proxy share a limit. Requests for the exempt paths (such as the health check) are never limited.
+type rateLimitHandler struct {
+	hc       *Coordinator
+	listener string
+	handler  http.Handler
+	exempt   map[string]bool
+
+	lock        sync.Mutex
+	global      *tokenBucket
+	clientRate  float64
+	clientBurst int
+	clients     map[string]*tokenBucket
+	lastSweep   time.Time
+}
+
+// newRateLimitHandler reads the rate limits for a listener from the configuration under configRoot, and returns the
+// handler wrapped with them. If neither limit is set, the handler is returned as is. A negative rate, or a burst that
+// is less than 1, will cause this func to panic.
+func newRateLimitHandler(hc *Coordinator, name, configRoot string, handler http.Handler) http.Handler {
+	viper.SetDefault(configRoot+".rate-limit-exempt", []string{"/burrow/admin"})
+	globalRate := viper.GetFloat64(configRoot + ".rate-limit")
+	clientRate := viper.GetFloat64(configRoot + ".client-rate-limit")
+	if (globalRate < 0) || (clientRate < 0) {
+		panic("HTTP server " + name + " rate-limit and client-rate-limit must be zero or greater")
+	}
+	if (globalRate == 0) && (clientRate == 0) {
+		return handler
+	}
+
+	// The bursts default to one second of requests, so that a limit below one request per second still allows one
+	viper.SetDefault(configRoot+".rate-limit-burst", int(globalRate)+1)
+	viper.SetDefault(configRoot+".client-rate-limit-burst", int(clientRate)+1)
+	globalBurst := viper.GetInt(configRoot + ".rate-limit-burst")
+	clientBurst := viper.GetInt(configRoot + ".client-rate-limit-burst")
+	if (globalBurst < 1) || (clientBurst < 1) {
+		panic("HTTP server " + name + " rate-limit-burst and client-rate-limit-burst must be at least 1")
+	}
+
+	now := time.Now()
+	limiter := &rateLimitHandler{
+		hc:         hc,
+		listener:   name,
+		handler:    handler,
+		exempt:     make(map[string]bool),
+		clientRate: clientRate,
+		clients:    make(map[string]*tokenBucket),
+		lastSweep:  now,
+	}
+	for _, path := range viper.GetStringSlice(configRoot + ".rate-limit-exempt") {
+		limiter.exempt[path] = true
+	}
+	if globalRate > 0 {
+		limiter.global = newTokenBucket(globalRate, globalBurst, now)
+	}
+	if clientRate > 0 {
+		limiter.clientBurst = clientBurst
+	}
+	return limiter
+}
+
+func (rl *rateLimitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !rl.exempt[r.URL.Path] {
+		if limit := rl.allow(clientAddress(r), time.Now()); limit != "" {
+			CountRateLimitedRequest(rl.listener, limit)
+			w.Header().Set("Retry-After", "1")
+			rl.hc.writeErrorResponse(w, r, http.StatusTooManyRequests, "too many requests")
+			return
+		}
+	}
+	rl.handler.ServeHTTP(w, r)
+}
+
+// allow takes a token for the request from the client's bucket, and then the global bucket. It returns the name of the
+// limit that the request is over ("client" or "global"), or an empty string if the request is allowed.
+func (rl *rateLimitHandler) allow(client string, now time.Time) string {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	if rl.clientRate > 0 {
+		if now.Sub(rl.lastSweep) >= rateLimitSweepInterval {
+			rl.sweepClients(now)
+		}
+		bucket, ok := rl.clients[client]
+		if !ok {
+			bucket = newTokenBucket(rl.clientRate, rl.clientBurst, now)
+			rl.clients[client] = bucket
+		}
+		if !bucket.take(now) {
+			return "client"
+		}
+	}
+	if (rl.global != nil) && !rl.global.take(now) {
+		return "global"
+	}
+	return ""
+}
+
+// sweepClients removes the buckets that have filled up again, as a new bucket for the client would be the same
+func (rl *rateLimitHandler) sweepClients(now time.Time) {
+	for client, bucket := range rl.clients {
+		bucket.refill(now)
+		if bucket.tokens >= bucket.burst {
+			delete(rl.clients, client)
+		}
+	}
+	rl.lastSweep = now
+}
+
+// clientAddress returns the IP address that the request came from, without the port
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,106 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func fixtureRateLimitCoordinator() *Coordinator {
+	logLevel := zap.NewAtomicLevelAt(zap.InfoLevel)
+	return &Coordinator{
+		Log: zap.NewNop(),
+		App: &protocol.ApplicationContext{
+			Logger:   zap.NewNop(),
+			LogLevel: &logLevel,
+		},
+	}
+}
+
+func rateLimitedRequest(coordinator *Coordinator, uri, remoteAddr string) int {
+	req := httptest.NewRequest("GET", uri, http.NoBody)
+	req.RemoteAddr = remoteAddr
+	rr := httptest.NewRecorder()
+	coordinator.servers["default"].Handler.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+func TestHttpServer_RateLimit_Client(t *testing.T) {
+	coordinator := fixtureRateLimitCoordinator()
+	viper.Reset()
+	viper.Set("httpserver.default.address", ":0")
+	viper.Set("httpserver.default.client-rate-limit", 0.01)
+	viper.Set("httpserver.default.client-rate-limit-burst", 2)
+	coordinator.Configure()
+
+	// Each client has its own burst
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(coordinator, "/v3/admin/loglevel", "192.0.2.1:1234"), "Expected first request to be allowed")
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(coordinator, "/v3/admin/loglevel", "192.0.2.1:1235"), "Expected second request to be allowed")
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(coordinator, "/v3/admin/loglevel", "192.0.2.1:1236"), "Expected third request to be limited")
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(coordinator, "/v3/admin/loglevel", "192.0.2.2:1234"), "Expected request from another client to be allowed")
+
+	// The health check is exempt by default
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(coordinator, "/burrow/admin", "192.0.2.1:1237"), "Expected health check to be allowed")
+}
+
+func TestHttpServer_RateLimit_Global(t *testing.T) {
+	coordinator := fixtureRateLimitCoordinator()
+	viper.Reset()
+	viper.Set("httpserver.default.address", ":0")
+	viper.Set("httpserver.default.rate-limit", 0.01)
+	viper.Set("httpserver.default.rate-limit-exempt", []string{"/burrow/admin/ready"})
+	coordinator.Configure()
+
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(coordinator, "/burrow/admin", "192.0.2.1:1234"), "Expected first request to be allowed")
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(coordinator, "/burrow/admin", "192.0.2.2:1234"), "Expected request from another client to be limited")
+	assert.NotEqual(t, http.StatusTooManyRequests, rateLimitedRequest(coordinator, "/burrow/admin/ready", "192.0.2.2:1234"), "Expected exempt path to be allowed")
+}
+
+func TestHttpServer_RateLimit_Bad(t *testing.T) {
+	coordinator := fixtureRateLimitCoordinator()
+	viper.Reset()
+	viper.Set("httpserver.default.address", ":0")
+	viper.Set("httpserver.default.rate-limit", -1)
+	assert.Panics(t, coordinator.Configure, "The code did not panic")
+
+	viper.Reset()
+	viper.Set("httpserver.default.address", ":0")
+	viper.Set("httpserver.default.client-rate-limit", 10)
+	viper.Set("httpserver.default.client-rate-limit-burst", 0)
+	assert.Panics(t, coordinator.Configure, "The code did not panic")
+}
+
+func TestRateLimitHandler_sweepClients(t *testing.T) {
+	now := time.Now()
+	limiter := &rateLimitHandler{
+		clientRate:  1,
+		clientBurst: 1,
+		clients:     make(map[string]*tokenBucket),
+		lastSweep:   now,
+	}
+
+	assert.Equal(t, "", limiter.allow("192.0.2.1", now), "Expected first request to be allowed")
+	assert.Equal(t, "client", limiter.allow("192.0.2.1", now), "Expected second request to be limited")
+	assert.Len(t, limiter.clients, 1, "Expected a bucket for the client")
+
+	// Once the bucket has refilled, the next sweep removes it
+	assert.Equal(t, "", limiter.allow("192.0.2.2", now.Add(rateLimitSweepInterval)), "Expected request from another client to be allowed")
+	_, ok := limiter.clients["192.0.2.1"]
+	assert.False(t, ok, "Expected idle client bucket to be removed")
+}
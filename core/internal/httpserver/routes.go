@@ -0,0 +1,91 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import "strings"
+
+// Every route is registered as a member of one of these groups. A listener can be restricted to serve only a subset of
+// the groups with the route-groups configuration.
+const (
+	routeGroupHealth  = "health"
+	routeGroupMetrics = "metrics"
+	routeGroupRead    = "read"
+	routeGroupConfig  = "config"
+	routeGroupDelete  = "delete"
+	routeGroupAdmin   = "admin"
+)
+
+var routeGroups = []string{
+	routeGroupHealth,
+	routeGroupMetrics,
+	routeGroupRead,
+	routeGroupConfig,
+	routeGroupDelete,
+	routeGroupAdmin,
+}
+
+// routeFilterKey is the context key under which a listener stores its routeFilter
+type routeFilterKey struct{}
+
+// routeFilter describes which routes a single listener will serve. A nil groups map means that all groups are enabled.
+// Individual routes are disabled by either their path (as registered, e.g. "/v3/admin/loglevel") or by their method
+// and path (e.g. "POST /v3/admin/loglevel").
+type routeFilter struct {
+	groups   map[string]bool
+	disabled map[string]bool
+}
+
+// newRouteFilter builds the routeFilter for a listener from its route-groups and disabled-routes configurations. An
+// unknown route group name will cause this func to panic, as it is called when configuring the coordinator.
+func newRouteFilter(groups, disabledRoutes []string) *routeFilter {
+	filter := &routeFilter{
+		disabled: make(map[string]bool),
+	}
+
+	if len(groups) > 0 {
+		filter.groups = make(map[string]bool)
+		for _, group := range groups {
+			group = strings.ToLower(strings.TrimSpace(group))
+			if !isRouteGroup(group) {
+				panic("unknown HTTP route group '" + group + "' (must be one of " + strings.Join(routeGroups, ", ") + ")")
+			}
+			filter.groups[group] = true
+		}
+	}
+
+	for _, route := range disabledRoutes {
+		route = strings.TrimSpace(route)
+		if parts := strings.Fields(route); len(parts) == 2 {
+			route = strings.ToUpper(parts[0]) + " " + parts[1]
+		}
+		filter.disabled[route] = true
+	}
+	return filter
+}
+
+func isRouteGroup(group string) bool {
+	for _, name := range routeGroups {
+		if name == group {
+			return true
+		}
+	}
+	return false
+}
+
+// allows returns true if the route registered with the given group, method, and path is served by the listener
+func (filter *routeFilter) allows(group, method, path string) bool {
+	if (filter.groups != nil) && !filter.groups[group] {
+		return false
+	}
+	if filter.disabled[path] || filter.disabled[method+" "+path] {
+		return false
+	}
+	return true
+}
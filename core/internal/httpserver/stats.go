@@ -0,0 +1,104 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// moduleCycles holds the timing of the last cycle of periodic work done by each module, keyed by the name passed to
+// RecordModuleCycle
+var moduleCycles = struct {
+	sync.RWMutex
+	cycles map[string]httpResponseModuleCycle
+}{cycles: make(map[string]httpResponseModuleCycle)}
+
+// RecordModuleCycle records that a cycle of periodic work for a module, such as fetching offsets for a cluster, began at
+// startTime and has just finished. The name should identify both the module and the work, such as
+// "cluster.local.offsets". The last cycle for each name is reported by the admin stats endpoint.
+func RecordModuleCycle(name string, startTime time.Time) {
+	duration := time.Since(startTime)
+
+	moduleCycles.Lock()
+	defer moduleCycles.Unlock()
+	cycle := moduleCycles.cycles[name]
+	cycle.Count++
+	cycle.LastStart = startTime.UnixNano() / int64(time.Millisecond)
+	cycle.LastDuration = float64(duration) / float64(time.Millisecond)
+	moduleCycles.cycles[name] = cycle
+}
+
+func getModuleCycles() map[string]httpResponseModuleCycle {
+	moduleCycles.RLock()
+	defer moduleCycles.RUnlock()
+
+	cycles := make(map[string]httpResponseModuleCycle, len(moduleCycles.cycles))
+	for name, cycle := range moduleCycles.cycles {
+		cycles[name] = cycle
+	}
+	return cycles
+}
+
+func (hc *Coordinator) getAdminStats(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	// Fetch the queue depths from the storage module
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchStats,
+		Reply:       make(chan interface{}),
+	}
+	hc.App.StorageChannel <- request
+	storageStats, _ := (<-request.Reply).(*protocol.StorageStats)
+
+	hc.writeResponse(w, r, http.StatusOK, httpResponseAdminStats{
+		Error:   false,
+		Message: "stats returned",
+		Stats: httpResponseStats{
+			Goroutines: runtime.NumGoroutine(),
+			Memory: httpResponseMemoryStats{
+				Alloc:        memStats.Alloc,
+				TotalAlloc:   memStats.TotalAlloc,
+				Sys:          memStats.Sys,
+				HeapAlloc:    memStats.HeapAlloc,
+				HeapInuse:    memStats.HeapInuse,
+				HeapObjects:  memStats.HeapObjects,
+				NumGC:        memStats.NumGC,
+				PauseTotalNs: memStats.PauseTotalNs,
+			},
+			Storage: storageStats,
+			Modules: getModuleCycles(),
+		},
+		Request: makeRequestInfo(r),
+	})
+}
+
+// getOffsetSchedule returns the effective schedule of the regular offset fetches for each cluster, as set up by the
+// offset scheduler in the application context
+func (hc *Coordinator) getOffsetSchedule(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	schedule := []*protocol.ScheduledOffsetFetch{}
+	if hc.App.OffsetScheduler != nil {
+		schedule = hc.App.OffsetScheduler.Schedule()
+	}
+
+	hc.writeResponse(w, r, http.StatusOK, httpResponseOffsetSchedule{
+		Error:    false,
+		Message:  "offset schedule returned",
+		Schedule: schedule,
+		Request:  makeRequestInfo(r),
+	})
+}
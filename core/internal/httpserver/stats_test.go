@@ -0,0 +1,103 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func TestRecordModuleCycle(t *testing.T) {
+	startTime := time.Now().Add(-100 * time.Millisecond)
+	RecordModuleCycle("cluster.testrecord.offsets", startTime)
+	RecordModuleCycle("cluster.testrecord.offsets", startTime)
+
+	cycle, ok := getModuleCycles()["cluster.testrecord.offsets"]
+	assert.True(t, ok, "Expected module cycle to be recorded")
+	assert.Equalf(t, int64(2), cycle.Count, "Expected count to be 2, not %v", cycle.Count)
+	assert.Equalf(t, startTime.UnixNano()/int64(time.Millisecond), cycle.LastStart, "Expected last start to be %v, not %v", startTime.UnixNano()/int64(time.Millisecond), cycle.LastStart)
+	assert.GreaterOrEqualf(t, cycle.LastDuration, float64(100), "Expected last duration to be at least 100ms, not %v", cycle.LastDuration)
+}
+
+func TestHttpServer_getAdminStats(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	RecordModuleCycle("cluster.teststats.offsets", time.Now())
+
+	// Respond to the expected storage request
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchStats, request.RequestType, "Expected request of type StorageFetchStats, not %v", request.RequestType)
+		request.Reply <- &protocol.StorageStats{RequestQueue: 1, WorkerQueues: []int{2, 3}}
+		close(request.Reply)
+	}()
+
+	// Set up a request
+	req, err := http.NewRequest("GET", "/v3/admin/stats", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+
+	// Call the handler via httprouter
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Parse response body
+	decoder := json.NewDecoder(rr.Body)
+	var resp httpResponseAdminStats
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Positive(t, resp.Stats.Goroutines, "Expected goroutine count to be positive")
+	assert.Positive(t, resp.Stats.Memory.Sys, "Expected memory sys to be positive")
+	assert.Equal(t, &protocol.StorageStats{RequestQueue: 1, WorkerQueues: []int{2, 3}}, resp.Stats.Storage)
+	assert.Equalf(t, int64(1), resp.Stats.Modules["cluster.teststats.offsets"].Count, "Expected module cycle count to be 1, not %v", resp.Stats.Modules["cluster.teststats.offsets"].Count)
+}
+
+func TestHttpServer_getOffsetSchedule(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Without a scheduler, the schedule is empty
+	req, err := http.NewRequest("GET", "/v3/admin/offset-schedule", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseOffsetSchedule
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Empty(t, resp.Schedule, "Expected schedule to be empty")
+
+	scheduler := helpers.NewOffsetFetchScheduler(true)
+	scheduler.Register("clusterA", 60*time.Second)
+	scheduler.Register("clusterB", 60*time.Second)
+	coordinator.App.OffsetScheduler = scheduler
+
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	resp = httpResponseOffsetSchedule{}
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.Equal(t, []*protocol.ScheduledOffsetFetch{
+		{Cluster: "clusterA", Interval: 60, Offset: 0},
+		{Cluster: "clusterB", Interval: 60, Offset: 30},
+	}, resp.Schedule)
+}
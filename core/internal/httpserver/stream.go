@@ -0,0 +1,176 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/viper"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// How often a status stream sends a heartbeat when there have been no updates, so that clients (and proxies) can tell
+// an idle stream from a dead one
+var streamHeartbeatInterval = 15 * time.Second
+
+// The number of updates that are queued for a stream client that is not keeping up. Updates beyond this are dropped
+// for that client, so that a slow client never holds up the evaluator
+const streamQueueDepth = 100
+
+// statusSubscriber is a client of the status stream, which receives the status of each group in a cluster (or a single
+// group, if group is set) as it is evaluated
+type statusSubscriber struct {
+	cluster string
+	group   string
+	updates chan *protocol.ConsumerGroupStatus
+}
+
+var (
+	statusSubscribersLock sync.RWMutex
+	statusSubscribers     = make(map[*statusSubscriber]struct{})
+)
+
+// PublishConsumerStatus sends a group status that the evaluator has just calculated to the status stream clients that
+// are watching the group. It never blocks.
+func PublishConsumerStatus(status *protocol.ConsumerGroupStatus) {
+	statusSubscribersLock.RLock()
+	defer statusSubscribersLock.RUnlock()
+
+	for subscriber := range statusSubscribers {
+		if (subscriber.cluster != status.Cluster) || ((subscriber.group != "") && (subscriber.group != status.Group)) {
+			continue
+		}
+		select {
+		case subscriber.updates <- status:
+		default:
+		}
+	}
+}
+
+func subscribeConsumerStatus(cluster, group string) *statusSubscriber {
+	subscriber := &statusSubscriber{
+		cluster: cluster,
+		group:   group,
+		updates: make(chan *protocol.ConsumerGroupStatus, streamQueueDepth),
+	}
+
+	statusSubscribersLock.Lock()
+	statusSubscribers[subscriber] = struct{}{}
+	statusSubscribersLock.Unlock()
+	return subscriber
+}
+
+func unsubscribeConsumerStatus(subscriber *statusSubscriber) {
+	statusSubscribersLock.Lock()
+	delete(statusSubscribers, subscriber)
+	statusSubscribersLock.Unlock()
+}
+
+// streamEvent is a single message on a status stream
+type streamEvent struct {
+	Type      string                        `json:"type"`
+	Timestamp int64                         `json:"timestamp"`
+	Status    *protocol.ConsumerGroupStatus `json:"status,omitempty"`
+}
+
+// handleStatusStream streams the status of the groups in a cluster as they are evaluated, until the client disconnects.
+// The "consumer" query parameter limits the stream to a single group. Each message is a JSON streamEvent, either a
+// "status" with the group status (with only the partitions that are not OK, as for the status request), or a
+// "heartbeat" if there have been no updates for streamHeartbeatInterval. The messages are JSON lines, unless the client
+// accepts text/event-stream, in which case they are sent as server-sent events.
+//
+// Groups are only evaluated when something asks for their status (such as the notifier), so the stream only has
+// updates as often as that happens. The status is as evaluated, so muted groups are not shown as muted.
+func (hc *Coordinator) handleStatusStream(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// Check that the cluster exists before starting the stream
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumers,
+		Cluster:     params.ByName("cluster"),
+		Reply:       make(chan interface{}),
+	}
+	hc.App.StorageChannel <- request
+	if response := <-request.Reply; response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+
+	subscriber := subscribeConsumerStatus(params.ByName("cluster"), r.URL.Query().Get("consumer"))
+	defer unsubscribeConsumerStatus(subscriber)
+
+	eventStream := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	if corsHeader := viper.GetString("general.access-control-allow-origin"); corsHeader != "" {
+		w.Header().Set("Access-Control-Allow-Origin", corsHeader)
+	}
+	if eventStream {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// The listener's write timeout would otherwise end the stream, so the deadline is pushed back with each message
+	controller := http.NewResponseController(w)
+	writeEvent := func(event *streamEvent) bool {
+		event.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
+		content, err := json.Marshal(event)
+		if err != nil {
+			return false
+		}
+		controller.SetWriteDeadline(time.Now().Add(2 * streamHeartbeatInterval))
+		if eventStream {
+			_, err = w.Write([]byte("event: " + event.Type + "\ndata: " + string(content) + "\n\n"))
+		} else {
+			_, err = w.Write(append(content, '\n'))
+		}
+		if err == nil {
+			err = controller.Flush()
+		}
+		return err == nil
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		var event *streamEvent
+		select {
+		case status := <-subscriber.updates:
+			event = &streamEvent{Type: "status", Status: notOKPartitions(status)}
+			heartbeat.Reset(streamHeartbeatInterval)
+		case <-heartbeat.C:
+			event = &streamEvent{Type: "heartbeat"}
+		case <-r.Context().Done():
+			// The client has gone away
+			return
+		}
+		if !writeEvent(event) {
+			return
+		}
+	}
+}
+
+// notOKPartitions returns a copy of the status with only the partitions that are not OK. The status itself is shared
+// with the evaluator cache, so it cannot be modified.
+func notOKPartitions(status *protocol.ConsumerGroupStatus) *protocol.ConsumerGroupStatus {
+	filtered := *status
+	filtered.Partitions = make([]*protocol.PartitionStatus, 0)
+	for _, partition := range status.Partitions {
+		if partition.Status > protocol.StatusOK {
+			filtered.Partitions = append(filtered.Partitions, partition)
+		}
+	}
+	return &filtered
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// Responds to storage requests for the consumer list of testcluster, and with a 404 for any other cluster
+func respondToClusterCheck(coordinator *Coordinator, count int) {
+	go func() {
+		for i := 0; i < count; i++ {
+			request := <-coordinator.App.StorageChannel
+			if request.Cluster == "testcluster" {
+				request.Reply <- []string{"testgroup", "othergroup"}
+			}
+			close(request.Reply)
+		}
+	}()
+}
+
+// Waits for the stream to subscribe, so that statuses published after this are not missed
+func waitForSubscribers(t *testing.T, count int) {
+	for i := 0; i < 100; i++ {
+		statusSubscribersLock.RLock()
+		subscribed := len(statusSubscribers)
+		statusSubscribersLock.RUnlock()
+		if subscribed == count {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Expected %v stream subscribers", count)
+}
+
+func TestHttpServer_handleStatusStream(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	respondToClusterCheck(coordinator, 1)
+	server := httptest.NewServer(coordinator.router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v3/kafka/testcluster/status/stream?consumer=testgroup")
+	assert.NoError(t, err, "Expected request to return no error")
+	assert.Equalf(t, http.StatusOK, resp.StatusCode, "Expected response code to be 200, not %v", resp.StatusCode)
+	assert.Equalf(t, "application/x-ndjson", resp.Header.Get("Content-Type"), "Expected JSON lines, not %v", resp.Header.Get("Content-Type"))
+	waitForSubscribers(t, 1)
+
+	// Only the watched group is streamed, with only its partitions that are not OK
+	PublishConsumerStatus(&protocol.ConsumerGroupStatus{Cluster: "testcluster", Group: "othergroup", Status: protocol.StatusError})
+	PublishConsumerStatus(&protocol.ConsumerGroupStatus{Cluster: "testcluster", Group: "testgroup", Status: protocol.StatusWarning,
+		Partitions: []*protocol.PartitionStatus{{Topic: "testtopic", Status: protocol.StatusOK}, {Topic: "testtopic", Partition: 1, Status: protocol.StatusWarning}},
+	})
+
+	// Skip any heartbeat that was sent before the statuses were published
+	reader := bufio.NewReader(resp.Body)
+	var event map[string]interface{}
+	for event == nil || event["type"] == "heartbeat" {
+		line, err := reader.ReadBytes('\n')
+		if !assert.NoError(t, err, "Expected a status line") {
+			resp.Body.Close()
+			return
+		}
+		event = nil
+		assert.NoError(t, json.Unmarshal(line, &event), "Expected status line to be JSON")
+	}
+	assert.Equalf(t, "status", event["type"], "Expected a status event, not %v", event["type"])
+	status := event["status"].(map[string]interface{})
+	assert.Equalf(t, "testgroup", status["group"], "Expected status for testgroup, not %v", status["group"])
+	assert.Equalf(t, "WARN", status["status"], "Expected status to be WARN, not %v", status["status"])
+	assert.Lenf(t, status["partitions"], 1, "Expected 1 partition, not %v", status["partitions"])
+
+	// The subscriber is removed when the client goes away
+	resp.Body.Close()
+	waitForSubscribers(t, 0)
+}
+
+func TestHttpServer_handleStatusStream_EventStream(t *testing.T) {
+	streamHeartbeatInterval = 50 * time.Millisecond
+	defer func() { streamHeartbeatInterval = 15 * time.Second }()
+
+	coordinator := fixtureConfiguredCoordinator()
+	respondToClusterCheck(coordinator, 1)
+	server := httptest.NewServer(coordinator.router)
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL+"/v3/kafka/testcluster/status/stream", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err, "Expected request to return no error")
+	defer resp.Body.Close()
+	assert.Equalf(t, "text/event-stream", resp.Header.Get("Content-Type"), "Expected server-sent events, not %v", resp.Header.Get("Content-Type"))
+
+	// With no updates, the stream sends heartbeats
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err, "Expected an event line")
+	assert.Equalf(t, "event: heartbeat\n", line, "Expected a heartbeat event, not %v", line)
+	line, err = reader.ReadString('\n')
+	assert.NoError(t, err, "Expected a data line")
+	assert.Truef(t, strings.HasPrefix(line, "data: {\"type\":\"heartbeat\""), "Expected heartbeat data, not %v", line)
+}
+
+func TestHttpServer_handleStatusStream_BadCluster(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	respondToClusterCheck(coordinator, 1)
+
+	req, err := http.NewRequest("GET", "/v3/kafka/nocluster/status/stream", http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
@@ -9,12 +9,34 @@
 
 package httpserver
 
-import "github.com/linkedin/Burrow/core/protocol"
+import (
+	"encoding/json"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
 
 type logLevelRequest struct {
 	Level string `json:"level"`
 }
 
+type muteRequest struct {
+	Duration int64 `json:"duration"`
+}
+
+type httpResponseMuteList struct {
+	Error   bool                    `json:"error"`
+	Message string                  `json:"message"`
+	Muted   map[string]int64        `json:"muted"`
+	Request httpResponseRequestInfo `json:"request"`
+}
+
+type httpResponseMuteSet struct {
+	Error   bool                    `json:"error"`
+	Message string                  `json:"message"`
+	Expires int64                   `json:"expires"`
+	Request httpResponseRequestInfo `json:"request"`
+}
+
 type httpResponseLogLevel struct {
 	Error   bool                    `json:"error"`
 	Message string                  `json:"message"`
@@ -22,6 +44,44 @@ type httpResponseLogLevel struct {
 	Request httpResponseRequestInfo `json:"request"`
 }
 
+type httpResponseAdminStats struct {
+	Error   bool                    `json:"error"`
+	Message string                  `json:"message"`
+	Stats   httpResponseStats       `json:"stats"`
+	Request httpResponseRequestInfo `json:"request"`
+}
+
+type httpResponseOffsetSchedule struct {
+	Error    bool                             `json:"error"`
+	Message  string                           `json:"message"`
+	Schedule []*protocol.ScheduledOffsetFetch `json:"schedule"`
+	Request  httpResponseRequestInfo          `json:"request"`
+}
+
+type httpResponseStats struct {
+	Goroutines int                                `json:"goroutines"`
+	Memory     httpResponseMemoryStats            `json:"memory"`
+	Storage    *protocol.StorageStats             `json:"storage"`
+	Modules    map[string]httpResponseModuleCycle `json:"modules"`
+}
+
+type httpResponseMemoryStats struct {
+	Alloc        uint64 `json:"alloc"`
+	TotalAlloc   uint64 `json:"total-alloc"`
+	Sys          uint64 `json:"sys"`
+	HeapAlloc    uint64 `json:"heap-alloc"`
+	HeapInuse    uint64 `json:"heap-inuse"`
+	HeapObjects  uint64 `json:"heap-objects"`
+	NumGC        uint32 `json:"num-gc"`
+	PauseTotalNs uint64 `json:"pause-total-ns"`
+}
+
+type httpResponseModuleCycle struct {
+	Count        int64   `json:"count"`
+	LastStart    int64   `json:"last-start"`
+	LastDuration float64 `json:"last-duration-ms"`
+}
+
 type httpResponseRequestInfo struct {
 	URI  string `json:"url"`
 	Host string `json:"host"`
@@ -56,10 +116,11 @@ type httpResponseClientProfile struct {
 }
 
 type httpResponseClusterList struct {
-	Error    bool                    `json:"error"`
-	Message  string                  `json:"message"`
-	Clusters []string                `json:"clusters"`
-	Request  httpResponseRequestInfo `json:"request"`
+	Error          bool                    `json:"error"`
+	Message        string                  `json:"message"`
+	Clusters       []string                `json:"clusters"`
+	FailedClusters []string                `json:"failed-clusters,omitempty"`
+	Request        httpResponseRequestInfo `json:"request"`
 }
 
 type httpResponseTopicList struct {
@@ -70,10 +131,11 @@ type httpResponseTopicList struct {
 }
 
 type httpResponseTopicDetail struct {
-	Error   bool                    `json:"error"`
-	Message string                  `json:"message"`
-	Offsets []int64                 `json:"offsets"`
-	Request httpResponseRequestInfo `json:"request"`
+	Error         bool                    `json:"error"`
+	Message       string                  `json:"message"`
+	Offsets       []int64                 `json:"offsets"`
+	OldestOffsets []int64                 `json:"oldest_offsets,omitempty"`
+	Request       httpResponseRequestInfo `json:"request"`
 }
 
 type httpResponseTopicConsumerDetail struct {
@@ -97,6 +159,20 @@ type httpResponseConsumerDetail struct {
 	Request httpResponseRequestInfo `json:"request"`
 }
 
+type httpResponseConsumerBaseline struct {
+	Error    bool                    `json:"error"`
+	Message  string                  `json:"message"`
+	Baseline *protocol.LagBaseline   `json:"baseline"`
+	Request  httpResponseRequestInfo `json:"request"`
+}
+
+type httpResponseConsumerStatusHistory struct {
+	Error   bool                        `json:"error"`
+	Message string                      `json:"message"`
+	History []protocol.StatusTransition `json:"history"`
+	Request httpResponseRequestInfo     `json:"request"`
+}
+
 type httpResponseConsumerStatus struct {
 	Error   bool                         `json:"error"`
 	Message string                       `json:"message"`
@@ -104,6 +180,120 @@ type httpResponseConsumerStatus struct {
 	Request httpResponseRequestInfo      `json:"request"`
 }
 
+type httpResponseConsumerStatusFields struct {
+	Error   bool                       `json:"error"`
+	Message string                     `json:"message"`
+	Status  map[string]json.RawMessage `json:"status"`
+	Request httpResponseRequestInfo    `json:"request"`
+}
+
+type httpResponseConsumerStatusWait struct {
+	Error   bool                         `json:"error"`
+	Message string                       `json:"message"`
+	Changed bool                         `json:"changed"`
+	Status  protocol.ConsumerGroupStatus `json:"status"`
+	Request httpResponseRequestInfo      `json:"request"`
+}
+
+type httpResponseTopicLag struct {
+	Error     bool                            `json:"error"`
+	Message   string                          `json:"message"`
+	Topic     string                          `json:"topic"`
+	TotalLag  uint64                          `json:"total_lag"`
+	Consumers []*httpResponseTopicConsumerLag `json:"consumers"`
+	Request   httpResponseRequestInfo         `json:"request"`
+}
+
+type httpResponseTopicConsumerLag struct {
+	Group       string                  `json:"group"`
+	Status      protocol.StatusConstant `json:"status"`
+	TopicStatus protocol.StatusConstant `json:"topic_status"`
+	Partitions  int                     `json:"partitions"`
+	Lag         uint64                  `json:"lag"`
+	MaxTimeLag  int64                   `json:"max_time_lag"`
+}
+
+type httpResponseConsumerTop struct {
+	Error     bool                            `json:"error"`
+	Message   string                          `json:"message"`
+	Consumers []*protocol.ConsumerGroupStatus `json:"consumers"`
+	Request   httpResponseRequestInfo         `json:"request"`
+}
+
+type httpResponseConsumerStatusBulk struct {
+	Error     bool                            `json:"error"`
+	Message   string                          `json:"message"`
+	Consumers []*protocol.ConsumerGroupStatus `json:"consumers"`
+	NotFound  []string                        `json:"not_found"`
+	Request   httpResponseRequestInfo         `json:"request"`
+}
+
+type httpResponseStalePartition struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Timestamp int64  `json:"timestamp"`
+	Age       int64  `json:"age"`
+}
+
+type httpResponseStalePartitions struct {
+	Error      bool                          `json:"error"`
+	Message    string                        `json:"message"`
+	StaleAfter int64                         `json:"stale-after"`
+	Partitions []*httpResponseStalePartition `json:"partitions"`
+	Request    httpResponseRequestInfo       `json:"request"`
+}
+
+type httpResponseBrokerOffsets struct {
+	Error   bool                           `json:"error"`
+	Message string                         `json:"message"`
+	Offsets *protocol.ClusterBrokerOffsets `json:"offsets"`
+	Request httpResponseRequestInfo        `json:"request"`
+}
+
+type httpResponseTopicOffsets struct {
+	Error   bool                          `json:"error"`
+	Message string                        `json:"message"`
+	Offsets *protocol.ClusterTopicOffsets `json:"offsets"`
+	Request httpResponseRequestInfo       `json:"request"`
+}
+
+type httpResponseAPIVersions struct {
+	Error       bool                         `json:"error"`
+	Message     string                       `json:"message"`
+	APIVersions *protocol.ClusterAPIVersions `json:"api_versions"`
+	Request     httpResponseRequestInfo      `json:"request"`
+}
+
+type httpResponseGroupOffsets struct {
+	Error   bool                           `json:"error"`
+	Message string                         `json:"message"`
+	Offsets *protocol.ConsumerGroupOffsets `json:"offsets"`
+	Request httpResponseRequestInfo        `json:"request"`
+}
+
+type httpResponseConsumerTimeLag struct {
+	Error      bool                         `json:"error"`
+	Message    string                       `json:"message"`
+	MaxTimeLag int64                        `json:"max_time_lag"`
+	Partitions []*httpResponsePartitionTime `json:"partitions"`
+	Request    httpResponseRequestInfo      `json:"request"`
+}
+
+type httpResponsePartitionTime struct {
+	Topic      string `json:"topic"`
+	Partition  int32  `json:"partition"`
+	CurrentLag uint64 `json:"current_lag"`
+	TimeLag    int64  `json:"time_lag"`
+}
+
+type httpResponseGroupFetchStatus struct {
+	Error       bool                               `json:"error"`
+	Message     string                             `json:"message"`
+	FetchStatus *protocol.ConsumerGroupFetchStatus `json:"fetch_status"`
+	Request     httpResponseRequestInfo            `json:"request"`
+}
+
 type httpResponseConfigGeneral struct {
 	PIDFile                  string `json:"pidfile"`
 	StdoutLogfile            string `json:"stdout-logfile"`
@@ -124,9 +314,11 @@ type httpResponseConfigZookeeper struct {
 	RootPath string   `json:"root-path"`
 }
 type httpResponseConfigHTTPServer struct {
-	Address string `json:"address"`
-	TLS     string `json:"tls"`
-	Timeout int    `json:"timeout"`
+	Address        string   `json:"address"`
+	TLS            string   `json:"tls"`
+	Timeout        int      `json:"timeout"`
+	RouteGroups    []string `json:"route-groups"`
+	DisabledRoutes []string `json:"disabled-routes"`
 }
 type httpResponseConfigMain struct {
 	Error      bool                                    `json:"error"`
@@ -163,9 +355,13 @@ type httpResponseConfigModuleStorage struct {
 type httpResponseConfigModuleCluster struct {
 	ClassName     string                    `json:"class-name"`
 	Servers       []string                  `json:"servers"`
+	ServerSet     string                    `json:"server-set,omitempty"`
 	ClientProfile httpResponseClientProfile `json:"client-profile"`
 	TopicRefresh  int64                     `json:"topic-refresh"`
 	OffsetRefresh int64                     `json:"offset-refresh"`
+	KafkaVersion  string                    `json:"kafka-version"`
+	MetadataState string                    `json:"metadata-state,omitempty"`
+	Failures      []string                  `json:"failures,omitempty"`
 }
 
 type httpResponseConfigModuleConsumer struct {
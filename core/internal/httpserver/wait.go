@@ -0,0 +1,130 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// longPollTimeoutKey is the context key under which a listener stores the longest time that a long-poll request may
+// wait for a change
+type longPollTimeoutKey struct{}
+
+// The long-poll timeout used when the listener has not set one (such as in tests)
+const defaultLongPollTimeout = 30 * time.Second
+
+// How often a long-poll request evaluates the group again while it waits for a change. The evaluator caches statuses,
+// so polling more often than its expire-cache only re-reads the cached status.
+var longPollInterval = time.Second
+
+// The group statuses that a client can give as its last known status
+var waitStatuses = []protocol.StatusConstant{
+	protocol.StatusNotFound,
+	protocol.StatusOK,
+	protocol.StatusWarning,
+	protocol.StatusError,
+	protocol.StatusMuted,
+}
+
+func parseWaitStatus(name string) (protocol.StatusConstant, bool) {
+	name = strings.ToUpper(name)
+	for _, status := range waitStatuses {
+		if status.String() == name {
+			return status, true
+		}
+	}
+	return protocol.StatusNotFound, false
+}
+
+// handleConsumerStatusWait is a long-poll version of the consumer status request. The "status" query parameter is the
+// status the client last saw for the group. If the group's status is different, it is returned immediately. Otherwise,
+// the group is evaluated again every longPollInterval until its status changes, and then returned. If it has not
+// changed after the "timeout" query parameter (in seconds), the current status is returned. The timeout defaults to,
+// and cannot be more than, the long-poll-timeout for the listener.
+func (hc *Coordinator) handleConsumerStatusWait(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	lastStatus, ok := parseWaitStatus(r.URL.Query().Get("status"))
+	if !ok {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "status must be one of NOTFOUND, OK, WARN, ERR, or MUTED")
+		return
+	}
+
+	timeout, ok := r.Context().Value(longPollTimeoutKey{}).(time.Duration)
+	if !ok {
+		timeout = defaultLongPollTimeout
+	}
+	if timeoutParam := r.URL.Query().Get("timeout"); timeoutParam != "" {
+		seconds, err := strconv.Atoi(timeoutParam)
+		if (err != nil) || (seconds <= 0) {
+			hc.writeErrorResponse(w, r, http.StatusBadRequest, "timeout must be a positive integer")
+			return
+		}
+		if requestTimeout := time.Duration(seconds) * time.Second; requestTimeout < timeout {
+			timeout = requestTimeout
+		}
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(longPollInterval)
+	defer ticker.Stop()
+
+	for {
+		request := &protocol.EvaluatorRequest{
+			Cluster: params.ByName("cluster"),
+			Group:   params.ByName("consumer"),
+			ShowAll: false,
+			Reply:   make(chan *protocol.ConsumerGroupStatus),
+		}
+		hc.App.EvaluatorChannel <- request
+		response := <-request.Reply
+
+		if response.Status != lastStatus {
+			hc.writeConsumerStatusWait(w, r, response, true)
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			hc.writeConsumerStatusWait(w, r, response, false)
+			return
+		case <-r.Context().Done():
+			// The client has gone away, so there is nobody to respond to
+			return
+		}
+	}
+}
+
+func (hc *Coordinator) writeConsumerStatusWait(w http.ResponseWriter, r *http.Request, status *protocol.ConsumerGroupStatus, changed bool) {
+	responseCode := http.StatusOK
+	if status.Status == protocol.StatusNotFound {
+		responseCode = http.StatusNotFound
+	}
+	message := "consumer status changed"
+	if !changed {
+		message = "consumer status unchanged"
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, responseCode, httpResponseConsumerStatusWait{
+		Error:   false,
+		Message: message,
+		Changed: changed,
+		Status:  *status,
+		Request: requestInfo,
+	})
+}
@@ -0,0 +1,172 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// Responds to evaluator requests for the test group with each of the statuses in turn, repeating the last one
+func respondWithStatuses(t *testing.T, coordinator *Coordinator, statuses ...protocol.StatusConstant) chan int {
+	requestCount := make(chan int, 1)
+	go func() {
+		count := 0
+		defer func() { requestCount <- count }()
+		for {
+			select {
+			case request := <-coordinator.App.EvaluatorChannel:
+				assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+				assert.Equalf(t, "testgroup", request.Group, "Expected request Group to be testgroup, not %v", request.Group)
+				status := statuses[len(statuses)-1]
+				if count < len(statuses) {
+					status = statuses[count]
+				}
+				request.Reply <- &protocol.ConsumerGroupStatus{Cluster: request.Cluster, Group: request.Group, Status: status}
+				count++
+			case <-time.After(500 * time.Millisecond):
+				return
+			}
+		}
+	}()
+	return requestCount
+}
+
+// Need a custom type for the test, due to conversions
+type waitResponseType struct {
+	Error   bool   `json:"error"`
+	Message string `json:"message"`
+	Changed bool   `json:"changed"`
+	Status  struct {
+		Status string `json:"status"`
+	} `json:"status"`
+}
+
+func doConsumerStatusWait(t *testing.T, coordinator *Coordinator, query string, timeout time.Duration) (*httptest.ResponseRecorder, waitResponseType) {
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/consumer/testgroup/status/wait"+query, http.NoBody)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	req = req.WithContext(context.WithValue(context.Background(), longPollTimeoutKey{}, timeout))
+
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+
+	var resp waitResponseType
+	if rr.Code != http.StatusBadRequest {
+		err = json.NewDecoder(rr.Body).Decode(&resp)
+		assert.NoError(t, err, "Expected body decode to return no error")
+	}
+	return rr, resp
+}
+
+func TestHttpServer_handleConsumerStatusWait_Changed(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	requestCount := respondWithStatuses(t, coordinator, protocol.StatusWarning)
+
+	rr, resp := doConsumerStatusWait(t, coordinator, "?status=ok", time.Second)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+	assert.True(t, resp.Changed, "Expected response Changed to be true")
+	assert.Equalf(t, "WARN", resp.Status.Status, "Expected status to be WARN, not %v", resp.Status.Status)
+	assert.Equalf(t, 1, <-requestCount, "Expected only one evaluator request")
+}
+
+func TestHttpServer_handleConsumerStatusWait_ChangeWhileWaiting(t *testing.T) {
+	defer func(interval time.Duration) { longPollInterval = interval }(longPollInterval)
+	longPollInterval = 10 * time.Millisecond
+
+	coordinator := fixtureConfiguredCoordinator()
+	requestCount := respondWithStatuses(t, coordinator, protocol.StatusOK, protocol.StatusOK, protocol.StatusError)
+
+	rr, resp := doConsumerStatusWait(t, coordinator, "?status=OK", time.Second)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+	assert.True(t, resp.Changed, "Expected response Changed to be true")
+	assert.Equalf(t, "ERR", resp.Status.Status, "Expected status to be ERR, not %v", resp.Status.Status)
+	assert.Equalf(t, 3, <-requestCount, "Expected three evaluator requests")
+}
+
+func TestHttpServer_handleConsumerStatusWait_Timeout(t *testing.T) {
+	defer func(interval time.Duration) { longPollInterval = interval }(longPollInterval)
+	longPollInterval = 10 * time.Millisecond
+
+	coordinator := fixtureConfiguredCoordinator()
+	respondWithStatuses(t, coordinator, protocol.StatusOK)
+
+	startTime := time.Now()
+	rr, resp := doConsumerStatusWait(t, coordinator, "?status=OK", 100*time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(startTime), 100*time.Millisecond, "Expected request to wait for the timeout")
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+	assert.False(t, resp.Changed, "Expected response Changed to be false")
+	assert.Equalf(t, "OK", resp.Status.Status, "Expected status to be OK, not %v", resp.Status.Status)
+}
+
+func TestHttpServer_handleConsumerStatusWait_BadRequest(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	for _, query := range []string{"", "?status=STALL", "?status=OK&timeout=0", "?status=OK&timeout=bar"} {
+		rr, _ := doConsumerStatusWait(t, coordinator, query, time.Second)
+		assert.Equalf(t, http.StatusBadRequest, rr.Code, "Expected response code to be 400 for query '%v', not %v", query, rr.Code)
+	}
+}
+
+func TestHttpServer_Configure_LongPollTimeout(t *testing.T) {
+	testCases := []struct {
+		timeout         int
+		longPollTimeout int
+		expected        time.Duration
+	}{
+		{300, 0, 30 * time.Second},
+		{20, 0, 10 * time.Second},
+		{20, 15, 15 * time.Second},
+	}
+
+	for i, testCase := range testCases {
+		coordinator := Coordinator{
+			Log: zap.NewNop(),
+			App: &protocol.ApplicationContext{
+				Logger: zap.NewNop(),
+			},
+		}
+
+		viper.Reset()
+		viper.Set("httpserver.default.address", ":0")
+		viper.Set("httpserver.default.timeout", testCase.timeout)
+		if testCase.longPollTimeout != 0 {
+			viper.Set("httpserver.default.long-poll-timeout", testCase.longPollTimeout)
+		}
+		coordinator.Configure()
+
+		ctx := coordinator.servers["default"].BaseContext(nil)
+		assert.Equalf(t, testCase.expected, ctx.Value(longPollTimeoutKey{}), "TEST %v: Expected long-poll timeout to be %v", i, testCase.expected)
+	}
+}
+
+func TestHttpServer_Configure_BadLongPollTimeout(t *testing.T) {
+	coordinator := Coordinator{
+		Log: zap.NewNop(),
+		App: &protocol.ApplicationContext{
+			Logger: zap.NewNop(),
+		},
+	}
+
+	viper.Reset()
+	viper.Set("httpserver.default.address", ":0")
+	viper.Set("httpserver.default.timeout", 20)
+	viper.Set("httpserver.default.long-poll-timeout", 20)
+	assert.Panics(t, coordinator.Configure, "The code did not panic")
+}
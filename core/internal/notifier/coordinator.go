@@ -31,6 +31,7 @@ import (
 	"math/rand"
 	"regexp"
 	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 
@@ -44,8 +45,8 @@ import (
 
 // Module defines a means of sending out notifications of consumer group status (such as email), as well as regular
 // expressions describing what groups to notify for. The module itself only provides the logic for how to send a
-// notification in the Notify func - timing loops, and handling requests for group evaluation, are handled in the
-// coordinator centrally.
+// notification in the Notify func, which returns an error if it was not sent - timing loops, retries, and handling
+// requests for group evaluation, are handled in the coordinator centrally.
 type Module interface {
 	protocol.Module
 	GetName() string
@@ -54,7 +55,7 @@ type Module interface {
 	GetGroupDenylist() *regexp.Regexp
 	GetLogger() *zap.Logger
 	AcceptConsumerGroup(*protocol.ConsumerGroupStatus) bool
-	Notify(*protocol.ConsumerGroupStatus, string, time.Time, bool)
+	Notify(*protocol.ConsumerGroupStatus, string, time.Time, bool) error
 }
 
 type consumerGroup struct {
@@ -62,6 +63,7 @@ type consumerGroup struct {
 	Start      time.Time
 	LastNotify map[string]time.Time
 	LastEval   time.Time
+	LastStatus protocol.StatusConstant
 }
 
 type clusterGroups struct {
@@ -83,9 +85,10 @@ type Coordinator struct {
 
 	minInterval       int64
 	groupRefresh      helpers.Ticker
-	doEvaluations     bool
+	doEvaluations     atomic.Bool
 	evaluatorResponse chan *protocol.ConsumerGroupStatus
 	running           sync.WaitGroup
+	sender            sync.WaitGroup
 	quitChannel       chan struct{}
 
 	templateParseFunc func(...string) (*template.Template, error)
@@ -93,6 +96,16 @@ type Coordinator struct {
 
 	clusters    map[string]*clusterGroups
 	clusterLock *sync.RWMutex
+	transitions map[string][]statusTransition
+	intervals   map[string][]sendIntervalOverride
+	fallbacks   map[string]string
+	escalations map[string]time.Duration
+	throttles   map[string]time.Duration
+	completes   map[string]float32
+	lastSent    map[string]map[string]time.Time
+	sentLock    *sync.Mutex
+	election    string
+	standby     standbyConfig
 	ShowAll     bool
 }
 
@@ -154,12 +167,21 @@ func (nc *Coordinator) Configure() {
 
 	nc.clusters = make(map[string]*clusterGroups)
 	nc.clusterLock = &sync.RWMutex{}
+	nc.transitions = make(map[string][]statusTransition)
+	nc.intervals = make(map[string][]sendIntervalOverride)
+	nc.fallbacks = make(map[string]string)
+	nc.escalations = make(map[string]time.Duration)
+	nc.throttles = make(map[string]time.Duration)
+	nc.completes = make(map[string]float32)
+	nc.lastSent = make(map[string]map[string]time.Time)
+	nc.sentLock = &sync.Mutex{}
 	nc.minInterval = math.MaxInt64
 
 	nc.quitChannel = make(chan struct{})
 	nc.running = sync.WaitGroup{}
 	nc.evaluatorResponse = make(chan *protocol.ConsumerGroupStatus)
 	nc.ShowAll = false
+	nc.configureElection()
 
 	// Set the function for parsing templates and calling module Notify (configurable to enable testing)
 	if nc.templateParseFunc == nil {
@@ -201,6 +223,59 @@ func (nc *Coordinator) Configure() {
 		if viper.GetInt(configRoot+".threshold") == 1 {
 			nc.ShowAll = true
 		}
+		viper.SetDefault(configRoot+".send-retries", 0)
+		viper.SetDefault(configRoot+".send-retry-backoff", 500)
+
+		// Groups matching a send-interval-overrides regex repeat their open notifications at that interval instead of
+		// send-interval, such as to re-alert more often for critical groups
+		if viper.IsSet(configRoot + ".send-interval-overrides") {
+			nc.intervals[name] = parseSendIntervalOverrides(viper.GetStringSlice(configRoot + ".send-interval-overrides"))
+		}
+
+		// If the module fails to send a notification, it is sent with the fallback module instead
+		if fallback := viper.GetString(configRoot + ".fallback"); fallback != "" {
+			nc.fallbacks[name] = fallback
+		}
+
+		// If a list of status transitions is given, it replaces the threshold for deciding when to send notifications
+		if viper.IsSet(configRoot + ".transitions") {
+			nc.transitions[name] = parseStatusTransitions(viper.GetStringSlice(configRoot + ".transitions"))
+		}
+
+		// An escalation module only sends notifications for incidents that have lasted at least escalate-after seconds,
+		// so that it can be used alongside another module to alert a second tier for groups that do not recover
+		if viper.IsSet(configRoot + ".escalate-after") {
+			escalateAfter := viper.GetInt64(configRoot + ".escalate-after")
+			if escalateAfter <= 0 {
+				panic("notifier " + name + ": escalate-after must be greater than zero")
+			}
+			if viper.IsSet(configRoot + ".transitions") {
+				panic("notifier " + name + ": escalate-after cannot be used with transitions")
+			}
+			nc.escalations[name] = time.Duration(escalateAfter) * time.Second
+		}
+
+		// A throttled module sends at most one open notification per group in each throttle window, even if the group
+		// recovers and goes bad again in between
+		if viper.IsSet(configRoot + ".throttle") {
+			throttle := viper.GetInt64(configRoot + ".throttle")
+			if throttle <= 0 {
+				panic("notifier " + name + ": throttle must be greater than zero")
+			}
+			nc.throttles[name] = time.Duration(throttle) * time.Second
+			nc.lastSent[name] = make(map[string]time.Time)
+		}
+
+		// A module with minimum-complete only sends open notifications for groups whose evaluation windows are at least
+		// that full, so that groups are not alerted on at startup while the windows fill. 1.0 requires every partition
+		// to have a full window, and lower values allow alerting on partial windows
+		if viper.IsSet(configRoot + ".minimum-complete") {
+			minimumComplete := viper.GetFloat64(configRoot + ".minimum-complete")
+			if (minimumComplete < 0) || (minimumComplete > 1) {
+				panic("notifier " + name + ": minimum-complete must be between 0.0 and 1.0")
+			}
+			nc.completes[name] = float32(minimumComplete)
+		}
 
 		// Check for disallowed config values
 		if viper.IsSet(configRoot+".group-whitelist") || viper.IsSet(configRoot+".group-blacklist") {
@@ -237,14 +312,21 @@ func (nc *Coordinator) Configure() {
 		// Set up extra fields for the templates
 		extras := viper.GetStringMapString(configRoot + ".extras")
 
-		// Compile the templates
+		// Compile the templates. The partition list helpers are replaced with ones that use this module's partition
+		// format and limit, and each template is executed once with a sample status to find errors now rather than when
+		// a notification is sent
+		partitions := newPartitionList(name, configRoot)
 		var templateOpen, templateClose *template.Template
 		tmpl, err := nc.templateParseFunc(viper.GetString(configRoot + ".template-open"))
 		if err != nil {
 			nc.Log.Panic("Failed to compile TemplateOpen", zap.Error(err), zap.String("module", name))
 			panic(err)
 		}
-		templateOpen = tmpl.Templates()[0]
+		templateOpen = tmpl.Templates()[0].Funcs(partitions.funcs())
+		if err = validateTemplate(templateOpen, extras); err != nil {
+			nc.Log.Panic("Failed to execute TemplateOpen", zap.Error(err), zap.String("module", name))
+			panic(err)
+		}
 
 		if viper.GetBool(configRoot + ".send-close") {
 			tmpl, err = nc.templateParseFunc(viper.GetString(configRoot + ".template-close"))
@@ -252,18 +334,26 @@ func (nc *Coordinator) Configure() {
 				nc.Log.Panic("Failed to compile TemplateClose", zap.Error(err), zap.String("module", name))
 				panic(err)
 			}
-			templateClose = tmpl.Templates()[0]
+			templateClose = tmpl.Templates()[0].Funcs(partitions.funcs())
+			if err = validateTemplate(templateClose, extras); err != nil {
+				nc.Log.Panic("Failed to execute TemplateClose", zap.Error(err), zap.String("module", name))
+				panic(err)
+			}
 		}
 
-		module := getModuleForClass(nc.App, name, viper.GetString(configRoot+".class-name"), groupAllowlist, groupDenylist, extras, templateOpen, templateClose, cluster)
-		module.Configure(name, configRoot)
-		nc.modules[name] = module
+		helpers.RecordConfigError(configRoot, func() {
+			module := getModuleForClass(nc.App, name, viper.GetString(configRoot+".class-name"), groupAllowlist, groupDenylist, extras, templateOpen, templateClose, cluster)
+			module.Configure(name, configRoot)
+			nc.modules[name] = module
+		})
 		interval := viper.GetInt64(configRoot + ".interval")
 		if interval < nc.minInterval {
 			nc.minInterval = interval
 		}
 	}
 
+	nc.validateFallbacks()
+
 	// If there are no modules specified, the minInterval will still be MaxInt64. Set it to a large number of seconds
 	if nc.minInterval == math.MaxInt64 {
 		nc.minInterval = 310536000
@@ -297,7 +387,7 @@ func (nc *Coordinator) Start() error {
 	nc.groupRefresh.Start()
 
 	// Run a goroutine to manage whether or not we're performing evaluations
-	go nc.manageEvalLoop()
+	nc.startElection()
 
 	// Run our main loop to watch tickers and take actions
 	nc.running.Add(1)
@@ -314,7 +404,7 @@ func (nc *Coordinator) Stop() error {
 	nc.Log.Info("stopping")
 
 	nc.groupRefresh.Stop()
-	nc.doEvaluations = false
+	nc.doEvaluations.Store(false)
 
 	close(nc.quitChannel)
 
@@ -335,17 +425,14 @@ func (nc *Coordinator) manageEvalLoop() {
 		}
 
 		// We've got the lock, start the evaluation loop
-		nc.doEvaluations = true
-		nc.running.Add(1)
-		go nc.sendEvaluatorRequests()
-		nc.Log.Info("starting evaluations", zap.Error(err))
+		nc.startEvaluations()
 
 		// Wait for ZK session expiration, and stop doing evaluations if it happens
 		nc.App.ZookeeperExpired.L.Lock()
 		nc.App.ZookeeperExpired.Wait()
 		nc.App.ZookeeperExpired.L.Unlock()
-		nc.doEvaluations = false
-		nc.Log.Info("stopping evaluations", zap.Error(err))
+		nc.doEvaluations.Store(false)
+		nc.Log.Info("stopping evaluations")
 
 		// Wait for the ZK connection to come back before trying again
 		for !nc.App.ZookeeperConnected {
@@ -387,7 +474,7 @@ func (nc *Coordinator) sendClusterRequest() {
 func (nc *Coordinator) sendEvaluatorRequests() {
 	defer nc.running.Done()
 
-	for nc.doEvaluations {
+	for nc.doEvaluations.Load() {
 		// Loop through all clusters and groups and send any evaluation requests that are due
 		timeNow := time.Now()
 		sendBefore := timeNow.Add(-time.Duration(nc.minInterval) * time.Second)
@@ -430,8 +517,9 @@ func (nc *Coordinator) responseLoop() {
 				continue
 			}
 
-			// As long as the response is not NotFound, send it to the modules
-			if response.Status != protocol.StatusNotFound {
+			// As long as the response is not NotFound, send it to the modules. Muted groups are skipped entirely, so any
+			// open incident is left as it is until the group is unmuted
+			if (response.Status != protocol.StatusNotFound) && (response.Status != protocol.StatusMuted) {
 				nc.running.Add(1)
 				go nc.checkAndSendResponseToModules(response)
 			}
@@ -485,9 +573,13 @@ func (nc *Coordinator) checkAndSendResponseToModules(response *protocol.Consumer
 
 	if response.Status == protocol.StatusOK {
 		// Incident closed - clear the start time and event ID
+		if !cgroup.Start.IsZero() {
+			nc.clearThrottles(response.Cluster+"/"+response.Group, time.Now(), true)
+		}
 		cgroup.ID = ""
 		cgroup.Start = time.Time{}
 	}
+	cgroup.LastStatus = response.Status
 }
 
 func (nc *Coordinator) processClusterList(replyChan chan interface{}) {
@@ -516,8 +608,11 @@ func (nc *Coordinator) processClusterList(replyChan chan interface{}) {
 	}
 
 	// Delete clusters that no longer exist
-	for cluster := range nc.clusters {
+	for cluster, groups := range nc.clusters {
 		if _, ok := requestMap[cluster]; !ok {
+			for group := range groups.Groups {
+				nc.clearThrottles(cluster+"/"+group, time.Time{}, false)
+			}
 			delete(nc.clusters, cluster)
 		}
 	}
@@ -556,6 +651,7 @@ func (nc *Coordinator) processConsumerList(cluster string, replyChan chan interf
 		// Use the map we just made to delete consumers that no longer exist
 		for group := range nc.clusters[cluster].Groups {
 			if _, ok := consumerMap[group]; !ok {
+				nc.clearThrottles(cluster+"/"+group, time.Time{}, false)
 				delete(nc.clusters[cluster].Groups, group)
 			}
 		}
@@ -576,13 +672,42 @@ func (nc *Coordinator) notifyModule(module Module, status *protocol.ConsumerGrou
 	// Closed incidents get sent regardless of the threshold for the module
 	moduleName := module.GetName()
 	if (!startTime.IsZero()) && (status.Status == protocol.StatusOK) && viper.GetBool("notifier."+moduleName+".send-close") {
-		module.Notify(status, eventID, startTime, true)
+		if _, ok := nc.escalations[moduleName]; ok && cgroup.LastNotify[moduleName].IsZero() {
+			// The incident closed before it was escalated, so this module has nothing to close
+			return
+		}
+		if _, ok := nc.throttles[moduleName]; ok && cgroup.LastNotify[moduleName].IsZero() {
+			// The open notification for this incident was throttled, so there is nothing to close
+			return
+		}
+		nc.sendNotification(module, status, eventID, startTime, true)
 		cgroup.LastNotify[module.GetName()] = time.Time{}
 		return
 	}
 
-	// Only send a notification if the current status is above the module's threshold
-	if int(status.Status) < viper.GetInt("notifier."+module.GetName()+".threshold") {
+	// Groups that have stopped committing are not alerted on unless the module is configured to notify for them
+	if status.Stale && (!viper.GetBool("notifier." + moduleName + ".notify-stale")) {
+		return
+	}
+
+	// Groups whose evaluation windows are still filling are not alerted on until they are complete enough
+	if minimumComplete, ok := nc.completes[moduleName]; ok && (status.Complete < minimumComplete) {
+		return
+	}
+
+	// Only send a notification if the current status is above the module's threshold. If the module has a list of
+	// status transitions, only send it if the change from the group's previous status is one of them instead
+	if transitions, ok := nc.transitions[moduleName]; ok {
+		if !matchStatusTransition(transitions, cgroup.LastStatus, status.Status) {
+			return
+		}
+	} else if int(status.Status) < viper.GetInt("notifier."+module.GetName()+".threshold") {
+		return
+	}
+
+	// Escalation modules wait until the incident has lasted long enough. The incident start time is cleared when the
+	// group recovers, which cancels the pending escalation
+	if escalateAfter, ok := nc.escalations[moduleName]; ok && (startTime.IsZero() || (time.Since(startTime) < escalateAfter)) {
 		return
 	}
 
@@ -591,10 +716,49 @@ func (nc *Coordinator) notifyModule(module Module, status *protocol.ConsumerGrou
 		return
 	}
 
-	// Only send the notification if it's been at least our Interval since the last one for this group
+	// Only send the notification if it's been at least our Interval (or the group's override) since the last one for
+	// this group
 	currentTime := time.Now()
-	if currentTime.Sub(cgroup.LastNotify[module.GetName()]) > (time.Duration(viper.GetInt("notifier."+moduleName+".send-interval")) * time.Second) {
-		module.Notify(status, eventID, startTime, false)
+	sendInterval := matchSendInterval(nc.intervals[moduleName], status.Group, time.Duration(viper.GetInt("notifier."+moduleName+".send-interval"))*time.Second)
+	if currentTime.Sub(cgroup.LastNotify[module.GetName()]) > sendInterval {
+		if nc.throttled(moduleName, status.Cluster+"/"+status.Group, currentTime) {
+			return
+		}
+		nc.sendNotification(module, status, eventID, startTime, false)
 		cgroup.LastNotify[module.GetName()] = currentTime
 	}
 }
+
+// throttled returns true if the module has a throttle configured and has already sent a notification for the key within
+// the throttle window. Otherwise, it records the send time for the key and returns false.
+func (nc *Coordinator) throttled(moduleName, key string, currentTime time.Time) bool {
+	throttle, ok := nc.throttles[moduleName]
+	if !ok {
+		return false
+	}
+
+	nc.sentLock.Lock()
+	defer nc.sentLock.Unlock()
+	if lastSent, ok := nc.lastSent[moduleName][key]; ok && (currentTime.Sub(lastSent) < throttle) {
+		return true
+	}
+	nc.lastSent[moduleName][key] = currentTime
+	return false
+}
+
+// clearThrottles removes the send times recorded by throttled for the key, for every module, so that they are not kept
+// after the group is gone. If expiredOnly is set, such as when the group's incident closes, only the ones whose throttle
+// window has passed by currentTime are removed, as the others still throttle the group's next incident.
+func (nc *Coordinator) clearThrottles(key string, currentTime time.Time, expiredOnly bool) {
+	if len(nc.throttles) == 0 {
+		return
+	}
+
+	nc.sentLock.Lock()
+	defer nc.sentLock.Unlock()
+	for moduleName, throttle := range nc.throttles {
+		if lastSent, ok := nc.lastSent[moduleName][key]; ok && ((!expiredOnly) || (currentTime.Sub(lastSent) >= throttle)) {
+			delete(nc.lastSent[moduleName], key)
+		}
+	}
+}
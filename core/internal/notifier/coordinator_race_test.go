@@ -48,7 +48,7 @@ func TestCoordinator_sendEvaluatorRequests(t *testing.T) {
 		LastEval:   time.Now().Add(-time.Duration(coordinator.minInterval) * time.Second),
 	}
 
-	coordinator.doEvaluations = true
+	coordinator.doEvaluations.Store(true)
 	coordinator.running.Add(1)
 	go coordinator.sendEvaluatorRequests()
 
@@ -75,7 +75,7 @@ func TestCoordinator_sendEvaluatorRequests(t *testing.T) {
 	default:
 		// All is good - we didn't expect to find another request
 	}
-	coordinator.doEvaluations = false
+	coordinator.doEvaluations.Store(false)
 }
 
 // We know this will trigger the race detector, because of the way we manipulate the ZK state
@@ -94,7 +94,7 @@ func TestCoordinator_manageEvalLoop_Start(t *testing.T) {
 
 	mockLock.AssertExpectations(t)
 	mockZk.AssertExpectations(t)
-	assert.True(t, coordinator.doEvaluations, "Expected doEvaluations to be true")
+	assert.True(t, coordinator.doEvaluations.Load(), "Expected doEvaluations to be true")
 }
 
 // We know this will trigger the race detector, because of the way we manipulate the ZK state
@@ -118,7 +118,7 @@ func TestCoordinator_manageEvalLoop_Expiration(t *testing.T) {
 
 	mockLock.AssertExpectations(t)
 	mockZk.AssertExpectations(t)
-	assert.False(t, coordinator.doEvaluations, "Expected doEvaluations to be false")
+	assert.False(t, coordinator.doEvaluations.Load(), "Expected doEvaluations to be false")
 }
 
 type statefulMockLock struct {
@@ -164,5 +164,5 @@ func TestCoordinator_manageEvalLoop_Reconnect(t *testing.T) {
 
 	mockLock.AssertExpectations(t)
 	mockZk.AssertExpectations(t)
-	assert.True(t, coordinator.doEvaluations, "Expected doEvaluations to be true")
+	assert.True(t, coordinator.doEvaluations.Load(), "Expected doEvaluations to be true")
 }
@@ -120,6 +120,24 @@ func TestCoordinator_Configure_BadTemplate(t *testing.T) {
 	assert.Panics(t, func() { coordinator.Configure() }, "The code did not panic")
 }
 
+func TestCoordinator_Configure_TemplateExecuteError(t *testing.T) {
+	coordinator := fixtureCoordinator()
+
+	// The template parses, but calling toplag with a string count fails when it is executed
+	coordinator.templateParseFunc = func(filenames ...string) (*template.Template, error) {
+		return template.New("test").Funcs(helperFunctionMap).Parse(`{{range toplag "5" .Result.Partitions}}{{.Topic}}{{end}}`)
+	}
+
+	assert.Panics(t, func() { coordinator.Configure() }, "The code did not panic")
+}
+
+func TestCoordinator_Configure_BadPartitionFormat(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("notifier.test.partition-format", "{{.Topic")
+
+	assert.Panics(t, func() { coordinator.Configure() }, "The code did not panic")
+}
+
 func TestCoordinator_Configure_SendClose(t *testing.T) {
 	coordinator := fixtureCoordinator()
 	viper.Set("notifier.test.send-close", true)
@@ -249,6 +267,56 @@ func TestCoordinator_responseLoop_NotFound(t *testing.T) {
 	assert.True(t, group.LastNotify["test"].IsZero(), "Expected group last time to be unset")
 }
 
+func TestCoordinator_responseLoop_Muted(t *testing.T) {
+	coordinator := fixtureCoordinator()
+
+	// For Muted, we expect the notifier will not be called at all
+	coordinator.notifyModuleFunc = func(module Module, status *protocol.ConsumerGroupStatus, startTime time.Time, eventId string) {
+		defer coordinator.running.Done()
+		assert.Fail(t, "Expected notifyModule to not be called")
+	}
+
+	coordinator.Configure()
+
+	// A test cluster and group with an open incident
+	incidentStart := time.Now().Add(-time.Minute)
+	coordinator.clusters["testcluster"] = &clusterGroups{
+		Lock:   &sync.RWMutex{},
+		Groups: make(map[string]*consumerGroup),
+	}
+	coordinator.clusters["testcluster"].Groups["testgroup"] = &consumerGroup{
+		ID:         "testidstring",
+		Start:      incidentStart,
+		LastStatus: protocol.StatusError,
+		LastNotify: make(map[string]time.Time),
+	}
+
+	responseMuted := &protocol.ConsumerGroupStatus{
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		Status:  protocol.StatusMuted,
+	}
+	go func() {
+		coordinator.evaluatorResponse <- responseMuted
+
+		// After a short wait, close the quit channel to release the responseLoop
+		time.Sleep(100 * time.Millisecond)
+		close(coordinator.quitChannel)
+	}()
+
+	coordinator.running.Add(1)
+	coordinator.responseLoop()
+	coordinator.running.Wait()
+	close(coordinator.evaluatorResponse)
+	time.Sleep(100 * time.Millisecond)
+
+	// The incident is left open for when the group is unmuted
+	group := coordinator.clusters["testcluster"].Groups["testgroup"]
+	assert.Equalf(t, "testidstring", group.ID, "Expected group incident ID to be testidstring, not %v", group.ID)
+	assert.Equalf(t, incidentStart, group.Start, "Expected group incident start time to be unchanged")
+	assert.Equalf(t, protocol.StatusError, group.LastStatus, "Expected group last status to be ERR, not %v", group.LastStatus)
+}
+
 func TestCoordinator_responseLoop_NoIncidentOK(t *testing.T) {
 	coordinator := fixtureCoordinator()
 
@@ -573,7 +641,7 @@ func TestCoordinator_checkAndSendResponseToModules(t *testing.T) {
 		}
 
 		if testSet.ExpectSend {
-			mockModule.On("Notify", response, mock.MatchedBy(func(s string) bool { return true }), mock.MatchedBy(func(t time.Time) bool { return true }), testSet.ExpectClose).Return()
+			mockModule.On("Notify", response, mock.MatchedBy(func(s string) bool { return true }), mock.MatchedBy(func(t time.Time) bool { return true }), testSet.ExpectClose).Return(nil)
 		}
 
 		// Call the func with a response that has the appropriate status
@@ -618,3 +686,439 @@ func TestCoordinator_ExecuteTemplate(t *testing.T) {
 	assert.Nil(t, err, "Expected no error to be returned")
 	assert.Equalf(t, "testidstring testcluster testgroup OK", bytesToSend.String(), "Unexpected, got: %v", bytesToSend.String())
 }
+
+func TestCoordinator_notifyModule_Stale(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.clusters = make(map[string]*clusterGroups)
+	coordinator.clusters["testcluster"] = &clusterGroups{
+		Lock:   &sync.RWMutex{},
+		Groups: make(map[string]*consumerGroup),
+	}
+
+	for _, notifyStale := range []bool{false, true} {
+		viper.Reset()
+		viper.Set("notifier.test.threshold", 2)
+		viper.Set("notifier.test.notify-stale", notifyStale)
+
+		coordinator.clusters["testcluster"].Groups["testgroup"] = &consumerGroup{
+			LastNotify: make(map[string]time.Time),
+		}
+		response := &protocol.ConsumerGroupStatus{
+			Cluster: "testcluster",
+			Group:   "testgroup",
+			Status:  protocol.StatusError,
+			Stale:   true,
+		}
+
+		mockModule := &helpers.MockModule{}
+		mockModule.On("GetName").Return("test")
+		if notifyStale {
+			mockModule.On("Notify", response, mock.MatchedBy(func(s string) bool { return true }), mock.MatchedBy(func(t time.Time) bool { return true }), false).Return(nil)
+		}
+
+		coordinator.running.Add(1)
+		coordinator.notifyModule(mockModule, response, time.Now(), "testid")
+
+		mockModule.AssertExpectations(t)
+		if !notifyStale {
+			mockModule.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestCoordinator_Configure_MinimumComplete(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("notifier.test.minimum-complete", 1.0)
+	coordinator.Configure()
+
+	assert.Equalf(t, float32(1.0), coordinator.completes["test"], "Expected minimum-complete for module test to be 1.0, not %v", coordinator.completes["test"])
+
+	coordinator = fixtureCoordinator()
+	viper.Set("notifier.test.minimum-complete", 1.5)
+	assert.Panics(t, func() { coordinator.Configure() }, "The code did not panic")
+}
+
+func TestCoordinator_notifyModule_MinimumComplete(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.completes = map[string]float32{"test": 0.5}
+	coordinator.clusters = make(map[string]*clusterGroups)
+	coordinator.clusters["testcluster"] = &clusterGroups{
+		Lock:   &sync.RWMutex{},
+		Groups: make(map[string]*consumerGroup),
+	}
+	viper.Reset()
+	viper.Set("notifier.test.threshold", 2)
+	viper.Set("notifier.test.send-close", true)
+
+	var testSet = []struct {
+		complete  float32
+		status    protocol.StatusConstant
+		startTime time.Time
+		expected  bool
+	}{
+		{0.25, protocol.StatusError, time.Now(), false},
+		{0.5, protocol.StatusError, time.Now(), true},
+		{1.0, protocol.StatusError, time.Now(), true},
+		// Close notifications are still sent for incomplete groups
+		{0.25, protocol.StatusOK, time.Now(), true},
+	}
+
+	for _, testCase := range testSet {
+		coordinator.clusters["testcluster"].Groups["testgroup"] = &consumerGroup{
+			LastNotify: make(map[string]time.Time),
+		}
+		response := &protocol.ConsumerGroupStatus{
+			Cluster:  "testcluster",
+			Group:    "testgroup",
+			Status:   testCase.status,
+			Complete: testCase.complete,
+		}
+
+		mockModule := &helpers.MockModule{}
+		mockModule.On("GetName").Return("test")
+		if testCase.expected {
+			mockModule.On("Notify", response, mock.Anything, mock.Anything, testCase.status == protocol.StatusOK).Return(nil)
+		}
+
+		coordinator.running.Add(1)
+		coordinator.notifyModule(mockModule, response, testCase.startTime, "testid")
+
+		mockModule.AssertExpectations(t)
+		if !testCase.expected {
+			mockModule.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestCoordinator_Configure_Transitions(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("notifier.test.transitions", []string{"OK->ERR"})
+	coordinator.Configure()
+
+	assert.Len(t, coordinator.transitions["test"], 1, "Expected one transition for module test")
+}
+
+func TestCoordinator_Configure_BadTransitions(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("notifier.test.transitions", []string{"OK->STALL"})
+
+	assert.Panics(t, func() { coordinator.Configure() }, "The code did not panic")
+}
+
+func TestCoordinator_notifyModule_Transitions(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.transitions = map[string][]statusTransition{
+		"test": parseStatusTransitions([]string{"OK->ERR"}),
+	}
+	coordinator.clusters = make(map[string]*clusterGroups)
+	coordinator.clusters["testcluster"] = &clusterGroups{
+		Lock:   &sync.RWMutex{},
+		Groups: make(map[string]*consumerGroup),
+	}
+	viper.Reset()
+	viper.Set("notifier.test.threshold", 3)
+
+	testCases := []struct {
+		previous protocol.StatusConstant
+		expected bool
+	}{
+		{protocol.StatusOK, true},
+		{protocol.StatusWarning, false},
+		{protocol.StatusError, false},
+	}
+
+	for _, testCase := range testCases {
+		coordinator.clusters["testcluster"].Groups["testgroup"] = &consumerGroup{
+			LastNotify: make(map[string]time.Time),
+			LastStatus: testCase.previous,
+		}
+		response := &protocol.ConsumerGroupStatus{
+			Cluster: "testcluster",
+			Group:   "testgroup",
+			Status:  protocol.StatusError,
+		}
+
+		mockModule := &helpers.MockModule{}
+		mockModule.On("GetName").Return("test")
+		if testCase.expected {
+			mockModule.On("Notify", response, mock.MatchedBy(func(s string) bool { return true }), mock.MatchedBy(func(t time.Time) bool { return true }), false).Return(nil)
+		}
+
+		coordinator.running.Add(1)
+		coordinator.notifyModule(mockModule, response, time.Now(), "testid")
+
+		mockModule.AssertExpectations(t)
+		if !testCase.expected {
+			mockModule.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestCoordinator_Configure_SendIntervalOverrides(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("notifier.test.send-interval-overrides", []string{"^critical-.*$=300"})
+	coordinator.Configure()
+
+	assert.Len(t, coordinator.intervals["test"], 1, "Expected one send interval override for module test")
+
+	coordinator = fixtureCoordinator()
+	viper.Set("notifier.test.send-interval-overrides", []string{"^critical-.*$"})
+	assert.Panics(t, func() { coordinator.Configure() }, "The code did not panic")
+}
+
+func TestCoordinator_notifyModule_SendIntervalOverrides(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.intervals = map[string][]sendIntervalOverride{
+		"test": parseSendIntervalOverrides([]string{"^critical-.*$=300", "^noisy-.*$=3600"}),
+	}
+	coordinator.clusters = make(map[string]*clusterGroups)
+	coordinator.clusters["testcluster"] = &clusterGroups{
+		Lock:   &sync.RWMutex{},
+		Groups: make(map[string]*consumerGroup),
+	}
+	viper.Reset()
+	viper.Set("notifier.test.threshold", 2)
+	viper.Set("notifier.test.send-interval", 900)
+
+	// Each group was last notified ten minutes ago
+	testCases := []struct {
+		group    string
+		expected bool
+	}{
+		{"critical-orders", true},
+		{"noisy-batch", false},
+		{"othergroup", false},
+	}
+
+	for _, testCase := range testCases {
+		coordinator.clusters["testcluster"].Groups[testCase.group] = &consumerGroup{
+			LastNotify: map[string]time.Time{"test": time.Now().Add(-10 * time.Minute)},
+		}
+		response := &protocol.ConsumerGroupStatus{
+			Cluster: "testcluster",
+			Group:   testCase.group,
+			Status:  protocol.StatusError,
+		}
+
+		mockModule := &helpers.MockModule{}
+		mockModule.On("GetName").Return("test")
+		if testCase.expected {
+			mockModule.On("Notify", response, "testid", mock.MatchedBy(func(t time.Time) bool { return true }), false).Return(nil)
+		}
+
+		coordinator.running.Add(1)
+		coordinator.notifyModule(mockModule, response, time.Now(), "testid")
+
+		mockModule.AssertExpectations(t)
+		if !testCase.expected {
+			mockModule.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestCoordinator_Configure_Escalation(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("notifier.test.escalate-after", 900)
+	coordinator.Configure()
+
+	assert.Equalf(t, 900*time.Second, coordinator.escalations["test"], "Expected escalation for module test to be 900s, not %v", coordinator.escalations["test"])
+}
+
+func TestCoordinator_Configure_BadEscalation(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("notifier.test.escalate-after", 0)
+	assert.Panics(t, func() { coordinator.Configure() }, "The code did not panic")
+
+	coordinator = fixtureCoordinator()
+	viper.Set("notifier.test.escalate-after", 900)
+	viper.Set("notifier.test.transitions", []string{"OK->ERR"})
+	assert.Panics(t, func() { coordinator.Configure() }, "The code did not panic")
+}
+
+func TestCoordinator_notifyModule_Escalation(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.escalations = map[string]time.Duration{"test": 15 * time.Minute}
+	coordinator.clusters = make(map[string]*clusterGroups)
+	coordinator.clusters["testcluster"] = &clusterGroups{
+		Lock:   &sync.RWMutex{},
+		Groups: make(map[string]*consumerGroup),
+	}
+	viper.Reset()
+	viper.Set("notifier.test.threshold", 3)
+	viper.Set("notifier.test.send-close", true)
+
+	testCases := []struct {
+		status    protocol.StatusConstant
+		started   time.Duration
+		escalated bool
+		expected  bool
+	}{
+		{protocol.StatusError, time.Minute, false, false},
+		{protocol.StatusError, 20 * time.Minute, false, true},
+		{protocol.StatusOK, time.Minute, false, false},
+		{protocol.StatusOK, 20 * time.Minute, true, true},
+	}
+
+	for i, testCase := range testCases {
+		group := &consumerGroup{
+			LastNotify: make(map[string]time.Time),
+		}
+		if testCase.escalated {
+			group.LastNotify["test"] = time.Now().Add(-time.Minute)
+		}
+		coordinator.clusters["testcluster"].Groups["testgroup"] = group
+		response := &protocol.ConsumerGroupStatus{
+			Cluster: "testcluster",
+			Group:   "testgroup",
+			Status:  testCase.status,
+		}
+
+		mockModule := &helpers.MockModule{}
+		mockModule.On("GetName").Return("test")
+		if testCase.expected {
+			mockModule.On("Notify", response, "testid", mock.MatchedBy(func(t time.Time) bool { return true }), testCase.status == protocol.StatusOK).Return(nil)
+		}
+
+		coordinator.running.Add(1)
+		coordinator.notifyModule(mockModule, response, time.Now().Add(-testCase.started), "testid")
+
+		mockModule.AssertExpectations(t)
+		if !testCase.expected {
+			mockModule.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+		if testCase.expected && (testCase.status == protocol.StatusError) {
+			assert.Falsef(t, group.LastNotify["test"].IsZero(), "TEST %v: Expected escalation to be recorded", i)
+		}
+	}
+}
+
+func TestCoordinator_Configure_Throttle(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("notifier.test.throttle", 600)
+	coordinator.Configure()
+
+	assert.Equalf(t, 600*time.Second, coordinator.throttles["test"], "Expected throttle for module test to be 600s, not %v", coordinator.throttles["test"])
+
+	coordinator = fixtureCoordinator()
+	viper.Set("notifier.test.throttle", 0)
+	assert.Panics(t, func() { coordinator.Configure() }, "The code did not panic")
+}
+
+func TestCoordinator_notifyModule_Throttle(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.throttles = map[string]time.Duration{"test": 10 * time.Minute}
+	coordinator.lastSent = map[string]map[string]time.Time{"test": make(map[string]time.Time)}
+	coordinator.sentLock = &sync.Mutex{}
+	coordinator.clusters = make(map[string]*clusterGroups)
+	coordinator.clusters["testcluster"] = &clusterGroups{
+		Lock:   &sync.RWMutex{},
+		Groups: make(map[string]*consumerGroup),
+	}
+	viper.Reset()
+	viper.Set("notifier.test.threshold", 3)
+	viper.Set("notifier.test.send-close", true)
+
+	// The group flaps between ERR and OK. Only the first incident is notified (open and close) within the window
+	testCases := []struct {
+		status   protocol.StatusConstant
+		expected bool
+	}{
+		{protocol.StatusError, true},
+		{protocol.StatusOK, true},
+		{protocol.StatusError, false},
+		{protocol.StatusOK, false},
+	}
+
+	group := &consumerGroup{
+		LastNotify: make(map[string]time.Time),
+	}
+	coordinator.clusters["testcluster"].Groups["testgroup"] = group
+	for i, testCase := range testCases {
+		response := &protocol.ConsumerGroupStatus{
+			Cluster: "testcluster",
+			Group:   "testgroup",
+			Status:  testCase.status,
+		}
+
+		mockModule := &helpers.MockModule{}
+		mockModule.On("GetName").Return("test")
+		if testCase.expected {
+			mockModule.On("Notify", response, "testid", mock.MatchedBy(func(t time.Time) bool { return true }), testCase.status == protocol.StatusOK).Return(nil)
+		}
+
+		coordinator.running.Add(1)
+		coordinator.notifyModule(mockModule, response, time.Now().Add(-time.Minute), "testid")
+
+		mockModule.AssertExpectations(t)
+		if !testCase.expected {
+			mockModule.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+		if testCase.status == protocol.StatusOK {
+			assert.Truef(t, group.LastNotify["test"].IsZero(), "TEST %v: Expected last notification to be cleared", i)
+		}
+	}
+
+	// Once the window has passed, the group is notified again
+	coordinator.lastSent["test"]["testcluster/testgroup"] = time.Now().Add(-11 * time.Minute)
+	response := &protocol.ConsumerGroupStatus{
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		Status:  protocol.StatusError,
+	}
+	mockModule := &helpers.MockModule{}
+	mockModule.On("GetName").Return("test")
+	mockModule.On("Notify", response, "testid", mock.MatchedBy(func(t time.Time) bool { return true }), false).Return(nil)
+
+	coordinator.running.Add(1)
+	coordinator.notifyModule(mockModule, response, time.Now().Add(-time.Minute), "testid")
+	mockModule.AssertExpectations(t)
+}
+
+func TestCoordinator_clearThrottles(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.throttles = map[string]time.Duration{"test": 10 * time.Minute}
+	coordinator.lastSent = map[string]map[string]time.Time{"test": {
+		"testcluster/recent":  time.Now().Add(-time.Minute),
+		"testcluster/expired": time.Now().Add(-11 * time.Minute),
+	}}
+	coordinator.sentLock = &sync.Mutex{}
+
+	// When an incident closes, only a send time outside the window is removed
+	coordinator.clearThrottles("testcluster/recent", time.Now(), true)
+	coordinator.clearThrottles("testcluster/expired", time.Now(), true)
+	assert.Len(t, coordinator.lastSent["test"], 1)
+	assert.Contains(t, coordinator.lastSent["test"], "testcluster/recent")
+
+	// When the group is removed, the send time is removed whether or not it is in the window
+	coordinator.clearThrottles("testcluster/recent", time.Time{}, false)
+	assert.Empty(t, coordinator.lastSent["test"])
+}
+
+func TestCoordinator_processConsumerList_ClearThrottles(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.minInterval = 60
+	coordinator.clusterLock = &sync.RWMutex{}
+	coordinator.throttles = map[string]time.Duration{"test": 10 * time.Minute}
+	coordinator.lastSent = map[string]map[string]time.Time{"test": {
+		"testcluster/testgroup":  time.Now(),
+		"testcluster/testgroup2": time.Now(),
+	}}
+	coordinator.sentLock = &sync.Mutex{}
+	coordinator.clusters = map[string]*clusterGroups{"testcluster": {
+		Lock: &sync.RWMutex{},
+		Groups: map[string]*consumerGroup{
+			"testgroup":  {LastNotify: make(map[string]time.Time)},
+			"testgroup2": {LastNotify: make(map[string]time.Time)},
+		},
+	}}
+
+	// testgroup2 is no longer in storage, so it is removed along with its throttle
+	replyChan := make(chan interface{}, 1)
+	replyChan <- []string{"testgroup"}
+	coordinator.running.Add(1)
+	coordinator.processConsumerList("testcluster", replyChan)
+
+	assert.NotContains(t, coordinator.clusters["testcluster"].Groups, "testgroup2")
+	assert.Len(t, coordinator.lastSent["test"], 1)
+	assert.Contains(t, coordinator.lastSent["test"], "testcluster/testgroup")
+}
@@ -0,0 +1,149 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package notifier
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// When several Burrow instances run for availability, only one of them should send notifications. The election
+// decides which one that is. All of the instances serve the HTTP API either way.
+const (
+	// electionZookeeper uses a lock in Zookeeper. The instance holding the lock sends notifications, and another
+	// instance takes over when its Zookeeper session expires. This is the default
+	electionZookeeper = "zookeeper"
+
+	// electionStandby makes this instance a standby for another instance. It only sends notifications while the
+	// active instance's health check is failing
+	electionStandby = "standby"
+
+	// electionNone always sends notifications, for when there is only one instance
+	electionNone = "none"
+)
+
+// Election returns the configured method for deciding which Burrow instance sends notifications. Burrow only
+// needs a Zookeeper connection for notifiers if this is "zookeeper".
+func Election() string {
+	viper.SetDefault("general.notifier-election", electionZookeeper)
+	return viper.GetString("general.notifier-election")
+}
+
+// standbyConfig is how a standby instance watches the active instance
+type standbyConfig struct {
+	activeURL      string
+	checkInterval  time.Duration
+	failoverChecks int
+}
+
+// configureElection reads and validates the notifier election configuration. An unknown election, or a standby
+// election without an active-url, will cause this func to panic.
+func (nc *Coordinator) configureElection() {
+	nc.election = Election()
+
+	switch nc.election {
+	case electionZookeeper, electionNone:
+	case electionStandby:
+		viper.SetDefault("general.notifier-check-interval", 10)
+		viper.SetDefault("general.notifier-failover-checks", 3)
+		nc.standby = standbyConfig{
+			activeURL:      viper.GetString("general.notifier-active-url"),
+			checkInterval:  time.Duration(viper.GetInt("general.notifier-check-interval")) * time.Second,
+			failoverChecks: viper.GetInt("general.notifier-failover-checks"),
+		}
+		if nc.standby.activeURL == "" {
+			panic("general.notifier-active-url must be set for the standby notifier election")
+		}
+		if nc.standby.checkInterval <= 0 {
+			panic("general.notifier-check-interval must be greater than zero")
+		}
+		if nc.standby.failoverChecks < 1 {
+			panic("general.notifier-failover-checks must be at least 1")
+		}
+	default:
+		panic("unknown general.notifier-election '" + nc.election + "' (must be zookeeper, standby, or none)")
+	}
+}
+
+// startElection starts the goroutine that decides whether or not this instance is performing evaluations
+func (nc *Coordinator) startElection() {
+	switch nc.election {
+	case electionStandby:
+		go nc.manageStandbyLoop(&http.Client{Timeout: nc.standby.checkInterval})
+	case electionNone:
+		nc.startEvaluations()
+	default:
+		go nc.manageEvalLoop()
+	}
+}
+
+// startEvaluations starts the goroutine that sends evaluator requests. If the goroutine from the last time this
+// instance was performing evaluations has not exited yet, this waits for it first, so that there is never more than one
+// sending requests for the same groups. It is only called from the election goroutine.
+func (nc *Coordinator) startEvaluations() {
+	nc.sender.Wait()
+
+	nc.doEvaluations.Store(true)
+	nc.running.Add(1)
+	nc.sender.Add(1)
+	go func() {
+		defer nc.sender.Done()
+		nc.sendEvaluatorRequests()
+	}()
+	nc.Log.Info("starting evaluations")
+}
+
+// manageStandbyLoop checks the health of the active instance every check interval. Once the check has failed
+// failoverChecks times in a row, this instance starts sending notifications. It stops again as soon as the active
+// instance is healthy, so that both do not send notifications.
+func (nc *Coordinator) manageStandbyLoop(client *http.Client) {
+	ticker := time.NewTicker(nc.standby.checkInterval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-ticker.C:
+			if nc.activeIsHealthy(client) {
+				failures = 0
+				if nc.doEvaluations.Load() {
+					nc.doEvaluations.Store(false)
+					nc.Log.Info("stopping evaluations, active instance is healthy")
+				}
+				continue
+			}
+
+			failures++
+			if (failures >= nc.standby.failoverChecks) && !nc.doEvaluations.Load() {
+				nc.Log.Warn("active instance is not healthy, taking over notifications", zap.Int("failed_checks", failures))
+				nc.startEvaluations()
+			}
+		case <-nc.quitChannel:
+			return
+		}
+	}
+}
+
+func (nc *Coordinator) activeIsHealthy(client *http.Client) bool {
+	response, err := client.Get(nc.standby.activeURL)
+	if err != nil {
+		nc.Log.Debug("active instance health check failed", zap.Error(err))
+		return false
+	}
+	response.Body.Close()
+	if (response.StatusCode < 200) || (response.StatusCode > 299) {
+		nc.Log.Debug("active instance health check failed", zap.Int("status_code", response.StatusCode))
+		return false
+	}
+	return true
+}
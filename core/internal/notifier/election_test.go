@@ -0,0 +1,116 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package notifier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoordinator_Configure_Election(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.Configure()
+	assert.Equal(t, electionZookeeper, coordinator.election, "Expected default election to be zookeeper")
+
+	coordinator = fixtureCoordinator()
+	viper.Set("general.notifier-election", "standby")
+	viper.Set("general.notifier-active-url", "http://burrow-active.example.com:8000/burrow/admin")
+	coordinator.Configure()
+	assert.Equal(t, electionStandby, coordinator.election, "Expected election to be standby")
+	assert.Equal(t, standbyConfig{
+		activeURL:      "http://burrow-active.example.com:8000/burrow/admin",
+		checkInterval:  10 * time.Second,
+		failoverChecks: 3,
+	}, coordinator.standby)
+}
+
+func TestCoordinator_Configure_BadElection(t *testing.T) {
+	badConfigs := []map[string]interface{}{
+		{"general.notifier-election": "bully"},
+		{"general.notifier-election": "standby"},
+		{"general.notifier-election": "standby", "general.notifier-active-url": "http://localhost:8000/burrow/admin", "general.notifier-check-interval": 0},
+		{"general.notifier-election": "standby", "general.notifier-active-url": "http://localhost:8000/burrow/admin", "general.notifier-failover-checks": 0},
+	}
+	for _, config := range badConfigs {
+		coordinator := fixtureCoordinator()
+		for key, value := range config {
+			viper.Set(key, value)
+		}
+		assert.Panicsf(t, func() { coordinator.Configure() }, "Expected panic for config %v", config)
+	}
+}
+
+func TestCoordinator_manageStandbyLoop(t *testing.T) {
+	var activeHealthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if activeHealthy.Load() {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	coordinator := fixtureCoordinator()
+	viper.Set("general.notifier-election", "standby")
+	viper.Set("general.notifier-active-url", server.URL)
+	coordinator.Configure()
+	coordinator.standby.checkInterval = 10 * time.Millisecond
+	coordinator.standby.failoverChecks = 2
+
+	activeHealthy.Store(true)
+	go coordinator.manageStandbyLoop(server.Client())
+	defer close(coordinator.quitChannel)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, coordinator.doEvaluations.Load(), "Expected standby to not evaluate while the active instance is healthy")
+
+	// The standby takes over once the active instance fails its checks
+	activeHealthy.Store(false)
+	assert.Eventually(t, func() bool { return coordinator.doEvaluations.Load() }, time.Second, 10*time.Millisecond, "Expected standby to start evaluations")
+
+	// And stops when the active instance is back
+	activeHealthy.Store(true)
+	assert.Eventually(t, func() bool { return !coordinator.doEvaluations.Load() }, time.Second, 10*time.Millisecond, "Expected standby to stop evaluations")
+}
+
+func TestCoordinator_startEvaluations_Restart(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.Configure()
+	coordinator.clusters["testcluster"] = &clusterGroups{
+		Lock: &sync.RWMutex{},
+		Groups: map[string]*consumerGroup{
+			"testgroup": {LastNotify: make(map[string]time.Time)},
+		},
+	}
+
+	// Stopping and starting again right away must not leave two goroutines sending requests for the group
+	coordinator.startEvaluations()
+	coordinator.doEvaluations.Store(false)
+	coordinator.startEvaluations()
+
+	request := <-coordinator.App.EvaluatorChannel
+	assert.Equal(t, "testgroup", request.Group, "Expected a request for testgroup")
+	select {
+	case <-coordinator.App.EvaluatorChannel:
+		assert.Fail(t, "Expected only one request for testgroup")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	coordinator.doEvaluations.Store(false)
+	coordinator.sender.Wait()
+}
@@ -167,8 +167,8 @@ func (module *EmailNotifier) AcceptConsumerGroup(status *protocol.ConsumerGroupS
 
 // Notify sends a single email message, with the from and to set to the configured addresses for the notifier. The
 // status, eventID, and startTime are all passed to the template for compiling the message. If stateGood is true, the
-// "close" template is used. Otherwise, the "open" template is used.
-func (module *EmailNotifier) Notify(status *protocol.ConsumerGroupStatus, eventID string, startTime time.Time, stateGood bool) {
+// "close" template is used. Otherwise, the "open" template is used. An error is returned if the message could not be sent.
+func (module *EmailNotifier) Notify(status *protocol.ConsumerGroupStatus, eventID string, startTime time.Time, stateGood bool) error {
 	logger := module.Log.With(
 		zap.String("cluster", status.Cluster),
 		zap.String("group", status.Group),
@@ -188,17 +188,18 @@ func (module *EmailNotifier) Notify(status *protocol.ConsumerGroupStatus, eventI
 
 	if err != nil {
 		logger.Error("failed to assemble", zap.Error(err))
-		return
+		return err
 	}
 
 	// Process template headers and send email
-	if m, err := module.createMessage(messageContent.String()); err == nil {
-		if err := module.sendMailFunc(m); err != nil {
-			logger.Error("failed to send", zap.Error(err))
-		}
-	} else {
+	m, err := module.createMessage(messageContent.String())
+	if err == nil {
+		err = module.sendMailFunc(m)
+	}
+	if err != nil {
 		logger.Error("failed to send", zap.Error(err))
 	}
+	return err
 }
 
 // sendEmail uses the gomail smtpDialer to send a constructed message. This function is mocked for testing purposes
@@ -0,0 +1,94 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package notifier
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// maxFallbackDepth is the most fallback modules that will be tried for a single notification. This stops a chain of
+// fallbacks that loops back on itself from sending forever.
+const maxFallbackDepth = 3
+
+// validateFallbacks makes sure that every fallback refers to another configured notifier module. Any bad fallback will
+// cause this func to panic, as it is called when configuring the coordinator.
+func (nc *Coordinator) validateFallbacks() {
+	for name, fallback := range nc.fallbacks {
+		if fallback == name {
+			panic("notifier " + name + " cannot be its own fallback")
+		}
+		if _, ok := nc.modules[fallback]; !ok {
+			panic("notifier " + name + " has an unknown fallback notifier '" + fallback + "'")
+		}
+	}
+}
+
+// sendNotification calls the module Notify func. If it fails, and the module has send-retries or a fallback configured,
+// the retries and the fallback are done in a goroutine. This is called with the cluster locks held, which the backoff
+// between retries would otherwise hold up the other notifications and the group list refresh for.
+func (nc *Coordinator) sendNotification(module Module, status *protocol.ConsumerGroupStatus, eventID string, startTime time.Time, stateGood bool) {
+	err := module.Notify(status, eventID, startTime, stateGood)
+	if err == nil {
+		return
+	}
+	if _, ok := nc.fallbacks[module.GetName()]; (!ok) && (viper.GetInt("notifier."+module.GetName()+".send-retries") == 0) {
+		return
+	}
+
+	nc.running.Add(1)
+	go func() {
+		defer nc.running.Done()
+		nc.retryNotification(module, status, eventID, startTime, stateGood, err, 0)
+	}()
+}
+
+// retryNotification calls the module Notify func again after it failed with err, up to the module's send-retries
+// times. If all attempts fail and the module has a fallback configured, the notification is sent with the fallback
+// module instead, regardless of the fallback module's own allowlist, denylist, and threshold. It returns true if any
+// module sent it.
+func (nc *Coordinator) retryNotification(module Module, status *protocol.ConsumerGroupStatus, eventID string, startTime time.Time, stateGood bool, err error, depth int) bool {
+	configRoot := "notifier." + module.GetName()
+	for attempt := 1; (err != nil) && (attempt <= viper.GetInt(configRoot+".send-retries")); attempt++ {
+		time.Sleep(time.Duration(viper.GetInt(configRoot+".send-retry-backoff")) * time.Millisecond)
+		err = module.Notify(status, eventID, startTime, stateGood)
+	}
+	if err == nil {
+		return true
+	}
+
+	fallback, ok := nc.fallbacks[module.GetName()]
+	if !ok {
+		return false
+	}
+	if depth >= maxFallbackDepth {
+		nc.Log.Error("not sending to fallback notifier, too many fallbacks",
+			zap.String("notifier", module.GetName()),
+			zap.String("fallback", fallback),
+			zap.String("cluster", status.Cluster),
+			zap.String("group", status.Group),
+		)
+		return false
+	}
+
+	nc.Log.Warn("sending to fallback notifier",
+		zap.String("notifier", module.GetName()),
+		zap.String("fallback", fallback),
+		zap.String("cluster", status.Cluster),
+		zap.String("group", status.Group),
+		zap.Error(err),
+	)
+	fallbackModule := nc.modules[fallback].(Module)
+	return nc.retryNotification(fallbackModule, status, eventID, startTime, stateGood, fallbackModule.Notify(status, eventID, startTime, stateGood), depth+1)
+}
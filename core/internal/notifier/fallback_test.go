@@ -0,0 +1,109 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package notifier
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linkedin/Burrow/core/internal/helpers"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func fixtureFallbackModule(coordinator *Coordinator, name string, notifyErr error) *helpers.MockModule {
+	module := &helpers.MockModule{}
+	module.On("GetName").Return(name)
+	module.On("Notify", mock.Anything, "testid", mock.Anything, false).Return(notifyErr)
+	coordinator.modules[name] = module
+	return module
+}
+
+func TestCoordinator_Configure_Fallback(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("notifier.test.fallback", "backup")
+	viper.Set("notifier.backup.class-name", "null")
+	viper.Set("notifier.backup.template-open", "template_open")
+	coordinator.Configure()
+
+	assert.Equal(t, map[string]string{"test": "backup"}, coordinator.fallbacks)
+}
+
+func TestCoordinator_Configure_BadFallback(t *testing.T) {
+	for _, fallback := range []string{"nomodule", "test"} {
+		coordinator := fixtureCoordinator()
+		viper.Set("notifier.test.fallback", fallback)
+
+		assert.Panicsf(t, func() { coordinator.Configure() }, "The code did not panic for fallback %v", fallback)
+	}
+}
+
+func TestCoordinator_sendNotification(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.modules = make(map[string]protocol.Module)
+	coordinator.fallbacks = map[string]string{"primary": "secondary"}
+	viper.Set("notifier.primary.send-retries", 2)
+	viper.Set("notifier.primary.send-retry-backoff", 100)
+
+	primary := fixtureFallbackModule(coordinator, "primary", errors.New("send failed"))
+	secondary := fixtureFallbackModule(coordinator, "secondary", nil)
+
+	// Only the first attempt is made before returning, and the retries and fallback are done afterwards
+	coordinator.sendNotification(primary, &protocol.ConsumerGroupStatus{}, "testid", time.Now(), false)
+	primary.AssertNumberOfCalls(t, "Notify", 1)
+	secondary.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	coordinator.running.Wait()
+	primary.AssertNumberOfCalls(t, "Notify", 3)
+	secondary.AssertNumberOfCalls(t, "Notify", 1)
+
+	// No fallback is used when the primary succeeds
+	primary = fixtureFallbackModule(coordinator, "primary", nil)
+	secondary = fixtureFallbackModule(coordinator, "secondary", nil)
+
+	coordinator.sendNotification(primary, &protocol.ConsumerGroupStatus{}, "testid", time.Now(), false)
+	coordinator.running.Wait()
+	primary.AssertNumberOfCalls(t, "Notify", 1)
+	secondary.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCoordinator_retryNotification(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.modules = make(map[string]protocol.Module)
+	coordinator.fallbacks = map[string]string{"primary": "secondary"}
+	viper.Set("notifier.primary.send-retries", 2)
+	viper.Set("notifier.primary.send-retry-backoff", 1)
+
+	primary := fixtureFallbackModule(coordinator, "primary", errors.New("send failed"))
+	secondary := fixtureFallbackModule(coordinator, "secondary", nil)
+
+	sent := coordinator.retryNotification(primary, &protocol.ConsumerGroupStatus{}, "testid", time.Now(), false, errors.New("send failed"), 0)
+	assert.True(t, sent, "Expected notification to be sent by the fallback")
+	primary.AssertNumberOfCalls(t, "Notify", 2)
+	secondary.AssertNumberOfCalls(t, "Notify", 1)
+}
+
+func TestCoordinator_retryNotification_FallbackLoop(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.modules = make(map[string]protocol.Module)
+	coordinator.fallbacks = map[string]string{"first": "second", "second": "first"}
+
+	first := fixtureFallbackModule(coordinator, "first", errors.New("send failed"))
+	second := fixtureFallbackModule(coordinator, "second", errors.New("send failed"))
+
+	sent := coordinator.retryNotification(first, &protocol.ConsumerGroupStatus{}, "testid", time.Now(), false, errors.New("send failed"), 0)
+	assert.False(t, sent, "Expected notification to not be sent")
+	first.AssertNumberOfCalls(t, "Notify", 1)
+	second.AssertNumberOfCalls(t, "Notify", 2)
+}
@@ -63,6 +63,14 @@ var helperFunctionMap = template.FuncMap{
 	"divide":          templateDivide,
 	"maxlag":          maxLagHelper,
 	"formattimestamp": formatTimestamp,
+
+	// Partition list helpers (see partitions.go). partitionlist and partitionsomitted are replaced for each notifier
+	// module to use its partition-format and partition-limit
+	"sortbylag":         templateSortByLag,
+	"limitpartitions":   templateLimitPartitions,
+	"toplag":            templateTopLag,
+	"partitionlist":     defaultPartitionList.list,
+	"partitionsomitted": defaultPartitionList.omitted,
 }
 
 // Helper function for the templates to encode an object into a JSON string
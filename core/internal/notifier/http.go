@@ -11,6 +11,7 @@ package notifier
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -151,8 +152,8 @@ func (module *HTTPNotifier) AcceptConsumerGroup(status *protocol.ConsumerGroupSt
 
 // Notify makes a single outbound HTTP request. The status, eventID, and startTime are all passed to the template for
 // compiling the request body. If stateGood is true, the "close" template and URL are used. Otherwise, the "open"
-// template and URL are used.
-func (module *HTTPNotifier) Notify(status *protocol.ConsumerGroupStatus, eventID string, startTime time.Time, stateGood bool) {
+// template and URL are used. An error is returned if the request could not be sent, or the response was not a 2xx.
+func (module *HTTPNotifier) Notify(status *protocol.ConsumerGroupStatus, eventID string, startTime time.Time, stateGood bool) error {
 	logger := module.Log.With(
 		zap.String("cluster", status.Cluster),
 		zap.String("group", status.Group),
@@ -177,26 +178,26 @@ func (module *HTTPNotifier) Notify(status *protocol.ConsumerGroupStatus, eventID
 	bytesToSend, err := executeTemplate(tmpl, module.extras, status, eventID, startTime)
 	if err != nil {
 		logger.Error("failed to assemble message", zap.Error(err))
-		return
+		return err
 	}
 
 	urlTmpl, err := template.New("url").Parse(url)
 	if err != nil {
 		logger.Error("failed to parse url", zap.Error(err))
-		return
+		return err
 	}
 
 	urlToSend, err := executeTemplate(urlTmpl, module.extras, status, eventID, startTime)
 	if err != nil {
 		logger.Error("failed to assemble url", zap.Error(err))
-		return
+		return err
 	}
 
 	// Send request to HTTP endpoint
 	req, err := http.NewRequest(method, urlToSend.String(), bytesToSend)
 	if err != nil {
 		logger.Error("failed to create request", zap.Error(err))
-		return
+		return err
 	}
 	username := viper.GetString("notifier." + module.name + ".username")
 	if username != "" {
@@ -212,14 +213,15 @@ func (module *HTTPNotifier) Notify(status *protocol.ConsumerGroupStatus, eventID
 	resp, err := module.httpClient.Do(req)
 	if err != nil {
 		logger.Error("failed to send", zap.Error(err))
-		return
+		return err
 	}
 	io.Copy(io.Discard, resp.Body)
 	resp.Body.Close()
 
-	if (resp.StatusCode >= 200) && (resp.StatusCode <= 299) {
-		logger.Debug("sent")
-	} else {
+	if (resp.StatusCode < 200) || (resp.StatusCode > 299) {
 		logger.Error("failed to send", zap.Int("response", resp.StatusCode))
+		return fmt.Errorf("bad response code %v", resp.StatusCode)
 	}
+	logger.Debug("sent")
+	return nil
 }
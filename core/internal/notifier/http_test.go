@@ -197,3 +197,28 @@ func TestHttpNotifier_Notify_Close(t *testing.T) {
 
 	module.Notify(status, "testidstring", time.Now(), true)
 }
+
+func TestHttpNotifier_Notify_BadResponse(t *testing.T) {
+	// handler that always fails
+	requestHandler := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}
+
+	// create test server with handler
+	ts := httptest.NewServer(http.HandlerFunc(requestHandler))
+	defer ts.Close()
+
+	module := fixtureHTTPNotifier()
+	viper.Set("notifier.test.url-open", ts.URL)
+	module.templateOpen, _ = template.New("test").Parse("{\"id\":\"{{.ID}}\"}")
+	module.Configure("test", "notifier.test")
+
+	status := &protocol.ConsumerGroupStatus{
+		Status:  protocol.StatusWarning,
+		Cluster: "testcluster",
+		Group:   "testgroup",
+	}
+
+	err := module.Notify(status, "testidstring", time.Now(), false)
+	assert.Error(t, err, "Expected Notify to return an error for a 503 response")
+}
@@ -0,0 +1,58 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package notifier
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sendIntervalOverride is a send interval for the groups that match a regular expression, which replaces the module's
+// send-interval for them
+type sendIntervalOverride struct {
+	Groups   *regexp.Regexp
+	Interval time.Duration
+}
+
+// parseSendIntervalOverrides parses a list of override specs, each of the form "REGEX=SECONDS". The regular expression
+// can contain "=", as the spec is split at the last one. Any bad spec will cause this func to panic, as it is called
+// when configuring the coordinator.
+func parseSendIntervalOverrides(specs []string) []sendIntervalOverride {
+	overrides := make([]sendIntervalOverride, 0, len(specs))
+	for _, spec := range specs {
+		split := strings.LastIndex(spec, "=")
+		if split <= 0 {
+			panic("bad send interval override '" + spec + "' (must be of the form REGEX=SECONDS)")
+		}
+		re, err := regexp.Compile(spec[:split])
+		if err != nil {
+			panic("bad group regular expression in send interval override '" + spec + "': " + err.Error())
+		}
+		seconds, err := strconv.ParseInt(strings.TrimSpace(spec[split+1:]), 10, 64)
+		if (err != nil) || (seconds <= 0) {
+			panic("bad interval in send interval override '" + spec + "' (must be a number of seconds greater than zero)")
+		}
+		overrides = append(overrides, sendIntervalOverride{Groups: re, Interval: time.Duration(seconds) * time.Second})
+	}
+	return overrides
+}
+
+// matchSendInterval returns the interval of the first override that matches the group, or the default interval if
+// none of them do
+func matchSendInterval(overrides []sendIntervalOverride, group string, defaultInterval time.Duration) time.Duration {
+	for _, override := range overrides {
+		if override.Groups.MatchString(group) {
+			return override.Interval
+		}
+	}
+	return defaultInterval
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSendIntervalOverrides(t *testing.T) {
+	overrides := parseSendIntervalOverrides([]string{"^critical-.*$=300", "^a=b$= 3600 "})
+	assert.Len(t, overrides, 2)
+	assert.Equal(t, "^critical-.*$", overrides[0].Groups.String())
+	assert.Equal(t, 300*time.Second, overrides[0].Interval)
+	assert.Equal(t, "^a=b$", overrides[1].Groups.String())
+	assert.Equal(t, 3600*time.Second, overrides[1].Interval)
+}
+
+func TestParseSendIntervalOverrides_Bad(t *testing.T) {
+	for _, spec := range []string{"critical", "=300", "critical=", "critical=0", "critical=-5", "critical=soon", "[=300"} {
+		assert.Panicsf(t, func() { parseSendIntervalOverrides([]string{spec}) }, "Expected panic for override %v", spec)
+	}
+}
+
+func TestMatchSendInterval(t *testing.T) {
+	overrides := parseSendIntervalOverrides([]string{"^critical-.*$=300", "^critical-batch$=7200", "batch=3600"})
+
+	testCases := []struct {
+		group    string
+		expected time.Duration
+	}{
+		{"critical-orders", 300 * time.Second},
+		{"critical-batch", 300 * time.Second},
+		{"nightly-batch", 3600 * time.Second},
+		{"other", 60 * time.Second},
+	}
+
+	for i, testCase := range testCases {
+		result := matchSendInterval(overrides, testCase.group, 60*time.Second)
+		assert.Equalf(t, testCase.expected, result, "TEST %v: Expected interval for %v to be %v, not %v", i, testCase.group, testCase.expected, result)
+	}
+	assert.Equal(t, 60*time.Second, matchSendInterval(nil, "critical-orders", 60*time.Second), "Expected the default with no overrides")
+}
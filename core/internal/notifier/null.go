@@ -49,6 +49,9 @@ type NullNotifier struct {
 	// CalledNotify is set to true if the Notify method is called
 	CalledNotify bool
 
+	// NotifyError is returned by the Notify method
+	NotifyError error
+
 	// CalledAcceptConsumerGroup is set to true if the AcceptConsumerGroup method is called
 	CalledAcceptConsumerGroup bool
 }
@@ -102,7 +105,8 @@ func (module *NullNotifier) AcceptConsumerGroup(status *protocol.ConsumerGroupSt
 	return true
 }
 
-// Notify is a no-op for the null notifier
-func (module *NullNotifier) Notify(status *protocol.ConsumerGroupStatus, eventID string, startTime time.Time, stateGood bool) {
+// Notify is a no-op for the null notifier. It returns NotifyError, which is nil unless set
+func (module *NullNotifier) Notify(status *protocol.ConsumerGroupStatus, eventID string, startTime time.Time, stateGood bool) error {
 	module.CalledNotify = true
+	return module.NotifyError
 }
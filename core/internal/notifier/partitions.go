@@ -0,0 +1,166 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package notifier
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// The format for each partition in the partitionlist template helper, if the notifier does not set partition-format
+const defaultPartitionFormat = "{{.Topic}}:{{.Partition}} {{.Status}} lag {{.CurrentLag}}"
+
+// The partition list for templates that are not parsed for a notifier module, which lists every partition with the
+// default format
+var defaultPartitionList = &partitionList{format: template.Must(template.New("partition").Parse(defaultPartitionFormat))}
+
+// partitionList formats the partitions of a group for the partitionlist template helper. Each notifier module has its
+// own, so that the number of partitions listed and the format of each can be set for each notifier (such as to keep
+// Slack messages short for groups with many bad partitions).
+type partitionList struct {
+	format *template.Template
+	limit  int
+}
+
+// newPartitionList creates the partitionList for a notifier from its partition-format and partition-limit configs.
+// A bad format or limit will cause this func to panic, as it is called when configuring the coordinator.
+func newPartitionList(name, configRoot string) *partitionList {
+	viper.SetDefault(configRoot+".partition-format", defaultPartitionFormat)
+	format, err := template.New("partition").Funcs(helperFunctionMap).Parse(viper.GetString(configRoot + ".partition-format"))
+	if err != nil {
+		panic("notifier " + name + ": bad partition-format: " + err.Error())
+	}
+
+	limit := viper.GetInt(configRoot + ".partition-limit")
+	if limit < 0 {
+		panic("notifier " + name + ": partition-limit must be zero or greater")
+	}
+	return &partitionList{format: format, limit: limit}
+}
+
+// list returns the partitions with the most lag first, each formatted with the partition format, and at most limit of
+// them (if the limit is not zero)
+func (pl *partitionList) list(partitions []*protocol.PartitionStatus) ([]string, error) {
+	sorted := templateSortByLag(partitions)
+	if pl.limit > 0 {
+		sorted = templateLimitPartitions(pl.limit, sorted)
+	}
+
+	lines := make([]string, 0, len(sorted))
+	for _, partition := range sorted {
+		buf := new(bytes.Buffer)
+		if err := pl.format.Execute(buf, partition); err != nil {
+			return nil, err
+		}
+		lines = append(lines, buf.String())
+	}
+	return lines, nil
+}
+
+// omitted returns the number of partitions that list leaves out because of the limit
+func (pl *partitionList) omitted(partitions []*protocol.PartitionStatus) int {
+	if (pl.limit == 0) || (len(partitions) <= pl.limit) {
+		return 0
+	}
+	return len(partitions) - pl.limit
+}
+
+// funcs returns the template helpers that use this notifier's partition list. They replace the default helpers of the
+// same names in the notifier's templates.
+func (pl *partitionList) funcs() template.FuncMap {
+	return template.FuncMap{
+		"partitionlist":     pl.list,
+		"partitionsomitted": pl.omitted,
+	}
+}
+
+// Template Helper - Return a copy of the partitions sorted with the most lag first. Partitions with the same lag are
+// sorted by topic and partition, so that the order is stable between notifications
+func templateSortByLag(partitions []*protocol.PartitionStatus) []*protocol.PartitionStatus {
+	sorted := make([]*protocol.PartitionStatus, len(partitions))
+	copy(sorted, partitions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].CurrentLag != sorted[j].CurrentLag {
+			return sorted[i].CurrentLag > sorted[j].CurrentLag
+		}
+		if sorted[i].Topic != sorted[j].Topic {
+			return sorted[i].Topic < sorted[j].Topic
+		}
+		return sorted[i].Partition < sorted[j].Partition
+	})
+	return sorted
+}
+
+// Template Helper - Return at most the first count partitions
+func templateLimitPartitions(count int, partitions []*protocol.PartitionStatus) []*protocol.PartitionStatus {
+	if (count < 0) || (count >= len(partitions)) {
+		return partitions
+	}
+	return partitions[:count]
+}
+
+// Template Helper - Return the count partitions with the most lag, such as for {{range toplag 5 .Result.Partitions}}
+func templateTopLag(count int, partitions []*protocol.PartitionStatus) []*protocol.PartitionStatus {
+	return templateLimitPartitions(count, templateSortByLag(partitions))
+}
+
+// validateTemplate executes a notifier template with a sample group status, so that templates that fail when they are
+// executed (such as by calling a helper with the wrong arguments) are found at startup rather than when a notification
+// is sent. The sample has every field that a template can use set.
+func validateTemplate(tmpl *template.Template, extras map[string]string) error {
+	offset := &protocol.ConsumerOffset{Offset: 1000, Timestamp: 1000000, ObservedTimestamp: 1000000, Lag: &protocol.Lag{Value: 100}}
+	partition := &protocol.PartitionStatus{
+		Topic:      "topic",
+		Partition:  0,
+		Status:     protocol.StatusWarning,
+		Start:      offset,
+		End:        offset,
+		CurrentLag: 100,
+		TimeLag:    1000,
+		Complete:   1.0,
+	}
+	status := &protocol.ConsumerGroupStatus{
+		Cluster:         "cluster",
+		Group:           "group",
+		Status:          protocol.StatusWarning,
+		Baseline:        &protocol.LagBaselineHour{},
+		Complete:        1.0,
+		Partitions:      []*protocol.PartitionStatus{partition},
+		TotalPartitions: 1,
+		Maxlag:          partition,
+		TotalLag:        100,
+		MaxTimeLag:      1000,
+		Members:         []*protocol.MemberStatus{{Status: protocol.StatusWarning, PartitionCount: 1, TotalLag: 100}},
+	}
+
+	// The output is not used, so the template is executed without the hostname and time that executeTemplate adds
+	return tmpl.Execute(io.Discard, struct {
+		Cluster string
+		Group   string
+		ID      string
+		Start   time.Time
+		Extras  map[string]string
+		Result  protocol.ConsumerGroupStatus
+	}{
+		Cluster: status.Cluster,
+		Group:   status.Group,
+		ID:      "id",
+		Start:   time.Now(),
+		Extras:  extras,
+		Result:  *status,
+	})
+}
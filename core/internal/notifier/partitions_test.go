@@ -0,0 +1,92 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package notifier
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func fixturePartitions() []*protocol.PartitionStatus {
+	return []*protocol.PartitionStatus{
+		{Topic: "topicb", Partition: 0, Status: protocol.StatusWarning, CurrentLag: 200},
+		{Topic: "topica", Partition: 1, Status: protocol.StatusStop, CurrentLag: 500},
+		{Topic: "topica", Partition: 0, Status: protocol.StatusWarning, CurrentLag: 200},
+	}
+}
+
+func TestTemplateSortByLag(t *testing.T) {
+	partitions := fixturePartitions()
+	sorted := templateSortByLag(partitions)
+
+	assert.Equalf(t, uint64(500), sorted[0].CurrentLag, "Expected the most lag first, not %v", sorted[0].CurrentLag)
+	assert.Equalf(t, "topica", sorted[1].Topic, "Expected ties to be sorted by topic, not %v", sorted[1].Topic)
+	assert.Equalf(t, "topicb", partitions[0].Topic, "Expected the partitions passed in to not be changed, not %v", partitions[0].Topic)
+}
+
+func TestTemplateTopLag(t *testing.T) {
+	top := templateTopLag(2, fixturePartitions())
+	assert.Len(t, top, 2, "Expected two partitions")
+	assert.Equalf(t, int32(1), top[0].Partition, "Expected topica:1 first, not partition %v", top[0].Partition)
+
+	assert.Len(t, templateTopLag(10, fixturePartitions()), 3, "Expected all partitions when there are fewer than the count")
+}
+
+func TestPartitionList(t *testing.T) {
+	viper.Reset()
+	viper.Set("notifier.test.partition-format", "{{.Topic}}:{{.Partition}}={{.CurrentLag}}")
+	viper.Set("notifier.test.partition-limit", 2)
+	partitions := newPartitionList("test", "notifier.test")
+
+	lines, err := partitions.list(fixturePartitions())
+	assert.NoError(t, err, "Expected list to return no error")
+	assert.Equal(t, []string{"topica:1=500", "topica:0=200"}, lines)
+	assert.Equalf(t, 1, partitions.omitted(fixturePartitions()), "Expected one partition to be omitted, not %v", partitions.omitted(fixturePartitions()))
+
+	// The helpers replace the defaults in a template parsed with the helper functions
+	tmpl := template.Must(template.New("test").Funcs(helperFunctionMap).Parse(
+		`{{range partitionlist .}}{{.}} {{end}}+{{partitionsomitted .}}`)).Funcs(partitions.funcs())
+	buf := new(bytes.Buffer)
+	err = tmpl.Execute(buf, fixturePartitions())
+	assert.NoError(t, err, "Expected template to execute with no error")
+	assert.Equal(t, "topica:1=500 topica:0=200 +1", buf.String())
+}
+
+func TestPartitionList_Default(t *testing.T) {
+	viper.Reset()
+	partitions := newPartitionList("test", "notifier.test")
+
+	lines, err := partitions.list(fixturePartitions())
+	assert.NoError(t, err, "Expected list to return no error")
+	assert.Len(t, lines, 3, "Expected every partition to be listed")
+	assert.Equal(t, "topica:1 STOP lag 500", lines[0])
+	assert.Equalf(t, 0, partitions.omitted(fixturePartitions()), "Expected no partitions to be omitted, not %v", partitions.omitted(fixturePartitions()))
+}
+
+func TestNewPartitionList_BadLimit(t *testing.T) {
+	viper.Reset()
+	viper.Set("notifier.test.partition-limit", -1)
+	assert.Panics(t, func() { newPartitionList("test", "notifier.test") }, "The code did not panic")
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("test").Funcs(helperFunctionMap).Parse(
+		`{{.Group}} {{.Result.Maxlag.End.Lag}} {{range toplag 5 .Result.Partitions}}{{.Topic}}{{end}}`))
+	assert.NoError(t, validateTemplate(tmpl, nil), "Expected the template to be valid")
+
+	tmpl = template.Must(template.New("test").Funcs(helperFunctionMap).Parse(`{{.Result.NoField}}`))
+	assert.Error(t, validateTemplate(tmpl, nil), "Expected an error for a field that does not exist")
+}
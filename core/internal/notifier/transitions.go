@@ -0,0 +1,73 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package notifier
+
+import (
+	"strings"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// statusTransition describes a change in group status that a notifier module sends open notifications for. A nil
+// From or To matches any status.
+type statusTransition struct {
+	From *protocol.StatusConstant
+	To   *protocol.StatusConstant
+}
+
+// The statuses that a group (rather than a partition) can have
+var groupStatuses = []protocol.StatusConstant{
+	protocol.StatusNotFound,
+	protocol.StatusOK,
+	protocol.StatusWarning,
+	protocol.StatusError,
+}
+
+// parseStatusTransitions parses a list of transition specs, each of the form "FROM->TO", where FROM and TO are the
+// group status names (NOTFOUND, OK, WARN, or ERR) or "*" to match any status. Any bad spec will cause this func to
+// panic, as it is called when configuring the coordinator.
+func parseStatusTransitions(specs []string) []statusTransition {
+	transitions := make([]statusTransition, 0, len(specs))
+	for _, spec := range specs {
+		parts := strings.Split(spec, "->")
+		if len(parts) != 2 {
+			panic("bad status transition '" + spec + "' (must be of the form FROM->TO)")
+		}
+		transitions = append(transitions, statusTransition{
+			From: parseTransitionStatus(spec, parts[0]),
+			To:   parseTransitionStatus(spec, parts[1]),
+		})
+	}
+	return transitions
+}
+
+func parseTransitionStatus(spec, name string) *protocol.StatusConstant {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "*" {
+		return nil
+	}
+	for _, status := range groupStatuses {
+		if status.String() == name {
+			return &status
+		}
+	}
+	panic("bad status '" + name + "' in status transition '" + spec + "' (must be one of NOTFOUND, OK, WARN, ERR, or *)")
+}
+
+// matchStatusTransition returns true if the change from the previous status to the current status matches any of the
+// transitions
+func matchStatusTransition(transitions []statusTransition, previous, current protocol.StatusConstant) bool {
+	for _, transition := range transitions {
+		if ((transition.From == nil) || (*transition.From == previous)) && ((transition.To == nil) || (*transition.To == current)) {
+			return true
+		}
+	}
+	return false
+}
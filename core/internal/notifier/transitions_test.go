@@ -0,0 +1,58 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package notifier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func TestParseStatusTransitions(t *testing.T) {
+	transitions := parseStatusTransitions([]string{"OK->ERR", " warn -> * ", "*->NOTFOUND"})
+	assert.Len(t, transitions, 3)
+	assert.Equal(t, protocol.StatusOK, *transitions[0].From)
+	assert.Equal(t, protocol.StatusError, *transitions[0].To)
+	assert.Equal(t, protocol.StatusWarning, *transitions[1].From)
+	assert.Nil(t, transitions[1].To)
+	assert.Nil(t, transitions[2].From)
+	assert.Equal(t, protocol.StatusNotFound, *transitions[2].To)
+}
+
+func TestParseStatusTransitions_Bad(t *testing.T) {
+	for _, spec := range []string{"OK", "OK->ERR->OK", "OK->STOP", "->ERR", "FOO->ERR"} {
+		assert.Panicsf(t, func() { parseStatusTransitions([]string{spec}) }, "Expected panic for transition %v", spec)
+	}
+}
+
+func TestMatchStatusTransition(t *testing.T) {
+	transitions := parseStatusTransitions([]string{"OK->ERR", "*->WARN"})
+
+	testCases := []struct {
+		previous protocol.StatusConstant
+		current  protocol.StatusConstant
+		expected bool
+	}{
+		{protocol.StatusOK, protocol.StatusError, true},
+		{protocol.StatusWarning, protocol.StatusError, false},
+		{protocol.StatusError, protocol.StatusError, false},
+		{protocol.StatusNotFound, protocol.StatusWarning, true},
+		{protocol.StatusError, protocol.StatusWarning, true},
+		{protocol.StatusError, protocol.StatusOK, false},
+	}
+
+	for i, testCase := range testCases {
+		result := matchStatusTransition(transitions, testCase.previous, testCase.current)
+		assert.Equalf(t, testCase.expected, result, "TEST %v: Expected %v->%v to be %v", i, testCase.previous, testCase.current, testCase.expected)
+	}
+	assert.False(t, matchStatusTransition([]statusTransition{}, protocol.StatusOK, protocol.StatusError), "Expected empty transitions to match nothing")
+}
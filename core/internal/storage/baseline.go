@@ -0,0 +1,110 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+type baselineHour struct {
+	samples  int64
+	mean     float64
+	variance float64
+}
+
+// lagBaseline is the stored form of a protocol.LagBaseline. The variance is stored, rather than the standard
+// deviation, as that is what is updated with each sample
+type lagBaseline struct {
+	hours [24]baselineHour
+}
+
+// addSample adds a lag sample to the hour. Until the hour has maxSamples samples, the mean and variance are those of all
+// the samples. After that, they are exponentially weighted, so that older samples fade out and the baseline follows
+// slow changes in the group's traffic.
+func (hour *baselineHour) addSample(lag float64, maxSamples int64) {
+	hour.samples++
+	weight := 1.0 / float64(hour.samples)
+	if hour.samples > maxSamples {
+		weight = 1.0 / float64(maxSamples)
+	}
+
+	diff := lag - hour.mean
+	increment := weight * diff
+	hour.mean += increment
+	hour.variance = (1 - weight) * (hour.variance + diff*increment)
+}
+
+func (module *InMemoryStorage) addBaselineSample(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.consumerLock.RLock()
+	consumerMap, ok := clusterMap.consumer[request.Group]
+	clusterMap.consumerLock.RUnlock()
+	if !ok {
+		// The group may have just been deleted, so there is nothing to keep a baseline for
+		requestLogger.Debug("dropped", zap.String("reason", "unknown consumer"))
+		return
+	}
+
+	consumerMap.lock.Lock()
+	defer consumerMap.lock.Unlock()
+
+	if consumerMap.baseline == nil {
+		consumerMap.baseline = &lagBaseline{}
+	}
+	hour := time.Unix(request.Timestamp/1000, 0).UTC().Hour()
+	consumerMap.baseline.hours[hour].addSample(float64(request.Lag), module.baselineSamples)
+	requestLogger.Debug("ok", zap.Uint64("lag", request.Lag))
+}
+
+func (module *InMemoryStorage) fetchGroupBaseline(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.consumerLock.RLock()
+	consumerMap, ok := clusterMap.consumer[request.Group]
+	clusterMap.consumerLock.RUnlock()
+	if !ok {
+		requestLogger.Warn("unknown consumer")
+		return
+	}
+
+	consumerMap.lock.RLock()
+	defer consumerMap.lock.RUnlock()
+
+	// A group that has no samples yet has an empty baseline
+	baseline := &protocol.LagBaseline{}
+	if consumerMap.baseline != nil {
+		for i, hour := range consumerMap.baseline.hours {
+			baseline.Hours[i] = protocol.LagBaselineHour{
+				Samples: hour.samples,
+				Mean:    hour.mean,
+				StdDev:  math.Sqrt(hour.variance),
+			}
+		}
+	}
+
+	requestLogger.Debug("ok")
+	request.Reply <- baseline
+}
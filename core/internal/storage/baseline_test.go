@@ -0,0 +1,91 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func fetchGroupBaselineSync(module *InMemoryStorage, cluster, group string) interface{} {
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchGroupBaseline,
+		Cluster:     cluster,
+		Group:       group,
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchGroupBaseline(&request, module.Log)
+	return <-request.Reply
+}
+
+func TestBaselineHour_addSample(t *testing.T) {
+	hour := &baselineHour{}
+	for _, lag := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		hour.addSample(lag, 100)
+	}
+	assert.Equal(t, int64(8), hour.samples)
+	assert.InDelta(t, 5.0, hour.mean, 0.0001, "Expected mean of all samples")
+	assert.InDelta(t, 4.0, hour.variance, 0.0001, "Expected variance of all samples")
+
+	// Once there are more than maxSamples samples, new samples are weighted more than the old ones
+	hour = &baselineHour{}
+	for i := 0; i < 10; i++ {
+		hour.addSample(100, 5)
+	}
+	hour.addSample(200, 5)
+	assert.InDelta(t, 120.0, hour.mean, 0.0001, "Expected new sample to have a weight of 1/5")
+}
+
+func TestInMemoryStorage_addBaselineSample(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	// 3:00 UTC and 4:00 UTC on the same day
+	timestamp := time.Date(2020, 1, 1, 3, 30, 0, 0, time.UTC).Unix() * 1000
+	for _, lag := range []uint64{100, 200} {
+		module.addBaselineSample(&protocol.StorageRequest{RequestType: protocol.StorageSetGroupBaselineSample, Cluster: "testcluster", Group: "testgroup", Lag: lag, Timestamp: timestamp}, module.Log)
+	}
+	module.addBaselineSample(&protocol.StorageRequest{RequestType: protocol.StorageSetGroupBaselineSample, Cluster: "testcluster", Group: "testgroup", Lag: 500, Timestamp: timestamp + 3600000}, module.Log)
+
+	// Samples for unknown groups are dropped
+	module.addBaselineSample(&protocol.StorageRequest{RequestType: protocol.StorageSetGroupBaselineSample, Cluster: "testcluster", Group: "nogroup", Lag: 500, Timestamp: timestamp}, module.Log)
+
+	response := fetchGroupBaselineSync(module, "testcluster", "testgroup")
+	assert.IsType(t, &protocol.LagBaseline{}, response, "Expected response to be of type *protocol.LagBaseline")
+	baseline := response.(*protocol.LagBaseline)
+	assert.Equal(t, protocol.LagBaselineHour{Samples: 2, Mean: 150, StdDev: 50}, baseline.Hours[3])
+	assert.Equal(t, protocol.LagBaselineHour{Samples: 1, Mean: 500, StdDev: 0}, baseline.Hours[4])
+	assert.Equal(t, protocol.LagBaselineHour{}, baseline.Hours[5])
+
+	assert.Nil(t, fetchGroupBaselineSync(module, "testcluster", "nogroup"), "Expected response to be nil for unknown group")
+	assert.Nil(t, fetchGroupBaselineSync(module, "nocluster", "testgroup"), "Expected response to be nil for unknown cluster")
+}
+
+func TestInMemoryStorage_fetchGroupBaseline_NoSamples(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	response := fetchGroupBaselineSync(module, "testcluster", "testgroup")
+	assert.Equal(t, &protocol.LagBaseline{}, response, "Expected an empty baseline")
+}
+
+func TestInMemoryStorage_Configure_BadBaselineSamples(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.baseline-samples", 0)
+
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// setTopicCompacted records whether a topic is compacted. This is kept apart from the broker offsets for the topic, as
+// the cluster module may know the topic config before any end offsets are stored for it. It is removed along with the
+// rest of the topic when the topic is deleted.
+func (module *InMemoryStorage) setTopicCompacted(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.brokerLock.Lock()
+	if request.Compacted {
+		clusterMap.compacted[request.Topic] = true
+	} else {
+		delete(clusterMap.compacted, request.Topic)
+	}
+	clusterMap.brokerLock.Unlock()
+
+	requestLogger.Debug("ok", zap.Bool("compacted", request.Compacted))
+}
+
+func (module *InMemoryStorage) fetchCompactedTopics(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.brokerLock.RLock()
+	topics := make([]string, 0, len(clusterMap.compacted))
+	for topic := range clusterMap.compacted {
+		topics = append(topics, topic)
+	}
+	clusterMap.brokerLock.RUnlock()
+	sort.Strings(topics)
+
+	requestLogger.Debug("ok")
+	request.Reply <- topics
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func fetchCompactedTopics(module *InMemoryStorage, cluster string) interface{} {
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchCompactedTopics,
+		Cluster:     cluster,
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchCompactedTopics(&request, module.Log)
+	return <-request.Reply
+}
+
+func TestInMemoryStorage_setTopicCompacted(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+	assert.Equal(t, []string{}, fetchCompactedTopics(module, "testcluster"), "Expected no compacted topics")
+
+	for _, topic := range []string{"testtopic", "othertopic"} {
+		module.setTopicCompacted(&protocol.StorageRequest{
+			RequestType: protocol.StorageSetTopicCompacted,
+			Cluster:     "testcluster",
+			Topic:       topic,
+			Compacted:   true,
+		}, module.Log)
+	}
+	assert.Equal(t, []string{"othertopic", "testtopic"}, fetchCompactedTopics(module, "testcluster"))
+
+	// The consumer's partitions for the topic are flagged
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchConsumer(&request, module.Log)
+	response := (<-request.Reply).(protocol.ConsumerTopics)
+	assert.True(t, response["testtopic"][0].Compacted, "Expected the partition to be flagged as compacted")
+
+	// A topic that is no longer compacted is removed, as is a deleted topic
+	module.setTopicCompacted(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetTopicCompacted,
+		Cluster:     "testcluster",
+		Topic:       "othertopic",
+		Compacted:   false,
+	}, module.Log)
+	module.deleteTopic(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteTopic,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+	}, module.Log)
+	assert.Equal(t, []string{}, fetchCompactedTopics(module, "testcluster"), "Expected no compacted topics")
+}
+
+func TestInMemoryStorage_fetchCompactedTopics_BadCluster(t *testing.T) {
+	module := startWithTestCluster("")
+	assert.Nil(t, fetchCompactedTopics(module, "nocluster"), "Expected no response for an unknown cluster")
+}
@@ -100,9 +100,11 @@ func (sc *Coordinator) Configure() {
 	// Create all configured storage modules, add to list of storage
 	for name := range modules {
 		configRoot := "storage." + name
-		module := getModuleForClass(sc.App, name, viper.GetString(configRoot+".class-name"))
-		module.Configure(name, configRoot)
-		sc.modules[name] = module
+		helpers.RecordConfigError(configRoot, func() {
+			module := getModuleForClass(sc.App, name, viper.GetString(configRoot+".class-name"))
+			module.Configure(name, configRoot)
+			sc.modules[name] = module
+		})
 	}
 }
 
@@ -0,0 +1,105 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"container/ring"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// sweeper runs the sweep every offset-sweep-interval seconds, until the module is stopped. The sweep removes the consumer
+// offsets older than max-offset-age, if it is set, the unknown topics that groups have not committed to for
+// expire-group seconds, if they are recorded, and the tombstones of groups deleted more than deleted-group-retention
+// seconds ago, if it is set. It is only started if there is something to sweep.
+func (module *InMemoryStorage) sweeper() {
+	defer module.sweepRunning.Done()
+
+	ticker := time.NewTicker(time.Duration(module.offsetSweepInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now().Unix()
+			if module.maxOffsetAge > 0 {
+				module.evictOldOffsets(now - module.maxOffsetAge)
+			}
+			if module.recordUnknownTopics {
+				module.expireUnknownTopics(now - module.expireGroup)
+			}
+			if module.deletedGroupRetention > 0 {
+				module.purgeTombstones(now - module.deletedGroupRetention)
+			}
+		case <-module.sweepQuit:
+			return
+		}
+	}
+}
+
+// evictOldOffsets removes the consumer offsets that were committed before the cutoff (in seconds) from the ring of
+// every partition, in every cluster. This bounds the memory used by partitions that a group stopped committing to
+// long ago, while the group itself is kept alive by commits for its other partitions. A partition that has had every
+// offset removed stays in the group, and is evaluated as OK with no offsets until it is committed to again.
+func (module *InMemoryStorage) evictOldOffsets(cutoff int64) {
+	evicted := 0
+	for cluster, clusterMap := range module.offsets {
+		clusterMap.consumerLock.RLock()
+		groups := make([]*consumerGroup, 0, len(clusterMap.consumer))
+		for _, consumerMap := range clusterMap.consumer {
+			groups = append(groups, consumerMap)
+		}
+		clusterMap.consumerLock.RUnlock()
+
+		clusterEvicted := 0
+		for _, consumerMap := range groups {
+			consumerMap.lock.Lock()
+			for _, partitions := range consumerMap.topics {
+				for _, partition := range partitions {
+					if partition.offsets != nil {
+						clusterEvicted += evictRingOffsets(partition.offsets, cutoff*1000)
+					}
+				}
+			}
+			consumerMap.lock.Unlock()
+		}
+		if clusterEvicted > 0 {
+			module.Log.Debug("evicted old consumer offsets",
+				zap.String("cluster", cluster),
+				zap.Int("count", clusterEvicted),
+			)
+		}
+		evicted += clusterEvicted
+	}
+	if evicted > 0 {
+		module.Log.Info("evicted old consumer offsets", zap.Int("count", evicted))
+	}
+}
+
+// evictRingOffsets clears the offsets in the ring that are older than the cutoff (in milliseconds), starting from the
+// oldest, and returns how many it cleared. It stops at the first offset that is new enough, so that the cleared slots
+// are at the start of the ring, the same as the empty slots of a ring that has not been filled yet.
+func evictRingOffsets(offsetRing *ring.Ring, cutoff int64) int {
+	evicted := 0
+	ringPtr := offsetRing
+	for i := 0; i < offsetRing.Len(); i++ {
+		if ringPtr.Value != nil {
+			if ringPtr.Value.(*protocol.ConsumerOffset).Timestamp >= cutoff {
+				break
+			}
+			ringPtr.Value = nil
+			evicted++
+		}
+		ringPtr = ringPtr.Next()
+	}
+	return evicted
+}
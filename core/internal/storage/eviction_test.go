@@ -0,0 +1,125 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func fetchTestConsumer(module *InMemoryStorage) protocol.ConsumerTopics {
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchConsumer(request, module.Log)
+	return (<-request.Reply).(protocol.ConsumerTopics)
+}
+
+func TestInMemoryStorage_Configure_MaxOffsetAge(t *testing.T) {
+	module := fixtureModule("", "")
+	module.Configure("test", "storage.test")
+	assert.Equal(t, int64(0), module.maxOffsetAge, "Expected offsets to be kept by default")
+	assert.Equal(t, 300, module.offsetSweepInterval, "Expected default offset-sweep-interval of 300")
+
+	module = fixtureModule("", "")
+	viper.Set("storage.test.max-offset-age", -1)
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+
+	module = fixtureModule("", "")
+	viper.Set("storage.test.offset-sweep-interval", 0)
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
+
+func TestInMemoryStorage_evictOldOffsets(t *testing.T) {
+	// The offsets are committed every 10 seconds, from 200 seconds ago to 110 seconds ago
+	now := time.Now().Unix()
+	module := startWithTestConsumerOffsets("", (now-200)*1000)
+
+	// The five offsets from before 150 seconds ago are removed, from the start of the ring
+	module.evictOldOffsets(now - 150)
+	offsets := fetchTestConsumer(module)["testtopic"][0].Offsets
+	assert.Len(t, offsets, 10, "Expected 10 offsets for the partition")
+	for i := 0; i < 5; i++ {
+		assert.Nilf(t, offsets[i], "Expected offset at position %v to be evicted", i)
+	}
+	for i := 5; i < 10; i++ {
+		assert.NotNilf(t, offsets[i], "Expected offset at position %v to be kept", i)
+	}
+	assert.Equal(t, int64(1500), offsets[5].Offset, "Expected the oldest remaining offset to be 1500")
+
+	// Once every offset is too old, the partition has none, and no lag
+	module.evictOldOffsets(now)
+	partition := fetchTestConsumer(module)["testtopic"][0]
+	for i, offset := range partition.Offsets {
+		assert.Nilf(t, offset, "Expected offset at position %v to be evicted", i)
+	}
+	assert.Equal(t, uint64(0), partition.CurrentLag, "Expected no lag")
+
+	// A new commit is stored as the only offset
+	module.addConsumerOffset(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      3000,
+		Order:       600,
+		Timestamp:   now * 1000,
+	}, module.Log)
+	partition = fetchTestConsumer(module)["testtopic"][0]
+	assert.Nil(t, partition.Offsets[8], "Expected the ring to have one offset")
+	assert.Equal(t, int64(3000), partition.Offsets[9].Offset, "Expected the new offset to be the latest")
+	assert.Equal(t, uint64(1321), partition.CurrentLag, "Expected the lag against the new offset")
+}
+
+func TestInMemoryStorage_offsetSweeper(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	viper.Set("storage.test.max-offset-age", 3600)
+	viper.Set("storage.test.offset-sweep-interval", 1)
+	module.Configure("test", "storage.test")
+	module.Start()
+
+	request := &protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              4321,
+		Timestamp:           time.Now().Unix() * 1000,
+	}
+	module.addBrokerOffset(request, module.Log)
+	module.addConsumerOffset(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      1000,
+		Order:       1,
+		Timestamp:   (time.Now().Unix() - 7200) * 1000,
+	}, module.Log)
+
+	assert.Eventually(t, func() bool {
+		return fetchTestConsumer(module)["testtopic"][0].Offsets[9] == nil
+	}, 3*time.Second, 100*time.Millisecond, "Expected the sweep to evict the old offset")
+
+	// The sweeper stops with the module
+	module.Stop()
+}
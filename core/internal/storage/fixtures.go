@@ -37,6 +37,9 @@ func CoordinatorWithOffsets() *Coordinator {
 	viper.Set("storage.test.group-allowlist", "")
 	viper.Set("cluster.testcluster.class-name", "kafka")
 
+	// Record the topics that groups commit to that the cluster does not have, so the evaluator can be tested with them
+	viper.Set("evaluator.test.misconfigured-topic-share", 0.5)
+
 	coordinator.Configure()
 	coordinator.Start()
 
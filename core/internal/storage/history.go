@@ -0,0 +1,194 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// statusRecord is a single line of the status-history-file. The status is stored as a number, as a
+// protocol.StatusConstant is written to JSON as a string, but cannot be read back from one
+type statusRecord struct {
+	Cluster   string `json:"cluster"`
+	Group     string `json:"group"`
+	Timestamp int64  `json:"timestamp"`
+	Status    int    `json:"status"`
+}
+
+// readStatusHistory reads the status changes from the named file, which has a JSON statusRecord on each line, in the
+// order they were recorded. A file that does not exist yet is not an error, as it is created the first time a status
+// is recorded.
+func readStatusHistory(filename string) ([]statusRecord, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records := make([]statusRecord, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record statusRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// loadStatusHistory stores the status changes read from the status-history-file for the configured clusters, and then
+// rewrites the file with only the changes that were kept, so that it does not grow without limit across restarts.
+func (module *InMemoryStorage) loadStatusHistory(records []statusRecord) error {
+	for _, record := range records {
+		groups, ok := module.statusHistory[record.Cluster]
+		if !ok {
+			continue
+		}
+		groups[record.Group] = module.appendStatus(groups[record.Group], protocol.StatusTransition{
+			Timestamp: record.Timestamp,
+			Status:    protocol.StatusConstant(record.Status),
+		})
+	}
+
+	file, err := os.OpenFile(module.historyFile+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for cluster, groups := range module.statusHistory {
+		for group, history := range groups {
+			for _, transition := range history {
+				if err = encoder.Encode(statusRecord{cluster, group, transition.Timestamp, int(transition.Status)}); err != nil {
+					file.Close()
+					return err
+				}
+			}
+		}
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(module.historyFile+".tmp", module.historyFile)
+}
+
+// appendStatus adds a status change to the history for a group, if it differs from the last status, and drops the
+// changes that are older than the retention or over the limit. The last change is always kept, as it gives the
+// group's current status.
+func (module *InMemoryStorage) appendStatus(history []protocol.StatusTransition, transition protocol.StatusTransition) []protocol.StatusTransition {
+	if (len(history) > 0) && (history[len(history)-1].Status == transition.Status) {
+		return history
+	}
+	history = append(history, transition)
+
+	keepAfter := transition.Timestamp - (module.historyRetention * 1000)
+	start := 0
+	for (start < len(history)-1) && ((history[start].Timestamp < keepAfter) || (len(history)-start > module.historyLimit)) {
+		start++
+	}
+	return history[start:]
+}
+
+func (module *InMemoryStorage) addGroupStatus(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	module.historyLock.Lock()
+	defer module.historyLock.Unlock()
+
+	groups, ok := module.statusHistory[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	history := groups[request.Group]
+	if (len(history) > 0) && (history[len(history)-1].Status == request.Status) {
+		return
+	}
+	groups[request.Group] = module.appendStatus(history, protocol.StatusTransition{
+		Timestamp: request.Timestamp,
+		Status:    request.Status,
+	})
+
+	if module.historyFile != "" {
+		module.writeStatusRecord(statusRecord{request.Cluster, request.Group, request.Timestamp, int(request.Status)}, requestLogger)
+	}
+	requestLogger.Debug("ok", zap.String("status", request.Status.String()))
+}
+
+// writeStatusRecord appends a status change to the status-history-file. It must be called with the history lock held.
+func (module *InMemoryStorage) writeStatusRecord(record statusRecord, requestLogger *zap.Logger) {
+	content, err := json.Marshal(record)
+	if err == nil {
+		var file *os.File
+		file, err = os.OpenFile(module.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err == nil {
+			_, err = file.Write(append(content, '\n'))
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		requestLogger.Error("failed to write status history file",
+			zap.String("filename", module.historyFile),
+			zap.Error(err),
+		)
+	}
+}
+
+// fetchGroupStatusHistory returns the status changes for the group within the time range of the request. The last
+// change before the start of the range is included first, if there is one, so that the status of the group at the
+// start of the range is known. Groups are kept in the history after they are deleted, so an unknown group is not an
+// error, and gets an empty history.
+func (module *InMemoryStorage) fetchGroupStatusHistory(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	module.historyLock.RLock()
+	defer module.historyLock.RUnlock()
+
+	groups, ok := module.statusHistory[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	endTimestamp := request.EndTimestamp
+	if endTimestamp == 0 {
+		endTimestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+
+	stored := groups[request.Group]
+	history := make([]protocol.StatusTransition, 0)
+	for i, transition := range stored {
+		if transition.Timestamp > endTimestamp {
+			break
+		}
+		if (i+1 < len(stored)) && (stored[i+1].Timestamp <= request.Timestamp) {
+			// The group changed status again before the start of the range
+			continue
+		}
+		history = append(history, transition)
+	}
+
+	requestLogger.Debug("ok")
+	request.Reply <- history
+}
@@ -0,0 +1,149 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func fetchGroupStatusHistorySync(module *InMemoryStorage, cluster, group string, start, end int64) interface{} {
+	request := protocol.StorageRequest{
+		RequestType:  protocol.StorageFetchGroupStatusHistory,
+		Cluster:      cluster,
+		Group:        group,
+		Timestamp:    start,
+		EndTimestamp: end,
+		Reply:        make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchGroupStatusHistory(&request, module.Log)
+	return <-request.Reply
+}
+
+func addGroupStatuses(module *InMemoryStorage, group string, startTime int64, statuses ...protocol.StatusConstant) {
+	for i, status := range statuses {
+		module.addGroupStatus(&protocol.StorageRequest{
+			RequestType: protocol.StorageSetGroupStatus,
+			Cluster:     "testcluster",
+			Group:       group,
+			Status:      status,
+			Timestamp:   startTime + int64(i*1000),
+		}, module.Log)
+	}
+}
+
+func TestInMemoryStorage_addGroupStatus(t *testing.T) {
+	module := startWithTestCluster("")
+	defer module.Stop()
+
+	startTime := (time.Now().Unix() * 1000) - 100000
+	addGroupStatuses(module, "testgroup", startTime, protocol.StatusOK, protocol.StatusOK, protocol.StatusWarning, protocol.StatusError, protocol.StatusError, protocol.StatusOK)
+
+	response := fetchGroupStatusHistorySync(module, "testcluster", "testgroup", 0, 0)
+	assert.IsType(t, []protocol.StatusTransition{}, response, "Expected response to be of type []protocol.StatusTransition")
+	assert.Equalf(t, []protocol.StatusTransition{
+		{Timestamp: startTime, Status: protocol.StatusOK},
+		{Timestamp: startTime + 2000, Status: protocol.StatusWarning},
+		{Timestamp: startTime + 3000, Status: protocol.StatusError},
+		{Timestamp: startTime + 5000, Status: protocol.StatusOK},
+	}, response, "Expected only status changes to be stored, got %v", response)
+
+	// The change before the start of the range gives the status at the start
+	response = fetchGroupStatusHistorySync(module, "testcluster", "testgroup", startTime+2500, startTime+4000)
+	assert.Equalf(t, []protocol.StatusTransition{
+		{Timestamp: startTime + 2000, Status: protocol.StatusWarning},
+		{Timestamp: startTime + 3000, Status: protocol.StatusError},
+	}, response, "Expected changes in the range, got %v", response)
+
+	// The history is kept when the group is deleted
+	module.deleteGroup(&protocol.StorageRequest{RequestType: protocol.StorageSetDeleteGroup, Cluster: "testcluster", Group: "testgroup"}, module.Log)
+	response = fetchGroupStatusHistorySync(module, "testcluster", "testgroup", 0, 0)
+	assert.Lenf(t, response, 4, "Expected history to be kept for the deleted group, got %v", response)
+
+	response = fetchGroupStatusHistorySync(module, "testcluster", "nogroup", 0, 0)
+	assert.Equalf(t, []protocol.StatusTransition{}, response, "Expected empty history for an unknown group, got %v", response)
+}
+
+func TestInMemoryStorage_addGroupStatus_Limits(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.status-history-retention", 10)
+	viper.Set("storage.test.status-history-limit", 3)
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	module.Configure("test", "storage.test")
+	module.Start()
+	defer module.Stop()
+
+	startTime := (time.Now().Unix() * 1000) - 100000
+	addGroupStatuses(module, "limitgroup", startTime, protocol.StatusOK, protocol.StatusWarning, protocol.StatusOK, protocol.StatusWarning, protocol.StatusOK)
+	response := fetchGroupStatusHistorySync(module, "testcluster", "limitgroup", 0, 0)
+	assert.Equalf(t, []protocol.StatusTransition{
+		{Timestamp: startTime + 2000, Status: protocol.StatusOK},
+		{Timestamp: startTime + 3000, Status: protocol.StatusWarning},
+		{Timestamp: startTime + 4000, Status: protocol.StatusOK},
+	}, response, "Expected only the last 3 changes, got %v", response)
+
+	// Changes older than the retention are dropped, but the last change is always kept
+	addGroupStatuses(module, "retentiongroup", startTime, protocol.StatusOK, protocol.StatusError)
+	addGroupStatuses(module, "retentiongroup", startTime+60000, protocol.StatusOK)
+	response = fetchGroupStatusHistorySync(module, "testcluster", "retentiongroup", 0, 0)
+	assert.Equalf(t, []protocol.StatusTransition{
+		{Timestamp: startTime + 60000, Status: protocol.StatusOK},
+	}, response, "Expected changes older than the retention to be dropped, got %v", response)
+}
+
+func TestInMemoryStorage_fetchGroupStatusHistory_BadCluster(t *testing.T) {
+	module := startWithTestCluster("")
+	defer module.Stop()
+
+	response := fetchGroupStatusHistorySync(module, "nocluster", "testgroup", 0, 0)
+	assert.Nil(t, response, "Expected response to be nil")
+}
+
+func TestInMemoryStorage_StatusHistoryFile(t *testing.T) {
+	historyFile := filepath.Join(t.TempDir(), "history.json")
+	startTime := (time.Now().Unix() * 1000) - 100000
+
+	module := fixtureModule("", "")
+	viper.Set("storage.test.status-history-file", historyFile)
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	module.Configure("test", "storage.test")
+	module.Start()
+	addGroupStatuses(module, "testgroup", startTime, protocol.StatusOK, protocol.StatusError, protocol.StatusError)
+	module.Stop()
+
+	// A new module reads the history back from the file
+	module = fixtureModule("", "")
+	viper.Set("storage.test.status-history-file", historyFile)
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	module.Configure("test", "storage.test")
+	module.Start()
+	defer module.Stop()
+
+	response := fetchGroupStatusHistorySync(module, "testcluster", "testgroup", 0, 0)
+	assert.Equalf(t, []protocol.StatusTransition{
+		{Timestamp: startTime, Status: protocol.StatusOK},
+		{Timestamp: startTime + 1000, Status: protocol.StatusError},
+	}, response, "Expected history to be read from the file, got %v", response)
+}
+
+func TestInMemoryStorage_Configure_BadStatusHistoryFile(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.status-history-file", writeImportFile(t, "history.json", "not json"))
+
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
@@ -0,0 +1,181 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// offsetSample is a single offset read from an import file. If Group is empty, the sample is a broker offset for the
+// partition. Otherwise, it is an offset committed by that consumer group. The Timestamp is in milliseconds.
+type offsetSample struct {
+	Cluster   string `json:"cluster"`
+	Group     string `json:"group"`
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// readOffsetSamples reads all the offset samples from the named file. Files with a ".csv" extension are read as CSV,
+// with the columns cluster, group, topic, partition, offset, and timestamp (a header line is optional). All other
+// files are read as a JSON array of objects with the same fields.
+func readOffsetSamples(filename string) ([]*offsetSample, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var samples []*offsetSample
+	if strings.EqualFold(filepath.Ext(filename), ".csv") {
+		samples, err = parseOffsetSamplesCSV(file)
+	} else {
+		err = json.NewDecoder(file).Decode(&samples)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, sample := range samples {
+		if (sample == nil) || (sample.Cluster == "") || (sample.Topic == "") {
+			return nil, fmt.Errorf("sample %v: cluster and topic are required", i)
+		}
+		if (sample.Partition < 0) || (sample.Offset < 0) || (sample.Timestamp <= 0) {
+			return nil, fmt.Errorf("sample %v: partition, offset, and timestamp must be positive", i)
+		}
+	}
+	return samples, nil
+}
+
+func parseOffsetSamplesCSV(reader io.Reader) ([]*offsetSample, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = 6
+	csvReader.TrimLeadingSpace = true
+
+	samples := make([]*offsetSample, 0)
+	for line := 1; ; line++ {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if (line == 1) && (record[0] == "cluster") {
+			// Skip the header
+			continue
+		}
+
+		partition, err := strconv.ParseInt(record[3], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: bad partition: %v", line, err)
+		}
+		offset, err := strconv.ParseInt(record[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: bad offset: %v", line, err)
+		}
+		timestamp, err := strconv.ParseInt(record[5], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: bad timestamp: %v", line, err)
+		}
+
+		samples = append(samples, &offsetSample{
+			Cluster:   record[0],
+			Group:     record[1],
+			Topic:     record[2],
+			Partition: int32(partition),
+			Offset:    offset,
+			Timestamp: timestamp,
+		})
+	}
+	return samples, nil
+}
+
+// offsetSampleRequests converts offset samples into the storage requests to replay them. The requests are ordered by
+// timestamp, with broker offsets ahead of consumer offsets for the same time, so that consumer lag is calculated
+// against the broker offset at the time of the commit. The partition count for each topic is taken from the highest
+// partition seen, and the commit order for consumer offsets follows the order of the requests.
+func offsetSampleRequests(samples []*offsetSample) []*protocol.StorageRequest {
+	sorted := make([]*offsetSample, len(samples))
+	copy(sorted, samples)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Timestamp != sorted[j].Timestamp {
+			return sorted[i].Timestamp < sorted[j].Timestamp
+		}
+		return (sorted[i].Group == "") && (sorted[j].Group != "")
+	})
+
+	partitionCounts := make(map[string]int32)
+	for _, sample := range sorted {
+		if sample.Partition >= partitionCounts[sample.Cluster+"/"+sample.Topic] {
+			partitionCounts[sample.Cluster+"/"+sample.Topic] = sample.Partition + 1
+		}
+	}
+
+	requests := make([]*protocol.StorageRequest, len(sorted))
+	for i, sample := range sorted {
+		requests[i] = &protocol.StorageRequest{
+			RequestType:         protocol.StorageSetBrokerOffset,
+			Cluster:             sample.Cluster,
+			Group:               sample.Group,
+			Topic:               sample.Topic,
+			Partition:           sample.Partition,
+			TopicPartitionCount: partitionCounts[sample.Cluster+"/"+sample.Topic],
+			Offset:              sample.Offset,
+			Order:               int64(i + 1),
+			Timestamp:           sample.Timestamp,
+		}
+		if sample.Group != "" {
+			requests[i].RequestType = protocol.StorageSetConsumerOffset
+		}
+	}
+	return requests
+}
+
+// importOffsets stores the requests read from the import file directly, before the workers are started, so that the
+// imported history is in place before any live offsets arrive.
+func (module *InMemoryStorage) importOffsets() {
+	if len(module.importRequests) == 0 {
+		return
+	}
+
+	for _, request := range module.importRequests {
+		requestLogger := module.Log.With(
+			zap.String("cluster", request.Cluster),
+			zap.String("consumer", request.Group),
+			zap.String("topic", request.Topic),
+			zap.Int32("partition", request.Partition),
+			zap.Int64("offset", request.Offset),
+			zap.Int64("timestamp", request.Timestamp),
+			zap.String("request", request.RequestType.String()),
+		)
+		if request.RequestType == protocol.StorageSetBrokerOffset {
+			module.addBrokerOffset(request, requestLogger)
+		} else {
+			module.addConsumerOffset(request, requestLogger)
+		}
+	}
+	module.Log.Info("imported offsets", zap.Int("count", len(module.importRequests)))
+	module.importRequests = nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func writeImportFile(t *testing.T, name, content string) string {
+	filename := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(filename, []byte(content), 0o600)
+	assert.NoError(t, err, "Expected import file to be written")
+	return filename
+}
+
+func TestReadOffsetSamples_JSON(t *testing.T) {
+	filename := writeImportFile(t, "offsets.json", `[
+		{"cluster": "testcluster", "topic": "testtopic", "partition": 1, "offset": 4321, "timestamp": 2000},
+		{"cluster": "testcluster", "group": "testgroup", "topic": "testtopic", "partition": 0, "offset": 1000, "timestamp": 1000}
+	]`)
+
+	samples, err := readOffsetSamples(filename)
+	assert.NoError(t, err, "Expected no error reading samples")
+	assert.Len(t, samples, 2)
+	assert.Equal(t, &offsetSample{Cluster: "testcluster", Topic: "testtopic", Partition: 1, Offset: 4321, Timestamp: 2000}, samples[0])
+	assert.Equal(t, &offsetSample{Cluster: "testcluster", Group: "testgroup", Topic: "testtopic", Partition: 0, Offset: 1000, Timestamp: 1000}, samples[1])
+}
+
+func TestReadOffsetSamples_CSV(t *testing.T) {
+	filename := writeImportFile(t, "offsets.csv", "cluster,group,topic,partition,offset,timestamp\n"+
+		"testcluster,,testtopic,1,4321,2000\n"+
+		"testcluster,testgroup,testtopic,0,1000,1000\n")
+
+	samples, err := readOffsetSamples(filename)
+	assert.NoError(t, err, "Expected no error reading samples")
+	assert.Len(t, samples, 2)
+	assert.Equal(t, &offsetSample{Cluster: "testcluster", Topic: "testtopic", Partition: 1, Offset: 4321, Timestamp: 2000}, samples[0])
+	assert.Equal(t, &offsetSample{Cluster: "testcluster", Group: "testgroup", Topic: "testtopic", Partition: 0, Offset: 1000, Timestamp: 1000}, samples[1])
+}
+
+func TestReadOffsetSamples_Bad(t *testing.T) {
+	testCases := map[string]string{
+		"missing.json":    "",
+		"badjson.json":    `{"cluster": "testcluster"}`,
+		"notopic.json":    `[{"cluster": "testcluster", "partition": 0, "offset": 1, "timestamp": 1}]`,
+		"negative.json":   `[{"cluster": "testcluster", "topic": "testtopic", "partition": -1, "offset": 1, "timestamp": 1}]`,
+		"columns.csv":     "testcluster,,testtopic,0,1\n",
+		"badoffset.csv":   "testcluster,,testtopic,0,foo,1\n",
+		"notimestamp.csv": "testcluster,,testtopic,0,1,0\n",
+	}
+
+	for name, content := range testCases {
+		filename := filepath.Join(t.TempDir(), name)
+		if content != "" {
+			filename = writeImportFile(t, name, content)
+		}
+		_, err := readOffsetSamples(filename)
+		assert.Errorf(t, err, "Expected error reading %v", name)
+	}
+}
+
+func TestOffsetSampleRequests(t *testing.T) {
+	samples := []*offsetSample{
+		{Cluster: "testcluster", Group: "testgroup", Topic: "testtopic", Partition: 0, Offset: 1000, Timestamp: 2000},
+		{Cluster: "testcluster", Topic: "testtopic", Partition: 2, Offset: 4321, Timestamp: 2000},
+		{Cluster: "testcluster", Topic: "testtopic", Partition: 0, Offset: 1234, Timestamp: 1000},
+	}
+
+	requests := offsetSampleRequests(samples)
+	assert.Len(t, requests, 3)
+	assert.Equal(t, protocol.StorageSetBrokerOffset, requests[0].RequestType)
+	assert.Equal(t, int64(1234), requests[0].Offset)
+	assert.Equal(t, protocol.StorageSetBrokerOffset, requests[1].RequestType)
+	assert.Equal(t, int64(4321), requests[1].Offset)
+	assert.Equal(t, protocol.StorageSetConsumerOffset, requests[2].RequestType)
+	assert.Equal(t, "testgroup", requests[2].Group)
+	for i, request := range requests {
+		assert.Equalf(t, int32(3), request.TopicPartitionCount, "Expected partition count to be 3 for request %v", i)
+		assert.Equalf(t, int64(i+1), request.Order, "Expected order to be %v for request %v", i+1, i)
+	}
+}
+
+func TestInMemoryStorage_Start_ImportFile(t *testing.T) {
+	timestampBase := (time.Now().Unix() * 1000) - 100000
+	content := "cluster,group,topic,partition,offset,timestamp\n"
+	for i := 0; i < 3; i++ {
+		timestamp := strconv.FormatInt(timestampBase+int64(i*10000), 10)
+		content += "testcluster,,testtopic,0," + strconv.Itoa(2000+(i*1000)) + "," + timestamp + "\n"
+		content += "testcluster,testgroup,testtopic,0," + strconv.Itoa(1000+(i*1000)) + "," + timestamp + "\n"
+	}
+
+	module := fixtureModule("", "")
+	viper.Set("storage.test.import-file", writeImportFile(t, "offsets.csv", content))
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	module.Configure("test", "storage.test")
+	module.Start()
+	defer module.Stop()
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	module.requestChannel <- &request
+	response := <-request.Reply
+
+	assert.IsType(t, protocol.ConsumerTopics{}, response, "Expected response to be of type protocol.ConsumerTopics")
+	val := response.(protocol.ConsumerTopics)
+	assert.Len(t, val["testtopic"], 1, "One partition for topic not returned")
+	assert.Equalf(t, uint64(1000), val["testtopic"][0].CurrentLag, "Expected current lag to be 1000, not %v", val["testtopic"][0].CurrentLag)
+
+	// The ring is not full, so the imported offsets are the last 3 entries
+	offsets := val["testtopic"][0].Offsets
+	assert.Lenf(t, offsets, 10, "Expected to get 10 offsets for the partition, not %v", len(offsets))
+	offsets = offsets[7:]
+	for i := 0; i < 3; i++ {
+		assert.NotNilf(t, offsets[i], "Expected offset to be NOT nil at position %v", i)
+		assert.Equalf(t, int64(1000+(i*1000)), offsets[i].Offset, "Expected offset at position %v to be %v, got %v", i, 1000+(i*1000), offsets[i].Offset)
+		assert.Equalf(t, &protocol.Lag{Value: 1000}, offsets[i].Lag, "Expected lag at position %v to be 1000, got %v", i, offsets[i].Lag)
+	}
+}
+
+func TestInMemoryStorage_Configure_BadImportFile(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.import-file", filepath.Join(t.TempDir(), "missing.json"))
+
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
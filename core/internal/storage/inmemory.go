@@ -13,6 +13,8 @@ import (
 	"container/ring"
 	"math/rand"
 	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,6 +22,7 @@ import (
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
+	"github.com/linkedin/Burrow/core/internal/helpers"
 	"github.com/linkedin/Burrow/core/internal/httpserver"
 	"github.com/linkedin/Burrow/core/protocol"
 )
@@ -43,6 +46,67 @@ type InMemoryStorage struct {
 	minDistance int64
 	queueDepth  int
 
+	// Give each cluster its own workers, so that requests for one cluster never wait behind another's. See Configure
+	shardByCluster bool
+
+	// The number of seconds to keep a deleted group, so that its history is restored if it reappears
+	deletedGroupRetention int64
+
+	// Record the topics that groups commit to that the cluster does not have. This is only done if an evaluator flags
+	// possibly misconfigured groups, which is the only use of them. See unknowntopics.go
+	recordUnknownTopics bool
+
+	// The age in seconds at which consumer offsets are removed by a sweep every offsetSweepInterval seconds, or zero if
+	// they are kept until they are pushed out of the ring. See eviction.go
+	maxOffsetAge        int64
+	offsetSweepInterval int
+	sweepQuit           chan struct{}
+	sweepRunning        sync.WaitGroup
+
+	// Replace the last offset for a partition, rather than adding a new one, when a group commits the same offset again
+	collapseDuplicates bool
+
+	// How to handle consumer partitions with no known end offset. See Configure for details
+	unknownEndOffset string
+
+	// How to handle an end offset that is lower than the last one stored for the partition, and the number of them in
+	// a row to reject before accepting the lower offset. See Configure for details
+	endOffsetRegression      string
+	endOffsetRegressionLimit int
+
+	// The number of commits to hold for each partition that has no end offset yet, rather than dropping them
+	pendingLimit int
+
+	// Burrow's own groups for each cluster, which are left out of consumer lists unless include-burrow-groups is set
+	hiddenGroups map[string]map[string]bool
+
+	// The number of samples that the lag baselines are averaged over
+	baselineSamples int64
+
+	// Muted groups for each cluster, with the time each mute expires. These are kept apart from the offsets so that a
+	// mute is not lost when the group is deleted or expires, and are saved to the mute-file if one is configured
+	muteFile    string
+	muteLock    sync.RWMutex
+	mutedGroups map[string]map[string]int64
+
+	// Status changes for each cluster and group, as recorded by the evaluator. These are also kept apart from the
+	// offsets, so that the history of a group outlives it, and are appended to the status-history-file if one is set
+	historyFile      string
+	historyRetention int64
+	historyLimit     int
+	historyLock      sync.RWMutex
+	statusHistory    map[string]map[string][]protocol.StatusTransition
+
+	// The broker and consumer offsets are saved to the snapshot-file, if one is set, and restored from it on start. See
+	// snapshot.go
+	snapshotFile     string
+	snapshotCodec    snapshotCodec
+	snapshotInterval int
+	snapshot         *storageSnapshot
+
+	// Queues for the sinks that every lag sample is sent to: the application's LagSinks, and the lag-sink-url webhook
+	lagSinks []*lagSinkQueue
+
 	requestChannel chan *protocol.StorageRequest
 	workersRunning sync.WaitGroup
 	mainRunning    sync.WaitGroup
@@ -50,17 +114,26 @@ type InMemoryStorage struct {
 	groupAllowlist *regexp.Regexp
 	groupDenylist  *regexp.Regexp
 	workers        []chan *protocol.StorageRequest
+	clusterWorkers map[string][]chan *protocol.StorageRequest
+	importRequests []*protocol.StorageRequest
 }
 
 type brokerOffset struct {
 	Offset    int64
 	Timestamp int64
+
+	// The number of lower end offsets that have been rejected since this one was stored
+	rejected int
 }
 
 type consumerPartition struct {
-	offsets  *ring.Ring
-	owner    string
-	clientID string
+	offsets    *ring.Ring
+	owner      string
+	clientID   string
+	instanceID string
+
+	// The broker end offset for the partition when the last offset was committed
+	brokerOffset int64
 }
 
 type consumerGroup struct {
@@ -68,18 +141,49 @@ type consumerGroup struct {
 	lock       *sync.RWMutex
 	topics     map[string][]*consumerPartition
 	lastCommit int64
+
+	// The lag baseline for the group, or nil if no samples have been added
+	baseline *lagBaseline
 }
 
 type clusterOffsets struct {
 	broker   map[string][]*ring.Ring
 	consumer map[string]*consumerGroup
 
+	// Deleted groups that are kept until deleted-group-retention has passed, under the consumerLock
+	tombstones map[string]*groupTombstone
+
+	// The oldest offset for each partition of a topic, or -1 for partitions where it has not been stored. These are
+	// only stored if the cluster module fetches them, and are kept under the brokerLock
+	brokerOldest map[string][]int64
+
+	// The offset that each partition of a topic was at a time in the past, or nil for partitions where it has not been
+	// stored. These are only stored if the cluster module fetches them, and are kept under the brokerLock
+	brokerLookback map[string][]*brokerOffset
+
+	// The offsets that each partition of a topic was at, at several times in the past, oldest first. These are only
+	// stored if the cluster module samples them, and are kept under the brokerLock
+	brokerTimeOffsets map[string][][]protocol.TimeOffset
+
+	// The topics that are compacted, if the cluster module detects them, kept under the brokerLock
+	compacted map[string]bool
+
 	// This lock is used when modifying broker topics or offsets
 	brokerLock *sync.RWMutex
 
 	// This lock is used when modifying the overall consumer list
 	// It does not need to be held for modifying an individual group
 	consumerLock *sync.RWMutex
+
+	// Commits held for partitions that have no end offset yet, by topic and partition, and the lock for them
+	pending     map[string]map[int32][]*protocol.StorageRequest
+	pendingLock *sync.Mutex
+
+	// The time of the last dropped commit by each group for each topic that the cluster has no end offsets for, the
+	// topics that the cluster module's topic filter leaves out, and the lock for them. See unknowntopics.go
+	unknownTopics     map[string]map[string]int64
+	filteredTopics    map[string]bool
+	unknownTopicsLock *sync.Mutex
 }
 
 // Represents the destination of adding an offset into
@@ -113,7 +217,75 @@ func (destination *offsetRingDestination) isShift() bool {
 
 // Configure validates the configuration for the module, creates a channel to receive requests on, and sets up the
 // storage map. If no expiration time for groups is set, a default value of 7 days is used. If no interval count is
-// set, a default of 10 intervals is used. If no worker count is set, a default of 20 workers is used.
+// set, a default of 10 intervals is used. If no worker count is set, a default of 20 workers is used. If an import-file
+// is set, the offsets in it are read here and stored when the module is started.
+//
+// The offsets for each cluster already have their own locks, but the workers are shared, so a worker that is waiting
+// on one cluster's lock holds up the requests for other clusters that are queued to it. If shard-by-cluster is set,
+// each cluster gets its own set of workers (the worker count is per cluster), and a separate set handles the requests
+// that are not for a known cluster. The requests themselves are not changed.
+//
+// A deleted group (such as one removed by the groups reaper) is normally gone at once, and starts with no history if
+// it reappears. If deleted-group-retention is set, a deleted group is kept for that many seconds, hidden from the
+// consumer list and fetches, and gets its offsets back if it commits again in that time. Tombstones that are older are
+// purged by the sweep every offset-sweep-interval seconds, or when the group reappears.
+//
+// The consumer and cluster modules fetch committed offsets and end offsets independently, so a group can have
+// committed offsets for a partition that has no end offset stored. Commits for a partition that the cluster module
+// has not reported yet are dropped until it does, but a partition can also lose its end offset after the group has
+// committed (such as when the topic is deleted and recreated with fewer partitions). The unknown-end-offset config
+// controls what happens then: "wait" (the default) returns the partition with no end offsets and zero lag, which the
+// evaluator skips, and "last-known" calculates the lag against the end offset stored when the group last committed.
+//
+// A partition's end offset can briefly go backwards while its leadership moves (such as during a reassignment), which
+// shows up as a spike in the lag. The end-offset-regression config controls what happens to an end offset that is
+// lower than the last one stored for the partition: "accept" (the default) stores it, "flag" stores it and logs a
+// warning, and "reject" drops it. As the end offset can also really go backwards (such as after an unclean leader
+// election), the lower offset is stored once end-offset-regression-limit (3 by default) have been rejected in a row.
+// Deleting the topic clears its end offsets, so a recreated topic is not affected. Flagged and rejected offsets are
+// counted in the burrow_storage_end_offset_regressions_total metric.
+//
+// The kafka consumer module stores Burrow's own progress reading the offsets topic as a group named burrow-<consumer>.
+// These groups are left out of the consumer lists, and so out of the HTTP listings and notifier evaluations, unless
+// include-burrow-groups is set. They can still be fetched by name.
+//
+// Each partition keeps its last intervals offsets, however old they are, as long as the group commits to any of its
+// partitions. If max-offset-age is set, offsets older than that many seconds are removed by a sweep every
+// offset-sweep-interval seconds (300 by default), so that partitions that are no longer committed to do not hold on to
+// them. The evaluator sees a partition with every offset removed as incomplete, and does not alert on it.
+//
+// If an evaluator has misconfigured-topic-share set, the topics that each group commits to that the cluster has no end
+// offsets for are recorded, leaving out the topics that the cluster module filters out. The same sweep removes the
+// ones that the group has not committed to for expire-group seconds.
+//
+// If collapse-duplicate-commits is set, a commit for the same offset as the last one stored for a partition replaces
+// it with the new timestamp, rather than taking another slot in the ring. This keeps a longer history for groups that
+// commit often without making progress, but the evaluator will see fewer samples for them.
+//
+// Groups can be muted, so that the evaluator returns a MUTED status for them, without changing their stored offsets.
+// If a mute-file is set, the muted groups are read from it here and it is rewritten whenever a group is muted or
+// unmuted, so that mutes are kept across restarts.
+//
+// A lag baseline is kept for each group that the evaluator sends lag samples for. Each hour of the day has a mean and
+// standard deviation of the group's total lag, which are averaged over the last baseline-samples samples (100 by
+// default) for that hour. The baseline is removed with the group.
+//
+// The status of each group is recorded whenever it changes, if the evaluator is configured to send it, so that the
+// status of a group at an earlier time can be looked up. Changes are kept for status-history-retention seconds (7 days
+// by default), up to status-history-limit changes (1000 by default) for each group. If a status-history-file is set,
+// each change is appended to it, and the changes in it are read back here, so that the history is kept across
+// restarts.
+//
+// The lag calculated for each commit is sent to the LagSinks in the application context, and POSTed to lag-sink-url
+// if it is set. Samples are queued for each sink, up to lag-sink-queue-depth samples (10000 by default), and sent in
+// batches of up to lag-sink-batch-size samples (500 by default), at least every lag-sink-interval seconds (5 by
+// default). Samples are dropped for a sink that has filled its queue, so that storage never waits for a sink. The
+// webhook request times out after lag-sink-timeout seconds (5 by default).
+//
+// If a snapshot-file is set, the broker and consumer offsets are written to it when the module stops, and every
+// snapshot-interval seconds if that is set, in the snapshot-format (json, gob, json-gzip, or gob-gzip). The snapshot
+// is read here and restored when the module is started, so that the lag history is kept across restarts. A snapshot
+// that cannot be read, or has an unsupported schema version, is discarded with a warning. See snapshot.go for details.
 func (module *InMemoryStorage) Configure(name, configRoot string) {
 	module.Log.Info("configuring")
 
@@ -129,8 +301,108 @@ func (module *InMemoryStorage) Configure(name, configRoot string) {
 	module.numWorkers = viper.GetInt(configRoot + ".workers")
 	module.minDistance = viper.GetInt64(configRoot + ".min-distance")
 	module.queueDepth = viper.GetInt(configRoot + ".queue-depth")
+	module.collapseDuplicates = viper.GetBool(configRoot + ".collapse-duplicate-commits")
+	module.shardByCluster = viper.GetBool(configRoot + ".shard-by-cluster")
+
+	module.maxOffsetAge = viper.GetInt64(configRoot + ".max-offset-age")
+	if module.maxOffsetAge < 0 {
+		panic("storage " + name + ": max-offset-age must be zero or greater")
+	}
+	viper.SetDefault(configRoot+".offset-sweep-interval", 300)
+	module.offsetSweepInterval = viper.GetInt(configRoot + ".offset-sweep-interval")
+	if module.offsetSweepInterval <= 0 {
+		panic("storage " + name + ": offset-sweep-interval must be greater than zero")
+	}
+
+	module.deletedGroupRetention = viper.GetInt64(configRoot + ".deleted-group-retention")
+	if module.deletedGroupRetention < 0 {
+		panic("storage " + name + ": deleted-group-retention must be zero or greater")
+	}
+	module.recordUnknownTopics = misconfiguredTopicCheckEnabled()
+
+	viper.SetDefault(configRoot+".baseline-samples", 100)
+	module.baselineSamples = viper.GetInt64(configRoot + ".baseline-samples")
+	if module.baselineSamples < 1 {
+		panic("storage " + name + ": baseline-samples must be at least 1")
+	}
+
+	viper.SetDefault(configRoot+".unknown-end-offset", "wait")
+	module.unknownEndOffset = viper.GetString(configRoot + ".unknown-end-offset")
+	if (module.unknownEndOffset != "wait") && (module.unknownEndOffset != "last-known") {
+		panic("storage " + name + ": unknown-end-offset must be either wait or last-known")
+	}
+
+	viper.SetDefault(configRoot+".end-offset-regression", "accept")
+	viper.SetDefault(configRoot+".end-offset-regression-limit", 3)
+	module.endOffsetRegression = viper.GetString(configRoot + ".end-offset-regression")
+	module.endOffsetRegressionLimit = viper.GetInt(configRoot + ".end-offset-regression-limit")
+	if (module.endOffsetRegression != "accept") && (module.endOffsetRegression != "flag") && (module.endOffsetRegression != "reject") {
+		panic("storage " + name + ": end-offset-regression must be one of accept, flag, or reject")
+	}
+	if module.endOffsetRegressionLimit < 1 {
+		panic("storage " + name + ": end-offset-regression-limit must be at least 1")
+	}
+
+	// Commits for partitions that have no end offset yet are dropped, unless some can be held until the end offset is
+	// known. See pending.go for details
+	module.pendingLimit = viper.GetInt(configRoot + ".pending-commit-limit")
+	if module.pendingLimit < 0 {
+		panic("storage " + name + ": pending-commit-limit must be zero or greater")
+	}
+
+	module.hiddenGroups = make(map[string]map[string]bool)
+	if !viper.GetBool(configRoot + ".include-burrow-groups") {
+		for cluster := range viper.GetStringMap("cluster") {
+			module.hiddenGroups[cluster] = helpers.GetReportedConsumerGroups(cluster)
+		}
+	}
+
+	module.muteFile = viper.GetString(configRoot + ".mute-file")
+	savedMutes := make(map[string]map[string]int64)
+	if module.muteFile != "" {
+		var err error
+		savedMutes, err = readMutedGroups(module.muteFile)
+		if err != nil {
+			panic("cannot read muted groups from " + module.muteFile + ": " + err.Error())
+		}
+	}
+	module.mutedGroups = make(map[string]map[string]int64)
+	for cluster := range viper.GetStringMap("cluster") {
+		module.mutedGroups[cluster] = make(map[string]int64)
+		for group, expires := range savedMutes[cluster] {
+			module.mutedGroups[cluster][group] = expires
+		}
+	}
+
+	viper.SetDefault(configRoot+".status-history-retention", 604800)
+	viper.SetDefault(configRoot+".status-history-limit", 1000)
+	module.historyRetention = viper.GetInt64(configRoot + ".status-history-retention")
+	module.historyLimit = viper.GetInt(configRoot + ".status-history-limit")
+	if module.historyRetention <= 0 {
+		panic("storage " + name + ": status-history-retention must be greater than zero")
+	}
+	if module.historyLimit < 1 {
+		panic("storage " + name + ": status-history-limit must be at least 1")
+	}
+	module.statusHistory = make(map[string]map[string][]protocol.StatusTransition)
+	for cluster := range viper.GetStringMap("cluster") {
+		module.statusHistory[cluster] = make(map[string][]protocol.StatusTransition)
+	}
+	module.historyFile = viper.GetString(configRoot + ".status-history-file")
+	if module.historyFile != "" {
+		records, err := readStatusHistory(module.historyFile)
+		if err == nil {
+			err = module.loadStatusHistory(records)
+		}
+		if err != nil {
+			panic("cannot read status history from " + module.historyFile + ": " + err.Error())
+		}
+	}
+
+	module.configureLagSinks(configRoot)
 
 	module.requestChannel = make(chan *protocol.StorageRequest, module.queueDepth)
+	module.sweepQuit = make(chan struct{})
 	module.workersRunning = sync.WaitGroup{}
 	module.mainRunning = sync.WaitGroup{}
 	module.offsets = make(map[string]clusterOffsets)
@@ -160,6 +432,38 @@ func (module *InMemoryStorage) Configure(name, configRoot string) {
 		}
 		module.groupDenylist = re
 	}
+
+	// Read the offsets to import now, so that a bad file is caught as a configuration error
+	if importFile := viper.GetString(configRoot + ".import-file"); importFile != "" {
+		samples, err := readOffsetSamples(importFile)
+		if err != nil {
+			panic("cannot import offsets from " + importFile + ": " + err.Error())
+		}
+		module.importRequests = offsetSampleRequests(samples)
+	}
+
+	module.snapshotFile = viper.GetString(configRoot + ".snapshot-file")
+	viper.SetDefault(configRoot+".snapshot-format", "json")
+	format := viper.GetString(configRoot + ".snapshot-format")
+	codec, ok := snapshotCodecs[format]
+	if !ok {
+		panic("storage " + name + ": snapshot-format must be one of " + strings.Join(sortedSnapshotFormats(), ", "))
+	}
+	module.snapshotCodec = codec
+	module.snapshotInterval = viper.GetInt(configRoot + ".snapshot-interval")
+	if module.snapshotInterval < 0 {
+		panic("storage " + name + ": snapshot-interval must be zero or greater")
+	}
+	module.snapshot = nil
+	if module.snapshotFile != "" {
+		// A snapshot that cannot be read is discarded rather than stopping Burrow, as it is only a cache of what the
+		// cluster and consumer modules will fetch again
+		snapshot, err := readSnapshot(module.snapshotFile, module.snapshotCodec)
+		if err != nil {
+			module.Log.Warn("discarding snapshot", zap.String("filename", module.snapshotFile), zap.Error(err))
+		}
+		module.snapshot = snapshot
+	}
 }
 
 // GetCommunicationChannel returns the RequestChannel that has been setup for this module.
@@ -167,29 +471,71 @@ func (module *InMemoryStorage) GetCommunicationChannel() chan *protocol.StorageR
 	return module.requestChannel
 }
 
-// Start sets up the rest of the storage map for each configured cluster. It then starts the configured number of
-// worker routines to handle requests. Finally, it starts a main loop which will receive requests and hash them to the
-// correct worker.
+// Start sets up the rest of the storage map for each configured cluster, restores the snapshot if one was read, and
+// stores any imported offsets in it. It then starts the configured number of worker routines to handle requests.
+// Finally, it starts a main loop which will receive requests and hash them to the correct worker.
 func (module *InMemoryStorage) Start() error {
 	module.Log.Info("starting")
 
 	for cluster := range viper.GetStringMap("cluster") {
 		module.
 			offsets[cluster] = clusterOffsets{
-			broker:       make(map[string][]*ring.Ring),
-			consumer:     make(map[string]*consumerGroup),
-			brokerLock:   &sync.RWMutex{},
-			consumerLock: &sync.RWMutex{},
+			broker:            make(map[string][]*ring.Ring),
+			brokerOldest:      make(map[string][]int64),
+			brokerLookback:    make(map[string][]*brokerOffset),
+			brokerTimeOffsets: make(map[string][][]protocol.TimeOffset),
+			compacted:         make(map[string]bool),
+			consumer:          make(map[string]*consumerGroup),
+			tombstones:        make(map[string]*groupTombstone),
+			brokerLock:        &sync.RWMutex{},
+			consumerLock:      &sync.RWMutex{},
+			pending:           make(map[string]map[int32][]*protocol.StorageRequest),
+			pendingLock:       &sync.Mutex{},
+			unknownTopics:     make(map[string]map[string]int64),
+			filteredTopics:    make(map[string]bool),
+			unknownTopicsLock: &sync.Mutex{},
 		}
 	}
 
-	// Start the appropriate number of workers, with a channel for each
-	module.workers = make([]chan *protocol.StorageRequest, module.numWorkers)
-	for i := 0; i < module.numWorkers; i++ {
+	if module.snapshot != nil {
+		module.restoreSnapshot(module.snapshot)
+		module.snapshot = nil
+	}
+	module.importOffsets()
+
+	for _, queue := range module.lagSinks {
+		queue.start()
+	}
+
+	// Start the appropriate number of workers, with a channel for each. If sharded by cluster, each cluster has that
+	// many workers of its own, and the first set only handles requests that are not for a known cluster
+	pools := 1
+	if module.shardByCluster {
+		pools += len(module.offsets)
+	}
+	module.workers = make([]chan *protocol.StorageRequest, module.numWorkers*pools)
+	for i := range module.workers {
 		module.workers[i] = make(chan *protocol.StorageRequest, module.queueDepth)
 		module.workersRunning.Add(1)
 		go module.requestWorker(i, module.workers[i])
 	}
+	module.clusterWorkers = make(map[string][]chan *protocol.StorageRequest)
+	if module.shardByCluster {
+		pool := 1
+		for cluster := range module.offsets {
+			module.clusterWorkers[cluster] = module.workers[pool*module.numWorkers : (pool+1)*module.numWorkers]
+			pool++
+		}
+	}
+
+	if (module.maxOffsetAge > 0) || module.recordUnknownTopics || (module.deletedGroupRetention > 0) {
+		module.sweepRunning.Add(1)
+		go module.sweeper()
+	}
+	if (module.snapshotFile != "") && (module.snapshotInterval > 0) {
+		module.sweepRunning.Add(1)
+		go module.snapshotWriter()
+	}
 
 	module.mainRunning.Add(1)
 	go module.mainLoop()
@@ -197,18 +543,30 @@ func (module *InMemoryStorage) Start() error {
 }
 
 // Stop closes the incoming request channel, which will close the main loop. It then closes each of the worker
-// channels, to close the workers, and waits for all goroutines to exit before returning.
+// channels, to close the workers, and waits for all goroutines to exit. Finally, it writes the snapshot-file, if one
+// is set, before returning.
 func (module *InMemoryStorage) Stop() error {
 	module.Log.Info("stopping")
 
+	close(module.sweepQuit)
+	module.sweepRunning.Wait()
+
 	close(module.requestChannel)
 	module.mainRunning.Wait()
 
-	for i := 0; i < module.numWorkers; i++ {
-		close(module.workers[i])
+	for _, worker := range module.workers {
+		close(worker)
 	}
 	module.workersRunning.Wait()
 
+	if module.snapshotFile != "" {
+		module.saveSnapshot()
+	}
+
+	for _, queue := range module.lagSinks {
+		queue.stop()
+	}
+
 	return nil
 }
 
@@ -217,18 +575,36 @@ func (module *InMemoryStorage) requestWorker(workerNum int, requestChannel chan
 
 	// Using a map for the request types avoids a bit of complexity below
 	var requestTypeMap = map[protocol.StorageRequestConstant]func(*protocol.StorageRequest, *zap.Logger){
-		protocol.StorageSetBrokerOffset:        module.addBrokerOffset,
-		protocol.StorageSetConsumerOffset:      module.addConsumerOffset,
-		protocol.StorageSetConsumerOwner:       module.addConsumerOwner,
-		protocol.StorageSetDeleteTopic:         module.deleteTopic,
-		protocol.StorageSetDeleteGroup:         module.deleteGroup,
-		protocol.StorageFetchClusters:          module.fetchClusterList,
-		protocol.StorageFetchConsumers:         module.fetchConsumerList,
-		protocol.StorageFetchTopics:            module.fetchTopicList,
-		protocol.StorageFetchConsumer:          module.fetchConsumer,
-		protocol.StorageFetchTopic:             module.fetchTopic,
-		protocol.StorageClearConsumerOwners:    module.clearConsumerOwners,
-		protocol.StorageFetchConsumersForTopic: module.fetchConsumersForTopicList,
+		protocol.StorageSetBrokerOffset:         module.addBrokerOffset,
+		protocol.StorageSetConsumerOffset:       module.addConsumerOffset,
+		protocol.StorageSetConsumerOwner:        module.addConsumerOwner,
+		protocol.StorageSetDeleteTopic:          module.deleteTopic,
+		protocol.StorageSetDeleteGroup:          module.deleteGroup,
+		protocol.StorageFetchClusters:           module.fetchClusterList,
+		protocol.StorageFetchConsumers:          module.fetchConsumerList,
+		protocol.StorageFetchTopics:             module.fetchTopicList,
+		protocol.StorageFetchConsumer:           module.fetchConsumer,
+		protocol.StorageFetchTopic:              module.fetchTopic,
+		protocol.StorageClearConsumerOwners:     module.clearConsumerOwners,
+		protocol.StorageFetchConsumersForTopic:  module.fetchConsumersForTopicList,
+		protocol.StorageFetchStats:              module.fetchStats,
+		protocol.StorageSetMuteGroup:            module.muteGroup,
+		protocol.StorageSetUnmuteGroup:          module.unmuteGroup,
+		protocol.StorageFetchMutedGroups:        module.fetchMutedGroups,
+		protocol.StorageFetchEndOffsets:         module.fetchEndOffsets,
+		protocol.StorageSetGroupBaselineSample:  module.addBaselineSample,
+		protocol.StorageFetchGroupBaseline:      module.fetchGroupBaseline,
+		protocol.StorageSetBrokerOldestOffset:   module.addBrokerOldestOffset,
+		protocol.StorageSetGroupStatus:          module.addGroupStatus,
+		protocol.StorageFetchGroupStatusHistory: module.fetchGroupStatusHistory,
+		protocol.StorageSetTopicCompacted:       module.setTopicCompacted,
+		protocol.StorageFetchCompactedTopics:    module.fetchCompactedTopics,
+		protocol.StorageSetBrokerLookbackOffset: module.addBrokerLookbackOffset,
+		protocol.StorageSetBrokerTimeOffsets:    module.addBrokerTimeOffsets,
+		protocol.StorageFetchTopicOldestOffsets: module.fetchTopicOldestOffsets,
+		protocol.StorageFetchGroupUnknownTopics: module.fetchUnknownTopics,
+		protocol.StorageSetDeletePartition:      module.deletePartition,
+		protocol.StorageSetTopicFiltered:        module.setTopicFiltered,
 	}
 
 	workerLogger := module.Log.With(zap.Int("worker", workerNum))
@@ -244,6 +620,7 @@ func (module *InMemoryStorage) requestWorker(workerNum int, requestChannel chan
 				zap.Int64("timestamp", r.Timestamp),
 				zap.String("owner", r.Owner),
 				zap.String("client_id", r.ClientID),
+				zap.String("instance_id", r.InstanceID),
 				zap.String("request", r.RequestType.String())))
 		}
 	}
@@ -254,12 +631,12 @@ func (module *InMemoryStorage) mainLoop() {
 
 	for r := range module.requestChannel {
 		switch r.RequestType {
-		case protocol.StorageSetBrokerOffset, protocol.StorageSetDeleteTopic, protocol.StorageFetchClusters, protocol.StorageFetchConsumers, protocol.StorageFetchTopics, protocol.StorageFetchTopic, protocol.StorageFetchConsumersForTopic:
+		case protocol.StorageSetBrokerOffset, protocol.StorageSetDeleteTopic, protocol.StorageSetDeletePartition, protocol.StorageFetchClusters, protocol.StorageFetchConsumers, protocol.StorageFetchTopics, protocol.StorageFetchTopic, protocol.StorageFetchConsumersForTopic, protocol.StorageFetchStats, protocol.StorageFetchMutedGroups, protocol.StorageFetchEndOffsets, protocol.StorageSetBrokerOldestOffset, protocol.StorageSetTopicCompacted, protocol.StorageFetchCompactedTopics, protocol.StorageSetBrokerLookbackOffset, protocol.StorageFetchTopicOldestOffsets, protocol.StorageSetBrokerTimeOffsets, protocol.StorageSetTopicFiltered:
 			// Send to any worker
-			module.workers[int(rand.Int31n(int32(module.numWorkers)))] <- r
-		case protocol.StorageSetConsumerOffset, protocol.StorageSetConsumerOwner, protocol.StorageSetDeleteGroup, protocol.StorageClearConsumerOwners, protocol.StorageFetchConsumer:
+			module.workerPool(r.Cluster)[int(rand.Int31n(int32(module.numWorkers)))] <- r
+		case protocol.StorageSetConsumerOffset, protocol.StorageSetConsumerOwner, protocol.StorageSetDeleteGroup, protocol.StorageClearConsumerOwners, protocol.StorageFetchConsumer, protocol.StorageSetMuteGroup, protocol.StorageSetUnmuteGroup, protocol.StorageSetGroupBaselineSample, protocol.StorageFetchGroupBaseline, protocol.StorageSetGroupStatus, protocol.StorageFetchGroupStatusHistory, protocol.StorageFetchGroupUnknownTopics:
 			// Hash to a consistent worker
-			module.workers[int(xxhash.ChecksumString64(r.Cluster+r.Group)%uint64(module.numWorkers))] <- r
+			module.workerPool(r.Cluster)[int(xxhash.ChecksumString64(r.Cluster+r.Group)%uint64(module.numWorkers))] <- r
 		default:
 			module.Log.Error("unknown storage request type",
 				zap.Int("request_type", int(r.RequestType)),
@@ -271,6 +648,15 @@ func (module *InMemoryStorage) mainLoop() {
 	}
 }
 
+// workerPool returns the workers that handle requests for the cluster. This is the cluster's own workers if the module
+// is sharded by cluster, and the shared workers otherwise (or if the cluster is not known).
+func (module *InMemoryStorage) workerPool(cluster string) []chan *protocol.StorageRequest {
+	if pool, ok := module.clusterWorkers[cluster]; ok {
+		return pool
+	}
+	return module.workers[:module.numWorkers]
+}
+
 func (module *InMemoryStorage) addBrokerOffset(request *protocol.StorageRequest, requestLogger *zap.Logger) {
 	clusterMap, ok := module.offsets[request.Cluster]
 	if !ok {
@@ -280,7 +666,6 @@ func (module *InMemoryStorage) addBrokerOffset(request *protocol.StorageRequest,
 	}
 
 	clusterMap.brokerLock.Lock()
-	defer clusterMap.brokerLock.Unlock()
 
 	topicList, ok := clusterMap.broker[request.Topic]
 	if !ok {
@@ -294,12 +679,21 @@ func (module *InMemoryStorage) addBrokerOffset(request *protocol.StorageRequest,
 		}
 	}
 
+	if latest, ok := topicList[request.Partition].Value.(*brokerOffset); ok && (request.Offset < latest.Offset) {
+		if module.rejectEndOffsetRegression(request, latest, requestLogger) {
+			clusterMap.broker[request.Topic] = topicList
+			clusterMap.brokerLock.Unlock()
+			return
+		}
+	}
+
 	// Advance to the next ring entry (this means the pointer is always at the most recent entry, rather than the
 	// oldest entry)
 	topicList[request.Partition] = topicList[request.Partition].Next()
 	partitionEntry := topicList[request.Partition]
 
-	if partitionEntry.Value == nil {
+	firstOffset := partitionEntry.Value == nil
+	if firstOffset {
 		partitionEntry.Value = &brokerOffset{
 			Offset:    request.Offset,
 			Timestamp: request.Timestamp,
@@ -308,10 +702,145 @@ func (module *InMemoryStorage) addBrokerOffset(request *protocol.StorageRequest,
 		ringval, _ := partitionEntry.Value.(*brokerOffset)
 		ringval.Offset = request.Offset
 		ringval.Timestamp = request.Timestamp
+		ringval.rejected = 0
 	}
 
 	requestLogger.Debug("ok")
 	clusterMap.broker[request.Topic] = topicList
+	clusterMap.brokerLock.Unlock()
+
+	if firstOffset && (module.pendingLimit > 0) {
+		module.storePendingCommits(&clusterMap, request.Topic, request.Partition, requestLogger)
+	}
+}
+
+// rejectEndOffsetRegression returns true if an end offset that is lower than the latest one stored for the partition
+// should be dropped, according to the end-offset-regression config. It must be called with the broker lock held.
+func (module *InMemoryStorage) rejectEndOffsetRegression(request *protocol.StorageRequest, latest *brokerOffset, requestLogger *zap.Logger) bool {
+	switch module.endOffsetRegression {
+	case "flag":
+		httpserver.CountEndOffsetRegression(request.Cluster, "flagged")
+		requestLogger.Warn("end offset went backwards", zap.Int64("previous_offset", latest.Offset))
+	case "reject":
+		if latest.rejected >= module.endOffsetRegressionLimit {
+			requestLogger.Warn("accepting end offset that went backwards",
+				zap.Int64("previous_offset", latest.Offset),
+				zap.Int("rejected", latest.rejected),
+			)
+			return false
+		}
+		latest.rejected++
+		httpserver.CountEndOffsetRegression(request.Cluster, "rejected")
+		requestLogger.Debug("dropped", zap.String("reason", "end offset went backwards"), zap.Int64("previous_offset", latest.Offset))
+		return true
+	}
+	return false
+}
+
+// addBrokerOldestOffset stores the oldest offset for a partition. Only the latest is kept, as it is only used to find
+// the size of the partition with the current end offset.
+func (module *InMemoryStorage) addBrokerOldestOffset(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+	if (request.Partition < 0) || (request.Partition >= request.TopicPartitionCount) {
+		requestLogger.Warn("partition out of range")
+		return
+	}
+
+	clusterMap.brokerLock.Lock()
+	defer clusterMap.brokerLock.Unlock()
+
+	partitions := clusterMap.brokerOldest[request.Topic]
+	for i := int32(len(partitions)); i < request.TopicPartitionCount; i++ {
+		partitions = append(partitions, -1)
+	}
+	partitions[request.Partition] = request.Offset
+	clusterMap.brokerOldest[request.Topic] = partitions
+
+	requestLogger.Debug("ok")
+}
+
+// addBrokerLookbackOffset stores the offset that a partition was at, at the time in the request. Only the latest is
+// kept, as it is only used to find the rate that the partition is produced to with the current end offset. If nothing
+// has been produced to the partition since that time, the current end offset is stored, if it is known.
+func (module *InMemoryStorage) addBrokerLookbackOffset(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+	if (request.Partition < 0) || (request.Partition >= request.TopicPartitionCount) {
+		requestLogger.Warn("partition out of range")
+		return
+	}
+
+	clusterMap.brokerLock.Lock()
+	defer clusterMap.brokerLock.Unlock()
+
+	offset := request.Offset
+	if offset < 0 {
+		topicMap := clusterMap.broker[request.Topic]
+		if (int(request.Partition) >= len(topicMap)) || (topicMap[request.Partition].Value == nil) {
+			requestLogger.Debug("dropped", zap.String("reason", "no end offset"))
+			return
+		}
+		offset = topicMap[request.Partition].Value.(*brokerOffset).Offset
+	}
+
+	partitions := clusterMap.brokerLookback[request.Topic]
+	for i := int32(len(partitions)); i < request.TopicPartitionCount; i++ {
+		partitions = append(partitions, nil)
+	}
+	partitions[request.Partition] = &brokerOffset{
+		Offset:    offset,
+		Timestamp: request.Timestamp,
+	}
+	clusterMap.brokerLookback[request.Topic] = partitions
+
+	requestLogger.Debug("ok")
+}
+
+// addBrokerTimeOffsets stores the offsets that a partition was at, at the sample times in the request, replacing the
+// ones stored before. As with the lookback offset, a sample with nothing produced since its time is stored with the
+// current end offset, or dropped if that is not known.
+func (module *InMemoryStorage) addBrokerTimeOffsets(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+	if (request.Partition < 0) || (request.Partition >= request.TopicPartitionCount) {
+		requestLogger.Warn("partition out of range")
+		return
+	}
+
+	clusterMap.brokerLock.Lock()
+	defer clusterMap.brokerLock.Unlock()
+
+	samples := make([]protocol.TimeOffset, 0, len(request.TimeOffsets))
+	for _, sample := range request.TimeOffsets {
+		if sample.Offset < 0 {
+			topicMap := clusterMap.broker[request.Topic]
+			if (int(request.Partition) >= len(topicMap)) || (topicMap[request.Partition].Value == nil) {
+				continue
+			}
+			sample.Offset = topicMap[request.Partition].Value.(*brokerOffset).Offset
+		}
+		samples = append(samples, sample)
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Timestamp < samples[j].Timestamp })
+
+	partitions := clusterMap.brokerTimeOffsets[request.Topic]
+	for i := int32(len(partitions)); i < request.TopicPartitionCount; i++ {
+		partitions = append(partitions, nil)
+	}
+	partitions[request.Partition] = samples
+	clusterMap.brokerTimeOffsets[request.Topic] = partitions
+
+	requestLogger.Debug("ok", zap.Int("samples", len(samples)))
 }
 
 func (module *InMemoryStorage) getBrokerOffset(clusterMap *clusterOffsets, topic string, partition int32, requestLogger *zap.Logger) (int64, int32) {
@@ -398,21 +927,18 @@ func (module *InMemoryStorage) addConsumerOffset(request *protocol.StorageReques
 	// Get the broker offset for this partition, as well as the partition count
 	brokerOffset, partitionCount := module.getBrokerOffset(&clusterMap, request.Topic, request.Partition, requestLogger)
 	if partitionCount == 0 {
-		// If the returned partitionCount is zero, there was an error that was already logged. Just stop processing
+		// If the returned partitionCount is zero, there was an error that was already logged. Just stop processing,
+		// unless the commit can be held until the partition has an end offset
+		if (module.pendingLimit > 0) && (request.Partition >= 0) && !module.holdPendingCommit(&clusterMap, request, requestLogger) {
+			module.addConsumerOffset(request, requestLogger)
+			return
+		}
+		module.recordUnknownTopicCommit(&clusterMap, request)
 		return
 	}
 
 	// Make the consumer group if it does not yet exist
-	clusterMap.consumerLock.Lock()
-	consumerMap, ok := clusterMap.consumer[request.Group]
-	if !ok {
-		clusterMap.consumer[request.Group] = &consumerGroup{
-			lock:   &sync.RWMutex{},
-			topics: make(map[string][]*consumerPartition),
-		}
-		consumerMap = clusterMap.consumer[request.Group]
-	}
-	clusterMap.consumerLock.Unlock()
+	consumerMap := module.getOrCreateConsumerGroup(&clusterMap, request.Group, requestLogger)
 
 	// For the rest of this, we need the write lock for the consumer group
 	consumerMap.lock.Lock()
@@ -438,9 +964,15 @@ func (module *InMemoryStorage) addConsumerOffset(request *protocol.StorageReques
 		}
 		requestLogger.Debug("ok", zap.Uint64("lag", partitionLag.Value))
 		consumerMap.lastCommit = request.Timestamp
+		consumerPartition.brokerOffset = brokerOffset
+		module.sendLagSample(request, brokerOffset, partitionLag.Value)
 	}
 
-	destination = module.mergeFrequentCommitIntoPrevious(destination, request, requestLogger)
+	if collapsed := module.collapseDuplicateCommit(destination, request, requestLogger); collapsed != nil {
+		destination = collapsed
+	} else {
+		destination = module.mergeFrequentCommitIntoPrevious(destination, request, requestLogger)
+	}
 	module.storeConsumerOffset(consumerPartition, destination, request, partitionLag)
 }
 
@@ -517,6 +1049,30 @@ func (module *InMemoryStorage) mergeFrequentCommitIntoPrevious(destination *offs
 	return destination
 }
 
+// If collapse-duplicate-commits is set and the new commit is for the same offset as the latest one in the ring, return a
+// destination that replaces the latest offset, so an idle group that keeps committing does not push its history out of
+// the ring. Unlike a min-distance merge, the timestamp is updated to that of the new commit. This means that the STALL
+// rule, which looks for an unchanged offset across the ring, will not see the repeated commits. Returns nil if the
+// commit is not collapsed.
+func (module *InMemoryStorage) collapseDuplicateCommit(destination *offsetRingDestination, request *protocol.StorageRequest, requestLogger *zap.Logger) *offsetRingDestination {
+	if (!module.collapseDuplicates) || (!destination.isAppend()) {
+		return nil
+	}
+
+	prevSlot := destination.extendDest.Prev()
+	if prevSlot.Value != nil {
+		prevItem, _ := prevSlot.Value.(*protocol.ConsumerOffset)
+		if (prevItem.Order < request.Order) && (prevItem.Offset == request.Offset) {
+			requestLogger.Debug("collapsed duplicate commit")
+			return &offsetRingDestination{
+				insertDest: prevSlot,
+				extendDest: nil,
+			}
+		}
+	}
+	return nil
+}
+
 func (module *InMemoryStorage) storeConsumerOffset(consumerPartition *consumerPartition, destination *offsetRingDestination, request *protocol.StorageRequest, partitionLag *protocol.Lag) {
 	if destination.isShift() {
 		// Shift each item past destination.insertDest forward (so we end up occupying extendDest)
@@ -563,16 +1119,7 @@ func (module *InMemoryStorage) addConsumerOwner(request *protocol.StorageRequest
 	}
 
 	// Make the consumer group if it does not yet exist
-	clusterMap.consumerLock.Lock()
-	consumerMap, ok := clusterMap.consumer[request.Group]
-	if !ok {
-		clusterMap.consumer[request.Group] = &consumerGroup{
-			lock:   &sync.RWMutex{},
-			topics: make(map[string][]*consumerPartition),
-		}
-		consumerMap = clusterMap.consumer[request.Group]
-	}
-	clusterMap.consumerLock.Unlock()
+	consumerMap := module.getOrCreateConsumerGroup(&clusterMap, request.Group, requestLogger)
 
 	// Get the partition count for this partition (we don't need the actual broker offset)
 	_, partitionCount := module.getBrokerOffset(&clusterMap, request.Topic, request.Partition, requestLogger)
@@ -597,6 +1144,7 @@ func (module *InMemoryStorage) addConsumerOwner(request *protocol.StorageRequest
 	requestLogger.Debug("ok")
 	consumerMap.topics[request.Topic][request.Partition].owner = request.Owner
 	consumerMap.topics[request.Topic][request.Partition].clientID = request.ClientID
+	consumerMap.topics[request.Topic][request.Partition].instanceID = request.InstanceID
 }
 
 func (module *InMemoryStorage) clearConsumerOwners(request *protocol.StorageRequest, requestLogger *zap.Logger) {
@@ -630,6 +1178,7 @@ func (module *InMemoryStorage) clearConsumerOwners(request *protocol.StorageRequ
 		for partitionID := range partitions {
 			consumerMap.topics[topic][partitionID].owner = ""
 			consumerMap.topics[topic][partitionID].clientID = ""
+			consumerMap.topics[topic][partitionID].instanceID = ""
 		}
 	}
 
@@ -643,17 +1192,89 @@ func (module *InMemoryStorage) deleteTopic(request *protocol.StorageRequest, req
 		return
 	}
 
-	// Work backwards - remove the topic from consumer groups first
-	for _, consumerMap := range clusterMap.consumer {
+	// Work backwards - remove the topic from consumer groups first. Commits for the deleted topic are dropped from now
+	// on, as it has no broker offsets, so the groups' status no longer includes the topic once it is evaluated again
+	clusterMap.consumerLock.RLock()
+	for group, consumerMap := range clusterMap.consumer {
 		consumerMap.lock.Lock()
-		// No need to check for existence
-		delete(consumerMap.topics, request.Topic)
+		if _, ok := consumerMap.topics[request.Topic]; ok {
+			delete(consumerMap.topics, request.Topic)
+			requestLogger.Info("removed committed offsets for deleted topic", zap.String("group", group))
+		}
 		consumerMap.lock.Unlock()
 	}
+	clusterMap.consumerLock.RUnlock()
 
 	// Now remove the topic from the broker list
 	clusterMap.brokerLock.Lock()
 	delete(clusterMap.broker, request.Topic)
+	delete(clusterMap.brokerOldest, request.Topic)
+	delete(clusterMap.brokerLookback, request.Topic)
+	delete(clusterMap.brokerTimeOffsets, request.Topic)
+	delete(clusterMap.compacted, request.Topic)
+	clusterMap.brokerLock.Unlock()
+	dropPendingCommits(&clusterMap, request.Topic)
+
+	requestLogger.Debug("ok")
+}
+
+// deletePartition removes one partition of a topic that the cluster module found is gone for good. Partitions past
+// the topic's partition count are cut off, and a partition within it (one that has lost its leader for good) has its
+// end offsets and the groups' commits cleared, so that it is evaluated as it would be before any were stored.
+func (module *InMemoryStorage) deletePartition(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+	shrink := request.Partition >= request.TopicPartitionCount
+
+	// As with deleteTopic, the consumer groups go first
+	clusterMap.consumerLock.RLock()
+	for group, consumerMap := range clusterMap.consumer {
+		consumerMap.lock.Lock()
+		partitions := consumerMap.topics[request.Topic]
+		if int(request.Partition) < len(partitions) {
+			if shrink {
+				consumerMap.topics[request.Topic] = partitions[:request.TopicPartitionCount]
+			} else {
+				partitions[request.Partition] = &consumerPartition{}
+			}
+			requestLogger.Info("removed committed offsets for deleted partition", zap.String("group", group))
+		}
+		consumerMap.lock.Unlock()
+	}
+	clusterMap.consumerLock.RUnlock()
+
+	clusterMap.brokerLock.Lock()
+	if partitions := clusterMap.broker[request.Topic]; int(request.Partition) < len(partitions) {
+		if shrink {
+			clusterMap.broker[request.Topic] = partitions[:request.TopicPartitionCount]
+		} else {
+			partitions[request.Partition] = ring.New(module.intervals)
+		}
+	}
+	if partitions := clusterMap.brokerOldest[request.Topic]; int(request.Partition) < len(partitions) {
+		if shrink {
+			clusterMap.brokerOldest[request.Topic] = partitions[:request.TopicPartitionCount]
+		} else {
+			partitions[request.Partition] = -1
+		}
+	}
+	if partitions := clusterMap.brokerLookback[request.Topic]; int(request.Partition) < len(partitions) {
+		if shrink {
+			clusterMap.brokerLookback[request.Topic] = partitions[:request.TopicPartitionCount]
+		} else {
+			partitions[request.Partition] = nil
+		}
+	}
+	if partitions := clusterMap.brokerTimeOffsets[request.Topic]; int(request.Partition) < len(partitions) {
+		if shrink {
+			clusterMap.brokerTimeOffsets[request.Topic] = partitions[:request.TopicPartitionCount]
+		} else {
+			partitions[request.Partition] = nil
+		}
+	}
 	clusterMap.brokerLock.Unlock()
 
 	requestLogger.Debug("ok")
@@ -672,16 +1293,19 @@ func (module *InMemoryStorage) deleteGroup(request *protocol.StorageRequest, req
 		delete(group.topics, request.Topic)
 		if len(group.topics) == 0 {
 			delete(clusterMap.consumer, request.Group)
+			module.tombstoneGroup(&clusterMap, request.Group, group)
 		} else {
 			// The consumer group consumes other topics, thus we need to keep its metrics
 			deleteAllGroupMetrics = false
 		}
-	} else {
+	} else if group, ok := clusterMap.consumer[request.Group]; ok {
 		delete(clusterMap.consumer, request.Group)
+		module.tombstoneGroup(&clusterMap, request.Group, group)
 	}
 	clusterMap.consumerLock.Unlock()
 
 	if deleteAllGroupMetrics {
+		clearUnknownTopics(&clusterMap, request.Group)
 		httpserver.DeleteConsumerMetrics(request.Cluster, request.Group)
 	} else {
 		// only a specific topic was deleted and the consumer group still exists, thus we delete only a subset of the metrics
@@ -735,6 +1359,9 @@ func (module *InMemoryStorage) fetchConsumerList(request *protocol.StorageReques
 	clusterMap.consumerLock.RLock()
 	consumerList := make([]string, 0, len(clusterMap.consumer))
 	for consumer := range clusterMap.consumer {
+		if module.hiddenGroups[request.Cluster][consumer] {
+			continue
+		}
 		consumerList = append(consumerList, consumer)
 	}
 	clusterMap.consumerLock.RUnlock()
@@ -772,6 +1399,73 @@ func (module *InMemoryStorage) fetchTopic(request *protocol.StorageRequest, requ
 	request.Reply <- offsetList
 }
 
+// fetchTopicOldestOffsets returns the oldest offset for each partition of a topic, which are only stored if the
+// cluster fetches them. Partitions that the oldest offset has not been stored for yet are -1.
+func (module *InMemoryStorage) fetchTopicOldestOffsets(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.brokerLock.RLock()
+	topicList, ok := clusterMap.broker[request.Topic]
+	if !ok {
+		requestLogger.Warn("unknown topic")
+		clusterMap.brokerLock.RUnlock()
+		return
+	}
+
+	oldestOffsets := make([]int64, 0, len(topicList))
+	if stored := clusterMap.brokerOldest[request.Topic]; len(stored) > 0 {
+		for p := range topicList {
+			if p < len(stored) {
+				oldestOffsets = append(oldestOffsets, stored[p])
+			} else {
+				oldestOffsets = append(oldestOffsets, -1)
+			}
+		}
+	}
+	clusterMap.brokerLock.RUnlock()
+
+	requestLogger.Debug("ok")
+	request.Reply <- oldestOffsets
+}
+
+func (module *InMemoryStorage) fetchEndOffsets(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.brokerLock.RLock()
+	endOffsets := make([]*protocol.EndOffset, 0)
+	for topic, partitions := range clusterMap.broker {
+		for partitionID, partition := range partitions {
+			// The ring always points to the most recent entry
+			if partition.Value == nil {
+				continue
+			}
+			ringval := partition.Value.(*brokerOffset)
+			endOffsets = append(endOffsets, &protocol.EndOffset{
+				Topic:     topic,
+				Partition: int32(partitionID),
+				Offset:    ringval.Offset,
+				Timestamp: ringval.Timestamp,
+			})
+		}
+	}
+	clusterMap.brokerLock.RUnlock()
+
+	requestLogger.Debug("ok")
+	request.Reply <- endOffsets
+}
+
 func getConsumerTopicList(consumerMap *consumerGroup) protocol.ConsumerTopics {
 	topicList := make(protocol.ConsumerTopics)
 	consumerMap.lock.RLock()
@@ -781,7 +1475,7 @@ func getConsumerTopicList(consumerMap *consumerGroup) protocol.ConsumerTopics {
 		topicList[topic] = make(protocol.ConsumerPartitions, len(partitions))
 
 		for partitionID, partition := range partitions {
-			consumerPartition := &protocol.ConsumerPartition{Owner: partition.owner, ClientID: partition.clientID}
+			consumerPartition := &protocol.ConsumerPartition{Owner: partition.owner, ClientID: partition.clientID, InstanceID: partition.instanceID, OldestOffset: -1, LookbackOffset: -1}
 			if partition.offsets != nil {
 				offsetRing := partition.offsets
 				consumerPartition.Offsets = make([]*protocol.ConsumerOffset, offsetRing.Len())
@@ -804,6 +1498,9 @@ func getConsumerTopicList(consumerMap *consumerGroup) protocol.ConsumerTopics {
 					}
 					ringPtr = ringPtr.Next()
 				}
+
+				// Start with the end offset from the last commit, in case the current one is not known
+				consumerPartition.BrokerOffsets = []int64{partition.brokerOffset}
 			} else {
 				consumerPartition.Offsets = make([]*protocol.ConsumerOffset, 0)
 			}
@@ -849,23 +1546,41 @@ func (module *InMemoryStorage) fetchConsumer(request *protocol.StorageRequest, r
 	// locking both the consumers and the brokers at the same time
 	clusterMap.brokerLock.RLock()
 	for topic, partitions := range topicList {
-		topicMap, ok := clusterMap.broker[topic]
-		if !ok {
-			// The topic may have just been deleted, so we'll skip this part and just return the consumer data we have
-			continue
-		}
+		// The topic may have just been deleted, in which case there are no end offsets for any partition
+		topicMap := clusterMap.broker[topic]
+		oldestOffsets := clusterMap.brokerOldest[topic]
+		lookbackOffsets := clusterMap.brokerLookback[topic]
+		timeOffsets := clusterMap.brokerTimeOffsets[topic]
+		compacted := clusterMap.compacted[topic]
 
 		for p, partition := range partitions {
-			// Build the slice of broker offsets to return
-			partition.BrokerOffsets = make([]int64, 0, module.intervals)
-			brokerOffsetPtr := topicMap[p].Next()
-			brokerOffsetPtr.Do(func(item interface{}) {
-				if item != nil {
-					partition.BrokerOffsets = append(partition.BrokerOffsets, item.(*brokerOffset).Offset) // nolint:scopelint
-				}
-			})
+			partition.Compacted = compacted
+			if p < len(oldestOffsets) {
+				partition.OldestOffset = oldestOffsets[p]
+			}
+			if (p < len(lookbackOffsets)) && (lookbackOffsets[p] != nil) {
+				partition.LookbackOffset = lookbackOffsets[p].Offset
+				partition.LookbackTimestamp = lookbackOffsets[p].Timestamp
+			}
+			if p < len(timeOffsets) {
+				partition.TimeOffsets = timeOffsets[p]
+			}
+			if (p < len(topicMap)) && (topicMap[p].Value != nil) {
+				// Build the slice of broker offsets to return
+				partition.BrokerOffsets = make([]int64, 0, module.intervals)
+				brokerOffsetPtr := topicMap[p].Next()
+				brokerOffsetPtr.Do(func(item interface{}) {
+					if item != nil {
+						partition.BrokerOffsets = append(partition.BrokerOffsets, item.(*brokerOffset).Offset) // nolint:scopelint
+					}
+				})
+			} else if module.unknownEndOffset == "wait" {
+				// Return the consumer data we have, without end offsets, and let the evaluator skip the partition
+				partition.BrokerOffsets = nil
+				continue
+			}
 
-			if len(partition.Offsets) > 0 {
+			if (len(partition.Offsets) > 0) && (len(partition.BrokerOffsets) > 0) {
 				brokerOffset := partition.BrokerOffsets[len(partition.BrokerOffsets)-1]
 				lastOffset := partition.Offsets[len(partition.Offsets)-1]
 				if lastOffset != nil {
@@ -899,6 +1614,9 @@ func (module *InMemoryStorage) fetchConsumersForTopicList(request *protocol.Stor
 
 	consumerListForTopic := make([]string, 0)
 	for consumerGroup := range clusterMap.consumer {
+		if module.hiddenGroups[request.Cluster][consumerGroup] {
+			continue
+		}
 		consumerMap := clusterMap.consumer[consumerGroup]
 		topicList := getConsumerTopicList(consumerMap)
 		for topic := range topicList {
@@ -914,3 +1632,18 @@ func (module *InMemoryStorage) fetchConsumersForTopicList(request *protocol.Stor
 	requestLogger.Debug("ok")
 	request.Reply <- consumerListForTopic
 }
+
+func (module *InMemoryStorage) fetchStats(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	stats := &protocol.StorageStats{
+		RequestQueue: len(module.requestChannel),
+		WorkerQueues: make([]int, len(module.workers)),
+	}
+	for i, worker := range module.workers {
+		stats.WorkerQueues[i] = len(worker)
+	}
+
+	requestLogger.Debug("ok")
+	request.Reply <- stats
+}
@@ -124,6 +124,13 @@ func TestInMemoryStorage_Configure_BadDenylistRegexp(t *testing.T) {
 	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
 }
 
+func TestInMemoryStorage_Configure_BadUnknownEndOffset(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.unknown-end-offset", "guess")
+
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
+
 func TestInMemoryStorage_Start(t *testing.T) {
 	module := startWithTestCluster("")
 	assert.Len(t, module.offsets, 1, "Module start did not define 1 cluster")
@@ -135,6 +142,40 @@ func TestInMemoryStorage_Stop(t *testing.T) {
 	module.Stop()
 }
 
+func TestInMemoryStorage_Start_ShardByCluster(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.workers", 2)
+	viper.Set("storage.test.shard-by-cluster", true)
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	viper.Set("cluster.othercluster.class-name", "kafka")
+	module.Configure("test", "storage.test")
+	module.Start()
+	defer module.Stop()
+
+	// Two workers each for the two clusters, and two for requests that are not for a cluster
+	assert.Len(t, module.workers, 6, "Expected 6 workers")
+	assert.Len(t, module.workerPool("testcluster"), 2, "Expected 2 workers for testcluster")
+	assert.NotEqual(t, module.workerPool("testcluster")[0], module.workerPool("othercluster")[0], "Expected the clusters to have different workers")
+	assert.Equal(t, module.workers[0], module.workerPool("")[0], "Expected the shared workers for no cluster")
+
+	// Requests are handled the same way
+	module.requestChannel <- &protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOffset,
+		Cluster:             "othercluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              4321,
+		Timestamp:           9876,
+	}
+	request := &protocol.StorageRequest{RequestType: protocol.StorageFetchTopics, Cluster: "othercluster", Reply: make(chan interface{})}
+	assert.Eventually(t, func() bool {
+		request.Reply = make(chan interface{})
+		module.requestChannel <- request
+		return len((<-request.Reply).([]string)) == 1
+	}, time.Second, 10*time.Millisecond, "Expected the topic to be stored")
+}
+
 func TestInMemoryStorage_addBrokerOffset(t *testing.T) {
 	module := startWithTestBrokerOffsets("")
 
@@ -214,6 +255,52 @@ func TestInMemoryStorage_addBrokerOffset_ExistingPartition(t *testing.T) {
 	assert.Equalf(t, int64(9876), previousOffset.Timestamp, "Expected timestamp for p0 to be 9876, got %v", previousOffset.Timestamp)
 }
 
+func TestInMemoryStorage_addBrokerOffset_Regression(t *testing.T) {
+	for _, testCase := range []struct {
+		mode     string
+		expected []int64
+	}{
+		{"accept", []int64{4000, 3000, 3000, 3000, 3100}},
+		{"flag", []int64{4000, 3000, 3000, 3000, 3100}},
+		// The first two lower offsets are rejected, and the third is accepted, as the limit is two
+		{"reject", []int64{4000, 4000, 4000, 3000, 3100}},
+	} {
+		module := fixtureModule("", "")
+		viper.Set("cluster.testcluster.class-name", "kafka")
+		viper.Set("storage.test.end-offset-regression", testCase.mode)
+		viper.Set("storage.test.end-offset-regression-limit", 2)
+		module.Configure("test", "storage.test")
+		module.Start()
+
+		request := protocol.StorageRequest{
+			RequestType:         protocol.StorageSetBrokerOffset,
+			Cluster:             "testcluster",
+			Topic:               "testtopic",
+			Partition:           0,
+			TopicPartitionCount: 1,
+		}
+		for i, offset := range []int64{4000, 3000, 3000, 3000, 3100} {
+			request.Offset = offset
+			request.Timestamp = int64(1000 + i)
+			module.addBrokerOffset(&request, module.Log)
+
+			latest := module.offsets["testcluster"].broker["testtopic"][0].Value.(*brokerOffset)
+			assert.Equalf(t, testCase.expected[i], latest.Offset, "Unexpected offset %v after storing %v (%v)", latest.Offset, offset, testCase.mode)
+		}
+		module.Stop()
+	}
+}
+
+func TestInMemoryStorage_Configure_BadEndOffsetRegression(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.end-offset-regression", "ignore")
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+
+	module = fixtureModule("", "")
+	viper.Set("storage.test.end-offset-regression-limit", 0)
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
+
 func TestInMemoryStorage_addBrokerOffset_AddMany(t *testing.T) {
 	module := startWithTestBrokerOffsets("")
 
@@ -248,6 +335,220 @@ func TestInMemoryStorage_addBrokerOffset_AddMany(t *testing.T) {
 	}
 }
 
+func TestInMemoryStorage_addBrokerOldestOffset(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	// The oldest offset is not known until it is stored
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchConsumer(&request, module.Log)
+	response := (<-request.Reply).(protocol.ConsumerTopics)
+	assert.Equalf(t, int64(-1), response["testtopic"][0].OldestOffset, "Expected oldest offset to be -1, not %v", response["testtopic"][0].OldestOffset)
+
+	module.addBrokerOldestOffset(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOldestOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           1,
+		TopicPartitionCount: 2,
+		Offset:              300,
+	}, module.Log)
+	assert.Equalf(t, []int64{-1, 300}, module.offsets["testcluster"].brokerOldest["testtopic"], "Expected partition 0 to be unknown, got %v", module.offsets["testcluster"].brokerOldest["testtopic"])
+
+	module.addBrokerOldestOffset(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOldestOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 2,
+		Offset:              200,
+	}, module.Log)
+
+	request.Reply = make(chan interface{})
+	go module.fetchConsumer(&request, module.Log)
+	response = (<-request.Reply).(protocol.ConsumerTopics)
+	assert.Equalf(t, int64(200), response["testtopic"][0].OldestOffset, "Expected oldest offset to be 200, not %v", response["testtopic"][0].OldestOffset)
+
+	// Deleting the topic removes its oldest offsets with the end offsets
+	module.deleteTopic(&protocol.StorageRequest{RequestType: protocol.StorageSetDeleteTopic, Cluster: "testcluster", Topic: "testtopic"}, module.Log)
+	_, ok := module.offsets["testcluster"].brokerOldest["testtopic"]
+	assert.False(t, ok, "Expected oldest offsets to be removed with the topic")
+}
+
+func TestInMemoryStorage_addBrokerLookbackOffset(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	// The lookback offset is not known until it is stored
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchConsumer(&request, module.Log)
+	response := (<-request.Reply).(protocol.ConsumerTopics)
+	assert.Equalf(t, int64(-1), response["testtopic"][0].LookbackOffset, "Expected lookback offset to be -1, not %v", response["testtopic"][0].LookbackOffset)
+
+	module.addBrokerLookbackOffset(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerLookbackOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              1500,
+		Timestamp:           startTime,
+	}, module.Log)
+
+	request.Reply = make(chan interface{})
+	go module.fetchConsumer(&request, module.Log)
+	response = (<-request.Reply).(protocol.ConsumerTopics)
+	assert.Equalf(t, int64(1500), response["testtopic"][0].LookbackOffset, "Expected lookback offset to be 1500, not %v", response["testtopic"][0].LookbackOffset)
+	assert.Equalf(t, startTime, response["testtopic"][0].LookbackTimestamp, "Expected lookback timestamp to be %v, not %v", startTime, response["testtopic"][0].LookbackTimestamp)
+
+	// With nothing produced since the lookback time, the end offset is stored
+	module.addBrokerLookbackOffset(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerLookbackOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              -1,
+		Timestamp:           startTime + 1000,
+	}, module.Log)
+	assert.Equalf(t, int64(4321), module.offsets["testcluster"].brokerLookback["testtopic"][0].Offset, "Expected lookback offset to be 4321, not %v", module.offsets["testcluster"].brokerLookback["testtopic"][0].Offset)
+
+	// Deleting the topic removes its lookback offsets with the end offsets
+	module.deleteTopic(&protocol.StorageRequest{RequestType: protocol.StorageSetDeleteTopic, Cluster: "testcluster", Topic: "testtopic"}, module.Log)
+	_, ok := module.offsets["testcluster"].brokerLookback["testtopic"]
+	assert.False(t, ok, "Expected lookback offsets to be removed with the topic")
+}
+
+func TestInMemoryStorage_addBrokerTimeOffsets(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	// The samples are stored oldest first, with the end offset for a sample that has had nothing produced since
+	module.addBrokerTimeOffsets(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerTimeOffsets,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		TimeOffsets: []protocol.TimeOffset{
+			{Timestamp: startTime + 2000, Offset: -1},
+			{Timestamp: startTime, Offset: 1500},
+		},
+	}, module.Log)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchConsumer(&request, module.Log)
+	response := (<-request.Reply).(protocol.ConsumerTopics)
+	assert.Equal(t, []protocol.TimeOffset{{Timestamp: startTime, Offset: 1500}, {Timestamp: startTime + 2000, Offset: 4321}}, response["testtopic"][0].TimeOffsets)
+
+	// A later round of samples replaces the ones before
+	module.addBrokerTimeOffsets(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerTimeOffsets,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		TimeOffsets:         []protocol.TimeOffset{{Timestamp: startTime + 3000, Offset: 2000}},
+	}, module.Log)
+	assert.Equal(t, []protocol.TimeOffset{{Timestamp: startTime + 3000, Offset: 2000}}, module.offsets["testcluster"].brokerTimeOffsets["testtopic"][0])
+
+	// Deleting the topic removes its samples with the end offsets
+	module.deleteTopic(&protocol.StorageRequest{RequestType: protocol.StorageSetDeleteTopic, Cluster: "testcluster", Topic: "testtopic"}, module.Log)
+	_, ok := module.offsets["testcluster"].brokerTimeOffsets["testtopic"]
+	assert.False(t, ok, "Expected samples to be removed with the topic")
+}
+
+func TestInMemoryStorage_deletePartition(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestCluster("")
+	for partition := int32(0); partition < 3; partition++ {
+		module.addBrokerOffset(&protocol.StorageRequest{
+			RequestType:         protocol.StorageSetBrokerOffset,
+			Cluster:             "testcluster",
+			Topic:               "testtopic",
+			Partition:           partition,
+			TopicPartitionCount: 3,
+			Offset:              4321,
+			Timestamp:           startTime,
+		}, module.Log)
+		module.addBrokerOldestOffset(&protocol.StorageRequest{
+			RequestType:         protocol.StorageSetBrokerOldestOffset,
+			Cluster:             "testcluster",
+			Topic:               "testtopic",
+			Partition:           partition,
+			TopicPartitionCount: 3,
+			Offset:              100,
+		}, module.Log)
+		module.addConsumerOffset(&protocol.StorageRequest{
+			RequestType: protocol.StorageSetConsumerOffset,
+			Cluster:     "testcluster",
+			Topic:       "testtopic",
+			Group:       "testgroup",
+			Partition:   partition,
+			Offset:      1000,
+			Order:       500,
+			Timestamp:   startTime,
+		}, module.Log)
+	}
+
+	// A partition within the partition count is cleared
+	module.deletePartition(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetDeletePartition,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           1,
+		TopicPartitionCount: 3,
+	}, module.Log)
+	clusterMap := module.offsets["testcluster"]
+	assert.Len(t, clusterMap.broker["testtopic"], 3, "Expected the partition count to be unchanged")
+	assert.Nil(t, clusterMap.broker["testtopic"][1].Value, "Expected the end offsets to be cleared")
+	assert.NotNil(t, clusterMap.broker["testtopic"][0].Value, "Expected the other partitions to be kept")
+	assert.Equal(t, []int64{100, -1, 100}, clusterMap.brokerOldest["testtopic"])
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchConsumer(&request, module.Log)
+	response := (<-request.Reply).(protocol.ConsumerTopics)
+	assert.Len(t, response["testtopic"], 3)
+	assert.Empty(t, response["testtopic"][1].Offsets, "Expected the commits to be cleared")
+	assert.NotEmpty(t, response["testtopic"][2].Offsets, "Expected the other partitions to be kept")
+
+	// The partitions past a lower partition count are removed
+	module.deletePartition(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetDeletePartition,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           2,
+		TopicPartitionCount: 2,
+	}, module.Log)
+	assert.Len(t, clusterMap.broker["testtopic"], 2, "Expected the topic to be cut to 2 partitions")
+	assert.Equal(t, []int64{100, -1}, clusterMap.brokerOldest["testtopic"])
+
+	request.Reply = make(chan interface{})
+	go module.fetchConsumer(&request, module.Log)
+	response = (<-request.Reply).(protocol.ConsumerTopics)
+	assert.Len(t, response["testtopic"], 2, "Expected the group's topic to be cut to 2 partitions")
+}
+
 func TestInMemoryStorage_addBrokerOffset_BadCluster(t *testing.T) {
 	module := startWithTestCluster("")
 	request := protocol.StorageRequest{
@@ -712,6 +1013,53 @@ func TestInMemoryStorage_addConsumerOffset_MinDistance(t *testing.T) {
 	}
 }
 
+func TestInMemoryStorage_addConsumerOffset_CollapseDuplicates(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+	module.collapseDuplicates = true
+
+	// This commit is for the same offset as the last one, and should replace it without advancing the ring
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      1900,
+		Order:       1000,
+		Timestamp:   startTime + 100000,
+	}
+	module.addConsumerOffset(&request, module.Log)
+
+	partitions := module.offsets["testcluster"].consumer["testgroup"].topics["testtopic"]
+	assert.Equal(t, 10, partitions[0].offsets.Len(), "10 offset ring entries not created")
+
+	r := partitions[0].offsets
+	for i := 0; i < 10; i++ {
+		assert.NotNilf(t, r.Value, "Expected ring value to be NOT nil at position %v", i)
+		offset := r.Value.(*protocol.ConsumerOffset)
+		offsetValue := int64(1000 + (i * 100))
+		timestampValue := startTime + int64(i*10000)
+		if i == 9 {
+			// The last offset in the ring has the timestamp of the duplicate commit
+			timestampValue = startTime + 100000
+		}
+
+		assert.Equalf(t, offsetValue, offset.Offset, "Expected offset at position %v to be %v, got %v", i, offsetValue, offset.Offset)
+		assert.Equalf(t, timestampValue, offset.Timestamp, "Expected timestamp at position %v to be %v, got %v", i, timestampValue, offset.Timestamp)
+		r = r.Next()
+	}
+
+	// A commit for a new offset still advances the ring
+	request.Offset = 2000
+	request.Order = 1001
+	request.Timestamp = startTime + 110000
+	module.addConsumerOffset(&request, module.Log)
+	r = partitions[0].offsets.Prev()
+	assert.Equalf(t, int64(2000), r.Value.(*protocol.ConsumerOffset).Offset, "Expected last offset to be 2000, got %v", r.Value.(*protocol.ConsumerOffset).Offset)
+	assert.Equalf(t, int64(1900), r.Prev().Value.(*protocol.ConsumerOffset).Offset, "Expected previous offset to be 1900, got %v", r.Prev().Value.(*protocol.ConsumerOffset).Offset)
+}
+
 func TestInMemoryStorage_addConsumerOffset_BadBrokerOffset(t *testing.T) {
 	module := startWithTestBrokerOffsets("")
 
@@ -762,6 +1110,7 @@ func TestInMemoryStorage_addConsumerOwner(t *testing.T) {
 		Partition:   0,
 		Owner:       "testhost.example.com",
 		ClientID:    "test_client_id",
+		InstanceID:  "test_instance_id",
 	}
 	module.addConsumerOwner(&request, module.Log)
 
@@ -772,6 +1121,7 @@ func TestInMemoryStorage_addConsumerOwner(t *testing.T) {
 	assert.Len(t, partitions, 1, "One partition not created")
 	assert.Equal(t, "testhost.example.com", partitions[0].owner, "Expected owner to be testhost.example.com, not %v", partitions[0].owner)
 	assert.Equal(t, "test_client_id", partitions[0].clientID, "Expected clientID to be test_client_id, not %v", partitions[0].clientID)
+	assert.Equal(t, "test_instance_id", partitions[0].instanceID, "Expected instanceID to be test_instance_id, not %v", partitions[0].instanceID)
 }
 
 func TestInMemoryStorage_deleteTopic(t *testing.T) {
@@ -790,6 +1140,19 @@ func TestInMemoryStorage_deleteTopic(t *testing.T) {
 	consumerMap := module.offsets["testcluster"].consumer["testgroup"]
 	_, ok = consumerMap.topics["testtopic"]
 	assert.False(t, ok, "Topic not deleted from group offsets")
+
+	// Commits for the deleted topic are not stored again
+	module.addConsumerOffset(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      5000,
+		Timestamp:   time.Now().Unix() * 1000,
+	}, module.Log)
+	_, ok = consumerMap.topics["testtopic"]
+	assert.False(t, ok, "Deleted topic added back to group offsets")
 }
 
 func TestInMemoryStorage_deleteTopic_BadCluster(t *testing.T) {
@@ -894,6 +1257,28 @@ func TestInMemoryStorage_fetchClusterList(t *testing.T) {
 	assert.False(t, ok, "Expected channel to be closed")
 }
 
+func TestInMemoryStorage_fetchStats(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchStats,
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchStats(&request, module.Log)
+	response := <-request.Reply
+
+	assert.IsType(t, &protocol.StorageStats{}, response, "Expected response to be of type *protocol.StorageStats")
+	val := response.(*protocol.StorageStats)
+	assert.Equalf(t, 0, val.RequestQueue, "Expected request queue to be 0, not %v", val.RequestQueue)
+	assert.Lenf(t, val.WorkerQueues, len(module.workers), "Expected %v worker queues, not %v", len(module.workers), len(val.WorkerQueues))
+
+	_, ok := <-request.Reply
+	assert.False(t, ok, "Expected channel to be closed")
+}
+
 func TestInMemoryStorage_fetchTopicList(t *testing.T) {
 	startTime := (time.Now().Unix() * 1000) - 100000
 	module := startWithTestConsumerOffsets("", startTime)
@@ -958,6 +1343,66 @@ func TestInMemoryStorage_fetchConsumerList(t *testing.T) {
 	assert.False(t, ok, "Expected channel to be closed")
 }
 
+func TestInMemoryStorage_fetchConsumerList_BurrowGroups(t *testing.T) {
+	for _, include := range []bool{false, true} {
+		module := fixtureModule("", "")
+		viper.Set("cluster.testcluster.class-name", "kafka")
+		viper.Set("consumer.testconsumer.class-name", "kafka")
+		viper.Set("consumer.testconsumer.cluster", "testcluster")
+		viper.Set("storage.test.include-burrow-groups", include)
+		module.Configure("test", "storage.test")
+		module.Start()
+
+		brokerRequest := protocol.StorageRequest{
+			RequestType:         protocol.StorageSetBrokerOffset,
+			Cluster:             "testcluster",
+			Topic:               "testtopic",
+			Partition:           0,
+			TopicPartitionCount: 1,
+			Offset:              4321,
+			Timestamp:           9876,
+		}
+		module.addBrokerOffset(&brokerRequest, module.Log)
+		for _, group := range []string{"testgroup", "burrow-testconsumer"} {
+			module.addConsumerOffset(&protocol.StorageRequest{
+				RequestType: protocol.StorageSetConsumerOffset,
+				Cluster:     "testcluster",
+				Topic:       "testtopic",
+				Group:       group,
+				Partition:   0,
+				Offset:      1000,
+				Order:       500,
+				Timestamp:   (time.Now().Unix() * 1000) - 100000,
+			}, module.Log)
+		}
+
+		expected := []string{"testgroup"}
+		if include {
+			expected = append(expected, "burrow-testconsumer")
+		}
+		for _, requestType := range []protocol.StorageRequestConstant{protocol.StorageFetchConsumers, protocol.StorageFetchConsumersForTopic} {
+			request := protocol.StorageRequest{
+				RequestType: requestType,
+				Cluster:     "testcluster",
+				Topic:       "testtopic",
+				Reply:       make(chan interface{}),
+			}
+
+			// Can't read a reply without concurrency
+			if requestType == protocol.StorageFetchConsumers {
+				go module.fetchConsumerList(&request, module.Log)
+			} else {
+				go module.fetchConsumersForTopicList(&request, module.Log)
+			}
+			response := <-request.Reply
+
+			assert.IsType(t, []string{}, response, "Expected response to be of type []string")
+			assert.ElementsMatchf(t, expected, response.([]string), "Expected %v with include-burrow-groups %v to return %v, not %v", requestType, include, expected, response)
+		}
+		module.Stop()
+	}
+}
+
 func TestInMemoryStorage_fetchConsumerList_BadCluster(t *testing.T) {
 	startTime := (time.Now().Unix() * 1000) - 100000
 	module := startWithTestConsumerOffsets("", startTime)
@@ -1000,6 +1445,77 @@ func TestInMemoryStorage_fetchTopic(t *testing.T) {
 	assert.False(t, ok, "Expected channel to be closed")
 }
 
+func TestInMemoryStorage_fetchTopicOldestOffsets(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	// Nothing is returned for the partitions until the oldest offsets are stored
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchTopicOldestOffsets,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchTopicOldestOffsets(&request, module.Log)
+	assert.Equal(t, []int64{}, <-request.Reply, "Expected no oldest offsets")
+
+	module.addBrokerOldestOffset(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOldestOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              200,
+	}, module.Log)
+
+	request.Reply = make(chan interface{})
+	go module.fetchTopicOldestOffsets(&request, module.Log)
+	assert.Equal(t, []int64{200}, <-request.Reply, "Expected the stored oldest offset")
+	_, ok := <-request.Reply
+	assert.False(t, ok, "Expected channel to be closed")
+
+	// Unknown topics and clusters get no reply
+	request = protocol.StorageRequest{RequestType: protocol.StorageFetchTopicOldestOffsets, Cluster: "testcluster", Topic: "notopic", Reply: make(chan interface{})}
+	go module.fetchTopicOldestOffsets(&request, module.Log)
+	assert.Nil(t, <-request.Reply, "Expected no reply for an unknown topic")
+	request = protocol.StorageRequest{RequestType: protocol.StorageFetchTopicOldestOffsets, Cluster: "nocluster", Topic: "testtopic", Reply: make(chan interface{})}
+	go module.fetchTopicOldestOffsets(&request, module.Log)
+	assert.Nil(t, <-request.Reply, "Expected no reply for an unknown cluster")
+}
+
+func TestInMemoryStorage_fetchEndOffsets(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchEndOffsets,
+		Cluster:     "testcluster",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchEndOffsets(&request, module.Log)
+	response := <-request.Reply
+
+	assert.IsType(t, []*protocol.EndOffset{}, response, "Expected response to be of type []*protocol.EndOffset")
+	val := response.([]*protocol.EndOffset)
+	assert.Equal(t, []*protocol.EndOffset{{Topic: "testtopic", Partition: 0, Offset: 4321, Timestamp: 9876}}, val)
+
+	_, ok := <-request.Reply
+	assert.False(t, ok, "Expected channel to be closed")
+
+	// An unknown cluster gets no response
+	request = protocol.StorageRequest{
+		RequestType: protocol.StorageFetchEndOffsets,
+		Cluster:     "nocluster",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchEndOffsets(&request, module.Log)
+	response, ok = <-request.Reply
+	assert.Nil(t, response, "Expected response to be nil")
+	assert.False(t, ok, "Expected channel to be closed")
+}
+
 func TestInMemoryStorage_fetchTopic_BadCluster(t *testing.T) {
 	startTime := (time.Now().Unix() * 1000) - 100000
 	module := startWithTestConsumerOffsets("", startTime)
@@ -1097,6 +1613,40 @@ func TestInMemoryStorage_fetchConsumer(t *testing.T) {
 	assert.False(t, ok, "Expected channel to be closed")
 }
 
+func TestInMemoryStorage_fetchConsumer_UnknownEndOffset(t *testing.T) {
+	testCases := []struct {
+		unknownEndOffset string
+		brokerOffsets    []int64
+		currentLag       uint64
+	}{
+		{"wait", nil, 0},
+		{"last-known", []int64{4321}, 2421},
+	}
+
+	for _, testCase := range testCases {
+		module := startWithTestConsumerOffsets("", (time.Now().Unix()*1000)-100000)
+		module.unknownEndOffset = testCase.unknownEndOffset
+
+		// Remove the partition from the broker offsets, as if the topic was recreated and not fetched yet
+		module.offsets["testcluster"].broker["testtopic"] = make([]*ring.Ring, 0)
+
+		request := protocol.StorageRequest{
+			RequestType: protocol.StorageFetchConsumer,
+			Cluster:     "testcluster",
+			Group:       "testgroup",
+			Reply:       make(chan interface{}),
+		}
+		go module.fetchConsumer(&request, module.Log)
+		response := <-request.Reply
+
+		val := response.(protocol.ConsumerTopics)
+		assert.Lenf(t, val["testtopic"], 1, "%v: One partition for topic not returned", testCase.unknownEndOffset)
+		assert.Lenf(t, val["testtopic"][0].Offsets, 10, "%v: Expected to get 10 offsets for the partition", testCase.unknownEndOffset)
+		assert.Equalf(t, testCase.brokerOffsets, val["testtopic"][0].BrokerOffsets, "%v: Expected broker offsets to be %v, not %v", testCase.unknownEndOffset, testCase.brokerOffsets, val["testtopic"][0].BrokerOffsets)
+		assert.Equalf(t, testCase.currentLag, val["testtopic"][0].CurrentLag, "%v: Expected current lag to be %v, not %v", testCase.unknownEndOffset, testCase.currentLag, val["testtopic"][0].CurrentLag)
+	}
+}
+
 func TestInMemoryStorage_fetchConsumer_BadCluster(t *testing.T) {
 	startTime := (time.Now().Unix() * 1000) - 100000
 	module := startWithTestConsumerOffsets("", startTime)
@@ -1308,3 +1858,67 @@ func TestInMemoryStorage_fetchConsumersForTopic_BadCluster(t *testing.T) {
 	assert.Nil(t, response, "Expected response to be nil")
 	assert.False(t, ok, "Expected channel to be closed")
 }
+
+// benchmarkClusterContention measures the time for fetches from three clusters while a fourth cluster is busy: its
+// broker lock is held most of the time, as during a long write, and requests for it keep arriving. Without sharding,
+// the busy cluster's requests tie up the shared workers.
+func benchmarkClusterContention(b *testing.B, shardByCluster bool) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.workers", 4)
+	viper.Set("storage.test.shard-by-cluster", shardByCluster)
+	clusters := []string{"busy", "quiet1", "quiet2", "quiet3"}
+	for _, cluster := range clusters {
+		viper.Set("cluster."+cluster+".class-name", "kafka")
+	}
+	module.Configure("test", "storage.test")
+	module.Start()
+	defer module.Stop()
+
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	busy := module.offsets["busy"]
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			busy.brokerLock.Lock()
+			time.Sleep(time.Millisecond)
+			busy.brokerLock.Unlock()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(100 * time.Microsecond):
+			}
+			module.requestChannel <- &protocol.StorageRequest{RequestType: protocol.StorageFetchTopics, Cluster: "busy", Reply: make(chan interface{}, 1)}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			request := &protocol.StorageRequest{RequestType: protocol.StorageFetchTopics, Cluster: clusters[1+(i%3)], Reply: make(chan interface{})}
+			module.requestChannel <- request
+			<-request.Reply
+			i++
+		}
+	})
+	b.StopTimer()
+	close(stop)
+	wg.Wait()
+}
+
+func BenchmarkInMemoryStorage_ClusterContention(b *testing.B) {
+	b.Run("shared", func(b *testing.B) { benchmarkClusterContention(b, false) })
+	b.Run("sharded", func(b *testing.B) { benchmarkClusterContention(b, true) })
+}
@@ -0,0 +1,191 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/internal/httpserver"
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// lagSinkQueue holds the lag samples for a single sink until they are sent. Samples are added by the storage workers,
+// and sent in batches by the queue's own goroutine, so a slow sink only fills its own queue.
+type lagSinkQueue struct {
+	name      string
+	sink      protocol.LagSink
+	samples   chan *protocol.LagSample
+	batchSize int
+	interval  time.Duration
+	log       *zap.Logger
+	running   sync.WaitGroup
+}
+
+func newLagSinkQueue(name string, sink protocol.LagSink, queueDepth, batchSize int, interval time.Duration, logger *zap.Logger) *lagSinkQueue {
+	return &lagSinkQueue{
+		name:      name,
+		sink:      sink,
+		samples:   make(chan *protocol.LagSample, queueDepth),
+		batchSize: batchSize,
+		interval:  interval,
+		log:       logger.With(zap.String("sink", name)),
+	}
+}
+
+// add queues a sample for the sink. It never blocks: if the queue is full, the sample is dropped and counted
+func (queue *lagSinkQueue) add(sample *protocol.LagSample) {
+	select {
+	case queue.samples <- sample:
+	default:
+		httpserver.CountDroppedLagSample(queue.name)
+	}
+}
+
+func (queue *lagSinkQueue) start() {
+	queue.running.Add(1)
+	go queue.sendLoop()
+}
+
+// stop closes the queue, which sends the samples that are left in it, and waits for the sink to return. It must only
+// be called once nothing else will add samples.
+func (queue *lagSinkQueue) stop() {
+	close(queue.samples)
+	queue.running.Wait()
+}
+
+// sendLoop sends the queued samples to the sink whenever there are batch-size of them, or every interval if there are
+// fewer, until the queue is closed
+func (queue *lagSinkQueue) sendLoop() {
+	defer queue.running.Done()
+
+	ticker := time.NewTicker(queue.interval)
+	defer ticker.Stop()
+
+	batch := make([]*protocol.LagSample, 0, queue.batchSize)
+	for {
+		select {
+		case sample, ok := <-queue.samples:
+			if !ok {
+				queue.send(batch)
+				return
+			}
+			batch = append(batch, sample)
+			if len(batch) < queue.batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		queue.send(batch)
+		batch = make([]*protocol.LagSample, 0, queue.batchSize)
+	}
+}
+
+func (queue *lagSinkQueue) send(batch []*protocol.LagSample) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := queue.sink.SendLagSamples(batch); err != nil {
+		queue.log.Warn("failed to send lag samples",
+			zap.Int("samples", len(batch)),
+			zap.Error(err),
+		)
+	}
+}
+
+// webhookLagSink is the built-in lag sink, which POSTs each batch of samples to a URL as a JSON array
+type webhookLagSink struct {
+	url        string
+	httpClient *http.Client
+}
+
+func (sink *webhookLagSink) SendLagSamples(samples []*protocol.LagSample) error {
+	content, err := json.Marshal(samples)
+	if err != nil {
+		return err
+	}
+	resp, err := sink.httpClient.Post(sink.url, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if (resp.StatusCode < 200) || (resp.StatusCode > 299) {
+		return errors.New("unexpected response status " + strconv.Itoa(resp.StatusCode))
+	}
+	return nil
+}
+
+// configureLagSinks sets up a queue for each of the application's lag sinks, and for the webhook if lag-sink-url is set.
+// Application sinks are named by their position in the list, for logging and the dropped samples metric.
+func (module *InMemoryStorage) configureLagSinks(configRoot string) {
+	viper.SetDefault(configRoot+".lag-sink-queue-depth", 10000)
+	viper.SetDefault(configRoot+".lag-sink-batch-size", 500)
+	viper.SetDefault(configRoot+".lag-sink-interval", 5)
+	viper.SetDefault(configRoot+".lag-sink-timeout", 5)
+	queueDepth := viper.GetInt(configRoot + ".lag-sink-queue-depth")
+	batchSize := viper.GetInt(configRoot + ".lag-sink-batch-size")
+	interval := viper.GetInt(configRoot + ".lag-sink-interval")
+	if queueDepth < 1 {
+		panic("storage " + module.name + ": lag-sink-queue-depth must be at least 1")
+	}
+	if batchSize < 1 {
+		panic("storage " + module.name + ": lag-sink-batch-size must be at least 1")
+	}
+	if interval <= 0 {
+		panic("storage " + module.name + ": lag-sink-interval must be greater than zero")
+	}
+
+	module.lagSinks = make([]*lagSinkQueue, 0)
+	for i, sink := range module.App.LagSinks {
+		module.lagSinks = append(module.lagSinks, newLagSinkQueue("app-"+strconv.Itoa(i), sink, queueDepth, batchSize, time.Duration(interval)*time.Second, module.Log))
+	}
+
+	if sinkURL := viper.GetString(configRoot + ".lag-sink-url"); sinkURL != "" {
+		if _, err := url.ParseRequestURI(sinkURL); err != nil {
+			panic("storage " + module.name + ": lag-sink-url is not a valid URL: " + err.Error())
+		}
+		sink := &webhookLagSink{
+			url: sinkURL,
+			httpClient: &http.Client{
+				Timeout: viper.GetDuration(configRoot+".lag-sink-timeout") * time.Second,
+			},
+		}
+		module.lagSinks = append(module.lagSinks, newLagSinkQueue("webhook", sink, queueDepth, batchSize, time.Duration(interval)*time.Second, module.Log))
+	}
+}
+
+// sendLagSample queues the lag that was just calculated for a commit for each of the lag sinks
+func (module *InMemoryStorage) sendLagSample(request *protocol.StorageRequest, brokerOffset int64, lag uint64) {
+	if len(module.lagSinks) == 0 {
+		return
+	}
+	sample := &protocol.LagSample{
+		Cluster:   request.Cluster,
+		Group:     request.Group,
+		Topic:     request.Topic,
+		Partition: request.Partition,
+		Offset:    request.Offset,
+		Timestamp: request.Timestamp,
+		EndOffset: brokerOffset,
+		Lag:       lag,
+	}
+	for _, queue := range module.lagSinks {
+		queue.add(sample)
+	}
+}
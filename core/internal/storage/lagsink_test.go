@@ -0,0 +1,156 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+type recordingLagSink struct {
+	lock    sync.Mutex
+	batches [][]*protocol.LagSample
+}
+
+func (sink *recordingLagSink) SendLagSamples(samples []*protocol.LagSample) error {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	sink.batches = append(sink.batches, samples)
+	return nil
+}
+
+func startWithLagSinks(sinks ...protocol.LagSink) *InMemoryStorage {
+	module := fixtureModule("", "")
+	module.App.LagSinks = sinks
+	startWithLagSinksConfigured(module)
+	return module
+}
+
+// startWithLagSinksConfigured starts a module that has been set up with its lag sinks, with an end offset for the test
+// partition so that the commits have lag
+func startWithLagSinksConfigured(module *InMemoryStorage) {
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	viper.Set("cluster.testcluster.servers", []string{"broker1.example.com:1234"})
+	viper.Set("storage.test.lag-sink-batch-size", 2)
+	module.Configure("test", "storage.test")
+	module.Start()
+
+	module.addBrokerOffset(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              4321,
+		Timestamp:           9876,
+	}, module.Log)
+}
+
+func addTestCommits(module *InMemoryStorage, count int) int64 {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	for i := 0; i < count; i++ {
+		module.addConsumerOffset(&protocol.StorageRequest{
+			RequestType: protocol.StorageSetConsumerOffset,
+			Cluster:     "testcluster",
+			Topic:       "testtopic",
+			Group:       "testgroup",
+			Partition:   0,
+			Offset:      int64(1000 + (i * 100)),
+			Order:       int64(500 + i),
+			Timestamp:   startTime + int64(i*10000),
+		}, module.Log)
+	}
+	return startTime
+}
+
+func TestInMemoryStorage_LagSink(t *testing.T) {
+	sink := &recordingLagSink{}
+	module := startWithLagSinks(sink)
+	startTime := addTestCommits(module, 3)
+
+	// Stopping sends the last partial batch
+	module.Stop()
+
+	assert.Len(t, sink.batches, 2, "Expected a full batch and a partial batch")
+	assert.Len(t, sink.batches[0], 2, "Expected the first batch to have lag-sink-batch-size samples")
+	assert.Len(t, sink.batches[1], 1, "Expected the remaining sample in the last batch")
+	assert.Equal(t, &protocol.LagSample{
+		Cluster:   "testcluster",
+		Group:     "testgroup",
+		Topic:     "testtopic",
+		Partition: 0,
+		Offset:    1000,
+		Timestamp: startTime,
+		EndOffset: 4321,
+		Lag:       3321,
+	}, sink.batches[0][0])
+	assert.Equal(t, uint64(3221), sink.batches[0][1].Lag)
+	assert.Equal(t, uint64(3121), sink.batches[1][0].Lag)
+}
+
+func TestInMemoryStorage_LagSink_Webhook(t *testing.T) {
+	received := make(chan []*protocol.LagSample, 5)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var samples []*protocol.LagSample
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&samples), "Expected a JSON array of samples")
+		received <- samples
+	}))
+	defer server.Close()
+
+	module := fixtureModule("", "")
+	viper.Set("storage.test.lag-sink-url", server.URL)
+	startWithLagSinksConfigured(module)
+	addTestCommits(module, 2)
+
+	select {
+	case samples := <-received:
+		assert.Len(t, samples, 2)
+		assert.Equal(t, "testgroup", samples[0].Group)
+		assert.Equal(t, int64(1100), samples[1].Offset)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the webhook to receive a batch")
+	}
+	module.Stop()
+}
+
+func TestLagSinkQueue_Full(t *testing.T) {
+	sink := &recordingLagSink{}
+	queue := newLagSinkQueue("test", sink, 1, 10, time.Hour, zap.NewNop())
+
+	// The queue is not started, so the second sample does not fit and is dropped rather than blocking
+	queue.add(&protocol.LagSample{Offset: 1})
+	queue.add(&protocol.LagSample{Offset: 2})
+
+	queue.start()
+	queue.stop()
+	assert.Len(t, sink.batches, 1)
+	assert.Equal(t, []*protocol.LagSample{{Offset: 1}}, sink.batches[0])
+}
+
+func TestInMemoryStorage_Configure_BadLagSink(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.lag-sink-batch-size", 0)
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+
+	module = fixtureModule("", "")
+	viper.Set("storage.test.lag-sink-url", "not a url")
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// readMutedGroups reads the muted groups from the named file, which is a JSON object of cluster names to objects of
+// group names to the time the mute expires (in milliseconds, or 0 if it does not). A file that does not exist yet is
+// not an error, as it is created the first time a group is muted.
+func readMutedGroups(filename string) (map[string]map[string]int64, error) {
+	mutedGroups := make(map[string]map[string]int64)
+
+	content, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return mutedGroups, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &mutedGroups); err != nil {
+		return nil, err
+	}
+	return mutedGroups, nil
+}
+
+// writeMutedGroups writes the muted groups that have not expired to the mute-file, if one is configured. The file is
+// written to a temporary name and then renamed, so that a failed write does not lose the existing mutes. It must be
+// called with the mute lock held.
+func (module *InMemoryStorage) writeMutedGroups(requestLogger *zap.Logger) {
+	if module.muteFile == "" {
+		return
+	}
+
+	timeNow := time.Now().Unix() * 1000
+	mutedGroups := make(map[string]map[string]int64)
+	for cluster, groups := range module.mutedGroups {
+		for group, expires := range groups {
+			if (expires != 0) && (expires <= timeNow) {
+				continue
+			}
+			if _, ok := mutedGroups[cluster]; !ok {
+				mutedGroups[cluster] = make(map[string]int64)
+			}
+			mutedGroups[cluster][group] = expires
+		}
+	}
+
+	content, err := json.Marshal(mutedGroups)
+	if err == nil {
+		err = os.WriteFile(module.muteFile+".tmp", content, 0o600)
+	}
+	if err == nil {
+		err = os.Rename(module.muteFile+".tmp", module.muteFile)
+	}
+	if err != nil {
+		requestLogger.Error("failed to write mute file",
+			zap.String("filename", module.muteFile),
+			zap.Error(err),
+		)
+	}
+}
+
+func (module *InMemoryStorage) muteGroup(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	module.muteLock.Lock()
+	defer module.muteLock.Unlock()
+
+	groups, ok := module.mutedGroups[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+	groups[request.Group] = request.Timestamp
+	module.writeMutedGroups(requestLogger)
+	requestLogger.Info("muted group")
+}
+
+func (module *InMemoryStorage) unmuteGroup(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	module.muteLock.Lock()
+	defer module.muteLock.Unlock()
+
+	groups, ok := module.mutedGroups[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+	if _, ok := groups[request.Group]; !ok {
+		return
+	}
+	delete(groups, request.Group)
+	module.writeMutedGroups(requestLogger)
+	requestLogger.Info("unmuted group")
+}
+
+func (module *InMemoryStorage) fetchMutedGroups(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	module.muteLock.RLock()
+	defer module.muteLock.RUnlock()
+
+	groups, ok := module.mutedGroups[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	// Expired mutes are left in place until the next change, but are not returned
+	timeNow := time.Now().Unix() * 1000
+	mutedGroups := make(map[string]int64)
+	for group, expires := range groups {
+		if (expires == 0) || (expires > timeNow) {
+			mutedGroups[group] = expires
+		}
+	}
+
+	requestLogger.Debug("ok")
+	request.Reply <- mutedGroups
+}
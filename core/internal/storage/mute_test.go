@@ -0,0 +1,105 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func fetchMutedGroupsSync(module *InMemoryStorage, cluster string) interface{} {
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchMutedGroups,
+		Cluster:     cluster,
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchMutedGroups(&request, module.Log)
+	return <-request.Reply
+}
+
+func TestInMemoryStorage_muteGroup(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	expires := (time.Now().Unix() * 1000) + 100000
+	module.muteGroup(&protocol.StorageRequest{RequestType: protocol.StorageSetMuteGroup, Cluster: "testcluster", Group: "testgroup"}, module.Log)
+	module.muteGroup(&protocol.StorageRequest{RequestType: protocol.StorageSetMuteGroup, Cluster: "testcluster", Group: "expiringgroup", Timestamp: expires}, module.Log)
+	module.muteGroup(&protocol.StorageRequest{RequestType: protocol.StorageSetMuteGroup, Cluster: "testcluster", Group: "expiredgroup", Timestamp: startTime}, module.Log)
+
+	response := fetchMutedGroupsSync(module, "testcluster")
+	assert.IsType(t, map[string]int64{}, response, "Expected response to be of type map[string]int64")
+	assert.Equalf(t, map[string]int64{"testgroup": 0, "expiringgroup": expires}, response, "Expected expired mute to not be returned, got %v", response)
+
+	// Unmuting leaves the group's offsets alone
+	module.unmuteGroup(&protocol.StorageRequest{RequestType: protocol.StorageSetUnmuteGroup, Cluster: "testcluster", Group: "testgroup"}, module.Log)
+	response = fetchMutedGroupsSync(module, "testcluster")
+	assert.Equalf(t, map[string]int64{"expiringgroup": expires}, response, "Expected unmuted group to not be returned, got %v", response)
+	_, ok := module.offsets["testcluster"].consumer["testgroup"]
+	assert.True(t, ok, "Expected group to still exist")
+}
+
+func TestInMemoryStorage_muteGroup_DeletedGroup(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	module.muteGroup(&protocol.StorageRequest{RequestType: protocol.StorageSetMuteGroup, Cluster: "testcluster", Group: "testgroup"}, module.Log)
+	module.deleteGroup(&protocol.StorageRequest{RequestType: protocol.StorageSetDeleteGroup, Cluster: "testcluster", Group: "testgroup"}, module.Log)
+
+	response := fetchMutedGroupsSync(module, "testcluster")
+	assert.Equalf(t, map[string]int64{"testgroup": 0}, response, "Expected mute to be kept when the group is deleted, got %v", response)
+}
+
+func TestInMemoryStorage_fetchMutedGroups_BadCluster(t *testing.T) {
+	module := startWithTestCluster("")
+
+	response := fetchMutedGroupsSync(module, "nocluster")
+	assert.Nil(t, response, "Expected response to be nil")
+}
+
+func TestInMemoryStorage_MuteFile(t *testing.T) {
+	muteFile := filepath.Join(t.TempDir(), "muted.json")
+	expires := (time.Now().Unix() * 1000) + 100000
+
+	module := fixtureModule("", "")
+	viper.Set("storage.test.mute-file", muteFile)
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	module.Configure("test", "storage.test")
+	module.Start()
+	module.muteGroup(&protocol.StorageRequest{RequestType: protocol.StorageSetMuteGroup, Cluster: "testcluster", Group: "testgroup", Timestamp: expires}, module.Log)
+	module.muteGroup(&protocol.StorageRequest{RequestType: protocol.StorageSetMuteGroup, Cluster: "testcluster", Group: "othergroup"}, module.Log)
+	module.unmuteGroup(&protocol.StorageRequest{RequestType: protocol.StorageSetUnmuteGroup, Cluster: "testcluster", Group: "othergroup"}, module.Log)
+	module.Stop()
+
+	// A new module reads the mutes back from the file
+	module = fixtureModule("", "")
+	viper.Set("storage.test.mute-file", muteFile)
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	module.Configure("test", "storage.test")
+	module.Start()
+	defer module.Stop()
+
+	response := fetchMutedGroupsSync(module, "testcluster")
+	assert.Equalf(t, map[string]int64{"testgroup": expires}, response, "Expected mutes to be read from the file, got %v", response)
+}
+
+func TestInMemoryStorage_Configure_BadMuteFile(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.mute-file", writeImportFile(t, "muted.json", "[1, 2, 3]"))
+
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// The lag for a commit is calculated when it is stored, against the end offset for the partition at that time. A commit
+// for a partition that has no end offset yet (such as a new topic, or new partitions, before the next offset refresh)
+// is normally dropped. With a pending-commit-limit, up to that many of these commits are held for each partition, and
+// are stored with their lag once the first end offset for the partition is stored.
+
+// holdPendingCommit keeps a commit for a partition with no end offset, dropping the oldest held commit for the
+// partition if it already has pending-commit-limit of them. It returns false, without holding the commit, if the end
+// offset has been stored since the caller looked for it, in which case the commit can be stored now.
+func (module *InMemoryStorage) holdPendingCommit(clusterMap *clusterOffsets, request *protocol.StorageRequest, requestLogger *zap.Logger) bool {
+	// The broker lock is held so that the first end offset for the partition can't be stored between checking for it
+	// and holding the commit, which would leave the commit held until the topic is deleted
+	clusterMap.brokerLock.RLock()
+	defer clusterMap.brokerLock.RUnlock()
+	topicPartitionList := clusterMap.broker[request.Topic]
+	if (request.Partition < int32(len(topicPartitionList))) && (topicPartitionList[request.Partition].Value != nil) {
+		return false
+	}
+
+	clusterMap.pendingLock.Lock()
+	defer clusterMap.pendingLock.Unlock()
+
+	topicMap, ok := clusterMap.pending[request.Topic]
+	if !ok {
+		topicMap = make(map[int32][]*protocol.StorageRequest)
+		clusterMap.pending[request.Topic] = topicMap
+	}
+	commits := append(topicMap[request.Partition], request)
+	if len(commits) > module.pendingLimit {
+		commits = commits[len(commits)-module.pendingLimit:]
+	}
+	topicMap[request.Partition] = commits
+	requestLogger.Debug("held", zap.String("reason", "no end offset"), zap.Int("pending", len(commits)))
+	return true
+}
+
+// takePendingCommits removes and returns the commits held for a partition
+func takePendingCommits(clusterMap *clusterOffsets, topic string, partition int32) []*protocol.StorageRequest {
+	clusterMap.pendingLock.Lock()
+	defer clusterMap.pendingLock.Unlock()
+
+	topicMap, ok := clusterMap.pending[topic]
+	if !ok {
+		return nil
+	}
+	commits := topicMap[partition]
+	delete(topicMap, partition)
+	if len(topicMap) == 0 {
+		delete(clusterMap.pending, topic)
+	}
+	return commits
+}
+
+// storePendingCommits stores the commits held for a partition, now that it has an end offset. It must be called
+// without the broker lock held, as storing a commit reads the end offset.
+func (module *InMemoryStorage) storePendingCommits(clusterMap *clusterOffsets, topic string, partition int32, requestLogger *zap.Logger) {
+	commits := takePendingCommits(clusterMap, topic, partition)
+	for _, commit := range commits {
+		module.addConsumerOffset(commit, requestLogger.With(zap.String("consumer", commit.Group)))
+	}
+	if len(commits) > 0 {
+		requestLogger.Debug("stored pending commits", zap.Int("count", len(commits)))
+	}
+}
+
+// dropPendingCommits removes all the commits held for a topic
+func dropPendingCommits(clusterMap *clusterOffsets, topic string) {
+	clusterMap.pendingLock.Lock()
+	delete(clusterMap.pending, topic)
+	clusterMap.pendingLock.Unlock()
+}
@@ -0,0 +1,105 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func startWithPendingCommitLimit(limit int) *InMemoryStorage {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.pending-commit-limit", limit)
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	module.Configure("test", "storage.test")
+	module.Start()
+	return module
+}
+
+func addNewTopicCommits(module *InMemoryStorage, count int) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	for i := 0; i < count; i++ {
+		module.addConsumerOffset(&protocol.StorageRequest{
+			RequestType: protocol.StorageSetConsumerOffset,
+			Cluster:     "testcluster",
+			Topic:       "newtopic",
+			Group:       "testgroup",
+			Partition:   0,
+			Offset:      int64(1000 + (i * 100)),
+			Order:       int64(i + 1),
+			Timestamp:   startTime + int64(i*10000),
+		}, module.Log)
+	}
+}
+
+func TestInMemoryStorage_PendingCommits(t *testing.T) {
+	module := startWithPendingCommitLimit(2)
+	defer module.Stop()
+
+	// The topic has no end offsets yet, so the commits are held, and only the newest 2 are kept
+	addNewTopicCommits(module, 3)
+	_, ok := module.offsets["testcluster"].consumer["testgroup"]
+	assert.False(t, ok, "Expected group to not be created for held commits")
+	assert.Lenf(t, module.offsets["testcluster"].pending["newtopic"][0], 2, "Expected 2 held commits, not %v", len(module.offsets["testcluster"].pending["newtopic"][0]))
+
+	// The first end offset stores the held commits, with their lag
+	module.addBrokerOffset(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOffset,
+		Cluster:             "testcluster",
+		Topic:               "newtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              2000,
+		Timestamp:           time.Now().Unix() * 1000,
+	}, module.Log)
+	_, ok = module.offsets["testcluster"].pending["newtopic"]
+	assert.False(t, ok, "Expected held commits to be removed")
+
+	consumer, ok := module.offsets["testcluster"].consumer["testgroup"]
+	assert.True(t, ok, "Expected group to be created for held commits")
+	var offsets []*protocol.ConsumerOffset
+	consumer.topics["newtopic"][0].offsets.Do(func(value interface{}) {
+		if value != nil {
+			offsets = append(offsets, value.(*protocol.ConsumerOffset))
+		}
+	})
+	assert.Lenf(t, offsets, 2, "Expected 2 offsets to be stored, not %v", len(offsets))
+	for _, offset := range offsets {
+		assert.Equalf(t, uint64(2000-offset.Offset), offset.Lag.Value, "Expected lag for offset %v to be against the end offset", offset.Offset)
+	}
+}
+
+func TestInMemoryStorage_PendingCommits_Disabled(t *testing.T) {
+	module := startWithPendingCommitLimit(0)
+	defer module.Stop()
+
+	addNewTopicCommits(module, 3)
+	assert.Empty(t, module.offsets["testcluster"].pending, "Expected no commits to be held")
+}
+
+func TestInMemoryStorage_PendingCommits_DeleteTopic(t *testing.T) {
+	module := startWithPendingCommitLimit(2)
+	defer module.Stop()
+
+	addNewTopicCommits(module, 1)
+	module.deleteTopic(&protocol.StorageRequest{RequestType: protocol.StorageSetDeleteTopic, Cluster: "testcluster", Topic: "newtopic"}, module.Log)
+	assert.Empty(t, module.offsets["testcluster"].pending, "Expected held commits to be dropped with the topic")
+}
+
+func TestInMemoryStorage_Configure_BadPendingCommitLimit(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.pending-commit-limit", -1)
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
@@ -0,0 +1,409 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"compress/gzip"
+	"container/ring"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// If a snapshot-file is set, the broker end offsets and the consumer group offsets are saved to it when the module is
+// stopped (and every snapshot-interval seconds, if set), and read back from it when the module is configured, so that
+// the offset history is not lost on a restart. The other state is not saved: the oldest and lookback offsets and the
+// compacted topics are fetched again by the cluster modules, and the lag baselines are rebuilt by the evaluator.
+//
+// The snapshot-format selects how the file is written:
+//   - "json" (the default) is readable, and the largest
+//   - "gob" is Go's binary encoding, which is smaller and faster to read and write
+//   - "json-gzip" and "gob-gzip" are the same, compressed with gzip
+//
+// Every format holds the same storageSnapshot. Its Version is the schema version of the snapshot, which is
+// snapshotVersion when it is written. A snapshot that cannot be read with the configured format, or that has a schema
+// version that this Burrow does not know how to migrate, is logged and discarded, and storage starts empty.
+
+// snapshotVersion is the schema version of the snapshots that are written. It must be increased whenever the snapshot
+// structs change in a way that older snapshots cannot be read into, and migrateSnapshot must be given a way to upgrade
+// the older version, or to discard it.
+const snapshotVersion = 1
+
+// storageSnapshot is the on-disk schema of a snapshot. Timestamps are in milliseconds.
+type storageSnapshot struct {
+	Version   int                         `json:"version"`
+	Timestamp int64                       `json:"timestamp"`
+	Clusters  map[string]*snapshotCluster `json:"clusters"`
+}
+
+type snapshotCluster struct {
+	// The end offsets for each partition of each topic, oldest first
+	Topics map[string][][]snapshotBrokerOffset `json:"topics"`
+	Groups map[string]*snapshotGroup           `json:"groups"`
+}
+
+type snapshotBrokerOffset struct {
+	Offset    int64 `json:"offset"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+type snapshotGroup struct {
+	LastCommit int64                           `json:"last-commit"`
+	Topics     map[string][]*snapshotPartition `json:"topics"`
+}
+
+type snapshotPartition struct {
+	Owner        string `json:"owner"`
+	ClientID     string `json:"client-id"`
+	InstanceID   string `json:"instance-id"`
+	BrokerOffset int64  `json:"broker-offset"`
+
+	// The committed offsets, oldest first
+	Offsets []snapshotConsumerOffset `json:"offsets"`
+}
+
+type snapshotConsumerOffset struct {
+	Offset            int64   `json:"offset"`
+	Order             int64   `json:"order"`
+	Timestamp         int64   `json:"timestamp"`
+	ObservedTimestamp int64   `json:"observed-timestamp"`
+	Lag               *uint64 `json:"lag"`
+}
+
+// snapshotCodec writes and reads a snapshot in one of the snapshot-formats
+type snapshotCodec interface {
+	encode(writer io.Writer, snapshot *storageSnapshot) error
+	decode(reader io.Reader) (*storageSnapshot, error)
+}
+
+type jsonSnapshotCodec struct{}
+
+func (jsonSnapshotCodec) encode(writer io.Writer, snapshot *storageSnapshot) error {
+	return json.NewEncoder(writer).Encode(snapshot)
+}
+
+func (jsonSnapshotCodec) decode(reader io.Reader) (*storageSnapshot, error) {
+	snapshot := &storageSnapshot{}
+	err := json.NewDecoder(reader).Decode(snapshot)
+	return snapshot, err
+}
+
+type gobSnapshotCodec struct{}
+
+func (gobSnapshotCodec) encode(writer io.Writer, snapshot *storageSnapshot) error {
+	return gob.NewEncoder(writer).Encode(snapshot)
+}
+
+func (gobSnapshotCodec) decode(reader io.Reader) (*storageSnapshot, error) {
+	snapshot := &storageSnapshot{}
+	err := gob.NewDecoder(reader).Decode(snapshot)
+	return snapshot, err
+}
+
+// gzipSnapshotCodec compresses the output of another codec
+type gzipSnapshotCodec struct {
+	codec snapshotCodec
+}
+
+func (codec gzipSnapshotCodec) encode(writer io.Writer, snapshot *storageSnapshot) error {
+	gzipWriter := gzip.NewWriter(writer)
+	if err := codec.codec.encode(gzipWriter, snapshot); err != nil {
+		gzipWriter.Close()
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+func (codec gzipSnapshotCodec) decode(reader io.Reader) (*storageSnapshot, error) {
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+	return codec.codec.decode(gzipReader)
+}
+
+var snapshotCodecs = map[string]snapshotCodec{
+	"json":      jsonSnapshotCodec{},
+	"gob":       gobSnapshotCodec{},
+	"json-gzip": gzipSnapshotCodec{codec: jsonSnapshotCodec{}},
+	"gob-gzip":  gzipSnapshotCodec{codec: gobSnapshotCodec{}},
+}
+
+// readSnapshot reads the snapshot in the file with the codec. A file that does not exist is not an error, and returns
+// a nil snapshot, as there is nothing to restore on the first start.
+func readSnapshot(filename string, codec snapshotCodec) (*storageSnapshot, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	snapshot, err := codec.decode(file)
+	if err != nil {
+		return nil, err
+	}
+	return migrateSnapshot(snapshot)
+}
+
+// migrateSnapshot upgrades a snapshot with an older schema version to the current one, or returns an error if it has
+// a version that cannot be upgraded. There is only the one version so far.
+func migrateSnapshot(snapshot *storageSnapshot) (*storageSnapshot, error) {
+	if snapshot.Version != snapshotVersion {
+		return nil, fmt.Errorf("snapshot version %v is not supported (expected %v)", snapshot.Version, snapshotVersion)
+	}
+	return snapshot, nil
+}
+
+// writeSnapshot writes the snapshot to a temporary file with the codec, and then renames it to the filename, so that
+// the previous snapshot is kept if the write fails part way
+func writeSnapshot(filename string, codec snapshotCodec, snapshot *storageSnapshot) error {
+	file, err := os.Create(filename + ".tmp")
+	if err != nil {
+		return err
+	}
+	err = codec.encode(file, snapshot)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(filename+".tmp", filename)
+	}
+	return err
+}
+
+// takeSnapshot copies the broker and consumer offsets for every cluster into a snapshot. Each cluster and group is
+// locked while it is copied, so the snapshot can be taken while the workers are running.
+func (module *InMemoryStorage) takeSnapshot() *storageSnapshot {
+	snapshot := &storageSnapshot{
+		Version:   snapshotVersion,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Clusters:  make(map[string]*snapshotCluster, len(module.offsets)),
+	}
+	for cluster, clusterMap := range module.offsets {
+		snapshotMap := &snapshotCluster{
+			Topics: make(map[string][][]snapshotBrokerOffset),
+			Groups: make(map[string]*snapshotGroup),
+		}
+
+		clusterMap.brokerLock.RLock()
+		for topic, partitions := range clusterMap.broker {
+			snapshotMap.Topics[topic] = make([][]snapshotBrokerOffset, len(partitions))
+			for partition, partitionRing := range partitions {
+				// The ring points to the most recent entry, so the oldest is the one after it
+				offsets := make([]snapshotBrokerOffset, 0, partitionRing.Len())
+				entry := partitionRing.Next()
+				for i := 0; i < partitionRing.Len(); i++ {
+					if value, ok := entry.Value.(*brokerOffset); ok {
+						offsets = append(offsets, snapshotBrokerOffset{Offset: value.Offset, Timestamp: value.Timestamp})
+					}
+					entry = entry.Next()
+				}
+				snapshotMap.Topics[topic][partition] = offsets
+			}
+		}
+		clusterMap.brokerLock.RUnlock()
+
+		clusterMap.consumerLock.RLock()
+		for group, consumerMap := range clusterMap.consumer {
+			snapshotMap.Groups[group] = snapshotConsumerGroup(consumerMap)
+		}
+		clusterMap.consumerLock.RUnlock()
+
+		snapshot.Clusters[cluster] = snapshotMap
+	}
+	return snapshot
+}
+
+func snapshotConsumerGroup(consumerMap *consumerGroup) *snapshotGroup {
+	consumerMap.lock.RLock()
+	defer consumerMap.lock.RUnlock()
+
+	group := &snapshotGroup{
+		LastCommit: consumerMap.lastCommit,
+		Topics:     make(map[string][]*snapshotPartition, len(consumerMap.topics)),
+	}
+	for topic, partitions := range consumerMap.topics {
+		group.Topics[topic] = make([]*snapshotPartition, len(partitions))
+		for i, partition := range partitions {
+			snapshotPartition := &snapshotPartition{
+				Owner:        partition.owner,
+				ClientID:     partition.clientID,
+				InstanceID:   partition.instanceID,
+				BrokerOffset: partition.brokerOffset,
+				Offsets:      make([]snapshotConsumerOffset, 0),
+			}
+			if partition.offsets != nil {
+				// The ring points to the oldest entry (or the next empty slot)
+				entry := partition.offsets
+				for j := 0; j < partition.offsets.Len(); j++ {
+					if value, ok := entry.Value.(*protocol.ConsumerOffset); ok {
+						offset := snapshotConsumerOffset{
+							Offset:            value.Offset,
+							Order:             value.Order,
+							Timestamp:         value.Timestamp,
+							ObservedTimestamp: value.ObservedTimestamp,
+						}
+						if value.Lag != nil {
+							lag := value.Lag.Value
+							offset.Lag = &lag
+						}
+						snapshotPartition.Offsets = append(snapshotPartition.Offsets, offset)
+					}
+					entry = entry.Next()
+				}
+			}
+			group.Topics[topic][i] = snapshotPartition
+		}
+	}
+	return group
+}
+
+// restoreSnapshot loads the offsets in the snapshot into storage. It is called from Start, before the workers are
+// started, so it does not take the locks. Clusters that are no longer configured, groups that do not pass the group
+// filters, and groups that would have expired are skipped. If the intervals config is smaller than when the snapshot
+// was taken, only the most recent offsets are kept.
+func (module *InMemoryStorage) restoreSnapshot(snapshot *storageSnapshot) {
+	expired := (time.Now().Unix() - module.expireGroup) * 1000
+	groupCount := 0
+	for cluster, snapshotMap := range snapshot.Clusters {
+		clusterMap, ok := module.offsets[cluster]
+		if !ok {
+			module.Log.Info("skipping snapshot for unknown cluster", zap.String("cluster", cluster))
+			continue
+		}
+
+		for topic, partitions := range snapshotMap.Topics {
+			topicList := make([]*ring.Ring, len(partitions))
+			for partition, offsets := range partitions {
+				// The ring is left pointing to the most recent entry, as addBrokerOffset does
+				topicList[partition] = ring.New(module.intervals)
+				for _, offset := range lastOffsets(len(offsets), module.intervals) {
+					topicList[partition] = topicList[partition].Next()
+					topicList[partition].Value = &brokerOffset{Offset: offsets[offset].Offset, Timestamp: offsets[offset].Timestamp}
+				}
+			}
+			clusterMap.broker[topic] = topicList
+		}
+
+		for group, snapshotGroup := range snapshotMap.Groups {
+			if (snapshotGroup.LastCommit < expired) || !module.acceptConsumerGroup(group) {
+				continue
+			}
+			consumerMap := &consumerGroup{
+				lock:       &sync.RWMutex{},
+				topics:     make(map[string][]*consumerPartition, len(snapshotGroup.Topics)),
+				lastCommit: snapshotGroup.LastCommit,
+			}
+			for topic, partitions := range snapshotGroup.Topics {
+				consumerMap.topics[topic] = make([]*consumerPartition, len(partitions))
+				for i, partition := range partitions {
+					consumerMap.topics[topic][i] = module.restorePartition(partition)
+				}
+			}
+			clusterMap.consumer[group] = consumerMap
+			groupCount++
+		}
+	}
+	module.Log.Info("restored snapshot",
+		zap.Int64("snapshot_timestamp", snapshot.Timestamp),
+		zap.Int("groups", groupCount),
+	)
+}
+
+func (module *InMemoryStorage) restorePartition(partition *snapshotPartition) *consumerPartition {
+	restored := &consumerPartition{
+		owner:        partition.Owner,
+		clientID:     partition.ClientID,
+		instanceID:   partition.InstanceID,
+		brokerOffset: partition.BrokerOffset,
+		offsets:      ring.New(module.intervals),
+	}
+
+	// The ring is left pointing to the slot after the most recent entry, which is the oldest entry once it is full, as
+	// storeConsumerOffset does
+	for _, i := range lastOffsets(len(partition.Offsets), module.intervals) {
+		offset := partition.Offsets[i]
+		value := &protocol.ConsumerOffset{
+			Offset:            offset.Offset,
+			Order:             offset.Order,
+			Timestamp:         offset.Timestamp,
+			ObservedTimestamp: offset.ObservedTimestamp,
+		}
+		if offset.Lag != nil {
+			value.Lag = &protocol.Lag{Value: *offset.Lag}
+		}
+		restored.offsets.Value = value
+		restored.offsets = restored.offsets.Next()
+	}
+	return restored
+}
+
+// lastOffsets returns the indexes of the last (up to) intervals of count offsets, in order
+func lastOffsets(count, intervals int) []int {
+	start := 0
+	if count > intervals {
+		start = count - intervals
+	}
+	indexes := make([]int, 0, count-start)
+	for i := start; i < count; i++ {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// saveSnapshot takes a snapshot and writes it to the snapshot-file. Errors are logged, and the last snapshot written
+// is left in place.
+func (module *InMemoryStorage) saveSnapshot() {
+	snapshot := module.takeSnapshot()
+	if err := writeSnapshot(module.snapshotFile, module.snapshotCodec, snapshot); err != nil {
+		module.Log.Error("failed to write snapshot", zap.String("filename", module.snapshotFile), zap.Error(err))
+		return
+	}
+	module.Log.Debug("wrote snapshot", zap.String("filename", module.snapshotFile), zap.Int("clusters", len(snapshot.Clusters)))
+}
+
+// snapshotWriter saves a snapshot every snapshot-interval seconds, until the module is stopped
+func (module *InMemoryStorage) snapshotWriter() {
+	defer module.sweepRunning.Done()
+
+	ticker := time.NewTicker(time.Duration(module.snapshotInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			module.saveSnapshot()
+		case <-module.sweepQuit:
+			return
+		}
+	}
+}
+
+// sortedSnapshotFormats returns the names of the snapshot-formats, for the configuration error
+func sortedSnapshotFormats() []string {
+	formats := make([]string, 0, len(snapshotCodecs))
+	for format := range snapshotCodecs {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
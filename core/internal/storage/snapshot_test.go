@@ -0,0 +1,199 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// startWithSnapshotFile starts a module with the test cluster, reading and writing the snapshot-file in the format
+func startWithSnapshotFile(snapshotFile, format string) *InMemoryStorage {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.snapshot-file", snapshotFile)
+	viper.Set("storage.test.snapshot-format", format)
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	module.Configure("test", "storage.test")
+	module.Start()
+	return module
+}
+
+func fetchConsumerSync(module *InMemoryStorage, group string) protocol.ConsumerTopics {
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       group,
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchConsumer(&request, module.Log)
+	response, _ := (<-request.Reply).(protocol.ConsumerTopics)
+	return response
+}
+
+func TestInMemoryStorage_Snapshot_RoundTrip(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 200000
+
+	for _, format := range sortedSnapshotFormats() {
+		snapshotFile := filepath.Join(t.TempDir(), "snapshot")
+
+		// testgroup has a full ring of offsets, and partialgroup has only a few
+		module := startWithSnapshotFile(snapshotFile, format)
+		for i := 0; i < 10; i++ {
+			module.addBrokerOffset(&protocol.StorageRequest{
+				RequestType:         protocol.StorageSetBrokerOffset,
+				Cluster:             "testcluster",
+				Topic:               "testtopic",
+				Partition:           0,
+				TopicPartitionCount: 1,
+				Offset:              int64(2000 + (i * 100)),
+				Timestamp:           startTime + int64(i*10000),
+			}, module.Log)
+		}
+		for i := 0; i < 12; i++ {
+			module.addConsumerOffset(&protocol.StorageRequest{
+				RequestType: protocol.StorageSetConsumerOffset,
+				Cluster:     "testcluster",
+				Topic:       "testtopic",
+				Group:       "testgroup",
+				Partition:   0,
+				Offset:      int64(1000 + (i * 100)),
+				Order:       int64(500 + i),
+				Timestamp:   startTime + int64(i*10000),
+			}, module.Log)
+		}
+		for i := 0; i < 3; i++ {
+			module.addConsumerOffset(&protocol.StorageRequest{
+				RequestType: protocol.StorageSetConsumerOffset,
+				Cluster:     "testcluster",
+				Topic:       "testtopic",
+				Group:       "partialgroup",
+				Partition:   0,
+				Offset:      int64(2500 + (i * 100)),
+				Order:       int64(600 + i),
+				Timestamp:   startTime + int64(i*10000),
+			}, module.Log)
+		}
+		expected := module.takeSnapshot()
+		expectedTestGroup := fetchConsumerSync(module, "testgroup")
+		expectedPartialGroup := fetchConsumerSync(module, "partialgroup")
+		module.Stop()
+
+		// A new module restores the offsets from the snapshot written when the first stopped
+		module = startWithSnapshotFile(snapshotFile, format)
+		restored := module.takeSnapshot()
+		restored.Timestamp = expected.Timestamp
+		assert.Equalf(t, expected, restored, "Expected the snapshot to round-trip with format %v", format)
+		assert.Equalf(t, expectedTestGroup, fetchConsumerSync(module, "testgroup"), "Expected testgroup to be restored with format %v", format)
+		assert.Equalf(t, expectedPartialGroup, fetchConsumerSync(module, "partialgroup"), "Expected partialgroup to be restored with format %v", format)
+
+		// The restored rings take new offsets in order
+		module.addConsumerOffset(&protocol.StorageRequest{
+			RequestType: protocol.StorageSetConsumerOffset,
+			Cluster:     "testcluster",
+			Topic:       "testtopic",
+			Group:       "partialgroup",
+			Partition:   0,
+			Offset:      2800,
+			Order:       603,
+			Timestamp:   startTime + 30000,
+		}, module.Log)
+		offsets := fetchConsumerSync(module, "partialgroup")["testtopic"][0].Offsets
+		assert.Lenf(t, offsets, 10, "Expected a ring of 10 offsets with format %v", format)
+		assert.Nil(t, offsets[5], "Expected the unused slots first")
+		assert.Equalf(t, int64(2800), offsets[9].Offset, "Expected the new offset last with format %v", format)
+		module.Stop()
+	}
+}
+
+func TestInMemoryStorage_Snapshot_FewerIntervals(t *testing.T) {
+	snapshotFile := filepath.Join(t.TempDir(), "snapshot")
+	module := startWithSnapshotFile(snapshotFile, "gob")
+	for i := 0; i < 10; i++ {
+		module.addBrokerOffset(&protocol.StorageRequest{
+			RequestType:         protocol.StorageSetBrokerOffset,
+			Cluster:             "testcluster",
+			Topic:               "testtopic",
+			Partition:           0,
+			TopicPartitionCount: 1,
+			Offset:              int64(2000 + (i * 100)),
+			Timestamp:           int64(i),
+		}, module.Log)
+	}
+	module.Stop()
+
+	module = fixtureModule("", "")
+	viper.Set("storage.test.snapshot-file", snapshotFile)
+	viper.Set("storage.test.snapshot-format", "gob")
+	viper.Set("storage.test.intervals", 3)
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	module.Configure("test", "storage.test")
+	module.Start()
+	defer module.Stop()
+
+	offsets := module.takeSnapshot().Clusters["testcluster"].Topics["testtopic"][0]
+	assert.Equal(t, []snapshotBrokerOffset{{2700, 7}, {2800, 8}, {2900, 9}}, offsets, "Expected only the most recent offsets to be kept")
+}
+
+func TestInMemoryStorage_Snapshot_DiscardUnsupportedVersion(t *testing.T) {
+	snapshotFile := filepath.Join(t.TempDir(), "snapshot")
+	snapshot := &storageSnapshot{
+		Version: snapshotVersion + 1,
+		Clusters: map[string]*snapshotCluster{
+			"testcluster": {Topics: map[string][][]snapshotBrokerOffset{"testtopic": {{{Offset: 100, Timestamp: 1}}}}},
+		},
+	}
+	assert.Nil(t, writeSnapshot(snapshotFile, snapshotCodecs["json"], snapshot))
+
+	module := startWithSnapshotFile(snapshotFile, "json")
+	defer module.Stop()
+	assert.Empty(t, module.offsets["testcluster"].broker, "Expected the snapshot to be discarded")
+}
+
+func TestInMemoryStorage_Snapshot_DiscardWrongFormat(t *testing.T) {
+	snapshotFile := filepath.Join(t.TempDir(), "snapshot")
+	assert.Nil(t, writeSnapshot(snapshotFile, snapshotCodecs["json"], &storageSnapshot{Version: snapshotVersion}))
+
+	module := startWithSnapshotFile(snapshotFile, "gob-gzip")
+	module.Stop()
+
+	// The discarded snapshot is replaced when the module stops
+	_, err := readSnapshot(snapshotFile, snapshotCodecs["gob-gzip"])
+	assert.Nil(t, err, "Expected the snapshot to be rewritten in the configured format")
+}
+
+func TestInMemoryStorage_Snapshot_MissingFile(t *testing.T) {
+	snapshotFile := filepath.Join(t.TempDir(), "snapshot")
+	module := startWithSnapshotFile(snapshotFile, "json")
+	module.Stop()
+
+	_, err := os.Stat(snapshotFile)
+	assert.Nil(t, err, "Expected the snapshot to be written on stop")
+}
+
+func TestInMemoryStorage_Configure_BadSnapshotFormat(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.snapshot-format", "xml")
+
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
+
+func TestInMemoryStorage_Configure_BadSnapshotInterval(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.snapshot-interval", -1)
+
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
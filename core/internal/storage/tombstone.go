@@ -0,0 +1,81 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// groupTombstone is a deleted group that is kept, hidden from the consumer list, until deleted-group-retention has
+// passed, so that the group gets its history back if it reappears
+type groupTombstone struct {
+	group   *consumerGroup
+	deleted int64
+}
+
+// tombstoneGroup keeps a group that is being deleted, if deleted-group-retention is set. The consumerLock for the
+// cluster must be held for write.
+func (module *InMemoryStorage) tombstoneGroup(clusterMap *clusterOffsets, group string, consumerMap *consumerGroup) {
+	if module.deletedGroupRetention == 0 {
+		return
+	}
+	clusterMap.tombstones[group] = &groupTombstone{
+		group:   consumerMap,
+		deleted: time.Now().Unix(),
+	}
+}
+
+// purgeTombstones removes the tombstones for groups that were deleted before the cutoff (in seconds), in every
+// cluster. It is called from the sweep, so that the deleted groups do not stay in memory until another group is deleted.
+func (module *InMemoryStorage) purgeTombstones(cutoff int64) {
+	purged := 0
+	for _, clusterMap := range module.offsets {
+		clusterMap.consumerLock.Lock()
+		for group, tombstone := range clusterMap.tombstones {
+			if tombstone.deleted < cutoff {
+				delete(clusterMap.tombstones, group)
+				purged++
+			}
+		}
+		clusterMap.consumerLock.Unlock()
+	}
+	if purged > 0 {
+		module.Log.Debug("purged deleted group tombstones", zap.Int("count", purged))
+	}
+}
+
+// getOrCreateConsumerGroup returns the group from the consumer list. If it does not exist, it is restored from its
+// tombstone if it was deleted less than deleted-group-retention ago, and otherwise a new group is created.
+func (module *InMemoryStorage) getOrCreateConsumerGroup(clusterMap *clusterOffsets, group string, requestLogger *zap.Logger) *consumerGroup {
+	clusterMap.consumerLock.Lock()
+	defer clusterMap.consumerLock.Unlock()
+
+	if consumerMap, ok := clusterMap.consumer[group]; ok {
+		return consumerMap
+	}
+
+	if tombstone, ok := clusterMap.tombstones[group]; ok {
+		delete(clusterMap.tombstones, group)
+		if time.Now().Unix()-tombstone.deleted <= module.deletedGroupRetention {
+			requestLogger.Info("restored deleted group", zap.Int64("deleted", tombstone.deleted))
+			clusterMap.consumer[group] = tombstone.group
+			return tombstone.group
+		}
+	}
+
+	clusterMap.consumer[group] = &consumerGroup{
+		lock:   &sync.RWMutex{},
+		topics: make(map[string][]*consumerPartition),
+	}
+	return clusterMap.consumer[group]
+}
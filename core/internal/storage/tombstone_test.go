@@ -0,0 +1,141 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func deleteAndRecommit(module *InMemoryStorage, startTime int64) {
+	module.deleteGroup(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteGroup,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+	}, module.Log)
+
+	module.addConsumerOffset(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      2000,
+		Order:       510,
+		Timestamp:   startTime + 100000,
+	}, module.Log)
+}
+
+func TestInMemoryStorage_deleteGroup_Tombstone(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+	module.deletedGroupRetention = 300
+	group := module.offsets["testcluster"].consumer["testgroup"]
+
+	module.deleteGroup(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteGroup,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+	}, module.Log)
+
+	_, ok := module.offsets["testcluster"].consumer["testgroup"]
+	assert.False(t, ok, "Expected the deleted group to be hidden from the consumer list")
+	tombstone, ok := module.offsets["testcluster"].tombstones["testgroup"]
+	assert.True(t, ok, "Expected a tombstone for the deleted group")
+	assert.Equal(t, group, tombstone.group)
+}
+
+func TestInMemoryStorage_deleteGroup_TombstoneRestored(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+	module.deletedGroupRetention = 300
+	group := module.offsets["testcluster"].consumer["testgroup"]
+
+	deleteAndRecommit(module, startTime)
+
+	assert.Equal(t, group, module.offsets["testcluster"].consumer["testgroup"], "Expected the group to be restored")
+	assert.Empty(t, module.offsets["testcluster"].tombstones, "Expected the tombstone to be removed")
+
+	// The history from before the delete is still there, with the new commit at the end
+	offsets := group.topics["testtopic"][0].offsets
+	assert.Equal(t, int64(2000), offsets.Prev().Value.(*protocol.ConsumerOffset).Offset)
+	assert.Equal(t, int64(1100), offsets.Value.(*protocol.ConsumerOffset).Offset)
+}
+
+func TestInMemoryStorage_deleteGroup_TombstoneExpired(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+	module.deletedGroupRetention = 300
+	group := module.offsets["testcluster"].consumer["testgroup"]
+
+	module.deleteGroup(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteGroup,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+	}, module.Log)
+	module.offsets["testcluster"].tombstones["testgroup"].deleted -= 301
+
+	module.addConsumerOffset(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      2000,
+		Order:       510,
+		Timestamp:   startTime + 100000,
+	}, module.Log)
+
+	newGroup := module.offsets["testcluster"].consumer["testgroup"]
+	assert.NotEqual(t, group, newGroup, "Expected a new group once the tombstone expired")
+	assert.Empty(t, module.offsets["testcluster"].tombstones, "Expected the expired tombstone to be purged")
+	assert.Nil(t, newGroup.topics["testtopic"][0].offsets.Value, "Expected the new group to have no history")
+}
+
+func TestInMemoryStorage_purgeTombstones(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+	module.deletedGroupRetention = 300
+
+	module.deleteGroup(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteGroup,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+	}, module.Log)
+	deleted := module.offsets["testcluster"].tombstones["testgroup"].deleted
+
+	// The tombstone is kept until the retention has passed, without another group being deleted
+	module.purgeTombstones(deleted - 1)
+	assert.Contains(t, module.offsets["testcluster"].tombstones, "testgroup", "Expected the tombstone to be kept")
+	module.purgeTombstones(deleted + 1)
+	assert.Empty(t, module.offsets["testcluster"].tombstones, "Expected the expired tombstone to be purged")
+}
+
+func TestInMemoryStorage_deleteGroup_NoTombstone(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+	group := module.offsets["testcluster"].consumer["testgroup"]
+
+	deleteAndRecommit(module, startTime)
+
+	assert.NotEqual(t, group, module.offsets["testcluster"].consumer["testgroup"], "Expected a new group without retention")
+	assert.Empty(t, module.offsets["testcluster"].tombstones)
+}
+
+func TestInMemoryStorage_Configure_BadDeletedGroupRetention(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.deleted-group-retention", -1)
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
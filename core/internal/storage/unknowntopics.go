@@ -0,0 +1,161 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+// Commits for topics that the cluster has no end offsets for are dropped, so a consumer that commits to the wrong
+// cluster never shows up in the group's offsets. If an evaluator flags possibly misconfigured groups, the topics that
+// each group committed to that were unknown are recorded instead, with the time of the last such commit, so that the
+// evaluator can flag groups that mostly commit to topics that the cluster does not have. Nothing is recorded until the
+// cluster has end offsets for some topic, so that the commits received before the first offset refresh are not
+// counted. Topics that the cluster module's topic filter leaves out have no end offsets either, but they exist, so
+// commits to them are not recorded. A topic that a group has not committed to for expire-group seconds is removed by
+// the storage sweep, as the group may never be fetched or deleted.
+
+// misconfiguredTopicCheckEnabled returns true if any evaluator flags possibly misconfigured groups
+func misconfiguredTopicCheckEnabled() bool {
+	for name := range viper.GetStringMap("evaluator") {
+		if viper.GetFloat64("evaluator."+name+".misconfigured-topic-share") > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// recordUnknownTopicCommit records a commit that was dropped, if it was dropped because the topic is not known
+func (module *InMemoryStorage) recordUnknownTopicCommit(clusterMap *clusterOffsets, request *protocol.StorageRequest) {
+	if !module.recordUnknownTopics {
+		return
+	}
+
+	clusterMap.brokerLock.RLock()
+	_, known := clusterMap.broker[request.Topic]
+	hasTopics := len(clusterMap.broker) > 0
+	clusterMap.brokerLock.RUnlock()
+	if known || !hasTopics {
+		return
+	}
+
+	clusterMap.unknownTopicsLock.Lock()
+	defer clusterMap.unknownTopicsLock.Unlock()
+	if clusterMap.filteredTopics[request.Topic] {
+		return
+	}
+	topics, ok := clusterMap.unknownTopics[request.Group]
+	if !ok {
+		topics = make(map[string]int64)
+		clusterMap.unknownTopics[request.Group] = topics
+	}
+	if request.Timestamp > topics[request.Topic] {
+		topics[request.Topic] = request.Timestamp
+	}
+}
+
+// setTopicFiltered records whether a topic is left out by the cluster module's topic filter. A filtered topic that a
+// group has already been recorded as committing to is removed from the group.
+func (module *InMemoryStorage) setTopicFiltered(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.unknownTopicsLock.Lock()
+	if request.Filtered {
+		clusterMap.filteredTopics[request.Topic] = true
+		for group, topics := range clusterMap.unknownTopics {
+			delete(topics, request.Topic)
+			if len(topics) == 0 {
+				delete(clusterMap.unknownTopics, group)
+			}
+		}
+	} else {
+		delete(clusterMap.filteredTopics, request.Topic)
+	}
+	clusterMap.unknownTopicsLock.Unlock()
+
+	requestLogger.Debug("ok", zap.Bool("filtered", request.Filtered))
+}
+
+// expireUnknownTopics removes the unknown topics that were last committed to before the cutoff (in seconds), in every
+// cluster, and the groups that have none left
+func (module *InMemoryStorage) expireUnknownTopics(cutoff int64) {
+	for _, clusterMap := range module.offsets {
+		clusterMap.unknownTopicsLock.Lock()
+		for group, topics := range clusterMap.unknownTopics {
+			for topic, lastCommit := range topics {
+				if lastCommit < cutoff*1000 {
+					delete(topics, topic)
+				}
+			}
+			if len(topics) == 0 {
+				delete(clusterMap.unknownTopics, group)
+			}
+		}
+		clusterMap.unknownTopicsLock.Unlock()
+	}
+}
+
+// clearUnknownTopics removes the unknown topics recorded for a group, such as when the group is deleted
+func clearUnknownTopics(clusterMap *clusterOffsets, group string) {
+	clusterMap.unknownTopicsLock.Lock()
+	delete(clusterMap.unknownTopics, group)
+	clusterMap.unknownTopicsLock.Unlock()
+}
+
+func (module *InMemoryStorage) fetchUnknownTopics(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	// Copy the topics for the group, dropping the ones that have not been committed to since the group would expire
+	expired := (time.Now().Unix() - module.expireGroup) * 1000
+	candidates := make([]string, 0)
+	clusterMap.unknownTopicsLock.Lock()
+	for topic, lastCommit := range clusterMap.unknownTopics[request.Group] {
+		if lastCommit < expired {
+			delete(clusterMap.unknownTopics[request.Group], topic)
+			continue
+		}
+		candidates = append(candidates, topic)
+	}
+	if topics, ok := clusterMap.unknownTopics[request.Group]; ok && (len(topics) == 0) {
+		delete(clusterMap.unknownTopics, request.Group)
+	}
+	clusterMap.unknownTopicsLock.Unlock()
+
+	// Leave out the topics that the cluster has end offsets for now, such as a new topic that was committed to before
+	// the first offset refresh that found it
+	topics := make([]string, 0, len(candidates))
+	clusterMap.brokerLock.RLock()
+	for _, topic := range candidates {
+		if _, known := clusterMap.broker[topic]; !known {
+			topics = append(topics, topic)
+		}
+	}
+	clusterMap.brokerLock.RUnlock()
+	sort.Strings(topics)
+
+	requestLogger.Debug("ok")
+	request.Reply <- topics
+}
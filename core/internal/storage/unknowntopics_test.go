@@ -0,0 +1,196 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/core/protocol"
+)
+
+func fetchUnknownTopicsSync(module *InMemoryStorage, cluster, group string) interface{} {
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchGroupUnknownTopics,
+		Cluster:     cluster,
+		Group:       group,
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchUnknownTopics(&request, module.Log)
+	return <-request.Reply
+}
+
+func commitToTopic(module *InMemoryStorage, group, topic string, timestamp int64) {
+	module.addConsumerOffset(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       topic,
+		Group:       group,
+		Partition:   0,
+		Offset:      100,
+		Timestamp:   timestamp,
+	}, module.Log)
+}
+
+// startWithUnknownTopics starts the module with the end offsets for testtopic, recording the unknown topics as it would
+// if an evaluator flagged possibly misconfigured groups
+func startWithUnknownTopics() *InMemoryStorage {
+	module := startWithTestBrokerOffsets("")
+	module.recordUnknownTopics = true
+	return module
+}
+
+func TestInMemoryStorage_Configure_UnknownTopics(t *testing.T) {
+	module := fixtureModule("", "")
+	module.Configure("test", "storage.test")
+	assert.False(t, module.recordUnknownTopics, "Expected unknown topics to not be recorded by default")
+
+	module = fixtureModule("", "")
+	viper.Set("evaluator.default.misconfigured-topic-share", 0)
+	viper.Set("evaluator.other.misconfigured-topic-share", 0.5)
+	module.Configure("test", "storage.test")
+	assert.True(t, module.recordUnknownTopics, "Expected unknown topics to be recorded if an evaluator uses them")
+}
+
+func TestInMemoryStorage_fetchUnknownTopics(t *testing.T) {
+	module := startWithUnknownTopics()
+	now := time.Now().Unix() * 1000
+
+	commitToTopic(module, "testgroup", "testtopic", now)
+	commitToTopic(module, "testgroup", "missingtopic2", now)
+	commitToTopic(module, "testgroup", "missingtopic1", now)
+
+	response := fetchUnknownTopicsSync(module, "testcluster", "testgroup")
+	assert.Equalf(t, []string{"missingtopic1", "missingtopic2"}, response, "Expected the unknown topics, sorted, not %v", response)
+
+	response = fetchUnknownTopicsSync(module, "testcluster", "othergroup")
+	assert.Equalf(t, []string{}, response, "Expected no unknown topics for another group, not %v", response)
+}
+
+func TestInMemoryStorage_fetchUnknownTopics_BadCluster(t *testing.T) {
+	module := startWithUnknownTopics()
+
+	response := fetchUnknownTopicsSync(module, "nocluster", "testgroup")
+	assert.Nil(t, response, "Expected response to be nil")
+}
+
+func TestInMemoryStorage_fetchUnknownTopics_NoBrokerOffsets(t *testing.T) {
+	module := startWithTestCluster("")
+	module.recordUnknownTopics = true
+	commitToTopic(module, "testgroup", "missingtopic", time.Now().Unix()*1000)
+
+	response := fetchUnknownTopicsSync(module, "testcluster", "testgroup")
+	assert.Equalf(t, []string{}, response, "Expected nothing to be recorded before the first end offsets, not %v", response)
+}
+
+func TestInMemoryStorage_fetchUnknownTopics_TopicCreated(t *testing.T) {
+	module := startWithUnknownTopics()
+	commitToTopic(module, "testgroup", "newtopic", time.Now().Unix()*1000)
+
+	module.addBrokerOffset(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOffset,
+		Cluster:             "testcluster",
+		Topic:               "newtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              200,
+		Timestamp:           time.Now().Unix() * 1000,
+	}, module.Log)
+
+	response := fetchUnknownTopicsSync(module, "testcluster", "testgroup")
+	assert.Equalf(t, []string{}, response, "Expected a topic with end offsets to be left out, not %v", response)
+}
+
+func TestInMemoryStorage_fetchUnknownTopics_Expired(t *testing.T) {
+	module := startWithUnknownTopics()
+	clusterMap := module.offsets["testcluster"]
+	module.recordUnknownTopicCommit(&clusterMap, &protocol.StorageRequest{
+		Cluster:   "testcluster",
+		Topic:     "missingtopic",
+		Group:     "testgroup",
+		Timestamp: (time.Now().Unix() - module.expireGroup - 60) * 1000,
+	})
+
+	response := fetchUnknownTopicsSync(module, "testcluster", "testgroup")
+	assert.Equalf(t, []string{}, response, "Expected an old commit to be dropped, not %v", response)
+	assert.NotContains(t, module.offsets["testcluster"].unknownTopics, "testgroup", "Expected the group to be removed")
+}
+
+func TestInMemoryStorage_deleteGroup_UnknownTopics(t *testing.T) {
+	module := startWithUnknownTopics()
+	commitToTopic(module, "testgroup", "missingtopic", time.Now().Unix()*1000)
+
+	module.deleteGroup(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteGroup,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+	}, module.Log)
+
+	response := fetchUnknownTopicsSync(module, "testcluster", "testgroup")
+	assert.Equalf(t, []string{}, response, "Expected the unknown topics to be removed with the group, not %v", response)
+}
+
+func TestInMemoryStorage_fetchUnknownTopics_Disabled(t *testing.T) {
+	module := startWithTestBrokerOffsets("")
+	commitToTopic(module, "testgroup", "missingtopic", time.Now().Unix()*1000)
+
+	assert.Empty(t, module.offsets["testcluster"].unknownTopics, "Expected nothing to be recorded if no evaluator uses it")
+}
+
+func TestInMemoryStorage_setTopicFiltered(t *testing.T) {
+	module := startWithUnknownTopics()
+	now := time.Now().Unix() * 1000
+	commitToTopic(module, "testgroup", "filteredtopic", now)
+	commitToTopic(module, "testgroup", "missingtopic", now)
+
+	// A topic that is filtered out is removed from the groups, and commits to it are no longer recorded
+	module.setTopicFiltered(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetTopicFiltered,
+		Cluster:     "testcluster",
+		Topic:       "filteredtopic",
+		Filtered:    true,
+	}, module.Log)
+	commitToTopic(module, "testgroup", "filteredtopic", now)
+	commitToTopic(module, "othergroup", "filteredtopic", now)
+
+	response := fetchUnknownTopicsSync(module, "testcluster", "testgroup")
+	assert.Equalf(t, []string{"missingtopic"}, response, "Expected only the topic that is not filtered, not %v", response)
+	assert.NotContains(t, module.offsets["testcluster"].unknownTopics, "othergroup", "Expected nothing to be recorded for othergroup")
+
+	// Once the filter no longer leaves it out, commits to it are recorded again
+	module.setTopicFiltered(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetTopicFiltered,
+		Cluster:     "testcluster",
+		Topic:       "filteredtopic",
+		Filtered:    false,
+	}, module.Log)
+	commitToTopic(module, "testgroup", "filteredtopic", now)
+
+	response = fetchUnknownTopicsSync(module, "testcluster", "testgroup")
+	assert.Equalf(t, []string{"filteredtopic", "missingtopic"}, response, "Expected both topics, not %v", response)
+}
+
+func TestInMemoryStorage_expireUnknownTopics(t *testing.T) {
+	module := startWithUnknownTopics()
+	now := time.Now().Unix()
+	commitToTopic(module, "testgroup", "missingtopic1", (now-120)*1000)
+	commitToTopic(module, "testgroup", "missingtopic2", now*1000)
+	commitToTopic(module, "othergroup", "missingtopic1", (now-120)*1000)
+
+	module.expireUnknownTopics(now - 60)
+
+	unknownTopics := module.offsets["testcluster"].unknownTopics
+	assert.Equal(t, map[string]map[string]int64{"testgroup": {"missingtopic2": now * 1000}}, unknownTopics, "Expected the old topics and the group with none left to be removed")
+}
@@ -0,0 +1,1192 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+// The gRPC API for Burrow. The messages mirror the JSON responses of the HTTP API, and the Go code is generated with
+// "go generate ./core/protocol/burrowpb" (this needs protoc, protoc-gen-go, and protoc-gen-go-grpc).
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: burrow.proto
+
+package burrowpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Status is the status of a group or partition. The values match protocol.StatusConstant.
+type Status int32
+
+const (
+	Status_NOTFOUND Status = 0
+	Status_OK       Status = 1
+	Status_WARN     Status = 2
+	Status_ERR      Status = 3
+	Status_STOP     Status = 4
+	Status_STALL    Status = 5
+	Status_REWIND   Status = 6
+	Status_MUTED    Status = 7
+)
+
+// Enum value maps for Status.
+var (
+	Status_name = map[int32]string{
+		0: "NOTFOUND",
+		1: "OK",
+		2: "WARN",
+		3: "ERR",
+		4: "STOP",
+		5: "STALL",
+		6: "REWIND",
+		7: "MUTED",
+	}
+	Status_value = map[string]int32{
+		"NOTFOUND": 0,
+		"OK":       1,
+		"WARN":     2,
+		"ERR":      3,
+		"STOP":     4,
+		"STALL":    5,
+		"REWIND":   6,
+		"MUTED":    7,
+	}
+)
+
+func (x Status) Enum() *Status {
+	p := new(Status)
+	*p = x
+	return p
+}
+
+func (x Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_burrow_proto_enumTypes[0].Descriptor()
+}
+
+func (Status) Type() protoreflect.EnumType {
+	return &file_burrow_proto_enumTypes[0]
+}
+
+func (x Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Status.Descriptor instead.
+func (Status) EnumDescriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{0}
+}
+
+type ListClustersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListClustersRequest) Reset() {
+	*x = ListClustersRequest{}
+	mi := &file_burrow_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListClustersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListClustersRequest) ProtoMessage() {}
+
+func (x *ListClustersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_burrow_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListClustersRequest.ProtoReflect.Descriptor instead.
+func (*ListClustersRequest) Descriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{0}
+}
+
+type ListClustersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Clusters      []string               `protobuf:"bytes,1,rep,name=clusters,proto3" json:"clusters,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListClustersResponse) Reset() {
+	*x = ListClustersResponse{}
+	mi := &file_burrow_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListClustersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListClustersResponse) ProtoMessage() {}
+
+func (x *ListClustersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_burrow_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListClustersResponse.ProtoReflect.Descriptor instead.
+func (*ListClustersResponse) Descriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListClustersResponse) GetClusters() []string {
+	if x != nil {
+		return x.Clusters
+	}
+	return nil
+}
+
+type GetConsumerStatusRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Cluster string                 `protobuf:"bytes,1,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	Group   string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	// If true, every partition of the group is returned, as for /lag. Otherwise only the partitions that are not OK are
+	// returned, as for /status
+	ShowAll       bool `protobuf:"varint,3,opt,name=show_all,json=showAll,proto3" json:"show_all,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetConsumerStatusRequest) Reset() {
+	*x = GetConsumerStatusRequest{}
+	mi := &file_burrow_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetConsumerStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetConsumerStatusRequest) ProtoMessage() {}
+
+func (x *GetConsumerStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_burrow_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetConsumerStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetConsumerStatusRequest) Descriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetConsumerStatusRequest) GetCluster() string {
+	if x != nil {
+		return x.Cluster
+	}
+	return ""
+}
+
+func (x *GetConsumerStatusRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *GetConsumerStatusRequest) GetShowAll() bool {
+	if x != nil {
+		return x.ShowAll
+	}
+	return false
+}
+
+type ConsumerGroupStatus struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Cluster               string                 `protobuf:"bytes,1,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	Group                 string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	Status                Status                 `protobuf:"varint,3,opt,name=status,proto3,enum=burrow.v1.Status" json:"status,omitempty"`
+	Stale                 bool                   `protobuf:"varint,4,opt,name=stale,proto3" json:"stale,omitempty"`
+	Anomalous             bool                   `protobuf:"varint,5,opt,name=anomalous,proto3" json:"anomalous,omitempty"`
+	Baseline              *LagBaselineHour       `protobuf:"bytes,6,opt,name=baseline,proto3" json:"baseline,omitempty"`
+	Complete              float32                `protobuf:"fixed32,7,opt,name=complete,proto3" json:"complete,omitempty"`
+	Partitions            []*PartitionStatus     `protobuf:"bytes,8,rep,name=partitions,proto3" json:"partitions,omitempty"`
+	PartitionCount        int32                  `protobuf:"varint,9,opt,name=partition_count,json=partitionCount,proto3" json:"partition_count,omitempty"`
+	ActivePartitionCount  int32                  `protobuf:"varint,10,opt,name=active_partition_count,json=activePartitionCount,proto3" json:"active_partition_count,omitempty"`
+	Maxlag                *PartitionStatus       `protobuf:"bytes,11,opt,name=maxlag,proto3" json:"maxlag,omitempty"`
+	Totallag              uint64                 `protobuf:"varint,12,opt,name=totallag,proto3" json:"totallag,omitempty"`
+	MaxTimeLag            int64                  `protobuf:"varint,13,opt,name=max_time_lag,json=maxTimeLag,proto3" json:"max_time_lag,omitempty"`
+	Members               []*MemberStatus        `protobuf:"bytes,14,rep,name=members,proto3" json:"members,omitempty"`
+	StalledPartitions     []*PartitionStatus     `protobuf:"bytes,15,rep,name=stalled_partitions,json=stalledPartitions,proto3" json:"stalled_partitions,omitempty"`
+	OffsetOutOfRange      bool                   `protobuf:"varint,16,opt,name=offset_out_of_range,json=offsetOutOfRange,proto3" json:"offset_out_of_range,omitempty"`
+	PossiblyMisconfigured bool                   `protobuf:"varint,17,opt,name=possibly_misconfigured,json=possiblyMisconfigured,proto3" json:"possibly_misconfigured,omitempty"`
+	MissingTopics         []string               `protobuf:"bytes,18,rep,name=missing_topics,json=missingTopics,proto3" json:"missing_topics,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *ConsumerGroupStatus) Reset() {
+	*x = ConsumerGroupStatus{}
+	mi := &file_burrow_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsumerGroupStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsumerGroupStatus) ProtoMessage() {}
+
+func (x *ConsumerGroupStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_burrow_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsumerGroupStatus.ProtoReflect.Descriptor instead.
+func (*ConsumerGroupStatus) Descriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ConsumerGroupStatus) GetCluster() string {
+	if x != nil {
+		return x.Cluster
+	}
+	return ""
+}
+
+func (x *ConsumerGroupStatus) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *ConsumerGroupStatus) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_NOTFOUND
+}
+
+func (x *ConsumerGroupStatus) GetStale() bool {
+	if x != nil {
+		return x.Stale
+	}
+	return false
+}
+
+func (x *ConsumerGroupStatus) GetAnomalous() bool {
+	if x != nil {
+		return x.Anomalous
+	}
+	return false
+}
+
+func (x *ConsumerGroupStatus) GetBaseline() *LagBaselineHour {
+	if x != nil {
+		return x.Baseline
+	}
+	return nil
+}
+
+func (x *ConsumerGroupStatus) GetComplete() float32 {
+	if x != nil {
+		return x.Complete
+	}
+	return 0
+}
+
+func (x *ConsumerGroupStatus) GetPartitions() []*PartitionStatus {
+	if x != nil {
+		return x.Partitions
+	}
+	return nil
+}
+
+func (x *ConsumerGroupStatus) GetPartitionCount() int32 {
+	if x != nil {
+		return x.PartitionCount
+	}
+	return 0
+}
+
+func (x *ConsumerGroupStatus) GetActivePartitionCount() int32 {
+	if x != nil {
+		return x.ActivePartitionCount
+	}
+	return 0
+}
+
+func (x *ConsumerGroupStatus) GetMaxlag() *PartitionStatus {
+	if x != nil {
+		return x.Maxlag
+	}
+	return nil
+}
+
+func (x *ConsumerGroupStatus) GetTotallag() uint64 {
+	if x != nil {
+		return x.Totallag
+	}
+	return 0
+}
+
+func (x *ConsumerGroupStatus) GetMaxTimeLag() int64 {
+	if x != nil {
+		return x.MaxTimeLag
+	}
+	return 0
+}
+
+func (x *ConsumerGroupStatus) GetMembers() []*MemberStatus {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *ConsumerGroupStatus) GetStalledPartitions() []*PartitionStatus {
+	if x != nil {
+		return x.StalledPartitions
+	}
+	return nil
+}
+
+func (x *ConsumerGroupStatus) GetOffsetOutOfRange() bool {
+	if x != nil {
+		return x.OffsetOutOfRange
+	}
+	return false
+}
+
+func (x *ConsumerGroupStatus) GetPossiblyMisconfigured() bool {
+	if x != nil {
+		return x.PossiblyMisconfigured
+	}
+	return false
+}
+
+func (x *ConsumerGroupStatus) GetMissingTopics() []string {
+	if x != nil {
+		return x.MissingTopics
+	}
+	return nil
+}
+
+type PartitionStatus struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Topic            string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Partition        int32                  `protobuf:"varint,2,opt,name=partition,proto3" json:"partition,omitempty"`
+	Owner            string                 `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+	ClientId         string                 `protobuf:"bytes,4,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	InstanceId       string                 `protobuf:"bytes,5,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	Status           Status                 `protobuf:"varint,6,opt,name=status,proto3,enum=burrow.v1.Status" json:"status,omitempty"`
+	Start            *ConsumerOffset        `protobuf:"bytes,7,opt,name=start,proto3" json:"start,omitempty"`
+	End              *ConsumerOffset        `protobuf:"bytes,8,opt,name=end,proto3" json:"end,omitempty"`
+	CurrentLag       uint64                 `protobuf:"varint,9,opt,name=current_lag,json=currentLag,proto3" json:"current_lag,omitempty"`
+	TimeLag          int64                  `protobuf:"varint,10,opt,name=time_lag,json=timeLag,proto3" json:"time_lag,omitempty"`
+	Complete         float32                `protobuf:"fixed32,11,opt,name=complete,proto3" json:"complete,omitempty"`
+	Compacted        bool                   `protobuf:"varint,12,opt,name=compacted,proto3" json:"compacted,omitempty"`
+	OffsetOutOfRange bool                   `protobuf:"varint,13,opt,name=offset_out_of_range,json=offsetOutOfRange,proto3" json:"offset_out_of_range,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *PartitionStatus) Reset() {
+	*x = PartitionStatus{}
+	mi := &file_burrow_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PartitionStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PartitionStatus) ProtoMessage() {}
+
+func (x *PartitionStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_burrow_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PartitionStatus.ProtoReflect.Descriptor instead.
+func (*PartitionStatus) Descriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PartitionStatus) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *PartitionStatus) GetPartition() int32 {
+	if x != nil {
+		return x.Partition
+	}
+	return 0
+}
+
+func (x *PartitionStatus) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *PartitionStatus) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *PartitionStatus) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+func (x *PartitionStatus) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_NOTFOUND
+}
+
+func (x *PartitionStatus) GetStart() *ConsumerOffset {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *PartitionStatus) GetEnd() *ConsumerOffset {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+func (x *PartitionStatus) GetCurrentLag() uint64 {
+	if x != nil {
+		return x.CurrentLag
+	}
+	return 0
+}
+
+func (x *PartitionStatus) GetTimeLag() int64 {
+	if x != nil {
+		return x.TimeLag
+	}
+	return 0
+}
+
+func (x *PartitionStatus) GetComplete() float32 {
+	if x != nil {
+		return x.Complete
+	}
+	return 0
+}
+
+func (x *PartitionStatus) GetCompacted() bool {
+	if x != nil {
+		return x.Compacted
+	}
+	return false
+}
+
+func (x *PartitionStatus) GetOffsetOutOfRange() bool {
+	if x != nil {
+		return x.OffsetOutOfRange
+	}
+	return false
+}
+
+type MemberStatus struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	InstanceId     string                 `protobuf:"bytes,1,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	ClientId       string                 `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Owner          string                 `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+	Status         Status                 `protobuf:"varint,4,opt,name=status,proto3,enum=burrow.v1.Status" json:"status,omitempty"`
+	PartitionCount int32                  `protobuf:"varint,5,opt,name=partition_count,json=partitionCount,proto3" json:"partition_count,omitempty"`
+	Totallag       uint64                 `protobuf:"varint,6,opt,name=totallag,proto3" json:"totallag,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *MemberStatus) Reset() {
+	*x = MemberStatus{}
+	mi := &file_burrow_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MemberStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MemberStatus) ProtoMessage() {}
+
+func (x *MemberStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_burrow_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MemberStatus.ProtoReflect.Descriptor instead.
+func (*MemberStatus) Descriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *MemberStatus) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+func (x *MemberStatus) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *MemberStatus) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *MemberStatus) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_NOTFOUND
+}
+
+func (x *MemberStatus) GetPartitionCount() int32 {
+	if x != nil {
+		return x.PartitionCount
+	}
+	return 0
+}
+
+func (x *MemberStatus) GetTotallag() uint64 {
+	if x != nil {
+		return x.Totallag
+	}
+	return 0
+}
+
+type LagBaselineHour struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Samples       int64                  `protobuf:"varint,1,opt,name=samples,proto3" json:"samples,omitempty"`
+	Mean          float64                `protobuf:"fixed64,2,opt,name=mean,proto3" json:"mean,omitempty"`
+	StdDev        float64                `protobuf:"fixed64,3,opt,name=std_dev,json=stdDev,proto3" json:"std_dev,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LagBaselineHour) Reset() {
+	*x = LagBaselineHour{}
+	mi := &file_burrow_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LagBaselineHour) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LagBaselineHour) ProtoMessage() {}
+
+func (x *LagBaselineHour) ProtoReflect() protoreflect.Message {
+	mi := &file_burrow_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LagBaselineHour.ProtoReflect.Descriptor instead.
+func (*LagBaselineHour) Descriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *LagBaselineHour) GetSamples() int64 {
+	if x != nil {
+		return x.Samples
+	}
+	return 0
+}
+
+func (x *LagBaselineHour) GetMean() float64 {
+	if x != nil {
+		return x.Mean
+	}
+	return 0
+}
+
+func (x *LagBaselineHour) GetStdDev() float64 {
+	if x != nil {
+		return x.StdDev
+	}
+	return 0
+}
+
+type GetConsumerDetailRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cluster       string                 `protobuf:"bytes,1,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	Group         string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetConsumerDetailRequest) Reset() {
+	*x = GetConsumerDetailRequest{}
+	mi := &file_burrow_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetConsumerDetailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetConsumerDetailRequest) ProtoMessage() {}
+
+func (x *GetConsumerDetailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_burrow_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetConsumerDetailRequest.ProtoReflect.Descriptor instead.
+func (*GetConsumerDetailRequest) Descriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetConsumerDetailRequest) GetCluster() string {
+	if x != nil {
+		return x.Cluster
+	}
+	return ""
+}
+
+func (x *GetConsumerDetailRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+type GetConsumerDetailResponse struct {
+	state         protoimpl.MessageState         `protogen:"open.v1"`
+	Topics        map[string]*ConsumerPartitions `protobuf:"bytes,1,rep,name=topics,proto3" json:"topics,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetConsumerDetailResponse) Reset() {
+	*x = GetConsumerDetailResponse{}
+	mi := &file_burrow_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetConsumerDetailResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetConsumerDetailResponse) ProtoMessage() {}
+
+func (x *GetConsumerDetailResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_burrow_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetConsumerDetailResponse.ProtoReflect.Descriptor instead.
+func (*GetConsumerDetailResponse) Descriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetConsumerDetailResponse) GetTopics() map[string]*ConsumerPartitions {
+	if x != nil {
+		return x.Topics
+	}
+	return nil
+}
+
+// ConsumerPartitions is the partitions of a topic, with the partition ID as the index.
+type ConsumerPartitions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Partitions    []*ConsumerPartition   `protobuf:"bytes,1,rep,name=partitions,proto3" json:"partitions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConsumerPartitions) Reset() {
+	*x = ConsumerPartitions{}
+	mi := &file_burrow_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsumerPartitions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsumerPartitions) ProtoMessage() {}
+
+func (x *ConsumerPartitions) ProtoReflect() protoreflect.Message {
+	mi := &file_burrow_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsumerPartitions.ProtoReflect.Descriptor instead.
+func (*ConsumerPartitions) Descriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ConsumerPartitions) GetPartitions() []*ConsumerPartition {
+	if x != nil {
+		return x.Partitions
+	}
+	return nil
+}
+
+type ConsumerPartition struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The offsets stored for the partition, oldest first. Unlike the JSON response, intervals that no offset has been
+	// stored for yet are left out
+	Offsets       []*ConsumerOffset `protobuf:"bytes,1,rep,name=offsets,proto3" json:"offsets,omitempty"`
+	Owner         string            `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	ClientId      string            `protobuf:"bytes,3,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	InstanceId    string            `protobuf:"bytes,4,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	CurrentLag    uint64            `protobuf:"varint,5,opt,name=current_lag,json=currentLag,proto3" json:"current_lag,omitempty"`
+	Compacted     bool              `protobuf:"varint,6,opt,name=compacted,proto3" json:"compacted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConsumerPartition) Reset() {
+	*x = ConsumerPartition{}
+	mi := &file_burrow_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsumerPartition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsumerPartition) ProtoMessage() {}
+
+func (x *ConsumerPartition) ProtoReflect() protoreflect.Message {
+	mi := &file_burrow_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsumerPartition.ProtoReflect.Descriptor instead.
+func (*ConsumerPartition) Descriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ConsumerPartition) GetOffsets() []*ConsumerOffset {
+	if x != nil {
+		return x.Offsets
+	}
+	return nil
+}
+
+func (x *ConsumerPartition) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *ConsumerPartition) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *ConsumerPartition) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+func (x *ConsumerPartition) GetCurrentLag() uint64 {
+	if x != nil {
+		return x.CurrentLag
+	}
+	return 0
+}
+
+func (x *ConsumerPartition) GetCompacted() bool {
+	if x != nil {
+		return x.Compacted
+	}
+	return false
+}
+
+type ConsumerOffset struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Offset     int64                  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Timestamp  int64                  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	ObservedAt int64                  `protobuf:"varint,3,opt,name=observed_at,json=observedAt,proto3" json:"observed_at,omitempty"`
+	// The lag when the offset was committed, if it is known
+	Lag           *uint64 `protobuf:"varint,4,opt,name=lag,proto3,oneof" json:"lag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConsumerOffset) Reset() {
+	*x = ConsumerOffset{}
+	mi := &file_burrow_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsumerOffset) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsumerOffset) ProtoMessage() {}
+
+func (x *ConsumerOffset) ProtoReflect() protoreflect.Message {
+	mi := &file_burrow_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsumerOffset.ProtoReflect.Descriptor instead.
+func (*ConsumerOffset) Descriptor() ([]byte, []int) {
+	return file_burrow_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ConsumerOffset) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ConsumerOffset) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ConsumerOffset) GetObservedAt() int64 {
+	if x != nil {
+		return x.ObservedAt
+	}
+	return 0
+}
+
+func (x *ConsumerOffset) GetLag() uint64 {
+	if x != nil && x.Lag != nil {
+		return *x.Lag
+	}
+	return 0
+}
+
+var File_burrow_proto protoreflect.FileDescriptor
+
+const file_burrow_proto_rawDesc = "" +
+	"\n" +
+	"\fburrow.proto\x12\tburrow.v1\"\x15\n" +
+	"\x13ListClustersRequest\"2\n" +
+	"\x14ListClustersResponse\x12\x1a\n" +
+	"\bclusters\x18\x01 \x03(\tR\bclusters\"e\n" +
+	"\x18GetConsumerStatusRequest\x12\x18\n" +
+	"\acluster\x18\x01 \x01(\tR\acluster\x12\x14\n" +
+	"\x05group\x18\x02 \x01(\tR\x05group\x12\x19\n" +
+	"\bshow_all\x18\x03 \x01(\bR\ashowAll\"\x90\x06\n" +
+	"\x13ConsumerGroupStatus\x12\x18\n" +
+	"\acluster\x18\x01 \x01(\tR\acluster\x12\x14\n" +
+	"\x05group\x18\x02 \x01(\tR\x05group\x12)\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x11.burrow.v1.StatusR\x06status\x12\x14\n" +
+	"\x05stale\x18\x04 \x01(\bR\x05stale\x12\x1c\n" +
+	"\tanomalous\x18\x05 \x01(\bR\tanomalous\x126\n" +
+	"\bbaseline\x18\x06 \x01(\v2\x1a.burrow.v1.LagBaselineHourR\bbaseline\x12\x1a\n" +
+	"\bcomplete\x18\a \x01(\x02R\bcomplete\x12:\n" +
+	"\n" +
+	"partitions\x18\b \x03(\v2\x1a.burrow.v1.PartitionStatusR\n" +
+	"partitions\x12'\n" +
+	"\x0fpartition_count\x18\t \x01(\x05R\x0epartitionCount\x124\n" +
+	"\x16active_partition_count\x18\n" +
+	" \x01(\x05R\x14activePartitionCount\x122\n" +
+	"\x06maxlag\x18\v \x01(\v2\x1a.burrow.v1.PartitionStatusR\x06maxlag\x12\x1a\n" +
+	"\btotallag\x18\f \x01(\x04R\btotallag\x12 \n" +
+	"\fmax_time_lag\x18\r \x01(\x03R\n" +
+	"maxTimeLag\x121\n" +
+	"\amembers\x18\x0e \x03(\v2\x17.burrow.v1.MemberStatusR\amembers\x12I\n" +
+	"\x12stalled_partitions\x18\x0f \x03(\v2\x1a.burrow.v1.PartitionStatusR\x11stalledPartitions\x12-\n" +
+	"\x13offset_out_of_range\x18\x10 \x01(\bR\x10offsetOutOfRange\x125\n" +
+	"\x16possibly_misconfigured\x18\x11 \x01(\bR\x15possiblyMisconfigured\x12%\n" +
+	"\x0emissing_topics\x18\x12 \x03(\tR\rmissingTopics\"\xc7\x03\n" +
+	"\x0fPartitionStatus\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x1c\n" +
+	"\tpartition\x18\x02 \x01(\x05R\tpartition\x12\x14\n" +
+	"\x05owner\x18\x03 \x01(\tR\x05owner\x12\x1b\n" +
+	"\tclient_id\x18\x04 \x01(\tR\bclientId\x12\x1f\n" +
+	"\vinstance_id\x18\x05 \x01(\tR\n" +
+	"instanceId\x12)\n" +
+	"\x06status\x18\x06 \x01(\x0e2\x11.burrow.v1.StatusR\x06status\x12/\n" +
+	"\x05start\x18\a \x01(\v2\x19.burrow.v1.ConsumerOffsetR\x05start\x12+\n" +
+	"\x03end\x18\b \x01(\v2\x19.burrow.v1.ConsumerOffsetR\x03end\x12\x1f\n" +
+	"\vcurrent_lag\x18\t \x01(\x04R\n" +
+	"currentLag\x12\x19\n" +
+	"\btime_lag\x18\n" +
+	" \x01(\x03R\atimeLag\x12\x1a\n" +
+	"\bcomplete\x18\v \x01(\x02R\bcomplete\x12\x1c\n" +
+	"\tcompacted\x18\f \x01(\bR\tcompacted\x12-\n" +
+	"\x13offset_out_of_range\x18\r \x01(\bR\x10offsetOutOfRange\"\xd2\x01\n" +
+	"\fMemberStatus\x12\x1f\n" +
+	"\vinstance_id\x18\x01 \x01(\tR\n" +
+	"instanceId\x12\x1b\n" +
+	"\tclient_id\x18\x02 \x01(\tR\bclientId\x12\x14\n" +
+	"\x05owner\x18\x03 \x01(\tR\x05owner\x12)\n" +
+	"\x06status\x18\x04 \x01(\x0e2\x11.burrow.v1.StatusR\x06status\x12'\n" +
+	"\x0fpartition_count\x18\x05 \x01(\x05R\x0epartitionCount\x12\x1a\n" +
+	"\btotallag\x18\x06 \x01(\x04R\btotallag\"X\n" +
+	"\x0fLagBaselineHour\x12\x18\n" +
+	"\asamples\x18\x01 \x01(\x03R\asamples\x12\x12\n" +
+	"\x04mean\x18\x02 \x01(\x01R\x04mean\x12\x17\n" +
+	"\astd_dev\x18\x03 \x01(\x01R\x06stdDev\"J\n" +
+	"\x18GetConsumerDetailRequest\x12\x18\n" +
+	"\acluster\x18\x01 \x01(\tR\acluster\x12\x14\n" +
+	"\x05group\x18\x02 \x01(\tR\x05group\"\xbf\x01\n" +
+	"\x19GetConsumerDetailResponse\x12H\n" +
+	"\x06topics\x18\x01 \x03(\v20.burrow.v1.GetConsumerDetailResponse.TopicsEntryR\x06topics\x1aX\n" +
+	"\vTopicsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x123\n" +
+	"\x05value\x18\x02 \x01(\v2\x1d.burrow.v1.ConsumerPartitionsR\x05value:\x028\x01\"R\n" +
+	"\x12ConsumerPartitions\x12<\n" +
+	"\n" +
+	"partitions\x18\x01 \x03(\v2\x1c.burrow.v1.ConsumerPartitionR\n" +
+	"partitions\"\xdb\x01\n" +
+	"\x11ConsumerPartition\x123\n" +
+	"\aoffsets\x18\x01 \x03(\v2\x19.burrow.v1.ConsumerOffsetR\aoffsets\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\x12\x1b\n" +
+	"\tclient_id\x18\x03 \x01(\tR\bclientId\x12\x1f\n" +
+	"\vinstance_id\x18\x04 \x01(\tR\n" +
+	"instanceId\x12\x1f\n" +
+	"\vcurrent_lag\x18\x05 \x01(\x04R\n" +
+	"currentLag\x12\x1c\n" +
+	"\tcompacted\x18\x06 \x01(\bR\tcompacted\"\x86\x01\n" +
+	"\x0eConsumerOffset\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x03R\x06offset\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x12\x1f\n" +
+	"\vobserved_at\x18\x03 \x01(\x03R\n" +
+	"observedAt\x12\x15\n" +
+	"\x03lag\x18\x04 \x01(\x04H\x00R\x03lag\x88\x01\x01B\x06\n" +
+	"\x04_lag*]\n" +
+	"\x06Status\x12\f\n" +
+	"\bNOTFOUND\x10\x00\x12\x06\n" +
+	"\x02OK\x10\x01\x12\b\n" +
+	"\x04WARN\x10\x02\x12\a\n" +
+	"\x03ERR\x10\x03\x12\b\n" +
+	"\x04STOP\x10\x04\x12\t\n" +
+	"\x05STALL\x10\x05\x12\n" +
+	"\n" +
+	"\x06REWIND\x10\x06\x12\t\n" +
+	"\x05MUTED\x10\a2\x93\x02\n" +
+	"\x06Burrow\x12O\n" +
+	"\fListClusters\x12\x1e.burrow.v1.ListClustersRequest\x1a\x1f.burrow.v1.ListClustersResponse\x12X\n" +
+	"\x11GetConsumerStatus\x12#.burrow.v1.GetConsumerStatusRequest\x1a\x1e.burrow.v1.ConsumerGroupStatus\x12^\n" +
+	"\x11GetConsumerDetail\x12#.burrow.v1.GetConsumerDetailRequest\x1a$.burrow.v1.GetConsumerDetailResponseB3Z1github.com/linkedin/Burrow/core/protocol/burrowpbb\x06proto3"
+
+var (
+	file_burrow_proto_rawDescOnce sync.Once
+	file_burrow_proto_rawDescData []byte
+)
+
+func file_burrow_proto_rawDescGZIP() []byte {
+	file_burrow_proto_rawDescOnce.Do(func() {
+		file_burrow_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_burrow_proto_rawDesc), len(file_burrow_proto_rawDesc)))
+	})
+	return file_burrow_proto_rawDescData
+}
+
+var file_burrow_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_burrow_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_burrow_proto_goTypes = []any{
+	(Status)(0),                       // 0: burrow.v1.Status
+	(*ListClustersRequest)(nil),       // 1: burrow.v1.ListClustersRequest
+	(*ListClustersResponse)(nil),      // 2: burrow.v1.ListClustersResponse
+	(*GetConsumerStatusRequest)(nil),  // 3: burrow.v1.GetConsumerStatusRequest
+	(*ConsumerGroupStatus)(nil),       // 4: burrow.v1.ConsumerGroupStatus
+	(*PartitionStatus)(nil),           // 5: burrow.v1.PartitionStatus
+	(*MemberStatus)(nil),              // 6: burrow.v1.MemberStatus
+	(*LagBaselineHour)(nil),           // 7: burrow.v1.LagBaselineHour
+	(*GetConsumerDetailRequest)(nil),  // 8: burrow.v1.GetConsumerDetailRequest
+	(*GetConsumerDetailResponse)(nil), // 9: burrow.v1.GetConsumerDetailResponse
+	(*ConsumerPartitions)(nil),        // 10: burrow.v1.ConsumerPartitions
+	(*ConsumerPartition)(nil),         // 11: burrow.v1.ConsumerPartition
+	(*ConsumerOffset)(nil),            // 12: burrow.v1.ConsumerOffset
+	nil,                               // 13: burrow.v1.GetConsumerDetailResponse.TopicsEntry
+}
+var file_burrow_proto_depIdxs = []int32{
+	0,  // 0: burrow.v1.ConsumerGroupStatus.status:type_name -> burrow.v1.Status
+	7,  // 1: burrow.v1.ConsumerGroupStatus.baseline:type_name -> burrow.v1.LagBaselineHour
+	5,  // 2: burrow.v1.ConsumerGroupStatus.partitions:type_name -> burrow.v1.PartitionStatus
+	5,  // 3: burrow.v1.ConsumerGroupStatus.maxlag:type_name -> burrow.v1.PartitionStatus
+	6,  // 4: burrow.v1.ConsumerGroupStatus.members:type_name -> burrow.v1.MemberStatus
+	5,  // 5: burrow.v1.ConsumerGroupStatus.stalled_partitions:type_name -> burrow.v1.PartitionStatus
+	0,  // 6: burrow.v1.PartitionStatus.status:type_name -> burrow.v1.Status
+	12, // 7: burrow.v1.PartitionStatus.start:type_name -> burrow.v1.ConsumerOffset
+	12, // 8: burrow.v1.PartitionStatus.end:type_name -> burrow.v1.ConsumerOffset
+	0,  // 9: burrow.v1.MemberStatus.status:type_name -> burrow.v1.Status
+	13, // 10: burrow.v1.GetConsumerDetailResponse.topics:type_name -> burrow.v1.GetConsumerDetailResponse.TopicsEntry
+	11, // 11: burrow.v1.ConsumerPartitions.partitions:type_name -> burrow.v1.ConsumerPartition
+	12, // 12: burrow.v1.ConsumerPartition.offsets:type_name -> burrow.v1.ConsumerOffset
+	10, // 13: burrow.v1.GetConsumerDetailResponse.TopicsEntry.value:type_name -> burrow.v1.ConsumerPartitions
+	1,  // 14: burrow.v1.Burrow.ListClusters:input_type -> burrow.v1.ListClustersRequest
+	3,  // 15: burrow.v1.Burrow.GetConsumerStatus:input_type -> burrow.v1.GetConsumerStatusRequest
+	8,  // 16: burrow.v1.Burrow.GetConsumerDetail:input_type -> burrow.v1.GetConsumerDetailRequest
+	2,  // 17: burrow.v1.Burrow.ListClusters:output_type -> burrow.v1.ListClustersResponse
+	4,  // 18: burrow.v1.Burrow.GetConsumerStatus:output_type -> burrow.v1.ConsumerGroupStatus
+	9,  // 19: burrow.v1.Burrow.GetConsumerDetail:output_type -> burrow.v1.GetConsumerDetailResponse
+	17, // [17:20] is the sub-list for method output_type
+	14, // [14:17] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_burrow_proto_init() }
+func file_burrow_proto_init() {
+	if File_burrow_proto != nil {
+		return
+	}
+	file_burrow_proto_msgTypes[11].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_burrow_proto_rawDesc), len(file_burrow_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_burrow_proto_goTypes,
+		DependencyIndexes: file_burrow_proto_depIdxs,
+		EnumInfos:         file_burrow_proto_enumTypes,
+		MessageInfos:      file_burrow_proto_msgTypes,
+	}.Build()
+	File_burrow_proto = out.File
+	file_burrow_proto_goTypes = nil
+	file_burrow_proto_depIdxs = nil
+}
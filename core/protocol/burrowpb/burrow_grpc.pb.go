@@ -0,0 +1,223 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+// The gRPC API for Burrow. The messages mirror the JSON responses of the HTTP API, and the Go code is generated with
+// "go generate ./core/protocol/burrowpb" (this needs protoc, protoc-gen-go, and protoc-gen-go-grpc).
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: burrow.proto
+
+package burrowpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Burrow_ListClusters_FullMethodName      = "/burrow.v1.Burrow/ListClusters"
+	Burrow_GetConsumerStatus_FullMethodName = "/burrow.v1.Burrow/GetConsumerStatus"
+	Burrow_GetConsumerDetail_FullMethodName = "/burrow.v1.Burrow/GetConsumerDetail"
+)
+
+// BurrowClient is the client API for Burrow service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Burrow serves the read operations of the HTTP API under /v3/kafka.
+type BurrowClient interface {
+	// ListClusters returns the names of the clusters that Burrow is monitoring, as /v3/kafka does.
+	ListClusters(ctx context.Context, in *ListClustersRequest, opts ...grpc.CallOption) (*ListClustersResponse, error)
+	// GetConsumerStatus returns the evaluated status of a group, as /v3/kafka/<cluster>/consumer/<group>/status and
+	// /lag do. Returns NOT_FOUND if the cluster or group is not known.
+	GetConsumerStatus(ctx context.Context, in *GetConsumerStatusRequest, opts ...grpc.CallOption) (*ConsumerGroupStatus, error)
+	// GetConsumerDetail returns the offsets stored for a group, as /v3/kafka/<cluster>/consumer/<group> does. Returns
+	// NOT_FOUND if the cluster or group is not known.
+	GetConsumerDetail(ctx context.Context, in *GetConsumerDetailRequest, opts ...grpc.CallOption) (*GetConsumerDetailResponse, error)
+}
+
+type burrowClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBurrowClient(cc grpc.ClientConnInterface) BurrowClient {
+	return &burrowClient{cc}
+}
+
+func (c *burrowClient) ListClusters(ctx context.Context, in *ListClustersRequest, opts ...grpc.CallOption) (*ListClustersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListClustersResponse)
+	err := c.cc.Invoke(ctx, Burrow_ListClusters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *burrowClient) GetConsumerStatus(ctx context.Context, in *GetConsumerStatusRequest, opts ...grpc.CallOption) (*ConsumerGroupStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ConsumerGroupStatus)
+	err := c.cc.Invoke(ctx, Burrow_GetConsumerStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *burrowClient) GetConsumerDetail(ctx context.Context, in *GetConsumerDetailRequest, opts ...grpc.CallOption) (*GetConsumerDetailResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetConsumerDetailResponse)
+	err := c.cc.Invoke(ctx, Burrow_GetConsumerDetail_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BurrowServer is the server API for Burrow service.
+// All implementations must embed UnimplementedBurrowServer
+// for forward compatibility.
+//
+// Burrow serves the read operations of the HTTP API under /v3/kafka.
+type BurrowServer interface {
+	// ListClusters returns the names of the clusters that Burrow is monitoring, as /v3/kafka does.
+	ListClusters(context.Context, *ListClustersRequest) (*ListClustersResponse, error)
+	// GetConsumerStatus returns the evaluated status of a group, as /v3/kafka/<cluster>/consumer/<group>/status and
+	// /lag do. Returns NOT_FOUND if the cluster or group is not known.
+	GetConsumerStatus(context.Context, *GetConsumerStatusRequest) (*ConsumerGroupStatus, error)
+	// GetConsumerDetail returns the offsets stored for a group, as /v3/kafka/<cluster>/consumer/<group> does. Returns
+	// NOT_FOUND if the cluster or group is not known.
+	GetConsumerDetail(context.Context, *GetConsumerDetailRequest) (*GetConsumerDetailResponse, error)
+	mustEmbedUnimplementedBurrowServer()
+}
+
+// UnimplementedBurrowServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBurrowServer struct{}
+
+func (UnimplementedBurrowServer) ListClusters(context.Context, *ListClustersRequest) (*ListClustersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListClusters not implemented")
+}
+func (UnimplementedBurrowServer) GetConsumerStatus(context.Context, *GetConsumerStatusRequest) (*ConsumerGroupStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConsumerStatus not implemented")
+}
+func (UnimplementedBurrowServer) GetConsumerDetail(context.Context, *GetConsumerDetailRequest) (*GetConsumerDetailResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConsumerDetail not implemented")
+}
+func (UnimplementedBurrowServer) mustEmbedUnimplementedBurrowServer() {}
+func (UnimplementedBurrowServer) testEmbeddedByValue()                {}
+
+// UnsafeBurrowServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BurrowServer will
+// result in compilation errors.
+type UnsafeBurrowServer interface {
+	mustEmbedUnimplementedBurrowServer()
+}
+
+func RegisterBurrowServer(s grpc.ServiceRegistrar, srv BurrowServer) {
+	// If the following call pancis, it indicates UnimplementedBurrowServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Burrow_ServiceDesc, srv)
+}
+
+func _Burrow_ListClusters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListClustersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BurrowServer).ListClusters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Burrow_ListClusters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BurrowServer).ListClusters(ctx, req.(*ListClustersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Burrow_GetConsumerStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetConsumerStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BurrowServer).GetConsumerStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Burrow_GetConsumerStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BurrowServer).GetConsumerStatus(ctx, req.(*GetConsumerStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Burrow_GetConsumerDetail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetConsumerDetailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BurrowServer).GetConsumerDetail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Burrow_GetConsumerDetail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BurrowServer).GetConsumerDetail(ctx, req.(*GetConsumerDetailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Burrow_ServiceDesc is the grpc.ServiceDesc for Burrow service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Burrow_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "burrow.v1.Burrow",
+	HandlerType: (*BurrowServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListClusters",
+			Handler:    _Burrow_ListClusters_Handler,
+		},
+		{
+			MethodName: "GetConsumerStatus",
+			Handler:    _Burrow_GetConsumerStatus_Handler,
+		},
+		{
+			MethodName: "GetConsumerDetail",
+			Handler:    _Burrow_GetConsumerDetail_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "burrow.proto",
+}
@@ -0,0 +1,16 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+// Package burrowpb - gRPC API definitions
+// The burrowpb package has the protobuf messages and the service definition for Burrow's gRPC API, which is served by
+// the grpcserver subsystem, and for the OffsetSource service that the grpc consumer module reads offsets from. The Go
+// code is generated from burrow.proto and offsetsource.proto, and is exported so that Go clients and servers can use it.
+package burrowpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative burrow.proto offsetsource.proto
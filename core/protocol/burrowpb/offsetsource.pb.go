@@ -0,0 +1,236 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+// The service that a store of committed offsets implements for Burrow to read from it with the grpc consumer module.
+// Unlike the Burrow service, Burrow is the client of this service, not the server. The Go code is generated with
+// "go generate ./core/protocol/burrowpb" (this needs protoc, protoc-gen-go, and protoc-gen-go-grpc).
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: offsetsource.proto
+
+package burrowpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StreamOffsetsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The name of the cluster in Burrow that the consumer module is for
+	Cluster       string `protobuf:"bytes,1,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamOffsetsRequest) Reset() {
+	*x = StreamOffsetsRequest{}
+	mi := &file_offsetsource_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamOffsetsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamOffsetsRequest) ProtoMessage() {}
+
+func (x *StreamOffsetsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_offsetsource_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamOffsetsRequest.ProtoReflect.Descriptor instead.
+func (*StreamOffsetsRequest) Descriptor() ([]byte, []int) {
+	return file_offsetsource_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StreamOffsetsRequest) GetCluster() string {
+	if x != nil {
+		return x.Cluster
+	}
+	return ""
+}
+
+type CommittedOffset struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Group     string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Topic     string                 `protobuf:"bytes,2,opt,name=topic,proto3" json:"topic,omitempty"`
+	Partition int32                  `protobuf:"varint,3,opt,name=partition,proto3" json:"partition,omitempty"`
+	Offset    int64                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	// The time that the offset was committed, in milliseconds since the epoch
+	Timestamp int64 `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// A number that is larger for each later commit to the partition for the group, such as the position of the commit
+	// in the store's log. Burrow uses it to put commits that arrive out of order back in order, and to drop the ones it
+	// already has. If it is 0, the timestamp is used.
+	Sequence      int64 `protobuf:"varint,6,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommittedOffset) Reset() {
+	*x = CommittedOffset{}
+	mi := &file_offsetsource_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommittedOffset) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommittedOffset) ProtoMessage() {}
+
+func (x *CommittedOffset) ProtoReflect() protoreflect.Message {
+	mi := &file_offsetsource_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommittedOffset.ProtoReflect.Descriptor instead.
+func (*CommittedOffset) Descriptor() ([]byte, []int) {
+	return file_offsetsource_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CommittedOffset) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *CommittedOffset) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *CommittedOffset) GetPartition() int32 {
+	if x != nil {
+		return x.Partition
+	}
+	return 0
+}
+
+func (x *CommittedOffset) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *CommittedOffset) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *CommittedOffset) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+var File_offsetsource_proto protoreflect.FileDescriptor
+
+const file_offsetsource_proto_rawDesc = "" +
+	"\n" +
+	"\x12offsetsource.proto\x12\tburrow.v1\"0\n" +
+	"\x14StreamOffsetsRequest\x12\x18\n" +
+	"\acluster\x18\x01 \x01(\tR\acluster\"\xad\x01\n" +
+	"\x0fCommittedOffset\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x14\n" +
+	"\x05topic\x18\x02 \x01(\tR\x05topic\x12\x1c\n" +
+	"\tpartition\x18\x03 \x01(\x05R\tpartition\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x03R\x06offset\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\x12\x1a\n" +
+	"\bsequence\x18\x06 \x01(\x03R\bsequence2^\n" +
+	"\fOffsetSource\x12N\n" +
+	"\rStreamOffsets\x12\x1f.burrow.v1.StreamOffsetsRequest\x1a\x1a.burrow.v1.CommittedOffset0\x01B3Z1github.com/linkedin/Burrow/core/protocol/burrowpbb\x06proto3"
+
+var (
+	file_offsetsource_proto_rawDescOnce sync.Once
+	file_offsetsource_proto_rawDescData []byte
+)
+
+func file_offsetsource_proto_rawDescGZIP() []byte {
+	file_offsetsource_proto_rawDescOnce.Do(func() {
+		file_offsetsource_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_offsetsource_proto_rawDesc), len(file_offsetsource_proto_rawDesc)))
+	})
+	return file_offsetsource_proto_rawDescData
+}
+
+var file_offsetsource_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_offsetsource_proto_goTypes = []any{
+	(*StreamOffsetsRequest)(nil), // 0: burrow.v1.StreamOffsetsRequest
+	(*CommittedOffset)(nil),      // 1: burrow.v1.CommittedOffset
+}
+var file_offsetsource_proto_depIdxs = []int32{
+	0, // 0: burrow.v1.OffsetSource.StreamOffsets:input_type -> burrow.v1.StreamOffsetsRequest
+	1, // 1: burrow.v1.OffsetSource.StreamOffsets:output_type -> burrow.v1.CommittedOffset
+	1, // [1:2] is the sub-list for method output_type
+	0, // [0:1] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_offsetsource_proto_init() }
+func file_offsetsource_proto_init() {
+	if File_offsetsource_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_offsetsource_proto_rawDesc), len(file_offsetsource_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_offsetsource_proto_goTypes,
+		DependencyIndexes: file_offsetsource_proto_depIdxs,
+		MessageInfos:      file_offsetsource_proto_msgTypes,
+	}.Build()
+	File_offsetsource_proto = out.File
+	file_offsetsource_proto_goTypes = nil
+	file_offsetsource_proto_depIdxs = nil
+}
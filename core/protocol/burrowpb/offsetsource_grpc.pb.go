@@ -0,0 +1,147 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+// The service that a store of committed offsets implements for Burrow to read from it with the grpc consumer module.
+// Unlike the Burrow service, Burrow is the client of this service, not the server. The Go code is generated with
+// "go generate ./core/protocol/burrowpb" (this needs protoc, protoc-gen-go, and protoc-gen-go-grpc).
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: offsetsource.proto
+
+package burrowpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	OffsetSource_StreamOffsets_FullMethodName = "/burrow.v1.OffsetSource/StreamOffsets"
+)
+
+// OffsetSourceClient is the client API for OffsetSource service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// OffsetSource streams the offsets that consumer groups commit to a store other than Kafka or Zookeeper.
+type OffsetSourceClient interface {
+	// StreamOffsets sends the latest offset committed for every partition of every group in the cluster, and then each
+	// offset as it is committed, until either side closes the stream. Burrow calls it again when the stream fails, so an
+	// offset may be sent more than once.
+	StreamOffsets(ctx context.Context, in *StreamOffsetsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CommittedOffset], error)
+}
+
+type offsetSourceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOffsetSourceClient(cc grpc.ClientConnInterface) OffsetSourceClient {
+	return &offsetSourceClient{cc}
+}
+
+func (c *offsetSourceClient) StreamOffsets(ctx context.Context, in *StreamOffsetsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CommittedOffset], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OffsetSource_ServiceDesc.Streams[0], OffsetSource_StreamOffsets_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamOffsetsRequest, CommittedOffset]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OffsetSource_StreamOffsetsClient = grpc.ServerStreamingClient[CommittedOffset]
+
+// OffsetSourceServer is the server API for OffsetSource service.
+// All implementations must embed UnimplementedOffsetSourceServer
+// for forward compatibility.
+//
+// OffsetSource streams the offsets that consumer groups commit to a store other than Kafka or Zookeeper.
+type OffsetSourceServer interface {
+	// StreamOffsets sends the latest offset committed for every partition of every group in the cluster, and then each
+	// offset as it is committed, until either side closes the stream. Burrow calls it again when the stream fails, so an
+	// offset may be sent more than once.
+	StreamOffsets(*StreamOffsetsRequest, grpc.ServerStreamingServer[CommittedOffset]) error
+	mustEmbedUnimplementedOffsetSourceServer()
+}
+
+// UnimplementedOffsetSourceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOffsetSourceServer struct{}
+
+func (UnimplementedOffsetSourceServer) StreamOffsets(*StreamOffsetsRequest, grpc.ServerStreamingServer[CommittedOffset]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamOffsets not implemented")
+}
+func (UnimplementedOffsetSourceServer) mustEmbedUnimplementedOffsetSourceServer() {}
+func (UnimplementedOffsetSourceServer) testEmbeddedByValue()                      {}
+
+// UnsafeOffsetSourceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OffsetSourceServer will
+// result in compilation errors.
+type UnsafeOffsetSourceServer interface {
+	mustEmbedUnimplementedOffsetSourceServer()
+}
+
+func RegisterOffsetSourceServer(s grpc.ServiceRegistrar, srv OffsetSourceServer) {
+	// If the following call pancis, it indicates UnimplementedOffsetSourceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&OffsetSource_ServiceDesc, srv)
+}
+
+func _OffsetSource_StreamOffsets_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamOffsetsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OffsetSourceServer).StreamOffsets(m, &grpc.GenericServerStream[StreamOffsetsRequest, CommittedOffset]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OffsetSource_StreamOffsetsServer = grpc.ServerStreamingServer[CommittedOffset]
+
+// OffsetSource_ServiceDesc is the grpc.ServiceDesc for OffsetSource service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OffsetSource_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "burrow.v1.OffsetSource",
+	HandlerType: (*OffsetSourceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamOffsets",
+			Handler:       _OffsetSource_StreamOffsets_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "offsetsource.proto",
+}
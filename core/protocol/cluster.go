@@ -0,0 +1,161 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package protocol
+
+import "time"
+
+// ClusterRequestConstant is used in ClusterRequest to indicate the type of request
+type ClusterRequestConstant int
+
+const (
+	// ClusterRefreshBrokerOffsets is the request type to fetch the end offsets for only the partitions that a single
+	// broker leads, outside of the regular offset refresh. Requires Cluster, Broker, and Reply to be set. The reply is
+	// a *ClusterBrokerOffsets, or nil if the cluster does not exist.
+	ClusterRefreshBrokerOffsets ClusterRequestConstant = 0
+
+	// ClusterFetchBrokerAPIVersions is the request type to get the API versions that each broker in the cluster
+	// supports. The versions are cached by the cluster module, and are fetched from the brokers the first time, or if
+	// Refresh is set. Requires Cluster and Reply to be set. The reply is a *ClusterAPIVersions, or nil if the cluster
+	// does not exist.
+	ClusterFetchBrokerAPIVersions ClusterRequestConstant = 1
+
+	// ClusterFetchTopicOffsetsAtTime is the request type to look up, for each partition of a topic, the earliest offset
+	// whose timestamp is at or after a time. Requires Cluster, Topic, Timestamp, and Reply to be set. The reply is a
+	// *ClusterTopicOffsets, or nil if the cluster does not exist.
+	ClusterFetchTopicOffsetsAtTime ClusterRequestConstant = 2
+)
+
+// ClusterRequest is sent over the ClusterChannel that is stored in the application context. It is a request to the
+// cluster module for the named cluster to do something outside of its regular refresh cycles, such as for debugging a
+// single broker. This request is typically used in the HTTP server.
+type ClusterRequest struct {
+	// The type of request that this struct encapsulates
+	RequestType ClusterRequestConstant
+
+	// If the request type is one that expects a response, the cluster module will send it over this channel. The
+	// channel is closed after the response is sent, or without a response if the cluster does not exist
+	Reply chan interface{}
+
+	// The name of the cluster to which the request applies
+	Cluster string
+
+	// The ID of the broker to which the request applies
+	Broker int32
+
+	// The name of the topic to which the request applies
+	Topic string
+
+	// The time to look up offsets for, in milliseconds
+	Timestamp int64
+
+	// If true, cached results are fetched again rather than returned
+	Refresh bool
+}
+
+// ClusterBrokerOffsets is the response to a ClusterRefreshBrokerOffsets request
+type ClusterBrokerOffsets struct {
+	// The ID of the broker that offsets were fetched from
+	Broker int32 `json:"broker"`
+
+	// The number of partitions that the broker leads, which offsets were requested for
+	Partitions int `json:"partitions"`
+
+	// The number of partitions that the broker returned an error for
+	PartitionErrors int `json:"partition_errors"`
+
+	// If the offset request to the broker failed, this is the error. Otherwise it is empty
+	Error string `json:"error,omitempty"`
+}
+
+// ClusterTopicOffsets is the response to a ClusterFetchTopicOffsetsAtTime request
+type ClusterTopicOffsets struct {
+	// The topic that offsets were looked up for
+	Topic string `json:"topic"`
+
+	// The time that offsets were looked up for, in milliseconds
+	Timestamp int64 `json:"timestamp"`
+
+	// The earliest offset whose timestamp is at or after Timestamp, for each partition, indexed by partition ID. This is
+	// -1 for a partition that has no messages that new, or that the offset could not be fetched for. It is nil if the
+	// topic does not exist.
+	Offsets []int64 `json:"offsets"`
+
+	// If the offsets could not be fetched for every partition, this is the error. Otherwise it is empty
+	Error string `json:"error,omitempty"`
+
+	// True if the Kafka version that the cluster module uses cannot look up offsets by timestamp
+	Unsupported bool `json:"-"`
+}
+
+// ClusterAPIVersions is the response to a ClusterFetchBrokerAPIVersions request
+type ClusterAPIVersions struct {
+	// The time the versions were fetched from the brokers, in milliseconds
+	Fetched int64 `json:"fetched"`
+
+	// True if every broker responded, and all of them support the same versions of every API. This shows that a
+	// cluster upgrade is complete
+	Uniform bool `json:"uniform"`
+
+	// The versions for each broker, sorted by broker ID
+	Brokers []*BrokerAPIVersions `json:"brokers"`
+}
+
+// BrokerAPIVersions is the API versions that one broker supports
+type BrokerAPIVersions struct {
+	// The ID of the broker
+	Broker int32 `json:"broker"`
+
+	// The version range of each API that the broker supports, sorted by API key
+	APIs []*APIVersionRange `json:"apis"`
+
+	// If the ApiVersions request to the broker failed, this is the error. Otherwise it is empty
+	Error string `json:"error,omitempty"`
+}
+
+// APIVersionRange is the versions of one Kafka API (identified by its key, as in the Kafka protocol) that a broker
+// supports
+type APIVersionRange struct {
+	APIKey     int16 `json:"api_key"`
+	MinVersion int16 `json:"min_version"`
+	MaxVersion int16 `json:"max_version"`
+}
+
+// OffsetScheduler coordinates the offset fetches of all the cluster modules. Each module registers its offset refresh
+// interval when it is configured, and asks for the delay before its first regular fetch when it is started. A scheduler
+// that staggers the clusters spreads their fetches across the interval, rather than letting every cluster fetch at the
+// same time.
+type OffsetScheduler interface {
+	// Register adds a cluster that fetches offsets every interval to the schedule
+	Register(cluster string, interval time.Duration)
+
+	// Start records that the cluster has started, and returns how long it must wait before its first regular fetch.
+	// After that, it fetches every interval.
+	Start(cluster string) time.Duration
+
+	// Schedule returns the effective schedule for every registered cluster, sorted by cluster name
+	Schedule() []*ScheduledOffsetFetch
+}
+
+// ScheduledOffsetFetch describes when a single cluster fetches offsets
+type ScheduledOffsetFetch struct {
+	// The name of the cluster
+	Cluster string `json:"cluster"`
+
+	// How often the cluster fetches offsets, in seconds
+	Interval float64 `json:"interval"`
+
+	// How far into the interval the cluster's fetches are shifted, in seconds. This is zero if the fetches are not
+	// staggered.
+	Offset float64 `json:"offset"`
+
+	// The time of the next regular fetch, as a UNIX timestamp in milliseconds. This is zero if the cluster has not
+	// started.
+	NextFetch int64 `json:"next-fetch"`
+}
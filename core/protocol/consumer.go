@@ -0,0 +1,82 @@
+// Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package protocol
+
+// ConsumerRequestConstant is used in ConsumerRequest to indicate the type of request
+type ConsumerRequestConstant int
+
+const (
+	// ConsumerRefreshGroup is the request type to fetch the committed offsets for a single group from the cluster and
+	// store them, outside of the consumer module's regular reads. Requires Cluster, Group, and Reply to be set. The
+	// reply is a *ConsumerGroupOffsets, or nil if there is no consumer module for the cluster that can fetch offsets.
+	ConsumerRefreshGroup ConsumerRequestConstant = 0
+
+	// ConsumerFetchGroupStatus is the request type to retrieve the result of the consumer module's last fetch of a
+	// single group's offsets, for troubleshooting a group whose offsets have stopped updating. Requires Cluster, Group,
+	// and Reply to be set. The reply is a *ConsumerGroupFetchStatus, or nil if there is no consumer module for the
+	// cluster that records fetches.
+	ConsumerFetchGroupStatus ConsumerRequestConstant = 1
+)
+
+// ConsumerRequest is sent over the ConsumerChannel that is stored in the application context. It is a request to a
+// consumer module for the named cluster to do something outside of its regular operation, such as for debugging a
+// single group. This request is typically used in the HTTP server.
+type ConsumerRequest struct {
+	// The type of request that this struct encapsulates
+	RequestType ConsumerRequestConstant
+
+	// If the request type is one that expects a response, the consumer module will send it over this channel. The
+	// channel is closed after the response is sent, or without a response if no consumer module can handle it
+	Reply chan interface{}
+
+	// The name of the cluster to which the request applies
+	Cluster string
+
+	// The name of the consumer group to which the request applies
+	Group string
+}
+
+// ConsumerGroupOffsets is the response to a ConsumerRefreshGroup request
+type ConsumerGroupOffsets struct {
+	// The name of the consumer module that fetched the offsets
+	Consumer string `json:"consumer"`
+
+	// The name of the consumer group that offsets were fetched for
+	Group string `json:"group"`
+
+	// The number of partitions that the group has committed offsets for, which were stored
+	Partitions int `json:"partitions"`
+
+	// The number of partitions that the group coordinator returned an error for
+	PartitionErrors int `json:"partition_errors"`
+
+	// If fetching the offsets failed, this is the error. Otherwise it is empty
+	Error string `json:"error,omitempty"`
+}
+
+// ConsumerGroupFetchStatus is the response to a ConsumerFetchGroupStatus request. A fetch is either a refresh of the
+// group's offsets from its coordinator, or the read of an offset commit for the group from the offsets topic.
+type ConsumerGroupFetchStatus struct {
+	// The name of the consumer module that fetched the offsets
+	Consumer string `json:"consumer"`
+
+	// The name of the consumer group
+	Group string `json:"group"`
+
+	// The error from the last fetch of the group's offsets, or "no error" if it succeeded. This is empty if the module
+	// has not fetched offsets for the group
+	LastError string `json:"last_error"`
+
+	// The time (in milliseconds) of the last successful fetch, or 0 if there has not been one
+	LastSuccess int64 `json:"last_success"`
+
+	// The time (in milliseconds) of the last failed fetch, or 0 if there has not been one
+	LastFailure int64 `json:"last_failure"`
+}
@@ -46,6 +46,10 @@ type PartitionStatus struct {
 	// If available (for active new consumers), the client_id of the consumer that currently owns this partition
 	ClientID string `json:"client_id"`
 
+	// If available (for active new consumers that use static membership), the group.instance.id of the consumer that
+	// currently owns this partition
+	InstanceID string `json:"instance_id"`
+
 	// The status of the partition
 	Status StatusConstant `json:"status"`
 
@@ -59,10 +63,23 @@ type PartitionStatus struct {
 	// last committed offset and the current broker end offset
 	CurrentLag uint64 `json:"current_lag"`
 
+	// An estimate of how far behind the consumer is for this partition in time (in milliseconds), from the rate that
+	// messages were produced to the partition over the cluster's offset-lookback. This is -1 if it is not known
+	TimeLag int64 `json:"time_lag"`
+
 	// A number between 0.0 and 1.0 that describes the percentage complete the offset information is for this partition.
 	// For example, if Burrow has been configured to store 10 offsets, and Burrow has only stored 7 commits for this
 	// partition, Complete will be 0.7
 	Complete float32 `json:"complete"`
+
+	// True if the partition's topic is compacted, in which case the checks that assume messages are produced at a steady
+	// rate (stall-window and lag-percent) are not applied to it
+	Compacted bool `json:"compacted,omitempty"`
+
+	// True if the consumer's last committed offset is below the oldest offset the broker has for the partition (the
+	// start of the log), such as after retention has removed the messages it had not consumed yet. This needs the
+	// cluster to fetch the oldest offsets
+	OffsetOutOfRange bool `json:"offset_out_of_range,omitempty"`
 }
 
 // ConsumerGroupStatus is the response object that is sent in reply to an EvaluatorRequest. It describes the current
@@ -75,9 +92,35 @@ type ConsumerGroupStatus struct {
 	Group string `json:"group"`
 
 	// The status of the consumer group. This is either NOTFOUND, OK, WARN, or ERR. It is calculated from the highest
-	// Status for the individual partitions
+	// Status for the individual partitions, unless the group has been muted, in which case it is MUTED
 	Status StatusConstant `json:"status"`
 
+	// Stale is true if the evaluator has a staleness window configured for the cluster, and the group has not
+	// committed an offset for any partition within that window. A stale group is still evaluated as normal, but
+	// notifiers do not send open notifications for it unless configured to
+	Stale bool `json:"stale"`
+
+	// Anomalous is true if the evaluator is configured to learn lag baselines, and the group's total lag is far above
+	// the baseline for this hour of the day. A group that would otherwise be OK is given the status WARN
+	Anomalous bool `json:"anomalous"`
+
+	// OffsetOutOfRange is true if the committed offset for any of the group's partitions is below the start of the log,
+	// so the group will be reset (skipping the removed messages) when it next fetches. Those partitions are given the
+	// status WARN if they would otherwise be OK
+	OffsetOutOfRange bool `json:"offset_out_of_range"`
+
+	// PossiblyMisconfigured is true if the evaluator is configured to check for it, and enough of the topics that the
+	// group has recently committed offsets for do not exist in the cluster, which usually means that the consumer is
+	// committing to the wrong cluster. The status of the group is not changed
+	PossiblyMisconfigured bool `json:"possibly_misconfigured"`
+
+	// If the group is PossiblyMisconfigured, the topics that it committed offsets for that do not exist in the cluster
+	MissingTopics []string `json:"missing_topics,omitempty"`
+
+	// If the evaluator is configured to learn lag baselines, the baseline for this hour of the day that the group's
+	// total lag was compared with
+	Baseline *LagBaselineHour `json:"baseline,omitempty"`
+
 	// A number between 0.0 and 1.0 that describes the percentage complete the partition information is for this group.
 	// A partition that has a Complete value of less than 1.0 will be treated as zero.
 	Complete float32 `json:"complete"`
@@ -92,11 +135,52 @@ type ConsumerGroupStatus struct {
 	// yet.
 	TotalPartitions int `json:"partition_count"`
 
+	// A count of the partitions that the group has committed an offset for within the evaluator's active partition
+	// window. A drop in this count, while TotalPartitions stays the same, can show that some of the group's consumers
+	// have failed or lost their assignments before it shows as lag.
+	ActivePartitions int `json:"active_partition_count"`
+
 	// A PartitionStatus object for the partition with the highest CurrentLag value
 	Maxlag *PartitionStatus `json:"maxlag"`
 
 	// The sum of all partition CurrentLag values for the group
 	TotalLag uint64 `json:"totallag"`
+
+	// The highest TimeLag of the partitions, or -1 if it is not known for any of them
+	MaxTimeLag int64 `json:"max_time_lag"`
+
+	// If the evaluator is configured to attribute lag to group members, a MemberStatus object for each member that
+	// owns one or more of the group's partitions, sorted with the most lag first. Partitions with no known owner are
+	// not included.
+	Members []*MemberStatus `json:"members,omitempty"`
+
+	// A PartitionStatus object for each partition that is stalled: the group is not advancing its committed offset for
+	// the partition while the end offset is. This includes partitions that are stalled for the evaluator's stall window
+	// even if the group is still making progress on its other partitions.
+	StalledPartitions []*PartitionStatus `json:"stalled_partitions,omitempty"`
+}
+
+// MemberStatus describes the lag for the partitions owned by a single member of a consumer group. Members are told
+// apart by their group.instance.id (for consumers that use static membership), client_id, and host.
+type MemberStatus struct {
+	// The group.instance.id of the member, if it uses static membership
+	InstanceID string `json:"instance_id"`
+
+	// The client_id of the member
+	ClientID string `json:"client_id"`
+
+	// The consumer host of the member
+	Owner string `json:"owner"`
+
+	// The status of the member. This is either OK, WARN, or ERR, calculated from the highest Status for the partitions
+	// the member owns
+	Status StatusConstant `json:"status"`
+
+	// The number of the group's partitions that the member owns
+	PartitionCount int `json:"partition_count"`
+
+	// The sum of the CurrentLag values for the partitions the member owns
+	TotalLag uint64 `json:"totallag"`
 }
 
 // StatusConstant describes the state of a partition or group as a single value. These values are ordered from least
@@ -130,9 +214,13 @@ const (
 	// StatusRewind indicates that the consumer has committed an offset for the partition that is less than the
 	// previous offset. It is not used for group status.
 	StatusRewind StatusConstant = 6
+
+	// StatusMuted indicates that the consumer group has been muted, so it is not being alerted on. The partitions are
+	// still evaluated as normal. It is not used for partition status, and it is not ordered with the other statuses
+	StatusMuted StatusConstant = 7
 )
 
-var statusStrings = [...]string{"NOTFOUND", "OK", "WARN", "ERR", "STOP", "STALL", "REWIND"}
+var statusStrings = [...]string{"NOTFOUND", "OK", "WARN", "ERR", "STOP", "STALL", "REWIND", "MUTED"}
 
 // String returns a string representation of a StatusConstant
 func (c StatusConstant) String() string {
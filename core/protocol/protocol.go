@@ -60,8 +60,25 @@ type ApplicationContext struct {
 	// information, or to fetch the same information. It is serviced by the storage Coordinator.
 	StorageChannel chan *StorageRequest
 
+	// This is the channel over which requests can be sent to the cluster modules, outside of their regular refreshes.
+	// It is serviced by the cluster Coordinator, and passed to the module for the cluster that is named in the request.
+	ClusterChannel chan *ClusterRequest
+
+	// This is the channel over which requests can be sent to the consumer modules, outside of their regular reads. It
+	// is serviced by the consumer Coordinator, and passed to a module for the cluster that is named in the request.
+	ConsumerChannel chan *ConsumerRequest
+
+	// OffsetScheduler coordinates the regular offset fetches of all the cluster modules. It is set up by the cluster
+	// Coordinator, and the cluster modules fetch on their own schedule if it is not set.
+	OffsetScheduler OffsetScheduler
+
 	// This is a boolean flag which is set by the last subsystem, the consumer, in order to signal when Burrow is ready
 	AppReady bool
+
+	// LagSinks receive every lag sample that the storage module calculates, in addition to the webhook set with the
+	// storage lag-sink-url config. This field can be set prior to calling core.Start() in order to process the
+	// samples without changing Burrow itself.
+	LagSinks []LagSink
 }
 
 // Module is a common interface for all modules so that they can be manipulated by the coordinators in the same way.
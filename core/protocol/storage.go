@@ -61,6 +61,85 @@ const (
 	// StorageFetchConsumersForTopic is the request type to obtain a list of all consumer groups consuming from a topic.
 	// Returns a []string
 	StorageFetchConsumersForTopic StorageRequestConstant = 11
+
+	// StorageFetchStats is the request type to retrieve the internal queue depths of the storage module. Requires
+	// Reply. Returns a *StorageStats
+	StorageFetchStats StorageRequestConstant = 12
+
+	// StorageSetMuteGroup is the request type to mute a consumer group, so that it is evaluated as StatusMuted.
+	// Requires Cluster and Group fields. If Timestamp is set, it is the time (in milliseconds) that the mute expires
+	StorageSetMuteGroup StorageRequestConstant = 13
+
+	// StorageSetUnmuteGroup is the request type to remove the mute for a consumer group. Requires Cluster and Group
+	// fields
+	StorageSetUnmuteGroup StorageRequestConstant = 14
+
+	// StorageFetchMutedGroups is the request type to retrieve the muted consumer groups in a cluster. Requires Reply and
+	// Cluster fields. Returns a map[string]int64 of group names to the time the mute expires (0 if it does not)
+	StorageFetchMutedGroups StorageRequestConstant = 15
+
+	// StorageFetchEndOffsets is the request type to retrieve the latest broker offset stored for every partition in a
+	// cluster, with the time it was fetched. Requires Reply and Cluster fields. Returns a []*EndOffset
+	StorageFetchEndOffsets StorageRequestConstant = 16
+
+	// StorageSetGroupBaselineSample is the request type to add a sample of a consumer group's total lag to its lag
+	// baseline, in the bucket for the hour of the day of the sample. Requires Cluster, Group, Lag, and Timestamp fields
+	StorageSetGroupBaselineSample StorageRequestConstant = 17
+
+	// StorageFetchGroupBaseline is the request type to retrieve the lag baseline for a consumer group. Requires Reply,
+	// Cluster, and Group fields. Returns a *LagBaseline
+	StorageFetchGroupBaseline StorageRequestConstant = 18
+
+	// StorageSetBrokerOldestOffset is the request type to store the oldest offset that the broker has for a partition
+	// (the start of the log). Requires Cluster, Topic, Partition, TopicPartitionCount, and Offset fields
+	StorageSetBrokerOldestOffset StorageRequestConstant = 19
+
+	// StorageSetGroupStatus is the request type to record the status of a consumer group at a point in time. Requires
+	// Cluster, Group, Status, and Timestamp fields. The status is only stored if it differs from the last one stored
+	StorageSetGroupStatus StorageRequestConstant = 20
+
+	// StorageFetchGroupStatusHistory is the request type to retrieve the status changes recorded for a consumer group.
+	// Requires Cluster, Group, Timestamp, and EndTimestamp fields. Returns a []StatusTransition
+	StorageFetchGroupStatusHistory StorageRequestConstant = 21
+
+	// StorageSetTopicCompacted is the request type to record whether a topic is compacted (its cleanup.policy includes
+	// compact). Requires Cluster, Topic, and Compacted fields
+	StorageSetTopicCompacted StorageRequestConstant = 22
+
+	// StorageFetchCompactedTopics is the request type to retrieve the names of the topics in a cluster that are
+	// compacted. Requires Cluster field. Returns a sorted []string
+	StorageFetchCompactedTopics StorageRequestConstant = 23
+
+	// StorageSetBrokerLookbackOffset is the request type to store the offset that a partition was at a time in the past,
+	// for calculating time lag. Requires Cluster, Topic, Partition, TopicPartitionCount, Offset, and Timestamp fields. An
+	// Offset of -1 means that nothing has been produced to the partition since Timestamp
+	StorageSetBrokerLookbackOffset StorageRequestConstant = 24
+
+	// StorageFetchTopicOldestOffsets is the request type to retrieve the oldest offset stored for each partition of a
+	// topic. Requires Reply, Cluster, and Topic fields. Returns a []int64, with -1 for the partitions where the oldest
+	// offset is not known, or an empty slice if no oldest offsets have been stored for the topic
+	StorageFetchTopicOldestOffsets StorageRequestConstant = 25
+
+	// StorageFetchGroupUnknownTopics is the request type to retrieve the topics that a consumer group has committed
+	// offsets for recently that the cluster has no end offsets for, and so were not stored. Requires Reply, Cluster,
+	// and Group fields. Returns a sorted []string
+	StorageFetchGroupUnknownTopics StorageRequestConstant = 26
+
+	// StorageSetDeletePartition is the request type to remove a single partition of a topic from the broker and all
+	// consumers. Requires Cluster, Topic, Partition, and TopicPartitionCount fields. If the partition is at or beyond
+	// TopicPartitionCount, the topic has fewer partitions than it did, and every partition past the count is removed.
+	// Otherwise, the partition's offsets are cleared, as if they had never been fetched or committed
+	StorageSetDeletePartition StorageRequestConstant = 27
+
+	// StorageSetBrokerTimeOffsets is the request type to store the offsets that a partition was at, at several times
+	// in the past, for finding when the message a group is at was produced. Requires Cluster, Topic, Partition,
+	// TopicPartitionCount, and TimeOffsets fields. The samples replace any that are stored for the partition
+	StorageSetBrokerTimeOffsets StorageRequestConstant = 28
+
+	// StorageSetTopicFiltered is the request type to record whether a topic is left out by the cluster module's topic
+	// filter, so that commits to it are not counted as commits to a topic that does not exist. Requires Cluster, Topic,
+	// and Filtered fields
+	StorageSetTopicFiltered StorageRequestConstant = 29
 )
 
 var storageRequestStrings = [...]string{
@@ -76,6 +155,24 @@ var storageRequestStrings = [...]string{
 	"StorageFetchTopic",
 	"StorageClearConsumerOwners",
 	"StorageFetchConsumersForTopic",
+	"StorageFetchStats",
+	"StorageSetMuteGroup",
+	"StorageSetUnmuteGroup",
+	"StorageFetchMutedGroups",
+	"StorageFetchEndOffsets",
+	"StorageSetGroupBaselineSample",
+	"StorageFetchGroupBaseline",
+	"StorageSetBrokerOldestOffset",
+	"StorageSetGroupStatus",
+	"StorageFetchGroupStatusHistory",
+	"StorageSetTopicCompacted",
+	"StorageFetchCompactedTopics",
+	"StorageSetBrokerLookbackOffset",
+	"StorageFetchTopicOldestOffsets",
+	"StorageFetchGroupUnknownTopics",
+	"StorageSetDeletePartition",
+	"StorageSetBrokerTimeOffsets",
+	"StorageSetTopicFiltered",
 }
 
 // String returns a string representation of a StorageRequestConstant for logging
@@ -121,7 +218,7 @@ type StorageRequest struct {
 	// The ID of the partition to which the request applies
 	Partition int32
 
-	// For StorageSetBrokerOffset requests, TopicPartitionCount indicates the total number of partitions for the topic
+	// For StorageSetBrokerOffset and StorageSetBrokerOldestOffset requests, TopicPartitionCount indicates the total number of partitions for the topic
 	TopicPartitionCount int32
 
 	// For StorageSetBrokerOffset and StorageSetConsumerOffset requests, the offset to store
@@ -130,14 +227,130 @@ type StorageRequest struct {
 	// For StorageSetConsumerOffset requests, the offset of the offset commit itself (i.e. the __consumer_offsets offset)
 	Order int64
 
-	// For StorageSetConsumerOffset requests, the timestamp of the offset being stored
+	// For StorageSetConsumerOffset requests, the timestamp of the offset being stored. For StorageSetMuteGroup
+	// requests, the time the mute expires. For StorageFetchGroupStatusHistory requests, the start of the time range
 	Timestamp int64
 
+	// For StorageFetchGroupStatusHistory requests, the end of the time range
+	EndTimestamp int64
+
 	// For StorageSetConsumerOwner requests, a string describing the consumer host that owns the partition
 	Owner string
 
 	// For StorageSetConsumerOwner requests, a string containing the client_id set by the consumer
 	ClientID string
+
+	// For StorageSetConsumerOwner requests, the group.instance.id set by the consumer, if it uses static membership
+	InstanceID string
+
+	// For StorageSetGroupBaselineSample requests, the total lag of the group
+	Lag uint64
+
+	// For StorageSetGroupStatus requests, the status of the group
+	Status StatusConstant
+
+	// For StorageSetTopicCompacted requests, whether the topic is compacted
+	Compacted bool
+
+	// For StorageSetTopicFiltered requests, whether the topic is left out by the topic filter
+	Filtered bool
+
+	// For StorageSetBrokerTimeOffsets requests, the offset that the partition was at, at each of the sample times
+	TimeOffsets []TimeOffset
+}
+
+// TimeOffset is the offset that a partition was at, at a time in the past. This is the earliest offset with a timestamp
+// at or after Timestamp (in milliseconds), or -1 if nothing had been produced to the partition since then.
+type TimeOffset struct {
+	Timestamp int64 `json:"timestamp"`
+	Offset    int64 `json:"offset"`
+}
+
+// StorageStats is the response that is sent for a StorageFetchStats request. It describes how many requests are waiting
+// to be processed by the storage module
+type StorageStats struct {
+	// The number of requests waiting to be assigned to a worker
+	RequestQueue int `json:"request-queue"`
+
+	// The number of requests waiting for each worker
+	WorkerQueues []int `json:"worker-queues"`
+}
+
+// EndOffset is the latest broker offset stored for a single partition. It is used as part of the response to a
+// StorageFetchEndOffsets request
+type EndOffset struct {
+	// The topic name for this partition
+	Topic string `json:"topic"`
+
+	// The partition ID
+	Partition int32 `json:"partition"`
+
+	// The broker end offset for the partition
+	Offset int64 `json:"offset"`
+
+	// The time (in milliseconds) that the end offset was fetched from the broker
+	Timestamp int64 `json:"timestamp"`
+}
+
+// LagBaseline is the lag profile that has been learned for a consumer group, from samples of its total lag. It is the
+// response to a StorageFetchGroupBaseline request
+type LagBaseline struct {
+	// The baseline for each hour of the day (in UTC), so that groups whose lag follows a daily traffic pattern are
+	// compared with the lag they usually have at that time
+	Hours [24]LagBaselineHour `json:"hours"`
+}
+
+// LagBaselineHour is the part of a LagBaseline for a single hour of the day. The mean and standard deviation are
+// weighted towards recent samples
+type LagBaselineHour struct {
+	// The number of samples that have been taken in this hour
+	Samples int64 `json:"samples"`
+
+	// The mean of the total lag for the group
+	Mean float64 `json:"mean"`
+
+	// The standard deviation of the total lag for the group
+	StdDev float64 `json:"stddev"`
+}
+
+// StatusTransition is a change in the status of a consumer group. A slice of them is the response to a
+// StorageFetchGroupStatusHistory request
+type StatusTransition struct {
+	// The time (in milliseconds) that the group was first evaluated with this status
+	Timestamp int64 `json:"timestamp"`
+
+	// The status of the group from this time until the next transition
+	Status StatusConstant `json:"status"`
+}
+
+// LagSample is the lag of a consumer group for a single partition, as calculated by the storage module when the group
+// commits an offset. Every sample is sent to the configured LagSinks.
+type LagSample struct {
+	Cluster   string `json:"cluster"`
+	Group     string `json:"group"`
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+
+	// The offset that the group committed, and the time (in milliseconds) it was committed
+	Offset    int64 `json:"offset"`
+	Timestamp int64 `json:"timestamp"`
+
+	// The end offset of the partition that the lag was calculated against
+	EndOffset int64 `json:"end-offset"`
+
+	// The number of messages that the group was behind when it committed the offset
+	Lag uint64 `json:"lag"`
+}
+
+// LagSink is the interface for receiving the lag samples calculated by the storage module. Samples are queued for each
+// sink, and sent to it in batches from a goroutine that only serves that sink, so a sink may block (such as to send
+// the samples over the network) without holding up storage. If a sink falls behind far enough to fill its queue,
+// samples are dropped for it until it catches up. An error returned by SendLagSamples is logged, and the batch is not
+// sent again.
+type LagSink interface {
+	// SendLagSamples is called with each batch of samples, in the order they were calculated for each partition. The
+	// slice is not used again after the call returns.
+	SendLagSamples(samples []*LagSample) error
 }
 
 // ConsumerPartition represents the information stored for a group for a single partition. It is used as part of the
@@ -152,6 +365,21 @@ type ConsumerPartition struct {
 	// and as such it is not provided when encoding to JSON (for HTTP responses)
 	BrokerOffsets []int64 `json:"-"`
 
+	// The oldest offset that the broker has for this partition, or -1 if it is not known (the cluster module only
+	// fetches it if fetch-oldest-offsets is set). This is used for evaluation only, and is not provided in JSON
+	OldestOffset int64 `json:"-"`
+
+	// The offset that the partition was at, at LookbackTimestamp, or -1 if it is not known (the cluster module only
+	// fetches it if offset-lookback is set). This is used for calculating time lag only, and is not provided in JSON
+	LookbackOffset int64 `json:"-"`
+
+	// The time (in milliseconds) that the partition was at LookbackOffset
+	LookbackTimestamp int64 `json:"-"`
+
+	// The offsets that the partition was at, at several times in the past, oldest first (the cluster module only
+	// samples them if time-lag-sampling is set). This is used for calculating time lag only, and is not provided in JSON
+	TimeOffsets []TimeOffset `json:"-"`
+
 	// A string that describes the consumer host that currently owns this partition, if the information is available
 	// (for active new consumers)
 	Owner string `json:"owner"`
@@ -159,9 +387,17 @@ type ConsumerPartition struct {
 	// A string containing the client_id set by the consumer (for active new consumers)
 	ClientID string `json:"client_id"`
 
+	// A string containing the group.instance.id set by the consumer (for active new consumers that use static
+	// membership)
+	InstanceID string `json:"instance_id"`
+
 	// The current number of messages that the consumer is behind for this partition. This is calculated using the
 	// last committed offset and the current broker end offset
 	CurrentLag uint64 `json:"current-lag"`
+
+	// True if the topic is compacted, which is only known if the cluster module fetches the topic configs (with
+	// detect-compacted-topics). The end offset of a compacted topic is not a count of the messages in it
+	Compacted bool `json:"compacted,omitempty"`
 }
 
 // Lag is just a wrapper for a uint64, but it can be `nil`
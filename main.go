@@ -84,8 +84,9 @@ func main() {
 	println(sarama.MaxVersion.String())
 	pwd, _ := os.Getwd()
 
-	// The only command line arg is the config file
+	// The command line args are the config file, and whether to only validate it
 	configPath := flag.String("config-dir", pwd, "Directory that contains the configuration file")
+	validateConfig := flag.Bool("validate", false, "Check the configuration, report any errors, and exit without starting")
 	flag.Parse()
 
 	// Load the configuration from the file
@@ -105,6 +106,19 @@ func main() {
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
 	viper.AutomaticEnv()
 
+	// Check the configuration for every module, reporting all the errors at once, without starting anything
+	if *validateConfig {
+		configErrors := core.ValidateConfig()
+		for _, configError := range configErrors {
+			fmt.Fprintln(os.Stderr, "Configuration error:", configError)
+		}
+		if len(configErrors) > 0 {
+			panic(exitCode{1})
+		}
+		fmt.Fprintln(os.Stderr, "Configuration is valid")
+		panic(exitCode{0})
+	}
+
 	// Create the PID file to lock out other processes
 	viper.SetDefault("general.pidfile", "burrow.pid")
 	pidFile := viper.GetString("general.pidfile")